@@ -0,0 +1,703 @@
+// Package a2a serves an OSSA agent to Agent2Agent (A2A) clients: its agent
+// card at /.well-known/agent.json and the JSON-RPC task methods at /.
+//
+//	tasks/send           start a task, or answer one waiting for input, and wait for it
+//	tasks/sendSubscribe  the same, streaming status and artifact updates over SSE
+//	tasks/get            a task's status, artifacts and history
+//	tasks/cancel         cancel a task
+//	tasks/resubscribe    stream a task's updates again
+//
+// Each task is a run of the manifest on an engine.Engine, recorded in a
+// runs.Store like those of ossa run. The run's input is the JSON object of
+// the message's data parts, with its text parts as "message"; its output
+// is the task's artifact. A tool call needing approval puts the task in the
+// input-required state until the client answers yes or no, or sends a data
+// part {"approved": bool}. The card and tasks are localized in the
+// request's Accept-Language locale.
+package a2a
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+// Task timings.
+const (
+	// taskRetention is how long a finished task can still be read.
+	taskRetention = time.Hour
+	// approvalTimeout is how long a task waits for an approval before
+	// treating it as denied.
+	approvalTimeout = 10 * time.Minute
+	// heartbeat is how often idle streams send a keep-alive.
+	heartbeat = 15 * time.Second
+)
+
+// maxRequestBody is the largest JSON-RPC request accepted.
+const maxRequestBody = 4 << 20
+
+// JSON-RPC error codes used in responses, including those A2A defines.
+const (
+	codeParseError              = -32700
+	codeInvalidRequest          = -32600
+	codeMethodNotFound          = -32601
+	codeInvalidParams           = -32602
+	codeInternalError           = -32603
+	codeTaskNotFound            = -32001
+	codeTaskNotCancelable       = -32002
+	codePushNotSupported        = -32003
+	codeContentTypeNotSupported = -32005
+)
+
+// Options configure a Server.
+type Options struct {
+	// Engine returns the engine a task runs on, with approve deciding its
+	// tool calls needing approval.
+	Engine func(approve ossa.ApprovalFunc) *engine.Engine
+	// Store records each task's run; nil records nothing.
+	Store runs.Store
+	// URL is the endpoint the agent card advertises, by default the one
+	// the card was fetched from.
+	URL string
+	// Token, if set, is the bearer token task requests need. The agent
+	// card stays public.
+	Token string
+	// Locale is the locale of the card's description for requests without
+	// an Accept-Language header; "" means ossa.DefaultLocale. Tasks run in
+	// their request's Accept-Language locale, or else the engine's.
+	Locale string
+}
+
+// Server serves one manifest over A2A.
+type Server struct {
+	m     *ossa.Manifest
+	opts  Options
+	start func(ctx context.Context, rec *runs.Recorder, input map[string]interface{}) (*runs.Run, error)
+
+	mu    sync.Mutex
+	tasks map[string]*task
+}
+
+// New returns a Server running m, an Agent, Task or Workflow loaded from
+// source.
+func New(m *ossa.Manifest, source string, opts Options) (*Server, error) {
+	s := &Server{m: m, opts: opts, tasks: map[string]*task{}}
+	switch m.Kind {
+	case ossa.KindAgent:
+		s.start = func(ctx context.Context, rec *runs.Recorder, input map[string]interface{}) (*runs.Run, error) {
+			return rec.RunAgent(ctx, m, source, input)
+		}
+	case ossa.KindTask:
+		s.start = func(ctx context.Context, rec *runs.Recorder, input map[string]interface{}) (*runs.Run, error) {
+			return rec.RunTask(ctx, m, source, input)
+		}
+	case ossa.KindWorkflow:
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workflow: %w", err)
+		}
+		wf, err := engine.ParseWorkflow(data)
+		if err != nil {
+			return nil, err
+		}
+		s.start = func(ctx context.Context, rec *runs.Recorder, input map[string]interface{}) (*runs.Run, error) {
+			return rec.RunWorkflow(ctx, wf, source, input)
+		}
+	default:
+		return nil, fmt.Errorf("a %s cannot be served", m.Kind)
+	}
+	return s, nil
+}
+
+// Card returns the agent card, advertising url unless Options.URL is set.
+// The manifest is its one skill, described in Options.Locale.
+func (s *Server) Card(url string) AgentCard {
+	return s.card(url, s.opts.Locale)
+}
+
+func (s *Server) card(url, locale string) AgentCard {
+	if s.opts.URL != "" {
+		url = s.opts.URL
+	}
+	if locale == "" {
+		locale = ossa.DefaultLocale
+	}
+	md := s.m.Metadata
+	description := md.Description
+	if description == "" {
+		description = s.m.RoleFor(locale)
+	}
+	version := md.Version
+	if version == "" {
+		version = "0.0.0"
+	}
+	tags := []string{strings.ToLower(string(s.m.Kind))}
+	for _, t := range s.m.Spec.Tools {
+		if t.Name != "" {
+			tags = append(tags, t.Name)
+		}
+	}
+	card := AgentCard{
+		Name:               md.Name,
+		Description:        description,
+		URL:                url,
+		Version:            version,
+		Capabilities:       Capabilities{Streaming: true},
+		DefaultInputModes:  []string{PartText, PartData},
+		DefaultOutputModes: []string{PartText, PartData},
+		Skills:             []Skill{{ID: md.Name, Name: md.Name, Description: description, Tags: tags}},
+	}
+	if s.opts.Token != "" {
+		card.Authentication = &Authentication{Schemes: []string{"bearer"}}
+	}
+	return card
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/agent.json":
+		if r.Method != http.MethodGet {
+			http.Error(w, "use GET", http.StatusMethodNotAllowed)
+			return
+		}
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		locale := ossa.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if locale == "" {
+			locale = s.opts.Locale
+		}
+		json.NewEncoder(w).Encode(s.card(scheme+"://"+r.Host+"/", locale))
+	case "/":
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.opts.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		s.handle(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func reply(w http.ResponseWriter, id json.RawMessage, result interface{}, err error) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Result: result}
+	if err != nil {
+		e, ok := err.(*rpcError)
+		if !ok {
+			e = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, e
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func decodeParams(req *request, v interface{}) error {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return errorf(codeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+	if err != nil || len(body) > maxRequestBody {
+		reply(w, nil, nil, errorf(codeInvalidRequest, "request too large"))
+		return
+	}
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		reply(w, nil, nil, errorf(codeParseError, "invalid JSON: %v", err))
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		reply(w, req.ID, nil, errorf(codeInvalidRequest, "not a JSON-RPC 2.0 request"))
+		return
+	}
+
+	switch req.Method {
+	case "tasks/send", "tasks/sendSubscribe":
+		var p TaskSendParams
+		if err := decodeParams(&req, &p); err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		t, n, err := s.send(p, ossa.ParseAcceptLanguage(r.Header.Get("Accept-Language")))
+		if err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		if req.Method == "tasks/sendSubscribe" {
+			s.stream(w, r, req.ID, t, nil, n)
+			return
+		}
+		t.wait(r.Context())
+		reply(w, req.ID, t.snapshot(p.HistoryLength), nil)
+	case "tasks/get", "tasks/resubscribe":
+		var p TaskQueryParams
+		if err := decodeParams(&req, &p); err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		t, err := s.task(p.ID)
+		if err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		if req.Method == "tasks/get" {
+			reply(w, req.ID, t.snapshot(p.HistoryLength), nil)
+			return
+		}
+		t.mu.Lock()
+		current := TaskStatusUpdateEvent{ID: t.id, Status: t.status, Final: final(t.status.State)}
+		n := len(t.events)
+		t.mu.Unlock()
+		s.stream(w, r, req.ID, t, []interface{}{current}, n)
+	case "tasks/cancel":
+		var p TaskQueryParams
+		if err := decodeParams(&req, &p); err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		t, err := s.task(p.ID)
+		if err == nil {
+			err = t.stop(r.Context())
+		}
+		if err != nil {
+			reply(w, req.ID, nil, err)
+			return
+		}
+		reply(w, req.ID, t.snapshot(nil), nil)
+	case "tasks/pushNotification/set", "tasks/pushNotification/get":
+		reply(w, req.ID, nil, errorf(codePushNotSupported, "push notifications are not supported"))
+	default:
+		reply(w, req.ID, nil, errorf(codeMethodNotFound, "method not found: %s", req.Method))
+	}
+}
+
+// send starts a task for p, running in locale if set, or gives p's message
+// to the task waiting for input it names. It returns the task and how many
+// of its events came before, from where a stream of the task begins.
+func (s *Server) send(p TaskSendParams, locale string) (*task, int, error) {
+	if len(p.Message.Parts) == 0 {
+		return nil, 0, errorf(codeInvalidParams, "message has no parts")
+	}
+	input, err := messageInput(p.Message)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.Message.Role == "" {
+		p.Message.Role = RoleUser
+	}
+
+	s.mu.Lock()
+	s.prune()
+	if t := s.tasks[p.ID]; t != nil {
+		s.mu.Unlock()
+		n, err := t.answer(p.Message)
+		return t, n, err
+	}
+	if p.ID == "" {
+		p.ID = runs.NewID(time.Now())
+	}
+	// Tasks only share cached tool results within their session.
+	scope := "task/" + p.ID
+	if p.SessionID != "" {
+		scope = "session/" + p.SessionID
+	}
+	ctx, cancel := context.WithCancel(engine.WithCacheScope(context.Background(), scope))
+	t := newTask(p.ID, p.SessionID, cancel)
+	t.history = append(t.history, p.Message)
+	s.tasks[p.ID] = t
+	s.mu.Unlock()
+
+	go s.run(ctx, t, input, locale)
+	return t, 0, nil
+}
+
+func (s *Server) run(ctx context.Context, t *task, input map[string]interface{}, locale string) {
+	defer t.cancel()
+	eng := *s.opts.Engine(t.approve)
+	if locale != "" {
+		eng.Locale = locale
+	}
+	eng.Events = func(ev engine.Event) {
+		if ev.Type == engine.EventToolCall {
+			t.mu.Lock()
+			t.setStatus(StateWorking, agentText("Calling "+ev.Tool))
+			t.mu.Unlock()
+		}
+	}
+	rec := &runs.Recorder{Engine: &eng, Store: s.opts.Store, OnStart: func(run *runs.Run) {
+		t.mu.Lock()
+		t.run = run.ID
+		t.setStatus(StateWorking, nil)
+		t.mu.Unlock()
+	}}
+	run, err := s.start(ctx, rec, input)
+	t.finish(run, err)
+}
+
+func (s *Server) task(id string) (*task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tasks[id]
+	if t == nil {
+		return nil, errorf(codeTaskNotFound, "task not found: %s", id)
+	}
+	return t, nil
+}
+
+// prune forgets tasks finished longer than taskRetention ago. s.mu must be
+// held.
+func (s *Server) prune() {
+	for id, t := range s.tasks {
+		t.mu.Lock()
+		old := !t.finished.IsZero() && time.Since(t.finished) > taskRetention
+		t.mu.Unlock()
+		if old {
+			delete(s.tasks, id)
+		}
+	}
+}
+
+// stream sends first and then t's events after the first n as SSE
+// JSON-RPC responses to id, until a final status update or the client
+// goes away.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, id json.RawMessage, t *task, first []interface{}, n int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		reply(w, id, nil, errorf(codeInternalError, "streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// send writes ev and reports whether it ends the stream.
+	send := func(ev interface{}) bool {
+		data, _ := json.Marshal(response{JSONRPC: "2.0", ID: id, Result: ev})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		status, ok := ev.(TaskStatusUpdateEvent)
+		return ok && status.Final
+	}
+	for _, ev := range first {
+		if send(ev) {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	tick := time.NewTicker(heartbeat)
+	defer tick.Stop()
+	for {
+		events, changed := t.since(n)
+		for _, ev := range events {
+			n++
+			if send(ev) {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+		select {
+		case <-changed:
+		case <-tick.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// task is a task and the update events it has sent for its streams.
+type task struct {
+	id        string
+	sessionID string
+	cancel    context.CancelFunc
+
+	mu        sync.Mutex
+	status    TaskStatus
+	artifacts []Artifact
+	history   []Message
+	run       string
+	canceled  bool
+	finished  time.Time
+	events    []interface{}
+	changed   chan struct{}
+	done      chan struct{}
+	// pending takes the decision while the task waits for an approval.
+	pending chan bool
+}
+
+func newTask(id, sessionID string, cancel context.CancelFunc) *task {
+	t := &task{id: id, sessionID: sessionID, cancel: cancel, changed: make(chan struct{}), done: make(chan struct{})}
+	t.status = TaskStatus{State: StateSubmitted, Timestamp: timestamp()}
+	return t
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// final reports whether a task in state stops for now, ending its streams.
+func final(state string) bool {
+	return state != StateSubmitted && state != StateWorking
+}
+
+func agentText(text string) *Message {
+	return &Message{Role: RoleAgent, Parts: []Part{{Type: PartText, Text: text}}}
+}
+
+// setStatus moves the task to state with the agent's msg, which joins the
+// history unless the task is just working. t.mu must be held.
+func (t *task) setStatus(state string, msg *Message) {
+	t.status = TaskStatus{State: state, Message: msg, Timestamp: timestamp()}
+	if msg != nil && state != StateWorking {
+		t.history = append(t.history, *msg)
+	}
+	t.publish(TaskStatusUpdateEvent{ID: t.id, Status: t.status, Final: final(state)})
+}
+
+// publish adds ev for the task's streams. t.mu must be held.
+func (t *task) publish(ev interface{}) {
+	t.events = append(t.events, ev)
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// since returns the events after the first n and a channel closed when
+// more come.
+func (t *task) since(n int) ([]interface{}, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n > len(t.events) {
+		n = len(t.events)
+	}
+	return t.events[n:], t.changed
+}
+
+// wait waits until the task stops for now or ctx is done.
+func (t *task) wait(ctx context.Context) {
+	for {
+		t.mu.Lock()
+		stopped, changed := final(t.status.State), t.changed
+		t.mu.Unlock()
+		if stopped {
+			return
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *task) snapshot(historyLength *int) Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task := Task{ID: t.id, SessionID: t.sessionID, Status: t.status, Artifacts: append([]Artifact(nil), t.artifacts...)}
+	if historyLength != nil && *historyLength > 0 {
+		start := len(t.history) - *historyLength
+		if start < 0 {
+			start = 0
+		}
+		task.History = append([]Message(nil), t.history[start:]...)
+	}
+	if t.run != "" {
+		task.Metadata = map[string]interface{}{"run": t.run}
+	}
+	return task
+}
+
+// approve puts the task in the input-required state until the client
+// decides on req.
+func (t *task) approve(ctx context.Context, req ossa.ApprovalRequest) (bool, error) {
+	ch := make(chan bool, 1)
+	question := "Approve calling " + req.Tool
+	if req.Summary != "" {
+		question += " (" + req.Summary + ")"
+	}
+	t.mu.Lock()
+	t.pending = ch
+	t.setStatus(StateInputRequired, &Message{Role: RoleAgent, Parts: []Part{
+		{Type: PartText, Text: question + "? Answer yes or no."},
+		{Type: PartData, Data: map[string]interface{}{"tool": req.Tool, "call_id": req.CallID, "summary": req.Summary}},
+	}})
+	t.mu.Unlock()
+
+	timer := time.NewTimer(approvalTimeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case ok := <-ch:
+		return ok, nil
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	t.mu.Lock()
+	answered := t.pending != ch
+	if !answered {
+		t.pending = nil
+		if err == nil {
+			t.setStatus(StateWorking, nil)
+		}
+	}
+	t.mu.Unlock()
+	if answered && err == nil {
+		return <-ch, nil
+	}
+	return false, err
+}
+
+// answer gives msg to the task's pending approval and returns how many
+// events came before.
+func (t *task) answer(msg Message) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil {
+		return 0, errorf(codeInvalidParams, "task %s is %s, not waiting for input", t.id, t.status.State)
+	}
+	n := len(t.events)
+	t.history = append(t.history, msg)
+	t.pending <- approved(msg)
+	t.pending = nil
+	t.setStatus(StateWorking, nil)
+	return n, nil
+}
+
+// stop cancels the task and waits for its run to end.
+func (t *task) stop(ctx context.Context) error {
+	t.mu.Lock()
+	if state := t.status.State; state != StateInputRequired && final(state) {
+		t.mu.Unlock()
+		return errorf(codeTaskNotCancelable, "task %s is already %s", t.id, state)
+	}
+	t.canceled = true
+	t.mu.Unlock()
+	t.cancel()
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (t *task) finish(run *runs.Run, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.canceled:
+		t.setStatus(StateCanceled, nil)
+	case err != nil:
+		t.setStatus(StateFailed, agentText(err.Error()))
+	default:
+		a := Artifact{Name: "output", Parts: outputParts(run.Output)}
+		t.artifacts = append(t.artifacts, a)
+		t.publish(TaskArtifactUpdateEvent{ID: t.id, Artifact: a})
+		t.setStatus(StateCompleted, nil)
+	}
+	t.finished = time.Now()
+	close(t.done)
+}
+
+// messageInput is the run input for msg: its data parts' fields, with its
+// text parts as "message".
+func messageInput(msg Message) (map[string]interface{}, error) {
+	input := map[string]interface{}{}
+	var text []string
+	for _, p := range msg.Parts {
+		switch p.Type {
+		case PartText:
+			text = append(text, p.Text)
+		case PartData:
+			for k, v := range p.Data {
+				input[k] = v
+			}
+		default:
+			return nil, errorf(codeContentTypeNotSupported, "%s parts are not supported", p.Type)
+		}
+	}
+	if _, ok := input["message"]; !ok && len(text) > 0 {
+		input["message"] = strings.Join(text, "\n")
+	}
+	return input, nil
+}
+
+// outputParts is output as a text part when it is an agent's plain text
+// answer, and as a data part otherwise.
+func outputParts(output map[string]interface{}) []Part {
+	if content, ok := output["content"].(string); ok && len(output) == 1 {
+		return []Part{{Type: PartText, Text: content}}
+	}
+	return []Part{{Type: PartData, Data: output}}
+}
+
+// approved reports whether msg approves: a data part's "approved" or a
+// text answer of yes.
+func approved(msg Message) bool {
+	for _, p := range msg.Parts {
+		if ok, isBool := p.Data["approved"].(bool); isBool {
+			return ok
+		}
+	}
+	for _, p := range msg.Parts {
+		switch strings.ToLower(strings.Trim(strings.TrimSpace(p.Text), ".!")) {
+		case "yes", "y", "approve", "approved", "ok":
+			return true
+		}
+	}
+	return false
+}
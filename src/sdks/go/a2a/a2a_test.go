@@ -0,0 +1,307 @@
+package a2a
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+const manifest = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: reviewer
+  version: 1.2.0
+  description: Reviews merge requests
+spec:
+  role: You review code.
+  tools:
+    - type: mcp
+      name: search
+`
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+// newServer serves the reviewer agent, which calls search, needing
+// approval, then answers with what it found or the denial.
+func newServer(t *testing.T, token string) (*httptest.Server, runs.Store) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "reviewer.ossa.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ossa.LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := modelFunc(func(req *engine.Request) (*engine.Response, error) {
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == engine.RoleUser {
+			return &engine.Response{ToolCalls: []ossa.ToolCall{{ID: "c1", Tool: "search"}}}, nil
+		}
+		return &engine.Response{Content: fmt.Sprintf(`{"found": %q}`, last.Content)}, nil
+	})
+	store := &runs.DirStore{Dir: t.TempDir()}
+	s, err := New(m, path, Options{Store: store, Token: token, Engine: func(approve ossa.ApprovalFunc) *engine.Engine {
+		return &engine.Engine{Model: model, Tools: func(m *ossa.Manifest) ossa.ToolExecFunc {
+			return func(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+				err := ossa.RequestApproval(ctx, approve, ossa.ApprovalRequest{Agent: m.Metadata.Name, Tool: call.Tool, CallID: call.ID, Summary: "Search the code"})
+				if err != nil {
+					return nil, err
+				}
+				return []byte("3 results"), nil
+			}
+		}}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func call(t *testing.T, srv *httptest.Server, method, params string) rpcResponse {
+	t.Helper()
+	body := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": %q, "params": %s}`, method, params)
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func callTask(t *testing.T, srv *httptest.Server, method, params string) Task {
+	t.Helper()
+	resp := call(t, srv, method, params)
+	if resp.Error != nil {
+		t.Fatalf("%s failed: %+v", method, resp.Error)
+	}
+	var task Task
+	json.Unmarshal(resp.Result, &task)
+	return task
+}
+
+func TestCard(t *testing.T) {
+	srv, _ := newServer(t, "s3cr3t")
+	resp, err := http.Get(srv.URL + "/.well-known/agent.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var card AgentCard
+	json.NewDecoder(resp.Body).Decode(&card)
+	if card.Name != "reviewer" || card.Version != "1.2.0" || card.URL != srv.URL+"/" || !card.Capabilities.Streaming {
+		t.Errorf("Unexpected card %+v", card)
+	}
+	if len(card.Skills) != 1 || card.Skills[0].Description != "Reviews merge requests" || strings.Join(card.Skills[0].Tags, ",") != "agent,search" {
+		t.Errorf("Unexpected skills %+v", card.Skills)
+	}
+	if card.Authentication == nil || card.Authentication.Schemes[0] != "bearer" {
+		t.Errorf("Expected bearer authentication, got %+v", card.Authentication)
+	}
+
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tasks/get", "params": {"id": "x"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", resp.StatusCode)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	m := ossa.NewManifest("greeter", ossa.KindAgent)
+	m.Spec.Role = "You greet people."
+	m.Spec.RoleLocales = map[string]string{"en": "You greet people.", "de": "Du begrüßt Leute.", "fr": "Tu salues les gens."}
+	prompts := make(chan string, 1)
+	model := modelFunc(func(req *engine.Request) (*engine.Response, error) {
+		prompts <- req.Messages[0].Content
+		return &engine.Response{Content: "Hallo"}, nil
+	})
+	s, err := New(m, "greeter.ossa.yaml", Options{Locale: "de", Engine: func(ossa.ApprovalFunc) *engine.Engine {
+		return &engine.Engine{Model: model}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card := s.Card("http://localhost/"); card.Skills[0].Description != "Du begrüßt Leute." {
+		t.Errorf("Expected the card in Options.Locale, got %+v", card.Skills)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/.well-known/agent.json", nil)
+	req.Header.Set("Accept-Language", "fr-CA, de;q=0.5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var card AgentCard
+	json.NewDecoder(resp.Body).Decode(&card)
+	resp.Body.Close()
+	if card.Skills[0].Description != "Tu salues les gens." {
+		t.Errorf("Expected the card in the Accept-Language locale, got %+v", card.Skills)
+	}
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "tasks/send", "params": {"id": "t1", "message": {"role": "user", "parts": [{"type": "text", "text": "Hi"}]}}}`
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+	req.Header.Set("Accept-Language", "fr")
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if prompt := <-prompts; prompt != "Tu salues les gens." {
+		t.Errorf("Expected the task run in French, got %q", prompt)
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv, store := newServer(t, "")
+	task := callTask(t, srv, "tasks/send", `{"id": "t1", "sessionId": "s1", "message": {"role": "user", "parts": [{"type": "text", "text": "Review MR 7"}]}}`)
+	if task.ID != "t1" || task.SessionID != "s1" || task.Status.State != StateInputRequired {
+		t.Fatalf("Expected the task waiting for approval, got %+v", task)
+	}
+	if parts := task.Status.Message.Parts; len(parts) != 2 || !strings.Contains(parts[0].Text, "search (Search the code)") || parts[1].Data["call_id"] != "c1" {
+		t.Errorf("Unexpected approval question %+v", task.Status.Message)
+	}
+
+	task = callTask(t, srv, "tasks/send", `{"id": "t1", "message": {"role": "user", "parts": [{"type": "text", "text": "Yes."}]}, "historyLength": 10}`)
+	if task.Status.State != StateCompleted || len(task.Artifacts) != 1 || task.Artifacts[0].Parts[0].Data["found"] != "3 results" {
+		t.Fatalf("Expected the task completed, got %+v", task)
+	}
+	// The request, the question and the answer.
+	if len(task.History) != 3 || task.History[2].Parts[0].Text != "Yes." {
+		t.Errorf("Unexpected history %+v", task.History)
+	}
+	run, err := store.Get(context.Background(), task.Metadata["run"].(string))
+	if err != nil || run.Status != runs.StatusSucceeded || run.Input["message"] != "Review MR 7" {
+		t.Errorf("Expected the run recorded, got %+v %v", run, err)
+	}
+	if got := callTask(t, srv, "tasks/get", `{"id": "t1"}`); got.Status.State != StateCompleted || got.History != nil {
+		t.Errorf("Unexpected task %+v", got)
+	}
+
+	for _, tc := range []struct {
+		method, params string
+		code           int
+	}{
+		{"tasks/get", `{"id": "nope"}`, codeTaskNotFound},
+		{"tasks/cancel", `{"id": "t1"}`, codeTaskNotCancelable},
+		{"tasks/send", `{"id": "t1", "message": {"parts": [{"type": "text", "text": "again"}]}}`, codeInvalidParams},
+		{"tasks/send", `{"id": "t2", "message": {"parts": [{"type": "file", "file": {"uri": "x"}}]}}`, codeContentTypeNotSupported},
+		{"tasks/pushNotification/set", `{}`, codePushNotSupported},
+		{"tasks/nope", `{}`, codeMethodNotFound},
+	} {
+		if resp := call(t, srv, tc.method, tc.params); resp.Error == nil || resp.Error.Code != tc.code {
+			t.Errorf("Expected %s %s to fail with %d, got %+v", tc.method, tc.params, tc.code, resp.Error)
+		}
+	}
+}
+
+// subscribe calls method and returns the streamed events.
+func subscribe(t *testing.T, srv *httptest.Server, method, params string) []map[string]interface{} {
+	t.Helper()
+	body := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": %q, "params": %s}`, method, params)
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Unexpected content type %q", ct)
+	}
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev.Result)
+	}
+	return events
+}
+
+// describe is the state of each status update, or the artifact.
+func describe(events []map[string]interface{}) string {
+	var out []string
+	for _, ev := range events {
+		if status, ok := ev["status"].(map[string]interface{}); ok {
+			out = append(out, status["state"].(string))
+		} else {
+			out = append(out, "artifact")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestSendSubscribe(t *testing.T) {
+	srv, _ := newServer(t, "")
+	events := subscribe(t, srv, "tasks/sendSubscribe", `{"id": "t1", "message": {"parts": [{"type": "data", "data": {"mr": 7}}]}}`)
+	if got := describe(events); got != "working working input-required" {
+		t.Fatalf("Unexpected events %q", got)
+	}
+	if events[2]["final"] != true {
+		t.Errorf("Expected the stream to end waiting for input, got %v", events[2])
+	}
+	if events := subscribe(t, srv, "tasks/resubscribe", `{"id": "t1"}`); describe(events) != "input-required" {
+		t.Errorf("Expected the current status on resubscribing, got %q", describe(events))
+	}
+
+	events = subscribe(t, srv, "tasks/sendSubscribe", `{"id": "t1", "message": {"parts": [{"type": "data", "data": {"approved": false}}]}}`)
+	if got := describe(events); got != "working artifact completed" {
+		t.Fatalf("Unexpected events %q", got)
+	}
+	artifact := events[1]["artifact"].(map[string]interface{})
+	data := artifact["parts"].([]interface{})[0].(map[string]interface{})["data"].(map[string]interface{})
+	if !strings.Contains(data["found"].(string), "not approved") {
+		t.Errorf("Expected the denial given to the agent, got %v", artifact)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	srv, store := newServer(t, "")
+	if task := callTask(t, srv, "tasks/send", `{"id": "t1", "message": {"parts": [{"type": "text", "text": "Review"}]}}`); task.Status.State != StateInputRequired {
+		t.Fatalf("Unexpected task %+v", task)
+	}
+	task := callTask(t, srv, "tasks/cancel", `{"id": "t1"}`)
+	if task.Status.State != StateCanceled {
+		t.Errorf("Expected the task canceled, got %+v", task)
+	}
+	run, err := store.Get(context.Background(), task.Metadata["run"].(string))
+	if err != nil || run.Status == runs.StatusRunning {
+		t.Errorf("Expected the run recorded as finished, got %+v %v", run, err)
+	}
+}
@@ -0,0 +1,136 @@
+package a2a
+
+// Task states.
+const (
+	StateSubmitted     = "submitted"
+	StateWorking       = "working"
+	StateInputRequired = "input-required"
+	StateCompleted     = "completed"
+	StateCanceled      = "canceled"
+	StateFailed        = "failed"
+	StateUnknown       = "unknown"
+)
+
+// Message roles.
+const (
+	RoleUser  = "user"
+	RoleAgent = "agent"
+)
+
+// Part types.
+const (
+	PartText = "text"
+	PartData = "data"
+	PartFile = "file"
+)
+
+// AgentCard describes the served agent at /.well-known/agent.json.
+type AgentCard struct {
+	Name               string          `json:"name"`
+	Description        string          `json:"description,omitempty"`
+	URL                string          `json:"url"`
+	Version            string          `json:"version"`
+	Capabilities       Capabilities    `json:"capabilities"`
+	Authentication     *Authentication `json:"authentication,omitempty"`
+	DefaultInputModes  []string        `json:"defaultInputModes"`
+	DefaultOutputModes []string        `json:"defaultOutputModes"`
+	Skills             []Skill         `json:"skills"`
+}
+
+// Capabilities are the optional protocol features an agent supports.
+type Capabilities struct {
+	Streaming              bool `json:"streaming"`
+	PushNotifications      bool `json:"pushNotifications"`
+	StateTransitionHistory bool `json:"stateTransitionHistory"`
+}
+
+// Authentication lists the schemes callers authenticate with.
+type Authentication struct {
+	Schemes []string `json:"schemes"`
+}
+
+// Skill is something the agent can do.
+type Skill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags"`
+}
+
+// Task is a unit of work the agent runs for a client.
+type Task struct {
+	ID        string                 `json:"id"`
+	SessionID string                 `json:"sessionId,omitempty"`
+	Status    TaskStatus             `json:"status"`
+	Artifacts []Artifact             `json:"artifacts,omitempty"`
+	History   []Message              `json:"history,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskStatus is a task's state, with the agent's message about it.
+type TaskStatus struct {
+	State     string   `json:"state"`
+	Message   *Message `json:"message,omitempty"`
+	Timestamp string   `json:"timestamp,omitempty"`
+}
+
+// Message is one turn of the conversation about a task.
+type Message struct {
+	Role     string                 `json:"role"`
+	Parts    []Part                 `json:"parts"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Part is a piece of a message or artifact: text, a JSON object or a file.
+type Part struct {
+	Type     string                 `json:"type"`
+	Text     string                 `json:"text,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	File     *File                  `json:"file,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// File is a file part's content, inline as base64 bytes or by URI.
+type File struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Bytes    string `json:"bytes,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+// Artifact is an output of a task.
+type Artifact struct {
+	Name     string                 `json:"name,omitempty"`
+	Parts    []Part                 `json:"parts"`
+	Index    int                    `json:"index"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskStatusUpdateEvent is streamed when a task's status changes. Final
+// marks the last event of the stream.
+type TaskStatusUpdateEvent struct {
+	ID     string     `json:"id"`
+	Status TaskStatus `json:"status"`
+	Final  bool       `json:"final"`
+}
+
+// TaskArtifactUpdateEvent is streamed when a task produces an artifact.
+type TaskArtifactUpdateEvent struct {
+	ID       string   `json:"id"`
+	Artifact Artifact `json:"artifact"`
+}
+
+// TaskSendParams are the params of tasks/send and tasks/sendSubscribe.
+type TaskSendParams struct {
+	ID            string                 `json:"id"`
+	SessionID     string                 `json:"sessionId,omitempty"`
+	Message       Message                `json:"message"`
+	HistoryLength *int                   `json:"historyLength,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskQueryParams are the params of tasks/get and tasks/resubscribe.
+type TaskQueryParams struct {
+	ID            string `json:"id"`
+	HistoryLength *int   `json:"historyLength,omitempty"`
+}
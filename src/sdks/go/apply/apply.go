@@ -0,0 +1,369 @@
+// Package apply pushes many manifests to a target, such as an ossa serve
+// registry, a Kubernetes cluster or a Drupal site, as one change.
+//
+// Apply validates every manifest before writing any, orders agents before
+// the tasks and workflows that use them, and skips manifests the target
+// already holds unchanged. If a write fails, the writes before it are
+// undone, so the target ends up with all of the manifests or none.
+//
+// Like kubectl apply, each write records the manifest as applied in the
+// LastAppliedAnnotation, and updates are three-way merges of the target's
+// copy, the last applied manifest and the new one (see
+// ossa.ThreeWayMerge): fields edited on the target and absent from the
+// manifests survive, and fields removed from a manifest are removed from
+// the target.
+//
+// With Options.Prune, Apply also deletes the manifests an earlier apply
+// wrote that match the selector and are no longer among the sources, as
+// kubectl apply --prune does. Policy-tier agents are never pruned.
+package apply
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// LastAppliedAnnotation holds the JSON of the manifest last applied, in
+// metadata.annotations of the target's copy.
+const LastAppliedAnnotation = ossa.LastAppliedAnnotation
+
+// Target stores manifests, identified by kind, namespace and name.
+type Target interface {
+	// String describes the target in messages.
+	String() string
+	// Get returns the target's copy of m, or nil if it has none.
+	Get(ctx context.Context, m *ossa.Manifest) (*ossa.Manifest, error)
+	// Put creates or replaces m.
+	Put(ctx context.Context, m *ossa.Manifest) error
+	// Delete removes m.
+	Delete(ctx context.Context, m *ossa.Manifest) error
+}
+
+// Lister is a Target that can list what it holds, which pruning needs.
+type Lister interface {
+	Target
+	// List returns the target's manifests whose labels match sel.
+	List(ctx context.Context, sel labels.Selector) ([]*ossa.Manifest, error)
+}
+
+// Change is what applying a manifest did.
+type Change string
+
+const (
+	Created   Change = "created"
+	Updated   Change = "updated"
+	Unchanged Change = "unchanged"
+	// Deleted and Protected are for pruned manifests: Protected ones are
+	// policy-tier agents left in place.
+	Deleted   Change = "deleted"
+	Protected Change = "protected"
+)
+
+// Source is a manifest to apply and where it was read from. Pruned entries
+// have no Path.
+type Source struct {
+	Path     string
+	Manifest *ossa.Manifest
+}
+
+// Entry is the outcome for one Source.
+type Entry struct {
+	Source
+	Change Change
+	// Conflicts lists fields changed on the target since the last apply
+	// that this apply overrode.
+	Conflicts []string
+	// write is what is stored; previous is the target's copy before an
+	// update, for rollback.
+	write, previous *ossa.Manifest
+}
+
+// Options configures Apply.
+type Options struct {
+	// DryRun reports what would change without writing.
+	DryRun bool
+	// Prune deletes managed manifests matching Selector that are not among
+	// the sources. It needs a non-empty Selector and a Lister target.
+	Prune    bool
+	Selector labels.Selector
+}
+
+// Result lists the entries in the order they were applied.
+type Result struct {
+	Entries []Entry
+	// RolledBack is set when a write failed and earlier writes were undone.
+	RolledBack bool
+	// RollbackErrors lists writes that could not be undone.
+	RollbackErrors []string
+	// Warnings lists collisions between the sources that did not stop the
+	// apply, such as label keys differing only in case; see
+	// ossa.CheckCollisions.
+	Warnings []string
+}
+
+// Count returns how many entries had the change.
+func (r *Result) Count(c Change) int {
+	n := 0
+	for _, e := range r.Entries {
+		if e.Change == c {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply validates, orders and writes the manifests to target. It returns an
+// error without writing if any manifest is invalid or two share an
+// identity, as ossa.CheckCollisions finds them; a write failure rolls back
+// and returns the partial Result with the error.
+func Apply(ctx context.Context, target Target, sources []Source, opts Options) (*Result, error) {
+	warnings, err := check(sources)
+	if err != nil {
+		return nil, err
+	}
+	var lister Lister
+	if opts.Prune {
+		var ok bool
+		if lister, ok = target.(Lister); !ok {
+			return nil, fmt.Errorf("%s cannot be pruned: it cannot list its manifests", target)
+		}
+		if opts.Selector.Empty() {
+			return nil, fmt.Errorf("prune needs a selector, to limit it to the manifests these sources manage")
+		}
+	}
+	ordered := Order(sources)
+
+	result := &Result{Warnings: warnings}
+	for _, src := range ordered {
+		current, err := target.Get(ctx, src.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", Key(src.Manifest), target, err)
+		}
+		e, err := plan(src, current)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	if lister != nil {
+		pruned, err := prunable(ctx, lister, opts.Selector, sources)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries = append(result.Entries, pruned...)
+	}
+	var problems []string
+	for _, e := range result.Entries {
+		if e.Change == Updated {
+			if r := ossa.ValidateManifest(e.write); !r.Valid {
+				problems = append(problems, fmt.Sprintf("%s: merged with %s: %s", e.Path, Key(e.Manifest), strings.Join(r.Errors, "; ")))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("nothing applied; merging with the target gives invalid manifests:\n  %s", strings.Join(problems, "\n  "))
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for i, e := range result.Entries {
+		var err error
+		switch e.Change {
+		case Unchanged, Protected:
+			continue
+		case Deleted:
+			if err = target.Delete(ctx, e.Manifest); err != nil {
+				err = fmt.Errorf("failed to prune %s from %s: %w", Key(e.Manifest), target, err)
+			}
+		default:
+			if err = target.Put(ctx, e.write); err != nil {
+				err = fmt.Errorf("failed to apply %s to %s: %w", e.Path, target, err)
+			}
+		}
+		if err != nil {
+			result.rollback(ctx, target, i)
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// prunable lists the manifests on the target that match sel, were written
+// by an apply and are not among the sources, workflows first so nothing is
+// left referring to a deleted agent.
+func prunable(ctx context.Context, target Lister, sel labels.Selector, sources []Source) ([]Entry, error) {
+	listed, err := target.List(ctx, sel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", target, err)
+	}
+	keep := map[string]bool{}
+	for _, src := range sources {
+		keep[Key(src.Manifest)] = true
+	}
+	var gone []Source
+	for _, m := range listed {
+		if _, managed := m.Metadata.Annotations[LastAppliedAnnotation]; managed && !keep[Key(m)] {
+			gone = append(gone, Source{Manifest: m})
+		}
+	}
+	gone = Order(gone)
+	entries := make([]Entry, 0, len(gone))
+	for i := len(gone) - 1; i >= 0; i-- {
+		e := Entry{Source: gone[i], Change: Deleted, previous: gone[i].Manifest}
+		if m := gone[i].Manifest; m.Kind == ossa.KindAgent && m.GetAccessTier() == ossa.TierPolicy {
+			e.Change = Protected
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// plan works out what applying src over current, the target's copy or nil,
+// writes.
+func plan(src Source, current *ossa.Manifest) (Entry, error) {
+	desired, err := withLastApplied(src.Manifest)
+	if err != nil {
+		return Entry{}, err
+	}
+	if current == nil {
+		return Entry{Source: src, Change: Created, write: desired}, nil
+	}
+	last, err := LastApplied(current)
+	if err != nil {
+		return Entry{}, fmt.Errorf("%s: %w", Key(current), err)
+	}
+	merged, conflicts, err := ossa.ThreeWayMerge(current, last, desired)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{Source: src, Change: Updated, Conflicts: conflicts, write: merged, previous: current}
+	if same, err := Equal(current, merged); err != nil {
+		return Entry{}, err
+	} else if same {
+		e.Change, e.Conflicts = Unchanged, nil
+	}
+	return e, nil
+}
+
+// withLastApplied returns a copy of m recording itself in
+// LastAppliedAnnotation.
+func withLastApplied(m *ossa.Manifest) (*ossa.Manifest, error) {
+	c := m.DeepCopy()
+	applied := m.DeepCopy()
+	applied.Metadata.Namespace = Namespace(m)
+	if err := c.SetLastApplied(applied); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LastApplied returns the manifest recorded in m's LastAppliedAnnotation,
+// or nil if there is none.
+func LastApplied(m *ossa.Manifest) (*ossa.Manifest, error) {
+	return m.LastApplied()
+}
+
+// rollback undoes the writes of the first n entries, newest first.
+func (r *Result) rollback(ctx context.Context, target Target, n int) {
+	r.RolledBack = true
+	for i := n - 1; i >= 0; i-- {
+		e := r.Entries[i]
+		var err error
+		switch e.Change {
+		case Created:
+			err = target.Delete(ctx, e.Manifest)
+		case Updated, Deleted:
+			err = target.Put(ctx, e.previous)
+		default:
+			continue
+		}
+		if err != nil {
+			r.RollbackErrors = append(r.RollbackErrors, fmt.Sprintf("%s: %v", Key(e.Manifest), err))
+		}
+	}
+}
+
+// check validates the sources and looks for collisions between them,
+// returning the warnings about those that do not stop the apply.
+func check(sources []Source) ([]string, error) {
+	var problems, warnings []string
+	entries := make([]ossa.CatalogEntry, len(sources))
+	for i, src := range sources {
+		entries[i] = ossa.CatalogEntry{Path: src.Path, Manifest: src.Manifest}
+	}
+	for _, c := range ossa.CheckCollisions(entries) {
+		if c.Warning {
+			warnings = append(warnings, c.Message)
+		} else {
+			problems = append(problems, c.Message)
+		}
+	}
+	for _, src := range sources {
+		if result := ossa.ValidateManifest(src.Manifest); !result.Valid {
+			for _, msg := range result.Errors {
+				problems = append(problems, fmt.Sprintf("%s: %s", src.Path, msg))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("nothing applied; fix these first:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return warnings, nil
+}
+
+// kindOrder ranks kinds so what a manifest refers to applies first.
+var kindOrder = map[ossa.Kind]int{ossa.KindAgent: 0, ossa.KindTask: 1, ossa.KindWorkflow: 2}
+
+// Order returns the sources with agents first, then tasks, then workflows,
+// each group sorted by namespace and name.
+func Order(sources []Source) []Source {
+	out := append([]Source(nil), sources...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].Manifest, out[j].Manifest
+		if ra, rb := kindOrder[a.Kind], kindOrder[b.Kind]; ra != rb {
+			return ra < rb
+		}
+		return Key(a) < Key(b)
+	})
+	return out
+}
+
+// Key identifies a manifest at a target: kind/namespace/name.
+func Key(m *ossa.Manifest) string {
+	return fmt.Sprintf("%s/%s/%s", m.Kind, Namespace(m), m.Metadata.Name)
+}
+
+// Namespace returns the manifest's namespace, "default" if unset.
+func Namespace(m *ossa.Manifest) string {
+	if m.Metadata.Namespace == "" {
+		return ossa.DefaultNamespace
+	}
+	return m.Metadata.Namespace
+}
+
+// Equal reports whether two manifests have the same JSON form, treating an
+// unset namespace as "default".
+func Equal(a, b *ossa.Manifest) (bool, error) {
+	ja, err := canonical(a)
+	if err != nil {
+		return false, err
+	}
+	jb, err := canonical(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ja, jb), nil
+}
+
+func canonical(m *ossa.Manifest) ([]byte, error) {
+	c := *m
+	c.Metadata.Namespace = Namespace(m)
+	return c.ToCanonicalJSON()
+}
@@ -0,0 +1,347 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/server"
+)
+
+// memTarget is a Target in memory that can fail a Put.
+type memTarget struct {
+	items  map[string]*ossa.Manifest
+	failOn string
+	puts   []string
+}
+
+func (t *memTarget) String() string { return "memory" }
+
+func (t *memTarget) Get(_ context.Context, m *ossa.Manifest) (*ossa.Manifest, error) {
+	return t.items[Key(m)], nil
+}
+
+func (t *memTarget) Put(_ context.Context, m *ossa.Manifest) error {
+	if m.Metadata.Name == t.failOn {
+		return errors.New("boom")
+	}
+	t.puts = append(t.puts, Key(m))
+	t.items[Key(m)] = m
+	return nil
+}
+
+func (t *memTarget) Delete(_ context.Context, m *ossa.Manifest) error {
+	delete(t.items, Key(m))
+	return nil
+}
+
+func (t *memTarget) List(_ context.Context, sel labels.Selector) ([]*ossa.Manifest, error) {
+	var out []*ossa.Manifest
+	for _, m := range t.items {
+		if sel.Matches(m.Metadata.Labels) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func agent(name, role string) *ossa.Manifest {
+	m := ossa.NewManifest(name, ossa.KindAgent)
+	m.Spec.Role = role
+	return m
+}
+
+func TestApply(t *testing.T) {
+	existing, _ := withLastApplied(agent("reviewer", "Reviews code."))
+	unchanged, _ := withLastApplied(agent("triager", "Triages issues."))
+	target := &memTarget{items: map[string]*ossa.Manifest{Key(existing): existing, Key(unchanged): unchanged}}
+	wf := ossa.NewManifest("release", ossa.KindWorkflow)
+	sources := []Source{
+		{Path: "release.ossa.yaml", Manifest: wf},
+		{Path: "reviewer.ossa.yaml", Manifest: agent("reviewer", "Reviews code carefully.")},
+		{Path: "triager.ossa.yaml", Manifest: agent("triager", "Triages issues.")},
+		{Path: "writer.ossa.yaml", Manifest: agent("writer", "Writes docs.")},
+	}
+
+	dry, err := Apply(context.Background(), target, sources, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(target.puts) != 0 || dry.Count(Created) != 2 || dry.Count(Updated) != 1 || dry.Count(Unchanged) != 1 {
+		t.Fatalf("Unexpected dry run %+v, puts %v", dry.Entries, target.puts)
+	}
+
+	result, err := Apply(context.Background(), target, sources, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Agent/default/reviewer", "Agent/default/writer", "Workflow/default/release"}
+	if strings.Join(target.puts, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected agents before workflows and unchanged skipped, got %v", target.puts)
+	}
+	if result.Entries[len(result.Entries)-1].Manifest != wf {
+		t.Errorf("Expected the workflow last, got %+v", result.Entries)
+	}
+
+	// A failed write undoes the ones before it.
+	target = &memTarget{items: map[string]*ossa.Manifest{Key(existing): existing}, failOn: "writer"}
+	result, err = Apply(context.Background(), target, sources, Options{})
+	if err == nil || !result.RolledBack || len(result.RollbackErrors) != 0 {
+		t.Fatalf("Expected a rollback, got %v %+v", err, result)
+	}
+	if len(target.items) != 1 || target.items[Key(existing)] != existing {
+		t.Errorf("Expected the target restored, got %v", target.items)
+	}
+
+	// Invalid manifests and duplicates stop everything up front.
+	target = &memTarget{items: map[string]*ossa.Manifest{}}
+	robot := agent("robot", "Beeps.")
+	robot.Kind = "Robot"
+	bad := []Source{
+		{Path: "a.yaml", Manifest: agent("writer", "Writes.")},
+		{Path: "b.yaml", Manifest: agent("writer", "Writes.")},
+		{Path: "c.yaml", Manifest: robot},
+	}
+	if _, err := Apply(context.Background(), target, bad, Options{}); err == nil ||
+		!strings.Contains(err.Error(), "b.yaml: Agent/default/writer is also defined in a.yaml") ||
+		!strings.Contains(err.Error(), "c.yaml: ") || len(target.puts) != 0 {
+		t.Errorf("Expected nothing applied, got %v, puts %v", err, target.puts)
+	}
+
+	// Label keys differing only in case are applied with a warning.
+	a, b := agent("a", "Writes."), agent("b", "Writes.")
+	a.Metadata.Labels = map[string]string{"team": "ops"}
+	b.Metadata.Labels = map[string]string{"Team": "ops"}
+	result, err = Apply(context.Background(), target, []Source{{Path: "a.yaml", Manifest: a}, {Path: "b.yaml", Manifest: b}}, Options{})
+	if err != nil || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "label keys Team, team") {
+		t.Errorf("Expected a label warning, got %v %+v", err, result)
+	}
+}
+
+func TestApplyMerges(t *testing.T) {
+	target := &memTarget{items: map[string]*ossa.Manifest{}}
+	apply := func(m *ossa.Manifest) Entry {
+		t.Helper()
+		result, err := Apply(context.Background(), target, []Source{{Path: "pager.ossa.yaml", Manifest: m}}, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Entries[0]
+	}
+	m := agent("pager", "Pages people.")
+	m.Metadata.Description = "On call"
+	m.Metadata.Labels = map[string]string{"team": "ops"}
+	if e := apply(m); e.Change != Created {
+		t.Fatalf("Expected created, got %s", e.Change)
+	}
+	if e := apply(m); e.Change != Unchanged {
+		t.Fatalf("Expected a re-apply unchanged, got %s", e.Change)
+	}
+
+	// Edit the target: a label the manifest never set, and its role.
+	live := *target.items[Key(m)]
+	live.Metadata.Labels = map[string]string{"team": "ops", "tier": "gold"}
+	live.Spec.Role = "Pages everyone."
+	target.items[Key(m)] = &live
+
+	next := agent("pager", "Pages people.")
+	next.Metadata.Labels = map[string]string{"team": "sre"}
+	e := apply(next)
+	got := target.items[Key(m)]
+	if e.Change != Updated || got.Metadata.Description != "" || got.Spec.Role != "Pages people." {
+		t.Fatalf("Expected the description removed and the role restored, got %s %+v", e.Change, got.Metadata)
+	}
+	if got.Metadata.Labels["tier"] != "gold" || got.Metadata.Labels["team"] != "sre" {
+		t.Errorf("Expected the target's label kept, got %v", got.Metadata.Labels)
+	}
+	if strings.Join(e.Conflicts, " ") != "spec.role" {
+		t.Errorf("Expected a conflict on spec.role, got %v", e.Conflicts)
+	}
+	last, err := LastApplied(got)
+	if err != nil || last == nil || last.Metadata.Labels["tier"] != "" {
+		t.Errorf("Expected the desired manifest recorded, got %+v %v", last, err)
+	}
+}
+
+func TestApplyPrune(t *testing.T) {
+	labelled := func(m *ossa.Manifest, app string, managed bool) *ossa.Manifest {
+		m.Metadata.Labels = map[string]string{"app": app}
+		if managed {
+			m, _ = withLastApplied(m)
+		}
+		return m
+	}
+	policy := agent("governor", "Sets policy.")
+	policy.Spec.AccessTier = ossa.TierPolicyShort
+	items := []*ossa.Manifest{
+		labelled(agent("old", "Was removed."), "bot", true),
+		labelled(ossa.NewManifest("old-flow", ossa.KindWorkflow), "bot", true),
+		labelled(policy, "bot", true),
+		labelled(agent("manual", "Made by hand."), "bot", false),
+		labelled(agent("other", "Another fleet."), "other", true),
+	}
+	target := &memTarget{items: map[string]*ossa.Manifest{}}
+	for _, m := range items {
+		target.items[Key(m)] = m
+	}
+	sel, _ := labels.Parse("app=bot")
+	sources := []Source{{Path: "writer.ossa.yaml", Manifest: labelled(agent("writer", "Writes docs."), "bot", false)}}
+
+	if _, err := Apply(context.Background(), target, sources, Options{Prune: true}); err == nil || !strings.Contains(err.Error(), "selector") {
+		t.Errorf("Expected prune without a selector refused, got %v", err)
+	}
+	result, err := Apply(context.Background(), target, sources, Options{Prune: true, Selector: sel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range result.Entries {
+		got = append(got, string(e.Change)+" "+e.Manifest.Metadata.Name)
+	}
+	want := "created writer, deleted old-flow, deleted old, protected governor"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("Expected %s, got %v", want, got)
+	}
+	if len(target.items) != 4 || target.items["Agent/default/old"] != nil || target.items["Agent/default/manual"] == nil ||
+		target.items["Agent/default/governor"] == nil || target.items["Agent/default/other"] == nil {
+		t.Errorf("Unexpected target after prune: %v", target.items)
+	}
+}
+
+func TestRegistryTarget(t *testing.T) {
+	dir := t.TempDir()
+	tenants := filepath.Join(t.TempDir(), "tenants.yaml")
+	os.WriteFile(tenants, []byte("tenants:\n  - namespace: ops\n    tokens: [s3cr3t]\n"), 0o644)
+	ts, err := server.LoadTenants(tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.New(server.Options{Dir: dir, Tenants: ts}))
+	defer srv.Close()
+
+	m := agent("pager", "Pages people.")
+	m.Metadata.Namespace = "ops"
+	reg := &Registry{URL: srv.URL, Token: "s3cr3t"}
+	ctx := context.Background()
+	if got, err := reg.Get(ctx, m); err != nil || got != nil {
+		t.Fatalf("Expected no agent yet, got %v %v", got, err)
+	}
+	for i, want := range []Change{Created, Unchanged} {
+		result, err := Apply(ctx, reg, []Source{{Path: "pager.ossa.yaml", Manifest: m}}, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.Entries[0].Change; got != want {
+			t.Errorf("Apply %d: expected %s, got %s", i, want, got)
+		}
+	}
+	got, err := reg.Get(ctx, m)
+	if err != nil || got.SourceURL() != srv.URL+"/api/agents/ops/pager" || got.Annotation(ossa.DigestAnnotation) == "" || got.VerifyDigest() != nil {
+		t.Errorf("Expected the published copy stamped, got %v %v", got, err)
+	}
+	if m.SourceURL() != "" || m.Annotation(ossa.DigestAnnotation) != "" {
+		t.Errorf("Expected Put to leave its argument alone, got %v", m.Metadata.Annotations)
+	}
+	sel, _ := labels.Parse("team=ops")
+	m.Metadata.Labels = map[string]string{"team": "ops"}
+	if _, err := Apply(ctx, reg, []Source{{Path: "pager.ossa.yaml", Manifest: m}}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if listed, err := reg.List(ctx, sel); err != nil || len(listed) != 1 || listed[0].Metadata.Name != "pager" {
+		t.Errorf("Expected the agent listed, got %v %v", listed, err)
+	}
+	if err := reg.Delete(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reg.Get(ctx, m); got != nil {
+		t.Errorf("Expected the agent deleted, got %+v", got)
+	}
+
+	reg.Token = "wrong"
+	if err := reg.Put(ctx, m); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an auth error, got %v", err)
+	}
+}
+
+func TestRegistryChannels(t *testing.T) {
+	tenants := filepath.Join(t.TempDir(), "tenants.yaml")
+	os.WriteFile(tenants, []byte("tenants:\n  - namespace: ops\n    tokens: [s3cr3t]\n"), 0o644)
+	ts, err := server.LoadTenants(tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.New(server.Options{Dir: t.TempDir(), Tenants: ts}))
+	defer srv.Close()
+	ctx := context.Background()
+	release := func(version, channel string) error {
+		m := agent("pager", "Pages people, "+version+".")
+		m.Metadata.Namespace, m.Metadata.Version = "ops", version
+		return (&Registry{URL: srv.URL, Token: "s3cr3t", Channel: channel}).Put(ctx, m)
+	}
+	if err := release("1.0.0", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := release("1.1.0", "beta"); err != nil {
+		t.Fatal(err)
+	}
+	if err := release("next", "beta"); err == nil || !strings.Contains(err.Error(), "semantic") {
+		t.Errorf("Expected a channel release to need a semantic version, got %v", err)
+	}
+
+	reg := &Registry{URL: srv.URL, Token: "s3cr3t"}
+	pull := func(channel string) *ossa.Manifest {
+		t.Helper()
+		m, err := reg.Pull(ctx, "ops", "pager", channel)
+		if err != nil || m == nil {
+			t.Fatalf("Failed to pull %q: %v", channel, err)
+		}
+		return m
+	}
+	if m := pull(""); m.Metadata.Version != "1.0.0" {
+		t.Errorf("Expected beta to leave stable alone, got %s", m.Metadata.Version)
+	}
+	if m := pull("beta"); m.Metadata.Version != "1.1.0" || m.Metadata.Annotations[server.ChannelAnnotation] != "beta" {
+		t.Errorf("Unexpected beta release %+v", m.Metadata)
+	}
+	channels, err := reg.Channels(ctx, "ops", "pager")
+	if err != nil || len(channels) != 2 || channels[0] != (Channel{"beta", "1.1.0"}) || channels[1] != (Channel{"stable", "1.0.0"}) {
+		t.Errorf("Unexpected channels %v %v", channels, err)
+	}
+
+	if v, err := reg.Promote(ctx, "ops", "pager", "stable", "", "beta"); err != nil || v != "1.1.0" {
+		t.Fatalf("Failed to promote: %q %v", v, err)
+	}
+	if m := pull(""); m.Metadata.Version != "1.1.0" || m.Metadata.Annotations[server.ChannelAnnotation] != "stable" {
+		t.Errorf("Expected the promoted release in the catalog, got %+v", m.Metadata)
+	}
+	if _, err := reg.Promote(ctx, "ops", "pager", "beta", "9.9.9", ""); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected promoting an unknown release to fail, got %v", err)
+	}
+}
+
+func TestResourceRoundTrip(t *testing.T) {
+	m := agent("pager", "Pages people.")
+	m.Metadata.Namespace, m.Metadata.Version, m.Metadata.Description = "ops", "1.2.0", "On call"
+	m.Metadata.Labels = map[string]string{"team": "ops"}
+	m.Metadata.Annotations = map[string]string{"owner": "sre"}
+	data, err := ToResource(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"apiVersion":"agents.ossa.dev/v1beta1","kind":"Agent"`) {
+		t.Errorf("Unexpected resource %s", data)
+	}
+	back, err := FromResource(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := Equal(m, back); !same {
+		t.Errorf("Expected a round trip, got %+v", back)
+	}
+}
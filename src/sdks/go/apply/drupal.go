@@ -0,0 +1,201 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Drupal stores manifests as content entities through JSON:API: one entity
+// per manifest, titled namespace/name, with the manifest's JSON in a text
+// field.
+type Drupal struct {
+	// URL is the site's base URL.
+	URL string
+	// Type is the JSON:API resource type, such as "node--ossa_agent"
+	// (the default).
+	Type string
+	// Field holds the manifest JSON; it defaults to "field_ossa_manifest".
+	Field string
+	// Token is sent as an OAuth bearer token.
+	Token string
+	// Client defaults to one with a 30 second timeout.
+	Client *http.Client
+}
+
+const jsonAPIType = "application/vnd.api+json"
+
+func (d *Drupal) String() string { return "drupal " + d.URL }
+
+func (d *Drupal) resourceType() string {
+	if d.Type == "" {
+		return "node--ossa_agent"
+	}
+	return d.Type
+}
+
+func (d *Drupal) field() string {
+	if d.Field == "" {
+		return "field_ossa_manifest"
+	}
+	return d.Field
+}
+
+// collection is /jsonapi/{entity}/{bundle} for the resource type.
+func (d *Drupal) collection() string {
+	entity, bundle, _ := strings.Cut(d.resourceType(), "--")
+	return strings.TrimSuffix(d.URL, "/") + "/jsonapi/" + entity + "/" + bundle
+}
+
+func drupalTitle(m *ossa.Manifest) string {
+	return Namespace(m) + "/" + m.Metadata.Name
+}
+
+// entityPage is a JSON:API collection response.
+type entityPage struct {
+	Data []struct {
+		ID         string                 `json:"id"`
+		Attributes map[string]interface{} `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Next struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"links"`
+}
+
+// find returns the entity ID and manifest stored for m, or "" if none.
+func (d *Drupal) find(ctx context.Context, m *ossa.Manifest) (string, *ossa.Manifest, error) {
+	q := url.Values{"filter[title]": {drupalTitle(m)}}
+	var page entityPage
+	if err := d.do(ctx, http.MethodGet, d.collection()+"?"+q.Encode(), nil, &page); err != nil {
+		return "", nil, err
+	}
+	if len(page.Data) == 0 {
+		return "", nil, nil
+	}
+	item := page.Data[0]
+	stored, err := d.stored(item.Attributes)
+	if err != nil {
+		return "", nil, fmt.Errorf("entity %s: %w", item.ID, err)
+	}
+	return item.ID, stored, nil
+}
+
+// stored parses the manifest in an entity's attributes.
+func (d *Drupal) stored(attributes map[string]interface{}) (*ossa.Manifest, error) {
+	raw, _ := attributes[d.field()].(string)
+	if v, ok := attributes[d.field()].(map[string]interface{}); ok {
+		raw, _ = v["value"].(string) // formatted text fields
+	}
+	return ossa.ParseManifest([]byte(raw), ".json")
+}
+
+// List pages through the collection; JSON:API cannot filter on labels
+// inside the manifest field, so the selector applies here.
+func (d *Drupal) List(ctx context.Context, sel labels.Selector) ([]*ossa.Manifest, error) {
+	var out []*ossa.Manifest
+	for next := d.collection(); next != ""; {
+		var page entityPage
+		if err := d.do(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Data {
+			m, err := d.stored(item.Attributes)
+			if err != nil {
+				return nil, fmt.Errorf("entity %s: %w", item.ID, err)
+			}
+			if sel.Matches(m.Metadata.Labels) {
+				out = append(out, m)
+			}
+		}
+		next = page.Links.Next.Href
+	}
+	return out, nil
+}
+
+func (d *Drupal) Get(ctx context.Context, m *ossa.Manifest) (*ossa.Manifest, error) {
+	_, stored, err := d.find(ctx, m)
+	return stored, err
+}
+
+func (d *Drupal) Put(ctx context.Context, m *ossa.Manifest) error {
+	id, _, err := d.find(ctx, m)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	resource := map[string]interface{}{
+		"type":       d.resourceType(),
+		"attributes": map[string]interface{}{"title": drupalTitle(m), d.field(): string(data)},
+	}
+	if id == "" {
+		return d.do(ctx, http.MethodPost, d.collection(), map[string]interface{}{"data": resource}, nil)
+	}
+	resource["id"] = id
+	return d.do(ctx, http.MethodPatch, d.collection()+"/"+id, map[string]interface{}{"data": resource}, nil)
+}
+
+func (d *Drupal) Delete(ctx context.Context, m *ossa.Manifest) error {
+	id, _, err := d.find(ctx, m)
+	if err != nil || id == "" {
+		return err
+	}
+	return d.do(ctx, http.MethodDelete, d.collection()+"/"+id, nil, nil)
+}
+
+func (d *Drupal) do(ctx context.Context, method, u string, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", jsonAPIType)
+	if body != nil {
+		req.Header.Set("Content-Type", jsonAPIType)
+	}
+	if d.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	}
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var doc struct {
+			Errors []struct {
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&doc) == nil && len(doc.Errors) > 0 {
+			return fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, doc.Errors[0].Detail)
+		}
+		return fmt.Errorf("%s %s: %s", method, u, resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
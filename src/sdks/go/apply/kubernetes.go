@@ -0,0 +1,208 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Kubernetes custom resources holding manifests.
+const (
+	// KubernetesGroup is the API group; each kind is a resource in it, such
+	// as agents.agents.ossa.dev.
+	KubernetesGroup = "agents.ossa.dev"
+	// KubernetesVersion is the served version of the resources.
+	KubernetesVersion = "v1beta1"
+	// APIVersionAnnotation keeps the manifest's apiVersion on the resource.
+	APIVersionAnnotation = "ossa.dev/api-version"
+	// VersionAnnotation and DescriptionAnnotation keep the manifest's
+	// metadata.version and metadata.description.
+	VersionAnnotation     = "ossa.dev/version"
+	DescriptionAnnotation = "ossa.dev/description"
+)
+
+// kubectlAnnotation is added by kubectl apply and not part of a manifest.
+const kubectlAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Kubernetes stores manifests as custom resources through kubectl, so the
+// usual kubeconfig, contexts and credentials apply. A manifest becomes a
+// resource of its kind in KubernetesGroup, with its metadata and its spec
+// as the resource spec.
+type Kubernetes struct {
+	// Kubectl is the kubectl executable; empty uses "kubectl" from PATH.
+	Kubectl string
+	// Context selects a kubeconfig context; empty uses the current one.
+	Context string
+}
+
+func (k *Kubernetes) String() string {
+	if k.Context != "" {
+		return "kubernetes context " + k.Context
+	}
+	return "kubernetes"
+}
+
+// resource is the custom resource form of a manifest.
+type resource struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       ossa.Kind        `json:"kind"`
+	Metadata   resourceMetadata `json:"metadata"`
+	Spec       json.RawMessage  `json:"spec"`
+}
+
+type resourceMetadata struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ToResource returns the custom resource for a manifest. The apiVersion,
+// metadata.version and metadata.description go in ossa.dev/ annotations.
+func ToResource(m *ossa.Manifest) ([]byte, error) {
+	spec, err := json.Marshal(m.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	annotations := map[string]string{APIVersionAnnotation: m.APIVersion}
+	for k, v := range m.Metadata.Annotations {
+		annotations[k] = v
+	}
+	if m.Metadata.Version != "" {
+		annotations[VersionAnnotation] = m.Metadata.Version
+	}
+	if m.Metadata.Description != "" {
+		annotations[DescriptionAnnotation] = m.Metadata.Description
+	}
+	return json.Marshal(resource{
+		APIVersion: KubernetesGroup + "/" + KubernetesVersion,
+		Kind:       m.Kind,
+		Metadata: resourceMetadata{
+			Name:        m.Metadata.Name,
+			Namespace:   Namespace(m),
+			Labels:      m.Metadata.Labels,
+			Annotations: annotations,
+		},
+		Spec: spec,
+	})
+}
+
+// FromResource is the inverse of ToResource.
+func FromResource(data []byte) (*ossa.Manifest, error) {
+	var r resource
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode resource: %w", err)
+	}
+	m := &ossa.Manifest{Kind: r.Kind, Metadata: ossa.Metadata{
+		Name:      r.Metadata.Name,
+		Namespace: r.Metadata.Namespace,
+		Labels:    r.Metadata.Labels,
+	}}
+	for k, v := range r.Metadata.Annotations {
+		switch k {
+		case APIVersionAnnotation:
+			m.APIVersion = v
+		case VersionAnnotation:
+			m.Metadata.Version = v
+		case DescriptionAnnotation:
+			m.Metadata.Description = v
+		case kubectlAnnotation:
+		default:
+			if m.Metadata.Annotations == nil {
+				m.Metadata.Annotations = map[string]string{}
+			}
+			m.Metadata.Annotations[k] = v
+		}
+	}
+	if len(r.Spec) > 0 {
+		if err := json.Unmarshal(r.Spec, &m.Spec); err != nil {
+			return nil, fmt.Errorf("failed to decode resource spec: %w", err)
+		}
+	}
+	return m, nil
+}
+
+func resourceName(kind ossa.Kind) string {
+	return strings.ToLower(string(kind)) + "s." + KubernetesGroup
+}
+
+func (k *Kubernetes) Get(ctx context.Context, m *ossa.Manifest) (*ossa.Manifest, error) {
+	out, err := k.kubectl(ctx, nil, "get", resourceName(m.Kind), m.Metadata.Name, "-n", Namespace(m), "-o", "json", "--ignore-not-found")
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil, err
+	}
+	return FromResource(out)
+}
+
+func (k *Kubernetes) Put(ctx context.Context, m *ossa.Manifest) error {
+	data, err := ToResource(m)
+	if err != nil {
+		return err
+	}
+	_, err = k.kubectl(ctx, data, "apply", "-f", "-")
+	return err
+}
+
+func (k *Kubernetes) Delete(ctx context.Context, m *ossa.Manifest) error {
+	_, err := k.kubectl(ctx, nil, "delete", resourceName(m.Kind), m.Metadata.Name, "-n", Namespace(m), "--ignore-not-found")
+	return err
+}
+
+// List gets the resources of every kind in all namespaces the context can
+// read, filtered by kubectl with the selector.
+func (k *Kubernetes) List(ctx context.Context, sel labels.Selector) ([]*ossa.Manifest, error) {
+	kinds := []string{resourceName(ossa.KindAgent), resourceName(ossa.KindTask), resourceName(ossa.KindWorkflow)}
+	args := []string{"get", strings.Join(kinds, ","), "--all-namespaces", "-o", "json"}
+	if !sel.Empty() {
+		args = append(args, "-l", sel.String())
+	}
+	out, err := k.kubectl(ctx, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode kubectl output: %w", err)
+	}
+	manifests := make([]*ossa.Manifest, 0, len(list.Items))
+	for _, item := range list.Items {
+		m, err := FromResource(item)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+func (k *Kubernetes) kubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	bin := k.Kubectl
+	if bin == "" {
+		bin = "kubectl"
+	}
+	verb := args[0]
+	if k.Context != "" {
+		args = append([]string{"--context", k.Context}, args...)
+	}
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("kubectl %s: %s", verb, msg)
+		}
+		return nil, fmt.Errorf("kubectl %s: %w", verb, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,233 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Registry is an ossa serve registry, written through
+// PUT and DELETE /api/agents/{namespace}/{name}.
+type Registry struct {
+	URL string
+	// Token is sent as a bearer token: a tenant token, API key or JWT with
+	// the publish role.
+	Token string
+	// Channel, if set, makes Get read and Put publish the release on a
+	// release channel, such as "beta", rather than the catalog's manifest.
+	// Publishing to a channel needs a semantic metadata.version.
+	Channel string
+	// Client defaults to one with a 30 second timeout.
+	Client *http.Client
+}
+
+func (r *Registry) String() string {
+	if r.Channel != "" {
+		return "registry " + r.URL + " channel " + r.Channel
+	}
+	return "registry " + r.URL
+}
+
+func (r *Registry) Get(ctx context.Context, m *ossa.Manifest) (*ossa.Manifest, error) {
+	return r.Pull(ctx, Namespace(m), m.Metadata.Name, r.Channel)
+}
+
+// Pull returns namespace/name as the catalog holds it, or the release on
+// channel if that is set; nil if there is none.
+func (r *Registry) Pull(ctx context.Context, namespace, name, channel string) (*ossa.Manifest, error) {
+	var agent struct {
+		Manifest *ossa.Manifest `json:"manifest"`
+	}
+	u := r.agentURL(namespace, name)
+	if channel != "" {
+		u += "/channels/" + url.PathEscape(channel)
+	}
+	resp, err := r.send(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&agent); err != nil {
+			return nil, fmt.Errorf("failed to decode registry response: %w", err)
+		}
+		return agent.Manifest, nil
+	}
+	return nil, registryError(resp)
+}
+
+// Put publishes a copy of m stamped with its ossa.SourceURLAnnotation, the
+// agent's URL in the registry, and ossa.DigestAnnotation.
+func (r *Registry) Put(ctx context.Context, m *ossa.Manifest) error {
+	u := r.agentURL(Namespace(m), m.Metadata.Name)
+	m = m.DeepCopy()
+	m.SetSourceURL(u)
+	if err := m.StampDigest(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if r.Channel != "" {
+		u += "?" + url.Values{"channel": {r.Channel}}.Encode()
+	}
+	resp, err := r.send(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return registryError(resp)
+	}
+	return nil
+}
+
+func (r *Registry) Delete(ctx context.Context, m *ossa.Manifest) error {
+	resp, err := r.send(ctx, http.MethodDelete, r.agentURL(Namespace(m), m.Metadata.Name), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return registryError(resp)
+	}
+	return nil
+}
+
+// List reads the catalog from GET /api/agents, then each manifest.
+func (r *Registry) List(ctx context.Context, sel labels.Selector) ([]*ossa.Manifest, error) {
+	u := strings.TrimSuffix(r.URL, "/") + "/api/agents"
+	if !sel.Empty() {
+		u += "?" + url.Values{"selector": {sel.String()}}.Encode()
+	}
+	resp, err := r.send(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, registryError(resp)
+	}
+	var summaries []struct {
+		Name      string    `json:"name"`
+		Namespace string    `json:"namespace"`
+		Kind      ossa.Kind `json:"kind"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	var out []*ossa.Manifest
+	for _, sum := range summaries {
+		m, err := r.Pull(ctx, sum.Namespace, sum.Name, "")
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// Channel is a release channel and the version it points at.
+type Channel struct {
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
+// Channels lists namespace/name's release channels.
+func (r *Registry) Channels(ctx context.Context, namespace, name string) ([]Channel, error) {
+	resp, err := r.send(ctx, http.MethodGet, r.agentURL(namespace, name)+"/channels", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, registryError(resp)
+	}
+	var channels []Channel
+	if err := json.NewDecoder(resp.Body).Decode(&channels); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return channels, nil
+}
+
+// Promote points channel at a release: version, or the one channel from
+// points at. Promoting to stable makes the release the catalog's manifest.
+// It returns the version promoted.
+func (r *Registry) Promote(ctx context.Context, namespace, name, channel, version, from string) (string, error) {
+	body, err := json.Marshal(struct {
+		Version string `json:"version,omitempty"`
+		From    string `json:"from,omitempty"`
+	}{version, from})
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.send(ctx, http.MethodPut, r.agentURL(namespace, name)+"/channels/"+url.PathEscape(channel), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", registryError(resp)
+	}
+	var promoted Channel
+	if err := json.NewDecoder(resp.Body).Decode(&promoted); err != nil {
+		return "", fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	return promoted.Version, nil
+}
+
+func (r *Registry) agentURL(namespace, name string) string {
+	return strings.TrimSuffix(r.URL, "/") + "/api/agents/" + url.PathEscape(namespace) + "/" + url.PathEscape(name)
+}
+
+func (r *Registry) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return client.Do(req)
+}
+
+// registryError reads the error or validation errors from a failed
+// response.
+func registryError(resp *http.Response) error {
+	var body struct {
+		Error  string   `json:"error"`
+		Errors []string `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &body) == nil {
+		if body.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, body.Error)
+		}
+		if len(body.Errors) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, strings.Join(body.Errors, "; "))
+		}
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
@@ -0,0 +1,236 @@
+// Package bundle reads and writes .ossabundle archives: a workflow with
+// the agents its steps resolved to, its lock file, signatures and assets,
+// in one gzipped tar, so a complete agent system can be carried to and
+// run on a host without registry access.
+//
+//	bundle.json                               index: every file's kind and sha256
+//	workflow.ossa.yaml                        the workflow, as written
+//	ossa.lock.yaml                            the refs it pins
+//	agents/default/support-agent/1.3.1.ossa.yaml  a resolved versioned ref
+//	agents/notes.ossa.yaml                    a step ref by path, at that path
+//	assets/...                                anything else the system needs
+//	signatures/<key id>.json                  ed25519 signatures of the index
+//
+// The index lists each file with its sha256, and signatures sign the
+// index, so verifying a bundle checks every file it holds. Extracted, a
+// bundle's agents resolve the workflow's versioned refs in place of the
+// registry (see DirSource).
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+)
+
+// Ext is the file extension of bundles.
+const Ext = ".ossabundle"
+
+// Format identifies the bundle layout in the index.
+const Format = "ossabundle/v1"
+
+// IndexFile is the path of the index in a bundle.
+const IndexFile = "bundle.json"
+
+// Entry kinds.
+const (
+	KindWorkflow = "workflow"
+	KindAgent    = "agent"
+	KindLock     = "lock"
+	KindAsset    = "asset"
+)
+
+// Index is bundle.json: what a bundle holds.
+type Index struct {
+	Format  string    `json:"format"`
+	Created time.Time `json:"created"`
+	// Workflow is the path of the workflow the bundle distributes.
+	Workflow string  `json:"workflow"`
+	Entries  []Entry `json:"entries"`
+}
+
+// Entry is one file of a bundle.
+type Entry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// Namespace, Name and Version identify an agent resolved from a
+	// versioned ref.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// Signature is an ed25519 signature of a bundle's index, by the key whose
+// ID it carries.
+type Signature struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// KeyID names a public key: the first 16 hex digits of its sha256.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// signedBytes is what signatures sign: the index's canonical JSON.
+func (x *Index) signedBytes() ([]byte, error) {
+	data, err := json.Marshal(x)
+	if err != nil {
+		return nil, err
+	}
+	return ossa.CanonicalJSON(data)
+}
+
+// AgentPath is where a bundle keeps a resolved agent.
+func AgentPath(namespace, name, version string) string {
+	return path.Join("agents", namespace, name, version+".ossa.yaml")
+}
+
+// Writer writes a bundle. Add files, then Close to write the index and
+// signatures.
+type Writer struct {
+	gz    *gzip.Writer
+	tw    *tar.Writer
+	index Index
+	keys  []ed25519.PrivateKey
+	paths map[string]bool
+	now   time.Time
+}
+
+// NewWriter starts a bundle on w.
+func NewWriter(w io.Writer) *Writer {
+	gz := gzip.NewWriter(w)
+	now := time.Now().UTC().Truncate(time.Second)
+	return &Writer{
+		gz:    gz,
+		tw:    tar.NewWriter(gz),
+		index: Index{Format: Format, Created: now},
+		paths: map[string]bool{},
+		now:   now,
+	}
+}
+
+// Sign has Close sign the index with key.
+func (w *Writer) Sign(key ed25519.PrivateKey) {
+	w.keys = append(w.keys, key)
+}
+
+// Add writes a file of kind at path, a slash-separated path relative to
+// the bundle root.
+func (w *Writer) Add(p, kind string, data []byte) error {
+	return w.add(Entry{Path: p, Kind: kind}, data)
+}
+
+// AddWorkflow writes the workflow document the bundle distributes.
+func (w *Writer) AddWorkflow(p string, data []byte) error {
+	if w.index.Workflow != "" {
+		return fmt.Errorf("bundle already has workflow %s", w.index.Workflow)
+	}
+	w.index.Workflow = p
+	return w.Add(p, KindWorkflow, data)
+}
+
+// AddResolved writes the release a versioned ref resolved to, at
+// AgentPath. Refs resolving to the same release share it.
+func (w *Writer) AddResolved(res *resolve.Resolution) error {
+	p := AgentPath(res.Ref.Namespace, res.Ref.Name, res.Version)
+	if w.paths[p] {
+		return nil
+	}
+	data, err := res.Manifest.ToYAML()
+	if err != nil {
+		return err
+	}
+	return w.add(Entry{Path: p, Kind: KindAgent, Namespace: res.Ref.Namespace, Name: res.Ref.Name, Version: res.Version}, []byte(data))
+}
+
+func (w *Writer) add(e Entry, data []byte) error {
+	p, err := cleanPath(e.Path)
+	if err != nil {
+		return err
+	}
+	if p == IndexFile || strings.HasPrefix(p, "signatures/") {
+		return fmt.Errorf("%s is reserved for the bundle's index and signatures", p)
+	}
+	if w.paths[p] {
+		return fmt.Errorf("bundle already has %s", p)
+	}
+	w.paths[p] = true
+	sum := sha256.Sum256(data)
+	e.Path, e.SHA256, e.Size = p, hex.EncodeToString(sum[:]), int64(len(data))
+	w.index.Entries = append(w.index.Entries, e)
+	return w.write(p, data)
+}
+
+func (w *Writer) write(p string, data []byte) error {
+	hdr := &tar.Header{Name: p, Mode: 0o644, Size: int64(len(data)), ModTime: w.now, Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// Close writes the index and its signatures and finishes the archive.
+func (w *Writer) Close() error {
+	if w.index.Workflow == "" {
+		return fmt.Errorf("bundle has no workflow")
+	}
+	sort.Slice(w.index.Entries, func(i, j int) bool { return w.index.Entries[i].Path < w.index.Entries[j].Path })
+	data, err := json.MarshalIndent(&w.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.write(IndexFile, append(data, '\n')); err != nil {
+		return err
+	}
+	signed, err := w.index.signedBytes()
+	if err != nil {
+		return err
+	}
+	for _, key := range w.keys {
+		pub := key.Public().(ed25519.PublicKey)
+		sig := Signature{
+			KeyID:     KeyID(pub),
+			PublicKey: base64.StdEncoding.EncodeToString(pub),
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, signed)),
+		}
+		data, err := json.MarshalIndent(sig, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := w.write("signatures/"+sig.KeyID+".json", append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// cleanPath checks p is a relative slash-separated path inside the bundle.
+func cleanPath(p string) (string, error) {
+	c := path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" || c == "." || path.IsAbs(c) || c == ".." || strings.HasPrefix(c, "../") {
+		return "", fmt.Errorf("invalid bundle path %q: must be relative and inside the bundle", p)
+	}
+	return c, nil
+}
@@ -0,0 +1,310 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/intoto"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+)
+
+const workflow = `apiVersion: ossa/v0.3.3
+kind: Workflow
+metadata:
+  name: support
+spec:
+  steps:
+    - id: triage
+      ref: support-agent@^1.2
+    - id: local
+      ref: ./agents/notes.ossa.yaml
+    - id: again
+      ref: support-agent@^1.2
+`
+
+func agent(name, version string) string {
+	return fmt.Sprintf(`apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: %s
+  version: %s
+spec:
+  role: Answers support questions
+`, name, version)
+}
+
+// releases is a resolve.Source over manifests held in memory.
+type releases map[string]string
+
+func (r releases) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	var out []string
+	for k := range r {
+		if v := strings.TrimPrefix(k, namespace+"/"+name+"@"); v != k {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (r releases) Fetch(ctx context.Context, namespace, name, version string) (*ossa.Manifest, error) {
+	data, ok := r[namespace+"/"+name+"@"+version]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return ossa.ParseManifest([]byte(data), ".yaml")
+}
+
+func writeWorkflow(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "agents"), 0o755)
+	os.WriteFile(filepath.Join(dir, "agents", "notes.ossa.yaml"), []byte(agent("notes", "0.1.0")), 0o644)
+	path := filepath.Join(dir, "support.ossa.yaml")
+	os.WriteFile(path, []byte(workflow), 0o644)
+	return path
+}
+
+func create(t *testing.T, keys ...ed25519.PrivateKey) []byte {
+	src := releases{
+		"default/support-agent@1.2.0": agent("support-agent", "1.2.0"),
+		"default/support-agent@1.3.1": agent("support-agent", "1.3.1"),
+		"default/support-agent@2.0.0": agent("support-agent", "2.0.0"),
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, k := range keys {
+		w.Sign(k)
+	}
+	r := &resolve.Resolver{Source: src, Lock: &resolve.Lock{}}
+	if err := w.AddWorkflowFile(context.Background(), writeWorkflow(t), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add("assets/faq.md", KindAsset, []byte("# FAQ\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBundle(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	r, err := NewReader(bytes.NewReader(create(t, priv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range r.Index.Entries {
+		got = append(got, e.Kind+":"+e.Path)
+	}
+	want := "agent:agents/default/support-agent/1.3.1.ossa.yaml agent:agents/notes.ossa.yaml asset:assets/faq.md lock:ossa.lock.yaml workflow:support.ossa.yaml"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Unexpected entries %v", got)
+	}
+	if data, err := r.Workflow(); err != nil || string(data) != workflow {
+		t.Errorf("Unexpected workflow %q %v", data, err)
+	}
+	if lock, _ := r.File(resolve.LockFile); !bytes.Contains(lock, []byte("version: 1.3.1")) {
+		t.Errorf("Expected the lock to pin 1.3.1, got %s", lock)
+	}
+
+	signers, err := r.Verify(pub)
+	if err != nil || len(signers) != 1 || signers[0] != KeyID(pub) {
+		t.Errorf("Expected a signature by %s, got %v %v", KeyID(pub), signers, err)
+	}
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := r.Verify(other); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned for an untrusted key, got %v", err)
+	}
+
+	// Resolving from the bundle picks the bundled release, offline.
+	res := &resolve.Resolver{Source: r.Source()}
+	ref, _, _ := resolve.ParseRef("support-agent@^1.2")
+	if got, err := res.Resolve(context.Background(), ref); err != nil || got.Version != "1.3.1" {
+		t.Errorf("Expected 1.3.1 from the bundle, got %+v %v", got, err)
+	}
+
+	dir := t.TempDir()
+	if err := r.Extract(dir); err != nil {
+		t.Fatal(err)
+	}
+	src, ok, err := DirSource(dir)
+	if err != nil || !ok {
+		t.Fatalf("Expected an extracted bundle, got %v %v", ok, err)
+	}
+	if m, err := src.Fetch(context.Background(), "default", "support-agent", "1.3.1"); err != nil || m.Metadata.Version != "1.3.1" {
+		t.Errorf("Unexpected extracted release %v", err)
+	}
+	if _, ok, _ := DirSource(t.TempDir()); ok {
+		t.Error("Expected no bundle in an empty directory")
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	data := create(t, priv)
+
+	// Repack with one file changed but the index kept.
+	r, _ := NewReader(bytes.NewReader(data))
+	r.files["assets/faq.md"] = []byte("# changed\n")
+	if _, err := r.Verify(); err == nil || !strings.Contains(err.Error(), "assets/faq.md: sha256") {
+		t.Errorf("Expected a checksum failure, got %v", err)
+	}
+
+	// An index edited to match is caught by its signature.
+	r, _ = NewReader(bytes.NewReader(data))
+	r.Index.Entries[0].Version = "9.9.9"
+	if _, err := r.Verify(); err == nil || !strings.Contains(err.Error(), "does not verify") {
+		t.Errorf("Expected a signature failure, got %v", err)
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	// A stand-in for crypt: the header, then the bundle reversed.
+	plain := create(t)
+	enc := []byte(ossa.EncryptedHeader)
+	for i := len(plain) - 1; i >= 0; i-- {
+		enc = append(enc, plain[i])
+	}
+	if _, err := NewReader(bytes.NewReader(enc)); !errors.Is(err, ossa.ErrEncrypted) {
+		t.Fatalf("Expected ErrEncrypted, got %v", err)
+	}
+	ossa.SetDecrypter(func(data []byte) ([]byte, error) {
+		data = data[len(ossa.EncryptedHeader):]
+		out := make([]byte, len(data))
+		for i, b := range data {
+			out[len(data)-1-i] = b
+		}
+		return out, nil
+	})
+	defer ossa.SetDecrypter(nil)
+	r, err := NewReader(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Workflow(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReaderRejects(t *testing.T) {
+	pack := func(hdr *tar.Header, body string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		hdr.Size = int64(len(body))
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(body))
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	for name, data := range map[string][]byte{
+		"escape":   pack(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644}, "x"),
+		"absolute": pack(&tar.Header{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0o644}, "x"),
+		"symlink":  pack(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}, ""),
+		"no index": pack(&tar.Header{Name: "a.yaml", Typeflag: tar.TypeReg, Mode: 0o644}, "x"),
+		"not gzip": []byte("plain"),
+	} {
+		if _, err := NewReader(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	w := NewWriter(&bytes.Buffer{})
+	if err := w.Add("../x", KindAsset, nil); err == nil {
+		t.Error("Expected a path outside the bundle rejected")
+	}
+	if err := w.Add(IndexFile, KindAsset, nil); err == nil {
+		t.Error("Expected the index path reserved")
+	}
+	if err := w.Close(); err == nil {
+		t.Error("Expected a bundle without a workflow rejected")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	got, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !got.Equal(priv) {
+		t.Errorf("Failed to parse a PKCS #8 key: %v", err)
+	}
+	der, _ = x509.MarshalPKIXPublicKey(pub)
+	gotPub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || !gotPub.Equal(pub) {
+		t.Errorf("Failed to parse a PKIX key: %v", err)
+	}
+	if _, err := ParsePublicKey([]byte("nope")); err == nil {
+		t.Error("Expected an invalid key rejected")
+	}
+}
+
+func TestVerifyLayout(t *testing.T) {
+	ownerPub, owner, _ := ed25519.GenerateKey(rand.Reader)
+	alicePub, alice, _ := ed25519.GenerateKey(rand.Reader)
+	a := intoto.NewKey(alicePub)
+	layout, _ := intoto.Sign(&intoto.Layout{
+		Type:    "layout",
+		Expires: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		Keys:    map[string]intoto.Key{a.ID(): a},
+		Steps:   []intoto.Step{{Type: "step", Name: "sign", PubKeys: []string{a.ID()}, Threshold: 1}},
+		Inspect: []interface{}{},
+	}, owner)
+
+	pack := func(products map[string][]byte) *Reader {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.AddWorkflow("support.ossa.yaml", []byte(workflow))
+		w.Add("agents/notes.ossa.yaml", KindAgent, []byte(agent("notes", "0.1.0")))
+		link, _ := intoto.Sign(intoto.NewLink("sign", nil, products), alice)
+		ldata, _ := json.Marshal(link)
+		data, _ := json.Marshal(layout)
+		w.Add(LayoutFile, KindInToto, data)
+		w.Add(InTotoDir+"/"+intoto.LinkName("sign", a.ID()), KindInToto, ldata)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := pack(map[string][]byte{
+		"support.ossa.yaml":      []byte(workflow),
+		"agents/notes.ossa.yaml": []byte(agent("notes", "0.1.0")),
+	})
+	if !r.HasLayout() {
+		t.Fatal("Expected a layout")
+	}
+	custody, err := r.VerifyLayout([]ed25519.PublicKey{ownerPub}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(custody) != 2 || len(custody[0].Steps) != 1 || custody[0].Steps[0].KeyID != a.ID() {
+		t.Errorf("Unexpected custody %+v", custody)
+	}
+
+	// An agent the signer did not attest breaks the chain.
+	r = pack(map[string][]byte{"support.ossa.yaml": []byte(workflow)})
+	if _, err := r.VerifyLayout([]ed25519.PublicKey{ownerPub}, time.Now()); err == nil || !strings.Contains(err.Error(), "agents/notes.ossa.yaml: not produced") {
+		t.Errorf("Expected an unattested agent reported, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package bundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/resolve"
+)
+
+// AddWorkflowFile writes the workflow at p with everything its steps
+// need: the release each versioned ref resolves to through r, each ref by
+// path, which must lie in the workflow's directory, and the lock file r
+// leaves, pinning the bundled releases. A nil r bundles workflows without
+// versioned refs only.
+func (w *Writer) AddWorkflowFile(ctx context.Context, p string, r *resolve.Resolver) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if err := w.AddWorkflow(filepath.Base(p), data); err != nil {
+		return err
+	}
+	steps, err := resolve.StepRefs(data)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(p)
+	for _, step := range steps {
+		_, versioned, err := resolve.ParseRef(step.Ref)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.Step, err)
+		}
+		if versioned {
+			continue
+		}
+		rel := filepath.ToSlash(step.Ref)
+		if filepath.IsAbs(step.Ref) || path.Clean(rel) == ".." || strings.HasPrefix(path.Clean(rel), "../") {
+			return fmt.Errorf("step %s: ref %s is outside the workflow's directory", step.Step, step.Ref)
+		}
+		if w.paths[path.Clean(rel)] {
+			continue
+		}
+		agent, err := os.ReadFile(filepath.Join(dir, step.Ref))
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.Step, err)
+		}
+		if err := w.Add(rel, KindAgent, agent); err != nil {
+			return fmt.Errorf("step %s: %w", step.Step, err)
+		}
+	}
+	if r == nil {
+		for _, step := range steps {
+			if _, versioned, _ := resolve.ParseRef(step.Ref); versioned {
+				return fmt.Errorf("step %s: versioned ref %s needs a registry", step.Step, step.Ref)
+			}
+		}
+		return nil
+	}
+	resolved, err := r.ResolveWorkflow(ctx, data)
+	if err != nil {
+		return err
+	}
+	for _, s := range resolved {
+		if err := w.AddResolved(s.Resolution); err != nil {
+			return fmt.Errorf("step %s: %w", s.Step, err)
+		}
+	}
+	if r.Lock != nil && len(resolved) > 0 {
+		used := map[string]bool{}
+		for _, s := range resolved {
+			used[s.Ref.Raw] = true
+		}
+		r.Lock.Retain(used)
+		lock, err := r.Lock.Marshal()
+		if err != nil {
+			return err
+		}
+		return w.Add(resolve.LockFile, KindLock, lock)
+	}
+	return nil
+}
+
+// ParsePrivateKey reads an ed25519 private key, PEM encoded PKCS #8 (as
+// openssl genpkey -algorithm ed25519 writes) or base64.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid private key: not ed25519")
+		}
+		return priv, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("invalid private key: %d bytes", len(raw))
+}
+
+// ParsePublicKey reads an ed25519 public key, PEM encoded PKIX (as openssl
+// pkey -pubout writes) or base64.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("invalid public key: not ed25519")
+		}
+		return pub, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
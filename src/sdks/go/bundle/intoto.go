@@ -0,0 +1,99 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/intoto"
+)
+
+// KindInToto is the kind of in-toto layouts and links, kept under
+// InTotoDir.
+const KindInToto = "in-toto"
+
+// InTotoDir holds a bundle's in-toto metadata: the layout at LayoutFile
+// and the links of its steps, as <step>.<key id>.link.
+const InTotoDir = "in-toto"
+
+// LayoutFile is the path of a bundle's in-toto layout.
+const LayoutFile = InTotoDir + "/root.layout"
+
+// Custody is the verified chain of custody of one manifest in a bundle:
+// the functionaries of each step whose link produced it as bundled.
+type Custody struct {
+	Path   string
+	SHA256 string
+	Steps  []intoto.Functionary
+}
+
+// HasLayout reports whether the bundle carries an in-toto layout.
+func (r *Reader) HasLayout() bool {
+	_, ok := r.files[LayoutFile]
+	return ok
+}
+
+// VerifyLayout verifies the bundle's in-toto layout against its owners'
+// keys and its links against the layout (see intoto.Verify), then checks
+// the final step of the layout produced every manifest the bundle holds,
+// the workflow and each agent, with its bundled sha256. It returns who
+// performed each step for each manifest. Call Verify first: the links'
+// hashes are only as good as the bundle's own.
+func (r *Reader) VerifyLayout(owners []ed25519.PublicKey, now time.Time) ([]Custody, error) {
+	data, ok := r.files[LayoutFile]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no in-toto layout (%s)", LayoutFile)
+	}
+	layout, err := intoto.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", LayoutFile, err)
+	}
+	var paths []string
+	for p := range r.files {
+		if strings.HasPrefix(p, InTotoDir+"/") && strings.HasSuffix(p, ".link") {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	var links []*intoto.Envelope
+	for _, p := range paths {
+		link, err := intoto.Parse(r.files[p])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		links = append(links, link)
+	}
+	res, err := intoto.Verify(layout, owners, links, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Layout.Steps) == 0 {
+		return nil, fmt.Errorf("in-toto layout has no steps")
+	}
+	final := res.Layout.Steps[len(res.Layout.Steps)-1].Name
+
+	var custody []Custody
+	var problems []string
+	for _, e := range r.Index.Entries {
+		if e.Kind != KindWorkflow && e.Kind != KindAgent {
+			continue
+		}
+		if res.Links[final].Products[e.Path]["sha256"] != e.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: not produced as bundled by step %s", e.Path, final))
+			continue
+		}
+		c := Custody{Path: e.Path, SHA256: e.SHA256}
+		for _, f := range res.Functionaries {
+			if res.Links[f.Step].Products[e.Path]["sha256"] == e.SHA256 {
+				c.Steps = append(c.Steps, f)
+			}
+		}
+		custody = append(custody, c)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("bundle's chain of custody is incomplete:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return custody, nil
+}
@@ -0,0 +1,312 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// MaxSize bounds the uncompressed size of a bundle a Reader accepts.
+const MaxSize = 512 << 20
+
+// ErrUnsigned is returned by Verify when keys are required and the bundle
+// has no signature by any of them.
+var ErrUnsigned = errors.New("bundle is not signed by a trusted key")
+
+// Reader reads a bundle, held in memory.
+type Reader struct {
+	Index      Index
+	Signatures []Signature
+	files      map[string][]byte
+}
+
+// Open reads the bundle at path.
+func Open(p string) (*Reader, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return r, nil
+}
+
+// NewReader reads a bundle from r. Only regular files inside the bundle
+// are accepted; links, devices and paths escaping it are errors. An
+// encrypted bundle is decrypted with the ossa.SetDecrypter function.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(ossa.EncryptedHeader)); ossa.IsEncrypted(head) {
+		data, err := io.ReadAll(io.LimitReader(br, MaxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxSize {
+			return nil, fmt.Errorf("bundle exceeds %d bytes", MaxSize)
+		}
+		if data, err = ossa.Decrypt(data); err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(bytes.NewReader(data))
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	b := &Reader{files: map[string][]byte{}}
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a bundle: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%s: bundles hold regular files only", hdr.Name)
+		}
+		p, err := cleanPath(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := b.files[p]; dup {
+			return nil, fmt.Errorf("%s appears twice", p)
+		}
+		total += hdr.Size
+		if hdr.Size < 0 || total > MaxSize {
+			return nil, fmt.Errorf("bundle exceeds %d MB", MaxSize>>20)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, err
+		}
+		b.files[p] = data
+	}
+
+	index, ok := b.files[IndexFile]
+	if !ok {
+		return nil, fmt.Errorf("not a bundle: no %s", IndexFile)
+	}
+	if err := json.Unmarshal(index, &b.Index); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", IndexFile, err)
+	}
+	if b.Index.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %q (want %s)", b.Index.Format, Format)
+	}
+	var sigs []string
+	for p := range b.files {
+		if strings.HasPrefix(p, "signatures/") {
+			sigs = append(sigs, p)
+		}
+	}
+	sort.Strings(sigs)
+	for _, p := range sigs {
+		var sig Signature
+		if err := json.Unmarshal(b.files[p], &sig); err != nil {
+			return nil, fmt.Errorf("invalid signature %s: %w", p, err)
+		}
+		b.Signatures = append(b.Signatures, sig)
+	}
+	return b, nil
+}
+
+// File returns the content of the file at path.
+func (r *Reader) File(p string) ([]byte, bool) {
+	data, ok := r.files[p]
+	return data, ok
+}
+
+// Workflow returns the bundle's workflow document.
+func (r *Reader) Workflow() ([]byte, error) {
+	data, ok := r.files[r.Index.Workflow]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no workflow %q", r.Index.Workflow)
+	}
+	return data, nil
+}
+
+// Verify checks every file against the index and every signature against
+// the index, and returns the IDs of the keys that signed it. With trusted
+// keys, a signature by one of them is required and others are ignored;
+// without, any valid signatures are accepted, so an unsigned bundle only
+// has its checksums checked.
+func (r *Reader) Verify(trusted ...ed25519.PublicKey) ([]string, error) {
+	var problems []string
+	listed := map[string]bool{IndexFile: true}
+	for _, e := range r.Index.Entries {
+		listed[e.Path] = true
+		data, ok := r.files[e.Path]
+		if !ok {
+			problems = append(problems, e.Path+": missing")
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != e.SHA256 {
+			problems = append(problems, e.Path+": sha256 does not match the index")
+		}
+	}
+	for p := range r.files {
+		if !listed[p] && !strings.HasPrefix(p, "signatures/") {
+			problems = append(problems, p+": not in the index")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("bundle does not verify:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	signed, err := r.Index.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	trust := map[string]ed25519.PublicKey{}
+	for _, k := range trusted {
+		trust[KeyID(k)] = k
+	}
+	var signers []string
+	for _, sig := range r.Signatures {
+		pub := trust[sig.KeyID]
+		if len(trusted) == 0 {
+			raw, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+			if err != nil || len(raw) != ed25519.PublicKeySize || KeyID(raw) != sig.KeyID {
+				return nil, fmt.Errorf("signature %s: invalid public key", sig.KeyID)
+			}
+			pub = raw
+		}
+		if pub == nil {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+		if err != nil || !ed25519.Verify(pub, signed, raw) {
+			return nil, fmt.Errorf("signature %s does not verify", sig.KeyID)
+		}
+		signers = append(signers, sig.KeyID)
+	}
+	if len(trusted) > 0 && len(signers) == 0 {
+		return nil, ErrUnsigned
+	}
+	return signers, nil
+}
+
+// Extract writes the bundle's files, index and signatures below dir.
+func (r *Reader) Extract(dir string) error {
+	paths := make([]string, 0, len(r.files))
+	for p := range r.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		dest := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, r.files[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Source returns the bundle's agents as a resolve.Source, so the
+// workflow's versioned refs resolve without a registry.
+func (r *Reader) Source() *Source {
+	s := &Source{read: func(p string) ([]byte, error) {
+		data, ok := r.files[p]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	}}
+	for _, e := range r.Index.Entries {
+		if e.Kind == KindAgent {
+			s.agents = append(s.agents, e)
+		}
+	}
+	return s
+}
+
+// DirSource returns the agents of the bundle extracted to dir as a
+// resolve.Source. It returns false when dir holds no extracted bundle.
+func DirSource(dir string) (*Source, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %w", IndexFile, err)
+	}
+	if index.Format != Format {
+		return nil, false, nil
+	}
+	s := &Source{read: func(p string) ([]byte, error) { return os.ReadFile(filepath.Join(dir, filepath.FromSlash(p))) }}
+	for _, e := range index.Entries {
+		if e.Kind == KindAgent {
+			s.agents = append(s.agents, e)
+		}
+	}
+	return s, true, nil
+}
+
+// Source resolves refs against the agents in a bundle. It implements
+// resolve.Source.
+type Source struct {
+	agents []Entry
+	read   func(p string) ([]byte, error)
+}
+
+// Versions returns the bundled versions of namespace/name.
+func (s *Source) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	var versions []string
+	for _, e := range s.agents {
+		if e.Namespace == namespace && e.Name == name {
+			versions = append(versions, e.Version)
+		}
+	}
+	return versions, nil
+}
+
+// Fetch returns a bundled release.
+func (s *Source) Fetch(ctx context.Context, namespace, name, version string) (*ossa.Manifest, error) {
+	p := AgentPath(namespace, name, version)
+	for _, e := range s.agents {
+		if e.Path != p {
+			continue
+		}
+		data, err := s.read(p)
+		if err != nil {
+			return nil, err
+		}
+		return ossa.ParseManifest(data, path.Ext(p))
+	}
+	return nil, fmt.Errorf("%s/%s@%s is not in the bundle", namespace, name, version)
+}
@@ -0,0 +1,407 @@
+// Package chatapi serves an OSSA agent behind the OpenAI chat completions
+// API, so clients and UIs written for OpenAI can talk to it unchanged:
+//
+//	POST /v1/chat/completions  run the agent on the conversation, streamed with "stream": true
+//	GET  /v1/models            the agent, as the one model
+//
+// The last user message becomes the run's input "message", with the
+// messages before it as "history"; the agent's own prompt, model, tools and
+// guardrails apply whatever model and sampling parameters the client asks
+// for. Prompts and answers go through spec.safety.moderation, a blocked
+// answer finishing with "content_filter". Each request is recorded as a run
+// in a runs.Store like those of ossa run.
+package chatapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+// maxRequestBody is the largest request body accepted.
+const maxRequestBody = 4 << 20
+
+// Finish reasons of a choice.
+const (
+	FinishStop          = "stop"
+	FinishContentFilter = "content_filter"
+)
+
+// ChatRequest is the body of POST /v1/chat/completions. Fields the agent
+// decides for itself, like temperature, are ignored.
+type ChatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	// User identifies the end user, whose requests share cached tool
+	// results; requests without one share none.
+	User string `json:"user,omitempty"`
+}
+
+// StreamOptions are a streamed request's options.
+type StreamOptions struct {
+	// IncludeUsage adds a last chunk with the request's usage.
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// ChatMessage is one message of the conversation.
+type ChatMessage struct {
+	Role    string  `json:"role,omitempty"`
+	Content Content `json:"content"`
+	Name    string  `json:"name,omitempty"`
+}
+
+// Content is a message's text. It may be sent as a string or as an array
+// of content parts, of which the text parts are kept.
+type Content string
+
+// UnmarshalJSON accepts a string, null or an array of content parts.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text *string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text != nil {
+			*c = Content(*text)
+		}
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content is neither a string nor content parts")
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	*c = Content(strings.Join(texts, "\n"))
+	return nil
+}
+
+// ChatCompletion is the response to a request, or one chunk of it when
+// streamed.
+type ChatCompletion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Choice is the agent's answer. Chunks of a stream carry it in Delta.
+type Choice struct {
+	Index        int          `json:"index"`
+	Message      *ChatMessage `json:"message,omitempty"`
+	Delta        *ChatMessage `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+// Usage is the tokens a request cost, over all of the agent's turns.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Options configure a Server.
+type Options struct {
+	// Engine runs the agent; each request runs on a copy of it.
+	Engine *engine.Engine
+	// Store records each request's run; nil records nothing.
+	Store runs.Store
+	// APIKey, if set, is the key clients must send as their OpenAI API
+	// key.
+	APIKey string
+}
+
+// Server serves one agent behind the chat completions API.
+type Server struct {
+	m         *ossa.Manifest
+	source    string
+	opts      Options
+	moderator *ossa.Moderator
+}
+
+// New returns a Server running the agent m, loaded from source.
+func New(m *ossa.Manifest, source string, opts Options) (*Server, error) {
+	if m.Kind != ossa.KindAgent {
+		return nil, fmt.Errorf("a %s cannot be served as a chat model", m.Kind)
+	}
+	s := &Server{m: m, source: source, opts: opts}
+	if safety := m.Spec.Safety; safety != nil {
+		moderator, err := ossa.NewModerator(safety.Moderation)
+		if err != nil {
+			return nil, err
+		}
+		s.moderator = moderator
+	}
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.APIKey != "" {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(s.opts.APIKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid_api_key", "Incorrect API key provided")
+			return
+		}
+	}
+	switch r.URL.Path {
+	case "/v1/models":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "", "use GET")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data":   []map[string]interface{}{{"id": s.m.Metadata.Name, "object": "model", "created": 0, "owned_by": "ossa"}},
+		})
+	case "/v1/chat/completions":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "", "use POST")
+			return
+		}
+		s.handleCompletion(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "", "not found: "+r.URL.Path)
+	}
+}
+
+// writeError answers with an OpenAI error object.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	kind := "invalid_request_error"
+	if status >= 500 {
+		kind = "server_error"
+	}
+	e := map[string]interface{}{"message": msg, "type": kind, "code": nil}
+	if code != "" {
+		e["code"] = code
+	}
+	writeJSON(w, status, map[string]interface{}{"error": e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+	if err != nil || len(body) > maxRequestBody {
+		writeError(w, http.StatusRequestEntityTooLarge, "", "request too large")
+		return
+	}
+	var req ChatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid request body: "+err.Error())
+		return
+	}
+	input, err := conversationInput(req.Messages)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+	message, err := s.moderate(r.Context(), ossa.StagePrompt, input["message"].(string))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	if message == nil {
+		writeError(w, http.StatusBadRequest, FinishContentFilter, "The prompt was blocked by the agent's moderation policy")
+		return
+	}
+	input["message"] = *message
+
+	if req.Stream {
+		s.stream(w, r, &req, input)
+		return
+	}
+	run, err := s.run(r, req.User, input, nil, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	text, finish, err := s.answer(r.Context(), run)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ChatCompletion{
+		ID:      "chatcmpl-" + run.ID,
+		Object:  "chat.completion",
+		Created: run.Started.Unix(),
+		Model:   s.m.Metadata.Name,
+		Choices: []Choice{{Message: &ChatMessage{Role: engine.RoleAssistant, Content: Content(text)}, FinishReason: &finish}},
+		Usage:   usage(run),
+	})
+}
+
+// stream answers req with chat.completion.chunk Server-Sent Events. The
+// answer's tokens are sent as the model generates them unless answers are
+// moderated, when the moderated answer is sent once the run is over.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, req *ChatRequest, input map[string]interface{}) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "", "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var id string
+	created := time.Now().Unix()
+	send := func(choices []Choice, u *Usage) {
+		data, _ := json.Marshal(ChatCompletion{ID: "chatcmpl-" + id, Object: "chat.completion.chunk", Created: created, Model: s.m.Metadata.Name, Choices: choices, Usage: u})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+	delta := func(text string) []Choice {
+		return []Choice{{Delta: &ChatMessage{Content: Content(text)}}}
+	}
+
+	// Events come from the run's goroutine only: agents run no steps in
+	// parallel.
+	streamed := false
+	live := !s.moderates(ossa.StageCompletion)
+	onStart := func(run *runs.Run) {
+		id = run.ID
+		send([]Choice{{Delta: &ChatMessage{Role: engine.RoleAssistant}}}, nil)
+	}
+	onEvent := func(ev engine.Event) {
+		if live && ev.Type == engine.EventToken && ev.Text != "" {
+			streamed = true
+			send(delta(ev.Text), nil)
+		}
+	}
+	run, err := s.run(r, req.User, input, onStart, onEvent)
+	if id == "" {
+		// Nothing was sent: the run never started.
+		data, _ := json.Marshal(map[string]interface{}{"error": map[string]interface{}{"message": err.Error(), "type": "server_error"}})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+		flusher.Flush()
+		return
+	}
+	var text, finish string
+	if err == nil {
+		text, finish, err = s.answer(r.Context(), run)
+	}
+	if err != nil {
+		text, finish, streamed = "error: "+err.Error(), FinishStop, false
+	}
+	if !streamed && text != "" {
+		send(delta(text), nil)
+	}
+	send([]Choice{{Delta: &ChatMessage{}, FinishReason: &finish}}, nil)
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		send([]Choice{}, usage(run))
+	}
+	io.WriteString(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// run runs the agent on input for r and user, in its Accept-Language
+// locale if it has one, recording the run.
+func (s *Server) run(r *http.Request, user string, input map[string]interface{}, onStart func(*runs.Run), onEvent func(engine.Event)) (*runs.Run, error) {
+	eng := *s.opts.Engine
+	eng.Events = onEvent
+	eng.NoModeration = true
+	if locale := ossa.ParseAcceptLanguage(r.Header.Get("Accept-Language")); locale != "" {
+		eng.Locale = locale
+	}
+	scope := "request/" + runs.NewID(time.Now())
+	if user != "" {
+		scope = "user/" + user
+	}
+	rec := &runs.Recorder{Engine: &eng, Store: s.opts.Store, OnStart: onStart}
+	return rec.RunAgent(engine.WithCacheScope(r.Context(), scope), s.m, s.source, input)
+}
+
+// answer is the run's output as the assistant's text, moderated: an
+// agent's plain text answer, or else its JSON output. A blocked answer is
+// empty, finishing with content_filter.
+func (s *Server) answer(ctx context.Context, run *runs.Run) (string, string, error) {
+	text, ok := run.Output["content"].(string)
+	if !ok || len(run.Output) != 1 {
+		data, err := json.Marshal(run.Output)
+		if err != nil {
+			return "", "", err
+		}
+		text = string(data)
+	}
+	moderated, err := s.moderate(ctx, ossa.StageCompletion, text)
+	if err != nil {
+		return "", "", err
+	}
+	if moderated == nil {
+		return "", FinishContentFilter, nil
+	}
+	return *moderated, FinishStop, nil
+}
+
+// moderate checks text with the agent's moderation, returning it, possibly
+// redacted, or nil if it is blocked.
+func (s *Server) moderate(ctx context.Context, stage ossa.ModerationStage, text string) (*string, error) {
+	if s.moderator == nil {
+		return &text, nil
+	}
+	result, err := s.moderator.Check(ctx, stage, text)
+	if err != nil {
+		return nil, err
+	}
+	result.Record(s.opts.Engine.AuditLogger, s.m.Metadata.Name)
+	if result.Blocked {
+		return nil, nil
+	}
+	return &result.Text, nil
+}
+
+// moderates reports whether the agent's moderation checks stage.
+func (s *Server) moderates(stage ossa.ModerationStage) bool {
+	return s.moderator.Moderates(stage)
+}
+
+// conversationInput is the run input for a conversation: its last message,
+// which must be the user's, and the messages before it.
+func conversationInput(messages []ChatMessage) (map[string]interface{}, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages is empty")
+	}
+	last := messages[len(messages)-1]
+	if last.Role != engine.RoleUser {
+		return nil, fmt.Errorf("the last message must be the user's, not the %s's", last.Role)
+	}
+	input := map[string]interface{}{"message": string(last.Content)}
+	if len(messages) > 1 {
+		history := make([]map[string]interface{}, 0, len(messages)-1)
+		for _, msg := range messages[:len(messages)-1] {
+			history = append(history, map[string]interface{}{"role": msg.Role, "content": string(msg.Content)})
+		}
+		input["history"] = history
+	}
+	return input, nil
+}
+
+func usage(run *runs.Run) *Usage {
+	return &Usage{
+		PromptTokens:     run.Usage.InputTokens,
+		CompletionTokens: run.Usage.OutputTokens,
+		TotalTokens:      run.Usage.InputTokens + run.Usage.OutputTokens,
+	}
+}
@@ -0,0 +1,226 @@
+package chatapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+const manifest = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: helper
+spec:
+  role: You help.
+  safety:
+    moderation:
+      enabled: true
+      provider: keywords
+      keywords: [launch codes]
+      stages: [prompt, completion]
+`
+
+// streamingModel answers with its words one token at a time, echoing the
+// user's message.
+type streamingModel struct {
+	last *engine.Request
+}
+
+func (s *streamingModel) answer(req *engine.Request) string {
+	s.last = req
+	var input map[string]interface{}
+	json.Unmarshal([]byte(req.Messages[len(req.Messages)-1].Content), &input)
+	return "You said: " + input["message"].(string)
+}
+
+func (s *streamingModel) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return &engine.Response{Content: s.answer(req), Usage: engine.Usage{InputTokens: 10, OutputTokens: 4}}, nil
+}
+
+func (s *streamingModel) Stream(_ context.Context, req *engine.Request, onText func(string)) (*engine.Response, error) {
+	text := s.answer(req)
+	for _, word := range strings.SplitAfter(text, " ") {
+		onText(word)
+	}
+	return &engine.Response{Content: text, Usage: engine.Usage{InputTokens: 10, OutputTokens: 4}}, nil
+}
+
+func newServer(t *testing.T, key string) (*httptest.Server, *streamingModel, runs.Store) {
+	t.Helper()
+	model := &streamingModel{}
+	store := &runs.DirStore{Dir: t.TempDir()}
+	return serve(t, manifest, model, key, store), model, store
+}
+
+func serve(t *testing.T, manifest string, model engine.Model, key string, store runs.Store) *httptest.Server {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "helper.ossa.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ossa.LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(m, path, Options{Engine: &engine.Engine{Model: model}, Store: store, APIKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, srv *httptest.Server, key, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestCompletion(t *testing.T) {
+	srv, model, store := newServer(t, "sk-test")
+	resp := post(t, srv, "sk-test", `{"model": "gpt-4o", "messages": [
+		{"role": "system", "content": "Be brief."},
+		{"role": "user", "content": "Hi"},
+		{"role": "assistant", "content": "Hello"},
+		{"role": "user", "content": [{"type": "text", "text": "Help me"}, {"type": "image_url", "image_url": {"url": "x"}}]}]}`)
+	defer resp.Body.Close()
+	var out ChatCompletion
+	json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != http.StatusOK || out.Object != "chat.completion" || out.Model != "helper" || len(out.Choices) != 1 {
+		t.Fatalf("Unexpected response %d %+v", resp.StatusCode, out)
+	}
+	choice := out.Choices[0]
+	if choice.Message.Role != engine.RoleAssistant || choice.Message.Content != "You said: Help me" || *choice.FinishReason != FinishStop {
+		t.Errorf("Unexpected choice %+v", choice.Message)
+	}
+	if out.Usage == nil || out.Usage.TotalTokens != 14 {
+		t.Errorf("Unexpected usage %+v", out.Usage)
+	}
+	// The agent keeps its own system prompt and gets the conversation as input.
+	if model.last.Messages[0].Content != "You help." {
+		t.Errorf("Expected the agent's prompt, got %q", model.last.Messages[0].Content)
+	}
+	run, err := store.Get(context.Background(), strings.TrimPrefix(out.ID, "chatcmpl-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := run.Input["history"].([]interface{}); len(history) != 3 || history[0].(map[string]interface{})["content"] != "Be brief." {
+		t.Errorf("Unexpected history %v", run.Input["history"])
+	}
+
+	resp = post(t, srv, "wrong", `{}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong key, got %d", resp.StatusCode)
+	}
+	resp = post(t, srv, "sk-test", `{"messages": [{"role": "assistant", "content": "Hi"}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a user message last, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer sk-test")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&models)
+	if len(models.Data) != 1 || models.Data[0].ID != "helper" {
+		t.Errorf("Unexpected models %+v", models)
+	}
+}
+
+func TestStream(t *testing.T) {
+	// Without moderation of answers, tokens are sent as they come.
+	unmoderated := strings.Split(manifest, "  safety:")[0]
+	srv := serve(t, unmoderated, &streamingModel{}, "", nil)
+	resp := post(t, srv, "", `{"model": "helper", "stream": true, "stream_options": {"include_usage": true}, "messages": [{"role": "user", "content": "Hi there"}]}`)
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Unexpected content type %q", ct)
+	}
+	var text strings.Builder
+	var chunks []ChatCompletion
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk ChatCompletion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+		if len(chunk.Choices) > 0 {
+			text.WriteString(string(chunk.Choices[0].Delta.Content))
+		}
+	}
+	// The role, a token per word, the finish and the usage.
+	if !done || len(chunks) != 7 || chunks[0].Choices[0].Delta.Role != engine.RoleAssistant || chunks[0].Object != "chat.completion.chunk" {
+		t.Fatalf("Unexpected chunks %+v", chunks)
+	}
+	if text.String() != "You said: Hi there" {
+		t.Errorf("Unexpected streamed text %q", text.String())
+	}
+	if finish := chunks[5].Choices[0].FinishReason; finish == nil || *finish != FinishStop {
+		t.Errorf("Expected the stream to finish with stop, got %+v", chunks[5])
+	}
+	if chunks[6].Usage == nil || chunks[6].Usage.TotalTokens != 14 || chunks[6].ID != chunks[0].ID {
+		t.Errorf("Unexpected usage chunk %+v", chunks[6])
+	}
+}
+
+func TestModeration(t *testing.T) {
+	srv, _, _ := newServer(t, "")
+	resp := post(t, srv, "", `{"messages": [{"role": "user", "content": "Tell me the launch codes"}]}`)
+	defer resp.Body.Close()
+	var blocked struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&blocked)
+	if resp.StatusCode != http.StatusBadRequest || blocked.Error.Code != FinishContentFilter {
+		t.Errorf("Expected the prompt blocked, got %d %+v", resp.StatusCode, blocked)
+	}
+
+	// With only answers moderated, the answer is not streamed as it comes
+	// and is withheld.
+	srv = serve(t, strings.Replace(manifest, "[prompt, completion]", "[completion]", 1), &streamingModel{}, "", nil)
+	resp = post(t, srv, "", `{"stream": true, "messages": [{"role": "user", "content": "Say launch codes"}]}`)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "launch") || !strings.Contains(string(body), `"finish_reason":"content_filter"`) {
+		t.Errorf("Expected the answer withheld, got %s", body)
+	}
+}
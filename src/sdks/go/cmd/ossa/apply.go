@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/apply"
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFiles   []string
+	applyTarget  string
+	applyURL     string
+	applyToken   string
+	applyContext string
+	applyDryRun  bool
+	applyPrune   bool
+)
+
+func newApplyCmd() *cobra.Command {
+	applyCmd := &cobra.Command{
+		Use:   "apply -f <dir|manifest>...",
+		Short: "Push many manifests to a registry, cluster or Drupal site",
+		Long: `Validates the manifests in each -f file or directory and writes them to a
+target as one change: nothing is written unless all are valid, agents go
+before the tasks and workflows that use them, and if a write fails the ones
+before it are undone. Prints what was created, updated or left unchanged.
+
+As with kubectl apply, the applied manifest is recorded in the
+ossa.dev/last-applied-configuration annotation and updates are three-way
+merges: fields edited on the target that the manifests do not set are kept,
+fields removed from a manifest are removed, and overridden edits are
+reported.
+
+With --prune, manifests on the target matching --selector that an earlier
+apply wrote and that are no longer in the -f files are deleted; --selector
+is required so a prune only touches the fleet these files manage.
+Policy-tier agents are never pruned, only listed as protected. Combine with
+--dry-run to see what would go.
+
+Targets:
+  registry    an ossa serve registry at --url (default: the registry_url
+              setting), with a publishing --token
+  kubernetes  agents.ossa.dev custom resources, through kubectl and its
+              current context or --context
+  drupal      JSON:API content entities on the site at --url`,
+		Args: cobra.NoArgs,
+		RunE: runApply,
+	}
+	applyCmd.Flags().StringSliceVarP(&applyFiles, "filename", "f", nil, "Manifest or directory to apply (repeatable)")
+	applyCmd.Flags().StringVar(&applyTarget, "target", "registry", "Where to apply: registry, kubernetes or drupal")
+	applyCmd.Flags().StringVar(&applyURL, "url", "", "Registry or Drupal base URL")
+	applyCmd.Flags().StringVar(&applyToken, "token", "", "Bearer token for the registry or Drupal (default $OSSA_TOKEN)")
+	applyCmd.Flags().StringVar(&applyContext, "context", "", "kubeconfig context for the kubernetes target")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show what would change without writing")
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "Delete managed manifests matching --selector that are no longer in the files")
+	addSelectorFlag(applyCmd)
+	applyCmd.MarkFlagRequired("filename")
+	return applyCmd
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	target, err := applyTargetFor(applyTarget)
+	if err != nil {
+		return err
+	}
+	sources, err := applySources(applyFiles)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no manifests to apply")
+	}
+
+	sel, err := labels.Parse(selectorFlag)
+	if err != nil {
+		return err
+	}
+	opts := apply.Options{DryRun: applyDryRun, Prune: applyPrune, Selector: sel}
+	result, err := apply.Apply(context.Background(), target, sources, opts)
+	if result != nil {
+		printApplyResult(cmd, target, result)
+	}
+	return err
+}
+
+func applyTargetFor(name string) (apply.Target, error) {
+	token := applyToken
+	if token == "" {
+		token = os.Getenv("OSSA_TOKEN")
+	}
+	switch name {
+	case "registry":
+		url := applyURL
+		if url == "" && settings != nil {
+			url = settings.Value("registry_url")
+		}
+		if url == "" {
+			return nil, fmt.Errorf("no registry: pass --url or set registry_url with ossa config set")
+		}
+		return &apply.Registry{URL: url, Token: token}, nil
+	case "kubernetes", "k8s":
+		return &apply.Kubernetes{Context: applyContext}, nil
+	case "drupal":
+		if applyURL == "" {
+			return nil, fmt.Errorf("the drupal target needs --url")
+		}
+		return &apply.Drupal{URL: applyURL, Token: token}, nil
+	}
+	return nil, fmt.Errorf("unknown target %q: use registry, kubernetes or drupal", name)
+}
+
+// applySources loads the manifests in files and directories. Any file that
+// fails to load stops the apply.
+func applySources(paths []string) ([]apply.Source, error) {
+	var entries []ossa.CatalogEntry
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			found, err := ossa.ScanWorkspace(path)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, found...)
+			continue
+		}
+		m, err := ossa.LoadManifest(path)
+		entries = append(entries, ossa.CatalogEntry{Path: path, Manifest: m, Err: err})
+	}
+	for _, e := range entries {
+		if e.Err != nil {
+			return nil, fmt.Errorf("nothing applied: %s: %w", e.Path, e.Err)
+		}
+	}
+	entries, err := selectEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]apply.Source, len(entries))
+	for i, e := range entries {
+		sources[i] = apply.Source{Path: e.Path, Manifest: e.Manifest}
+	}
+	return sources, nil
+}
+
+func printApplyResult(cmd *cobra.Command, target apply.Target, result *apply.Result) {
+	out := cmd.OutOrStdout()
+	if result.RolledBack {
+		fmt.Fprintf(out, "❌ Rolled back all changes to %s\n", target)
+		for _, msg := range result.RollbackErrors {
+			fmt.Fprintf(out, "  • could not undo %s\n", msg)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, e := range result.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Change, apply.Key(e.Manifest), e.Path)
+	}
+	w.Flush()
+	for _, e := range result.Entries {
+		for _, path := range e.Conflicts {
+			fmt.Fprintf(out, "  ⚠ %s: overrode %s, changed on the target since the last apply\n", apply.Key(e.Manifest), path)
+		}
+		if e.Change == apply.Protected {
+			fmt.Fprintf(out, "  🔒 %s: policy-tier agent not pruned; delete it by hand if it should go\n", apply.Key(e.Manifest))
+		}
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintf(out, "  ⚠ %s\n", w)
+	}
+	suffix := ""
+	if applyDryRun {
+		suffix = " (dry run)"
+	}
+	pruned := ""
+	if applyPrune {
+		pruned = fmt.Sprintf(", %d deleted", result.Count(apply.Deleted))
+	}
+	fmt.Fprintf(out, "✅ %s: %d created, %d updated, %d unchanged%s%s\n", target,
+		result.Count(apply.Created), result.Count(apply.Updated), result.Count(apply.Unchanged), pruned, suffix)
+}
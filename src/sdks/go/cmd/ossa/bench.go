@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchN          int
+	benchParallel   int
+	benchStructural bool
+)
+
+func newBenchCmd() *cobra.Command {
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure SDK performance",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [manifest|dir...]",
+		Short: "Measure parse and validate throughput",
+		Long: `Parses and validates manifests --n times, round robin, on --parallel
+goroutines sharing one validator, and reports manifests per second, the
+mean time spent parsing and validating, p50 and p99 latencies and heap
+allocations per manifest. Without manifests a representative agent is
+used (ossa.BenchManifest).
+
+The schema is compiled once, before timing starts, as a server
+validating many manifests would; --structural skips the JSON Schema and
+measures the SDK's own checks only. Compare --json results between
+releases to catch regressions; go test -bench . ./ossa has the same
+measurements as Go benchmarks.`,
+		RunE: runBenchValidate,
+	}
+	validateCmd.Flags().IntVar(&benchN, "n", 10000, "Manifests to parse and validate")
+	validateCmd.Flags().IntVar(&benchParallel, "parallel", 1, "Goroutines validating at once")
+	validateCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to custom schema (defaults to embedded v0.3.3)")
+	validateCmd.Flags().BoolVar(&benchStructural, "structural", false, "Skip JSON Schema validation")
+	validateCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output as JSON")
+
+	benchCmd.AddCommand(validateCmd)
+	return benchCmd
+}
+
+func runBenchValidate(cmd *cobra.Command, args []string) error {
+	docs := []ossa.BenchDoc{{Name: ".yaml", Data: []byte(ossa.BenchManifest)}}
+	if len(args) > 0 {
+		paths, err := vetPaths(args)
+		if err != nil {
+			return err
+		}
+		docs = docs[:0]
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, ossa.BenchDoc{Name: filepath.Ext(path), Data: data})
+		}
+	}
+
+	v := ossa.DefaultValidator()
+	if !benchStructural {
+		schema := ossa.EmbeddedSchema()
+		if schemaPath != "" {
+			data, err := os.ReadFile(schemaPath)
+			if err != nil {
+				return err
+			}
+			schema = data
+		}
+		var err error
+		if v, err = ossa.NewValidator(ossa.WithSchema(schema)); err != nil {
+			return err
+		}
+	}
+
+	res, err := ossa.BenchValidate(docs, v, benchN, benchParallel)
+	if err != nil {
+		return err
+	}
+	if outputJSON {
+		out, err := json.MarshalIndent(struct {
+			*ossa.BenchResult
+			OpsPerSecond float64 `json:"ops_per_second"`
+		}{res, res.OpsPerSecond()}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		return nil
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%d manifests (%d distinct, %.1f MB) in %s on %d goroutines\n",
+		res.N, len(docs), float64(res.Bytes)/1e6, res.Duration.Round(time.Millisecond), res.Workers)
+	fmt.Fprintf(w, "  %.0f manifests/s, %.2f MB/s\n", res.OpsPerSecond(), float64(res.Bytes)/1e6/res.Duration.Seconds())
+	fmt.Fprintf(w, "  parse %s, validate %s per manifest\n", res.Parse, res.Validate)
+	fmt.Fprintf(w, "  p50 %s, p99 %s\n", res.P50, res.P99)
+	fmt.Fprintf(w, "  %d allocs, %d bytes per manifest\n", res.AllocsPerOp, res.BytesPerOp)
+	if res.Invalid > 0 {
+		fmt.Fprintf(w, "  ⚠ %d of %d validations failed\n", res.Invalid, res.N)
+	}
+	return nil
+}
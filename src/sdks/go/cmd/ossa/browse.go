@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+func newBrowseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "browse [dir]",
+		Short: "Browse the manifests in a workspace",
+		Long: `Lists the agents in a workspace directory (default ".") with kind, access
+tier, model and modification time. From the list, preview a manifest, validate
+it, or diff two manifests field by field.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runBrowse,
+	}
+}
+
+func runBrowse(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	b := &browser{dir: dir, in: bufio.NewScanner(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+	if err := b.scan(); err != nil {
+		return err
+	}
+	return b.run()
+}
+
+type browser struct {
+	dir     string
+	entries []ossa.CatalogEntry
+	filter  string
+	in      *bufio.Scanner
+	out     io.Writer
+}
+
+func (b *browser) scan() error {
+	entries, err := ossa.ScanWorkspace(b.dir)
+	if err != nil {
+		return err
+	}
+	b.entries = entries
+	return nil
+}
+
+func (b *browser) run() error {
+	for {
+		visible := b.visible()
+		b.list(visible)
+		fmt.Fprintln(b.out, "  N) preview  v N) validate  d N M) diff  /text) filter  r) rescan  q) quit")
+		fmt.Fprint(b.out, "> ")
+		if !b.in.Scan() {
+			return nil
+		}
+		fields := strings.Fields(b.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		pick := func(s string) (ossa.CatalogEntry, bool) {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 1 || i > len(visible) {
+				fmt.Fprintf(b.out, "❌ no entry %s\n", s)
+				return ossa.CatalogEntry{}, false
+			}
+			return visible[i-1], true
+		}
+
+		switch {
+		case fields[0] == "q":
+			return nil
+		case fields[0] == "r":
+			if err := b.scan(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(fields[0], "/"):
+			b.filter = strings.TrimPrefix(strings.Join(fields, " "), "/")
+		case fields[0] == "v" && len(fields) == 2:
+			if e, ok := pick(fields[1]); ok {
+				b.validate(e)
+			}
+		case fields[0] == "d" && len(fields) == 3:
+			x, ok1 := pick(fields[1])
+			y, ok2 := pick(fields[2])
+			if ok1 && ok2 {
+				b.diff(x, y)
+			}
+		case len(fields) == 1:
+			if e, ok := pick(fields[0]); ok {
+				b.preview(e)
+			}
+		}
+	}
+}
+
+// visible returns the entries whose path, name, kind, tier or model
+// contains the filter text.
+func (b *browser) visible() []ossa.CatalogEntry {
+	if b.filter == "" {
+		return b.entries
+	}
+	var out []ossa.CatalogEntry
+	for _, e := range b.entries {
+		text := strings.Join([]string{e.Path, e.Name, string(e.Kind), string(e.Tier), e.Model}, " ")
+		if strings.Contains(strings.ToLower(text), strings.ToLower(b.filter)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (b *browser) list(entries []ossa.CatalogEntry) {
+	fmt.Fprintf(b.out, "\n%s: %d manifests", b.dir, len(entries))
+	if b.filter != "" {
+		fmt.Fprintf(b.out, " matching %q", b.filter)
+	}
+	fmt.Fprintln(b.out)
+	w := tabwriter.NewWriter(b.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  #\tNAME\tKIND\tTIER\tMODEL\tMODIFIED\tPATH")
+	for i, e := range entries {
+		name := e.Name
+		if e.Err != nil {
+			name = "(invalid)"
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, orDash(name), orDash(string(e.Kind)),
+			orDash(string(e.Tier)), orDash(e.Model), e.Modified.Format("2006-01-02 15:04"), e.Path)
+	}
+	w.Flush()
+}
+
+func (b *browser) preview(e ossa.CatalogEntry) {
+	if e.Err != nil {
+		fmt.Fprintf(b.out, "❌ %s: %v\n", e.Path, e.Err)
+		return
+	}
+	data, err := e.Manifest.ToYAML()
+	if err != nil {
+		fmt.Fprintf(b.out, "❌ %v\n", err)
+		return
+	}
+	fmt.Fprintf(b.out, "\n--- %s\n%s", e.Path, data)
+}
+
+func (b *browser) validate(e ossa.CatalogEntry) {
+	if e.Err != nil {
+		fmt.Fprintf(b.out, "❌ %s: %v\n", e.Path, e.Err)
+		return
+	}
+	result := ossa.ValidateManifest(e.Manifest)
+	if result.Valid {
+		fmt.Fprintf(b.out, "✅ %s is valid\n", e.Path)
+	} else {
+		fmt.Fprintf(b.out, "❌ %s is invalid (%d errors)\n", e.Path, len(result.Errors))
+	}
+	for _, msg := range result.Errors {
+		fmt.Fprintf(b.out, "  • %s\n", msg)
+	}
+	for _, msg := range result.Warnings {
+		fmt.Fprintf(b.out, "  ⚠ %s\n", msg)
+	}
+}
+
+func (b *browser) diff(x, y ossa.CatalogEntry) {
+	if x.Err != nil || y.Err != nil {
+		fmt.Fprintln(b.out, "❌ cannot diff manifests that failed to load")
+		return
+	}
+	changes, err := ossa.DiffManifests(x.Manifest, y.Manifest)
+	if err != nil {
+		fmt.Fprintf(b.out, "❌ %v\n", err)
+		return
+	}
+	fmt.Fprintf(b.out, "\n--- %s\n+++ %s\n", x.Path, y.Path)
+	if len(changes) == 0 {
+		fmt.Fprintln(b.out, "(no differences)")
+	}
+	for _, c := range changes {
+		fmt.Fprintln(b.out, c)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blueflyio/ossa-go/bundle"
+	"github.com/blueflyio/ossa-go/crypt"
+	"github.com/blueflyio/ossa-go/intoto"
+	"github.com/blueflyio/ossa-go/resolve"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOutput string
+	bundleAssets []string
+	bundleLock   string
+	bundleKeys   []string
+	bundleTrust  []string
+	bundleInToto string
+	bundleTo     []string
+	layoutKeys   []string
+	attestKey    string
+	attestDir    string
+	functionary  []string
+)
+
+func newBundleCmd() *cobra.Command {
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Package a workflow and its agents for air-gapped distribution",
+		Long: `A bundle (` + bundle.Ext + `) is a gzipped tar holding a workflow, the
+agents its steps use, its lock file, any assets and ed25519 signatures,
+so a complete agent system can be copied to a host without registry
+access. Its index, bundle.json, lists every file with its sha256, and
+signatures sign the index.
+
+Extracted, ossa run resolves the workflow's versioned refs against the
+bundled agents instead of a registry.
+
+For chain of custody, a bundle may carry an in-toto layout, signed by
+the project owners, naming who authors, reviews and signs its manifests,
+and the links those people signed as they did (ossa bundle sign-layout
+and ossa bundle attest write them). verify --layout-key checks them.`,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create <workflow>",
+		Short: "Create a bundle from a workflow",
+		Long: `Resolves the workflow's versioned refs against the registry, pinned by
+--lock where it has them, and bundles the workflow, each resolved release,
+each ref by path (which must lie in the workflow's directory), the
+resulting lock file and each --asset file or directory. --key signs the
+bundle with an ed25519 private key (PEM PKCS #8, as openssl genpkey
+-algorithm ed25519 writes, or base64); repeat it to sign with several.
+--recipient encrypts the whole bundle to age public keys or KMS keys (see
+ossa encrypt); extract and verify decrypt it with --identity.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBundleCreate,
+	}
+	addRegistryFlags(createCmd)
+	createCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "Bundle to write (default <workflow name>"+bundle.Ext+")")
+	createCmd.Flags().StringArrayVar(&bundleAssets, "asset", nil, "File or directory to include under assets/ (repeatable)")
+	createCmd.Flags().StringVar(&bundleLock, "lock", "", "Lock file pinning refs (default ossa.lock.yaml beside the workflow)")
+	createCmd.Flags().StringArrayVar(&bundleKeys, "key", nil, "ed25519 private key to sign with (repeatable)")
+	createCmd.Flags().StringVar(&bundleInToto, "in-toto", "", "Directory with the in-toto root.layout and *.link files to include")
+	createCmd.Flags().StringArrayVarP(&bundleTo, "recipient", "r", nil, "age public key or KMS key URI to encrypt the bundle to (repeatable)")
+
+	extractCmd := &cobra.Command{
+		Use:   "extract <bundle> [dir]",
+		Short: "Verify a bundle and extract it",
+		Long: `Verifies the bundle, as ossa bundle verify does, and writes its files to
+dir (default: the bundle's name without ` + bundle.Ext + `). Run the
+workflow from there with ossa run.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: runBundleExtract,
+	}
+	extractCmd.Flags().StringArrayVar(&bundleTrust, "trust", nil, "ed25519 public key whose signature is required (repeatable)")
+	extractCmd.Flags().StringArrayVar(&layoutKeys, "layout-key", nil, "Owner public key the in-toto layout must be signed by (repeatable)")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify <bundle>",
+		Short: "Check a bundle's checksums and signatures",
+		Long: `Checks every file against the index and every signature against the
+index. With --trust, a signature by one of the given public keys (PEM
+PKIX, as openssl pkey -pubout writes, or base64) is required.
+
+With --layout-key, the bundle's in-toto layout must be signed by each
+given owner key and unexpired, every step must have links from enough of
+its functionaries satisfying its artifact rules, and the layout's last
+step must have produced every manifest as bundled. Who performed each
+step is reported per manifest.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBundleVerify,
+	}
+	verifyCmd.Flags().StringArrayVar(&bundleTrust, "trust", nil, "ed25519 public key whose signature is required (repeatable)")
+	verifyCmd.Flags().StringArrayVar(&layoutKeys, "layout-key", nil, "Owner public key the in-toto layout must be signed by (repeatable)")
+
+	attestCmd := &cobra.Command{
+		Use:   "attest <step> <bundle|file...>",
+		Short: "Sign an in-toto link for a supply chain step",
+		Long: `Records performing step, such as authoring or reviewing manifests, in
+an in-toto link signed with --key: its products are the given files, by
+their paths relative to the current directory, or a bundle's workflow and
+agents, by their bundle paths. The link is written to --dir as
+<step>.<key id>.link, ready for bundle create --in-toto.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: runBundleAttest,
+	}
+	attestCmd.Flags().StringVar(&attestKey, "key", "", "ed25519 private key of the functionary")
+	attestCmd.Flags().StringVar(&attestDir, "dir", "in-toto", "Directory to write the link to")
+	attestCmd.MarkFlagRequired("key")
+
+	signLayoutCmd := &cobra.Command{
+		Use:   "sign-layout <layout.json>",
+		Short: "Sign an in-toto layout as a project owner",
+		Long: `Signs an in-toto layout with --key, writing root.layout to --dir. A
+--functionary name=key.pub adds that public key to the layout and
+replaces name in the steps' pubkeys with its key ID, so layouts can be
+written with names.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBundleSignLayout,
+	}
+	signLayoutCmd.Flags().StringVar(&attestKey, "key", "", "ed25519 private key of the owner")
+	signLayoutCmd.Flags().StringVar(&attestDir, "dir", "in-toto", "Directory to write root.layout to")
+	signLayoutCmd.Flags().StringArrayVar(&functionary, "functionary", nil, "name=public key of a functionary (repeatable)")
+	signLayoutCmd.MarkFlagRequired("key")
+
+	bundleCmd.AddCommand(createCmd, extractCmd, verifyCmd, attestCmd, signLayoutCmd)
+	return bundleCmd
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	workflow := args[0]
+	out := bundleOutput
+	if out == "" {
+		name := filepath.Base(workflow)
+		out = strings.TrimSuffix(strings.TrimSuffix(name, filepath.Ext(name)), ".ossa") + bundle.Ext
+	}
+	lockPath := bundleLock
+	if lockPath == "" {
+		lockPath = filepath.Join(filepath.Dir(workflow), resolve.LockFile)
+	}
+
+	var keys []ed25519.PrivateKey
+	for _, path := range bundleKeys {
+		key, err := readPrivateKey(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	// Only a workflow with versioned refs needs the registry.
+	data, err := os.ReadFile(workflow)
+	if err != nil {
+		return err
+	}
+	steps, err := resolve.StepRefs(data)
+	if err != nil {
+		return err
+	}
+	var r *resolve.Resolver
+	for _, s := range steps {
+		if _, versioned, _ := resolve.ParseRef(s.Ref); versioned {
+			reg, err := registryClient()
+			if err != nil {
+				return err
+			}
+			lock, err := resolve.LoadLock(lockPath)
+			if err != nil {
+				return err
+			}
+			r = &resolve.Resolver{Source: &resolve.Registry{URL: reg.URL, Token: reg.Token}, Lock: lock}
+			break
+		}
+	}
+
+	var k *crypt.Keyring
+	if len(bundleTo) > 0 {
+		if k, err = keyring(nil, bundleTo); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	w := bundle.NewWriter(&buf)
+	for _, key := range keys {
+		w.Sign(key)
+	}
+	err = w.AddWorkflowFile(context.Background(), workflow, r)
+	if err == nil && bundleInToto != "" {
+		err = addInToto(w, bundleInToto)
+	}
+	for _, asset := range bundleAssets {
+		if err != nil {
+			break
+		}
+		err = addAsset(w, asset)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+	b, err := bundle.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	data = buf.Bytes()
+	if k != nil {
+		if data, err = k.Encrypt(data); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		return err
+	}
+	printBundle(cmd, b)
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ %s: %d files, %d signatures\n", out, len(b.Index.Entries), len(b.Signatures))
+	if k != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "🔒 encrypted to %d recipients\n", len(k.Recipients))
+	}
+	return nil
+}
+
+// addAsset adds a file, or the files below a directory, under assets/.
+func addAsset(w *bundle.Writer, asset string) error {
+	root := filepath.Dir(filepath.Clean(asset))
+	return filepath.Walk(asset, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s: assets must be regular files", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return w.Add("assets/"+filepath.ToSlash(rel), bundle.KindAsset, data)
+	})
+}
+
+// addInToto adds the layout and links in dir under bundle.InTotoDir.
+func addInToto(w *bundle.Writer, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.link"))
+	if err != nil {
+		return err
+	}
+	files = append([]string{filepath.Join(dir, "root.layout")}, files...)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := w.Add(bundle.InTotoDir+"/"+filepath.Base(path), bundle.KindInToto, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runBundleExtract(cmd *cobra.Command, args []string) error {
+	b, err := openVerified(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	dir := strings.TrimSuffix(filepath.Base(args[0]), bundle.Ext)
+	if len(args) > 1 {
+		dir = args[1]
+	}
+	if err := b.Extract(dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ extracted %d files to %s; run: ossa run %s\n",
+		len(b.Index.Entries), dir, filepath.Join(dir, filepath.FromSlash(b.Index.Workflow)))
+	return nil
+}
+
+func runBundleVerify(cmd *cobra.Command, args []string) error {
+	b, err := openVerified(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	printBundle(cmd, b)
+	return nil
+}
+
+// openVerified opens and verifies a bundle against --trust, reporting who
+// signed it.
+func openVerified(cmd *cobra.Command, path string) (*bundle.Reader, error) {
+	var trusted []ed25519.PublicKey
+	for _, p := range bundleTrust {
+		key, err := readPublicKey(p)
+		if err != nil {
+			return nil, err
+		}
+		trusted = append(trusted, key)
+	}
+	b, err := bundle.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	signers, err := b.Verify(trusted...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case len(signers) > 0:
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ %s verified, signed by %s\n", path, strings.Join(signers, ", "))
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ %s checksums verified\n", path)
+		fmt.Fprintf(cmd.ErrOrStderr(), "⚠ %s is not signed\n", path)
+	}
+
+	if len(layoutKeys) == 0 {
+		if b.HasLayout() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠ %s has an in-toto layout; pass --layout-key to verify it\n", path)
+		}
+		return b, nil
+	}
+	var owners []ed25519.PublicKey
+	for _, p := range layoutKeys {
+		key, err := readPublicKey(p)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, key)
+	}
+	custody, err := b.VerifyLayout(owners, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: in-toto: %w", path, err)
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MANIFEST\tSTEP\tFUNCTIONARY")
+	for _, c := range custody {
+		for _, f := range c.Steps {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Path, f.Step, f.KeyID[:16])
+		}
+	}
+	w.Flush()
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ in-toto chain of custody verified for %d manifests\n", len(custody))
+	return b, nil
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := bundle.ParsePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := bundle.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func runBundleAttest(cmd *cobra.Command, args []string) error {
+	key, err := readPrivateKey(attestKey)
+	if err != nil {
+		return err
+	}
+	step, files := args[0], args[1:]
+	products := map[string][]byte{}
+	if len(files) == 1 && strings.HasSuffix(files[0], bundle.Ext) {
+		b, err := bundle.Open(files[0])
+		if err != nil {
+			return err
+		}
+		if _, err := b.Verify(); err != nil {
+			return err
+		}
+		for _, e := range b.Index.Entries {
+			if e.Kind == bundle.KindWorkflow || e.Kind == bundle.KindAgent {
+				products[e.Path], _ = b.File(e.Path)
+			}
+		}
+	} else {
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			products[filepath.ToSlash(filepath.Clean(path))] = data
+		}
+	}
+	link, err := intoto.Sign(intoto.NewLink(step, nil, products), key)
+	if err != nil {
+		return err
+	}
+	id := intoto.NewKey(key.Public().(ed25519.PublicKey)).ID()
+	out := filepath.Join(attestDir, intoto.LinkName(step, id))
+	if err := writeEnvelope(out, link); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ %s: %s by %s, %d products\n", out, step, id[:16], len(products))
+	return nil
+}
+
+func runBundleSignLayout(cmd *cobra.Command, args []string) error {
+	key, err := readPrivateKey(attestKey)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var layout intoto.Layout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return fmt.Errorf("%s: invalid layout: %w", args[0], err)
+	}
+	if layout.Type == "" {
+		layout.Type = "layout"
+	}
+	if layout.Keys == nil {
+		layout.Keys = map[string]intoto.Key{}
+	}
+	if layout.Inspect == nil {
+		layout.Inspect = []interface{}{}
+	}
+	for _, f := range functionary {
+		name, path, ok := strings.Cut(f, "=")
+		if !ok {
+			return fmt.Errorf("invalid --functionary %q: want name=public key", f)
+		}
+		pub, err := readPublicKey(path)
+		if err != nil {
+			return err
+		}
+		k := intoto.NewKey(pub)
+		layout.Keys[k.ID()] = k
+		for i := range layout.Steps {
+			for j, id := range layout.Steps[i].PubKeys {
+				if id == name {
+					layout.Steps[i].PubKeys[j] = k.ID()
+				}
+			}
+		}
+	}
+	for i := range layout.Steps {
+		if layout.Steps[i].Type == "" {
+			layout.Steps[i].Type = "step"
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, layout.Expires); err != nil {
+		return fmt.Errorf("%s: expires must be an RFC 3339 time", args[0])
+	}
+	env, err := intoto.Sign(&layout, key)
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(attestDir, "root.layout")
+	if err := writeEnvelope(out, env); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ %s: %d steps, signed by %s\n", out, len(layout.Steps), env.Signatures[0].KeyID[:16])
+	return nil
+}
+
+func writeEnvelope(path string, e *intoto.Envelope) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func printBundle(cmd *cobra.Command, b *bundle.Reader) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tPATH\tSIZE\tSHA256")
+	for _, e := range b.Index.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", e.Kind, e.Path, e.Size, e.SHA256[:12])
+	}
+	w.Flush()
+}
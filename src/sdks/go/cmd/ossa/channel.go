@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteVersion string
+	promoteFrom    string
+)
+
+func newChannelCmd() *cobra.Command {
+	channelCmd := &cobra.Command{
+		Use:   "channel",
+		Short: "List and promote an agent's release channels",
+		Long: `Release channels point at an agent's releases so rollouts can be staged:
+publish to beta with ossa publish --channel beta, try it with ossa pull
+--channel beta, then promote it:
+
+  ossa channel promote support-agent stable --from beta
+  ossa channel promote payments/biller beta --version 2.1.0
+
+Promoting to stable makes the release the catalog's manifest.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list <[namespace/]agent>",
+		Short: "Show the release each channel points at",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reg, err := registryClient()
+			if err != nil {
+				return err
+			}
+			namespace, name := splitAgent(args[0])
+			channels, err := reg.Channels(context.Background(), namespace, name)
+			if err != nil {
+				return err
+			}
+			if len(channels) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s/%s has no release channels\n", namespace, name)
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "CHANNEL\tVERSION")
+			for _, c := range channels {
+				fmt.Fprintf(w, "%s\t%s\n", c.Channel, c.Version)
+			}
+			return w.Flush()
+		},
+	}
+	addRegistryFlags(listCmd)
+
+	promoteCmd := &cobra.Command{
+		Use:   "promote <[namespace/]agent> <channel>",
+		Short: "Point a channel at a release",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (promoteVersion == "") == (promoteFrom == "") {
+				return fmt.Errorf("pass one of --version or --from")
+			}
+			reg, err := registryClient()
+			if err != nil {
+				return err
+			}
+			namespace, name := splitAgent(args[0])
+			version, err := reg.Promote(context.Background(), namespace, name, args[1], promoteVersion, promoteFrom)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✅ %s/%s %s → %s\n", namespace, name, args[1], version)
+			return nil
+		},
+	}
+	addRegistryFlags(promoteCmd)
+	promoteCmd.Flags().StringVar(&promoteVersion, "version", "", "Release to promote")
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "Promote the release another channel points at")
+
+	channelCmd.AddCommand(listCmd, promoteCmd)
+	return channelCmd
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/internal/config"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+// settings is the loaded config, set before any command other than ossa
+// config runs.
+var settings *config.Config
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Get and set CLI settings",
+		Long: `Reads and writes CLI settings in ~/.config/ossa/config.yaml ($OSSA_CONFIG
+overrides the path). Each setting can also come from an OSSA_* environment
+variable, which wins over the file; flags win over both.`,
+	}
+
+	getCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the effective value of a setting",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			value, _, err := cfg.Get(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), value)
+			return nil
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Store a setting in the config file (an empty value unsets it)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, path, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := cfg.Set(args[0], args[1]); err != nil {
+				return err
+			}
+			return cfg.Save(path)
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all settings with their values and sources",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, path, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "# %s\n", path)
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV\tDESCRIPTION")
+			for _, k := range config.Keys {
+				value, source, err := cfg.Get(k.Name)
+				if err != nil {
+					value, source = err.Error(), "invalid"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", k.Name, orDash(value), source, k.Env, k.Help)
+			}
+			return w.Flush()
+		},
+	}
+
+	configCmd.AddCommand(getCmd, setCmd, listCmd)
+	return configCmd
+}
+
+func loadConfig() (*config.Config, string, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, path, nil
+}
+
+// configExempt reports whether cmd is under ossa config or ossa schema.
+func configExempt(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if (c.Name() == "config" || c.Name() == "schema") && c.HasParent() {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConfig fills in flag defaults from the config and rejects invalid
+// settings. ossa config and ossa schema are exempt so they can repair them.
+func applyConfig(cmd *cobra.Command) error {
+	if configExempt(cmd) {
+		return nil
+	}
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	settings = cfg
+	version, _, err := cfg.Get("schema_version")
+	if err != nil {
+		return err
+	}
+	if _, err := ossa.ResolveSchema(ossa.FindSchemaStore("."), version); err != nil {
+		return fmt.Errorf("schema_version %s: %w", version, err)
+	}
+	output, _, err := cfg.Get("output")
+	if err != nil {
+		return err
+	}
+	if f := cmd.Flags().Lookup("json"); f != nil && !f.Changed && output == "json" {
+		outputJSON = true
+	}
+	if path := cfg.Value("model_policy"); path != "" {
+		policy, err := ossa.LoadModelPolicy(path)
+		if err != nil {
+			return fmt.Errorf("model_policy: %w", err)
+		}
+		ossa.RegisterRule(policy.Rule())
+	}
+	if path := cfg.Value("endpoint_policy"); path != "" {
+		policy, err := ossa.LoadEndpointPolicy(path)
+		if err != nil {
+			return fmt.Errorf("endpoint_policy: %w", err)
+		}
+		ossa.RegisterRule(policy.Rule())
+		endpointPolicy = policy
+	}
+	return applyIdentities(cfg)
+}
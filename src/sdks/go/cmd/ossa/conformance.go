@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/junit"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	conformanceCorpus string
+	conformanceFormat string
+)
+
+func newConformanceCmd() *cobra.Command {
+	conformanceCmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "Generate and run the cross-SDK conformance corpus",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate [dir]",
+		Short: "Write valid and invalid manifest fixtures",
+		Long: `Writes manifest fixtures derived from the embedded schema into dir
+(default ./conformance), together with expected.json listing whether the
+schema accepts each one.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runConformanceGenerate,
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run <command> [args...]",
+		Short: "Check a validator against the corpus",
+		Long: `Runs command once per fixture and compares its verdict with
+expected.json. The fixture path replaces {} in the arguments, or is appended
+when no argument is {}. Exit status 0 means valid, any other status invalid.
+--format junit reports a testcase per fixture for CI.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runConformanceRun,
+	}
+	runCmd.Flags().StringVar(&conformanceCorpus, "corpus", "conformance", "Corpus directory")
+	runCmd.Flags().StringVar(&conformanceFormat, "format", "text", "Output format: text or junit")
+	runCmd.Flags().SetInterspersed(false)
+
+	conformanceCmd.AddCommand(generateCmd, runCmd)
+	return conformanceCmd
+}
+
+func runConformanceGenerate(cmd *cobra.Command, args []string) error {
+	dir := "conformance"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	cases, err := ossa.GenerateConformanceCases(ossa.EmbeddedSchema())
+	if err != nil {
+		return err
+	}
+	if err := ossa.WriteConformanceCorpus(dir, cases); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d fixtures to %s\n", len(cases), dir)
+	return nil
+}
+
+func runConformanceRun(cmd *cobra.Command, args []string) error {
+	if conformanceFormat != "text" && conformanceFormat != "junit" {
+		return fmt.Errorf("invalid --format %s: use text or junit", conformanceFormat)
+	}
+	validate := func(ctx context.Context, path string) (bool, error) {
+		argv := make([]string, 0, len(args)+1)
+		substituted := false
+		for _, a := range args {
+			if a == "{}" {
+				a = path
+				substituted = true
+			}
+			argv = append(argv, a)
+		}
+		if !substituted {
+			argv = append(argv, path)
+		}
+		c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		c.Stderr = os.Stderr
+		err := c.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	results, err := ossa.RunConformance(cmd.Context(), conformanceCorpus, validate)
+	if err != nil {
+		return err
+	}
+	if conformanceFormat == "junit" {
+		return conformanceJUnit(cmd, results)
+	}
+	var failed []string
+	for _, r := range results {
+		if r.Pass() {
+			continue
+		}
+		switch {
+		case r.Err != nil:
+			failed = append(failed, fmt.Sprintf("  • %s: %v", r.Case.Name, r.Err))
+		default:
+			failed = append(failed, fmt.Sprintf("  • %s: expected valid=%t, got valid=%t (%s)", r.Case.Name, r.Case.Valid, r.Got, r.Case.Description))
+		}
+	}
+	if len(failed) == 0 {
+		fmt.Printf("✅ %d/%d cases passed\n", len(results), len(results))
+		return nil
+	}
+	fmt.Printf("❌ %d/%d cases failed\n%s\n", len(failed), len(results), strings.Join(failed, "\n"))
+	return fmt.Errorf("conformance failed")
+}
+
+// conformanceJUnit reports results as a JUnit suite, a testcase per
+// fixture.
+func conformanceJUnit(cmd *cobra.Command, results []ossa.ConformanceResult) error {
+	suite := junit.Suite{Name: "ossa conformance"}
+	for _, r := range results {
+		c := junit.Case{Name: r.Case.Name, ClassName: "ossa.conformance"}
+		switch {
+		case r.Pass():
+		case r.Err != nil:
+			c.Error = &junit.Problem{Message: "validator did not run", Text: r.Err.Error()}
+		default:
+			c.Failure = &junit.Problem{Message: fmt.Sprintf("expected valid=%t, got valid=%t", r.Case.Valid, r.Got), Text: r.Case.Description}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	report := &junit.Report{}
+	report.Add(suite)
+	if err := writeJUnit(cmd, report); err != nil {
+		return err
+	}
+	if report.Failed() {
+		return fmt.Errorf("conformance failed")
+	}
+	return nil
+}
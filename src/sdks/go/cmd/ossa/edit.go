@@ -0,0 +1,432 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// editSections are the top-level forms offered by ossa edit.
+var editSections = []string{"metadata", "spec.llm", "spec.tools", "spec.safety"}
+
+// editMaxErrors bounds the validation errors shown under a form.
+const editMaxErrors = 5
+
+func newEditCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit [manifest]",
+		Short: "Edit a manifest interactively",
+		Long: `Opens a manifest in a terminal UI with forms for metadata, llm, tools and
+safety built from the schema. The manifest is validated after every change,
+and writing it back keeps comments and key order.
+
+Keys: ↑/↓ move, enter opens a section or edits a field, esc goes back, a adds
+a list item, d deletes the selected item or field, w writes the manifest and
+q quits, asking first if there are unsaved changes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runEdit,
+	}
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	doc, err := ossa.LoadDocument(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	kind, _ := doc.Get("kind")
+	kindStr, _ := kind.(string)
+	m := &editModel{path: args[0], doc: doc, kind: ossa.Kind(kindStr), input: textinput.New()}
+	m.push("", false)
+	m.validate()
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithInput(cmd.InOrStdin()), tea.WithOutput(cmd.OutOrStdout())).Run()
+	return err
+}
+
+// editItem is one row of a form: a section, a field or a list element.
+type editItem struct {
+	label string
+	path  string
+	typ   string
+	field *ossa.FieldSummary
+}
+
+// opens reports whether the item is a form of its own rather than a value.
+func (it editItem) opens() bool {
+	return it.field == nil || strings.HasPrefix(it.typ, "object") || strings.HasPrefix(it.typ, "[]object")
+}
+
+// editForm is an open form: the sections at "", the fields of an object
+// or the elements of a list.
+type editForm struct {
+	path   string
+	list   bool
+	items  []editItem
+	cursor int
+	err    error
+}
+
+// editModel is the bubbletea model of ossa edit.
+type editModel struct {
+	path  string
+	doc   *ossa.Document
+	kind  ossa.Kind
+	forms []*editForm
+
+	// editing is the field being typed into, if any.
+	editing *editItem
+	input   textinput.Model
+
+	result     *ossa.ValidationResult
+	invalid    error
+	message    string
+	dirty      bool
+	confirming bool
+}
+
+func (m *editModel) Init() tea.Cmd { return nil }
+
+func (m *editModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if m.editing != nil {
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+	if key.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+	if m.editing != nil {
+		return m.updateInput(key)
+	}
+	if m.confirming {
+		m.confirming = false
+		if key.String() == "y" {
+			return m, tea.Quit
+		}
+		m.message = ""
+		return m, nil
+	}
+
+	form := m.forms[len(m.forms)-1]
+	m.message = ""
+	switch key.String() {
+	case "up", "k":
+		if form.cursor > 0 {
+			form.cursor--
+		}
+	case "down", "j":
+		if form.cursor < len(form.items)-1 {
+			form.cursor++
+		}
+	case "enter", "right", "l":
+		if form.cursor < len(form.items) {
+			m.open(form.items[form.cursor])
+		}
+	case "esc", "left", "h", "backspace":
+		if len(m.forms) > 1 {
+			m.forms = m.forms[:len(m.forms)-1]
+			m.refresh()
+		}
+	case "a":
+		if form.list {
+			elem := fmt.Sprintf("%s.%d", form.path, m.doc.Len(form.path))
+			if m.apply(elem, map[string]interface{}{}, false) {
+				m.push(elem, false)
+			}
+		}
+	case "d":
+		if form.cursor < len(form.items) && form.path != "" {
+			if item := form.items[form.cursor]; form.list || m.has(item.path) {
+				m.apply(item.path, nil, true)
+			}
+		}
+	case "w":
+		if err := m.doc.Save(m.path); err != nil {
+			m.message = "❌ " + err.Error()
+		} else {
+			m.dirty = false
+			m.message = "✅ Wrote " + m.path
+		}
+	case "q":
+		if !m.dirty {
+			return m, tea.Quit
+		}
+		m.confirming = true
+		m.message = "Discard unsaved changes? [y/N]"
+	}
+	return m, nil
+}
+
+// updateInput handles a key while a field is being edited: enter applies
+// the value, esc cancels.
+func (m *editModel) updateInput(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.Type {
+	case tea.KeyEnter:
+		item := m.editing
+		m.editing = nil
+		value, err := parseValue(strings.TrimSpace(m.input.Value()), item.typ)
+		if err != nil {
+			m.message = "❌ " + err.Error()
+			return m, nil
+		}
+		m.apply(item.path, value, false)
+		return m, nil
+	case tea.KeyEsc:
+		m.editing = nil
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(key)
+	return m, cmd
+}
+
+// open enters the form of item, or starts editing its value.
+func (m *editModel) open(item editItem) {
+	if item.opens() {
+		m.push(item.path, item.path == "spec.tools" || strings.HasPrefix(item.typ, "[]object"))
+		return
+	}
+	m.editing = &item
+	m.input.SetValue("")
+	if v, ok := m.doc.Get(item.path); ok {
+		m.input.SetValue(inputValue(v))
+	}
+	m.input.CursorEnd()
+	m.input.Focus()
+}
+
+// push opens the form at path, listing its elements if list is set.
+func (m *editModel) push(path string, list bool) {
+	m.forms = append(m.forms, &editForm{path: path, list: list})
+	m.refresh()
+}
+
+// refresh rebuilds the items of the open form from the document and the
+// schema.
+func (m *editModel) refresh() {
+	form := m.forms[len(m.forms)-1]
+	form.items, form.err = nil, nil
+	switch {
+	case form.path == "":
+		for _, s := range editSections {
+			form.items = append(form.items, editItem{label: s, path: s})
+		}
+	case form.list:
+		for i := 0; i < m.doc.Len(form.path); i++ {
+			elem := fmt.Sprintf("%s.%d", form.path, i)
+			v, _ := m.doc.Get(elem)
+			form.items = append(form.items, editItem{label: listLabel(v), path: elem})
+		}
+	default:
+		info, err := ossa.ExplainPath(ossa.EmbeddedSchema(), fieldPath(form.path), m.kind)
+		if err != nil {
+			form.err = err
+			break
+		}
+		for i := range info.Fields {
+			f := &info.Fields[i]
+			form.items = append(form.items, editItem{label: f.Name, path: form.path + "." + f.Name, typ: f.Type, field: f})
+		}
+	}
+	if form.cursor >= len(form.items) {
+		form.cursor = max(len(form.items)-1, 0)
+	}
+}
+
+func (m *editModel) has(path string) bool {
+	_, ok := m.doc.Get(path)
+	return ok
+}
+
+// apply sets or deletes path and validates the result.
+func (m *editModel) apply(path string, value interface{}, remove bool) bool {
+	var err error
+	if remove {
+		err = m.doc.Delete(path)
+	} else {
+		err = m.doc.Set(path, value)
+	}
+	if err != nil {
+		m.message = "❌ " + err.Error()
+		return false
+	}
+	m.dirty = true
+	m.validate()
+	m.refresh()
+	return true
+}
+
+func (m *editModel) validate() {
+	m.result, m.invalid = m.doc.Validate()
+}
+
+func (m *editModel) View() string {
+	var b strings.Builder
+	form := m.forms[len(m.forms)-1]
+	title := m.path
+	if m.dirty {
+		title += " (modified)"
+	}
+	fmt.Fprintf(&b, "ossa edit %s\n", title)
+	if form.path != "" {
+		fmt.Fprintf(&b, "%s\n", form.path)
+	}
+	b.WriteString("\n")
+
+	if form.err != nil {
+		fmt.Fprintf(&b, "❌ %v\n", form.err)
+	}
+	if len(form.items) == 0 && form.list {
+		b.WriteString("  (empty)\n")
+	}
+	for i, it := range form.items {
+		cursor := "  "
+		if i == form.cursor {
+			cursor = "> "
+		}
+		line := it.label
+		if it.field != nil {
+			if it.field.Required {
+				line += "*"
+			}
+			line += " <" + it.typ + ">"
+			if v, ok := m.doc.Get(it.path); ok {
+				line += " = " + formatValue(v)
+			}
+		}
+		b.WriteString(cursor + line + "\n")
+	}
+	if form.cursor < len(form.items) {
+		if f := form.items[form.cursor].field; f != nil && f.Description != "" {
+			fmt.Fprintf(&b, "\n%s\n", f.Description)
+		}
+	}
+
+	b.WriteString("\n")
+	switch {
+	case m.invalid != nil:
+		fmt.Fprintf(&b, "❌ %v\n", m.invalid)
+	case !m.result.Valid:
+		fmt.Fprintf(&b, "❌ %d errors\n", len(m.result.Errors))
+		for i, msg := range m.result.Errors {
+			if i == editMaxErrors {
+				fmt.Fprintf(&b, "  … and %d more\n", len(m.result.Errors)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  • %s\n", msg)
+		}
+	default:
+		fmt.Fprintf(&b, "✅ valid (%d warnings)\n", len(m.result.Warnings))
+	}
+	if m.message != "" {
+		fmt.Fprintf(&b, "%s\n", m.message)
+	}
+
+	b.WriteString("\n")
+	if m.editing != nil {
+		fmt.Fprintf(&b, "%s <%s>: %s\n", m.editing.label, m.editing.typ, m.input.View())
+		b.WriteString("enter apply  esc cancel\n")
+		return b.String()
+	}
+	help := "↑/↓ move  enter open/edit  esc back  d delete  w write  q quit"
+	if form.list {
+		help = "↑/↓ move  enter open  esc back  a add  d delete  w write  q quit"
+	}
+	b.WriteString(help + "\n")
+	return b.String()
+}
+
+// fieldPath drops list indices from a document path.
+func fieldPath(path string) string {
+	var segs []string
+	for _, seg := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(seg); err != nil {
+			segs = append(segs, seg)
+		}
+	}
+	return strings.Join(segs, ".")
+}
+
+// parseValue converts input to the JSON type the schema expects.
+func parseValue(input, typ string) (interface{}, error) {
+	switch typ {
+	case "boolean":
+		return strconv.ParseBool(input)
+	case "integer":
+		return strconv.Atoi(input)
+	case "number":
+		return strconv.ParseFloat(input, 64)
+	case "[]string":
+		var list []string
+		for _, item := range strings.Split(input, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case "map[string]string":
+		m := map[string]string{}
+		for _, pair := range strings.Split(input, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, fmt.Errorf("expected key=value pairs, got %q", pair)
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+	return input, nil
+}
+
+// inputValue is v as parseValue reads it back, to start editing from.
+func inputValue(v interface{}) string {
+	switch t := v.(type) {
+	case []interface{}:
+		items := make([]string, len(t))
+		for i, item := range t {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ", ")
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(t))
+		for k, item := range t {
+			pairs = append(pairs, k+"="+fmt.Sprint(item))
+		}
+		return strings.Join(pairs, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return fmt.Sprintf("{%d fields}", len(t))
+	case []interface{}:
+		return fmt.Sprintf("[%d items]", len(t))
+	}
+	return fmt.Sprint(v)
+}
+
+// listLabel names a list element by its name or id and type.
+func listLabel(v interface{}) string {
+	m, _ := v.(map[string]interface{})
+	label := "(unnamed)"
+	for _, key := range []string{"name", "id"} {
+		if s, ok := m[key].(string); ok && s != "" {
+			label = s
+			break
+		}
+	}
+	if t, ok := m["type"].(string); ok {
+		label += " (" + t + ")"
+	}
+	return label
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/crypt"
+	"github.com/blueflyio/ossa-go/internal/config"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	identityFlags []string
+	recipients    []string
+	cryptOutput   string
+)
+
+func newEncryptCmd() *cobra.Command {
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt <file...>",
+		Short: "Encrypt manifests at rest for age recipients or KMS keys",
+		Long: `Encrypts each file in place, in the age format, to every --recipient:
+an age public key (age1..., as age-keygen or ossa encrypt keygen print)
+or a KMS key URI (gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K
+or vault://[mount/]key). Encrypted manifests keep their names, so
+workspaces, registries and bundles hold them as before, and every command
+decrypts them transparently for holders of an --identity (or the identity
+setting): an age identity file or a KMS key URI the caller is authorized
+to decrypt with.
+
+With --output, a single file is written there instead.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runEncrypt,
+	}
+	encryptCmd.Flags().StringArrayVarP(&recipients, "recipient", "r", nil, "age public key or KMS key URI to encrypt to (repeatable)")
+	encryptCmd.Flags().StringVarP(&cryptOutput, "output", "o", "", "Write the encrypted file here instead of in place")
+
+	keygenCmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate an age identity",
+		Long: `Writes a new age identity to --output (default stdout), in the format
+age-keygen writes, and prints its public key, the recipient to encrypt
+to.`,
+		Args: cobra.NoArgs,
+		RunE: runKeygen,
+	}
+	keygenCmd.Flags().StringVarP(&cryptOutput, "output", "o", "", "Identity file to write (created 0600)")
+	encryptCmd.AddCommand(keygenCmd)
+	return encryptCmd
+}
+
+func newDecryptCmd() *cobra.Command {
+	decryptCmd := &cobra.Command{
+		Use:   "decrypt <file>",
+		Short: "Decrypt an encrypted manifest",
+		Long: `Decrypts a file encrypted by ossa encrypt, or by age, with the --identity
+files and KMS keys, printing it or writing it to --output.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runDecrypt,
+	}
+	decryptCmd.Flags().StringVarP(&cryptOutput, "output", "o", "", "Write the decrypted file here instead of stdout")
+	return decryptCmd
+}
+
+// applyIdentities makes encrypted manifests and bundles readable with the
+// --identity flags, or else the identity setting.
+func applyIdentities(cfg *config.Config) error {
+	ids := identityFlags
+	if len(ids) == 0 {
+		for _, id := range strings.Split(cfg.Value("identity"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	k, err := keyring(ids, nil)
+	if err != nil {
+		return fmt.Errorf("identity: %w", err)
+	}
+	ossa.SetDecrypter(k.Decrypt)
+	return nil
+}
+
+// keyring parses identities and recipients.
+func keyring(identities, recipients []string) (*crypt.Keyring, error) {
+	k := &crypt.Keyring{}
+	for _, id := range identities {
+		if err := k.AddIdentity(id); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range recipients {
+		if err := k.AddRecipient(r); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func runEncrypt(cmd *cobra.Command, args []string) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("--recipient is required")
+	}
+	if cryptOutput != "" && len(args) > 1 {
+		return fmt.Errorf("--output takes a single file")
+	}
+	k, err := keyring(nil, recipients)
+	if err != nil {
+		return err
+	}
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if crypt.IsEncrypted(data) {
+			return fmt.Errorf("%s is already encrypted", path)
+		}
+		enc, err := k.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		out := path
+		if cryptOutput != "" {
+			out = cryptOutput
+		}
+		if err := os.WriteFile(out, enc, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "🔒 %s: encrypted to %d recipients\n", out, len(k.Recipients))
+	}
+	return nil
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if !crypt.IsEncrypted(data) {
+		return fmt.Errorf("%s is not encrypted", args[0])
+	}
+	plain, err := ossa.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if cryptOutput == "" {
+		_, err = cmd.OutOrStdout().Write(plain)
+		return err
+	}
+	return os.WriteFile(cryptOutput, plain, 0o600)
+}
+
+func runKeygen(cmd *cobra.Command, args []string) error {
+	id, err := crypt.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+	data := fmt.Sprintf("# public key: %s\n%s\n", id.Recipient(), id)
+	if cryptOutput == "" {
+		fmt.Fprint(cmd.OutOrStdout(), data)
+		return nil
+	}
+	f, err := os.OpenFile(cryptOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Public key: %s\n", id.Recipient())
+	return nil
+}
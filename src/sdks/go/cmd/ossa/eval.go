@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/eval"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+	"github.com/spf13/cobra"
+)
+
+var (
+	evalAgent  string
+	evalFormat string
+	evalReport string
+	evalMargin float64
+)
+
+func newEvalCmd() *cobra.Command {
+	evalCmd := &cobra.Command{
+		Use:   "eval [suite|dir...]",
+		Short: "Score an agent against *.ossaeval.yaml suites",
+		Long: `Runs each case of each suite on the suite's agent and grades its output
+with the suite's graders: exact, regex, judge (a model scores the output
+against criteria, with the suite's judge model) and func, which the ossa
+CLI has none of; func graders are for Go programs using package eval.
+Directories are searched for *.ossaeval.yaml files.
+
+Fails unless every suite reaches its threshold. --format json or junit
+writes the reports in that form, to --report or stdout; JUnit XML shows
+each case as a test in CI.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runEval,
+	}
+	evalCmd.Flags().StringVar(&evalAgent, "agent", "", "Agent manifest to evaluate instead of each suite's agent")
+	evalCmd.Flags().StringVar(&evalFormat, "format", "text", "Report format: text, json or junit")
+	evalCmd.Flags().StringVar(&evalReport, "report", "", "Write the json or junit report to a file")
+	evalCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	evalCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	evalCmd.AddCommand(newEvalCompareCmd())
+	return evalCmd
+}
+
+func newEvalCompareCmd() *cobra.Command {
+	compareCmd := &cobra.Command{
+		Use:   "compare <suite> <base> <candidate>",
+		Short: "Compare two versions of an agent on a suite",
+		Long: `Runs a suite on a base and a candidate version of an agent, each a
+manifest path or a registry ref such as support-agent@1.2.0, and compares
+them case by case: wins, losses and ties for the candidate, the mean score
+delta with its standard error, and a sign test of whether the split of
+wins and losses is more than chance.
+
+The verdict is improved or regressed when the sign test's p-value is below
+0.05, else unchanged. Deltas within --margin are ties, so noisy judge
+scores need not decide the verdict. Fails if the candidate regressed.`,
+		Args: cobra.ExactArgs(3),
+		RunE: runEvalCompare,
+	}
+	addRegistryFlags(compareCmd)
+	compareCmd.Flags().Float64Var(&evalMargin, "margin", 0, "Score delta within which a case is a tie")
+	compareCmd.Flags().StringVar(&evalFormat, "format", "text", "Report format: text or json")
+	compareCmd.Flags().StringVar(&evalReport, "report", "", "Write the json comparison to a file")
+	compareCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	compareCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	return compareCmd
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	if evalFormat != "text" && evalFormat != "json" && evalFormat != "junit" {
+		return fmt.Errorf("invalid --format %s: use text, json or junit", evalFormat)
+	}
+	paths, err := evalPaths(args)
+	if err != nil {
+		return err
+	}
+	var reports []*eval.Report
+	failed := 0
+	for _, path := range paths {
+		report, err := runSuite(cmd, path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		if !report.Passed {
+			failed++
+		}
+		if evalFormat == "text" || evalReport != "" {
+			printReport(cmd, report)
+		}
+	}
+
+	if evalFormat != "text" {
+		var out []byte
+		if evalFormat == "junit" {
+			out, err = eval.JUnit(reports...)
+		} else {
+			out, err = json.MarshalIndent(reports, "", "  ")
+			out = append(out, '\n')
+		}
+		if err != nil {
+			return err
+		}
+		if evalReport != "" {
+			err = os.WriteFile(evalReport, out, 0o644)
+		} else {
+			_, err = cmd.OutOrStdout().Write(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d suites failed", failed, len(reports))
+	}
+	return nil
+}
+
+func runEvalCompare(cmd *cobra.Command, args []string) error {
+	if evalFormat != "text" && evalFormat != "json" {
+		return fmt.Errorf("invalid --format %s: use text or json", evalFormat)
+	}
+	suite, err := eval.LoadSuite(args[0])
+	if err != nil {
+		return err
+	}
+	var reports [2]*eval.Report
+	for i, agent := range args[1:] {
+		m, dir, err := loadEvalAgent(agent)
+		if err != nil {
+			return err
+		}
+		if reports[i], err = evalAgentOn(cmd, suite, m, dir); err != nil {
+			return err
+		}
+	}
+	cmp, err := eval.Compare(reports[0], reports[1], evalMargin)
+	if err != nil {
+		return err
+	}
+
+	if evalFormat == "json" || evalReport != "" {
+		out, err := json.MarshalIndent(cmp, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = append(out, '\n')
+		if evalReport != "" {
+			err = os.WriteFile(evalReport, out, 0o644)
+		} else {
+			_, err = cmd.OutOrStdout().Write(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if evalFormat == "text" {
+		printComparison(cmd, args[1], args[2], cmp)
+	}
+	if cmp.Verdict == eval.VerdictRegressed {
+		return fmt.Errorf("%s regressed on %s", args[2], cmp.Suite)
+	}
+	return nil
+}
+
+// runSuite runs the suite at path on its agent, or --agent.
+func runSuite(cmd *cobra.Command, path string) (*eval.Report, error) {
+	suite, err := eval.LoadSuite(path)
+	if err != nil {
+		return nil, err
+	}
+	agentPath := evalAgent
+	if agentPath == "" {
+		agentPath = suite.AgentPath()
+	}
+	if agentPath == "" {
+		return nil, fmt.Errorf("%s: the suite names no agent; pass --agent", path)
+	}
+	m, err := ossa.LoadManifest(agentPath)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ := filepath.Abs(filepath.Dir(agentPath))
+	return evalAgentOn(cmd, suite, m, dir)
+}
+
+// loadEvalAgent loads a manifest path, or fetches a versioned registry ref
+// such as support-agent@1.2.0; registry agents run in the working
+// directory.
+func loadEvalAgent(arg string) (*ossa.Manifest, string, error) {
+	ref, versioned, err := resolve.ParseRef(arg)
+	if err != nil {
+		return nil, "", err
+	}
+	if !versioned {
+		m, err := ossa.LoadManifest(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		dir, _ := filepath.Abs(filepath.Dir(arg))
+		return m, dir, nil
+	}
+	reg, err := registryClient()
+	if err != nil {
+		return nil, "", err
+	}
+	r := &resolve.Resolver{Source: &resolve.Registry{URL: reg.URL, Token: reg.Token}}
+	res, err := r.Resolve(context.Background(), ref)
+	if err != nil {
+		return nil, "", err
+	}
+	dir, _ := os.Getwd()
+	return res.Manifest, dir, nil
+}
+
+// evalAgentOn runs suite on m, with tools relative to dir.
+func evalAgentOn(cmd *cobra.Command, suite *eval.Suite, m *ossa.Manifest, dir string) (*eval.Report, error) {
+	if m.Kind != ossa.KindAgent {
+		return nil, fmt.Errorf("%s is a %s, not an Agent", m.Metadata.Name, m.Kind)
+	}
+	e := newEngine(dir, promptApproval(cmd.InOrStdin(), cmd.ErrOrStderr()))
+	r := &eval.Runner{Engine: e}
+	return r.Run(context.Background(), suite, m)
+}
+
+// evalPaths expands directories to the suites below them.
+func evalPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		found := 0
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && path != arg && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), eval.FileSuffix) {
+				paths = append(paths, path)
+				found++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("no *%s files in %s", eval.FileSuffix, arg)
+		}
+	}
+	return paths, nil
+}
+
+func printReport(cmd *cobra.Command, r *eval.Report) {
+	w := cmd.ErrOrStderr()
+	if evalFormat == "text" && evalReport == "" {
+		w = cmd.OutOrStdout()
+	}
+	for _, c := range r.Cases {
+		mark := "✅"
+		if !c.Passed {
+			mark = "❌"
+		}
+		fmt.Fprintf(w, "%s %s/%s  %.2f\n", mark, r.Suite, c.Name, c.Score)
+		if c.Error != "" {
+			fmt.Fprintf(w, "   error: %s\n", c.Error)
+		}
+		for _, g := range c.Grades {
+			if !g.Passed {
+				fmt.Fprintf(w, "   %s %.2f: %s\n", g.Grader, g.Score, g.Reason)
+			}
+		}
+	}
+	verdict := "passed"
+	if !r.Passed {
+		verdict = "failed"
+	}
+	fmt.Fprintf(w, "%s %s: %d of %d cases passed, score %.2f (threshold %.2f), %d tokens\n",
+		verdict, r.Suite, len(r.Cases)-len(r.Failed()), len(r.Cases), r.Score, r.Threshold, r.Usage.InputTokens+r.Usage.OutputTokens)
+}
+
+func printComparison(cmd *cobra.Command, base, candidate string, c *eval.Comparison) {
+	w := cmd.OutOrStdout()
+	for _, d := range c.Cases {
+		mark := "="
+		switch d.Outcome {
+		case eval.OutcomeWin:
+			mark = "▲"
+		case eval.OutcomeLoss:
+			mark = "▼"
+		}
+		fmt.Fprintf(w, "%s %s/%s  %.2f -> %.2f (%+.2f)\n", mark, c.Suite, d.Name, d.Base, d.Candidate, d.Delta)
+	}
+	fmt.Fprintf(w, "%s: %.2f -> %.2f, %d wins, %d losses, %d ties\n", c.Suite, c.Base.Score, c.Candidate.Score, c.Wins, c.Losses, c.Ties)
+	fmt.Fprintf(w, "mean delta %+.3f ± %.3f, sign test p = %.3f: %s %s against %s\n", c.Delta, c.StdErr, c.PValue, candidate, c.Verdict, base)
+}
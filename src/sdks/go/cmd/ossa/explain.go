@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainKind   string
+	explainSchema string
+)
+
+func newExplainCmd() *cobra.Command {
+	explainCmd := &cobra.Command{
+		Use:   "explain [path]",
+		Short: "Describe a manifest field from the schema",
+		Long: `Prints the description, type, constraints and examples of a manifest field,
+read from the embedded schema, followed by its fields if it is an object. Paths
+are dotted, e.g. spec.safety.guardrails; lists are entered implicitly, so
+spec.tools.type is the type of each tool.`,
+		Example: "  ossa explain spec.safety.guardrails\n  ossa explain spec.steps --kind Workflow",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runExplain,
+	}
+	explainCmd.Flags().StringVar(&explainKind, "kind", "", "Explain spec fields for this kind (default all kinds)")
+	explainCmd.Flags().StringVar(&explainSchema, "schema", "", "Schema file (defaults to embedded v0.3.3)")
+	explainCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output as JSON")
+	return explainCmd
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	kind := ossa.Kind(explainKind)
+	if kind != "" && !ossa.ValidKinds[kind] {
+		return fmt.Errorf("invalid kind: %s", kind)
+	}
+	schema, err := readSchema(explainSchema)
+	if err != nil {
+		return err
+	}
+	e, err := ossa.ExplainPath(schema, args[0], kind)
+	if err != nil {
+		return err
+	}
+
+	if outputJSON {
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	required := ""
+	if e.Required {
+		required = " (required)"
+	}
+	fmt.Printf("FIELD:    %s <%s>%s\n", e.Path, e.Type, required)
+	if e.Description != "" {
+		fmt.Printf("\nDESCRIPTION:\n    %s\n", e.Description)
+	}
+	if len(e.Enum) > 0 {
+		fmt.Printf("\nVALUES:\n")
+		for _, v := range e.Enum {
+			fmt.Printf("    %v\n", v)
+		}
+	}
+	if e.Default != nil {
+		fmt.Printf("\nDEFAULT:\n    %v\n", e.Default)
+	}
+	if len(e.Constraints) > 0 {
+		fmt.Printf("\nCONSTRAINTS:\n    %s\n", strings.Join(e.Constraints, "\n    "))
+	}
+	if len(e.Examples) > 0 {
+		fmt.Printf("\nEXAMPLES:\n")
+		for _, v := range e.Examples {
+			data, _ := json.Marshal(v)
+			fmt.Printf("    %s\n", data)
+		}
+	}
+	if len(e.Fields) > 0 {
+		fmt.Printf("\nFIELDS:\n")
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, f := range e.Fields {
+			req := ""
+			if f.Required {
+				req = " (required)"
+			}
+			fmt.Fprintf(w, "    %s\t<%s>%s\t%s\n", f.Name, f.Type, req, summary(f.Description))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// summary shortens a field description to one line for the FIELDS table.
+func summary(desc string) string {
+	if i := strings.IndexByte(desc, '\n'); i >= 0 {
+		desc = desc[:i]
+	}
+	if r := []rune(desc); len(r) > 72 {
+		desc = string(r[:69]) + "..."
+	}
+	return desc
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var fuzzCorpusFormat string
+
+func newFuzzCorpusCmd() *cobra.Command {
+	fuzzCmd := &cobra.Command{
+		Use:   "fuzz-corpus",
+		Short: "Manage seed corpora for fuzzing manifest ingestion",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [dir]",
+		Short: "Write seed manifests for fuzzers",
+		Long: `Writes seed manifests into dir (default ./corpus). The raw format writes
+one input per file, as go-fuzz and libFuzzer expect; the go format writes
+native Go fuzzing corpus files, for use under testdata/fuzz/<FuzzTarget>.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runFuzzCorpusExport,
+	}
+	exportCmd.Flags().StringVar(&fuzzCorpusFormat, "format", "raw", "Corpus format: raw or go")
+
+	fuzzCmd.AddCommand(exportCmd)
+	return fuzzCmd
+}
+
+func runFuzzCorpusExport(cmd *cobra.Command, args []string) error {
+	if fuzzCorpusFormat != "raw" && fuzzCorpusFormat != "go" {
+		return fmt.Errorf("unsupported format: %s", fuzzCorpusFormat)
+	}
+	dir := "corpus"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	seeds, err := ossa.FuzzSeeds()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create corpus directory: %w", err)
+	}
+	for _, s := range seeds {
+		data := s.Data
+		if fuzzCorpusFormat == "go" {
+			data = []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", s.Data))
+		}
+		if err := os.WriteFile(filepath.Join(dir, s.Name), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write seed: %w", err)
+		}
+	}
+	fmt.Printf("Wrote %d seeds to %s\n", len(seeds), dir)
+	return nil
+}
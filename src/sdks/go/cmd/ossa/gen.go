@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/internal/gentypes"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genOutput  string
+	genSchema  string
+	genPackage string
+	genLang    string
+)
+
+func newGenCmd() *cobra.Command {
+	genCmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate code and definitions from the OSSA schema",
+	}
+
+	cueCmd := &cobra.Command{
+		Use:   "cue",
+		Short: "Generate CUE definitions",
+		Long: `Translates the embedded JSON Schema into CUE definitions (#Agent, #Task,
+#Workflow, #Manifest and one per schema definition) in package ossa.`,
+		Args: cobra.NoArgs,
+		RunE: runGenCUE,
+	}
+	cueCmd.Flags().StringVarP(&genOutput, "output", "o", "", "Write to this file instead of stdout")
+
+	typesCmd := &cobra.Command{
+		Use:   "types",
+		Short: "Generate Go types from a schema",
+		Long: `Generates Go structs, enum constants and validate tags from an OSSA JSON
+Schema, for maintainers adopting a new spec version. The output is a starting
+point to diff against types.go rather than a drop-in replacement.`,
+		Args: cobra.NoArgs,
+		RunE: runGenTypes,
+	}
+	typesCmd.Flags().StringVar(&genSchema, "schema", "", "Schema file (defaults to embedded v0.3.3)")
+	typesCmd.Flags().StringVar(&genPackage, "package", "ossa", "Package name of the generated file")
+	typesCmd.Flags().StringVarP(&genOutput, "output", "o", "", "Write to this file instead of stdout")
+
+	bindingsCmd := &cobra.Command{
+		Use:   "bindings",
+		Short: "Generate TypeScript or Python types from a schema",
+		Long: `Emits type definitions for other SDKs from the canonical schema: TypeScript
+interfaces with Zod schemas (--lang ts) or Pydantic v2 models (--lang python).
+Type names match the Go types generated by gen types.`,
+		Args: cobra.NoArgs,
+		RunE: runGenBindings,
+	}
+	bindingsCmd.Flags().StringVar(&genLang, "lang", "", "Target language: "+strings.Join(gentypes.Langs, ", "))
+	bindingsCmd.Flags().StringVar(&genSchema, "schema", "", "Schema file (defaults to embedded v0.3.3)")
+	bindingsCmd.Flags().StringVarP(&genOutput, "output", "o", "", "Write to this file instead of stdout")
+	bindingsCmd.MarkFlagRequired("lang")
+
+	genCmd.AddCommand(cueCmd, typesCmd, bindingsCmd)
+	return genCmd
+}
+
+func runGenCUE(cmd *cobra.Command, args []string) error {
+	data, err := ossa.ExportCUE(ossa.EmbeddedSchema())
+	if err != nil {
+		return err
+	}
+	return writeOutput(genOutput, data)
+}
+
+func runGenTypes(cmd *cobra.Command, args []string) error {
+	schema, err := readSchema(genSchema)
+	if err != nil {
+		return err
+	}
+	data, err := gentypes.Generate(schema, gentypes.Options{Package: genPackage})
+	if err != nil {
+		return err
+	}
+	return writeOutput(genOutput, data)
+}
+
+func runGenBindings(cmd *cobra.Command, args []string) error {
+	schema, err := readSchema(genSchema)
+	if err != nil {
+		return err
+	}
+	data, err := gentypes.GenerateBindings(schema, genLang, gentypes.Options{})
+	if err != nil {
+		return err
+	}
+	return writeOutput(genOutput, data)
+}
+
+// readSchema reads a schema file. If path is empty it returns the schema
+// for the schema_version setting, vendored or embedded.
+func readSchema(path string) ([]byte, error) {
+	if path == "" {
+		version := ""
+		if settings != nil {
+			version = settings.Value("schema_version")
+		}
+		return ossa.ResolveSchema(ossa.FindSchemaStore("."), version)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return data, nil
+}
+
+// writeOutput writes data to path, or to stdout if path is empty.
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
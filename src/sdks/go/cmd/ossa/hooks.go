@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies hooks written by ossa hooks install, which may be
+// overwritten without --force.
+const hookMarker = "# Installed by ossa hooks install"
+
+const preCommitConfig = `# Runs ossa checks on changed manifests; see ossa hooks --help.
+repos:
+  - repo: local
+    hooks:
+      - id: ossa
+        name: ossa validate, lint and fmt
+        entry: ossa hooks run --files
+        language: system
+        files: '\.(ya?ml|json)$'
+        stages: [pre-commit, pre-push]
+`
+
+var (
+	hookNames     []string
+	hookFramework bool
+	hookForce     bool
+	hookFix       bool
+	hookFiles     bool
+)
+
+func newHooksCmd() *cobra.Command {
+	hooksCmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Install Git hooks that check manifests",
+		Long: `Installs Git hooks that validate, lint and format-check the manifests
+changed by a commit or push. The hooks are one-line scripts calling
+"ossa hooks run", so the checks ship with the binary and every clone using the
+same ossa version enforces the same rules.`,
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Write the Git hooks or a pre-commit config",
+		Long: `Writes pre-commit and pre-push hooks into the repository's hooks directory
+(honouring core.hooksPath). Hooks not written by ossa are left alone unless
+--force is given. With --pre-commit-framework a .pre-commit-config.yaml is
+written instead, for teams that manage hooks with pre-commit.`,
+		Args: cobra.NoArgs,
+		RunE: runHooksInstall,
+	}
+	installCmd.Flags().StringSliceVar(&hookNames, "hook", []string{"pre-commit", "pre-push"}, "Hooks to install")
+	installCmd.Flags().BoolVar(&hookFramework, "pre-commit-framework", false, "Write .pre-commit-config.yaml instead of Git hooks")
+	installCmd.Flags().BoolVar(&hookForce, "force", false, "Overwrite existing hooks or config")
+
+	runCmd := &cobra.Command{
+		Use:   "run <pre-commit|pre-push> | --files [file...]",
+		Short: "Check the manifests a commit or push changes",
+		Long: `Runs the hook checks: each changed manifest must load, validate and be
+formatted as ossa writes it (two-space indent, comments kept). pre-commit checks
+staged files; pre-push reads the refs being pushed from stdin and checks files
+changed in commits the remote lacks. With --files the arguments are the files
+to check, as passed by the pre-commit framework.`,
+		RunE: runHooksRun,
+	}
+	runCmd.Flags().BoolVar(&hookFiles, "files", false, "Check the files given as arguments")
+	runCmd.Flags().BoolVar(&hookFix, "fix", false, "Rewrite unformatted manifests instead of failing")
+	runCmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "Fail on lint warnings")
+	runCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to custom schema (defaults to embedded v0.3.3)")
+
+	hooksCmd.AddCommand(installCmd, runCmd)
+	return hooksCmd
+}
+
+func runHooksInstall(cmd *cobra.Command, args []string) error {
+	if hookFramework {
+		top, err := git("rev-parse", "--show-toplevel")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(top, ".pre-commit-config.yaml")
+		if _, err := os.Stat(path); err == nil && !hookForce {
+			return fmt.Errorf("%s exists; add this to it or use --force:\n\n%s", path, preCommitConfig)
+		}
+		if err := os.WriteFile(path, []byte(preCommitConfig), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Wrote %s\n", path)
+		return nil
+	}
+
+	dir, err := git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range hookNames {
+		if name != "pre-commit" && name != "pre-push" {
+			return fmt.Errorf("unsupported hook %q (want pre-commit or pre-push)", name)
+		}
+		path := filepath.Join(dir, name)
+		if existing, err := os.ReadFile(path); err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !hookForce {
+			return fmt.Errorf("%s exists and was not installed by ossa; use --force to replace it", path)
+		}
+		script := fmt.Sprintf("#!/bin/sh\n%s; the checks run in ossa itself.\nexec ossa hooks run %s \"$@\"\n", hookMarker, name)
+		if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Installed %s\n", path)
+	}
+	return nil
+}
+
+func runHooksRun(cmd *cobra.Command, args []string) error {
+	var files []string
+	var err error
+	switch {
+	case hookFiles:
+		files = args
+	case len(args) >= 1 && args[0] == "pre-commit":
+		files, err = gitLines("diff", "--cached", "--name-only", "--diff-filter=ACMR")
+	case len(args) >= 1 && args[0] == "pre-push":
+		files, err = pushedFiles(cmd.InOrStdin())
+	default:
+		return fmt.Errorf("expected pre-commit, pre-push or --files")
+	}
+	if err != nil {
+		return err
+	}
+
+	checked, failed := 0, 0
+	for _, path := range files {
+		ok, isManifest := checkHookFile(path)
+		if !isManifest {
+			continue
+		}
+		checked++
+		if !ok {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d manifests failed", failed, checked)
+	}
+	return nil
+}
+
+// pushedFiles returns the files changed by the refs a pre-push hook is
+// told about on stdin, one "<local ref> <local sha> <remote ref> <remote
+// sha>" line each. New branches are compared against all remote refs.
+func pushedFiles(in io.Reader) ([]string, error) {
+	seen := map[string]bool{}
+	var files []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || strings.Trim(fields[1], "0") == "" {
+			continue // malformed or a branch deletion
+		}
+		args := []string{"log", "--name-only", "--diff-filter=ACMR", "--format=", fields[1]}
+		if strings.Trim(fields[3], "0") == "" {
+			args = append(args, "--not", "--remotes")
+		} else {
+			args = append(args, "^"+fields[3])
+		}
+		lines, err := gitLines(args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range lines {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	return files, scanner.Err()
+}
+
+// checkHookFile loads, validates and format-checks one file. isManifest
+// is false for deleted files and YAML or JSON that is not an OSSA manifest.
+func checkHookFile(path string) (ok, isManifest bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return true, false
+	}
+	named := ossa.IsManifestName(path)
+	m, err := ossa.LoadManifest(path)
+	if !named && (err != nil || !strings.HasPrefix(m.APIVersion, "ossa/")) {
+		return true, false
+	}
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", path, err)
+		return false, true
+	}
+
+	opts := []ossa.Option{}
+	if schemaPath != "" {
+		opts = append(opts, ossa.WithSchemaFile(schemaPath))
+	}
+	if warningsAsErrors {
+		opts = append(opts, ossa.WithStrict())
+	}
+	v, err := ossa.NewValidator(opts...)
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", path, err)
+		return false, true
+	}
+	result := v.Validate(m)
+	var problems []string
+	problems = append(problems, result.Errors...)
+	if msg := checkFormat(path, data); msg != "" {
+		problems = append(problems, msg)
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("✅ %s\n", path)
+	} else {
+		fmt.Printf("❌ %s\n", path)
+	}
+	for _, p := range problems {
+		fmt.Printf("  • %s\n", p)
+	}
+	for _, w := range result.Warnings {
+		fmt.Printf("  ⚠ %s\n", w)
+	}
+	return len(problems) == 0, true
+}
+
+// checkFormat compares a YAML manifest with its ossa.Document rendering
+// and, with --fix, rewrites it. JSON manifests are not format-checked.
+func checkFormat(path string, data []byte) string {
+	if manifestFormat(path) != "yaml" {
+		return ""
+	}
+	doc, err := ossa.ParseDocument(data)
+	if err != nil {
+		return err.Error()
+	}
+	formatted, err := doc.Bytes()
+	if err != nil {
+		return err.Error()
+	}
+	if bytes.Equal(formatted, data) {
+		return ""
+	}
+	if hookFix {
+		if err := os.WriteFile(path, formatted, 0o644); err != nil {
+			return err.Error()
+		}
+		fmt.Printf("  formatted %s; stage it again\n", path)
+		return ""
+	}
+	return "not formatted; run ossa hooks run --fix --files " + path
+}
+
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func gitLines(args ...string) ([]string, error) {
+	out, err := git(args...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	importOperations []string
+	importTags       []string
+	importServer     string
+	importInto       string
+	importScopes     []string
+	importBaseURL    string
+	importTier       string
+)
+
+func newImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate tools from external API descriptions",
+	}
+
+	openapiCmd := &cobra.Command{
+		Use:   "openapi [spec]",
+		Short: "Convert OpenAPI operations into tools",
+		Long: `Converts operations of an OpenAPI 3 document into http tools with
+parameter schemas and handlers. Prints a tools: block, or appends the tools to
+an existing manifest with --into. The tools run on the http runtime, which
+fills header placeholders such as ${PETSTORE_TOKEN} from the environment.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runImportOpenAPI,
+	}
+	openapiCmd.Flags().StringSliceVar(&importOperations, "operations", nil, "Only import these operationIds")
+	openapiCmd.Flags().StringSliceVar(&importTags, "tags", nil, "Only import operations with these tags")
+	openapiCmd.Flags().StringVar(&importServer, "server", "", "Base URL (defaults to the first server in the document)")
+	openapiCmd.Flags().StringVar(&importInto, "into", "", "Append tools to this manifest instead of printing them")
+
+	gitlabCmd := &cobra.Command{
+		Use:   "gitlab",
+		Short: "Generate GitLab API tools",
+		Long: `Emits a curated set of GitLab REST API tools (issues, merge requests,
+pipelines). Write tools are left out for access tiers that cannot write; with
+--into the manifest's tier is used unless --tier is given. The tools run on
+the http runtime with the token in $GITLAB_TOKEN.`,
+		Args: cobra.NoArgs,
+		RunE: runImportGitLab,
+	}
+	gitlabCmd.Flags().StringSliceVar(&importScopes, "scopes", nil, "Tool groups: "+strings.Join(ossa.GitLabScopes(), ", ")+" (default all)")
+	gitlabCmd.Flags().StringVar(&importBaseURL, "base-url", ossa.DefaultGitLabURL, "GitLab API root")
+	gitlabCmd.Flags().StringVar(&importTier, "tier", "", "Access tier used to drop write tools")
+	gitlabCmd.Flags().StringVar(&importInto, "into", "", "Append tools to this manifest instead of printing them")
+
+	importCmd.AddCommand(openapiCmd, gitlabCmd)
+	return importCmd
+}
+
+func runImportOpenAPI(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	tools, err := ossa.ImportOpenAPI(data, ossa.OpenAPIImportOptions{
+		Operations: importOperations,
+		Tags:       importTags,
+		Server:     importServer,
+	})
+	if err != nil {
+		return err
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("no operations matched")
+	}
+	if importInto == "" {
+		return printTools(tools)
+	}
+	manifest, err := ossa.LoadManifest(importInto)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+	return addTools(manifest, tools)
+}
+
+func runImportGitLab(cmd *cobra.Command, args []string) error {
+	var manifest *ossa.Manifest
+	tier := ossa.AccessTier(importTier)
+	if importInto != "" {
+		var err error
+		if manifest, err = ossa.LoadManifest(importInto); err != nil {
+			return fmt.Errorf("failed to load manifest: %w", err)
+		}
+		if tier == "" {
+			tier = manifest.EffectiveAccessTier()
+		}
+	}
+	if tier != "" && !ossa.ValidAccessTiers[tier] {
+		return fmt.Errorf("invalid access tier: %s", tier)
+	}
+
+	tools, err := ossa.GitLabTools(ossa.GitLabImportOptions{
+		Scopes:  importScopes,
+		BaseURL: importBaseURL,
+		Tier:    tier,
+	})
+	if err != nil {
+		return err
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("no tools available for %s agents", tier)
+	}
+	if manifest == nil {
+		return printTools(tools)
+	}
+	return addTools(manifest, tools)
+}
+
+// addTools appends tools to the manifest at importInto, skipping names that
+// already exist.
+func addTools(manifest *ossa.Manifest, tools []ossa.ToolConfig) error {
+	existing := make(map[string]bool, len(manifest.Spec.Tools))
+	for _, t := range manifest.Spec.Tools {
+		existing[t.Name] = true
+	}
+	added := 0
+	for _, t := range tools {
+		if existing[t.Name] {
+			fmt.Fprintf(os.Stderr, "skipping %s: tool already exists\n", t.Name)
+			continue
+		}
+		manifest.Spec.Tools = append(manifest.Spec.Tools, t)
+		added++
+	}
+
+	if err := ossa.SaveManifest(manifest, importInto, manifestFormat(importInto)); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Added %d tools to %s\n", added, importInto)
+	return nil
+}
+
+func printTools(tools []ossa.ToolConfig) error {
+	data, err := yaml.Marshal(map[string]interface{}{"tools": tools})
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// manifestFormat picks the SaveManifest format from a file name.
+func manifestFormat(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return "json"
+	}
+	return "yaml"
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/blueflyio/ossa-go/lsp"
+	"github.com/spf13/cobra"
+)
+
+func newLspCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Run the language server for manifests",
+		Long: `Runs a Language Server Protocol server over stdio for *.ossa.yaml files,
+with diagnostics from the schema, semantic checks and lint warnings, hover
+documentation from the schema, completion of field names and enum values, and
+go-to-definition for workflow step refs. Point your editor's LSP client at
+"ossa lsp".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lsp.New(cmd.InOrStdin(), cmd.OutOrStdout()).Serve()
+		},
+	}
+}
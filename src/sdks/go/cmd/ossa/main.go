@@ -5,14 +5,20 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/junit"
 	"github.com/blueflyio/ossa-go/ossa"
 	"github.com/spf13/cobra"
 )
 
 var (
-	schemaPath string
-	outputJSON bool
+	schemaPath       string
+	outputJSON       bool
+	warningsAsErrors bool
+	runtimeChecks    bool
+	validateFormat   string
 )
 
 func main() {
@@ -21,7 +27,22 @@ func main() {
 		Short:   "OSSA CLI - Open Standard for Software Agents (The OpenAPI for agents)",
 		Long:    `OSSA CLI validates and manages AI agent manifests.\n\nVersion: ` + ossa.Version + ` (OSSA ` + ossa.OSSAVersion + `)`,
 		Version: ossa.Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := newLogger(cmd.ErrOrStderr(), logLevel, logFormat)
+			if err != nil {
+				return err
+			}
+			ossa.SetLogger(logger)
+			if err := applyOffline(cmd); err != nil {
+				return err
+			}
+			return applyConfig(cmd)
+		},
 	}
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Make no network calls, failing anything that needs one (or set offline)")
+	rootCmd.PersistentFlags().StringArrayVar(&identityFlags, "identity", nil, "age identity file or KMS key URI decrypting encrypted manifests and bundles (repeatable)")
 
 	// Validate command
 	validateCmd := &cobra.Command{
@@ -33,6 +54,9 @@ func main() {
 	}
 	validateCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to custom schema (defaults to embedded v0.3.3)")
 	validateCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output as JSON")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text, json or junit (a testcase per manifest, for CI)")
+	validateCmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "Fail on warnings (deprecations, shorthand, best practices)")
+	validateCmd.Flags().BoolVar(&runtimeChecks, "runtime-checks", false, "Also check this host can run the manifest, such as its Ollama model being pulled")
 
 	// Info command
 	infoCmd := &cobra.Command{
@@ -48,6 +72,39 @@ func main() {
 
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(newImportCmd())
+	rootCmd.AddCommand(newConformanceCmd())
+	rootCmd.AddCommand(newFuzzCorpusCmd())
+	rootCmd.AddCommand(newGenCmd())
+	rootCmd.AddCommand(newVetCmd())
+	rootCmd.AddCommand(newBenchCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newExplainCmd())
+	rootCmd.AddCommand(newEditCmd())
+	rootCmd.AddCommand(newBrowseCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newQueryCmd())
+	rootCmd.AddCommand(newApplyCmd())
+	rootCmd.AddCommand(newResolveCmd())
+	rootCmd.AddCommand(newPublishCmd())
+	rootCmd.AddCommand(newPullCmd())
+	rootCmd.AddCommand(newBundleCmd())
+	rootCmd.AddCommand(newChannelCmd())
+	rootCmd.AddCommand(newEncryptCmd())
+	rootCmd.AddCommand(newDecryptCmd())
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(newEvalCmd())
+	rootCmd.AddCommand(newRunsCmd())
+	rootCmd.AddCommand(newSchedulerCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newUpCmd())
+	rootCmd.AddCommand(newLspCmd())
+	rootCmd.AddCommand(newHooksCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newUpgradeCmd())
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newPluginCmd())
+	addPluginCmds(rootCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -56,27 +113,32 @@ func main() {
 
 func runValidate(cmd *cobra.Command, args []string) error {
 	path := args[0]
-
-	var result *ossa.ValidationResult
-	var err error
-
-	if schemaPath != "" {
-		result, err = ossa.ValidateFile(path, schemaPath)
-	} else {
-		result, err = ossa.ValidateFile(path, "")
+	format, err := outputFormat()
+	if err != nil {
+		return err
 	}
-
+	result, err := validateManifest(cmd, path)
 	if err != nil {
-		return fmt.Errorf("validation error: %w", err)
+		return err
 	}
 
-	if outputJSON {
-		// JSON output
+	switch format {
+	case "json":
 		if result.Valid {
-			fmt.Println(`{"valid": true}`)
+			fmt.Printf(`{"valid": true, "warnings": %d}`, len(result.Warnings))
 		} else {
-			fmt.Printf(`{"valid": false, "errors": %d}`, len(result.Errors))
-			fmt.Println()
+			fmt.Printf(`{"valid": false, "errors": %d, "warnings": %d}`, len(result.Errors), len(result.Warnings))
+		}
+		fmt.Println()
+		return nil
+	case "junit":
+		report := &junit.Report{}
+		report.Add(junit.Suite{Name: "ossa validate", Cases: []junit.Case{validationCase(path, result)}})
+		if err := writeJUnit(cmd, report); err != nil {
+			return err
+		}
+		if !result.Valid {
+			return fmt.Errorf("validation failed")
 		}
 		return nil
 	}
@@ -84,16 +146,90 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	// Human-readable output
 	if result.Valid {
 		fmt.Printf("✅ %s is valid\n", path)
+		for _, w := range result.Warnings {
+			fmt.Printf("  ⚠ %s\n", w)
+		}
 		return nil
 	}
 
 	fmt.Printf("❌ %s is invalid (%d errors)\n", path, len(result.Errors))
 	for _, e := range result.Errors {
-		fmt.Printf("  • %s: %s\n", e.Path, e.Message)
+		fmt.Printf("  • %s\n", e)
 	}
 	return fmt.Errorf("validation failed")
 }
 
+// outputFormat is --format, or json for --json.
+func outputFormat() (string, error) {
+	if outputJSON {
+		return "json", nil
+	}
+	switch validateFormat {
+	case "", "text":
+		return "text", nil
+	case "json", "junit":
+		return validateFormat, nil
+	}
+	return "", fmt.Errorf("invalid --format %s: use text, json or junit", validateFormat)
+}
+
+// validateManifest validates path against --schema or its vendored schema,
+// adding runtime checks and promoting warnings as the flags ask.
+func validateManifest(cmd *cobra.Command, path string) (*ossa.ValidationResult, error) {
+	var result *ossa.ValidationResult
+	var err error
+
+	if schemaPath != "" {
+		result, err = ossa.ValidateFile(path, schemaPath)
+	} else {
+		vendored, verr := vendoredSchemaFor(path)
+		if verr != nil {
+			return nil, verr
+		}
+		result, err = ossa.ValidateFile(path, vendored)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if runtimeChecks {
+		if err := ossa.RequireNetwork("--runtime-checks"); err != nil {
+			return nil, err
+		}
+		m, err := ossa.LoadManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("validation error: %w", err)
+		}
+		result.Warnings = append(result.Warnings, engine.CheckRuntime(cmd.Context(), m)...)
+	}
+	if warningsAsErrors {
+		result.PromoteWarnings()
+	}
+	return result, nil
+}
+
+// validationCase is the JUnit testcase of a manifest's validation: its
+// errors are the failure, its warnings system-out.
+func validationCase(path string, result *ossa.ValidationResult) junit.Case {
+	c := junit.Case{Name: path, ClassName: "ossa.validate"}
+	if len(result.Warnings) > 0 {
+		c.SystemOut = "warnings:\n  " + strings.Join(result.Warnings, "\n  ")
+	}
+	if !result.Valid {
+		c.Failure = &junit.Problem{Message: fmt.Sprintf("%d errors", len(result.Errors)), Text: strings.Join(result.Errors, "\n")}
+	}
+	return c
+}
+
+func writeJUnit(cmd *cobra.Command, report *junit.Report) error {
+	data, err := report.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
 func runInfo(cmd *cobra.Command, args []string) error {
 	path := args[0]
 
@@ -128,8 +264,24 @@ func runInfo(cmd *cobra.Command, args []string) error {
 	if manifest.Spec.LLM != nil {
 		fmt.Printf("LLM:         %s/%s\n", manifest.Spec.LLM.Provider, manifest.Spec.LLM.Model)
 	}
+	if e := manifest.Spec.Embeddings; e != nil {
+		fmt.Printf("Embeddings:  %s/%s\n", e.Provider, e.Model)
+	}
+	if in := manifest.Spec.Input; in != nil && len(in.Modalities) > 0 {
+		fmt.Printf("Input:       %s\n", strings.Join(in.Modalities, ", "))
+	}
 	if len(manifest.Spec.Tools) > 0 {
 		fmt.Printf("Tools:       %d\n", len(manifest.Spec.Tools))
+		for _, tool := range manifest.Spec.Tools {
+			if tool.IsGated() {
+				fmt.Printf("  • %s (%s)\n", tool.Name, tool.RolloutStatus())
+			}
+			if tool.LLM != nil {
+				if llm, err := manifest.LLMFor(tool.Name); err == nil {
+					fmt.Printf("  • %s (llm %s/%s)\n", tool.Name, llm.Provider, llm.Model)
+				}
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var migrateFixDeprecations bool
+
+func newMigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [manifest...]",
+		Short: "Report or fix deprecated manifest fields",
+		Long: `Lists the deprecated fields and values each manifest uses, with the spec
+version that removes them. With --fix-deprecations the manifests are rewritten
+in place with the replacements; deprecations that need manual changes are
+still listed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runMigrate,
+	}
+	migrateCmd.Flags().BoolVar(&migrateFixDeprecations, "fix-deprecations", false, "Rewrite manifests to replace deprecated forms")
+	return migrateCmd
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	for _, path := range args {
+		manifest, err := ossa.LoadManifest(path)
+		if err != nil {
+			return fmt.Errorf("failed to load manifest: %w", err)
+		}
+
+		if !migrateFixDeprecations {
+			found := manifest.FindDeprecations()
+			if len(found) == 0 {
+				fmt.Printf("✅ %s uses no deprecated fields\n", path)
+				continue
+			}
+			fmt.Printf("⚠ %s uses %d deprecated fields\n", path, len(found))
+			for _, d := range found {
+				fix := ""
+				if d.Fix != nil {
+					fix = " [fixable]"
+				}
+				fmt.Printf("  • %s%s\n", d.Message(), fix)
+			}
+			continue
+		}
+
+		fixed := manifest.FixDeprecations()
+		if len(fixed) > 0 {
+			if err := ossa.SaveManifest(manifest, path, manifestFormat(path)); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✅ Fixed %d deprecations in %s\n", len(fixed), path)
+		for _, d := range manifest.FindDeprecations() {
+			fmt.Printf("  • %s [manual]\n", d.Message())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var offline bool
+
+// applyOffline turns on offline mode for --offline, or else the offline
+// setting, before anything can reach the network, ossa schema included.
+// Besides the SDK's own checks, the default transport stops dialing, so
+// network calls from any command fail rather than go out.
+func applyOffline(cmd *cobra.Command) error {
+	on := offline
+	if f := cmd.Flags().Lookup("offline"); f == nil || !f.Changed {
+		cfg, _, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		value, _, err := cfg.Get("offline")
+		if err != nil && !configExempt(cmd) {
+			return err
+		}
+		on = value == "true"
+	}
+	if !on {
+		return nil
+	}
+	t, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("offline: unexpected default transport %T", http.DefaultTransport)
+	}
+	ossa.SetOffline(true)
+	ossa.DisableNetwork(t)
+	return nil
+}
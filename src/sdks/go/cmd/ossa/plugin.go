@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/internal/config"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/plugin"
+	"github.com/spf13/cobra"
+)
+
+func newPluginCmd() *cobra.Command {
+	pluginCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Inspect CLI plugins",
+		Long: `Executables named ossa-<name> on PATH run as "ossa <name>". A plugin receives
+its arguments unchanged and, in the JSON file named by $OSSA_PLUGIN_HANDSHAKE,
+the parsed manifests among them and the effective CLI settings. Plugins cannot
+replace built-in commands.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List plugins found on PATH",
+		Args:  cobra.NoArgs,
+		RunE:  runPluginList,
+	}
+
+	pluginCmd.AddCommand(listCmd)
+	return pluginCmd
+}
+
+// pluginAnnotation marks the commands addPluginCmds registers.
+const pluginAnnotation = "ossa-plugin"
+
+// addPluginCmds registers a subcommand for every plugin on PATH that does
+// not clash with a built-in command.
+func addPluginCmds(root *cobra.Command) {
+	for _, p := range plugin.Discover(os.Getenv("PATH")) {
+		if builtinCmd(root, p.Name) {
+			continue
+		}
+		p := p
+		root.AddCommand(&cobra.Command{
+			Use:                p.Name,
+			Short:              "Plugin " + p.Path,
+			Annotations:        map[string]string{pluginAnnotation: p.Path},
+			DisableFlagParsing: true,
+			SilenceUsage:       true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runPlugin(p, args)
+			},
+		})
+	}
+}
+
+func builtinCmd(root *cobra.Command, name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range root.Commands() {
+		if _, ok := c.Annotations[pluginAnnotation]; ok {
+			continue
+		}
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func runPlugin(p plugin.Plugin, args []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	h := &plugin.Handshake{
+		Version:     plugin.HandshakeVersion,
+		OSSAVersion: ossa.Version,
+		SpecVersion: ossa.OSSAVersion,
+		Args:        args,
+		WorkDir:     wd,
+		Config:      map[string]string{},
+		Manifests:   plugin.LoadManifests(args),
+	}
+	if h.Args == nil {
+		h.Args = []string{}
+	}
+	for _, k := range config.Keys {
+		h.Config[k.Name] = settings.Value(k.Name)
+	}
+	if store := ossa.FindSchemaStore("."); store != nil {
+		h.SchemaDir = store.Dir
+	}
+
+	path, err := plugin.Write(h)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	c := exec.Command(p.Path, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = append(os.Environ(), plugin.HandshakeEnv+"="+path)
+	err = c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Remove(path)
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func runPluginList(cmd *cobra.Command, args []string) error {
+	plugins := plugin.Discover(os.Getenv("PATH"))
+	if len(plugins) == 0 {
+		fmt.Println("No plugins found on PATH")
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATH\tNOTE")
+	root := cmd.Root()
+	for _, p := range plugins {
+		note := ""
+		if builtinCmd(root, p.Name) {
+			note = "ignored: clashes with a built-in command"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Path, note)
+		for _, s := range p.Shadowed {
+			fmt.Fprintf(w, "%s\t%s\tshadowed by %s\n", p.Name, s, filepath.Dir(p.Path))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/apply"
+	"github.com/blueflyio/ossa-go/eval"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	publishChannel     string
+	publishGates       string
+	publishEvalReports []string
+	publishRedTeam     string
+)
+
+func newPublishCmd() *cobra.Command {
+	publishCmd := &cobra.Command{
+		Use:   "publish <manifest>...",
+		Short: "Publish manifests to a registry, optionally on a release channel",
+		Long: `Validates and publishes each manifest to an ossa serve registry.
+
+By default a publish replaces the catalog's manifest, which is the stable
+channel. With --channel beta (or any other channel) the manifest is kept as
+a release on that channel instead, leaving stable alone until the release
+is promoted with ossa channel promote. Publishing to a channel needs a
+semantic metadata.version, and the registry records the channel in the
+ossa.dev/channel annotation.
+
+The published copy is stamped with ossa.dev/source-url, its URL in the
+registry, and ossa.dev/digest, its sha256 without that annotation; the
+files are left as they are.
+
+--gates names a file whose gates block says what a release needs:
+
+  gates:
+    eval: regression-suite
+    min_score: 0.85
+    redteam: required
+
+The eval gate is met by the suite's score in an --eval-report, the JSON
+that ossa eval --format json writes, and the redteam gate by --redteam
+passed. Nothing is published unless every gate is met.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runPublish,
+	}
+	addRegistryFlags(publishCmd)
+	publishCmd.Flags().StringVar(&publishChannel, "channel", "", "Release channel to publish to, e.g. beta (default: stable)")
+	publishCmd.Flags().StringVar(&publishGates, "gates", "", "File whose gates block the release must pass")
+	publishCmd.Flags().StringArrayVar(&publishEvalReports, "eval-report", nil, "JSON report of ossa eval, evidence for the eval gate (repeatable)")
+	publishCmd.Flags().StringVar(&publishRedTeam, "redteam", "", "Outcome of the red-team run: passed or failed")
+	return publishCmd
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	reg, err := registryClient()
+	if err != nil {
+		return err
+	}
+	reg.Channel = publishChannel
+	if err := checkPublishGates(); err != nil {
+		return fmt.Errorf("nothing published: %w", err)
+	}
+
+	manifests := make([]*ossa.Manifest, len(args))
+	var problems []string
+	for i, path := range args {
+		m, err := ossa.LoadManifest(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		if result := ossa.ValidateManifest(m); !result.Valid {
+			problems = append(problems, fmt.Sprintf("%s: %s", path, strings.Join(result.Errors, "; ")))
+		}
+		manifests[i] = m
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("nothing published:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	out := cmd.OutOrStdout()
+	for i, m := range manifests {
+		if err := reg.Put(context.Background(), m); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", args[i], err)
+		}
+		version := ""
+		if m.Metadata.Version != "" {
+			version = "@" + m.Metadata.Version
+		}
+		fmt.Fprintf(out, "✅ Published %s/%s%s to %s\n", apply.Namespace(m), m.Metadata.Name, version, reg)
+	}
+	return nil
+}
+
+// checkPublishGates checks the --gates file against the --eval-report and
+// --redteam evidence.
+func checkPublishGates() error {
+	if publishGates == "" {
+		if len(publishEvalReports) > 0 || publishRedTeam != "" {
+			return fmt.Errorf("--eval-report and --redteam need --gates")
+		}
+		return nil
+	}
+	data, err := os.ReadFile(publishGates)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Gates *ossa.PublishGates `yaml:"gates"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", publishGates, err)
+	}
+	if file.Gates == nil {
+		return fmt.Errorf("%s: no gates block", publishGates)
+	}
+	if result := file.Gates.Validate(); !result.Valid {
+		return fmt.Errorf("%s: %s", publishGates, strings.Join(result.Errors, "; "))
+	}
+
+	var reports []*eval.Report
+	for _, path := range publishEvalReports {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var r []*eval.Report
+		if err := json.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("%s: not an ossa eval --format json report: %w", path, err)
+		}
+		reports = append(reports, r...)
+	}
+	evidence := eval.Evidence(reports...)
+	switch publishRedTeam {
+	case "":
+	case "passed", "failed":
+		passed := publishRedTeam == "passed"
+		evidence.RedTeamPassed = &passed
+	default:
+		return fmt.Errorf("invalid --redteam %s: use passed or failed", publishRedTeam)
+	}
+	return file.Gates.CheckGates(evidence)
+}
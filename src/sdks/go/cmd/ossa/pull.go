@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pullChannel string
+	pullOutput  string
+)
+
+func newPullCmd() *cobra.Command {
+	pullCmd := &cobra.Command{
+		Use:   "pull <[namespace/]agent>",
+		Short: "Fetch an agent's manifest from a registry",
+		Long: `Fetches an agent from an ossa serve registry and prints it as YAML, or
+writes it to --output (JSON if the file ends in .json, CBOR if .cbor).
+
+Without --channel this is the catalog's manifest, the stable channel; with
+--channel beta it is the release the beta channel points at, annotated
+ossa.dev/channel: beta.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runPull,
+	}
+	addRegistryFlags(pullCmd)
+	pullCmd.Flags().StringVar(&pullChannel, "channel", "", "Release channel to pull from, e.g. stable or beta")
+	pullCmd.Flags().StringVarP(&pullOutput, "output", "o", "", "Write the manifest to a file")
+	return pullCmd
+}
+
+func runPull(cmd *cobra.Command, args []string) error {
+	reg, err := registryClient()
+	if err != nil {
+		return err
+	}
+	namespace, name := splitAgent(args[0])
+	m, err := reg.Pull(context.Background(), namespace, name, pullChannel)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		if pullChannel != "" {
+			return fmt.Errorf("%s/%s has no %s release", namespace, name, pullChannel)
+		}
+		return fmt.Errorf("agent not found: %s/%s", namespace, name)
+	}
+	if pullOutput == "" {
+		data, err := m.ToYAML()
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), data)
+		return nil
+	}
+	format := "yaml"
+	switch ext := filepath.Ext(pullOutput); {
+	case strings.EqualFold(ext, ".json"):
+		format = "json"
+	case strings.EqualFold(ext, ossa.CBORExt):
+		format = "cbor"
+	}
+	if err := os.MkdirAll(filepath.Dir(pullOutput), 0o755); err != nil {
+		return err
+	}
+	return ossa.SaveManifest(m, pullOutput, format)
+}
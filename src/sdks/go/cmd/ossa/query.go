@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+	"github.com/spf13/cobra"
+)
+
+var queryToken string
+
+func newQueryCmd() *cobra.Command {
+	queryCmd := &cobra.Command{
+		Use:   "query <expression> [dir|manifest|url...]",
+		Short: "Find manifests matching an expression",
+		Long: `Evaluates a CEL-like expression against every manifest in the given
+workspaces and files (default ".") and lists those it holds for. A source
+starting with http:// or https:// is an ossa serve registry, queried through
+its API with --token or $OSSA_TOKEN.
+
+  ossa query 'spec.llm.provider == "anthropic" && metadata.labels.team == "ops"'
+  ossa query 'spec.tools.exists(t, t.type == "mcp") && !has(spec.safety)' agents/
+  ossa query 'size(spec.tools) > 10' https://ossa.example.com
+
+Fields are reached with dots or ["key"], list elements with [i], and missing
+fields are null. Operators: == != < <= > >= in && || !. Functions: size(x),
+has(x); string methods contains, startsWith, endsWith, matches; list macros
+exists(v, pred) and all(v, pred).`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runQuery,
+	}
+	queryCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output as JSON")
+	addSelectorFlag(queryCmd)
+	queryCmd.Flags().StringVar(&queryToken, "token", "", "Bearer token for registry sources (default $OSSA_TOKEN)")
+	return queryCmd
+}
+
+// queryMatch is one manifest a query held for.
+type queryMatch struct {
+	Path      string    `json:"path"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	Kind      ossa.Kind `json:"kind"`
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
+	q, err := ossa.CompileQuery(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := labels.Parse(selectorFlag); err != nil {
+		return err
+	}
+	sources := args[1:]
+	if len(sources) == 0 {
+		sources = []string{"."}
+	}
+
+	matches := []queryMatch{}
+	failed := 0
+	for _, src := range sources {
+		entries, err := querySource(src)
+		if err == nil {
+			entries, err = selectEntries(entries)
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Err != nil {
+				continue
+			}
+			ok, err := q.Match(e.Manifest)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "⚠ %s: %v\n", e.Path, err)
+				failed++
+				continue
+			}
+			if ok {
+				matches = append(matches, queryMatch{Path: e.Path, Name: e.Name, Namespace: e.Manifest.Metadata.Namespace, Kind: e.Kind})
+			}
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(matches); err != nil {
+			return err
+		}
+	} else if len(matches) > 0 {
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tKIND\tNAME")
+		for _, m := range matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.Path, m.Kind, m.Name)
+		}
+		w.Flush()
+	}
+	if failed > 0 {
+		return fmt.Errorf("query failed on %d manifests", failed)
+	}
+	return nil
+}
+
+// querySource loads the manifests of a workspace, a file or a registry.
+func querySource(src string) ([]ossa.CatalogEntry, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return registryManifests(src)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ossa.ScanWorkspace(src)
+	}
+	m, err := ossa.LoadManifest(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+	return []ossa.CatalogEntry{{Path: src, Name: m.Metadata.Name, Kind: m.Kind, Manifest: m}}, nil
+}
+
+// registryManifests fetches every agent an ossa serve registry lists.
+func registryManifests(base string) ([]ossa.CatalogEntry, error) {
+	if err := ossa.RequireNetwork("registry " + base); err != nil {
+		return nil, err
+	}
+	base = strings.TrimSuffix(base, "/")
+	token := queryToken
+	if token == "" {
+		token = os.Getenv("OSSA_TOKEN")
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	get := func(path string, out interface{}) error {
+		req, err := http.NewRequest(http.MethodGet, base+path, nil)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to query registry: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to query registry: GET %s%s: %s", base, path, resp.Status)
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	sel, err := labels.Parse(selectorFlag)
+	if err != nil {
+		return nil, err
+	}
+	registry := &resolve.Registry{URL: base, Token: token, Client: client}
+	var agents []ossa.CatalogEntry
+	opts := ossa.ListOptions{Selector: sel}
+	for {
+		list, err := registry.List(context.Background(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query registry: %w", err)
+		}
+		agents = append(agents, list.Items...)
+		if opts.Continue = list.Continue; opts.Continue == "" {
+			break
+		}
+	}
+	entries := make([]ossa.CatalogEntry, 0, len(agents))
+	for _, a := range agents {
+		namespace, name, _ := strings.Cut(a.Path, "/")
+		path := "/" + url.PathEscape(namespace) + "/" + url.PathEscape(name)
+		var agent struct {
+			Manifest *ossa.Manifest `json:"manifest"`
+		}
+		if err := get("/api/agents"+path, &agent); err != nil {
+			return nil, err
+		}
+		if agent.Manifest == nil {
+			continue
+		}
+		entries = append(entries, ossa.CatalogEntry{Path: base + path, Name: name, Kind: agent.Manifest.Kind, Manifest: agent.Manifest})
+	}
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/apply"
+	"github.com/spf13/cobra"
+)
+
+var (
+	registryURL   string
+	registryToken string
+)
+
+// addRegistryFlags adds --url and --token to a command talking to an ossa
+// serve registry.
+func addRegistryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&registryURL, "url", "", "Registry base URL (default: the registry_url setting)")
+	cmd.Flags().StringVar(&registryToken, "token", "", "Bearer token for the registry (default $OSSA_TOKEN)")
+}
+
+// registryClient returns the registry named by --url or the registry_url
+// setting, authenticated with --token or $OSSA_TOKEN.
+func registryClient() (*apply.Registry, error) {
+	url := registryURL
+	if url == "" && settings != nil {
+		url = settings.Value("registry_url")
+	}
+	if url == "" {
+		return nil, fmt.Errorf("no registry: pass --url or set registry_url with ossa config set")
+	}
+	token := registryToken
+	if token == "" {
+		token = os.Getenv("OSSA_TOKEN")
+	}
+	return &apply.Registry{URL: url, Token: token}, nil
+}
+
+// splitAgent splits [namespace/]name, defaulting the namespace.
+func splitAgent(arg string) (namespace, name string) {
+	if ns, n, ok := strings.Cut(arg, "/"); ok {
+		return ns, n
+	}
+	return "default", arg
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/resolve"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resolveLock   string
+	resolveUpdate bool
+	resolveFrozen bool
+)
+
+func newResolveCmd() *cobra.Command {
+	resolveCmd := &cobra.Command{
+		Use:   "resolve <workflow>...",
+		Short: "Resolve versioned agent refs and pin them in a lock file",
+		Long: `Resolves the versioned refs among the workflows' steps, such as
+support-agent@^1.2 or payments/biller@~2.0, against the releases an ossa
+serve registry holds, and pins each to a release in the lock file.
+
+Constraints: 1.2.3 exact; 1.2 or 1.2.x any patch; ^1.2.3 compatible
+(<2.0.0); ~1.2.3 patches (<1.3.0); comparisons such as ">=1.2 <2"; and
+alternatives joined with ||.
+
+A ref keeps its locked release while the constraint still matches it, so
+resolving is repeatable; --update moves every ref to its newest match. A
+release whose content changed since it was locked is an error. In CI,
+--frozen fails instead of writing when the lock file is out of date.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runResolve,
+	}
+	addRegistryFlags(resolveCmd)
+	resolveCmd.Flags().StringVar(&resolveLock, "lock", resolve.LockFile, "Lock file to read and write")
+	resolveCmd.Flags().BoolVar(&resolveUpdate, "update", false, "Re-resolve locked refs to their newest matching releases")
+	resolveCmd.Flags().BoolVar(&resolveFrozen, "frozen", false, "Fail if the lock file would change, without writing it")
+	return resolveCmd
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	reg, err := registryClient()
+	if err != nil {
+		return err
+	}
+	lock, err := resolve.LoadLock(resolveLock)
+	if err != nil {
+		return err
+	}
+	r := &resolve.Resolver{Source: &resolve.Registry{URL: reg.URL, Token: reg.Token}, Lock: lock, Update: resolveUpdate}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKFLOW\tSTEP\tREF\tVERSION")
+	used := map[string]bool{}
+	changed := 0
+	var failed error
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		steps, err := r.ResolveWorkflow(context.Background(), data)
+		for _, s := range steps {
+			used[s.Ref.Raw] = true
+			note := ""
+			if s.Changed {
+				note = " (new)"
+				changed++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s%s\n", path, s.Step, s.Ref, s.Version, note)
+		}
+		if err != nil && failed == nil {
+			failed = fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	w.Flush()
+	if failed != nil {
+		return failed
+	}
+	dropped := lock.Retain(used)
+	if resolveFrozen {
+		if changed > 0 || dropped > 0 {
+			return fmt.Errorf("%s is out of date: %d refs to pin, %d stale; run ossa resolve", resolveLock, changed, dropped)
+		}
+		return nil
+	}
+	if err := lock.Save(resolveLock); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "✅ %s: %d refs pinned, %d changed, %d removed\n", resolveLock, len(lock.Refs), changed, dropped)
+	return nil
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/bundle"
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+	"github.com/blueflyio/ossa-go/runs"
+	"github.com/blueflyio/ossa-go/tracing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runInput        string
+	runInputFile    string
+	runShadows      []string
+	runShadowReport string
+	runAllowCommand []string
+	runMaxTurns     int
+	runLocale       string
+	runSession      string
+	runFlags        []string
+	runNoRecord     bool
+	runResume       string
+	runSeed         int
+)
+
+func newRunCmd() *cobra.Command {
+	runCmd := &cobra.Command{
+		Use:   "run [manifest]",
+		Short: "Run an agent or workflow",
+		Long: `Runs an Agent, or a Workflow's steps, and prints the final output.
+
+Models are called through the provider in each agent's spec.llm (openai
+reads OPENAI_API_KEY and OPENAI_BASE_URL). Tools run on their
+handler.runtime; exec tools may only run commands given with
+--allow-command, and actions needing approval are asked on the terminal.
+sql tools cannot run: the CLI has no database drivers.
+Tools gated by enabled, flag or rollout_percent are offered to the model,
+and run, only if --flag and --session enable them.
+Workflow steps ref manifests by path, relative to the workflow, or by
+version, such as support-agent@^1.2, pinned by ossa.lock.yaml and fetched
+from the registry.
+
+--shadow runs a candidate version of an agent alongside the current one:
+each step running an agent of the candidate's name runs the candidate on
+the same input, with its tool calls stubbed (a call the current version
+made gets its recorded result; any other gets {"stubbed": true}), and its
+output compared. Only the current version's output reaches later steps.
+Candidates can be pulled with ossa pull --channel beta -o candidate.yaml.
+
+Each run is recorded, with its steps, tool calls and tokens, for ossa runs.
+A workflow run is checkpointed after each top-level step: --resume <run-id>
+continues a failed or interrupted run from there, on its recorded input,
+without running its completed steps again. The manifest defaults to the
+one the run started from.
+
+--seed sends a sampling seed with every model turn whose spec.llm sets
+none, on providers supporting it (openai, gemini, ollama), and records it,
+so ossa runs replay --live can repeat the run about as closely as the
+provider allows. A resumed run keeps its recorded seed.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if runResume != "" {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: runRun,
+	}
+	addRegistryFlags(runCmd)
+	runCmd.Flags().StringVar(&runInput, "input", "", "Input as a JSON object")
+	runCmd.Flags().StringVar(&runInputFile, "input-file", "", "Read the input JSON object from a file")
+	runCmd.Flags().StringArrayVar(&runShadows, "shadow", nil, "Candidate agent manifest to run in shadow (repeatable)")
+	runCmd.Flags().StringVar(&runShadowReport, "shadow-report", "", "Write the shadow comparison report as JSON to a file")
+	runCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	runCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	runCmd.Flags().StringVar(&runLocale, "locale", "", "Locale of localized roles and prompts, e.g. de-AT (default en)")
+	runCmd.Flags().StringVar(&runSession, "session", "", "Session bucketing tools' rollout_percent")
+	runCmd.Flags().StringArrayVar(&runFlags, "flag", nil, "Feature flag to turn on for tools gated by it (repeatable)")
+	runCmd.Flags().IntVar(&runSeed, "seed", 0, "Sampling seed for models that accept one, recorded with the run")
+	runCmd.Flags().BoolVar(&runNoRecord, "no-record", false, "Do not record the run in the runs store")
+	runCmd.Flags().StringVar(&runResume, "resume", "", "Resume a failed or interrupted workflow run from its last checkpoint")
+	runCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Print the whole run as JSON")
+	return runCmd
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	var opts runOptions
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if runResume != "" {
+		if runNoRecord || runInput != "" || runInputFile != "" {
+			return fmt.Errorf("--resume continues a recorded run on its recorded input")
+		}
+		store, err := runsStore()
+		if err != nil {
+			return err
+		}
+		if opts.resume, err = store.Get(context.Background(), runResume); err != nil {
+			return fmt.Errorf("%s: %w", runResume, err)
+		}
+		if path == "" {
+			path = opts.resume.Source
+		}
+		if path == "" {
+			return fmt.Errorf("run %s did not record its manifest", runResume)
+		}
+	}
+	input, err := readRunInput()
+	if err != nil {
+		return err
+	}
+	opts.seed = seedFlag(cmd)
+	opts.shadows = map[string]*ossa.Manifest{}
+	for _, p := range runShadows {
+		candidate, err := ossa.LoadManifest(p)
+		if err != nil {
+			return fmt.Errorf("shadow %s: %w", p, err)
+		}
+		opts.shadows[candidate.Metadata.Name] = candidate
+	}
+	run, err := runManifest(cmd, path, input, opts)
+	if run == nil {
+		return err
+	}
+
+	var shadows []engine.ShadowReport
+	for _, s := range run.Steps {
+		if s.Shadow != nil {
+			shadows = append(shadows, *s.Shadow)
+		}
+	}
+	if runShadowReport != "" {
+		if shadows == nil {
+			shadows = []engine.ShadowReport{}
+		}
+		report, err := marshalRun(shadows)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(runShadowReport, report, 0o644); err != nil {
+			return err
+		}
+	}
+	for _, r := range shadows {
+		printShadow(cmd, r)
+	}
+	if err != nil {
+		return err
+	}
+	var result interface{} = run.Output
+	if outputJSON {
+		result = run
+	}
+	out, err := marshalRun(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), string(out))
+	return nil
+}
+
+// runOptions are what runManifest does beside running a manifest.
+type runOptions struct {
+	// shadows are candidate agents run in shadow, by name.
+	shadows map[string]*ossa.Manifest
+	// replay is a recorded run to answer from instead of models and
+	// tools; nothing is recorded.
+	replay *runs.Run
+	// resume is a recorded workflow run to continue, on its own input.
+	resume *runs.Run
+	// seed is the engine's seed, if any.
+	seed *int
+}
+
+// seedFlag is --seed, or nil if it was not given.
+func seedFlag(cmd *cobra.Command) *int {
+	if f := cmd.Flags().Lookup("seed"); f == nil || !f.Changed {
+		return nil
+	}
+	seed := runSeed
+	return &seed
+}
+
+// runManifest runs the agent or workflow at path, recording it in the runs
+// store unless --no-record is set. The run is returned even if it failed.
+func runManifest(cmd *cobra.Command, path string, input map[string]interface{}, opts runOptions) (*runs.Run, error) {
+	data, err := ossa.ReadManifestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ossa.ParseManifest(data, filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	e := newEngine(filepath.Dir(path), promptApproval(cmd.InOrStdin(), cmd.ErrOrStderr()))
+	e.Shadows = opts.shadows
+	e.Seed = opts.seed
+	rec := &runs.Recorder{Engine: e}
+	if opts.replay != nil {
+		rec.Engine = opts.replay.Replay(rec.Engine)
+	} else if !runNoRecord {
+		if rec.Store, err = runsStore(); err != nil {
+			return nil, err
+		}
+	}
+
+	var run *runs.Run
+	if m.Kind == ossa.KindWorkflow {
+		w, perr := engine.ParseWorkflow(data)
+		if perr != nil {
+			return nil, perr
+		}
+		ctx, end := context.Background(), func(*engine.WorkflowResult, error) {}
+		if t := runTracer(); t != nil {
+			ctx, end = t.StartWorkflow(ctx, w, input)
+		}
+		if opts.resume != nil {
+			run, err = rec.ResumeWorkflow(ctx, w, opts.resume)
+		} else {
+			run, err = rec.RunWorkflow(ctx, w, path, input)
+		}
+		if run != nil {
+			end(&engine.WorkflowResult{Workflow: w.Name, Output: run.Output, Usage: run.Usage}, err)
+		} else {
+			end(nil, err)
+		}
+	} else {
+		if opts.resume != nil {
+			return nil, fmt.Errorf("--resume needs a workflow")
+		}
+		if len(opts.shadows) > 0 {
+			return nil, fmt.Errorf("--shadow needs a workflow")
+		}
+		run, err = rec.RunAgent(context.Background(), m, path, input)
+	}
+	if run != nil && rec.Store != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "📝 run %s (ossa runs show %s)\n", run.ID, run.ID)
+		if run.Status == runs.StatusFailed && len(run.Done) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "   resume after step %s with ossa run --resume %s\n", run.Done[len(run.Done)-1], run.ID)
+		}
+	}
+	return run, err
+}
+
+// newEngine returns an engine for manifests in dir, set up from the
+// --allow-command and --max-turns flags and traced to trace_endpoint.
+func newEngine(dir string, approve ossa.ApprovalFunc) *engine.Engine {
+	e := &engine.Engine{
+		Model:    &engine.Providers{},
+		Tools:    toolPolicies.Tools(toolRuntimes(dir, runAllowCommand, approve)),
+		Load:     stepLoader(dir),
+		MaxTurns: runMaxTurns,
+		Locale:   runLocale,
+		Session:  runSession,
+		Flags:    runFlagSet(),
+	}
+	if t := runTracer(); t != nil {
+		t.Instrument(e)
+	}
+	return e
+}
+
+// runFlagSet is the feature flags --flag turns on.
+func runFlagSet() ossa.FlagSet {
+	flags := ossa.FlagSet{}
+	for _, f := range runFlags {
+		flags[f] = true
+	}
+	return flags
+}
+
+var (
+	tracerOnce sync.Once
+	tracer     *tracing.Tracer
+)
+
+// runTracer is the tracer of the trace_* settings, or nil if tracing is
+// off: the otlp exporter needs trace_endpoint, and langfuse and langsmith
+// their keys in the environment. Engines share it, so a workflow's agents
+// are one trace.
+func runTracer() *tracing.Tracer {
+	tracerOnce.Do(func() {
+		endpoint := settings.Value("trace_endpoint")
+		var exp tracing.Exporter
+		switch exporter := settings.Value("trace_exporter"); exporter {
+		case "langfuse":
+			l, err := tracing.LangfuseFromEnv(nil)
+			if err != nil {
+				ossa.Logger().Warn("tracing is off", "exporter", exporter, "error", err)
+				return
+			}
+			if endpoint != "" {
+				l.Host = endpoint
+			}
+			exp = l
+		case "langsmith":
+			l, err := tracing.LangSmithFromEnv(nil)
+			if err != nil {
+				ossa.Logger().Warn("tracing is off", "exporter", exporter, "error", err)
+				return
+			}
+			if endpoint != "" {
+				l.Endpoint = endpoint
+			}
+			exp = l
+		default:
+			if endpoint == "" {
+				return
+			}
+			headers := map[string]string{}
+			for _, kv := range strings.Split(settings.Value("trace_headers"), ",") {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+				}
+			}
+			exp = &tracing.OTLP{Endpoint: endpoint, Headers: headers}
+		}
+		tracer = &tracing.Tracer{Exporter: exp}
+	})
+	return tracer
+}
+
+// marshalRun indents v without escaping the arrows of shadow differences.
+func marshalRun(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func readRunInput() (map[string]interface{}, error) {
+	data := []byte(runInput)
+	if runInputFile != "" {
+		var err error
+		if data, err = os.ReadFile(runInputFile); err != nil {
+			return nil, err
+		}
+	}
+	input := map[string]interface{}{}
+	if len(data) == 0 {
+		return input, nil
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("input is not a JSON object: %w", err)
+	}
+	return input, nil
+}
+
+// stepLoader loads step refs: versioned refs from the registry at the
+// release ossa.lock.yaml pins, paths relative to dir.
+func stepLoader(dir string) func(ctx context.Context, ref string) (*ossa.Manifest, error) {
+	var mu sync.Mutex
+	var resolver *resolve.Resolver
+	return func(ctx context.Context, ref string) (*ossa.Manifest, error) {
+		r, versioned, err := resolve.ParseRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		if !versioned {
+			if !filepath.IsAbs(ref) {
+				ref = filepath.Join(dir, ref)
+			}
+			return ossa.LoadManifest(ref)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if resolver == nil {
+			lock, err := resolve.LoadLock(filepath.Join(dir, resolve.LockFile))
+			if err != nil {
+				return nil, err
+			}
+			// An extracted bundle carries its agents; no registry is needed.
+			src, bundled, err := bundle.DirSource(dir)
+			if err != nil {
+				return nil, err
+			}
+			if bundled {
+				resolver = &resolve.Resolver{Source: src, Lock: lock}
+			} else {
+				reg, err := registryClient()
+				if err != nil {
+					return nil, err
+				}
+				resolver = &resolve.Resolver{Source: &resolve.Registry{URL: reg.URL, Token: reg.Token}, Lock: lock}
+			}
+		}
+		res, err := resolver.Resolve(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return res.Manifest, nil
+	}
+}
+
+func printShadow(cmd *cobra.Command, r engine.ShadowReport) {
+	w := cmd.ErrOrStderr()
+	head := fmt.Sprintf("%s %s → %s (step %s)", r.Agent, r.CurrentVersion, r.CandidateVersion, r.Step)
+	switch {
+	case r.Error != "":
+		fmt.Fprintf(w, "❌ shadow %s failed: %s\n", head, r.Error)
+	case r.Match:
+		fmt.Fprintf(w, "✅ shadow %s matches\n", head)
+	default:
+		fmt.Fprintf(w, "⚠ shadow %s differs\n", head)
+		for _, d := range r.Differences {
+			fmt.Fprintf(w, "    %s\n", d)
+		}
+		if strings.Join(r.CurrentTools, ",") != strings.Join(r.CandidateTools, ",") {
+			fmt.Fprintf(w, "    tools: [%s] -> [%s]\n", strings.Join(r.CurrentTools, " "), strings.Join(r.CandidateTools, " "))
+		}
+	}
+	if r.Stubbed > 0 {
+		fmt.Fprintf(w, "    %d tool calls stubbed\n", r.Stubbed)
+	}
+	fmt.Fprintf(w, "    tokens: %d -> %d\n", r.CurrentUsage.InputTokens+r.CurrentUsage.OutputTokens, r.CandidateUsage.InputTokens+r.CandidateUsage.OutputTokens)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runsLive   bool
+	runsName   string
+	runsStatus string
+	runsSince  time.Duration
+	runsLimit  int
+)
+
+func newRunsCmd() *cobra.Command {
+	runsCmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Inspect and replay recorded runs",
+		Long: `Lists, shows and replays the runs ossa run recorded: their input, steps,
+tool calls, tokens and outcome. Runs are kept as JSON files in the runs_dir
+setting, by default runs beside the config file.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded runs, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  runRunsList,
+	}
+	listCmd.Flags().StringVar(&runsName, "name", "", "Only runs of this agent or workflow")
+	listCmd.Flags().StringVar(&runsStatus, "status", "", "Only runs with this status: running, succeeded or failed")
+	listCmd.Flags().DurationVar(&runsSince, "since", 0, "Only runs started within this long, e.g. 24h")
+	listCmd.Flags().IntVar(&runsLimit, "limit", 20, "Maximum runs to list (0 for all)")
+
+	showCmd := &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show a run's steps, tool calls and output",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRunsShow,
+	}
+	showCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Print the whole run, with the model conversations, as JSON")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <run-id>",
+		Short: "Re-execute a recorded run from its recorded responses",
+		Long: `Re-executes a recorded run's manifest on the recorded input, answering
+each model turn, tool call and Task step from the recording instead of
+calling them, and reports whether the run is reproduced. A manifest or
+engine change that alters a prompt, a tool call or a step's input makes
+the replay diverge, and the first difference is reported. Nothing is
+recorded.
+
+--live runs the manifest again for real instead, as a new recorded run,
+and reports how its output differs from the recorded output. It samples
+with --seed, or else the recorded run's seed, so models that accept a seed
+come as close to the recorded answers as they can.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRunsReplay,
+	}
+	replayCmd.Flags().BoolVar(&runsLive, "live", false, "Call models and tools again instead of the recording")
+	replayCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	replayCmd.Flags().IntVar(&runSeed, "seed", 0, "Sampling seed for --live (default: the recorded run's)")
+	replayCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	addRegistryFlags(replayCmd)
+
+	runsCmd.AddCommand(listCmd, showCmd, replayCmd)
+	return runsCmd
+}
+
+// runsStore is the store named by the runs_dir setting.
+func runsStore() (runs.Store, error) {
+	var dir string
+	if settings != nil {
+		dir = settings.Value("runs_dir")
+	}
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("no runs directory: set runs_dir with ossa config set: %w", err)
+		}
+		dir = filepath.Join(config, "ossa", "runs")
+	}
+	return &runs.DirStore{Dir: dir}, nil
+}
+
+func runRunsList(cmd *cobra.Command, args []string) error {
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	f := runs.Filter{Name: runsName, Status: runsStatus, Limit: runsLimit}
+	if runsSince > 0 {
+		f.Since = time.Now().Add(-runsSince)
+	}
+	list, err := store.List(context.Background(), f)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No runs recorded.")
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tNAME\tSTATUS\tSTARTED\tDURATION\tTOKENS\tTOOL CALLS")
+	for _, r := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n", r.ID, r.Kind, nameVersion(r.Name, r.Version), r.Status,
+			r.Started.Local().Format("2006-01-02 15:04:05"), r.Duration().Round(time.Millisecond),
+			r.Usage.InputTokens+r.Usage.OutputTokens, r.ToolCalls())
+	}
+	return w.Flush()
+}
+
+func runRunsShow(cmd *cobra.Command, args []string) error {
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	r, err := store.Get(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if outputJSON {
+		out, err := marshalRun(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), string(out))
+		return nil
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "Run %s: %s %s, %s\n", r.ID, r.Kind, nameVersion(r.Name, r.Version), r.Status)
+	if r.Source != "" {
+		fmt.Fprintf(w, "Source:  %s\n", r.Source)
+	}
+	fmt.Fprintf(w, "Started: %s (%s)\n", r.Started.Local().Format("2006-01-02 15:04:05"), r.Duration().Round(time.Millisecond))
+	fmt.Fprintf(w, "Tokens:  %d in, %d out\n", r.Usage.InputTokens, r.Usage.OutputTokens)
+	if r.Seed != nil {
+		fmt.Fprintf(w, "Seed:    %d\n", *r.Seed)
+	}
+	fmt.Fprintf(w, "Input:   %s\n", compactJSON(r.Input))
+	if r.Agent != nil {
+		printAgentRun(w, "", r.Agent)
+	}
+	if len(r.Steps) > 0 {
+		fmt.Fprintln(w, "Steps:")
+		for _, s := range r.Steps {
+			switch {
+			case s.Skipped:
+				fmt.Fprintf(w, "  %s: skipped\n", s.Step)
+			case s.Result != nil:
+				fmt.Fprintf(w, "  %s: %s\n", s.Step, nameVersion(s.Result.Agent, s.Result.Version))
+				printAgentRun(w, "    ", s.Result)
+			default:
+				fmt.Fprintf(w, "  %s (%s) → %s\n", s.Step, s.Kind, compactJSON(s.Output))
+			}
+			if s.Shadow != nil {
+				fmt.Fprintf(w, "    shadow %s: match %v, %d differences\n", s.Shadow.CandidateVersion, s.Shadow.Match, len(s.Shadow.Differences))
+			}
+		}
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error:   %s\n", r.Error)
+	}
+	if r.Output != nil {
+		fmt.Fprintf(w, "Output:  %s\n", compactJSON(r.Output))
+	}
+	return nil
+}
+
+// printAgentRun prints an agent's turns, tool calls and answer.
+func printAgentRun(w io.Writer, indent string, res *engine.Result) {
+	fmt.Fprintf(w, "%s%d turns, %d tokens\n", indent, res.Turns, res.Usage.InputTokens+res.Usage.OutputTokens)
+	for _, c := range res.ToolCalls {
+		outcome := truncate(c.Output, 120)
+		if c.Error != "" {
+			outcome = "error: " + c.Error
+		}
+		fmt.Fprintf(w, "%s→ %s %s: %s\n", indent, c.Tool, compactJSON(c.Arguments), outcome)
+	}
+	if res.Output != nil {
+		fmt.Fprintf(w, "%s← %s\n", indent, truncate(compactJSON(res.Output), 200))
+	}
+}
+
+func runRunsReplay(cmd *cobra.Command, args []string) error {
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	recorded, err := store.Get(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if recorded.Source == "" {
+		return fmt.Errorf("run %s did not record its manifest", recorded.ID)
+	}
+	seed := seedFlag(cmd)
+	if seed != nil && !runsLive {
+		return fmt.Errorf("--seed needs --live: a replay answers from the recording")
+	}
+	if runsLive {
+		if seed == nil {
+			seed = recorded.Seed
+		}
+		run, err := runManifest(cmd, recorded.Source, recorded.Input, runOptions{seed: seed})
+		if err != nil {
+			return err
+		}
+		return printReplay(cmd, recorded, run)
+	}
+
+	run, err := runManifest(cmd, recorded.Source, recorded.Input, runOptions{replay: recorded})
+	if errors.Is(err, runs.ErrDiverged) {
+		fmt.Fprintf(cmd.OutOrStdout(), "❌ %v\n", err)
+		return fmt.Errorf("run %s was not reproduced", recorded.ID)
+	}
+	if recorded.Status == runs.StatusFailed && errors.Is(err, runs.ErrFailedHere) {
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ run %s reproduced up to its failure: %s\n", recorded.ID, recorded.Error)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if run.Usage != recorded.Usage {
+		fmt.Fprintf(cmd.OutOrStdout(), "⚠ tokens differ from run %s: %d -> %d\n", recorded.ID,
+			recorded.Usage.InputTokens+recorded.Usage.OutputTokens, run.Usage.InputTokens+run.Usage.OutputTokens)
+	}
+	return printReplay(cmd, recorded, run)
+}
+
+// printReplay reports how a replay's output differs from the recorded run's.
+func printReplay(cmd *cobra.Command, recorded, run *runs.Run) error {
+	changes := ossa.DiffValues(jsonValue(recorded.Output), jsonValue(run.Output))
+	if len(changes) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "✅ output matches run %s\n", recorded.ID)
+		return nil
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "⚠ output differs from run %s:\n", recorded.ID)
+	for _, c := range changes {
+		fmt.Fprintf(cmd.OutOrStdout(), "    %s\n", c)
+	}
+	return nil
+}
+
+func nameVersion(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// compactJSON renders a JSON object on one line, {} for nil.
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if string(data) == "null" {
+		return "{}"
+	}
+	return string(data)
+}
+
+// jsonValue normalizes v to its decoded JSON form for comparison.
+func jsonValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
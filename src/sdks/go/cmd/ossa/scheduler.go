@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/scheduler"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schedulerAddr  string
+	schedulerToken string
+	schedulerPoll  time.Duration
+)
+
+func newSchedulerCmd() *cobra.Command {
+	schedulerCmd := &cobra.Command{
+		Use:   "scheduler <manifest|dir>...",
+		Short: "Run agents, Tasks and Workflows on their triggers",
+		Long: `Runs the Tasks and Workflows in the given files and directories on their
+spec.triggers until interrupted, and agents on their queue triggers,
+recording each run for ossa runs:
+
+  triggers:
+    - type: cron
+      schedule: "0 8 * * mon-fri"
+      input: {scope: daily}
+    - type: webhook
+      path: /hooks/report
+    - type: file
+      paths: ["inbox/*.csv"]
+    - type: queue
+      queue: {transport: nats, url_env: NATS_URL, subject: tickets.new, reply: tickets.triaged}
+
+Cron triggers run in local time, with input {"scheduled": time}. Webhook
+triggers are served on --addr: a POST to the path starts a run with the
+JSON body as input, and needs "Authorization: Bearer <token>" when
+--webhook-token is set. File triggers check their paths, relative to the
+manifest, every --poll and run with the changed files as input. Each
+trigger's own input is merged under what the event provides.
+
+Queue triggers run once per message, one message at a time, with the
+message's JSON object as input, or {"message": text} for anything else,
+and publish {"run", "status", "output", "error"} to the reply subject, or
+to the message's own reply address. NATS and in-process mem queues are
+built in.
+
+A job is not started by its cron or file triggers while an earlier run
+from them is still going. Tools needing approval are refused, since
+nobody is at the terminal to approve them.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runScheduler,
+	}
+	addRegistryFlags(schedulerCmd)
+	schedulerCmd.Flags().StringVar(&schedulerAddr, "addr", "localhost:8090", "Listen address for webhook triggers")
+	schedulerCmd.Flags().StringVar(&schedulerToken, "webhook-token", "", "Bearer token webhook requests must carry (default $OSSA_WEBHOOK_TOKEN)")
+	schedulerCmd.Flags().DurationVar(&schedulerPoll, "poll", scheduler.DefaultPoll, "How often file triggers check their files")
+	schedulerCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	schedulerCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	return schedulerCmd
+}
+
+func runScheduler(cmd *cobra.Command, args []string) error {
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	token := schedulerToken
+	if token == "" {
+		token = os.Getenv("OSSA_WEBHOOK_TOKEN")
+	}
+	s := &scheduler.Scheduler{
+		Engine: func(j *scheduler.Job) *engine.Engine {
+			return newEngine(filepath.Dir(j.Source), refuseApproval)
+		},
+		Store: store,
+		Token: token,
+		Poll:  schedulerPoll,
+	}
+	if err := addJobs(cmd, s, args); err != nil {
+		return err
+	}
+
+	webhooks := false
+	for _, j := range s.Jobs() {
+		for _, t := range j.Manifest.Spec.Triggers {
+			webhooks = webhooks || t.Type == ossa.TriggerWebhook
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: schedulerAddr, Handler: s.Handler()}
+	errc := make(chan error, 1)
+	if webhooks {
+		fmt.Fprintf(cmd.OutOrStdout(), "Webhooks on http://%s\n", schedulerAddr)
+		go func() {
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				errc <- err
+				stop()
+			}
+		}()
+	}
+	s.Run(ctx)
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdown)
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// addJobs adds the agents, Tasks and Workflows with triggers in paths.
+// Directories are scanned for them.
+func addJobs(cmd *cobra.Command, s *scheduler.Scheduler, paths []string) error {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := ossa.ScanWorkspace(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Err == nil && len(e.Manifest.Spec.Triggers) > 0 &&
+				(e.Manifest.Kind == ossa.KindAgent || e.Manifest.Kind == ossa.KindTask || e.Manifest.Kind == ossa.KindWorkflow) {
+				files = append(files, e.Path)
+			}
+		}
+	}
+	for _, path := range files {
+		j, err := scheduler.LoadJob(path)
+		if err != nil {
+			return err
+		}
+		if err := s.Add(j); err != nil {
+			return err
+		}
+		w := cmd.OutOrStdout()
+		fmt.Fprintf(w, "📅 %s %s\n", j.Manifest.Kind, j.Name())
+		if len(j.Manifest.Spec.Triggers) == 0 {
+			fmt.Fprintln(w, "    ⚠ no triggers")
+		}
+		if j.Manifest.Kind == ossa.KindTask {
+			fmt.Fprintln(w, "    ⚠ ossa has no Task runner; its runs will fail")
+		}
+		for _, t := range j.Manifest.Spec.Triggers {
+			switch t.Type {
+			case ossa.TriggerCron:
+				c, _ := ossa.ParseCron(t.Schedule)
+				fmt.Fprintf(w, "    cron %q, next at %s\n", t.Schedule, c.Next(time.Now()).Format("2006-01-02 15:04"))
+			case ossa.TriggerWebhook:
+				fmt.Fprintf(w, "    webhook POST %s\n", t.Path)
+			case ossa.TriggerFile:
+				fmt.Fprintf(w, "    file %v\n", t.Paths)
+			case ossa.TriggerQueue:
+				fmt.Fprintf(w, "    queue %s %s\n", t.Queue.Transport, t.Queue.Subject)
+			default:
+				fmt.Fprintf(w, "    %s (not run by the scheduler)\n", t.Type)
+			}
+		}
+	}
+	if len(s.Jobs()) == 0 {
+		return fmt.Errorf("no agents, Tasks or Workflows with triggers found")
+	}
+	return nil
+}
+
+// refuseApproval turns down approvals, which a daemon cannot ask for.
+func refuseApproval(_ context.Context, req ossa.ApprovalRequest) (bool, error) {
+	ossa.Logger().Warn("refused tool call needing approval", "agent", req.Agent, "tool", req.Tool)
+	return false, nil
+}
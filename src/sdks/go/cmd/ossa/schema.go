@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaAddVersion string
+	schemaAddSHA256  string
+)
+
+// maxSchemaSize bounds downloaded schemas.
+const maxSchemaSize = 16 << 20
+
+func newSchemaCmd() *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Manage vendored schemas",
+		Long: `Vendors schemas into .ossa/schemas so a project can validate against spec
+versions newer than the embedded one. Each schema is pinned by sha256 in
+.ossa/schemas/lock.yaml, and commands refuse a vendored schema that no longer
+matches its pin. Vendored schemas win over the embedded one for the same
+version.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <url|file>",
+		Short: "Vendor a schema and pin its sha256",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSchemaAdd,
+	}
+	addCmd.Flags().StringVar(&schemaAddVersion, "version", "", "Spec version (default: from the schema's $id or title)")
+	addCmd.Flags().StringVar(&schemaAddSHA256, "sha256", "", "Expected sha256; the schema is rejected if it differs")
+
+	updateCmd := &cobra.Command{
+		Use:   "update [version...]",
+		Short: "Re-fetch vendored schemas from their sources and re-pin",
+		RunE:  runSchemaUpdate,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List vendored schemas and check their pins",
+		Args:  cobra.NoArgs,
+		RunE:  runSchemaList,
+	}
+
+	schemaCmd.AddCommand(addCmd, updateCmd, listCmd)
+	return schemaCmd
+}
+
+// schemaStore returns the project's schema store, creating a handle for
+// ./.ossa/schemas if there is none yet.
+func schemaStore() *ossa.DirSchemaStore {
+	if store := ossa.FindSchemaStore("."); store != nil {
+		return store
+	}
+	return &ossa.DirSchemaStore{Dir: ossa.VendorDir}
+}
+
+func runSchemaAdd(cmd *cobra.Command, args []string) error {
+	data, err := fetchSchema(args[0])
+	if err != nil {
+		return err
+	}
+	version := schemaAddVersion
+	if version == "" {
+		if version = ossa.SchemaVersion(data); version == "" {
+			return fmt.Errorf("cannot tell the schema's version; pass --version")
+		}
+	}
+	if schemaAddSHA256 != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(schemaAddSHA256, got) {
+			return fmt.Errorf("sha256 mismatch: expected %s, got %s", schemaAddSHA256, got)
+		}
+	}
+	entry, err := schemaStore().Add(version, args[0], data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Vendored schema %s (sha256 %s)\n", entry.Version, entry.SHA256)
+	return nil
+}
+
+func runSchemaUpdate(cmd *cobra.Command, args []string) error {
+	store := ossa.FindSchemaStore(".")
+	if store == nil {
+		return fmt.Errorf("no %s directory; add a schema first", ossa.VendorDir)
+	}
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+	want := map[string]bool{}
+	for _, v := range args {
+		want[v] = true
+	}
+	for _, e := range entries {
+		if len(want) > 0 && !want[e.Version] {
+			continue
+		}
+		delete(want, e.Version)
+		data, err := fetchSchema(e.Source)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", e.Version, err)
+		}
+		updated, err := store.Add(e.Version, e.Source, data)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", e.Version, err)
+		}
+		if updated.SHA256 == e.SHA256 {
+			fmt.Printf("✅ %s is up to date\n", e.Version)
+		} else {
+			fmt.Printf("✅ Updated %s: sha256 %s -> %s\n", e.Version, e.SHA256, updated.SHA256)
+		}
+	}
+	for v := range want {
+		return fmt.Errorf("schema %s is not vendored", v)
+	}
+	return nil
+}
+
+func runSchemaList(cmd *cobra.Command, args []string) error {
+	store := ossa.FindSchemaStore(".")
+	if store == nil {
+		fmt.Printf("No vendored schemas; the embedded %s is used\n", ossa.OSSAVersion)
+		return nil
+	}
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tSHA256\tSOURCE")
+	failed := 0
+	for _, e := range entries {
+		status := "ok"
+		if _, err := store.Load(e.Version); err != nil {
+			status = err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Version, status, e.SHA256[:12], e.Source)
+	}
+	w.Flush()
+	if failed > 0 {
+		return fmt.Errorf("%d vendored schemas failed verification", failed)
+	}
+	return nil
+}
+
+// fetchSchema reads a schema from an http(s) URL or a file.
+func fetchSchema(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		return data, nil
+	}
+	if err := ossa.RequireNetwork("downloading " + source); err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download schema: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download schema: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download schema: %w", err)
+	}
+	if len(data) > maxSchemaSize {
+		return nil, fmt.Errorf("schema over %d bytes", maxSchemaSize)
+	}
+	return data, nil
+}
+
+// vendoredSchemaFor returns the verified vendored schema file for the
+// manifest's apiVersion, or "" when that version is not vendored.
+func vendoredSchemaFor(manifest string) (string, error) {
+	store := ossa.FindSchemaStore(".")
+	if store == nil {
+		return "", nil
+	}
+	m, err := ossa.LoadManifest(manifest)
+	if err != nil {
+		return "", nil // reported by validation
+	}
+	version := strings.TrimPrefix(m.APIVersion, "ossa/v")
+	if _, err := store.Load(version); err != nil {
+		if errors.Is(err, ossa.ErrSchemaNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return store.Path(version)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var selectorFlag string
+
+// addSelectorFlag adds --selector to a command reading many manifests.
+func addSelectorFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&selectorFlag, "selector", "l", "", "Only manifests whose labels match, e.g. team=ops,tier!=policy")
+}
+
+// selectEntries keeps the loaded entries whose labels match --selector.
+// Entries that failed to load are kept so their errors still show.
+func selectEntries(entries []ossa.CatalogEntry) ([]ossa.CatalogEntry, error) {
+	sel, err := labels.Parse(selectorFlag)
+	if err != nil || sel.Empty() {
+		return entries, err
+	}
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if e.Manifest == nil || sel.Matches(e.Manifest.Metadata.Labels) {
+			kept = append(kept, e)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/blueflyio/ossa-go/a2a"
+	"github.com/blueflyio/ossa-go/chatapi"
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr     string
+	serveTenants  string
+	serveAuth     string
+	serveRate     float64
+	serveBurst    int
+	serveMaxBody  int64
+	serveProxy    bool
+	serveRun      bool
+	serveA2AURL   string
+	serveA2AToken string
+	serveAPIKey   string
+	serveEncrypt  []string
+)
+
+func newServeCmd() *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve [dir]",
+		Short: "Serve the validation API and web UI",
+		Long: `Starts an HTTP server with a validation API and a web UI offering
+drag-and-drop validation, a searchable catalog of the agents in dir, and a
+documentation page per agent. Without dir only validation is available.
+
+With --tenants, agents are isolated by metadata.namespace: requests need a
+bearer token from the tenants file, callers only see their namespaces, and
+they may publish manifests into them within their quotas:
+
+  tenants:
+    - namespace: payments
+      tokens: [s3cr3t]
+      quota: {max_agents: 50, max_manifest_bytes: 65536}
+
+With --auth, API keys and OIDC/JWT bearer tokens are accepted too, with read,
+publish, run and admin roles taken from the key or from token claims. Every
+validation and publish is audit logged with the caller's identity:
+
+  api_keys:
+    - {key: 9f2c..., subject: ci, roles: [read, publish], namespaces: [payments]}
+  jwt:
+    issuer: https://accounts.example.com
+    audience: ossa
+    role_map: {ossa-admins: admin}
+
+With --rate, each client IP may make that many requests per second, in
+bursts of up to --burst; others get 429 with a Retry-After header. Request
+bodies over --max-body bytes get 413. Request, rate limit, payload and
+validation counters are exported at /metrics for Prometheus.
+
+With --run, callers with the run role (anyone, without credentials) may
+run the catalog's agents and workflows, recorded for ossa runs:
+
+  POST /api/agents/{namespace}/{name}/runs   start a run with a JSON input
+  GET  /api/runs/{id}/events                 follow it over SSE or WebSocket
+  POST /api/runs/{id}/approvals/{call_id}    approve a tool call: {"approved": true}
+  DELETE /api/runs/{id}                      cancel it
+
+Events are JSON objects with a type: run_started, agent_started, token,
+tool_call, tool_result, approval_required, approval_decided,
+agent_finished, step_started, step_finished and run_finished. Tool calls
+needing approval wait for a decision, over the API or as a WebSocket
+message {"type": "approval", "call_id": ..., "approved": true}.
+
+With --encrypt-to, published and archived manifests are stored encrypted
+to those age recipients or KMS keys (see ossa encrypt). The server reads
+them with --identity, and pulls return them decrypted to callers the
+registry authorizes.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runServe,
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Listen address")
+	serveCmd.Flags().StringVar(&serveTenants, "tenants", "", "Tenants file enabling per-namespace tokens, quotas and publishing")
+	serveCmd.Flags().StringVar(&serveAuth, "auth", "", "Auth file with API keys and OIDC/JWT settings")
+	serveCmd.Flags().Float64Var(&serveRate, "rate", 0, "Requests per second allowed per client IP (0 for unlimited)")
+	serveCmd.Flags().IntVar(&serveBurst, "burst", 0, "Requests a client may burst above --rate (default: the rate rounded up)")
+	serveCmd.Flags().Int64Var(&serveMaxBody, "max-body", 0, "Largest request body in bytes (default: the manifest size limit)")
+	serveCmd.Flags().BoolVar(&serveProxy, "trust-proxy", false, "Rate limit by the client IP a reverse proxy puts in X-Forwarded-For")
+	serveCmd.Flags().BoolVar(&serveRun, "run", false, "Allow running agents and workflows through the API")
+	serveCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable, with --run)")
+	serveCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent (with --run)")
+	serveCmd.Flags().StringVar(&runLocale, "locale", "", "Locale for requests without an Accept-Language header (default en)")
+	serveCmd.Flags().StringArrayVar(&serveEncrypt, "encrypt-to", nil, "age public key or KMS key URI to encrypt stored manifests to (repeatable)")
+
+	a2aCmd := &cobra.Command{
+		Use:   "a2a <manifest>",
+		Short: "Serve an agent to Agent2Agent (A2A) clients",
+		Long: `Serves the agent, Task or Workflow in manifest over the A2A protocol, so
+A2A orchestrators can hand it tasks. Its agent card is at
+/.well-known/agent.json and the JSON-RPC methods tasks/send,
+tasks/sendSubscribe (streaming over SSE), tasks/get, tasks/cancel and
+tasks/resubscribe at /. Each task is recorded for ossa runs.
+
+A task's input is the JSON object of its message's data parts, with its
+text parts as "message"; the run's output becomes the task's artifact.
+Tool calls needing approval put the task in the input-required state
+until the client answers with a message of "yes" or "no", or a data part
+{"approved": true}.
+
+With --token, task requests need "Authorization: Bearer <token>"; the
+agent card stays public.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runServeA2A,
+	}
+	a2aCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Listen address")
+	a2aCmd.Flags().StringVar(&serveA2AURL, "url", "", "Endpoint the agent card advertises (default: the address it was fetched from)")
+	a2aCmd.Flags().StringVar(&serveA2AToken, "token", "", "Bearer token task requests must carry (default $OSSA_A2A_TOKEN)")
+	a2aCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	a2aCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	a2aCmd.Flags().StringVar(&runLocale, "locale", "", "Locale for requests without an Accept-Language header (default en)")
+
+	openaiCmd := &cobra.Command{
+		Use:   "openai <manifest>",
+		Short: "Serve an agent behind the OpenAI chat completions API",
+		Long: `Serves the agent in manifest as an OpenAI-compatible chat model, so
+clients, SDKs and chat UIs built for OpenAI can talk to it by pointing
+their base URL at http://<addr>/v1:
+
+  POST /v1/chat/completions   chat with the agent, streamed with "stream": true
+  GET  /v1/models             the agent, as the one model
+
+The last user message is the run's input "message", and the messages
+before it its "history". The agent's own prompt, model, tools and
+spec.safety.moderation apply: blocked prompts get a 400 content_filter
+error and blocked answers finish with content_filter. Tool calls needing
+approval are refused, since nobody is there to approve them. Each request
+is recorded for ossa runs.
+
+With --api-key, clients must send it as their OpenAI API key.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runServeOpenAI,
+	}
+	openaiCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Listen address")
+	openaiCmd.Flags().StringVar(&serveAPIKey, "api-key", "", "API key clients must send (default $OSSA_API_KEY)")
+	openaiCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	openaiCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	openaiCmd.Flags().StringVar(&runLocale, "locale", "", "Locale of localized roles and prompts, e.g. de-AT (default en)")
+	serveCmd.AddCommand(a2aCmd, openaiCmd)
+	return serveCmd
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	opts := server.Options{
+		RateLimit:    server.RateLimit{Rate: serveRate, Burst: serveBurst, TrustProxy: serveProxy},
+		MaxBodyBytes: serveMaxBody,
+	}
+	if len(args) == 1 {
+		opts.Dir = args[0]
+	}
+	if serveTenants != "" {
+		tenants, err := server.LoadTenants(serveTenants)
+		if err != nil {
+			return err
+		}
+		opts.Tenants = tenants
+	}
+	if serveAuth != "" {
+		auth, err := server.LoadAuth(serveAuth)
+		if err != nil {
+			return err
+		}
+		opts.Auth = auth
+	}
+	if len(serveEncrypt) > 0 {
+		if len(identityFlags) == 0 && settings.Value("identity") == "" {
+			return fmt.Errorf("--encrypt-to needs an --identity to read the manifests back")
+		}
+		k, err := keyring(nil, serveEncrypt)
+		if err != nil {
+			return err
+		}
+		opts.Encrypt = k.Encrypt
+	}
+	if serveRun {
+		if opts.Dir == "" {
+			return fmt.Errorf("--run needs a workspace dir")
+		}
+		store, err := runsStore()
+		if err != nil {
+			return err
+		}
+		opts.Store = store
+		opts.Engine = func(entry ossa.CatalogEntry, approve ossa.ApprovalFunc) *engine.Engine {
+			return newEngine(filepath.Dir(entry.Path), approve)
+		}
+	}
+	fmt.Printf("Serving on http://%s\n", serveAddr)
+	return http.ListenAndServe(serveAddr, server.New(opts))
+}
+
+func runServeA2A(cmd *cobra.Command, args []string) error {
+	m, err := ossa.LoadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	token := serveA2AToken
+	if token == "" {
+		token = os.Getenv("OSSA_A2A_TOKEN")
+	}
+	dir := filepath.Dir(args[0])
+	s, err := a2a.New(m, args[0], a2a.Options{
+		Engine: func(approve ossa.ApprovalFunc) *engine.Engine {
+			return newEngine(dir, approve)
+		},
+		Store:  store,
+		URL:    serveA2AURL,
+		Token:  token,
+		Locale: runLocale,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Serving %s over A2A on http://%s\n", m.Metadata.Name, serveAddr)
+	return http.ListenAndServe(serveAddr, s)
+}
+
+func runServeOpenAI(cmd *cobra.Command, args []string) error {
+	m, err := ossa.LoadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	key := serveAPIKey
+	if key == "" {
+		key = os.Getenv("OSSA_API_KEY")
+	}
+	s, err := chatapi.New(m, args[0], chatapi.Options{
+		Engine: newEngine(filepath.Dir(args[0]), refuseApproval),
+		Store:  store,
+		APIKey: key,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Serving %s as an OpenAI model on http://%s/v1\n", m.Metadata.Name, serveAddr)
+	return http.ListenAndServe(serveAddr, s)
+}
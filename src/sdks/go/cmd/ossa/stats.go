@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+func newStatsCmd() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats [dir]",
+		Short: "Report aggregate numbers for a workspace",
+		Long: `Scans a workspace (default ".") and reports agents per access tier, LLM
+providers and models, tools per agent, safety coverage and spec versions in
+use. Nothing leaves the machine; --json output is meant for governance
+dashboards.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runStats,
+	}
+	statsCmd.Flags().BoolVarP(&outputJSON, "json", "j", false, "Output as JSON")
+	addSelectorFlag(statsCmd)
+	return statsCmd
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	entries, err := ossa.ScanWorkspace(dir)
+	if err != nil {
+		return err
+	}
+	if entries, err = selectEntries(entries); err != nil {
+		return err
+	}
+	stats := ossa.ComputeStats(entries)
+	out := cmd.OutOrStdout()
+
+	if outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	fmt.Fprintf(out, "%s: %d manifests, %d agents", dir, stats.Manifests, stats.Agents)
+	if stats.LoadErrors > 0 {
+		fmt.Fprintf(out, " (%d failed to load)", stats.LoadErrors)
+	}
+	fmt.Fprintln(out)
+
+	kinds := map[string]int{}
+	for k, n := range stats.Kinds {
+		kinds[string(k)] = n
+	}
+	printCounts(out, "Kinds", kinds)
+	printCounts(out, "Spec versions", stats.APIVersions)
+	printCounts(out, "Agents per tier", stats.Tiers)
+	printCounts(out, "Providers", stats.Providers)
+	printCounts(out, "Models", stats.Models)
+	printCounts(out, "Tool types", stats.ToolTypes)
+
+	if stats.Agents > 0 {
+		t := stats.ToolsPerAgent
+		fmt.Fprintf(out, "\nTools per agent: min %d, median %g, mean %.1f, max %d\n", t.Min, t.Median, t.Mean, t.Max)
+		c := stats.Coverage
+		fmt.Fprintln(out, "\nSafety coverage")
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, row := range []struct {
+			name string
+			n    int
+		}{
+			{"guardrails", c.Guardrails},
+			{"human approval", c.HumanApproval},
+			{"moderation", c.Moderation},
+			{"injection detection", c.InjectionDetection},
+			{"none", c.None},
+		} {
+			fmt.Fprintf(w, "  %s\t%d\t%.0f%%\n", row.name, row.n, 100*float64(row.n)/float64(stats.Agents))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// printCounts prints a titled table of counts, largest first.
+func printCounts(out io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(out, "\n%s\n", title)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s\t%d\n", k, counts[k])
+	}
+	w.Flush()
+}
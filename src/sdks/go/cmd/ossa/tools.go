@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runtime/drupal"
+	"github.com/blueflyio/ossa-go/runtime/exec"
+	"github.com/blueflyio/ossa-go/runtime/fs"
+	httptool "github.com/blueflyio/ossa-go/runtime/http"
+	"github.com/blueflyio/ossa-go/runtime/queue"
+	"github.com/blueflyio/ossa-go/runtime/script"
+	"github.com/blueflyio/ossa-go/runtime/slack"
+	"github.com/blueflyio/ossa-go/runtime/smtp"
+	"github.com/blueflyio/ossa-go/runtime/sql"
+	"github.com/blueflyio/ossa-go/runtime/wasm"
+)
+
+// endpointPolicy, from the endpoint_policy setting, restricts the HTTP
+// requests tool runtimes make; nil leaves them unrestricted.
+var endpointPolicy *ossa.EndpointPolicy
+
+// toolPolicies enforces tools' handler timeout, retries and circuit_breaker
+// across the engines of the process, such as those of ossa serve's runs,
+// and keeps handler.cache results in memory, whatever its backend: the CLI
+// has no Redis client. Results are kept per --session, and in ossa serve
+// per caller, as engine.ToolPolicies scopes them.
+var toolPolicies = &engine.ToolPolicies{Cache: ossa.NewToolCache(ossa.NewMemoryCache())}
+
+// toolClient returns the client a tool's runtime makes HTTP requests
+// with: dialing with its handler.tls settings through the egress proxy,
+// with timeout, enforcing endpointPolicy.
+func toolClient(m *ossa.Manifest, tool ossa.ToolConfig, baseDir string, timeout time.Duration) (*http.Client, error) {
+	transport, err := ossa.ToolTransport(m, tool, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+	if endpointPolicy != nil {
+		client = endpointPolicy.Client(client)
+	}
+	return client, nil
+}
+
+// toolRuntimes builds each agent's tools from their handler.runtime, on
+// first call. baseDir is the manifest's directory; allowed lists the
+// commands exec tools may run; approve asks before actions that need a
+// human decision.
+func toolRuntimes(baseDir string, allowed []string, approve ossa.ApprovalFunc) engine.ToolsFunc {
+	return func(m *ossa.Manifest) ossa.ToolExecFunc {
+		var mu sync.Mutex
+		runtimes := map[string]ossa.ToolExecFunc{}
+		return func(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+			mu.Lock()
+			run, ok := runtimes[call.Tool]
+			if !ok {
+				var err error
+				if run, err = newToolRuntime(ctx, m, call.Tool, baseDir, allowed, approve); err != nil {
+					mu.Unlock()
+					return nil, err
+				}
+				runtimes[call.Tool] = run
+			}
+			mu.Unlock()
+			return run(ctx, call)
+		}
+	}
+}
+
+func newToolRuntime(ctx context.Context, m *ossa.Manifest, name, baseDir string, allowed []string, approve ossa.ApprovalFunc) (ossa.ToolExecFunc, error) {
+	var tool *ossa.ToolConfig
+	for i := range m.Spec.Tools {
+		if m.Spec.Tools[i].Name == name {
+			tool = &m.Spec.Tools[i]
+		}
+	}
+	if tool == nil {
+		return nil, fmt.Errorf("%s has no tool %s", m.Metadata.Name, name)
+	}
+	if !tool.EnabledFor(runSession, runFlagSet()) {
+		return nil, fmt.Errorf("tool %s is not enabled (%s)", name, tool.RolloutStatus())
+	}
+	if tool.Handler == nil || tool.Handler.Runtime == "" {
+		return nil, fmt.Errorf("tool %s has no handler.runtime", name)
+	}
+	if tool.Handler.TLS != nil && tool.Handler.TLS.InsecureSkipVerify {
+		ossa.Logger().Warn("tool skips TLS certificate verification", "agent", m.Metadata.Name, "tool", name)
+	}
+	switch tool.Handler.Runtime {
+	case ossa.RuntimeExec:
+		r, err := exec.New(m, *tool, exec.Options{BaseDir: baseDir, AllowedCommands: allowed, Audit: func(rec exec.AuditRecord) {
+			ossa.Logger().Info("exec", "agent", rec.Agent, "tool", rec.Tool, "command", rec.Command, "exit", rec.ExitCode, "duration", rec.Duration)
+		}})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeFS:
+		r, err := fs.New(m, *tool, fs.Options{BaseDir: baseDir})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeScript:
+		client, err := toolClient(m, *tool, baseDir, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		r, err := script.New(m, *tool, script.Options{BaseDir: baseDir, Client: client})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeWASM:
+		r, err := wasm.New(ctx, m, *tool, wasm.Options{BaseDir: baseDir})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeSQL:
+		// The CLI registers no database/sql drivers; programs embedding the
+		// engine import the ones they need.
+		if cfg := tool.Handler.SQL; cfg != nil && !slices.Contains(stdsql.Drivers(), cfg.Driver) {
+			return nil, fmt.Errorf("tool %s: the ossa CLI has no %s driver; run sql tools from a Go program that imports one (see package runtime/sql)", name, cfg.Driver)
+		}
+		r, err := sql.New(m, *tool, sql.Options{})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeDrupal:
+		client, err := toolClient(m, *tool, baseDir, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		r, err := drupal.New(m, *tool, drupal.Options{Client: client, BaseDir: baseDir})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeSlack:
+		client, err := toolClient(m, *tool, baseDir, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		r, err := slack.New(m, *tool, slack.Options{Approve: approve, Client: client, BaseDir: baseDir})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeHTTP:
+		client, err := toolClient(m, *tool, baseDir, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		r, err := httptool.New(m, *tool, httptool.Options{Client: client, BaseDir: baseDir})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeSMTP:
+		r, err := smtp.New(m, *tool, smtp.Options{Approve: approve})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	case ossa.RuntimeQueue:
+		r, err := queue.New(m, *tool, queue.Options{Approve: approve})
+		if err != nil {
+			return nil, err
+		}
+		return r.Execute, nil
+	}
+	return nil, fmt.Errorf("tool %s: unknown handler.runtime %q", name, tool.Handler.Runtime)
+}
+
+// promptApproval asks on the terminal; anything but y or yes denies.
+func promptApproval(in io.Reader, out io.Writer) ossa.ApprovalFunc {
+	var mu sync.Mutex
+	scanner := bufio.NewScanner(in)
+	return func(_ context.Context, req ossa.ApprovalRequest) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "✋ %s wants to call %s:\n%s\nApprove? [y/N] ", req.Agent, req.Tool, req.Summary)
+		if !scanner.Scan() {
+			return false, scanner.Err()
+		}
+		answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		return answer == "y" || answer == "yes", nil
+	}
+}
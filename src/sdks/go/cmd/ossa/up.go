@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upMockModel bool
+	upMockTools bool
+)
+
+func newUpCmd() *cobra.Command {
+	upCmd := &cobra.Command{
+		Use:   "up [dir]",
+		Short: "Validate a workspace and run it locally with the web UI",
+		Long: `Validates every manifest in dir (default .), then serves the workspace as
+ossa serve --run does: the web UI and catalog at http://<addr>/, and the
+run API for its agents and workflows. Nothing starts if a manifest fails
+to load or validate.
+
+The events of every run are printed as they happen, one line each, so
+runs started from the UI or the API can be followed in the terminal.
+
+--mock-model answers for every agent without calling a provider: the
+first turn calls each tool offered once, with no arguments, and the next
+answers with {"mock": true}. --mock-tools answers every tool call with
+{"stubbed": true} instead of running it. Together they run a workspace
+with no keys, network or side effects, for demos and UI work.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runUp,
+	}
+	upCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Listen address")
+	upCmd.Flags().BoolVar(&upMockModel, "mock-model", false, "Answer for every agent's model instead of calling its provider")
+	upCmd.Flags().BoolVar(&upMockTools, "mock-tools", false, "Stub every tool call instead of running the tool")
+	upCmd.Flags().StringArrayVar(&runAllowCommand, "allow-command", nil, "Command exec tools may run (repeatable)")
+	upCmd.Flags().IntVar(&runMaxTurns, "max-turns", engine.DefaultMaxTurns, "Maximum model turns per agent")
+	upCmd.Flags().StringVar(&runLocale, "locale", "", "Locale for requests without an Accept-Language header (default en)")
+	return upCmd
+}
+
+func runUp(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	entries, err := ossa.ScanWorkspace(dir)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, e := range entries {
+		if e.Err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", e.Path, e.Err))
+			continue
+		}
+		if result := ossa.ValidateManifest(e.Manifest); !result.Valid {
+			problems = append(problems, fmt.Sprintf("%s: %s", e.Path, strings.Join(result.Errors, "; ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("nothing started:\n  %s", strings.Join(problems, "\n  "))
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no manifests in %s", dir)
+	}
+
+	store, err := runsStore()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	tail := &eventTail{w: out}
+	opts := server.Options{
+		Dir:   dir,
+		Store: store,
+		Engine: func(entry ossa.CatalogEntry, approve ossa.ApprovalFunc) *engine.Engine {
+			e := newEngine(filepath.Dir(entry.Path), approve)
+			if upMockModel {
+				e.Model = mockModel{}
+			}
+			if upMockTools {
+				e.Tools = mockTools
+			}
+			e.Events = tail.printer(entry.Name)
+			return e
+		},
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(out, "✅ %s %s (%s)\n", e.Kind, e.Name, e.Path)
+	}
+	fmt.Fprintf(out, "Serving %d manifests on http://%s\n", len(entries), serveAddr)
+	return http.ListenAndServe(serveAddr, server.New(opts))
+}
+
+// eventTail prints the events of concurrent runs, a line at a time.
+type eventTail struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// printer returns an engine.Events printing the events of a run of entry.
+// Tokens are left out; the finished agent's output has their text.
+func (t *eventTail) printer(entry string) func(engine.Event) {
+	return func(ev engine.Event) {
+		if ev.Type == engine.EventToken {
+			return
+		}
+		line := entry + " " + ev.Type
+		for _, f := range []struct{ name, value string }{
+			{"step", ev.Step}, {"agent", ev.Agent}, {"tool", ev.Tool}, {"error", ev.Error},
+		} {
+			if f.value != "" {
+				line += " " + f.name + "=" + f.value
+			}
+		}
+		if ev.Output != nil {
+			data, _ := json.Marshal(ev.Output)
+			line += " output=" + string(data)
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		fmt.Fprintln(t.w, line)
+	}
+}
+
+// mockModel is the model of every agent under --mock-model.
+type mockModel struct{}
+
+func (mockModel) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != engine.RoleTool && len(req.Tools) > 0 {
+		resp := &engine.Response{Provider: "mock", Model: "mock"}
+		for i, t := range req.Tools {
+			resp.ToolCalls = append(resp.ToolCalls, ossa.ToolCall{ID: fmt.Sprintf("call_%d", i+1), Tool: t.Name, Arguments: map[string]interface{}{}})
+		}
+		return resp, nil
+	}
+	return &engine.Response{Content: `{"mock": true}`, Provider: "mock", Model: "mock"}, nil
+}
+
+// mockTools stubs every tool call under --mock-tools.
+func mockTools(*ossa.Manifest) ossa.ToolExecFunc {
+	return func(context.Context, ossa.ToolCall) ([]byte, error) {
+		return []byte(engine.StubOutput), nil
+	}
+}
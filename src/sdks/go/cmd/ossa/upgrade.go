@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/blueflyio/ossa-go/internal/selfupdate"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCheckOnly bool
+
+func newUpgradeCmd() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade ossa to the latest release",
+		Long: `Checks the release endpoint (the release_url setting) for a newer version,
+downloads the binary for this platform, verifies the signed checksums and
+replaces the running binary atomically. With --check-only nothing is
+downloaded; the exit status is non-zero when an upgrade is available.`,
+		Args: cobra.NoArgs,
+		RunE: runUpgrade,
+	}
+	upgradeCmd.Flags().BoolVar(&upgradeCheckOnly, "check-only", false, "Only report whether a newer release exists")
+	return upgradeCmd
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	u := &selfupdate.Updater{Endpoint: cfg.Value("release_url")}
+	rel, err := u.Latest(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if !selfupdate.Newer(ossa.Version, rel.Version) {
+		fmt.Printf("✅ ossa %s is up to date (latest %s)\n", ossa.Version, rel.Version)
+		return nil
+	}
+	if upgradeCheckOnly {
+		fmt.Printf("ossa %s is available (current %s); run ossa upgrade\n", rel.Version, ossa.Version)
+		return fmt.Errorf("upgrade available")
+	}
+
+	bin, err := u.Download(cmd.Context(), rel)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	if err := selfupdate.Replace(exe, bin); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Upgraded ossa %s -> %s\n", ossa.Version, rel.Version)
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/blueflyio/ossa-go/junit"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vetCUE    bool
+	vetCUEBin string
+)
+
+func newVetCmd() *cobra.Command {
+	vetCmd := &cobra.Command{
+		Use:   "vet [manifest|dir...]",
+		Short: "Validate several manifests",
+		Long: `Validates each manifest, and those found in each directory, and reports all
+failures, then checks the manifests against each other: two with the same
+kind, namespace and name (conflicting versions if their metadata.versions
+differ) fail, and label keys differing only in case are warned about.
+--selector limits the run to manifests with matching labels. With
+--cue the manifests
+are checked by the cue tool against the generated CUE definitions instead, so
+results match what a CUE pipeline unifying OSSA manifests would see.
+--format junit reports a testcase per manifest for CI.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runVet,
+	}
+	vetCmd.Flags().BoolVar(&vetCUE, "cue", false, "Validate with cue vet against the generated definitions")
+	vetCmd.Flags().StringVar(&vetCUEBin, "cue-bin", "cue", "cue executable used with --cue")
+	vetCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to custom schema (defaults to embedded v0.3.3)")
+	vetCmd.Flags().BoolVar(&warningsAsErrors, "warnings-as-errors", false, "Fail on warnings (ignored with --cue)")
+	vetCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text, json or junit")
+	addSelectorFlag(vetCmd)
+	return vetCmd
+}
+
+func runVet(cmd *cobra.Command, args []string) error {
+	paths, err := vetPaths(args)
+	if err != nil {
+		return err
+	}
+	format, err := outputFormat()
+	if err != nil {
+		return err
+	}
+	if format == "junit" {
+		if vetCUE {
+			return fmt.Errorf("--format junit cannot be used with --cue")
+		}
+		return vetJUnit(cmd, paths)
+	}
+	check := func(path string) error { return runValidate(cmd, []string{path}) }
+	if vetCUE {
+		var cleanup func()
+		var err error
+		check, cleanup, err = cueChecker()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+
+	failed := 0
+	for _, path := range paths {
+		if err := check(path); err != nil {
+			failed++
+		}
+	}
+	collided := 0
+	for _, c := range vetCollisions(paths) {
+		switch {
+		case format == "json":
+			data, _ := json.Marshal(struct {
+				Collision string   `json:"collision"`
+				Paths     []string `json:"paths"`
+				Warning   bool     `json:"warning"`
+			}{c.Message, c.Paths, c.Warning})
+			fmt.Println(string(data))
+		case c.Warning:
+			fmt.Printf("⚠ %s\n", c.Message)
+		default:
+			fmt.Printf("❌ %s\n", c.Message)
+		}
+		if !c.Warning || warningsAsErrors {
+			collided++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d manifests failed", failed, len(paths))
+	}
+	if collided > 0 {
+		return fmt.Errorf("%d collisions between manifests", collided)
+	}
+	return nil
+}
+
+// vetCollisions returns the collisions between the manifests at paths;
+// those that fail to load were reported already.
+func vetCollisions(paths []string) []ossa.Collision {
+	entries := make([]ossa.CatalogEntry, 0, len(paths))
+	for _, path := range paths {
+		if m, err := ossa.LoadManifest(path); err == nil {
+			entries = append(entries, ossa.CatalogEntry{Path: path, Manifest: m})
+		}
+	}
+	return ossa.CheckCollisions(entries)
+}
+
+// vetJUnit validates paths into one JUnit suite, a testcase per manifest;
+// a manifest that cannot be read is an error case.
+func vetJUnit(cmd *cobra.Command, paths []string) error {
+	suite := junit.Suite{Name: "ossa vet"}
+	began := time.Now()
+	for _, path := range paths {
+		start := time.Now()
+		result, err := validateManifest(cmd, path)
+		var c junit.Case
+		if err != nil {
+			c = junit.Case{Name: path, ClassName: "ossa.validate", Error: &junit.Problem{Message: "could not validate", Text: err.Error()}}
+		} else {
+			c = validationCase(path, result)
+		}
+		c.Time = junit.Seconds(time.Since(start))
+		suite.Cases = append(suite.Cases, c)
+	}
+	for _, col := range vetCollisions(paths) {
+		c := junit.Case{Name: col.Key, ClassName: "ossa.collisions"}
+		if col.Warning && !warningsAsErrors {
+			c.SystemOut = "warnings:\n  " + col.Message
+		} else {
+			c.Failure = &junit.Problem{Message: "collision", Text: col.Message}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = junit.Seconds(time.Since(began))
+	report := &junit.Report{}
+	report.Add(suite)
+	if err := writeJUnit(cmd, report); err != nil {
+		return err
+	}
+	if report.Failed() {
+		return fmt.Errorf("%d of %d checks failed", report.Failures+report.Errors, len(suite.Cases))
+	}
+	return nil
+}
+
+// vetPaths expands directories to the manifests below them and applies
+// --selector.
+func vetPaths(args []string) ([]string, error) {
+	var entries []ossa.CatalogEntry
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			found, err := ossa.ScanWorkspace(arg)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, found...)
+			continue
+		}
+		e := ossa.CatalogEntry{Path: arg}
+		if selectorFlag != "" {
+			e.Manifest, e.Err = ossa.LoadManifest(arg)
+		}
+		entries = append(entries, e)
+	}
+	entries, err := selectEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.Path
+	}
+	return paths, nil
+}
+
+// cueChecker writes the generated definitions to a temporary directory and
+// returns a function that runs cue vet on one manifest.
+func cueChecker() (func(path string) error, func(), error) {
+	if _, err := exec.LookPath(vetCUEBin); err != nil {
+		return nil, nil, fmt.Errorf("cue executable not found (see https://cuelang.org/docs/introduction/installation/): %w", err)
+	}
+	defs, err := ossa.ExportCUE(ossa.EmbeddedSchema())
+	if err != nil {
+		return nil, nil, err
+	}
+	dir, err := os.MkdirTemp("", "ossa-cue-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defsPath := filepath.Join(dir, "ossa.cue")
+	if err := os.WriteFile(defsPath, defs, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	check := func(path string) error {
+		// Checking against the manifest's own kind gives clearer errors
+		// than the #Manifest disjunction.
+		def := "#Manifest"
+		if m, err := ossa.LoadManifest(path); err == nil && ossa.ValidKinds[m.Kind] {
+			def = "#" + string(m.Kind)
+		}
+		var out bytes.Buffer
+		c := exec.Command(vetCUEBin, "vet", "-c", "-d", def, defsPath, path)
+		c.Stdout, c.Stderr = &out, &out
+		if err := c.Run(); err != nil {
+			fmt.Printf("❌ %s does not satisfy %s\n%s", path, def, out.String())
+			return err
+		}
+		fmt.Printf("✅ %s satisfies %s\n", path, def)
+		return nil
+	}
+	return check, func() { os.RemoveAll(dir) }, nil
+}
@@ -0,0 +1,301 @@
+// Package crypt encrypts manifests and bundles at rest in the age format
+// (https://age-encryption.org/v1), so files written for X25519 recipients
+// open with the age and rage tools and the reverse. A file is encrypted
+// once, to a random file key, which each recipient wraps in a stanza of
+// the header: X25519 recipients (age1...) with their public key, and KMS
+// recipients with a cloud or Vault key, so access is granted by KMS
+// policy rather than by holding a secret. Anyone holding an identity for
+// any stanza decrypts the whole file.
+//
+// Keyring collects recipients and identities from their string forms;
+// its Decrypt, set with ossa.SetDecrypter, makes encrypted manifests load
+// transparently.
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/internal/chacha20poly1305"
+)
+
+// Header begins every age file.
+const Header = "age-encryption.org/v1\n"
+
+// ErrNoIdentity is returned when no identity unwraps any stanza.
+var ErrNoIdentity = errors.New("no identity matched any of the file's recipients")
+
+// Stanza is one recipient's wrapping of the file key.
+type Stanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+// Recipient wraps a file key for one reader.
+type Recipient interface {
+	Wrap(fileKey []byte) ([]*Stanza, error)
+}
+
+// Identity unwraps a file key. It returns ErrNoIdentity when none of the
+// stanzas is for it.
+type Identity interface {
+	Unwrap(stanzas []*Stanza) ([]byte, error)
+}
+
+const (
+	fileKeySize = 16
+	chunkSize   = 64 << 10
+	columns     = 64
+)
+
+var b64 = base64.RawStdEncoding
+
+// IsEncrypted reports whether data is an age file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Header))
+}
+
+// Encrypt encrypts plaintext to recipients.
+func Encrypt(plaintext []byte, recipients ...Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients to encrypt to")
+	}
+	fileKey := make([]byte, fileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+	var hdr bytes.Buffer
+	hdr.WriteString(Header)
+	for _, r := range recipients {
+		stanzas, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stanzas {
+			writeStanza(&hdr, s)
+		}
+	}
+	hdr.WriteString("---")
+	mac, err := headerMAC(fileKey, hdr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	out := append(hdr.Bytes(), ' ')
+	out = append(out, b64.EncodeToString(mac)...)
+	out = append(out, '\n')
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	key, err := hkdfKey(fileKey, nonce, "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, _ := chacha20poly1305.New(key)
+	var counter [chacha20poly1305.NonceSize]byte
+	for {
+		n := len(plaintext)
+		last := n <= chunkSize
+		if !last {
+			n = chunkSize
+		}
+		if last {
+			counter[11] = 1
+		}
+		out = aead.Seal(out, counter[:], plaintext[:n], nil)
+		plaintext = plaintext[n:]
+		if last {
+			return out, nil
+		}
+		incrementCounter(&counter)
+	}
+}
+
+// Decrypt decrypts an age file with the first identity that unwraps its
+// file key.
+func Decrypt(data []byte, identities ...Identity) ([]byte, error) {
+	stanzas, hdrLen, mac, payload, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	var fileKey []byte
+	for _, id := range identities {
+		fileKey, err = id.Unwrap(stanzas)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrNoIdentity) {
+			return nil, err
+		}
+	}
+	if fileKey == nil {
+		return nil, ErrNoIdentity
+	}
+	want, err := headerMAC(fileKey, data[:hdrLen])
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, want) {
+		return nil, fmt.Errorf("age header does not verify")
+	}
+
+	if len(payload) < 16 {
+		return nil, fmt.Errorf("age payload is truncated")
+	}
+	key, err := hkdfKey(fileKey, payload[:16], "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, _ := chacha20poly1305.New(key)
+	payload = payload[16:]
+	var out []byte
+	var counter [chacha20poly1305.NonceSize]byte
+	for {
+		n := len(payload)
+		last := n <= chunkSize+chacha20poly1305.Overhead
+		if !last {
+			n = chunkSize + chacha20poly1305.Overhead
+		}
+		if last {
+			counter[11] = 1
+		}
+		chunk, err := aead.Open(nil, counter[:], payload[:n], nil)
+		if err != nil {
+			return nil, fmt.Errorf("age payload does not decrypt: %w", err)
+		}
+		if last && len(chunk) == 0 && len(out) > 0 {
+			return nil, fmt.Errorf("age payload has an empty final chunk")
+		}
+		out = append(out, chunk...)
+		payload = payload[n:]
+		if last {
+			return out, nil
+		}
+		incrementCounter(&counter)
+	}
+}
+
+func incrementCounter(c *[chacha20poly1305.NonceSize]byte) {
+	for i := 10; i >= 0; i-- {
+		c[i]++
+		if c[i] != 0 {
+			return
+		}
+	}
+	panic("age: chunk counter overflow")
+}
+
+// hkdfKey derives an n byte key with HKDF-SHA-256 (RFC 5869).
+func hkdfKey(secret, salt []byte, info string, n int) ([]byte, error) {
+	if n > 255*sha256.Size {
+		return nil, errors.New("hkdf: key too long")
+	}
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		expand.Reset()
+		expand.Write(t)
+		expand.Write([]byte(info))
+		expand.Write([]byte{i})
+		t = expand.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n], nil
+}
+
+func headerMAC(fileKey, header []byte) ([]byte, error) {
+	key, err := hkdfKey(fileKey, nil, "header", 32)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(header)
+	return h.Sum(nil), nil
+}
+
+func writeStanza(w *bytes.Buffer, s *Stanza) {
+	w.WriteString("-> " + s.Type)
+	for _, a := range s.Args {
+		w.WriteString(" " + a)
+	}
+	w.WriteByte('\n')
+	body := b64.EncodeToString(s.Body)
+	for len(body) >= columns {
+		w.WriteString(body[:columns] + "\n")
+		body = body[columns:]
+	}
+	w.WriteString(body + "\n")
+}
+
+// parseHeader returns the stanzas of an age file, the length of the header
+// the MAC covers, the MAC and the payload.
+func parseHeader(data []byte) ([]*Stanza, int, []byte, []byte, error) {
+	if !IsEncrypted(data) {
+		return nil, 0, nil, nil, fmt.Errorf("not an age file")
+	}
+	invalid := func(msg string) ([]*Stanza, int, []byte, []byte, error) {
+		return nil, 0, nil, nil, fmt.Errorf("invalid age header: %s", msg)
+	}
+	r := bufio.NewReader(bytes.NewReader(data[len(Header):]))
+	offset := len(Header)
+	readLine := func() (string, bool) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", false
+		}
+		offset += len(line)
+		return strings.TrimSuffix(line, "\n"), true
+	}
+	var stanzas []*Stanza
+	for {
+		line, ok := readLine()
+		if !ok {
+			return invalid("truncated")
+		}
+		if strings.HasPrefix(line, "--- ") {
+			mac, err := b64.DecodeString(line[4:])
+			if err != nil || len(mac) != sha256.Size {
+				return invalid("bad MAC")
+			}
+			return stanzas, offset - len(line) - 1 + len("---"), mac, data[offset:], nil
+		}
+		if !strings.HasPrefix(line, "-> ") {
+			return invalid("expected a stanza")
+		}
+		fields := strings.Split(line[3:], " ")
+		if fields[0] == "" {
+			return invalid("stanza without a type")
+		}
+		s := &Stanza{Type: fields[0], Args: fields[1:]}
+		for {
+			line, ok := readLine()
+			if !ok || len(line) > columns {
+				return invalid("bad stanza body")
+			}
+			chunk, err := b64.DecodeString(line)
+			if err != nil {
+				return invalid("bad stanza body")
+			}
+			s.Body = append(s.Body, chunk...)
+			if len(line) < columns {
+				break
+			}
+		}
+		stanzas = append(stanzas, s)
+	}
+}
@@ -0,0 +1,221 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func newIdentity(t *testing.T) *X25519Identity {
+	t.Helper()
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestRoundTrip(t *testing.T) {
+	id := newIdentity(t)
+	for _, n := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17} {
+		plaintext := bytes.Repeat([]byte("x"), n)
+		enc, err := Encrypt(plaintext, id.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(enc) {
+			t.Fatalf("%d bytes: missing age header", n)
+		}
+		got, err := Decrypt(enc, id)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("%d bytes: round trip changed the payload", n)
+		}
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// RFC 5869, test case 1
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm, err := hkdfKey(ikm, salt, string(info), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(okm), "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	alice, bob, eve := newIdentity(t), newIdentity(t), newIdentity(t)
+	enc, err := Encrypt([]byte("secret prompt"), alice.Recipient(), bob.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []Identity{alice, bob} {
+		if got, err := Decrypt(enc, eve, id); err != nil || string(got) != "secret prompt" {
+			t.Errorf("Decrypt = %q, %v", got, err)
+		}
+	}
+	if _, err := Decrypt(enc, eve); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Expected ErrNoIdentity, got %v", err)
+	}
+}
+
+func TestTampering(t *testing.T) {
+	id := newIdentity(t)
+	enc, err := Encrypt([]byte("spec: {}"), id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte{}, enc...)
+	payload[len(payload)-1] ^= 1
+	if _, err := Decrypt(payload, id); err == nil {
+		t.Error("Expected a modified payload rejected")
+	}
+	header := bytes.Replace(enc, []byte("-> X25519 "), []byte("-> X25519 A"), 1)
+	if _, err := Decrypt(header, id); err == nil {
+		t.Error("Expected a modified header rejected")
+	}
+	if _, err := Decrypt(enc[:len(enc)-20], id); err == nil {
+		t.Error("Expected a truncated file rejected")
+	}
+}
+
+func TestKeyStrings(t *testing.T) {
+	id := newIdentity(t)
+	parsed, err := ParseX25519Identity(id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Recipient().String() != id.Recipient().String() {
+		t.Error("Identity did not round trip")
+	}
+	r := id.Recipient().String()
+	if !strings.HasPrefix(r, "age1") || !strings.HasPrefix(id.String(), "AGE-SECRET-KEY-1") {
+		t.Errorf("Unexpected key strings %s, %s", r, id)
+	}
+	if _, err := ParseX25519Recipient(r); err != nil {
+		t.Error(err)
+	}
+	// Change the last character, so the checksum cannot happen to match.
+	last := "q"
+	if strings.HasSuffix(r, "q") {
+		last = "p"
+	}
+	if _, err := ParseX25519Recipient(r[:len(r)-1] + last); err == nil {
+		t.Error("Expected a bad checksum rejected")
+	}
+	if _, err := ParseX25519Recipient(id.String()); err == nil {
+		t.Error("Expected an identity rejected as a recipient")
+	}
+}
+
+// fakeKMS encrypts by XOR with its key byte.
+type fakeKMS struct {
+	uri string
+	key byte
+}
+
+func (k *fakeKMS) URI() string { return k.uri }
+
+func (k *fakeKMS) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	out := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		out[i] = b ^ k.key
+	}
+	return out, nil
+}
+
+func (k *fakeKMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return k.Encrypt(ctx, ciphertext)
+}
+
+func TestKMS(t *testing.T) {
+	kms := &fakeKMS{uri: "vault://transit/ossa", key: 0x5a}
+	id := newIdentity(t)
+	enc, err := Encrypt([]byte("endpoint: https://internal"), KMSRecipient{KMS: kms}, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decrypt(enc, KMSIdentity{KMS: kms}); err != nil || string(got) != "endpoint: https://internal" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if got, err := Decrypt(enc, id); err != nil || string(got) != "endpoint: https://internal" {
+		t.Errorf("Decrypt with the X25519 identity = %q, %v", got, err)
+	}
+	other := KMSIdentity{KMS: &fakeKMS{uri: "vault://transit/other", key: 0x5a}}
+	if _, err := Decrypt(enc, other); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Expected ErrNoIdentity for another key, got %v", err)
+	}
+}
+
+func TestParseKMS(t *testing.T) {
+	for uri, want := range map[string]string{
+		"gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/k": "gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/k",
+		"vault://ossa":         "vault://transit/ossa",
+		"vault://secrets/ossa": "vault://secrets/ossa",
+	} {
+		kms, err := ParseKMS(uri)
+		if err != nil {
+			t.Errorf("%s: %v", uri, err)
+			continue
+		}
+		if kms.URI() != want {
+			t.Errorf("%s: URI %s, want %s", uri, kms.URI(), want)
+		}
+	}
+	for _, uri := range []string{"gcpkms://ossa", "awskms://ossa", "vault://"} {
+		if _, err := ParseKMS(uri); err == nil {
+			t.Errorf("%s: expected an error", uri)
+		}
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	id := newIdentity(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "identity.txt")
+	if err := os.WriteFile(file, []byte("# created by a test\n"+id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	k := &Keyring{}
+	if err := k.AddIdentity(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.AddRecipient(id.Recipient().String()); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := k.Encrypt([]byte("apiVersion: ossa/v0.3.3\nkind: Agent\nmetadata:\n  name: secret\nspec:\n  role: Keep it quiet\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "secret.ossa.yaml")
+	if err := os.WriteFile(path, enc, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ossa.LoadManifest(path); !errors.Is(err, ossa.ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted without a decrypter, got %v", err)
+	}
+	ossa.SetDecrypter(k.Decrypt)
+	defer ossa.SetDecrypter(nil)
+	m, err := ossa.LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Metadata.Name != "secret" {
+		t.Errorf("Unexpected name %q", m.Metadata.Name)
+	}
+}
@@ -0,0 +1,107 @@
+package crypt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keyring holds the recipients to encrypt to and the identities to
+// decrypt with.
+type Keyring struct {
+	Recipients []Recipient
+	Identities []Identity
+}
+
+// ParseRecipient parses an age1... public key or a KMS key URI (see
+// ParseKMS).
+func ParseRecipient(s string) (Recipient, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "://") {
+		kms, err := ParseKMS(s)
+		if err != nil {
+			return nil, err
+		}
+		return KMSRecipient{KMS: kms}, nil
+	}
+	return ParseX25519Recipient(s)
+}
+
+// ParseIdentities reads an identity file as age-keygen writes it: one
+// AGE-SECRET-KEY-1... per line, with # comments and blank lines. Lines
+// may also be KMS key URIs, decrypting through the KMS.
+func ParseIdentities(data []byte) ([]Identity, error) {
+	var ids []Identity
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := parseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no identities")
+	}
+	return ids, nil
+}
+
+func parseIdentity(s string) (Identity, error) {
+	if strings.Contains(s, "://") {
+		kms, err := ParseKMS(s)
+		if err != nil {
+			return nil, err
+		}
+		return KMSIdentity{KMS: kms}, nil
+	}
+	return ParseX25519Identity(s)
+}
+
+// AddRecipient adds a recipient in its string form.
+func (k *Keyring) AddRecipient(s string) error {
+	r, err := ParseRecipient(s)
+	if err != nil {
+		return err
+	}
+	k.Recipients = append(k.Recipients, r)
+	return nil
+}
+
+// AddIdentity adds a KMS key URI or the identities in a file.
+func (k *Keyring) AddIdentity(s string) error {
+	if strings.Contains(s, "://") {
+		id, err := parseIdentity(s)
+		if err != nil {
+			return err
+		}
+		k.Identities = append(k.Identities, id)
+		return nil
+	}
+	data, err := os.ReadFile(s)
+	if err != nil {
+		return err
+	}
+	ids, err := ParseIdentities(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s, err)
+	}
+	k.Identities = append(k.Identities, ids...)
+	return nil
+}
+
+// Encrypt encrypts data to the keyring's recipients.
+func (k *Keyring) Encrypt(data []byte) ([]byte, error) {
+	return Encrypt(data, k.Recipients...)
+}
+
+// Decrypt decrypts an age file with the keyring's identities and returns
+// other data as it is.
+func (k *Keyring) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	return Decrypt(data, k.Identities...)
+}
@@ -0,0 +1,225 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// kmsStanza is the stanza type of KMS-wrapped file keys; its one argument
+// is the key's URI. age tools skip it.
+const kmsStanza = "ossa-kms"
+
+// KMS encrypts and decrypts small secrets with a key held by a key
+// management service. Implement it to use a service without built-in
+// support.
+type KMS interface {
+	// URI identifies the key, as in the stanzas it wraps.
+	URI() string
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// KMSRecipient wraps file keys with a KMS key; KMSIdentity unwraps them
+// for callers the KMS authorizes.
+type KMSRecipient struct{ KMS KMS }
+
+// KMSIdentity unwraps file keys wrapped by the same KMS key.
+type KMSIdentity struct{ KMS KMS }
+
+// kmsTimeout bounds each call to a KMS.
+const kmsTimeout = 30 * time.Second
+
+// Wrap implements Recipient.
+func (r KMSRecipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	body, err := r.KMS.Encrypt(ctx, fileKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.KMS.URI(), err)
+	}
+	return []*Stanza{{Type: kmsStanza, Args: []string{r.KMS.URI()}, Body: body}}, nil
+}
+
+// Unwrap implements Identity.
+func (i KMSIdentity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != kmsStanza || len(s.Args) != 1 || s.Args[0] != i.KMS.URI() {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+		defer cancel()
+		fileKey, err := i.KMS.Decrypt(ctx, s.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", i.KMS.URI(), err)
+		}
+		if len(fileKey) != fileKeySize {
+			return nil, fmt.Errorf("%s: unwrapped key has %d bytes", i.KMS.URI(), len(fileKey))
+		}
+		return fileKey, nil
+	}
+	return nil, ErrNoIdentity
+}
+
+// ParseKMS returns the KMS for a key URI:
+//
+//	gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K
+//	    Google Cloud KMS, authorized by $GOOGLE_OAUTH_ACCESS_TOKEN
+//	    (gcloud auth print-access-token)
+//	vault://[mount/]key
+//	    a HashiCorp Vault transit key (mount defaults to transit) at
+//	    $VAULT_ADDR, authorized by $VAULT_TOKEN
+func ParseKMS(uri string) (KMS, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || rest == "" {
+		return nil, fmt.Errorf("invalid KMS key %q", uri)
+	}
+	switch scheme {
+	case "gcpkms":
+		if !strings.HasPrefix(rest, "projects/") || !strings.Contains(rest, "/cryptoKeys/") {
+			return nil, fmt.Errorf("invalid Cloud KMS key %q: want gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K", uri)
+		}
+		return &GCPKMS{Key: rest}, nil
+	case "vault":
+		mount, key, ok := strings.Cut(rest, "/")
+		if !ok {
+			mount, key = "transit", rest
+		}
+		return &VaultTransit{Mount: mount, Key: key}, nil
+	}
+	return nil, fmt.Errorf("unsupported KMS %q: want gcpkms:// or vault://", scheme)
+}
+
+// GCPKMS is a Google Cloud KMS symmetric key.
+type GCPKMS struct {
+	// Key is the key's resource name, projects/.../cryptoKeys/K.
+	Key string
+	// Token authorizes calls; empty reads $GOOGLE_OAUTH_ACCESS_TOKEN.
+	Token string
+	// Endpoint defaults to https://cloudkms.googleapis.com.
+	Endpoint string
+	Client   *http.Client
+}
+
+func (k *GCPKMS) URI() string { return "gcpkms://" + k.Key }
+
+func (k *GCPKMS) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var out struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	err := k.call(ctx, "encrypt", map[string][]byte{"plaintext": plaintext}, &out)
+	return out.Ciphertext, err
+}
+
+func (k *GCPKMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var out struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	err := k.call(ctx, "decrypt", map[string][]byte{"ciphertext": ciphertext}, &out)
+	return out.Plaintext, err
+}
+
+func (k *GCPKMS) call(ctx context.Context, op string, in, out interface{}) error {
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = "https://cloudkms.googleapis.com"
+	}
+	token := k.Token
+	if token == "" {
+		token = os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("no Cloud KMS credentials: set GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	return postJSON(ctx, k.Client, endpoint+"/v1/"+k.Key+":"+op, map[string]string{"Authorization": "Bearer " + token}, in, out)
+}
+
+// VaultTransit is a HashiCorp Vault transit key.
+type VaultTransit struct {
+	Mount, Key string
+	// Addr and Token default to $VAULT_ADDR and $VAULT_TOKEN.
+	Addr, Token string
+	Client      *http.Client
+}
+
+func (v *VaultTransit) URI() string { return "vault://" + v.Mount + "/" + v.Key }
+
+func (v *VaultTransit) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var out struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &out)
+	return []byte(out.Data.Ciphertext), err
+}
+
+func (v *VaultTransit) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var out struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(ciphertext)}, &out); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Data.Plaintext)
+}
+
+func (v *VaultTransit) call(ctx context.Context, op string, in, out interface{}) error {
+	addr, token := v.Addr, v.Token
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if addr == "" || token == "" {
+		return fmt.Errorf("no Vault credentials: set VAULT_ADDR and VAULT_TOKEN")
+	}
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + v.Mount + "/" + op + "/" + v.Key
+	return postJSON(ctx, v.Client, url, map[string]string{"X-Vault-Token": token}, in, out)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, in, out interface{}) error {
+	if err := ossa.RequireNetwork("KMS " + url); err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,232 @@
+package crypt
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/internal/chacha20poly1305"
+)
+
+const x25519Label = "age-encryption.org/v1/X25519"
+
+// X25519Recipient is an age public key, age1....
+type X25519Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// X25519Identity is an age secret key, AGE-SECRET-KEY-1....
+type X25519Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// GenerateX25519Identity returns a new random identity.
+func GenerateX25519Identity() (*X25519Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+// ParseX25519Recipient parses an age1... public key.
+func ParseX25519Recipient(s string) (*X25519Recipient, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil || hrp != "age" {
+		return nil, fmt.Errorf("invalid age recipient %q", s)
+	}
+	key, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient %q: %w", s, err)
+	}
+	return &X25519Recipient{key: key}, nil
+}
+
+// ParseX25519Identity parses an AGE-SECRET-KEY-1... secret key.
+func ParseX25519Identity(s string) (*X25519Identity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil || hrp != "age-secret-key-" {
+		return nil, fmt.Errorf("invalid age identity")
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity: %w", err)
+	}
+	return &X25519Identity{key: key}, nil
+}
+
+func (r *X25519Recipient) String() string {
+	s, _ := bech32Encode("age", r.key.Bytes())
+	return s
+}
+
+func (i *X25519Identity) String() string {
+	s, _ := bech32Encode("AGE-SECRET-KEY-", i.key.Bytes())
+	return strings.ToUpper(s)
+}
+
+// Recipient returns the identity's public key.
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return &X25519Recipient{key: i.key.PublicKey()}
+}
+
+// Wrap implements Recipient.
+func (r *X25519Recipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(r.key)
+	if err != nil {
+		return nil, err
+	}
+	share := ephemeral.PublicKey().Bytes()
+	salt := append(append([]byte{}, share...), r.key.Bytes()...)
+	key, err := hkdfKey(shared, salt, x25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, _ := chacha20poly1305.New(key)
+	body := aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil)
+	return []*Stanza{{Type: "X25519", Args: []string{b64.EncodeToString(share)}, Body: body}}, nil
+}
+
+// Unwrap implements Identity.
+func (i *X25519Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != "X25519" {
+			continue
+		}
+		if len(s.Args) != 1 || len(s.Body) != fileKeySize+chacha20poly1305.Overhead {
+			return nil, errors.New("invalid X25519 stanza")
+		}
+		raw, err := b64.DecodeString(s.Args[0])
+		if err != nil {
+			return nil, errors.New("invalid X25519 stanza")
+		}
+		share, err := ecdh.X25519().NewPublicKey(raw)
+		if err != nil {
+			return nil, errors.New("invalid X25519 stanza")
+		}
+		shared, err := i.key.ECDH(share)
+		if err != nil {
+			return nil, errors.New("invalid X25519 stanza")
+		}
+		salt := append(append([]byte{}, raw...), i.key.PublicKey().Bytes()...)
+		key, err := hkdfKey(shared, salt, x25519Label, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, err
+		}
+		aead, _ := chacha20poly1305.New(key)
+		if fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), s.Body, nil); err == nil {
+			return fileKey, nil
+		}
+	}
+	return nil, ErrNoIdentity
+}
+
+// bech32 as BIP 173 defines it, without its 90 character limit, as age
+// uses it.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	var out []byte
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// convertBits regroups data from frombits to tobits bit groups.
+func convertBits(data []byte, frombits, tobits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var n uint
+	var out []byte
+	maxv := uint32(1)<<tobits - 1
+	for _, b := range data {
+		if uint32(b)>>frombits != 0 {
+			return nil, errors.New("invalid data")
+		}
+		acc = acc<<frombits | uint32(b)
+		n += frombits
+		for n >= tobits {
+			n -= tobits
+			out = append(out, byte(acc>>n&maxv))
+		}
+	}
+	if pad {
+		if n > 0 {
+			out = append(out, byte(acc<<(tobits-n)&maxv))
+		}
+	} else if n >= frombits || acc<<(tobits-n)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+func bech32Encode(hrp string, data []byte) (string, error) {
+	hrp = strings.ToLower(hrp)
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	poly := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var sb strings.Builder
+	sb.WriteString(hrp + "1")
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[poly>>(5*(5-i))&31])
+	}
+	return sb.String(), nil
+}
+
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("mixed case")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, errors.New("invalid separator")
+	}
+	hrp := s[:pos]
+	var values []byte
+	for i := pos + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, errors.New("invalid character")
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
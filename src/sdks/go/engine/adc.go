@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	googleTokenURL     = "https://oauth2.googleapis.com/token"
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// googleCredentials is an Application Default Credentials file, as
+// gcloud auth application-default login or a service account key writes.
+type googleCredentials struct {
+	Type string `json:"type"`
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// adcTokens hands out OAuth2 access tokens from Application Default
+// Credentials: the file GOOGLE_APPLICATION_CREDENTIALS names, else the one
+// gcloud keeps, else the metadata server of the Google Cloud machine it
+// runs on. Tokens are reused until a minute before they expire.
+type adcTokens struct {
+	client *http.Client
+	now    func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a valid access token, fetching one with client if it has
+// none.
+func (a *adcTokens) Token(ctx context.Context, client *http.Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.client = client
+	now := time.Now
+	if a.now != nil {
+		now = a.now
+	}
+	if a.token != "" && now().Add(time.Minute).Before(a.expiry) {
+		return a.token, nil
+	}
+	token, ttl, err := a.fetch(ctx, now())
+	if err != nil {
+		return "", fmt.Errorf("failed to get Google Cloud credentials: %w", err)
+	}
+	a.token, a.expiry = token, now().Add(ttl)
+	return token, nil
+}
+
+func (a *adcTokens) fetch(ctx context.Context, now time.Time) (string, time.Duration, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudCredentialsPath()
+		if _, err := os.Stat(path); err != nil {
+			return a.metadataToken(ctx)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	var creds googleCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	switch creds.Type {
+	case "authorized_user":
+		return a.exchange(ctx, googleTokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	case "service_account":
+		tokenURL := creds.TokenURI
+		if tokenURL == "" {
+			tokenURL = googleTokenURL
+		}
+		assertion, err := signJWT(creds, tokenURL, now)
+		if err != nil {
+			return "", 0, err
+		}
+		return a.exchange(ctx, tokenURL, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	}
+	return "", 0, fmt.Errorf("%s has unsupported credentials type %q", path, creds.Type)
+}
+
+// gcloudCredentialsPath is where gcloud auth application-default login
+// writes credentials.
+func gcloudCredentialsPath() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if dir := os.Getenv("APPDATA"); dir != "" {
+		return filepath.Join(dir, "gcloud", "application_default_credentials.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// signJWT is the service account's signed assertion asking for
+// cloud-platform access.
+func signJWT(creds googleCredentials, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account %s has no PEM private key", creds.ClientEmail)
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return "", fmt.Errorf("service account %s key is not RSA", creds.ClientEmail)
+		}
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("failed to parse service account %s key: %w", creds.ClientEmail, err)
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": creds.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": cloudPlatformScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// exchange posts form to an OAuth2 token endpoint.
+func (a *adcTokens) exchange(ctx context.Context, tokenURL string, form url.Values) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return a.do(req)
+}
+
+// metadataToken asks the metadata server, at GCE_METADATA_HOST if set, for
+// the token of the machine's service account.
+func (a *adcTokens) metadataToken(ctx context.Context) (string, time.Duration, error) {
+	host := envOr("GCE_METADATA_HOST", "metadata.google.internal")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, ttl, err := a.do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("no credentials file and no metadata server: %w", err)
+	}
+	return token, ttl, nil
+}
+
+func (a *adcTokens) do(req *http.Request) (string, time.Duration, error) {
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", 0, fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if out.AccessToken == "" {
+		return "", 0, fmt.Errorf("%s returned no access token", req.URL.Host)
+	}
+	return out.AccessToken, time.Duration(out.ExpiresIn) * time.Second, nil
+}
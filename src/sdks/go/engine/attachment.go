@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// AttachmentsKey is the input field RunAgent takes attachments from: a
+// list of Attachments, or of their JSON objects with data in base64. The
+// field is not part of the prompt.
+const AttachmentsKey = "$attachments"
+
+// Attachment is an image or audio input, sent to the model with the user
+// turn. It carries its data or, for providers that fetch it, a URL.
+type Attachment struct {
+	// Type is ossa.ModalityImage or ossa.ModalityAudio; it defaults to
+	// the modality of MIMEType.
+	Type     string `json:"type,omitempty"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// dataURL is the URL of a, or else its data as a data: URL.
+func (a Attachment) dataURL() string {
+	if a.URL != "" {
+		return a.URL
+	}
+	return "data:" + a.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(a.Data)
+}
+
+// inputAttachments removes the attachments from input and checks them
+// against spec.input.
+func inputAttachments(m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, []Attachment, error) {
+	raw, ok := input[AttachmentsKey]
+	if !ok {
+		return input, nil, nil
+	}
+	rest := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if k != AttachmentsKey {
+			rest[k] = v
+		}
+	}
+	atts, ok := raw.([]Attachment)
+	if !ok {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode %s: %w", AttachmentsKey, err)
+		}
+		if err := json.Unmarshal(data, &atts); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", AttachmentsKey, err)
+		}
+	}
+	in := m.Spec.Input
+	if in != nil && in.MaxAttachments > 0 && len(atts) > in.MaxAttachments {
+		return nil, nil, fmt.Errorf("%d attachments exceed spec.input.max_attachments %d", len(atts), in.MaxAttachments)
+	}
+	for i := range atts {
+		a := &atts[i]
+		if a.Type == "" {
+			a.Type = ossa.MIMEModality(a.MIMEType)
+		}
+		switch {
+		case !in.Accepts(a.Type):
+			return nil, nil, fmt.Errorf("attachment %d: agent does not accept %s input", i, a.Type)
+		case ossa.MIMEModality(a.MIMEType) != a.Type || !in.AcceptsMIMEType(a.MIMEType):
+			return nil, nil, fmt.Errorf("attachment %d: media type %q is not accepted", i, a.MIMEType)
+		case len(a.Data) == 0 && a.URL == "":
+			return nil, nil, fmt.Errorf("attachment %d has neither data nor url", i)
+		case len(a.Data) > in.MaxBytes(a.Type):
+			return nil, nil, fmt.Errorf("attachment %d: %d bytes exceed the %d byte limit", i, len(a.Data), in.MaxBytes(a.Type))
+		}
+	}
+	return rest, atts, nil
+}
+
+// audioFormat is the format name of an audio media type, as OpenAI's
+// input_audio wants it.
+func audioFormat(mimeType string) (string, error) {
+	switch mimeType {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return "wav", nil
+	case "audio/mpeg", "audio/mp3":
+		return "mp3", nil
+	}
+	return "", fmt.Errorf("unsupported audio type %s", mimeType)
+}
+
+// imageFormat is the subtype of an image media type, as Bedrock wants it.
+func imageFormat(mimeType string) (string, error) {
+	switch format := strings.TrimPrefix(mimeType, "image/"); format {
+	case "png", "jpeg", "gif", "webp":
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported image type %s", mimeType)
+}
+
+// onlyImageData fails unless every attachment of m is image data, for
+// providers taking nothing else.
+func onlyImageData(provider string, m Message) error {
+	for _, a := range m.Attachments {
+		if a.Type != ossa.ModalityImage {
+			return fmt.Errorf("the %s provider does not accept %s input", provider, a.Type)
+		}
+		if len(a.Data) == 0 {
+			return fmt.Errorf("the %s provider needs image data, not a URL", provider)
+		}
+	}
+	return nil
+}
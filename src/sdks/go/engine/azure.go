@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when
+// spec.llm.azure.api_version is not set.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureOpenAI is a Model calling the Azure OpenAI deployment each request's
+// spec.llm.azure names. It authenticates with the API key in the variable
+// api_key_env names, AZURE_OPENAI_API_KEY by default, or else with a
+// Microsoft Entra ID token from AZURE_OPENAI_AD_TOKEN.
+type AzureOpenAI struct {
+	// Client defaults to one with a 2 minute timeout.
+	Client *http.Client
+}
+
+func (a *AzureOpenAI) Complete(ctx context.Context, req *Request) (*Response, error) {
+	o, err := a.deployment(req)
+	if err != nil {
+		return nil, err
+	}
+	return o.Complete(ctx, req)
+}
+
+// Stream is OpenAI.Stream against the request's deployment.
+func (a *AzureOpenAI) Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error) {
+	o, err := a.deployment(req)
+	if err != nil {
+		return nil, err
+	}
+	return o.Stream(ctx, req, onText)
+}
+
+// Embed is OpenAI.Embed against the deployment of spec.embeddings.azure.
+func (a *AzureOpenAI) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	o, err := a.deployment(&Request{LLM: embeddingLLM(req.Embeddings)})
+	if err != nil {
+		return nil, err
+	}
+	return o.Embed(ctx, req)
+}
+
+// deployment returns the OpenAI client for req's deployment.
+func (a *AzureOpenAI) deployment(req *Request) (*OpenAI, error) {
+	if req.LLM == nil || req.LLM.Azure == nil || req.LLM.Azure.Endpoint == "" {
+		return nil, fmt.Errorf("the azure provider needs spec.llm.azure.endpoint")
+	}
+	cfg := req.LLM.Azure
+	endpoint := expandEnv(cfg.Endpoint)
+	if endpoint == "" {
+		return nil, fmt.Errorf("spec.llm.azure.endpoint %s is not set", cfg.Endpoint)
+	}
+	deployment := cfg.Deployment
+	if deployment == "" {
+		deployment = req.LLM.Model
+	}
+	version := cfg.APIVersion
+	if version == "" {
+		version = DefaultAzureAPIVersion
+	}
+	o := &OpenAI{
+		BaseURL: strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment),
+		Client:  a.Client,
+		query:   "api-version=" + url.QueryEscape(version),
+	}
+	keyEnv := cfg.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = "AZURE_OPENAI_API_KEY"
+	}
+	if key := os.Getenv(keyEnv); key != "" {
+		o.Headers = map[string]string{"api-key": key}
+	} else if token := os.Getenv("AZURE_OPENAI_AD_TOKEN"); token != "" {
+		o.APIKey = token
+	} else {
+		return nil, fmt.Errorf("the azure provider needs %s or AZURE_OPENAI_AD_TOKEN", keyEnv)
+	}
+	return o, nil
+}
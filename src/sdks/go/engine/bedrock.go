@@ -0,0 +1,376 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Bedrock is a Model calling the AWS Bedrock Converse API. The model ID
+// is spec.llm.model, a foundation model ID such as
+// anthropic.claude-3-5-sonnet-20240620-v1:0, an inference profile such as
+// us.anthropic.claude-3-5-sonnet-20240620-v1:0, or an ARN. The region is
+// spec.llm.bedrock.region, or AWS_REGION.
+//
+// Requests are signed with Signature Version 4 using the access key, or
+// carry BearerToken, a Bedrock API key, when it is set.
+type Bedrock struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is needed with temporary credentials.
+	SessionToken string
+	BearerToken  string
+	// Client defaults to one with a 2 minute timeout.
+	Client *http.Client
+
+	now func() time.Time
+}
+
+type bedrockMessage struct {
+	Role    string         `json:"role"`
+	Content []bedrockBlock `json:"content"`
+}
+
+type bedrockBlock struct {
+	Text       string             `json:"text,omitempty"`
+	Image      *bedrockImage      `json:"image,omitempty"`
+	ToolUse    *bedrockToolUse    `json:"toolUse,omitempty"`
+	ToolResult *bedrockToolResult `json:"toolResult,omitempty"`
+}
+
+type bedrockImage struct {
+	Format string `json:"format"`
+	Source struct {
+		Bytes []byte `json:"bytes"`
+	} `json:"source"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string                 `json:"toolUseId"`
+	Name      string                 `json:"name"`
+	Input     map[string]interface{} `json:"input"`
+}
+
+type bedrockToolResult struct {
+	ToolUseID string         `json:"toolUseId"`
+	Content   []bedrockBlock `json:"content"`
+}
+
+type bedrockTool struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON map[string]interface{} `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+func (b *Bedrock) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if req.LLM == nil || req.LLM.Model == "" {
+		return nil, fmt.Errorf("the bedrock provider needs spec.llm.model")
+	}
+	body, err := bedrockBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.send(ctx, req.LLM.Model, req.LLM.Bedrock, "converse", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Output struct {
+			Message bedrockMessage `json:"message"`
+		} `json:"output"`
+		Usage struct {
+			InputTokens  int `json:"inputTokens"`
+			OutputTokens int `json:"outputTokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode bedrock converse response: %w", err)
+	}
+	res := &Response{Usage: Usage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens}}
+	for _, block := range out.Output.Message.Content {
+		res.Content += block.Text
+		if tu := block.ToolUse; tu != nil {
+			res.ToolCalls = append(res.ToolCalls, ossa.ToolCall{ID: tu.ToolUseID, Tool: tu.Name, Arguments: tu.Input})
+		}
+	}
+	return res, nil
+}
+
+// Embed invokes a Cohere embedding model with all texts at once, or a
+// Titan one with each in turn.
+func (b *Bedrock) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	cfg := req.Embeddings
+	if strings.Contains(cfg.Model, "cohere.embed") {
+		resp, err := b.send(ctx, cfg.Model, cfg.Bedrock, "invoke", map[string]interface{}{"texts": req.Texts, "input_type": "search_document"})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var out struct {
+			Embeddings [][]float64 `json:"embeddings"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("failed to decode bedrock invoke response: %w", err)
+		}
+		return checkVectors("bedrock invoke", out.Embeddings, len(req.Texts))
+	}
+
+	vectors := make([][]float64, len(req.Texts))
+	for i, text := range req.Texts {
+		body := map[string]interface{}{"inputText": text}
+		if cfg.Dimensions > 0 {
+			body["dimensions"] = cfg.Dimensions
+		}
+		resp, err := b.send(ctx, cfg.Model, cfg.Bedrock, "invoke", body)
+		if err != nil {
+			return nil, err
+		}
+		var out struct {
+			Embedding []float64 `json:"embedding"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode bedrock invoke response: %w", err)
+		}
+		vectors[i] = out.Embedding
+	}
+	return checkVectors("bedrock invoke", vectors, len(req.Texts))
+}
+
+// send posts body to action, converse or invoke, of model in cfg's region,
+// returning the response once its status is 200.
+func (b *Bedrock) send(ctx context.Context, model string, cfg *ossa.BedrockConfig, action string, body interface{}) (*http.Response, error) {
+	region := os.Getenv("AWS_REGION")
+	endpoint := ""
+	if cfg != nil {
+		if r := expandEnv(cfg.Region); r != "" {
+			region = r
+		}
+		endpoint = expandEnv(cfg.Endpoint)
+	}
+	if region == "" {
+		return nil, fmt.Errorf("the bedrock provider needs spec.llm.bedrock.region or AWS_REGION")
+	}
+	if endpoint == "" {
+		endpoint = "https://bedrock-runtime." + region + ".amazonaws.com"
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/model/" + awsEscape(model) + "/" + action
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.BearerToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+b.BearerToken)
+	} else {
+		now := time.Now
+		if b.now != nil {
+			now = b.now
+		}
+		signV4(httpReq, data, b.AccessKeyID, b.SecretAccessKey, b.SessionToken, region, "bedrock", now())
+	}
+	client := b.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError("bedrock "+action, resp)
+	}
+	return resp, nil
+}
+
+// bedrockBody is the Converse request for req. System messages become the
+// system prompt and tool results user messages, with consecutive messages
+// of one role merged as Bedrock wants them alternating.
+func bedrockBody(req *Request) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	var system []bedrockBlock
+	var messages []bedrockMessage
+	add := func(role string, block bedrockBlock) {
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, block)
+			return
+		}
+		messages = append(messages, bedrockMessage{Role: role, Content: []bedrockBlock{block}})
+	}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case RoleSystem:
+			if m.Content != "" {
+				system = append(system, bedrockBlock{Text: m.Content})
+			}
+		case RoleTool:
+			add(RoleUser, bedrockBlock{ToolResult: &bedrockToolResult{ToolUseID: m.ToolCallID, Content: []bedrockBlock{{Text: m.Content}}}})
+		default:
+			if m.Content != "" {
+				add(m.Role, bedrockBlock{Text: m.Content})
+			}
+			if err := onlyImageData("bedrock", m); err != nil {
+				return nil, err
+			}
+			for _, a := range m.Attachments {
+				format, err := imageFormat(a.MIMEType)
+				if err != nil {
+					return nil, err
+				}
+				image := &bedrockImage{Format: format}
+				image.Source.Bytes = a.Data
+				add(m.Role, bedrockBlock{Image: image})
+			}
+			for _, c := range m.ToolCalls {
+				input := c.Arguments
+				if input == nil {
+					input = map[string]interface{}{}
+				}
+				add(m.Role, bedrockBlock{ToolUse: &bedrockToolUse{ToolUseID: c.ID, Name: c.Tool, Input: input}})
+			}
+		}
+	}
+	body["messages"] = messages
+	if len(system) > 0 {
+		body["system"] = system
+	}
+	if llm := req.LLM; llm != nil {
+		config := map[string]interface{}{}
+		if llm.MaxTokens != 0 {
+			config["maxTokens"] = llm.MaxTokens
+		}
+		if llm.Temperature != 0 {
+			config["temperature"] = llm.Temperature
+		}
+		if llm.TopP != 0 {
+			config["topP"] = llm.TopP
+		}
+		// Converse has no penalties, seed or response format.
+		if len(llm.Stop) > 0 {
+			config["stopSequences"] = llm.Stop
+		}
+		if len(config) > 0 {
+			body["inferenceConfig"] = config
+		}
+	}
+	var tools []bedrockTool
+	for _, t := range req.Tools {
+		if t.Name == "" {
+			continue
+		}
+		var tool bedrockTool
+		tool.ToolSpec.Name, tool.ToolSpec.Description = t.Name, t.Description
+		tool.ToolSpec.InputSchema.JSON = t.Parameters
+		if tool.ToolSpec.InputSchema.JSON == nil {
+			tool.ToolSpec.InputSchema.JSON = map[string]interface{}{"type": "object"}
+		}
+		tools = append(tools, tool)
+	}
+	if len(tools) > 0 {
+		body["toolConfig"] = map[string]interface{}{"tools": tools}
+	}
+	return body, nil
+}
+
+// signV4 signs r, whose body is body, with AWS Signature Version 4.
+func signV4(r *http.Request, body []byte, keyID, secret, token, region, service string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := amzDate[:8]
+	r.Header.Set("X-Amz-Date", amzDate)
+	if token != "" {
+		r.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": r.URL.Host}
+	for _, name := range []string{"Content-Type", "X-Amz-Date", "X-Amz-Security-Token"} {
+		if v := r.Header.Get(name); v != "" {
+			headers[strings.ToLower(name)] = strings.TrimSpace(v)
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Services other than S3 want each path segment escaped twice.
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	var query []string
+	for key, values := range r.URL.Query() {
+		for _, v := range values {
+			query = append(query, awsEscape(key)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(query)
+
+	payload := sha256.Sum256(body)
+	canonical := strings.Join([]string{r.Method, path, strings.Join(query, "&"), canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payload[:])}, "\n")
+	hashed := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+keyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape percent-encodes all of s but the unreserved characters, as
+// AWS signing does.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
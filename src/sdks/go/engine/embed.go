@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// EmbedRequest asks an agent's embedding model for the vectors of Texts.
+type EmbedRequest struct {
+	Embeddings *ossa.EmbeddingsConfig
+	Texts      []string
+}
+
+// Embedder turns texts into vectors, one per text and in their order. The
+// adapters of providers with embedding models are Embedders as well as
+// Models.
+type Embedder interface {
+	Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error)
+}
+
+// NewEmbedder returns the Embedder for spec.embeddings.provider, with the
+// credentials NewModel uses for that provider.
+func NewEmbedder(cfg *ossa.EmbeddingsConfig) (Embedder, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("agent has no spec.embeddings")
+	}
+	model, err := NewModel(embeddingLLM(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return asEmbedder(cfg, model)
+}
+
+// Embed sends req to the adapter for its spec.embeddings.provider, the
+// one Complete uses for that provider.
+func (p *Providers) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	if req.Embeddings == nil {
+		return nil, fmt.Errorf("agent has no spec.embeddings")
+	}
+	model, err := p.model(embeddingLLM(req.Embeddings))
+	if err != nil {
+		return nil, err
+	}
+	e, err := asEmbedder(req.Embeddings, model)
+	if err != nil {
+		return nil, err
+	}
+	return e.Embed(ctx, req)
+}
+
+func asEmbedder(cfg *ossa.EmbeddingsConfig, model Model) (Embedder, error) {
+	e, ok := model.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("the %s provider has no embeddings adapter", cfg.Provider)
+	}
+	return e, nil
+}
+
+// embeddingLLM is cfg as the LLMConfig adapters locate models with.
+func embeddingLLM(cfg *ossa.EmbeddingsConfig) *ossa.LLMConfig {
+	return &ossa.LLMConfig{Provider: cfg.Provider, Model: cfg.Model, Azure: cfg.Azure, Bedrock: cfg.Bedrock, Vertex: cfg.Vertex}
+}
+
+// checkVectors fails unless op returned one vector for each of n texts.
+func checkVectors(op string, vectors [][]float64, n int) ([][]float64, error) {
+	if len(vectors) != n {
+		return nil, fmt.Errorf("%s returned %d vectors for %d texts", op, len(vectors), n)
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("%s returned no vector for text %d", op, i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,440 @@
+// Package engine runs OSSA agents and workflows.
+//
+// An agent runs as a loop of model turns: the Model sees the agent's
+// system prompt, its input and its tools, and each tool call it makes is
+// executed and its result sent back, until it answers without calling
+// any. A workflow runs its steps in order, mapping their inputs with
+// ${{ }} expressions over the workflow input and earlier step outputs.
+//
+// The engine carries no provider or tool code of its own: Model talks to
+// an LLM (see NewModel) and Tools picks the runtime for each tool call.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultMaxTurns bounds an agent's model turns when Engine.MaxTurns is 0.
+const DefaultMaxTurns = 10
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one entry of the conversation sent to the model.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	// ToolCalls are the calls an assistant message made.
+	ToolCalls []ossa.ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Attachments are the images and audio of a user message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Usage is what an assistant message cost. It is not sent to models.
+	Usage *Usage `json:"usage,omitempty"`
+	// Provider and Model are what answered an assistant message, if the
+	// Model said. They are not sent to models.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Request is one model turn.
+type Request struct {
+	// Agent is the name of the agent taking the turn.
+	Agent    string
+	LLM      *ossa.LLMConfig
+	Messages []Message
+	Tools    []ossa.ToolConfig
+}
+
+// Usage counts the tokens of one or more model turns.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u *Usage) add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+}
+
+// Response is the model's answer to a Request: text, tool calls or both.
+type Response struct {
+	Content   string
+	ToolCalls []ossa.ToolCall
+	Usage     Usage
+	// Provider and Model are what answered, set by Providers, which may
+	// route the request to a fallback.
+	Provider string
+	Model    string
+}
+
+// Model completes a conversation with an LLM.
+type Model interface {
+	Complete(ctx context.Context, req *Request) (*Response, error)
+}
+
+// ToolsFunc returns the executor for an agent's tool calls.
+type ToolsFunc func(m *ossa.Manifest) ossa.ToolExecFunc
+
+// Engine runs agents and workflows. Model must be set.
+type Engine struct {
+	Model Model
+	// Tools executes tool calls; without it every tool call fails and the
+	// model is told so.
+	Tools ToolsFunc
+	// Load resolves a workflow step's ref to its manifest.
+	Load func(ctx context.Context, ref string) (*ossa.Manifest, error)
+	// Task runs a kind: Task step, which has no model loop; without it
+	// Task steps fail.
+	Task func(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error)
+	// Shadows maps agent names to candidate versions run in shadow; see
+	// ShadowReport.
+	Shadows map[string]*ossa.Manifest
+	// ShadowTools executes a candidate's tool calls; nil means StubTools.
+	ShadowTools func(candidate *ossa.Manifest, current *Result) ossa.ToolExecFunc
+	// Session identifies the user or conversation the engine runs for:
+	// it buckets tools' rollout_percent, so a session keeps seeing the
+	// same tools. Flags turns tools' feature flags on; nil turns them all
+	// off. The model is only offered the tools ossa.Manifest.ToolsFor
+	// returns for them.
+	Session string
+	Flags   ossa.FlagSource
+	// Locale picks the variants of localized roles and prompts, falling
+	// back as ossa.LocalizedText.For does; "" means ossa.DefaultLocale.
+	Locale string
+	// NoModeration skips spec.safety.moderation, for callers such as
+	// package chatapi that moderate prompts and answers themselves.
+	NoModeration bool
+	// AuditLogger receives an audit event for each moderation result that
+	// flags content; nil only logs them to ossa.Logger().
+	AuditLogger *slog.Logger
+	// MaxTurns bounds an agent's model turns; 0 means DefaultMaxTurns.
+	MaxTurns int
+	// Seed, if set, is the sampling seed of model turns whose llm sets
+	// none, so a run can be repeated as closely as providers allow: the
+	// openai, gemini and ollama providers send it. The engine makes no
+	// random choices of its own.
+	Seed *int
+	// CountTokens counts the tokens of text when fitting prompts to the
+	// context window; it defaults to EstimateTokens.
+	CountTokens func(text string) int
+	// Memory returns the Memory compacting an agent's conversation
+	// memory; nil means the one spec.state.context_window configures.
+	Memory func(m *ossa.Manifest) Memory
+	// Checkpoint is called after each top-level workflow step completes,
+	// so the run can be resumed from there with ResumeWorkflow. cp is
+	// reused by later steps; an error ends the run.
+	Checkpoint func(ctx context.Context, cp *Checkpoint) error
+	// Events, if set, is sent each step of a run as it happens, from
+	// several goroutines for parallel workflow steps. Shadow runs send
+	// none.
+	Events func(Event)
+	// Trace, if set, is called as each agent run starts. The context it
+	// returns is the run's, passed to its model and tool calls, and end
+	// is called with the run's outcome. Shadow runs are not traced; see
+	// package tracing.
+	Trace func(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (context.Context, func(res *Result, err error))
+}
+
+// ToolRecord is one tool call an agent made and its outcome.
+type ToolRecord struct {
+	ID        string                 `json:"id"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Output    string                 `json:"output,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// Result is the outcome of an agent run.
+type Result struct {
+	Agent   string `json:"agent"`
+	Version string `json:"version,omitempty"`
+	// Output is the final answer: the JSON object the model answered with,
+	// or {"content": text}.
+	Output    map[string]interface{} `json:"output"`
+	ToolCalls []ToolRecord           `json:"tool_calls,omitempty"`
+	Usage     Usage                  `json:"usage"`
+	Turns     int                    `json:"turns"`
+	// Messages is the conversation, from the system prompt to the answer.
+	Messages []Message `json:"messages,omitempty"`
+}
+
+// RunAgent runs m on input until the model answers. Failed tool calls are
+// reported to the model rather than ending the run. Each turn after tool
+// calls uses the llm override of the first called tool that has one, and
+// spec.llm otherwise. Images and audio in input[AttachmentsKey] are sent
+// with the input if spec.input accepts them. Conversation memory in the
+// input is compacted as spec.state.context_window says, and each prompt
+// fitted to the model's context window as spec.llm.context says. The input
+// and the answer go through spec.safety.moderation: blocked ones end the
+// run with an error wrapping ossa.ErrContentBlocked. The concurrency limits
+// of tool calls hold across the run, and the workflow run it is a step of.
+func (e *Engine) RunAgent(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (res *Result, err error) {
+	ctx = e.withSession(withToolLimits(ctx))
+	var exec ossa.ToolExecFunc
+	if e.Tools != nil {
+		exec = e.Tools(m)
+	}
+	if e.Trace != nil {
+		var end func(*Result, error)
+		ctx, end = e.Trace(ctx, m, input)
+		defer func() { end(res, err) }()
+	}
+	return e.runAgent(ctx, m, input, exec, e.Events)
+}
+
+// withToolLimits returns ctx with ossa.ToolLimits for the run, unless it
+// is part of a run that has them.
+func withToolLimits(ctx context.Context) context.Context {
+	if ossa.ToolLimitsFrom(ctx) != nil {
+		return ctx
+	}
+	return ossa.WithToolLimits(ctx, ossa.NewToolLimits())
+}
+
+// runAgent runs m, sending its events to emit if set.
+func (e *Engine) runAgent(ctx context.Context, m *ossa.Manifest, input map[string]interface{}, exec ossa.ToolExecFunc, emit func(Event)) (res *Result, err error) {
+	if e.Model == nil {
+		return nil, fmt.Errorf("engine has no model")
+	}
+	if exec == nil {
+		exec = noTools
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	input, attachments, err := inputAttachments(m, input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Metadata.Name, err)
+	}
+	if input, err = e.compactMemory(ctx, m, input); err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Metadata.Name, err)
+	}
+	prompt, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+	moderator, err := e.moderator(m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Metadata.Name, err)
+	}
+	userPrompt, err := e.moderate(ctx, moderator, m, ossa.StagePrompt, string(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("%s: input: %w", m.Metadata.Name, err)
+	}
+	req := &Request{
+		Agent: m.Metadata.Name,
+		LLM:   e.seeded(m.Spec.LLM),
+		Tools: m.ToolsFor(e.Session, e.Flags),
+		Messages: []Message{
+			{Role: RoleSystem, Content: systemPrompt(m, e.locale())},
+			{Role: RoleUser, Content: userPrompt, Attachments: attachments},
+		},
+	}
+	name := m.Metadata.Name
+	res = &Result{Agent: name, Version: m.Metadata.Version}
+	max := e.MaxTurns
+	if max <= 0 {
+		max = DefaultMaxTurns
+	}
+	stream, _ := e.Model.(StreamingModel)
+	if emit == nil {
+		emit, stream = func(Event) {}, nil
+	}
+	// Answers are moderated once complete, so none may be streamed first.
+	if moderator.Moderates(ossa.StageCompletion) {
+		stream = nil
+	}
+	emit(Event{Type: EventAgentStarted, Agent: name})
+	defer func() {
+		res.Messages = req.Messages
+		ev := Event{Type: EventAgentFinished, Agent: name, Output: res.Output, Usage: &res.Usage}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		emit(ev)
+	}()
+	for res.Turns < max {
+		res.Turns++
+		call, err := e.fitPrompt(req, name, input)
+		if err != nil {
+			return res, fmt.Errorf("%s: turn %d: %w", m.Metadata.Name, res.Turns, err)
+		}
+		var resp *Response
+		if stream != nil {
+			turn := res.Turns
+			resp, err = stream.Stream(ctx, call, func(text string) {
+				emit(Event{Type: EventToken, Agent: name, Turn: turn, Text: text})
+			})
+		} else {
+			resp, err = e.Model.Complete(ctx, call)
+		}
+		if err != nil {
+			return res, fmt.Errorf("%s: turn %d: %w", m.Metadata.Name, res.Turns, err)
+		}
+		res.Usage.add(resp.Usage)
+		usage := resp.Usage
+		req.Messages = append(req.Messages, Message{Role: RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls, Usage: &usage, Provider: resp.Provider, Model: resp.Model})
+		if len(resp.ToolCalls) == 0 {
+			answer, err := e.moderate(ctx, moderator, m, ossa.StageCompletion, resp.Content)
+			// The conversation keeps the answer as moderated, or drops it.
+			last := len(req.Messages) - 1
+			if err != nil {
+				req.Messages = req.Messages[:last]
+				return res, fmt.Errorf("%s: answer: %w", m.Metadata.Name, err)
+			}
+			req.Messages[last].Content = answer
+			res.Output = parseOutput(answer)
+			return res, nil
+		}
+		for _, c := range resp.ToolCalls {
+			emit(Event{Type: EventToolCall, Agent: name, Turn: res.Turns, CallID: c.ID, Tool: c.Tool, Arguments: c.Arguments})
+		}
+		results, _ := m.ExecuteToolCalls(ctx, resp.ToolCalls, exec)
+		for i, r := range results {
+			rec := ToolRecord{ID: r.ID, Tool: r.Tool, Arguments: resp.ToolCalls[i].Arguments, Output: string(r.Output)}
+			content := rec.Output
+			if r.Err == nil {
+				content, r.Err = screen(m, rec.Tool, content)
+			}
+			if r.Err != nil {
+				rec.Error = r.Err.Error()
+				content = "error: " + rec.Error
+			}
+			emit(Event{Type: EventToolResult, Agent: name, CallID: rec.ID, Tool: rec.Tool, Result: rec.Output, Error: rec.Error})
+			res.ToolCalls = append(res.ToolCalls, rec)
+			req.Messages = append(req.Messages, Message{Role: RoleTool, Content: content, ToolCallID: r.ID})
+		}
+		if req.LLM, err = turnLLM(m, resp.ToolCalls); err != nil {
+			return res, fmt.Errorf("%s: turn %d: %w", m.Metadata.Name, res.Turns, err)
+		}
+		req.LLM = e.seeded(req.LLM)
+	}
+	return res, fmt.Errorf("%s: no answer after %d turns", m.Metadata.Name, max)
+}
+
+// moderator is m's spec.safety.moderation, or nil if it has none.
+func (e *Engine) moderator(m *ossa.Manifest) (*ossa.Moderator, error) {
+	if e.NoModeration || m.Spec.Safety == nil {
+		return nil, nil
+	}
+	return ossa.NewModerator(m.Spec.Safety.Moderation)
+}
+
+// moderate checks text with moderator, returning it, possibly redacted,
+// and records the result if it flags the text.
+func (e *Engine) moderate(ctx context.Context, moderator *ossa.Moderator, m *ossa.Manifest, stage ossa.ModerationStage, text string) (string, error) {
+	if moderator == nil {
+		return text, nil
+	}
+	result, err := moderator.Check(ctx, stage, text)
+	if err != nil {
+		return "", err
+	}
+	result.Record(e.AuditLogger, m.Metadata.Name)
+	if result.Blocked {
+		return "", ossa.ErrContentBlocked
+	}
+	return result.Text, nil
+}
+
+// screen runs spec.safety.injection_detection on the output of tool,
+// applying its action to suspected injections: block, the default, fails
+// the call, redact removes the findings and flag only logs them.
+func screen(m *ossa.Manifest, tool, output string) (string, error) {
+	safety := m.Spec.Safety
+	if safety == nil || safety.InjectionDetection == nil || !safety.InjectionDetection.Enabled {
+		return output, nil
+	}
+	cfg := safety.InjectionDetection
+	report := ossa.NewInjectionAnalyzer(cfg).Analyze(tool, output)
+	if !report.Detected {
+		return output, nil
+	}
+	rules := make([]string, len(report.Findings))
+	for i, f := range report.Findings {
+		rules[i] = f.Rule
+	}
+	ossa.Logger().Warn("possible prompt injection in tool output", "agent", m.Metadata.Name, "tool", tool, "score", report.Score, "rules", rules, "action", cfg.Action)
+	switch cfg.Action {
+	case ossa.ModerationFlag:
+		return output, nil
+	case ossa.ModerationRedact:
+		for _, f := range report.Findings {
+			if !f.Encoded {
+				output = strings.ReplaceAll(output, f.Match, "[REDACTED]")
+			}
+		}
+		return output, nil
+	}
+	return "", fmt.Errorf("output blocked: possible prompt injection (%s)", strings.Join(rules, ", "))
+}
+
+// seeded returns llm with e.Seed as its seed if it sets none.
+func (e *Engine) seeded(llm *ossa.LLMConfig) *ossa.LLMConfig {
+	if e.Seed == nil || llm == nil || llm.Seed != nil {
+		return llm
+	}
+	c := *llm
+	seed := *e.Seed
+	c.Seed = &seed
+	return &c
+}
+
+// turnLLM is the model for the turn handling the results of calls.
+func turnLLM(m *ossa.Manifest, calls []ossa.ToolCall) (*ossa.LLMConfig, error) {
+	for _, c := range calls {
+		for _, t := range m.Spec.Tools {
+			if t.Name == c.Tool && t.LLM != nil {
+				return m.LLMFor(c.Tool)
+			}
+		}
+	}
+	return m.Spec.LLM, nil
+}
+
+func noTools(_ context.Context, call ossa.ToolCall) ([]byte, error) {
+	return nil, fmt.Errorf("no runtime for tool %s", call.Tool)
+}
+
+func (e *Engine) locale() string {
+	if e.Locale == "" {
+		return ossa.DefaultLocale
+	}
+	return e.Locale
+}
+
+// systemPrompt is spec.prompts.system, falling back to spec.role.
+func systemPrompt(m *ossa.Manifest, locale string) string {
+	if p := m.Spec.Prompts; p != nil && p.System != nil && !p.System.Template.IsZero() {
+		return p.System.Template.For(locale)
+	}
+	return m.RoleFor(locale)
+}
+
+// parseOutput decodes a JSON object answer, possibly fenced as a Markdown
+// code block, or wraps plain text as {"content": text}.
+func parseOutput(content string) map[string]interface{} {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "```json"), "```")
+		text = strings.TrimSpace(strings.TrimSuffix(text, "```"))
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &out); err == nil && out != nil {
+		return out
+	}
+	return map[string]interface{}{"content": content}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import "context"
+
+// Event types sent to Engine.Events.
+const (
+	// EventAgentStarted is sent as an agent run starts.
+	EventAgentStarted = "agent_started"
+	// EventToken carries a piece of the answer's text as the model
+	// generates it, when the model streams; see StreamingModel.
+	EventToken = "token"
+	// EventToolCall is sent before a tool call is executed.
+	EventToolCall = "tool_call"
+	// EventToolResult carries a tool call's output or error.
+	EventToolResult = "tool_result"
+	// EventAgentFinished carries an agent's output and usage, or its error.
+	EventAgentFinished = "agent_finished"
+	// EventStepStarted and EventStepFinished bracket each workflow step
+	// that is not skipped.
+	EventStepStarted  = "step_started"
+	EventStepFinished = "step_finished"
+)
+
+// Event is a step of a run as it happens, for showing live progress. Which
+// fields are set depends on Type.
+type Event struct {
+	Type string `json:"type"`
+	// Agent is the agent an agent, token or tool event belongs to.
+	Agent string `json:"agent,omitempty"`
+	// Step is the workflow step of a step event.
+	Step string `json:"step,omitempty"`
+	// Turn is the model turn of a token or tool_call event, from 1.
+	Turn int `json:"turn,omitempty"`
+	// Text is a token event's text.
+	Text      string                 `json:"text,omitempty"`
+	CallID    string                 `json:"call_id,omitempty"`
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	// Result is a tool_result event's tool output.
+	Result string `json:"result,omitempty"`
+	// Output is the output of a finished agent or step.
+	Output map[string]interface{} `json:"output,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+	Usage  *Usage                 `json:"usage,omitempty"`
+}
+
+// StreamingModel is a Model that can send its answer's text as it is
+// generated. The engine streams turns when Engine.Events is set.
+type StreamingModel interface {
+	Model
+	// Stream is Complete, calling onText with each piece of text as it
+	// arrives.
+	Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error)
+}
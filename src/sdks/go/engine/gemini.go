@@ -0,0 +1,484 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultGeminiURL is the Gemini API base URL.
+const DefaultGeminiURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// Gemini is a Model calling Google's Gemini models, through the Gemini API
+// with an API key or through Vertex AI with Application Default
+// Credentials. A request whose spec.llm.vertex is set goes to Vertex AI in
+// that project and location, with Project and Location filling in what it
+// leaves out.
+type Gemini struct {
+	// APIKey is the Gemini API key.
+	APIKey string
+	// Vertex sends requests to Vertex AI even without spec.llm.vertex.
+	Vertex   bool
+	Project  string
+	Location string
+	// BaseURL defaults to DefaultGeminiURL, or the Vertex AI endpoint of the
+	// location.
+	BaseURL string
+	// Token returns the Vertex AI access token. It defaults to Application
+	// Default Credentials.
+	Token func(ctx context.Context) (string, error)
+	// Client defaults to one with a 2 minute timeout.
+	Client *http.Client
+
+	adc adcTokens
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFile             `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+type geminiFile struct {
+	MimeType string `json:"mimeType"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (g *Gemini) Complete(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := g.post(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode Gemini response: %w", err)
+	}
+	res := &Response{}
+	out.addTo(res, nil)
+	return res, nil
+}
+
+// Stream is Complete over streamGenerateContent, sending the text to
+// onText as it arrives.
+func (g *Gemini) Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error) {
+	resp, err := g.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res := &Response{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		data = strings.TrimSpace(data)
+		if !ok || data == "" {
+			continue
+		}
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode Gemini response chunk: %w", err)
+		}
+		chunk.addTo(res, onText)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Gemini stream: %w", err)
+	}
+	return res, nil
+}
+
+// Embed calls batchEmbedContents of the Gemini API, or predict on Vertex
+// AI.
+func (g *Gemini) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	cfg := req.Embeddings
+	if cfg.Vertex != nil || g.Vertex {
+		instances := make([]map[string]interface{}, len(req.Texts))
+		for i, text := range req.Texts {
+			instances[i] = map[string]interface{}{"content": text}
+		}
+		body := map[string]interface{}{"instances": instances}
+		if cfg.Dimensions > 0 {
+			body["parameters"] = map[string]interface{}{"outputDimensionality": cfg.Dimensions}
+		}
+		resp, err := g.send(ctx, cfg.Model, cfg.Vertex, ":predict", body)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		var out struct {
+			Predictions []struct {
+				Embeddings struct {
+					Values []float64 `json:"values"`
+				} `json:"embeddings"`
+			} `json:"predictions"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("failed to decode Vertex AI predict response: %w", err)
+		}
+		vectors := make([][]float64, len(out.Predictions))
+		for i, p := range out.Predictions {
+			vectors[i] = p.Embeddings.Values
+		}
+		return checkVectors("vertex ai predict", vectors, len(req.Texts))
+	}
+
+	requests := make([]map[string]interface{}, len(req.Texts))
+	for i, text := range req.Texts {
+		requests[i] = map[string]interface{}{"model": "models/" + cfg.Model, "content": geminiContent{Parts: []geminiPart{{Text: text}}}}
+		if cfg.Dimensions > 0 {
+			requests[i]["outputDimensionality"] = cfg.Dimensions
+		}
+	}
+	resp, err := g.send(ctx, cfg.Model, nil, ":batchEmbedContents", map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode Gemini embeddings: %w", err)
+	}
+	vectors := make([][]float64, len(out.Embeddings))
+	for i, e := range out.Embeddings {
+		vectors[i] = e.Values
+	}
+	return checkVectors("gemini batchEmbedContents", vectors, len(req.Texts))
+}
+
+// addTo adds the first candidate's parts to res, sending text to onText if
+// set. Usage is cumulative, so the last seen is kept.
+func (r *geminiResponse) addTo(res *Response, onText func(string)) {
+	if u := r.UsageMetadata; u != nil {
+		res.Usage = Usage{InputTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount}
+	}
+	if len(r.Candidates) == 0 {
+		return
+	}
+	for _, part := range r.Candidates[0].Content.Parts {
+		if part.Text != "" {
+			res.Content += part.Text
+			if onText != nil {
+				onText(part.Text)
+			}
+		}
+		if fc := part.FunctionCall; fc != nil {
+			// Gemini API calls carry no ID, so number them.
+			id := fc.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d", len(res.ToolCalls))
+			}
+			res.ToolCalls = append(res.ToolCalls, ossa.ToolCall{ID: id, Tool: fc.Name, Arguments: fc.Args})
+		}
+	}
+}
+
+// post sends req to generateContent, or streamGenerateContent, returning
+// the response once its status is 200.
+func (g *Gemini) post(ctx context.Context, req *Request, stream bool) (*http.Response, error) {
+	if req.LLM == nil || req.LLM.Model == "" {
+		return nil, fmt.Errorf("the google provider needs spec.llm.model")
+	}
+	method := ":generateContent"
+	if stream {
+		method = ":streamGenerateContent?alt=sse"
+	}
+	return g.send(ctx, req.LLM.Model, req.LLM.Vertex, method, geminiBody(req))
+}
+
+// send posts body to method of model, on Vertex AI when vertex is set or
+// g.Vertex, returning the response once its status is 200.
+func (g *Gemini) send(ctx context.Context, model string, vertex *ossa.VertexConfig, method string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoint string
+	headers := map[string]string{}
+	if cfg := vertex; cfg != nil || g.Vertex {
+		project, location, base := g.Project, g.Location, g.BaseURL
+		if cfg != nil {
+			if p := expandEnv(cfg.Project); p != "" {
+				project = p
+			}
+			if l := expandEnv(cfg.Location); l != "" {
+				location = l
+			}
+			if e := expandEnv(cfg.Endpoint); e != "" {
+				base = e
+			}
+		}
+		if project == "" {
+			return nil, fmt.Errorf("the google provider needs spec.llm.vertex.project or GOOGLE_CLOUD_PROJECT for Vertex AI")
+		}
+		if location == "" {
+			location = "us-central1"
+		}
+		if base == "" {
+			base = "https://" + location + "-aiplatform.googleapis.com"
+			if location == "global" {
+				base = "https://aiplatform.googleapis.com"
+			}
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/projects/" + url.PathEscape(project) + "/locations/" + url.PathEscape(location) +
+			"/publishers/google/models/" + url.PathEscape(model) + method
+		var token string
+		if g.Token != nil {
+			token, err = g.Token(ctx)
+		} else {
+			token, err = g.adc.Token(ctx, g.Client)
+		}
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "Bearer " + token
+	} else {
+		if g.APIKey == "" {
+			return nil, fmt.Errorf("the google provider needs GEMINI_API_KEY or GOOGLE_API_KEY, or Vertex AI")
+		}
+		base := g.BaseURL
+		if base == "" {
+			base = DefaultGeminiURL
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/models/" + url.PathEscape(model) + method
+		headers["x-goog-api-key"] = g.APIKey
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+	client := g.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError("gemini", resp)
+	}
+	return resp, nil
+}
+
+// geminiBody is the generateContent request for req. System messages
+// become the system instruction, assistant messages model turns and tool
+// results function responses, named after the call they answer.
+func geminiBody(req *Request) map[string]interface{} {
+	body := map[string]interface{}{}
+	var system []geminiPart
+	var contents []geminiContent
+	names := map[string]string{}
+	add := func(role string, part geminiPart) {
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, part)
+			return
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{part}})
+	}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case RoleSystem:
+			if m.Content != "" {
+				system = append(system, geminiPart{Text: m.Content})
+			}
+		case RoleTool:
+			var response map[string]interface{}
+			if json.Unmarshal([]byte(m.Content), &response) != nil || response == nil {
+				response = map[string]interface{}{"content": m.Content}
+			}
+			add("user", geminiPart{FunctionResponse: &geminiFunctionResponse{Name: names[m.ToolCallID], Response: response}})
+		case RoleAssistant:
+			if m.Content != "" {
+				add("model", geminiPart{Text: m.Content})
+			}
+			for _, c := range m.ToolCalls {
+				names[c.ID] = c.Tool
+				args := c.Arguments
+				if args == nil {
+					args = map[string]interface{}{}
+				}
+				add("model", geminiPart{FunctionCall: &geminiFunctionCall{Name: c.Tool, Args: args}})
+			}
+		default:
+			if m.Content != "" {
+				add("user", geminiPart{Text: m.Content})
+			}
+			// Attachments are passed inline, or by URI for files
+			// Gemini can fetch.
+			for _, a := range m.Attachments {
+				if len(a.Data) > 0 {
+					add("user", geminiPart{InlineData: &geminiBlob{MimeType: a.MIMEType, Data: a.Data}})
+				} else {
+					add("user", geminiPart{FileData: &geminiFile{MimeType: a.MIMEType, FileURI: a.URL}})
+				}
+			}
+		}
+	}
+	body["contents"] = contents
+	if len(system) > 0 {
+		body["systemInstruction"] = geminiContent{Parts: system}
+	}
+	if llm := req.LLM; llm != nil {
+		config := map[string]interface{}{}
+		if llm.MaxTokens != 0 {
+			config["maxOutputTokens"] = llm.MaxTokens
+		}
+		if llm.Temperature != 0 {
+			config["temperature"] = llm.Temperature
+		}
+		if llm.TopP != 0 {
+			config["topP"] = llm.TopP
+		}
+		if llm.FrequencyPenalty != 0 {
+			config["frequencyPenalty"] = llm.FrequencyPenalty
+		}
+		if llm.PresencePenalty != 0 {
+			config["presencePenalty"] = llm.PresencePenalty
+		}
+		if len(llm.Stop) > 0 {
+			config["stopSequences"] = llm.Stop
+		}
+		if llm.Seed != nil {
+			config["seed"] = *llm.Seed
+		}
+		if f := llm.ResponseFormat; f != nil && f.Type != ossa.ResponseFormatText {
+			config["responseMimeType"] = "application/json"
+			if f.Type == ossa.ResponseFormatJSONSchema && f.Schema != nil {
+				config["responseSchema"] = geminiSchema(f.Schema)
+			}
+		}
+		if len(config) > 0 {
+			body["generationConfig"] = config
+		}
+	}
+	var declarations []map[string]interface{}
+	for _, t := range req.Tools {
+		if t.Name == "" {
+			continue
+		}
+		decl := map[string]interface{}{"name": t.Name}
+		if t.Description != "" {
+			decl["description"] = t.Description
+		}
+		if t.Parameters != nil {
+			decl["parameters"] = geminiSchema(t.Parameters)
+		}
+		declarations = append(declarations, decl)
+	}
+	if len(declarations) > 0 {
+		body["tools"] = []map[string]interface{}{{"functionDeclarations": declarations}}
+	}
+	return body
+}
+
+// geminiSchemaKeys are the JSON Schema keywords function declarations
+// accept; Gemini rejects the rest.
+var geminiSchemaKeys = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true, "nullable": true, "enum": true,
+	"properties": true, "required": true, "items": true, "anyOf": true, "default": true,
+	"minItems": true, "maxItems": true, "minProperties": true, "maxProperties": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+}
+
+// geminiSchema is the JSON Schema s with only the keywords Gemini accepts,
+// a ["T", "null"] type becoming a nullable T.
+func geminiSchema(s map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range s {
+		if !geminiSchemaKeys[k] {
+			continue
+		}
+		switch k {
+		case "type":
+			if types, ok := v.([]interface{}); ok {
+				for _, t := range types {
+					if t == "null" {
+						out["nullable"] = true
+					} else {
+						v = t
+					}
+				}
+			}
+		case "properties":
+			if props, ok := v.(map[string]interface{}); ok {
+				converted := map[string]interface{}{}
+				for name, p := range props {
+					if ps, ok := p.(map[string]interface{}); ok {
+						converted[name] = geminiSchema(ps)
+					}
+				}
+				v = converted
+			}
+		case "items":
+			if items, ok := v.(map[string]interface{}); ok {
+				v = geminiSchema(items)
+			}
+		case "anyOf":
+			if list, ok := v.([]interface{}); ok {
+				converted := make([]interface{}, 0, len(list))
+				for _, item := range list {
+					if is, ok := item.(map[string]interface{}); ok {
+						converted = append(converted, geminiSchema(is))
+					}
+				}
+				v = converted
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
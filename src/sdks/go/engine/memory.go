@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Memory keeps an agent's conversation memory, the entries of its memory
+// input field, within bounds. RunAgent compacts the memory once, before
+// the first turn.
+type Memory interface {
+	Compact(ctx context.Context, m *ossa.Manifest, entries []json.RawMessage) ([]json.RawMessage, error)
+}
+
+// SummaryPrompt is the system prompt SummarizingMemory summarizes with.
+const SummaryPrompt = "Summarize the conversation below for the assistant that continues it. Keep facts, names, decisions and open questions; leave out pleasantries. Answer with the summary only."
+
+// summaryPrefix starts the content of the entry replacing summarized ones.
+const summaryPrefix = "Summary of the earlier conversation: "
+
+// WindowMemory keeps the most recent entries: at most MaxMessages of them,
+// of at most MaxTokens together. A zero limit is no limit.
+type WindowMemory struct {
+	MaxMessages int
+	MaxTokens   int
+	// CountTokens defaults to EstimateTokens.
+	CountTokens func(text string) int
+}
+
+// Compact drops the oldest entries over the limits.
+func (w *WindowMemory) Compact(ctx context.Context, m *ossa.Manifest, entries []json.RawMessage) ([]json.RawMessage, error) {
+	if w.MaxMessages > 0 && len(entries) > w.MaxMessages {
+		entries = entries[len(entries)-w.MaxMessages:]
+	}
+	if w.MaxTokens > 0 {
+		count := counter(w.CountTokens)
+		tokens := entriesTokens(count, entries)
+		for len(entries) > 0 && tokens > w.MaxTokens {
+			tokens -= entryTokens(count, entries[0])
+			entries = entries[1:]
+		}
+	}
+	return entries, nil
+}
+
+// SummarizingMemory has Model summarize the oldest entries into one once
+// they reach Threshold of MaxTokens, keeping the KeepMessages most recent
+// as they are. If the summary fails the entries are kept and a warning
+// logged: the prompt is still fitted to the context window.
+type SummarizingMemory struct {
+	Model Model
+	// LLM is the model to summarize with, typically a cheap one.
+	LLM       *ossa.LLMConfig
+	MaxTokens int
+	// Threshold defaults to ossa.DefaultSummaryThreshold and KeepMessages
+	// to ossa.DefaultKeepMessages.
+	Threshold    float64
+	KeepMessages int
+	// CountTokens defaults to EstimateTokens.
+	CountTokens func(text string) int
+}
+
+// Compact replaces the older entries with a summary if the entries are
+// near MaxTokens.
+func (s *SummarizingMemory) Compact(ctx context.Context, m *ossa.Manifest, entries []json.RawMessage) ([]json.RawMessage, error) {
+	threshold := s.Threshold
+	if threshold == 0 {
+		threshold = ossa.DefaultSummaryThreshold
+	}
+	keep := s.KeepMessages
+	if keep == 0 {
+		keep = ossa.DefaultKeepMessages
+	}
+	count := counter(s.CountTokens)
+	if s.MaxTokens == 0 || len(entries) <= keep || float64(entriesTokens(count, entries)) < threshold*float64(s.MaxTokens) {
+		return entries, nil
+	}
+	old, recent := entries[:len(entries)-keep], entries[len(entries)-keep:]
+	var transcript strings.Builder
+	for _, e := range old {
+		transcript.WriteString(entryText(e))
+		transcript.WriteString("\n")
+	}
+	resp, err := s.Model.Complete(ctx, &Request{
+		Agent: m.Metadata.Name,
+		LLM:   s.LLM,
+		Messages: []Message{
+			{Role: RoleSystem, Content: SummaryPrompt},
+			{Role: RoleUser, Content: transcript.String()},
+		},
+	})
+	if err == nil && strings.TrimSpace(resp.Content) == "" {
+		err = fmt.Errorf("empty summary")
+	}
+	if err != nil {
+		ossa.Logger().Warn("failed to summarize memory", "agent", m.Metadata.Name, "entries", len(old), "error", err)
+		return entries, nil
+	}
+	summary, err := json.Marshal(map[string]string{"role": RoleSystem, "content": summaryPrefix + strings.TrimSpace(resp.Content)})
+	if err != nil {
+		return nil, err
+	}
+	return append([]json.RawMessage{summary}, recent...), nil
+}
+
+// memory is the Memory of m: e.Memory's, else the one its
+// spec.state.context_window configures; nil if none.
+func (e *Engine) memory(m *ossa.Manifest) (Memory, error) {
+	if e.Memory != nil {
+		return e.Memory(m), nil
+	}
+	if m.Spec.State == nil || m.Spec.State.ContextWindow == nil {
+		return nil, nil
+	}
+	c := m.Spec.State.ContextWindow
+	if c.Strategy != ossa.StrategySummarization {
+		if c.MaxMessages == 0 && c.MaxTokens == 0 {
+			return nil, nil
+		}
+		return &WindowMemory{MaxMessages: c.MaxMessages, MaxTokens: c.MaxTokens, CountTokens: e.CountTokens}, nil
+	}
+	llm, err := m.SummarizerLLM()
+	if err != nil {
+		return nil, err
+	}
+	return &SummarizingMemory{Model: e.Model, LLM: llm, MaxTokens: c.MaxTokens, Threshold: c.Threshold, KeepMessages: c.KeepMessages, CountTokens: e.CountTokens}, nil
+}
+
+// compactMemory returns input with its memory field compacted by the
+// Memory of m. Input without a list there is returned as it is.
+func (e *Engine) compactMemory(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+	mem, err := e.memory(m)
+	if err != nil || mem == nil {
+		return input, err
+	}
+	field := memoryField(m.Spec.LLM)
+	raw, ok := input[field]
+	if !ok {
+		return input, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", field, err)
+	}
+	var entries []json.RawMessage
+	if json.Unmarshal(data, &entries) != nil {
+		return input, nil
+	}
+	compacted, err := mem.Compact(ctx, m, entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact %s: %w", field, err)
+	}
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		out[k] = v
+	}
+	out[field] = compacted
+	return out, nil
+}
+
+// memoryField is the input field holding conversation memory.
+func memoryField(llm *ossa.LLMConfig) string {
+	if llm != nil && llm.Context != nil && llm.Context.MemoryField != "" {
+		return llm.Context.MemoryField
+	}
+	return DefaultMemoryField
+}
+
+// entryText is the content of a {role, content} entry as "role: content",
+// else the entry's JSON.
+func entryText(entry json.RawMessage) string {
+	var msg struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	if json.Unmarshal(entry, &msg) == nil && msg.Content != "" {
+		if msg.Role == "" {
+			return msg.Content
+		}
+		return msg.Role + ": " + msg.Content
+	}
+	return string(entry)
+}
+
+func counter(count func(string) int) func(string) int {
+	if count == nil {
+		return EstimateTokens
+	}
+	return count
+}
+
+func entriesTokens(count func(string) int, entries []json.RawMessage) int {
+	n := 0
+	for _, e := range entries {
+		n += entryTokens(count, e)
+	}
+	return n
+}
+
+// entryTokens counts an entry as prompt.entry does.
+func entryTokens(count func(string) int, entry json.RawMessage) int {
+	return count(string(entry)) + 1
+}
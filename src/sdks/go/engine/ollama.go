@@ -0,0 +1,339 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultOllamaURL is where a local Ollama server listens.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// Ollama is a Model calling the chat API of an Ollama server, which runs
+// models pulled onto the host.
+type Ollama struct {
+	// BaseURL defaults to OllamaURL().
+	BaseURL string
+	// Client defaults to one with a 5 minute timeout, as local models may
+	// load slowly.
+	Client *http.Client
+}
+
+// OllamaURL is the Ollama server OLLAMA_HOST names, or DefaultOllamaURL.
+// As with the ollama command, a bare host gets port 11434 and a URL the
+// default port of its scheme.
+func OllamaURL() string {
+	host := strings.TrimSuffix(strings.TrimSpace(os.Getenv("OLLAMA_HOST")), "/")
+	if host == "" {
+		return DefaultOllamaURL
+	}
+	port := "11434"
+	scheme, addr, ok := strings.Cut(host, "://")
+	switch {
+	case !ok:
+		scheme, addr = "http", host
+	case scheme == "https":
+		port = "443"
+	default:
+		port = "80"
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), port)
+	}
+	// 0.0.0.0 is where a server listens, not where to reach it.
+	addr = strings.Replace(addr, "0.0.0.0", "localhost", 1)
+	return scheme + "://" + addr
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	// ToolName is the tool a tool message answers.
+	ToolName string `json:"tool_name,omitempty"`
+	// Images are base64 image data, for vision models.
+	Images []string `json:"images,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaChat struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (o *Ollama) Complete(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := o.post(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out ollamaChat
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama chat response: %w", err)
+	}
+	res := &Response{}
+	out.addTo(res, nil)
+	return res, nil
+}
+
+// Stream is Complete over a streamed chat, one JSON object per line,
+// sending the text to onText as it arrives.
+func (o *Ollama) Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error) {
+	resp, err := o.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res := &Response{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChat
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode Ollama chat chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		chunk.addTo(res, onText)
+		if chunk.Done {
+			return res, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Ollama chat stream: %w", err)
+	}
+	return nil, fmt.Errorf("ollama chat stream ended early")
+}
+
+// addTo adds the message of c to res, sending text to onText if set.
+func (c *ollamaChat) addTo(res *Response, onText func(string)) {
+	if c.Message.Content != "" {
+		res.Content += c.Message.Content
+		if onText != nil {
+			onText(c.Message.Content)
+		}
+	}
+	// Ollama's tool calls carry no ID, so number them.
+	for _, tc := range c.Message.ToolCalls {
+		id := fmt.Sprintf("call_%d", len(res.ToolCalls))
+		res.ToolCalls = append(res.ToolCalls, ossa.ToolCall{ID: id, Tool: tc.Function.Name, Arguments: tc.Function.Arguments})
+	}
+	if c.Done {
+		res.Usage = Usage{InputTokens: c.PromptEvalCount, OutputTokens: c.EvalCount}
+	}
+}
+
+// post sends req to /api/chat, returning the response once its status is
+// 200.
+func (o *Ollama) post(ctx context.Context, req *Request, stream bool) (*http.Response, error) {
+	body := map[string]interface{}{"stream": stream}
+	if llm := req.LLM; llm != nil {
+		body["model"] = llm.Model
+		options := map[string]interface{}{}
+		if llm.Temperature != 0 {
+			options["temperature"] = llm.Temperature
+		}
+		if llm.MaxTokens != 0 {
+			options["num_predict"] = llm.MaxTokens
+		}
+		if llm.TopP != 0 {
+			options["top_p"] = llm.TopP
+		}
+		if llm.FrequencyPenalty != 0 {
+			options["frequency_penalty"] = llm.FrequencyPenalty
+		}
+		if llm.PresencePenalty != 0 {
+			options["presence_penalty"] = llm.PresencePenalty
+		}
+		if len(llm.Stop) > 0 {
+			options["stop"] = llm.Stop
+		}
+		if llm.Seed != nil {
+			options["seed"] = *llm.Seed
+		}
+		// format is "json" or the schema the answer must match.
+		if f := llm.ResponseFormat; f != nil {
+			switch f.Type {
+			case ossa.ResponseFormatJSONObject:
+				body["format"] = "json"
+			case ossa.ResponseFormatJSONSchema:
+				body["format"] = f.Schema
+			}
+		}
+		if len(options) > 0 {
+			body["options"] = options
+		}
+	}
+	names := map[string]string{}
+	messages := make([]ollamaMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = ollamaMessage{Role: m.Role, Content: m.Content, ToolName: names[m.ToolCallID]}
+		if err := onlyImageData("ollama", m); err != nil {
+			return nil, err
+		}
+		for _, a := range m.Attachments {
+			messages[i].Images = append(messages[i].Images, base64.StdEncoding.EncodeToString(a.Data))
+		}
+		for _, c := range m.ToolCalls {
+			names[c.ID] = c.Tool
+			var tc ollamaToolCall
+			tc.Function.Name, tc.Function.Arguments = c.Tool, c.Arguments
+			if tc.Function.Arguments == nil {
+				tc.Function.Arguments = map[string]interface{}{}
+			}
+			messages[i].ToolCalls = append(messages[i].ToolCalls, tc)
+		}
+	}
+	body["messages"] = messages
+	var tools []openAITool
+	for _, t := range req.Tools {
+		if t.Name == "" {
+			continue
+		}
+		params := t.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object"}
+		}
+		tools = append(tools, openAITool{Type: "function", Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: params}})
+	}
+	if len(tools) > 0 {
+		body["tools"] = tools
+	}
+
+	return o.send(ctx, "/api/chat", "ollama chat", body)
+}
+
+// send posts body to path, returning the response once its status is
+// 200; op names the call in errors.
+func (o *Ollama) send(ctx context.Context, path, op string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url()+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := o.client().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError(op, resp)
+	}
+	return resp, nil
+}
+
+// Embed calls /api/embed.
+func (o *Ollama) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	body := map[string]interface{}{"model": req.Embeddings.Model, "input": req.Texts}
+	if d := req.Embeddings.Dimensions; d > 0 {
+		body["dimensions"] = d
+	}
+	resp, err := o.send(ctx, "/api/embed", "ollama embed", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama embed response: %w", err)
+	}
+	return checkVectors("ollama embed", out.Embeddings, len(req.Texts))
+}
+
+// HasModel reports whether model has been pulled onto the server. A name
+// without a tag means its latest tag.
+func (o *Ollama) HasModel(ctx context.Context, model string) (bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, o.url()+"/api/tags", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := o.client().Do(httpReq)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("ollama tags: %s", resp.Status)
+	}
+	var out struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return false, fmt.Errorf("failed to decode Ollama tags: %w", err)
+	}
+	if !strings.Contains(model, ":") {
+		model += ":latest"
+	}
+	for _, m := range out.Models {
+		if m.Name == model || m.Model == model {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (o *Ollama) url() string {
+	if o.BaseURL != "" {
+		return strings.TrimSuffix(o.BaseURL, "/")
+	}
+	return OllamaURL()
+}
+
+func (o *Ollama) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return &http.Client{Timeout: 5 * time.Minute}
+}
+
+// CheckRuntime returns warnings about what running m needs that this host
+// lacks: for now, an Ollama model not yet pulled or an Ollama server out
+// of reach.
+func CheckRuntime(ctx context.Context, m *ossa.Manifest) []string {
+	llm := m.Spec.LLM
+	if llm == nil || expandEnv(llm.Provider) != "ollama" || llm.Model == "" {
+		return nil
+	}
+	o := &Ollama{Client: &http.Client{Timeout: 10 * time.Second}}
+	ok, err := o.HasModel(ctx, llm.Model)
+	if err != nil {
+		return []string{fmt.Sprintf("spec.llm: could not reach Ollama at %s: %v", o.url(), err)}
+	}
+	if !ok {
+		return []string{fmt.Sprintf("spec.llm.model: %s is not on the Ollama host %s; run ollama pull %s", llm.Model, o.url(), llm.Model)}
+	}
+	return nil
+}
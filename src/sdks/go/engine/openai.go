@@ -0,0 +1,411 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultOpenAIURL is the OpenAI API base URL.
+const DefaultOpenAIURL = "https://api.openai.com/v1"
+
+// OpenAI is a Model calling the chat completions API of OpenAI or a
+// compatible server. The model name and sampling come from each request's
+// LLM config.
+type OpenAI struct {
+	// BaseURL defaults to DefaultOpenAIURL.
+	BaseURL string
+	APIKey  string
+	// Headers are sent with every request, for gateways wanting more than
+	// the bearer key.
+	Headers map[string]string
+	// ModelName, if set, maps spec.llm.model to the name the server knows
+	// it by.
+	ModelName func(model string) string
+	// Client defaults to one with a 2 minute timeout.
+	Client *http.Client
+
+	// query is added to the endpoint URL, for Azure's api-version.
+	query string
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	// Parts, if set, are sent as the content instead.
+	Parts []openAIPart `json:"-"`
+}
+
+// MarshalJSON sends the content as parts when the message has them.
+func (m openAIMessage) MarshalJSON() ([]byte, error) {
+	type message openAIMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []openAIPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+type openAIPart struct {
+	Type       string            `json:"type"`
+	Text       string            `json:"text,omitempty"`
+	ImageURL   *openAIImageURL   `json:"image_url,omitempty"`
+	InputAudio *openAIInputAudio `json:"input_audio,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// openAIParts is the content of m, text then attachments, as parts.
+func openAIParts(m Message) ([]openAIPart, error) {
+	parts := []openAIPart{{Type: "text", Text: m.Content}}
+	for _, a := range m.Attachments {
+		p := openAIPart{Type: "image_url"}
+		if a.Type == ossa.ModalityAudio {
+			format, err := audioFormat(a.MIMEType)
+			if err != nil {
+				return nil, err
+			}
+			if len(a.Data) == 0 {
+				return nil, fmt.Errorf("chat completions need audio data, not a URL")
+			}
+			p.Type = "input_audio"
+			p.InputAudio = &openAIInputAudio{Data: base64.StdEncoding.EncodeToString(a.Data), Format: format}
+		} else {
+			p.ImageURL = &openAIImageURL{URL: a.dataURL()}
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+func (o *OpenAI) Complete(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := o.post(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return decodeCompletion(resp.Body)
+}
+
+func decodeCompletion(body io.Reader) (*Response, error) {
+	var out struct {
+		Choices []struct {
+			Message openAIMessage `json:"message"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode chat completion: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion has no choices")
+	}
+	return out.Choices[0].Message.response(out.Usage)
+}
+
+// Stream is Complete over a streamed chat completion, sending the text
+// deltas to onText as they arrive. A server answering in one piece
+// instead has its whole text sent at once.
+func (o *OpenAI) Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error) {
+	resp, err := o.post(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		res, err := decodeCompletion(resp.Body)
+		if err == nil && res.Content != "" {
+			onText(res.Content)
+		}
+		return res, err
+	}
+
+	var msg openAIMessage
+	var usage openAIUsage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		data = strings.TrimSpace(data)
+		if !ok || data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			return msg.response(usage)
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *openAIUsage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode chat completion chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			msg.Content += delta.Content
+			onText(delta.Content)
+		}
+		// Tool calls arrive in pieces, keyed by index.
+		for _, d := range delta.ToolCalls {
+			for len(msg.ToolCalls) <= d.Index {
+				msg.ToolCalls = append(msg.ToolCalls, openAIToolCall{Type: "function"})
+			}
+			tc := &msg.ToolCalls[d.Index]
+			if d.ID != "" {
+				tc.ID = d.ID
+			}
+			tc.Function.Name += d.Function.Name
+			tc.Function.Arguments += d.Function.Arguments
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chat completion stream: %w", err)
+	}
+	return nil, fmt.Errorf("chat completion stream ended early")
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// response converts an assistant message to a Response.
+func (msg openAIMessage) response(usage openAIUsage) (*Response, error) {
+	res := &Response{Content: msg.Content, Usage: Usage{InputTokens: usage.PromptTokens, OutputTokens: usage.CompletionTokens}}
+	for _, tc := range msg.ToolCalls {
+		call := ossa.ToolCall{ID: tc.ID, Tool: tc.Function.Name}
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &call.Arguments); err != nil {
+				return nil, fmt.Errorf("tool call %s has invalid arguments: %w", tc.ID, err)
+			}
+		}
+		res.ToolCalls = append(res.ToolCalls, call)
+	}
+	return res, nil
+}
+
+// post sends req to the chat completions endpoint, returning the response
+// once its status is 200.
+func (o *OpenAI) post(ctx context.Context, req *Request, stream bool) (*http.Response, error) {
+	body := map[string]interface{}{}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	if llm := req.LLM; llm != nil {
+		body["model"] = llm.Model
+		if o.ModelName != nil {
+			body["model"] = o.ModelName(llm.Model)
+		}
+		if llm.Temperature != 0 {
+			body["temperature"] = llm.Temperature
+		}
+		if llm.MaxTokens != 0 {
+			body["max_tokens"] = llm.MaxTokens
+		}
+		if llm.TopP != 0 {
+			body["top_p"] = llm.TopP
+		}
+		if llm.FrequencyPenalty != 0 {
+			body["frequency_penalty"] = llm.FrequencyPenalty
+		}
+		if llm.PresencePenalty != 0 {
+			body["presence_penalty"] = llm.PresencePenalty
+		}
+		if len(llm.Stop) > 0 {
+			body["stop"] = llm.Stop
+		}
+		if llm.Seed != nil {
+			body["seed"] = *llm.Seed
+		}
+		if f := llm.ResponseFormat; f != nil {
+			body["response_format"] = openAIResponseFormat(f)
+		}
+	}
+	messages := make([]openAIMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.Attachments) > 0 {
+			parts, err := openAIParts(m)
+			if err != nil {
+				return nil, err
+			}
+			messages[i].Parts = parts
+		}
+		for _, c := range m.ToolCalls {
+			tc := openAIToolCall{ID: c.ID, Type: "function"}
+			args, err := json.Marshal(c.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			tc.Function.Name, tc.Function.Arguments = c.Tool, string(args)
+			messages[i].ToolCalls = append(messages[i].ToolCalls, tc)
+		}
+	}
+	body["messages"] = messages
+	var tools []openAITool
+	for _, t := range req.Tools {
+		if t.Name == "" {
+			continue
+		}
+		params := t.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object"}
+		}
+		tools = append(tools, openAITool{Type: "function", Function: openAIFunction{Name: t.Name, Description: t.Description, Parameters: params}})
+	}
+	if len(tools) > 0 {
+		body["tools"] = tools
+	}
+
+	return o.send(ctx, "/chat/completions", "chat completions", body)
+}
+
+// send posts body to path under the base URL, returning the response once
+// its status is 200; op names the call in errors.
+func (o *OpenAI) send(ctx context.Context, path, op string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	base := o.BaseURL
+	if base == "" {
+		base = DefaultOpenAIURL
+	}
+	endpoint := strings.TrimSuffix(base, "/") + path
+	if o.query != "" {
+		endpoint += "?" + o.query
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	for k, v := range o.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError(op, resp)
+	}
+	return resp, nil
+}
+
+// Embed calls the embeddings endpoint.
+func (o *OpenAI) Embed(ctx context.Context, req *EmbedRequest) ([][]float64, error) {
+	cfg := req.Embeddings
+	body := map[string]interface{}{"model": cfg.Model, "input": req.Texts}
+	if o.ModelName != nil {
+		body["model"] = o.ModelName(cfg.Model)
+	}
+	if cfg.Dimensions > 0 {
+		body["dimensions"] = cfg.Dimensions
+	}
+	resp, err := o.send(ctx, "/embeddings", "embeddings", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings response: %w", err)
+	}
+	vectors := make([][]float64, len(out.Data))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embeddings returned index %d of %d", d.Index, len(vectors))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return checkVectors("embeddings", vectors, len(req.Texts))
+}
+
+// openAIResponseFormat is the response_format of f.
+func openAIResponseFormat(f *ossa.ResponseFormat) map[string]interface{} {
+	if f.Type != ossa.ResponseFormatJSONSchema {
+		return map[string]interface{}{"type": f.Type}
+	}
+	name := f.Name
+	if name == "" {
+		name = "response"
+	}
+	schema := map[string]interface{}{"name": name, "schema": f.Schema}
+	if f.Strict {
+		schema["strict"] = true
+	}
+	return map[string]interface{}{"type": f.Type, "json_schema": schema}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// ToolPolicies enforces the call settings of tools' handlers around their
+// calls: timeout, retries and circuit_breaker, through an ossa.CallPolicy
+// per tool, and cache. Breakers are shared by every engine whose tools one
+// ToolPolicies wraps, so share it between the engines of a process; cached
+// results only within a run's Engine.Session and WithCacheScope scope. The zero value caches nothing and is ready to use;
+// a ToolPolicies is safe for concurrent use.
+type ToolPolicies struct {
+	// Cache keeps the results of tools with handler.cache; nil caches
+	// none.
+	Cache *ossa.ToolCache
+	// Session scopes cached results, which are also kept apart per agent,
+	// per Engine.Session of the run and per WithCacheScope; "" adds no
+	// scope of its own.
+	Session string
+
+	mu       sync.Mutex
+	policies map[string]*ossa.CallPolicy
+}
+
+// Tools wraps tools so each call is answered from the cache if it can be,
+// and otherwise goes through its tool's policy.
+func (p *ToolPolicies) Tools(tools ToolsFunc) ToolsFunc {
+	return func(m *ossa.Manifest) ossa.ToolExecFunc {
+		exec := tools(m)
+		return func(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+			tool := ossa.ToolConfig{Name: call.Tool}
+			for _, t := range m.Spec.Tools {
+				if t.Name == call.Tool {
+					tool = t
+				}
+			}
+			run := func(ctx context.Context) ([]byte, error) {
+				var out []byte
+				err := p.policy(m, tool).Do(ctx, func(ctx context.Context) error {
+					var err error
+					out, err = exec(ctx, call)
+					return err
+				})
+				return out, err
+			}
+			if p.Cache == nil {
+				return run(ctx)
+			}
+			// Agents' tools of the same name need not answer alike.
+			session, _ := ctx.Value(sessionKey{}).(string)
+			outer, _ := ctx.Value(cacheScopeKey{}).(string)
+			scope := strings.Join([]string{p.Session, session, outer, m.Metadata.Namespace + "/" + m.Metadata.Name}, "\x00")
+			return p.Cache.Do(ctx, scope, tool, call.Arguments, run)
+		}
+	}
+}
+
+// policy returns the policy of m's tool, keyed by its agent and handler
+// settings so an edited manifest gets a new one.
+func (p *ToolPolicies) policy(m *ossa.Manifest, tool ossa.ToolConfig) *ossa.CallPolicy {
+	handler := tool.Handler
+	key := string(m.Kind) + "/" + m.Metadata.Namespace + "/" + m.Metadata.Name + "/" + tool.Name
+	if handler != nil {
+		settings, _ := json.Marshal([]interface{}{handler.Timeout, handler.Retries, handler.CircuitBreaker})
+		key += "/" + string(settings)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.policies == nil {
+		p.policies = map[string]*ossa.CallPolicy{}
+	}
+	policy, ok := p.policies[key]
+	if !ok {
+		policy = ossa.NewCallPolicy(handler)
+		p.policies[key] = policy
+	}
+	return policy
+}
+
+type (
+	sessionKey    struct{}
+	cacheScopeKey struct{}
+)
+
+// WithCacheScope returns ctx, keeping the results ToolPolicies caches for
+// the runs of ctx apart from those of other scopes, such as the tenants
+// and callers of a server. Scopes nest.
+func WithCacheScope(ctx context.Context, scope string) context.Context {
+	if outer, _ := ctx.Value(cacheScopeKey{}).(string); outer != "" {
+		scope = outer + "\x00" + scope
+	}
+	return context.WithValue(ctx, cacheScopeKey{}, scope)
+}
+
+// withSession returns ctx carrying e.Session for ToolPolicies to scope
+// cached results by.
+func (e *Engine) withSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, e.Session)
+}
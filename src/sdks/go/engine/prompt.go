@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Prompt budgeting defaults; see ossa.ContextConfig.
+const (
+	// DefaultReserveTokens are kept free for the answer when neither
+	// spec.llm.context.reserve_tokens nor spec.llm.maxTokens is set.
+	DefaultReserveTokens = 4096
+	// DefaultMemoryField is the input field holding conversation memory.
+	DefaultMemoryField = "history"
+	// AttachmentTokens is what an image or audio attachment is counted as.
+	AttachmentTokens = 1024
+)
+
+// messageOverhead is counted for each message's role and framing.
+const messageOverhead = 4
+
+// EstimateTokens approximates the tokens of text at four bytes a token,
+// about the rate of English under the providers' tokenizers.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// prompt is a request split into the parts spec.llm.context budgets.
+type prompt struct {
+	count func(string) int
+	// input is the user input without its memory.
+	input  map[string]interface{}
+	field  string
+	memory []json.RawMessage
+	// turns are the run's assistant messages, each with the tool results
+	// answering it.
+	turns [][]Message
+}
+
+// fitPrompt returns req fitted to the context window of its model by
+// truncating the memory in input and the run's turns, or an error when
+// even that does not make it fit. req itself is not changed, so the run
+// keeps its whole conversation.
+func (e *Engine) fitPrompt(req *Request, name string, input map[string]interface{}) (*Request, error) {
+	llm := req.LLM
+	if llm == nil || len(req.Messages) < 2 {
+		return req, nil
+	}
+	cfg := llm.Context
+	if cfg == nil {
+		cfg = &ossa.ContextConfig{}
+	}
+	window := contextWindow(llm)
+	if window == 0 {
+		return req, nil
+	}
+	reserve := cfg.ReserveTokens
+	if reserve == 0 {
+		reserve = llm.MaxTokens
+	}
+	if reserve == 0 {
+		reserve = DefaultReserveTokens
+	}
+	budgets := ossa.ContextBudgets{}
+	if cfg.Budgets != nil {
+		budgets = *cfg.Budgets
+	}
+	p := &prompt{count: counter(e.CountTokens), input: input, field: memoryField(llm)}
+	if err := p.split(req); err != nil {
+		return nil, err
+	}
+
+	system := p.message(req.Messages[0])
+	if budgets.System > 0 && system > budgets.System {
+		return nil, fmt.Errorf("system prompt of %d tokens exceeds its budget of %d", system, budgets.System)
+	}
+	tools := p.tools(req.Tools)
+	if budgets.Tools > 0 && tools > budgets.Tools {
+		return nil, fmt.Errorf("tool schemas of %d tokens exceed their budget of %d", tools, budgets.Tools)
+	}
+	available := window - reserve
+	fixed := system + tools + p.message(Message{Content: p.text(p.input)})
+
+	// Memory goes first, as it is older than anything the run did.
+	memory, history := p.memoryTokens(), p.historyTokens()
+	droppedMemory, droppedTurns := 0, 0
+	for len(p.memory) > 0 && ((budgets.Memory > 0 && memory > budgets.Memory) || fixed+memory+history > available) {
+		i := dropAt(len(p.memory), cfg.Truncation)
+		memory -= p.entry(p.memory[i])
+		p.memory = append(p.memory[:i:i], p.memory[i+1:]...)
+		droppedMemory++
+	}
+	// The last turn is kept: this turn answers its tool results.
+	for len(p.turns) > 1 && ((budgets.History > 0 && history > budgets.History) || fixed+memory+history > available) {
+		i := dropAt(len(p.turns), cfg.Truncation)
+		history -= p.turn(p.turns[i])
+		p.turns = append(p.turns[:i:i], p.turns[i+1:]...)
+		droppedTurns++
+	}
+	total := fixed + memory + history
+	switch {
+	case (droppedMemory > 0 || droppedTurns > 0) && cfg.Truncation == ossa.TruncateError:
+		return nil, fmt.Errorf("prompt of %d tokens needs truncating to fit its budgets, which spec.llm.context.truncation forbids", p.size(req))
+	case total > available:
+		return nil, fmt.Errorf("prompt of %d tokens does not fit the %d the context window of %s leaves, even truncated", total, available, llm.Model)
+	case droppedMemory == 0 && droppedTurns == 0:
+		return req, nil
+	}
+	ossa.Logger().Warn("truncated prompt", "agent", name, "model", llm.Model, "dropped_memory", droppedMemory, "dropped_turns", droppedTurns, "tokens", total, "available", available)
+	return p.request(req), nil
+}
+
+// contextWindow is spec.llm.context.window, else the smallest known
+// window of the model and its fallbacks, which may take the call; 0 if
+// none is known.
+func contextWindow(llm *ossa.LLMConfig) int {
+	if llm.Context != nil && llm.Context.Window > 0 {
+		return llm.Context.Window
+	}
+	window := 0
+	models := []string{llm.Model}
+	for _, f := range llm.Fallbacks {
+		models = append(models, f.Model)
+	}
+	for _, f := range llm.FallbackModels {
+		models = append(models, f.Model)
+	}
+	for _, model := range models {
+		if w, ok := ossa.ContextWindow(model); ok && (window == 0 || w < window) {
+			window = w
+		}
+	}
+	return window
+}
+
+// split takes the memory out of the input and groups the turns after the
+// system and user messages.
+func (p *prompt) split(req *Request) error {
+	if raw, ok := p.input[p.field]; ok {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", p.field, err)
+		}
+		if json.Unmarshal(data, &p.memory) == nil {
+			rest := make(map[string]interface{}, len(p.input))
+			for k, v := range p.input {
+				if k != p.field {
+					rest[k] = v
+				}
+			}
+			p.input = rest
+		}
+	}
+	for _, m := range req.Messages[2:] {
+		if n := len(p.turns); m.Role == RoleTool && n > 0 {
+			p.turns[n-1] = append(p.turns[n-1], m)
+			continue
+		}
+		p.turns = append(p.turns, []Message{m})
+	}
+	return nil
+}
+
+// request is req with the prompt's memory and turns.
+func (p *prompt) request(req *Request) *Request {
+	out := *req
+	user := req.Messages[1]
+	input := make(map[string]interface{}, len(p.input)+1)
+	for k, v := range p.input {
+		input[k] = v
+	}
+	if len(p.memory) > 0 {
+		input[p.field] = p.memory
+	}
+	user.Content = p.text(input)
+	out.Messages = []Message{req.Messages[0], user}
+	for _, turn := range p.turns {
+		out.Messages = append(out.Messages, turn...)
+	}
+	return &out
+}
+
+func (p *prompt) text(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func (p *prompt) message(m Message) int {
+	n := messageOverhead + p.count(m.Content) + len(m.Attachments)*AttachmentTokens
+	for _, c := range m.ToolCalls {
+		n += p.count(c.Tool) + p.count(p.text(c.Arguments))
+	}
+	return n
+}
+
+func (p *prompt) tools(tools []ossa.ToolConfig) int {
+	n := 0
+	for _, t := range tools {
+		if t.Name != "" {
+			n += p.count(p.text(map[string]interface{}{"name": t.Name, "description": t.Description, "parameters": t.Parameters}))
+		}
+	}
+	return n
+}
+
+func (p *prompt) memoryTokens() int {
+	n := 0
+	for _, entry := range p.memory {
+		n += p.entry(entry)
+	}
+	return n
+}
+
+func (p *prompt) entry(entry json.RawMessage) int {
+	return entryTokens(p.count, entry)
+}
+
+func (p *prompt) historyTokens() int {
+	n := 0
+	for _, turn := range p.turns {
+		n += p.turn(turn)
+	}
+	return n
+}
+
+func (p *prompt) turn(turn []Message) int {
+	n := 0
+	for _, m := range turn {
+		n += p.message(m)
+	}
+	return n
+}
+
+// size is the tokens of req as it stands.
+func (p *prompt) size(req *Request) int {
+	n := p.tools(req.Tools)
+	for _, m := range req.Messages {
+		n += p.message(m)
+	}
+	return n
+}
+
+// dropAt is the index of the entry of n to drop by strategy: the first,
+// or for TruncateMiddle the second, keeping the first while others
+// remain.
+func dropAt(n int, strategy string) int {
+	if strategy == ossa.TruncateMiddle && n > 2 {
+		return 1
+	}
+	return 0
+}
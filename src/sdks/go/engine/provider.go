@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Gateway base URLs.
+const (
+	// DefaultLiteLLMURL is where a LiteLLM proxy listens by default.
+	DefaultLiteLLMURL = "http://localhost:4000"
+	// DefaultOpenRouterURL is the OpenRouter API base URL.
+	DefaultOpenRouterURL = "https://openrouter.ai/api/v1"
+)
+
+// NewModel returns the Model for an agent's spec.llm.provider:
+//
+//	openai      OPENAI_API_KEY and, for compatible servers, OPENAI_BASE_URL
+//	litellm     a LiteLLM proxy at LITELLM_BASE_URL, with LITELLM_API_KEY
+//	openrouter  OpenRouter with OPENROUTER_API_KEY, crediting
+//	            OPENROUTER_SITE_URL and OPENROUTER_SITE_NAME if set
+//	ollama      an Ollama server at OLLAMA_HOST, localhost:11434 by default
+//	google      Gemini with GEMINI_API_KEY or GOOGLE_API_KEY, or on Vertex AI
+//	            with Application Default Credentials when spec.llm.vertex
+//	            is set or GOOGLE_GENAI_USE_VERTEXAI is true, in
+//	            GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_LOCATION by default
+//	azure       the Azure OpenAI deployment of spec.llm.azure
+//	bedrock     AWS Bedrock in spec.llm.bedrock.region, signing with
+//	            AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+//	            AWS_SESSION_TOKEN, or with AWS_BEARER_TOKEN_BEDROCK
+//
+// LiteLLM gets spec.llm.model as written, a model_list alias or a
+// provider/model name. OpenRouter wants vendor/model names: a bare known
+// model name such as gpt-4o or claude-sonnet-4 gets its vendor prefixed.
+func NewModel(llm *ossa.LLMConfig) (Model, error) {
+	if llm == nil {
+		return nil, fmt.Errorf("agent has no spec.llm")
+	}
+	switch llm.Provider {
+	case "openai":
+		return &OpenAI{BaseURL: os.Getenv("OPENAI_BASE_URL"), APIKey: os.Getenv("OPENAI_API_KEY")}, nil
+	case "litellm":
+		return &OpenAI{BaseURL: envOr("LITELLM_BASE_URL", DefaultLiteLLMURL), APIKey: os.Getenv("LITELLM_API_KEY")}, nil
+	case "openrouter":
+		key := os.Getenv("OPENROUTER_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("the openrouter provider needs OPENROUTER_API_KEY")
+		}
+		headers := map[string]string{}
+		if site := os.Getenv("OPENROUTER_SITE_URL"); site != "" {
+			headers["HTTP-Referer"] = site
+		}
+		if name := os.Getenv("OPENROUTER_SITE_NAME"); name != "" {
+			headers["X-Title"] = name
+		}
+		return &OpenAI{BaseURL: envOr("OPENROUTER_BASE_URL", DefaultOpenRouterURL), APIKey: key, Headers: headers, ModelName: OpenRouterModel}, nil
+	case "ollama":
+		return &Ollama{}, nil
+	case "google":
+		g := &Gemini{
+			APIKey:   envOr("GEMINI_API_KEY", os.Getenv("GOOGLE_API_KEY")),
+			Vertex:   parseBool(os.Getenv("GOOGLE_GENAI_USE_VERTEXAI")),
+			Project:  os.Getenv("GOOGLE_CLOUD_PROJECT"),
+			Location: os.Getenv("GOOGLE_CLOUD_LOCATION"),
+		}
+		if g.APIKey == "" && !g.Vertex && llm.Vertex == nil {
+			return nil, fmt.Errorf("the google provider needs GEMINI_API_KEY or GOOGLE_API_KEY, or Vertex AI")
+		}
+		return g, nil
+	case "azure":
+		return &AzureOpenAI{}, nil
+	case "bedrock":
+		b := &Bedrock{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			BearerToken:     os.Getenv("AWS_BEARER_TOKEN_BEDROCK"),
+		}
+		if b.BearerToken == "" && (b.AccessKeyID == "" || b.SecretAccessKey == "") {
+			return nil, fmt.Errorf("the bedrock provider needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or AWS_BEARER_TOKEN_BEDROCK")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported llm provider %q", llm.Provider)
+}
+
+// openRouterVendors maps model name prefixes to their OpenRouter vendor.
+var openRouterVendors = []struct{ prefix, vendor string }{
+	{"gpt-", "openai"},
+	{"o1", "openai"},
+	{"o3", "openai"},
+	{"o4", "openai"},
+	{"claude-", "anthropic"},
+	{"gemini-", "google"},
+	{"gemma-", "google"},
+	{"mistral-", "mistralai"},
+	{"mixtral-", "mistralai"},
+	{"codestral-", "mistralai"},
+	{"llama-", "meta-llama"},
+	{"deepseek-", "deepseek"},
+	{"command-", "cohere"},
+	{"qwen", "qwen"},
+	{"grok-", "x-ai"},
+}
+
+// OpenRouterModel returns model as OpenRouter names it: vendor/model names
+// as they are, and a bare name with the vendor its prefix implies.
+func OpenRouterModel(model string) string {
+	if strings.Contains(model, "/") {
+		return model
+	}
+	for _, v := range openRouterVendors {
+		if strings.HasPrefix(model, v.prefix) {
+			return v.vendor + "/" + model
+		}
+	}
+	return model
+}
+
+// expandEnv replaces ${VAR} and ${VAR:-default} in s with the variable's
+// value, or the default when it is unset or empty.
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		name, fallback, _ := strings.Cut(name, ":-")
+		return envOr(name, fallback)
+	})
+}
+
+func parseBool(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Providers is a Model sending each request to the adapter for its
+// spec.llm.provider, so the agents of one workflow may use different
+// providers. Adapters are created with NewModel on first use. A request
+// whose model fails goes on to its spec.llm.fallbacks, in the order of
+// spec.llm.routing; see route.
+type Providers struct {
+	mu      sync.Mutex
+	models  map[string]Model
+	latency map[string]time.Duration
+}
+
+func (p *Providers) Complete(ctx context.Context, req *Request) (*Response, error) {
+	return p.route(ctx, req, func(ctx context.Context, model Model, req *Request) (*Response, error) {
+		return model.Complete(ctx, req)
+	})
+}
+
+// Stream streams req when its provider's adapter is a StreamingModel and
+// completes it in one piece otherwise. Once text has been sent to onText,
+// a failure no longer falls back.
+func (p *Providers) Stream(ctx context.Context, req *Request, onText func(string)) (*Response, error) {
+	sent := false
+	return p.route(ctx, req, func(ctx context.Context, model Model, req *Request) (*Response, error) {
+		var resp *Response
+		var err error
+		if s, ok := model.(StreamingModel); ok {
+			resp, err = s.Stream(ctx, req, func(text string) {
+				sent = true
+				onText(text)
+			})
+		} else if resp, err = model.Complete(ctx, req); err == nil && resp.Content != "" {
+			onText(resp.Content)
+		}
+		if err != nil && sent {
+			return nil, &streamedError{err}
+		}
+		return resp, err
+	})
+}
+
+func (p *Providers) model(llm *ossa.LLMConfig) (Model, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	model, ok := p.models[llm.Provider]
+	if !ok {
+		var err error
+		if model, err = NewModel(llm); err != nil {
+			return nil, err
+		}
+		if p.models == nil {
+			p.models = map[string]Model{}
+		}
+		p.models[llm.Provider] = model
+	}
+	return model, nil
+}
+
+// StatusError is the HTTP error status a provider answered a call with.
+type StatusError struct {
+	Op     string
+	Status string
+	Code   int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Op, e.Status, e.Body)
+}
+
+func statusError(op string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{Op: op, Status: resp.Status, Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+}
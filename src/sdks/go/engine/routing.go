@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// failurePenalty is the latency recorded for a failed call with no
+// timeout, so latency-optimized routing moves away from failing models.
+const failurePenalty = 30 * time.Second
+
+// candidate is a model a request may be sent to: spec.llm, or one of its
+// fallbacks with the settings it leaves out taken from spec.llm.
+type candidate struct {
+	llm     *ossa.LLMConfig
+	trigger *ossa.FallbackTrigger
+}
+
+// streamedError is a failure after text was sent, which no other model can
+// take over.
+type streamedError struct{ err error }
+
+func (e *streamedError) Error() string { return e.err.Error() }
+func (e *streamedError) Unwrap() error { return e.err }
+
+// route calls req's model, then, while calls fail, the fallbacks whose
+// trigger the failure matches, in the order of spec.llm.routing. Each call
+// is retried as spec.llm.retry_config, or the next fallback's max_retries,
+// says, within routing.timeout_ms or the next fallback's
+// latency_threshold_ms. The response names the provider and model that
+// served it.
+func (p *Providers) route(ctx context.Context, req *Request, call func(context.Context, Model, *Request) (*Response, error)) (*Response, error) {
+	if req.LLM == nil {
+		return nil, fmt.Errorf("agent has no spec.llm")
+	}
+	list := p.order(req.LLM)
+	var lastErr error
+	for i, c := range list {
+		if i > 0 && !c.matches(lastErr) {
+			continue
+		}
+		if i > 0 {
+			ossa.Logger().Warn("falling back", "agent", req.Agent, "provider", c.llm.Provider, "model", c.llm.Model, "error", lastErr)
+		}
+		var next *ossa.FallbackTrigger
+		if i+1 < len(list) {
+			next = list[i+1].trigger
+		}
+		model, err := p.model(c.llm)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		r := *req
+		r.LLM = c.llm
+		start := time.Now()
+		var resp *Response
+		timeout := callTimeout(req.LLM, next)
+		attempt := func(ctx context.Context) error {
+			var err error
+			resp, err = call(ctx, model, &r)
+			return err
+		}
+		if policy := callPolicy(c.llm, timeout, next); policy != nil {
+			err = policy.Do(ctx, func(ctx context.Context) error {
+				err := attempt(ctx)
+				if err != nil && !retryable(err) {
+					return ossa.Permanent(err)
+				}
+				return err
+			})
+		} else if timeout > 0 {
+			attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+			err = attempt(attemptCtx)
+			cancel()
+		} else {
+			err = attempt(ctx)
+		}
+		if err == nil {
+			p.observe(c.llm, time.Since(start))
+			resp.Provider, resp.Model = c.llm.Provider, c.llm.Model
+			return resp, nil
+		}
+		if timeout <= 0 {
+			timeout = failurePenalty
+		}
+		p.observe(c.llm, timeout)
+		var streamed *streamedError
+		if errors.As(err, &streamed) {
+			return nil, streamed.err
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// order is the candidates for llm in the order its routing policy tries
+// them.
+func (p *Providers) order(llm *ossa.LLMConfig) []candidate {
+	list := []candidate{{llm: llm}}
+	for _, f := range append(append([]ossa.FallbackLLM(nil), llm.Fallbacks...), llm.FallbackModels...) {
+		c := *llm
+		c.Provider, c.Model, c.Azure, c.Bedrock, c.Vertex, c.Pricing = f.Provider, f.Model, f.Azure, f.Bedrock, f.Vertex, f.Pricing
+		if f.Temperature != 0 {
+			c.Temperature = f.Temperature
+		}
+		if f.MaxTokens != 0 {
+			c.MaxTokens = f.MaxTokens
+		}
+		c.Fallbacks, c.FallbackModels, c.Routing = nil, nil, nil
+		list = append(list, candidate{llm: &c, trigger: f.Trigger})
+	}
+	if llm.Routing == nil {
+		return list
+	}
+	switch llm.Routing.Policy {
+	case ossa.RoutingCostOptimized:
+		// Models without pricing go last.
+		sort.SliceStable(list, func(i, j int) bool {
+			a, b := list[i].llm.Pricing, list[j].llm.Pricing
+			if a == nil || b == nil {
+				return a != nil
+			}
+			return a.InputPerMillion+a.OutputPerMillion < b.InputPerMillion+b.OutputPerMillion
+		})
+	case ossa.RoutingLatencyOptimized:
+		// Models not yet called go first, to learn their latency.
+		p.mu.Lock()
+		latency := make([]time.Duration, len(list))
+		for i, c := range list {
+			latency[i] = p.latency[latencyKey(c.llm)]
+		}
+		p.mu.Unlock()
+		idx := make([]int, len(list))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(i, j int) bool { return latency[idx[i]] < latency[idx[j]] })
+		sorted := make([]candidate, len(list))
+		for i, k := range idx {
+			sorted[i] = list[k]
+		}
+		list = sorted
+	}
+	return list
+}
+
+// observe adds the time a call to llm took to its moving average.
+func (p *Providers) observe(llm *ossa.LLMConfig, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.latency == nil {
+		p.latency = map[string]time.Duration{}
+	}
+	key := latencyKey(llm)
+	if prev, ok := p.latency[key]; ok {
+		d = (7*prev + 3*d) / 10
+	}
+	p.latency[key] = d
+}
+
+func latencyKey(llm *ossa.LLMConfig) string {
+	return llm.Provider + "/" + llm.Model
+}
+
+// matches reports whether err, of the call tried before, hands over to the
+// fallback c.
+func (c candidate) matches(err error) bool {
+	t := c.trigger
+	if t == nil || isTimeout(err) {
+		return true
+	}
+	if len(t.ErrorCodes) > 0 {
+		var status *StatusError
+		if !errors.As(err, &status) {
+			return false
+		}
+		for _, code := range t.ErrorCodes {
+			if code == status.Code {
+				return true
+			}
+		}
+		return false
+	}
+	return t.OnError == nil || *t.OnError
+}
+
+// callTimeout bounds a call: the latency threshold of the fallback after
+// it, else routing.timeout_ms.
+func callTimeout(llm *ossa.LLMConfig, next *ossa.FallbackTrigger) time.Duration {
+	if next != nil && next.LatencyThresholdMs > 0 {
+		return time.Duration(next.LatencyThresholdMs) * time.Millisecond
+	}
+	if llm.Routing != nil {
+		return time.Duration(llm.Routing.TimeoutMs) * time.Millisecond
+	}
+	return 0
+}
+
+// callPolicy retries a call to llm max_retries times when the fallback
+// after it sets them, else as spec.llm.retry_config says. It is nil when
+// neither asks for retries.
+func callPolicy(llm *ossa.LLMConfig, timeout time.Duration, next *ossa.FallbackTrigger) *ossa.CallPolicy {
+	retries := llm.RetryConfig
+	if next != nil && next.MaxRetries > 0 {
+		r := ossa.RetryConfig{}
+		if retries != nil {
+			r = *retries
+		}
+		r.MaxAttempts = next.MaxRetries + 1
+		retries = &r
+	}
+	if retries == nil {
+		return nil
+	}
+	return ossa.NewRetryPolicy(timeout, retries)
+}
+
+// retryable reports whether err may pass on a second try: a timeout, a
+// network error, or a status saying so.
+func retryable(err error) bool {
+	var streamed *streamedError
+	if errors.As(err, &streamed) {
+		return false
+	}
+	if isTimeout(err) {
+		return true
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests || status.Code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
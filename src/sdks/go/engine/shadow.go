@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// ShadowReport compares a candidate version of an agent, run in shadow on
+// the same input as the current version, with the current run. The
+// candidate's output never reaches later steps and its failure does not
+// fail the workflow.
+type ShadowReport struct {
+	Step             string `json:"step"`
+	Agent            string `json:"agent"`
+	CurrentVersion   string `json:"current_version,omitempty"`
+	CandidateVersion string `json:"candidate_version,omitempty"`
+	// Match reports whether the outputs and the sequence of tools called
+	// agree.
+	Match bool `json:"match"`
+	// Differences lists the output fields that differ, as
+	// ossa.FieldChange strings.
+	Differences    []string `json:"differences,omitempty"`
+	CurrentTools   []string `json:"current_tools,omitempty"`
+	CandidateTools []string `json:"candidate_tools,omitempty"`
+	// Stubbed counts the candidate's tool calls that the current run did
+	// not make, answered without side effects by StubTools.
+	Stubbed        int     `json:"stubbed,omitempty"`
+	CurrentUsage   Usage   `json:"current_usage"`
+	CandidateUsage Usage   `json:"candidate_usage"`
+	Candidate      *Result `json:"candidate,omitempty"`
+	// Error is why the candidate failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// StubOutput answers a candidate tool call the current run did not make.
+const StubOutput = `{"stubbed": true}`
+
+// StubTools returns an executor for a shadow candidate that never reaches a
+// real tool: a call the current run also made, with the same arguments,
+// gets the recorded result, and any other call gets StubOutput.
+func StubTools(_ *ossa.Manifest, current *Result) ossa.ToolExecFunc {
+	return func(_ context.Context, call ossa.ToolCall) ([]byte, error) {
+		for _, rec := range current.ToolCalls {
+			if rec.Tool == call.Tool && sameArguments(rec.Arguments, call.Arguments) {
+				if rec.Error != "" {
+					return nil, &recordedError{rec.Error}
+				}
+				return []byte(rec.Output), nil
+			}
+		}
+		return []byte(StubOutput), nil
+	}
+}
+
+type recordedError struct{ msg string }
+
+func (e *recordedError) Error() string { return e.msg }
+
+// sameArguments compares arguments by their JSON encoding, so numbers
+// decoded differently still match.
+func sameArguments(a, b map[string]interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
+
+// shadow runs candidate on the input the current version got and compares
+// the two.
+func (e *Engine) shadow(ctx context.Context, step string, candidate *ossa.Manifest, input map[string]interface{}, current *Result) *ShadowReport {
+	report := &ShadowReport{
+		Step:             step,
+		Agent:            current.Agent,
+		CurrentVersion:   current.Version,
+		CandidateVersion: candidate.Metadata.Version,
+		CurrentTools:     toolNames(current),
+		CurrentUsage:     current.Usage,
+	}
+	tools := e.ShadowTools
+	if tools == nil {
+		tools = StubTools
+	}
+	res, err := e.runAgent(ctx, candidate, input, tools(candidate, current), nil)
+	report.Candidate = res
+	if res != nil {
+		report.CandidateTools = toolNames(res)
+		report.CandidateUsage = res.Usage
+		for _, rec := range res.ToolCalls {
+			if rec.Output == StubOutput {
+				report.Stubbed++
+			}
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	for _, c := range ossa.DiffValues(jsonValue(current.Output), jsonValue(res.Output)) {
+		report.Differences = append(report.Differences, c.String())
+	}
+	report.Match = len(report.Differences) == 0 && reflect.DeepEqual(report.CurrentTools, report.CandidateTools)
+	ossa.Logger().Info("shadow run", "step", step, "agent", current.Agent,
+		"current", current.Version, "candidate", candidate.Metadata.Version, "match", report.Match)
+	return report
+}
+
+func toolNames(r *Result) []string {
+	var names []string
+	for _, rec := range r.ToolCalls {
+		names = append(names, rec.Tool)
+	}
+	return names
+}
+
+// jsonValue normalizes v to what decoding its JSON gives, so outputs built
+// differently compare equal when they encode the same.
+func jsonValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
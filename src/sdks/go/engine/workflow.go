@@ -0,0 +1,519 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/dop251/goja"
+	"gopkg.in/yaml.v3"
+)
+
+// Step kinds beside Agent and Task.
+const (
+	StepParallel    = "Parallel"
+	StepConditional = "Conditional"
+	StepLoop        = "Loop"
+)
+
+// Workflow is the runnable part of a kind: Workflow manifest. ossa.Manifest
+// does not model steps, so workflows are parsed from their source.
+type Workflow struct {
+	Name    string
+	Version string
+	Steps   []Step
+}
+
+// Step is one entry of spec.steps.
+type Step struct {
+	ID        string                 `yaml:"id"`
+	Name      string                 `yaml:"name,omitempty"`
+	Kind      string                 `yaml:"kind,omitempty"`
+	Ref       string                 `yaml:"ref,omitempty"`
+	Inline    map[string]interface{} `yaml:"inline,omitempty"`
+	Input     map[string]interface{} `yaml:"input,omitempty"`
+	Condition string                 `yaml:"condition,omitempty"`
+	Parallel  []Step                 `yaml:"parallel,omitempty"`
+	Branches  []Branch               `yaml:"branches,omitempty"`
+	Else      []Step                 `yaml:"else,omitempty"`
+	Loop      *Loop                  `yaml:"loop,omitempty"`
+	Steps     []Step                 `yaml:"steps,omitempty"`
+}
+
+// Branch is one case of a Conditional step.
+type Branch struct {
+	Condition string `yaml:"condition"`
+	Steps     []Step `yaml:"steps"`
+}
+
+// Loop configures a Loop step: its steps run once per item of Over, with
+// the item and its index bound to As and Index.
+type Loop struct {
+	Over  string `yaml:"over"`
+	As    string `yaml:"as,omitempty"`
+	Index string `yaml:"index,omitempty"`
+}
+
+// ParseWorkflow reads the steps of a kind: Workflow manifest.
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	var doc struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name    string `yaml:"name"`
+			Version string `yaml:"version"`
+		} `yaml:"metadata"`
+		Spec struct {
+			Steps []Step `yaml:"steps"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	if doc.Kind != string(ossa.KindWorkflow) {
+		return nil, fmt.Errorf("expected kind %s, got %q", ossa.KindWorkflow, doc.Kind)
+	}
+	if len(doc.Spec.Steps) == 0 {
+		return nil, fmt.Errorf("workflow %s has no steps", doc.Metadata.Name)
+	}
+	return &Workflow{Name: doc.Metadata.Name, Version: doc.Metadata.Version, Steps: doc.Spec.Steps}, nil
+}
+
+// StepResult is the outcome of one step. Steps inside Parallel, Conditional
+// and Loop steps have their own results, listed before their parent's.
+type StepResult struct {
+	Step string `json:"step"`
+	Kind string `json:"kind,omitempty"`
+	// Name is the manifest an Agent or Task step ran.
+	Name    string                 `json:"name,omitempty"`
+	Skipped bool                   `json:"skipped,omitempty"`
+	Input   map[string]interface{} `json:"input,omitempty"`
+	Output  map[string]interface{} `json:"output,omitempty"`
+	// Result is the agent run of an Agent step.
+	Result *Result `json:"result,omitempty"`
+	// Shadow compares a candidate version of the step's agent; see
+	// Engine.Shadows.
+	Shadow *ShadowReport `json:"shadow,omitempty"`
+}
+
+// WorkflowResult is the outcome of a workflow run.
+type WorkflowResult struct {
+	Workflow string `json:"workflow"`
+	// Output is that of the last step that ran.
+	Output map[string]interface{} `json:"output,omitempty"`
+	Steps  []StepResult           `json:"steps"`
+	// Usage totals the agent steps; shadow runs are not counted.
+	Usage Usage `json:"usage"`
+}
+
+// Shadows returns the shadow reports of the run's steps.
+func (r *WorkflowResult) Shadows() []ShadowReport {
+	var out []ShadowReport
+	for _, s := range r.Steps {
+		if s.Shadow != nil {
+			out = append(out, *s.Shadow)
+		}
+	}
+	return out
+}
+
+// Checkpoint is the state of a workflow run after a top-level step.
+type Checkpoint struct {
+	// Done lists the top-level steps that completed, in order.
+	Done []string `json:"done"`
+	// Steps are the results of the done steps and the steps within them.
+	Steps []StepResult `json:"steps,omitempty"`
+	// Output is that of the last done step that ran.
+	Output map[string]interface{} `json:"output,omitempty"`
+}
+
+// RunWorkflow runs w's steps on input. A failing step ends the run; the
+// result holds the steps that ran before it.
+func (e *Engine) RunWorkflow(ctx context.Context, w *Workflow, input map[string]interface{}) (*WorkflowResult, error) {
+	return e.ResumeWorkflow(ctx, w, input, nil)
+}
+
+// ResumeWorkflow runs w from cp, a checkpoint of an earlier run on the same
+// input: its done steps are not run again, and later steps see their
+// outputs. A nil cp runs w from the start.
+func (e *Engine) ResumeWorkflow(ctx context.Context, w *Workflow, input map[string]interface{}, cp *Checkpoint) (*WorkflowResult, error) {
+	// Steps share the limits of their agents' tool calls.
+	ctx = e.withSession(withToolLimits(ctx))
+	sc := &scope{input: input, steps: map[string]interface{}{}, mu: &sync.Mutex{}}
+	state := &Checkpoint{}
+	if cp != nil {
+		state.Done = append(state.Done, cp.Done...)
+		state.Steps = append(state.Steps, cp.Steps...)
+		state.Output = cp.Output
+		for _, r := range cp.Steps {
+			if !r.Skipped {
+				sc.set(r.Step, r.Output)
+			}
+		}
+	}
+	done := map[string]bool{}
+	for _, id := range state.Done {
+		done[id] = true
+	}
+
+	var err error
+	for _, st := range w.Steps {
+		if done[st.ID] {
+			continue
+		}
+		results, out, serr := e.runStep(ctx, st, sc)
+		state.Steps = append(state.Steps, results...)
+		if serr != nil {
+			err = serr
+			break
+		}
+		if out != nil {
+			state.Output = out
+		}
+		state.Done = append(state.Done, st.ID)
+		if e.Checkpoint != nil {
+			if err = e.Checkpoint(ctx, state); err != nil {
+				err = fmt.Errorf("checkpoint after step %s: %w", st.ID, err)
+				break
+			}
+		}
+	}
+	steps, out := state.Steps, state.Output
+	res := &WorkflowResult{Workflow: w.Name, Output: out, Steps: steps}
+	for _, s := range steps {
+		if s.Result != nil {
+			res.Usage.add(s.Result.Usage)
+		}
+	}
+	if err != nil {
+		return res, fmt.Errorf("workflow %s: %w", w.Name, err)
+	}
+	return res, nil
+}
+
+func (e *Engine) runSteps(ctx context.Context, steps []Step, sc *scope) ([]StepResult, map[string]interface{}, error) {
+	var all []StepResult
+	var last map[string]interface{}
+	for _, st := range steps {
+		results, out, err := e.runStep(ctx, st, sc)
+		all = append(all, results...)
+		if err != nil {
+			return all, last, err
+		}
+		if out != nil {
+			last = out
+		}
+	}
+	return all, last, nil
+}
+
+// runStep runs st and returns its results and output, nil if skipped.
+func (e *Engine) runStep(ctx context.Context, st Step, sc *scope) (results []StepResult, out map[string]interface{}, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if st.Condition != "" {
+		ok, err := sc.truthy(st.Condition)
+		if err != nil {
+			return nil, nil, fmt.Errorf("step %s: condition: %w", st.ID, err)
+		}
+		if !ok {
+			return []StepResult{{Step: st.ID, Kind: st.Kind, Skipped: true}}, nil, nil
+		}
+	}
+	if e.Events != nil {
+		e.Events(Event{Type: EventStepStarted, Step: st.ID})
+		defer func() {
+			ev := Event{Type: EventStepFinished, Step: st.ID, Output: out}
+			if err != nil {
+				ev.Error = err.Error()
+			}
+			e.Events(ev)
+		}()
+	}
+
+	switch st.Kind {
+	case StepParallel:
+		results, out, err = e.runParallel(ctx, st, sc)
+	case StepConditional:
+		results, out, err = e.runConditional(ctx, st, sc)
+	case StepLoop:
+		results, out, err = e.runLoop(ctx, st, sc)
+	default:
+		var r StepResult
+		r, err = e.runLeaf(ctx, st, sc)
+		results, out = []StepResult{r}, r.Output
+		if err == nil {
+			sc.set(st.ID, out)
+		}
+		return results, out, err
+	}
+	if err != nil {
+		return results, nil, err
+	}
+	sc.set(st.ID, out)
+	return append(results, StepResult{Step: st.ID, Kind: st.Kind, Output: out}), out, nil
+}
+
+// runLeaf runs an Agent or Task step.
+func (e *Engine) runLeaf(ctx context.Context, st Step, sc *scope) (StepResult, error) {
+	r := StepResult{Step: st.ID, Kind: st.Kind}
+	m, err := e.manifest(ctx, st)
+	if err != nil {
+		return r, fmt.Errorf("step %s: %w", st.ID, err)
+	}
+	expanded, err := sc.expand(st.Input)
+	if err != nil {
+		return r, fmt.Errorf("step %s: input: %w", st.ID, err)
+	}
+	r.Input, _ = expanded.(map[string]interface{})
+	r.Name = m.Metadata.Name
+	if r.Kind == "" {
+		r.Kind = string(m.Kind)
+	}
+
+	if m.Kind == ossa.KindTask {
+		if e.Task == nil {
+			return r, fmt.Errorf("step %s: no runner for Task %s", st.ID, m.Metadata.Name)
+		}
+		r.Output, err = e.Task(ctx, m, r.Input)
+		if err != nil {
+			return r, fmt.Errorf("step %s: %w", st.ID, err)
+		}
+		return r, nil
+	}
+
+	r.Result, err = e.RunAgent(ctx, m, r.Input)
+	if err != nil {
+		return r, fmt.Errorf("step %s: %w", st.ID, err)
+	}
+	r.Output = r.Result.Output
+	if candidate := e.Shadows[m.Metadata.Name]; candidate != nil {
+		r.Shadow = e.shadow(ctx, st.ID, candidate, r.Input, r.Result)
+	}
+	return r, nil
+}
+
+// manifest loads a step's ref or parses its inline manifest.
+func (e *Engine) manifest(ctx context.Context, st Step) (*ossa.Manifest, error) {
+	if st.Inline != nil {
+		data, err := yaml.Marshal(st.Inline)
+		if err != nil {
+			return nil, err
+		}
+		return ossa.ParseManifest(data, ".yaml")
+	}
+	if st.Ref == "" {
+		return nil, fmt.Errorf("no ref or inline manifest")
+	}
+	if e.Load == nil {
+		return nil, fmt.Errorf("cannot load %s: engine has no loader", st.Ref)
+	}
+	return e.Load(ctx, st.Ref)
+}
+
+// runParallel runs the parallel (or nested) steps concurrently. Its output
+// maps each step ID to that step's output.
+func (e *Engine) runParallel(ctx context.Context, st Step, sc *scope) ([]StepResult, map[string]interface{}, error) {
+	children := st.Parallel
+	if len(children) == 0 {
+		children = st.Steps
+	}
+	results := make([][]StepResult, len(children))
+	outs := make([]map[string]interface{}, len(children))
+	errs := make([]error, len(children))
+	var wg sync.WaitGroup
+	for i, child := range children {
+		wg.Add(1)
+		go func(i int, child Step) {
+			defer wg.Done()
+			results[i], outs[i], errs[i] = e.runStep(ctx, child, sc)
+		}(i, child)
+	}
+	wg.Wait()
+
+	var all []StepResult
+	out := map[string]interface{}{}
+	var failed error
+	for i, child := range children {
+		all = append(all, results[i]...)
+		if errs[i] != nil && failed == nil {
+			failed = errs[i]
+		}
+		if outs[i] != nil {
+			out[child.ID] = outs[i]
+		}
+	}
+	return all, out, failed
+}
+
+// runConditional runs the steps of the first branch whose condition holds,
+// or else the else steps. Its output is that of the last step run.
+func (e *Engine) runConditional(ctx context.Context, st Step, sc *scope) ([]StepResult, map[string]interface{}, error) {
+	steps := st.Else
+	for i, b := range st.Branches {
+		ok, err := sc.truthy(b.Condition)
+		if err != nil {
+			return nil, nil, fmt.Errorf("step %s: branch %d: %w", st.ID, i, err)
+		}
+		if ok {
+			steps = b.Steps
+			break
+		}
+	}
+	results, out, err := e.runSteps(ctx, steps, sc)
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return results, out, err
+}
+
+// runLoop runs the nested steps once per item, in order. Its output is
+// {"items": [...]}, the last nested output of each iteration.
+func (e *Engine) runLoop(ctx context.Context, st Step, sc *scope) ([]StepResult, map[string]interface{}, error) {
+	if st.Loop == nil || st.Loop.Over == "" {
+		return nil, nil, fmt.Errorf("step %s: Loop needs loop.over", st.ID)
+	}
+	v, err := sc.eval(st.Loop.Over)
+	if err != nil {
+		return nil, nil, fmt.Errorf("step %s: loop.over: %w", st.ID, err)
+	}
+	items, ok := v.([]interface{})
+	if !ok && v != nil {
+		return nil, nil, fmt.Errorf("step %s: loop.over is %T, not a list", st.ID, v)
+	}
+	as, index := st.Loop.As, st.Loop.Index
+	if as == "" {
+		as = "item"
+	}
+	if index == "" {
+		index = "index"
+	}
+	var all []StepResult
+	outs := []interface{}{}
+	for i, item := range items {
+		results, out, err := e.runSteps(ctx, st.Steps, sc.with(map[string]interface{}{as: item, index: i}))
+		all = append(all, results...)
+		if err != nil {
+			return all, nil, fmt.Errorf("step %s: item %d: %w", st.ID, i, err)
+		}
+		outs = append(outs, out)
+	}
+	return all, map[string]interface{}{"items": outs}, nil
+}
+
+// scope holds what ${{ }} expressions see: workflow.input, steps.<id>.output
+// and loop variables.
+type scope struct {
+	input map[string]interface{}
+	mu    *sync.Mutex
+	steps map[string]interface{}
+	vars  map[string]interface{}
+}
+
+func (sc *scope) set(id string, out map[string]interface{}) {
+	if id == "" {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.steps[id] = map[string]interface{}{"output": out}
+}
+
+// with returns a scope sharing step outputs with extra variables bound.
+func (sc *scope) with(vars map[string]interface{}) *scope {
+	c := *sc
+	c.vars = map[string]interface{}{}
+	for k, v := range sc.vars {
+		c.vars[k] = v
+	}
+	for k, v := range vars {
+		c.vars[k] = v
+	}
+	return &c
+}
+
+var (
+	exprPattern = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+	// stepIDPattern finds steps.<id> references whose id is not a valid
+	// JavaScript identifier, such as steps.security-scan.
+	stepIDPattern = regexp.MustCompile(`\bsteps\.([A-Za-z0-9_]*-[A-Za-z0-9_-]*)`)
+)
+
+// eval evaluates a JavaScript expression, with or without ${{ }}.
+func (sc *scope) eval(expr string) (interface{}, error) {
+	if m := exprPattern.FindStringSubmatch(strings.TrimSpace(expr)); m != nil && m[0] == strings.TrimSpace(expr) {
+		expr = m[1]
+	}
+	expr = stepIDPattern.ReplaceAllString(expr, `steps["$1"]`)
+
+	vm := goja.New()
+	sc.mu.Lock()
+	steps := make(map[string]interface{}, len(sc.steps))
+	for k, v := range sc.steps {
+		steps[k] = v
+	}
+	sc.mu.Unlock()
+	vm.Set("workflow", map[string]interface{}{"input": sc.input})
+	vm.Set("steps", steps)
+	for k, v := range sc.vars {
+		vm.Set(k, v)
+	}
+	v, err := vm.RunString("(" + expr + ")")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", expr, err)
+	}
+	return v.Export(), nil
+}
+
+func (sc *scope) truthy(expr string) (bool, error) {
+	v, err := sc.eval(expr)
+	if err != nil {
+		return false, err
+	}
+	vm := goja.New()
+	return vm.ToValue(v).ToBoolean(), nil
+}
+
+// expand replaces the expressions in an input mapping. A string that is one
+// expression takes its value's type; others are interpolated as text.
+func (sc *scope) expand(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			e, err := sc.expand(x)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = e
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, x := range v {
+			e, err := sc.expand(x)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	case string:
+		if m := exprPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			return sc.eval(m[1])
+		}
+		var failed error
+		s := exprPattern.ReplaceAllStringFunc(v, func(match string) string {
+			x, err := sc.eval(exprPattern.FindStringSubmatch(match)[1])
+			if err != nil && failed == nil {
+				failed = err
+			}
+			return fmt.Sprint(x)
+		})
+		return s, failed
+	}
+	return v, nil
+}
@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+)
+
+// Case outcomes of a comparison, for the candidate.
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+	OutcomeTie  = "tie"
+)
+
+// Significance is the p-value below which a comparison's verdict is
+// improved or regressed rather than unchanged.
+const Significance = 0.05
+
+// Verdicts of a comparison.
+const (
+	VerdictImproved  = "improved"
+	VerdictRegressed = "regressed"
+	VerdictUnchanged = "unchanged"
+)
+
+// Comparison is how a candidate agent scored on a suite against a base.
+type Comparison struct {
+	Suite     string      `json:"suite"`
+	Base      Side        `json:"base"`
+	Candidate Side        `json:"candidate"`
+	Cases     []CaseDelta `json:"cases"`
+	Wins      int         `json:"wins"`
+	Losses    int         `json:"losses"`
+	Ties      int         `json:"ties"`
+	// Delta is the mean of the cases' deltas, and StdErr its standard
+	// error.
+	Delta  float64 `json:"delta"`
+	StdErr float64 `json:"std_err"`
+	// PValue is the two-sided sign test's: how likely as lopsided a split
+	// of wins and losses is if the versions are equally good.
+	PValue  float64 `json:"p_value"`
+	Verdict string  `json:"verdict"`
+}
+
+// Side is one agent of a comparison.
+type Side struct {
+	Agent   string  `json:"agent"`
+	Version string  `json:"version,omitempty"`
+	Score   float64 `json:"score"`
+	Passed  bool    `json:"passed"`
+}
+
+// CaseDelta is how a case's score moved.
+type CaseDelta struct {
+	Name      string  `json:"name"`
+	Base      float64 `json:"base"`
+	Candidate float64 `json:"candidate"`
+	Delta     float64 `json:"delta"`
+	Outcome   string  `json:"outcome"`
+}
+
+// Compare compares the reports of a base and a candidate agent on the
+// same suite, case by case. Deltas within margin are ties, so judge noise
+// need not count as a win or loss.
+func Compare(base, candidate *Report, margin float64) (*Comparison, error) {
+	if base.Suite != candidate.Suite {
+		return nil, fmt.Errorf("cannot compare suite %s with %s", base.Suite, candidate.Suite)
+	}
+	if len(base.Cases) == 0 {
+		return nil, fmt.Errorf("reports of %s have no cases", base.Suite)
+	}
+	if err := uniqueCases(base); err != nil {
+		return nil, err
+	}
+	if err := uniqueCases(candidate); err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64, len(candidate.Cases))
+	for _, c := range candidate.Cases {
+		scores[c.Name] = c.Score
+	}
+	if len(scores) != len(base.Cases) {
+		return nil, fmt.Errorf("reports of %s have different cases", base.Suite)
+	}
+	cmp := &Comparison{
+		Suite:     base.Suite,
+		Base:      Side{Agent: base.Agent, Version: base.Version, Score: base.Score, Passed: base.Passed},
+		Candidate: Side{Agent: candidate.Agent, Version: candidate.Version, Score: candidate.Score, Passed: candidate.Passed},
+	}
+	for _, c := range base.Cases {
+		score, ok := scores[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("case %s of %s is not in both reports", c.Name, base.Suite)
+		}
+		d := CaseDelta{Name: c.Name, Base: c.Score, Candidate: score, Delta: score - c.Score, Outcome: OutcomeTie}
+		switch {
+		case d.Delta > margin:
+			d.Outcome = OutcomeWin
+			cmp.Wins++
+		case d.Delta < -margin:
+			d.Outcome = OutcomeLoss
+			cmp.Losses++
+		default:
+			cmp.Ties++
+		}
+		cmp.Cases = append(cmp.Cases, d)
+		cmp.Delta += d.Delta
+	}
+	n := float64(len(cmp.Cases))
+	cmp.Delta /= n
+	if n > 1 {
+		var ss float64
+		for _, d := range cmp.Cases {
+			ss += (d.Delta - cmp.Delta) * (d.Delta - cmp.Delta)
+		}
+		cmp.StdErr = math.Sqrt(ss/(n-1)) / math.Sqrt(n)
+	}
+	cmp.PValue = signTest(cmp.Wins, cmp.Losses)
+	cmp.Verdict = VerdictUnchanged
+	if cmp.PValue < Significance {
+		cmp.Verdict = VerdictImproved
+		if cmp.Losses > cmp.Wins {
+			cmp.Verdict = VerdictRegressed
+		}
+	}
+	return cmp, nil
+}
+
+// uniqueCases rejects a report naming a case twice, which would otherwise
+// be compared twice while another case is skipped.
+func uniqueCases(r *Report) error {
+	seen := make(map[string]bool, len(r.Cases))
+	for _, c := range r.Cases {
+		if seen[c.Name] {
+			return fmt.Errorf("case %s appears twice in the report of %s %s", c.Name, r.Agent, r.Version)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// signTest is the exact two-sided p-value of wins against losses under
+// even odds, ties left out.
+func signTest(wins, losses int) float64 {
+	n := wins + losses
+	k := wins
+	if losses < k {
+		k = losses
+	}
+	// P(X <= k) for X ~ Binomial(n, 1/2), summed in logs to stay finite.
+	var tail float64
+	for i := 0; i <= k; i++ {
+		tail += math.Exp(logChoose(n, i) - float64(n)*math.Ln2)
+	}
+	return math.Min(1, 2*tail)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
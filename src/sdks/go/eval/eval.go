@@ -0,0 +1,238 @@
+// Package eval scores agents against datasets. A suite, declared in an
+// *.ossaeval.yaml file, names an agent and lists cases: inputs with what
+// the output should be, checked by graders. Graders match fields exactly
+// or by regular expression, ask a model to judge the output against
+// criteria, or call Go functions registered with the Runner:
+//
+//	name: triage-regression
+//	agent: ../agents/triage.ossa.yaml
+//	threshold: 0.9
+//	judge: {model: gpt-4o-mini}
+//	graders:
+//	  - {type: exact, field: priority}
+//	cases:
+//	  - name: outage
+//	    input: {ticket: "The whole site is down"}
+//	    expected: {priority: urgent}
+//	    graders:
+//	      - {type: judge, criteria: "Names the affected service"}
+//
+// Each grader scores a case between 0 and 1 and passes at its threshold;
+// a case scores the weighted mean of its grades and passes if they all
+// do. A suite scores the mean of its cases and passes at its threshold.
+// Reports are written as JSON or JUnit XML, and Evidence turns them into
+// the eval scores publish gates check.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"gopkg.in/yaml.v3"
+)
+
+// FileSuffix names suite files.
+const FileSuffix = ".ossaeval.yaml"
+
+// Grader types.
+const (
+	// GraderExact compares a field of the output with the expected value.
+	GraderExact = "exact"
+	// GraderRegex matches a field of the output against Pattern.
+	GraderRegex = "regex"
+	// GraderJudge has a model score the output against Criteria.
+	GraderJudge = "judge"
+	// GraderFunc calls the Runner's function named by Func.
+	GraderFunc = "func"
+)
+
+// Thresholds used when a suite or grader sets none.
+const (
+	DefaultThreshold      = 1.0
+	DefaultJudgeThreshold = 0.7
+)
+
+// Suite is an *.ossaeval.yaml file.
+type Suite struct {
+	Name string `json:"name" yaml:"name"`
+	// Agent is the path of the agent's manifest, relative to the suite.
+	Agent string `json:"agent" yaml:"agent"`
+	// Threshold is the score the suite passes at; 0 means
+	// DefaultThreshold.
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// Judge is the model of judge graders, merged into the agent's
+	// spec.llm as a tool's llm override is.
+	Judge *ossa.LLMConfig `json:"judge,omitempty" yaml:"judge,omitempty"`
+	// Graders grade every case, before the case's own.
+	Graders []Grader `json:"graders,omitempty" yaml:"graders,omitempty"`
+	Cases   []Case   `json:"cases" yaml:"cases"`
+
+	dir string
+}
+
+// Case is one input of a suite and what its output should be.
+type Case struct {
+	Name  string                 `json:"name" yaml:"name"`
+	Input map[string]interface{} `json:"input,omitempty" yaml:"input,omitempty"`
+	// Expected is the output exact graders compare with: an object whose
+	// field at a grader's Field is expected there, or a value for the
+	// field itself.
+	Expected interface{} `json:"expected,omitempty" yaml:"expected,omitempty"`
+	Graders  []Grader    `json:"graders,omitempty" yaml:"graders,omitempty"`
+}
+
+// Grader checks the output of a case.
+type Grader struct {
+	Type string `json:"type" yaml:"type"`
+	// Field is a dotted path into the output, such as "ticket.priority"
+	// or "items.0"; empty means the whole output, or its content when the
+	// agent answered with text.
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+	// Expected overrides the case's Expected for an exact grader.
+	Expected interface{} `json:"expected,omitempty" yaml:"expected,omitempty"`
+	// Pattern is a regex grader's regular expression.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Criteria is what a judge grader has the model check.
+	Criteria string `json:"criteria,omitempty" yaml:"criteria,omitempty"`
+	// Func names a func grader's function in Runner.Funcs.
+	Func string `json:"func,omitempty" yaml:"func,omitempty"`
+	// Threshold is the score the grader passes at; 0 means 1, or
+	// DefaultJudgeThreshold for judges.
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// Weight is the grader's share of the case score; 0 means 1.
+	Weight float64 `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// LoadSuite reads and validates the suite at path.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSuite(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	return s, nil
+}
+
+// ParseSuite decodes and validates a suite. Its agent path is relative to
+// the working directory.
+func ParseSuite(data []byte) (*Suite, error) {
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid suite: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// AgentPath is the path of the suite's agent manifest.
+func (s *Suite) AgentPath() string {
+	if s.Agent == "" || filepath.IsAbs(s.Agent) {
+		return s.Agent
+	}
+	return filepath.Join(s.dir, s.Agent)
+}
+
+// Validate checks the suite, reporting every problem found.
+func (s *Suite) Validate() error {
+	var problems []string
+	if s.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if len(s.Cases) == 0 {
+		problems = append(problems, "cases: at least one case is required")
+	}
+	if s.Threshold < 0 || s.Threshold > 1 {
+		problems = append(problems, fmt.Sprintf("threshold: %g is not between 0 and 1", s.Threshold))
+	}
+	for i, g := range s.Graders {
+		problems = append(problems, g.problems(fmt.Sprintf("graders[%d]", i))...)
+	}
+	names := map[string]bool{}
+	for i, c := range s.Cases {
+		path := fmt.Sprintf("cases[%d]", i)
+		switch {
+		case c.Name == "":
+			problems = append(problems, path+": name is required")
+		case names[c.Name]:
+			problems = append(problems, fmt.Sprintf("%s: duplicate name %s", path, c.Name))
+		}
+		names[c.Name] = true
+		if len(s.Graders)+len(c.Graders) == 0 {
+			problems = append(problems, path+": no graders")
+		}
+		for j, g := range c.Graders {
+			problems = append(problems, g.problems(fmt.Sprintf("%s.graders[%d]", path, j))...)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid suite %s: %s", s.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (g *Grader) problems(path string) []string {
+	var problems []string
+	switch g.Type {
+	case GraderExact:
+	case GraderRegex:
+		if _, err := regexp.Compile(g.Pattern); err != nil || g.Pattern == "" {
+			problems = append(problems, path+": regex grader needs a valid pattern")
+		}
+	case GraderJudge:
+		if g.Criteria == "" {
+			problems = append(problems, path+": judge grader needs criteria")
+		}
+	case GraderFunc:
+		if g.Func == "" {
+			problems = append(problems, path+": func grader needs func")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s: invalid type: %s", path, g.Type))
+	}
+	if g.Threshold < 0 || g.Threshold > 1 {
+		problems = append(problems, fmt.Sprintf("%s.threshold: %g is not between 0 and 1", path, g.Threshold))
+	}
+	if g.Weight < 0 {
+		problems = append(problems, path+".weight: must not be negative")
+	}
+	return problems
+}
+
+// name is how reports refer to g.
+func (g *Grader) name() string {
+	n := g.Type
+	if g.Type == GraderFunc {
+		n = g.Func
+	}
+	if g.Field != "" {
+		n += "(" + g.Field + ")"
+	}
+	return n
+}
+
+func (g *Grader) threshold() float64 {
+	switch {
+	case g.Threshold > 0:
+		return g.Threshold
+	case g.Type == GraderJudge:
+		return DefaultJudgeThreshold
+	}
+	return 1
+}
+
+func (g *Grader) weight() float64 {
+	if g.Weight > 0 {
+		return g.Weight
+	}
+	return 1
+}
@@ -0,0 +1,262 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+const suiteYAML = `
+name: triage
+agent: triage.ossa.yaml
+threshold: 0.8
+judge: {model: gpt-4o-mini}
+graders:
+  - {type: exact, field: priority}
+cases:
+  - name: outage
+    input: {ticket: "The site is down"}
+    expected: {priority: urgent, team: ops}
+    graders:
+      - {type: regex, field: summary, pattern: "(?i)site"}
+      - {type: judge, criteria: "Names the service", weight: 2}
+  - name: typo
+    input: {ticket: "Typo on the about page"}
+    expected: {priority: low}
+    graders:
+      - {type: func, func: short}
+`
+
+// triage answers as a triage agent, and as a judge scoring 0.9.
+func triage(judged *[]*engine.Request) modelFunc {
+	return func(req *engine.Request) (*engine.Response, error) {
+		if req.Messages[0].Content == JudgePrompt {
+			*judged = append(*judged, req)
+			return &engine.Response{Content: "```json\n{\"score\": 0.9, \"reason\": \"names it\"}\n```"}, nil
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		switch {
+		case strings.Contains(prompt, "down"):
+			return &engine.Response{Content: `{"priority": "urgent", "summary": "Site outage"}`, Usage: engine.Usage{InputTokens: 10, OutputTokens: 5}}, nil
+		case strings.Contains(prompt, "crash"):
+			return nil, errors.New("provider unavailable")
+		}
+		return &engine.Response{Content: `{"priority": "medium", "summary": "A typo to fix on the about page"}`, Usage: engine.Usage{InputTokens: 10, OutputTokens: 5}}, nil
+	}
+}
+
+func triageAgent() *ossa.Manifest {
+	m := ossa.NewManifest("triage", ossa.KindAgent)
+	m.Metadata.Version = "1.0.0"
+	m.Spec.LLM = &ossa.LLMConfig{Provider: "openai", Model: "gpt-4o"}
+	return m
+}
+
+func TestLoadSuite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "triage"+FileSuffix)
+	if err := os.WriteFile(path, []byte(suiteYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSuite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.AgentPath() != filepath.Join(dir, "triage.ossa.yaml") || len(s.Cases) != 2 || s.Judge.Model != "gpt-4o-mini" {
+		t.Errorf("Unexpected suite %+v", s)
+	}
+
+	_, err = ParseSuite([]byte(`
+name: bad
+threshold: 2
+cases:
+  - name: a
+    graders: [{type: regex, pattern: "("}, {type: judge, weight: -1}, {type: fuzzy}]
+  - name: a
+`))
+	if err == nil {
+		t.Fatal("Expected an invalid suite")
+	}
+	for _, want := range []string{
+		"threshold: 2 is not between 0 and 1",
+		"cases[0].graders[0]: regex grader needs a valid pattern",
+		"cases[0].graders[1]: judge grader needs criteria",
+		"cases[0].graders[1].weight: must not be negative",
+		"cases[0].graders[2]: invalid type: fuzzy",
+		"cases[1]: duplicate name a",
+		"cases[1]: no graders",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, err := ParseSuite([]byte(suiteYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var judged []*engine.Request
+	r := &Runner{Engine: &engine.Engine{Model: triage(&judged)}}
+	if _, err := r.Run(context.Background(), s, triageAgent()); err == nil || !strings.Contains(err.Error(), "no grader func short") {
+		t.Fatalf("Expected the missing func to fail the suite, got %v", err)
+	}
+
+	r.Funcs = map[string]Func{"short": func(_ context.Context, in GradeInput) (float64, string, error) {
+		if len(text(in.Output["summary"])) > 20 {
+			return 0.5, "summary is long", nil
+		}
+		return 1, "", nil
+	}}
+	report, err := r.Run(context.Background(), s, triageAgent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Suite != "triage" || report.Agent != "triage" || report.Version != "1.0.0" || report.Usage.InputTokens != 20 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	outage := report.Cases[0]
+	if !outage.Passed || len(outage.Grades) != 3 {
+		t.Fatalf("Expected outage to pass 3 grades, got %+v", outage)
+	}
+	// (1 + 1 + 0.9*2) / 4
+	if outage.Score != 0.95 {
+		t.Errorf("Expected the weighted mean 0.95, got %v", outage.Score)
+	}
+	if g := outage.Grades[2]; g.Grader != "judge" || g.Reason != "names it" {
+		t.Errorf("Unexpected judge grade %+v", g)
+	}
+	if len(judged) != 1 || judged[0].LLM.Model != "gpt-4o-mini" || judged[0].LLM.Provider != "openai" {
+		t.Errorf("Expected the judge to merge into the agent's llm, got %+v", judged)
+	}
+	typo := report.Cases[1]
+	if typo.Passed || typo.Score != 0.25 {
+		t.Errorf("Expected typo to fail with 0.25, got %+v", typo)
+	}
+	if g := typo.Grades[0]; g.Grader != "exact(priority)" || g.Reason != "got medium, want low" {
+		t.Errorf("Unexpected exact grade %+v", g)
+	}
+	if report.Score != 0.6 || report.Passed || len(report.Failed()) != 1 {
+		t.Errorf("Expected the suite to fail at 0.6, got %v %v", report.Score, report.Passed)
+	}
+	if ev := Evidence(report); ev.EvalScores["triage"] != 0.6 {
+		t.Errorf("Unexpected evidence %+v", ev)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	s, err := ParseSuite([]byte(`
+name: crash
+cases:
+  - name: crash
+    input: {ticket: "It crashed"}
+    expected: "ok"
+    graders: [{type: exact}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var judged []*engine.Request
+	r := &Runner{Engine: &engine.Engine{Model: triage(&judged)}}
+	report, err := r.Run(context.Background(), s, triageAgent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := report.Cases[0]
+	if c.Passed || c.Score != 0 || !strings.Contains(c.Error, "provider unavailable") || report.Threshold != DefaultThreshold {
+		t.Errorf("Expected the failed run to score 0, got %+v", c)
+	}
+
+	out, err := JUnit(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuites tests="2" failures="1" errors="1"`,
+		`<testcase name="crash" classname="crash"`,
+		`<error message="agent run failed">`,
+		`<testcase name="threshold" classname="crash"`,
+		`<property name="score" value="0.000"></property>`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected %s in\n%s", want, out)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	output := map[string]interface{}{"ticket": map[string]interface{}{"tags": []interface{}{"a", "b"}}}
+	if v, ok := field(output, "ticket.tags.1"); !ok || v != "b" {
+		t.Errorf("Expected b, got %v", v)
+	}
+	if _, ok := field(output, "ticket.tags.2"); ok {
+		t.Error("Expected no ticket.tags.2")
+	}
+	if v, _ := field(map[string]interface{}{"content": "hi"}, ""); v != "hi" {
+		t.Errorf("Expected a text answer's content, got %v", v)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	report := func(version string, scores ...float64) *Report {
+		r := &Report{Suite: "triage", Agent: "triage", Version: version}
+		for i, s := range scores {
+			r.Cases = append(r.Cases, CaseResult{Name: string(rune('a' + i)), Score: s})
+			r.Score += s / float64(len(scores))
+		}
+		return r
+	}
+	base := report("1.0.0", 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.9)
+	cmp, err := Compare(base, report("1.1.0", 1, 1, 1, 1, 1, 1, 1, 0.88), 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.Wins != 7 || cmp.Losses != 0 || cmp.Ties != 1 || cmp.Cases[7].Outcome != OutcomeTie {
+		t.Errorf("Unexpected outcomes %+v", cmp)
+	}
+	// 2 * 0.5^7
+	if math.Abs(cmp.PValue-0.015625) > 1e-9 || cmp.Verdict != VerdictImproved {
+		t.Errorf("Expected an improvement at p = 0.0156, got %v %s", cmp.PValue, cmp.Verdict)
+	}
+	if math.Abs(cmp.Delta-0.435) > 1e-9 || cmp.StdErr <= 0 || cmp.Candidate.Version != "1.1.0" {
+		t.Errorf("Unexpected delta %v ± %v", cmp.Delta, cmp.StdErr)
+	}
+
+	cmp, err = Compare(base, report("1.1.0", 0.4, 0.6, 0.5, 0.5, 0.5, 0.5, 0.5, 0.9), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.Wins != 1 || cmp.Losses != 1 || cmp.PValue != 1 || cmp.Verdict != VerdictUnchanged {
+		t.Errorf("Expected no change, got %+v", cmp)
+	}
+
+	cmp, _ = Compare(report("1.1.0", 1, 1, 1, 1, 1, 1, 1, 1), base, 0)
+	if cmp.Verdict != VerdictRegressed {
+		t.Errorf("Expected a regression, got %s", cmp.Verdict)
+	}
+	if _, err := Compare(base, report("1.1.0", 1), 0); err == nil {
+		t.Error("Expected reports with different cases not to compare")
+	}
+	if _, err := Compare(report("1.0.0"), report("1.1.0"), 0); err == nil || !strings.Contains(err.Error(), "no cases") {
+		t.Errorf("Expected reports without cases not to compare, got %v", err)
+	}
+	dup := report("1.0.0", 0.5, 0.5)
+	dup.Cases[1].Name = "a"
+	if _, err := Compare(dup, report("1.1.0", 1, 1), 0); err == nil || !strings.Contains(err.Error(), "case a appears twice") {
+		t.Errorf("Expected a duplicate case to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/junit"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Report is the outcome of a suite.
+type Report struct {
+	Suite   string `json:"suite"`
+	Agent   string `json:"agent"`
+	Version string `json:"version,omitempty"`
+	// Score is the mean score of the cases, and Passed whether it reached
+	// Threshold.
+	Score     float64       `json:"score"`
+	Threshold float64       `json:"threshold"`
+	Passed    bool          `json:"passed"`
+	Cases     []CaseResult  `json:"cases"`
+	Usage     engine.Usage  `json:"usage"`
+	Duration  time.Duration `json:"duration_ns"`
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	Name   string                 `json:"name"`
+	Input  map[string]interface{} `json:"input,omitempty"`
+	Output map[string]interface{} `json:"output,omitempty"`
+	Grades []Grade                `json:"grades,omitempty"`
+	// Score is the weighted mean of the grades; Passed is whether every
+	// grade passed.
+	Score  float64 `json:"score"`
+	Passed bool    `json:"passed"`
+	// Error is why the agent run failed; the case scores 0.
+	Error    string        `json:"error,omitempty"`
+	Usage    engine.Usage  `json:"usage"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Grade is one grader's verdict on a case.
+type Grade struct {
+	Grader string  `json:"grader"`
+	Score  float64 `json:"score"`
+	Passed bool    `json:"passed"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Failed returns the cases that did not pass.
+func (r *Report) Failed() []CaseResult {
+	var failed []CaseResult
+	for _, c := range r.Cases {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Evidence returns reports as the eval scores of publish gates, by suite.
+func Evidence(reports ...*Report) ossa.GateEvidence {
+	scores := make(map[string]float64, len(reports))
+	for _, r := range reports {
+		scores[r.Suite] = r.Score
+	}
+	return ossa.GateEvidence{EvalScores: scores}
+}
+
+// JUnit renders reports as JUnit XML, a testsuite per report and a
+// testcase per case, for CI systems to show. Failing grades are the
+// failure text; a failed run is an error.
+func JUnit(reports ...*Report) ([]byte, error) {
+	out := &junit.Report{}
+	for _, r := range reports {
+		suite := junit.Suite{
+			Name: r.Suite,
+			Time: junit.Seconds(r.Duration),
+			Properties: &junit.Properties{Items: []junit.Property{
+				{Name: "agent", Value: r.Agent},
+				{Name: "version", Value: r.Version},
+				{Name: "score", Value: fmt.Sprintf("%.3f", r.Score)},
+				{Name: "threshold", Value: fmt.Sprintf("%.3f", r.Threshold)},
+			}},
+		}
+		for _, c := range r.Cases {
+			tc := junit.Case{Name: c.Name, ClassName: r.Suite, Time: junit.Seconds(c.Duration)}
+			switch {
+			case c.Error != "":
+				tc.Error = &junit.Problem{Message: "agent run failed", Text: c.Error}
+			case !c.Passed:
+				var lines []string
+				for _, g := range c.Grades {
+					if !g.Passed {
+						lines = append(lines, fmt.Sprintf("%s: %.2f: %s", g.Grader, g.Score, g.Reason))
+					}
+				}
+				tc.Failure = &junit.Problem{Message: fmt.Sprintf("scored %.2f", c.Score), Text: strings.Join(lines, "\n")}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if !r.Passed {
+			// The suite itself is a case, so a score below threshold fails
+			// the build even when every case passed on its own.
+			suite.Cases = append(suite.Cases, junit.Case{
+				Name:      "threshold",
+				ClassName: r.Suite,
+				Failure:   &junit.Problem{Message: fmt.Sprintf("suite scored %.2f, below %.2f", r.Score, r.Threshold)},
+			})
+		}
+		out.Add(suite)
+	}
+	return out.Marshal()
+}
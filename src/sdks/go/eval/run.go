@@ -0,0 +1,258 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// JudgePrompt is the system prompt of judge graders.
+const JudgePrompt = `You grade the output of an AI agent against criteria. You are given the criteria, the agent's input, the expected output if any, and the output. Answer with a JSON object only: {"score": a number from 0 (fails the criteria) to 1 (fully meets them), "reason": one sentence}.`
+
+// GradeInput is what a Func grades.
+type GradeInput struct {
+	Case   *Case
+	Grader *Grader
+	// Output is the agent's output; Result is its whole run.
+	Output map[string]interface{}
+	Result *engine.Result
+}
+
+// Func is a custom grader, named by a func grader. It returns a
+// score between 0 and 1 and why; an error fails the grade.
+type Func func(ctx context.Context, in GradeInput) (score float64, reason string, err error)
+
+// Runner runs suites on Engine.
+type Runner struct {
+	Engine *engine.Engine
+	// Funcs are the functions of func graders, by name.
+	Funcs map[string]Func
+	// Judge answers judge graders; nil means Engine.Model.
+	Judge engine.Model
+}
+
+// Run runs each case of s on m and grades it. Cases whose run fails are
+// reported failed; the error is for a suite that cannot run at all.
+func (r *Runner) Run(ctx context.Context, s *Suite, m *ossa.Manifest) (*Report, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	for _, c := range s.Cases {
+		for _, g := range append(append([]Grader(nil), s.Graders...), c.Graders...) {
+			if g.Type == GraderFunc && r.Funcs[g.Func] == nil {
+				return nil, fmt.Errorf("case %s: no grader func %s", c.Name, g.Func)
+			}
+		}
+	}
+	judge, err := m.Spec.LLM.Merge(s.Judge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid judge: %w", err)
+	}
+	report := &Report{Suite: s.Name, Agent: m.Metadata.Name, Version: m.Metadata.Version, Threshold: s.Threshold}
+	if report.Threshold == 0 {
+		report.Threshold = DefaultThreshold
+	}
+	start := time.Now()
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		res := r.runCase(ctx, s, c, m, judge)
+		report.Cases = append(report.Cases, res)
+		report.Usage.InputTokens += res.Usage.InputTokens
+		report.Usage.OutputTokens += res.Usage.OutputTokens
+		report.Score += res.Score
+	}
+	report.Score /= float64(len(s.Cases))
+	report.Passed = report.Score >= report.Threshold
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, s *Suite, c *Case, m *ossa.Manifest, judge *ossa.LLMConfig) CaseResult {
+	start := time.Now()
+	res := CaseResult{Name: c.Name, Input: c.Input}
+	defer func() { res.Duration = time.Since(start) }()
+	run, err := r.Engine.RunAgent(ctx, m, c.Input)
+	if run != nil {
+		res.Output, res.Usage = run.Output, run.Usage
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	graders := append(append([]Grader(nil), s.Graders...), c.Graders...)
+	total, weights := 0.0, 0.0
+	res.Passed = true
+	for i := range graders {
+		g := &graders[i]
+		grade := r.grade(ctx, GradeInput{Case: c, Grader: g, Output: run.Output, Result: run}, judge)
+		res.Grades = append(res.Grades, grade)
+		total += grade.Score * g.weight()
+		weights += g.weight()
+		res.Passed = res.Passed && grade.Passed
+	}
+	res.Score = total / weights
+	return res
+}
+
+func (r *Runner) grade(ctx context.Context, in GradeInput, judge *ossa.LLMConfig) Grade {
+	g := in.Grader
+	var score float64
+	var reason string
+	var err error
+	switch g.Type {
+	case GraderExact:
+		score, reason = gradeExact(in)
+	case GraderRegex:
+		score, reason = gradeRegex(in)
+	case GraderJudge:
+		score, reason, err = r.gradeJudge(ctx, in, judge)
+	case GraderFunc:
+		score, reason, err = r.Funcs[g.Func](ctx, in)
+	}
+	if err != nil {
+		score, reason = 0, err.Error()
+	}
+	score = clamp(score)
+	return Grade{Grader: g.name(), Score: score, Passed: err == nil && score >= g.threshold(), Reason: reason}
+}
+
+func gradeExact(in GradeInput) (float64, string) {
+	got, ok := field(in.Output, in.Grader.Field)
+	if !ok {
+		return 0, fmt.Sprintf("output has no %s", in.Grader.Field)
+	}
+	want := in.Grader.Expected
+	if want == nil {
+		want = in.Case.Expected
+		if o, ok := normalize(want).(map[string]interface{}); ok && in.Grader.Field != "" {
+			if v, ok := field(o, in.Grader.Field); ok {
+				want = v
+			}
+		}
+	}
+	if reflect.DeepEqual(normalize(got), normalize(want)) {
+		return 1, ""
+	}
+	return 0, fmt.Sprintf("got %s, want %s", text(got), text(want))
+}
+
+func gradeRegex(in GradeInput) (float64, string) {
+	got, ok := field(in.Output, in.Grader.Field)
+	if !ok {
+		return 0, fmt.Sprintf("output has no %s", in.Grader.Field)
+	}
+	if re := regexp.MustCompile(in.Grader.Pattern); re.MatchString(text(got)) {
+		return 1, ""
+	}
+	return 0, fmt.Sprintf("%s does not match %s", text(got), in.Grader.Pattern)
+}
+
+func (r *Runner) gradeJudge(ctx context.Context, in GradeInput, llm *ossa.LLMConfig) (float64, string, error) {
+	model := r.Judge
+	if model == nil {
+		model = r.Engine.Model
+	}
+	got, ok := field(in.Output, in.Grader.Field)
+	if !ok {
+		return 0, fmt.Sprintf("output has no %s", in.Grader.Field), nil
+	}
+	prompt, err := json.Marshal(map[string]interface{}{"criteria": in.Grader.Criteria, "input": in.Case.Input, "expected": in.Case.Expected, "output": got})
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := model.Complete(ctx, &engine.Request{
+		Agent: in.Result.Agent,
+		LLM:   llm,
+		Messages: []engine.Message{
+			{Role: engine.RoleSystem, Content: JudgePrompt},
+			{Role: engine.RoleUser, Content: string(prompt)},
+		},
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("judge failed: %w", err)
+	}
+	content := strings.TrimSpace(resp.Content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(strings.TrimPrefix(content, "```json"), "```")
+		content = strings.TrimSpace(strings.TrimSuffix(content, "```"))
+	}
+	var verdict struct {
+		Score  *float64 `json:"score"`
+		Reason string   `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(content), &verdict); err != nil || verdict.Score == nil {
+		return 0, "", fmt.Errorf("judge answered without a score: %s", resp.Content)
+	}
+	return *verdict.Score, verdict.Reason, nil
+}
+
+// field is the value at a dotted path of output; the whole output, or
+// its content if the agent answered with text, for "".
+func field(output map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		if content, ok := output["content"]; ok && len(output) == 1 {
+			return content, true
+		}
+		return output, true
+	}
+	var v interface{} = output
+	for _, key := range strings.Split(path, ".") {
+		switch o := v.(type) {
+		case map[string]interface{}:
+			next, ok := o[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(o) {
+				return nil, false
+			}
+			v = o[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// normalize is v as JSON decodes it, so YAML ints equal JSON numbers.
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return v
+	}
+	return out
+}
+
+// text is v as a string: itself if it is one, else its JSON.
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func clamp(score float64) float64 {
+	switch {
+	case score < 0:
+		return 0
+	case score > 1:
+		return 1
+	}
+	return score
+}
@@ -0,0 +1,297 @@
+// Package chacha20poly1305 implements the ChaCha20-Poly1305 AEAD of
+// RFC 8439, which the age encryption format uses and the standard library
+// does not export.
+package chacha20poly1305
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	// KeySize is the size of keys.
+	KeySize = 32
+	// NonceSize is the size of nonces.
+	NonceSize = 12
+	// Overhead is the size of the tag appended to ciphertexts.
+	Overhead = 16
+)
+
+var errOpen = errors.New("chacha20poly1305: message authentication failed")
+
+type aead struct {
+	key [KeySize]byte
+}
+
+// New returns the AEAD for a 32-byte key.
+func New(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("chacha20poly1305: bad key length")
+	}
+	a := &aead{}
+	copy(a.key[:], key)
+	return a, nil
+}
+
+func (a *aead) NonceSize() int { return NonceSize }
+func (a *aead) Overhead() int  { return Overhead }
+
+func (a *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+Overhead)
+	ct := out[:len(plaintext)]
+	xorKeyStream(ct, plaintext, &a.key, nonce, 1)
+	tag := a.tag(nonce, additionalData, ct)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (a *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length")
+	}
+	if len(ciphertext) < Overhead {
+		return nil, errOpen
+	}
+	ct, got := ciphertext[:len(ciphertext)-Overhead], ciphertext[len(ciphertext)-Overhead:]
+	want := a.tag(nonce, additionalData, ct)
+	if subtle.ConstantTimeCompare(want[:], got) != 1 {
+		return nil, errOpen
+	}
+	ret, out := sliceForAppend(dst, len(ct))
+	xorKeyStream(out, ct, &a.key, nonce, 1)
+	return ret, nil
+}
+
+// tag is the Poly1305 tag of additionalData and ct, keyed by the first
+// block of the key stream.
+func (a *aead) tag(nonce, additionalData, ct []byte) [16]byte {
+	var block [64]byte
+	chachaBlock(&block, &a.key, nonce, 0)
+	var p poly1305
+	p.init(block[:32])
+	p.write(additionalData)
+	p.pad()
+	p.write(ct)
+	p.pad()
+	var lens [16]byte
+	binary.LittleEndian.PutUint64(lens[:8], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(lens[8:], uint64(len(ct)))
+	p.write(lens[:])
+	return p.sum()
+}
+
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	return head, head[len(in):]
+}
+
+// chachaBlock computes the ChaCha20 block for counter.
+func chachaBlock(out *[64]byte, key *[KeySize]byte, nonce []byte, counter uint32) {
+	var s [16]uint32
+	s[0], s[1], s[2], s[3] = 0x61707865, 0x3320646e, 0x79622d32, 0x6b206574
+	for i := 0; i < 8; i++ {
+		s[4+i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	s[12] = counter
+	s[13] = binary.LittleEndian.Uint32(nonce[0:])
+	s[14] = binary.LittleEndian.Uint32(nonce[4:])
+	s[15] = binary.LittleEndian.Uint32(nonce[8:])
+	x := s
+	quarter := func(a, b, c, d int) {
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+	}
+	for i := 0; i < 10; i++ {
+		quarter(0, 4, 8, 12)
+		quarter(1, 5, 9, 13)
+		quarter(2, 6, 10, 14)
+		quarter(3, 7, 11, 15)
+		quarter(0, 5, 10, 15)
+		quarter(1, 6, 11, 12)
+		quarter(2, 7, 8, 13)
+		quarter(3, 4, 9, 14)
+	}
+	for i := range x {
+		binary.LittleEndian.PutUint32(out[4*i:], x[i]+s[i])
+	}
+}
+
+func xorKeyStream(dst, src []byte, key *[KeySize]byte, nonce []byte, counter uint32) {
+	var block [64]byte
+	for len(src) > 0 {
+		chachaBlock(&block, key, nonce, counter)
+		counter++
+		n := len(src)
+		if n > 64 {
+			n = 64
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ block[i]
+		}
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// poly1305 is the one-time authenticator, in 26-bit limbs.
+type poly1305 struct {
+	r, h   [5]uint32
+	s      [4]uint32
+	buf    [16]byte
+	buffed int
+}
+
+func (p *poly1305) init(key []byte) {
+	p.r[0] = binary.LittleEndian.Uint32(key[0:]) & 0x3ffffff
+	p.r[1] = (binary.LittleEndian.Uint32(key[3:]) >> 2) & 0x3ffff03
+	p.r[2] = (binary.LittleEndian.Uint32(key[6:]) >> 4) & 0x3ffc0ff
+	p.r[3] = (binary.LittleEndian.Uint32(key[9:]) >> 6) & 0x3f03fff
+	p.r[4] = (binary.LittleEndian.Uint32(key[12:]) >> 8) & 0x00fffff
+	for i := 0; i < 4; i++ {
+		p.s[i] = binary.LittleEndian.Uint32(key[16+4*i:])
+	}
+}
+
+func (p *poly1305) write(m []byte) {
+	if p.buffed > 0 {
+		n := copy(p.buf[p.buffed:], m)
+		p.buffed += n
+		m = m[n:]
+		if p.buffed < 16 {
+			return
+		}
+		p.block(p.buf[:], 1<<24)
+		p.buffed = 0
+	}
+	for len(m) >= 16 {
+		p.block(m[:16], 1<<24)
+		m = m[16:]
+	}
+	p.buffed = copy(p.buf[:], m)
+}
+
+// pad completes a partial block with zeros, as RFC 8439 pads its fields.
+func (p *poly1305) pad() {
+	if p.buffed > 0 {
+		for i := p.buffed; i < 16; i++ {
+			p.buf[i] = 0
+		}
+		p.block(p.buf[:], 1<<24)
+		p.buffed = 0
+	}
+}
+
+func (p *poly1305) block(m []byte, hibit uint32) {
+	const mask = 0x3ffffff
+	r0, r1, r2, r3, r4 := uint64(p.r[0]), uint64(p.r[1]), uint64(p.r[2]), uint64(p.r[3]), uint64(p.r[4])
+	s1, s2, s3, s4 := r1*5, r2*5, r3*5, r4*5
+	h0 := uint64(p.h[0] + binary.LittleEndian.Uint32(m[0:])&mask)
+	h1 := uint64(p.h[1] + (binary.LittleEndian.Uint32(m[3:])>>2)&mask)
+	h2 := uint64(p.h[2] + (binary.LittleEndian.Uint32(m[6:])>>4)&mask)
+	h3 := uint64(p.h[3] + (binary.LittleEndian.Uint32(m[9:])>>6)&mask)
+	h4 := uint64(p.h[4] + (binary.LittleEndian.Uint32(m[12:])>>8 | hibit))
+
+	d0 := h0*r0 + h1*s4 + h2*s3 + h3*s2 + h4*s1
+	d1 := h0*r1 + h1*r0 + h2*s4 + h3*s3 + h4*s2
+	d2 := h0*r2 + h1*r1 + h2*r0 + h3*s4 + h4*s3
+	d3 := h0*r3 + h1*r2 + h2*r1 + h3*r0 + h4*s4
+	d4 := h0*r4 + h1*r3 + h2*r2 + h3*r1 + h4*r0
+
+	c := d0 >> 26
+	p.h[0] = uint32(d0) & mask
+	d1 += c
+	c = d1 >> 26
+	p.h[1] = uint32(d1) & mask
+	d2 += c
+	c = d2 >> 26
+	p.h[2] = uint32(d2) & mask
+	d3 += c
+	c = d3 >> 26
+	p.h[3] = uint32(d3) & mask
+	d4 += c
+	c = d4 >> 26
+	p.h[4] = uint32(d4) & mask
+	p.h[0] += uint32(c * 5)
+	p.h[1] += p.h[0] >> 26
+	p.h[0] &= mask
+}
+
+func (p *poly1305) sum() [16]byte {
+	const mask = 0x3ffffff
+	if p.buffed > 0 {
+		p.buf[p.buffed] = 1
+		for i := p.buffed + 1; i < 16; i++ {
+			p.buf[i] = 0
+		}
+		p.block(p.buf[:], 0)
+	}
+	h0, h1, h2, h3, h4 := p.h[0], p.h[1], p.h[2], p.h[3], p.h[4]
+	c := h1 >> 26
+	h1 &= mask
+	h2 += c
+	c = h2 >> 26
+	h2 &= mask
+	h3 += c
+	c = h3 >> 26
+	h3 &= mask
+	h4 += c
+	c = h4 >> 26
+	h4 &= mask
+	h0 += c * 5
+	c = h0 >> 26
+	h0 &= mask
+	h1 += c
+
+	// g = h + 5 - 2^130; use it if it did not go negative.
+	g0 := h0 + 5
+	c = g0 >> 26
+	g0 &= mask
+	g1 := h1 + c
+	c = g1 >> 26
+	g1 &= mask
+	g2 := h2 + c
+	c = g2 >> 26
+	g2 &= mask
+	g3 := h3 + c
+	c = g3 >> 26
+	g3 &= mask
+	g4 := h4 + c - (1 << 26)
+	sel := (g4 >> 31) - 1
+	h0 = h0&^sel | g0&sel
+	h1 = h1&^sel | g1&sel
+	h2 = h2&^sel | g2&sel
+	h3 = h3&^sel | g3&sel
+	h4 = h4&^sel | g4&sel
+
+	w0 := uint64(h0 | h1<<26)
+	w1 := uint64(h1>>6 | h2<<20)
+	w2 := uint64(h2>>12 | h3<<14)
+	w3 := uint64(h3>>18 | h4<<8)
+
+	var out [16]byte
+	f := (w0 & 0xffffffff) + uint64(p.s[0])
+	binary.LittleEndian.PutUint32(out[0:], uint32(f))
+	f = (w1 & 0xffffffff) + uint64(p.s[1]) + f>>32
+	binary.LittleEndian.PutUint32(out[4:], uint32(f))
+	f = (w2 & 0xffffffff) + uint64(p.s[2]) + f>>32
+	binary.LittleEndian.PutUint32(out[8:], uint32(f))
+	f = (w3 & 0xffffffff) + uint64(p.s[3]) + f>>32
+	binary.LittleEndian.PutUint32(out[12:], uint32(f))
+	return out
+}
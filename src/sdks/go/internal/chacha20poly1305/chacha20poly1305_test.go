@@ -0,0 +1,66 @@
+package chacha20poly1305
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.NewReplacer(" ", "", ":", "", "\n", "").Replace(s))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Vectors from RFC 8439.
+
+func TestBlock(t *testing.T) {
+	var key [KeySize]byte
+	copy(key[:], unhex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"))
+	var out [64]byte
+	chachaBlock(&out, &key, unhex("000000090000004a00000000"), 1)
+	if want := unhex("10f1e7e4d13b5915500fdd1fa32071c4c7d1f4c733c068030422aa9ac3d46c4e"); !bytes.Equal(out[:32], want) {
+		t.Errorf("Unexpected block %x", out)
+	}
+}
+
+func TestPoly1305(t *testing.T) {
+	var p poly1305
+	p.init(unhex("85:d6:be:78:57:55:6d:33:7f:44:52:fe:42:d5:06:a8:01:03:80:8a:fb:0d:b2:fd:4a:bf:f6:af:41:49:f5:1b"))
+	p.write([]byte("Cryptographic Forum Research Group"))
+	if tag := p.sum(); !bytes.Equal(tag[:], unhex("a8:06:1d:c1:30:51:36:c6:c2:2b:8b:af:0c:01:27:a9")) {
+		t.Errorf("Unexpected tag %x", tag)
+	}
+}
+
+func TestAEAD(t *testing.T) {
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	aad := unhex("50515253c0c1c2c3c4c5c6c7")
+	key := unhex("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce := unhex("070000004041424344454647")
+	want := unhex(`d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d6
+3dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b36
+92ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc
+3ff4def08e4b7a9de576d26586cec64b6116
+1ae10b594f09e26a7e902ecbd0600691`)
+
+	a, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := a.Seal(nil, nonce, plaintext, aad)
+	if !bytes.Equal(ct, want) {
+		t.Errorf("Unexpected ciphertext\n%x\nwant\n%x", ct, want)
+	}
+	got, err := a.Open(nil, nonce, ct, aad)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Failed to open: %v", err)
+	}
+	ct[0] ^= 1
+	if _, err := a.Open(nil, nonce, ct, aad); err == nil {
+		t.Error("Expected a modified ciphertext rejected")
+	}
+}
@@ -0,0 +1,209 @@
+// Package config stores CLI settings in ~/.config/ossa/config.yaml with
+// OSSA_* environment overrides, loaded via viper.
+//
+// A setting's effective value comes from, in order: its environment
+// variable, the config file, and its default. Command-line flags override
+// all three; that is left to the CLI, which knows which flags were set.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/internal/selfupdate"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/spf13/viper"
+)
+
+// Key describes one setting.
+type Key struct {
+	Name    string
+	Env     string
+	Default string
+	// Allowed lists the accepted values; empty accepts any.
+	Allowed []string
+	Help    string
+}
+
+// Source says where an effective value came from.
+type Source string
+
+const (
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+	SourceDefault Source = "default"
+)
+
+// Keys are the supported settings, sorted by name.
+var Keys = []Key{
+	{Name: "color", Env: "OSSA_COLOR", Default: "auto", Allowed: []string{"auto", "always", "never"}, Help: "Color preference for terminal output"},
+	{Name: "endpoint_policy", Env: "OSSA_ENDPOINT_POLICY", Help: "Endpoint policy file validation and tool runtimes enforce (allowed domains, CIDRs and schemes)"},
+	{Name: "identity", Env: "OSSA_IDENTITY", Help: "Identity files or KMS key URIs, comma separated, decrypting encrypted manifests and bundles"},
+	{Name: "model_policy", Env: "OSSA_MODEL_POLICY", Help: "Model policy file validation enforces (allowed and banned models)"},
+	{Name: "offline", Env: "OSSA_OFFLINE", Default: "false", Allowed: []string{"true", "false"}, Help: "Make no network calls, as with --offline (for air-gapped hosts)"},
+	{Name: "output", Env: "OSSA_OUTPUT", Default: "text", Allowed: []string{"text", "json"}, Help: "Default output format"},
+	{Name: "registry_url", Env: "OSSA_REGISTRY_URL", Help: "Agent registry base URL"},
+	{Name: "release_url", Env: "OSSA_RELEASE_URL", Default: selfupdate.DefaultEndpoint, Help: "Latest-release endpoint for ossa upgrade"},
+	{Name: "runs_dir", Env: "OSSA_RUNS_DIR", Help: "Where ossa run records runs (default: runs in the config directory)"},
+	{Name: "schema_version", Env: "OSSA_SCHEMA_VERSION", Default: ossa.OSSAVersion, Help: "Schema version used without --schema (embedded or vendored)"},
+	{Name: "trace_endpoint", Env: "OSSA_TRACE_ENDPOINT", Help: "Where ossa run exports traces: an OTLP/HTTP endpoint (e.g. http://localhost:6006), or the Langfuse or LangSmith URL"},
+	{Name: "trace_exporter", Env: "OSSA_TRACE_EXPORTER", Default: "otlp", Allowed: []string{"otlp", "langfuse", "langsmith"}, Help: "Trace exporter; langfuse and langsmith take their keys from LANGFUSE_* and LANGSMITH_*"},
+	{Name: "trace_headers", Env: "OSSA_TRACE_HEADERS", Help: "Headers the otlp exporter sends, as key=value,key=value"},
+}
+
+// Path returns the config file path: $OSSA_CONFIG if set, otherwise
+// ossa/config.yaml in the user config directory.
+func Path() (string, error) {
+	if p := os.Getenv("OSSA_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "ossa", "config.yaml"), nil
+}
+
+// Config is the contents of a config file, read through viper with the
+// keys' environment variables and defaults bound.
+type Config struct {
+	// file holds the values Save writes; v is rebuilt from it on Set.
+	file map[string]string
+	v    *viper.Viper
+}
+
+// Load reads the config file at path. A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	r := viper.New()
+	r.SetConfigFile(path)
+	r.SetConfigType("yaml")
+	file := map[string]string{}
+	if err := r.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		var parseErr viper.ConfigParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	for _, name := range r.AllKeys() {
+		if _, err := lookup(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		file[name] = r.GetString(name)
+	}
+	return &Config{file: file, v: newViper(file)}, nil
+}
+
+// newViper resolves keys from their environment variables, then file, then
+// their defaults.
+func newViper(file map[string]string) *viper.Viper {
+	v := viper.New()
+	for _, k := range Keys {
+		v.SetDefault(k.Name, k.Default)
+		_ = v.BindEnv(k.Name, k.Env)
+	}
+	values := make(map[string]interface{}, len(file))
+	for name, value := range file {
+		values[name] = value
+	}
+	_ = v.MergeConfigMap(values)
+	return v
+}
+
+// Save writes the config to path, creating its directory.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	w := viper.New()
+	w.SetConfigType("yaml")
+	for name, value := range c.file {
+		w.Set(name, value)
+	}
+	if err := w.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// Set stores a value in the config file contents; an empty value unsets
+// the key.
+func (c *Config) Set(name, value string) error {
+	k, err := lookup(name)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		delete(c.file, name)
+	} else {
+		if err := k.check(value); err != nil {
+			return err
+		}
+		c.file[name] = value
+	}
+	c.v = newViper(c.file)
+	return nil
+}
+
+// Get returns the effective value of a key and its source.
+func (c *Config) Get(name string) (string, Source, error) {
+	k, err := lookup(name)
+	if err != nil {
+		return "", "", err
+	}
+	v := c.v.GetString(name)
+	switch {
+	case os.Getenv(k.Env) != "":
+		if err := k.check(v); err != nil {
+			return "", "", fmt.Errorf("%s: %w", k.Env, err)
+		}
+		return v, SourceEnv, nil
+	case c.v.InConfig(name):
+		if err := k.check(v); err != nil {
+			return "", "", err
+		}
+		return v, SourceFile, nil
+	}
+	return v, SourceDefault, nil
+}
+
+// Value returns the effective value of a key, ignoring invalid values.
+func (c *Config) Value(name string) string {
+	v, _, err := c.Get(name)
+	if err != nil {
+		k, _ := lookup(name)
+		return k.Default
+	}
+	return v
+}
+
+func lookup(name string) (Key, error) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	var names []string
+	for _, k := range Keys {
+		names = append(names, k.Name)
+	}
+	sort.Strings(names)
+	return Key{}, fmt.Errorf("unknown config key %q (keys: %s)", name, strings.Join(names, ", "))
+}
+
+func (k Key) check(value string) error {
+	if len(k.Allowed) == 0 {
+		return nil
+	}
+	for _, a := range k.Allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q (want %s)", k.Name, value, strings.Join(k.Allowed, ", "))
+}
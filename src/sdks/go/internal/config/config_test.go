@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ossa", "config.yaml")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, src, _ := cfg.Get("output"); v != "text" || src != SourceDefault {
+		t.Errorf("Expected default text output, got %s from %s", v, src)
+	}
+
+	if err := cfg.Set("output", "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("registry_url", "https://registry.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("output", "xml"); err == nil || !strings.Contains(err.Error(), "text, json") {
+		t.Errorf("Expected invalid value error, got %v", err)
+	}
+	if err := cfg.Set("colour", "never"); err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, src, _ := cfg.Get("output"); v != "json" || src != SourceFile {
+		t.Errorf("Expected json from file, got %s from %s", v, src)
+	}
+	t.Setenv("OSSA_OUTPUT", "text")
+	if v, src, _ := cfg.Get("output"); v != "text" || src != SourceEnv {
+		t.Errorf("Expected text from env, got %s from %s", v, src)
+	}
+	t.Setenv("OSSA_OUTPUT", "yaml")
+	if _, _, err := cfg.Get("output"); err == nil || !strings.Contains(err.Error(), "OSSA_OUTPUT") {
+		t.Errorf("Expected invalid env error, got %v", err)
+	}
+	if v := cfg.Value("output"); v != "text" {
+		t.Errorf("Expected Value to fall back to the default, got %s", v)
+	}
+
+	if err := cfg.Set("registry_url", ""); err != nil {
+		t.Fatal(err)
+	}
+	if v, src, _ := cfg.Get("registry_url"); v != "" || src != SourceDefault {
+		t.Errorf("Expected registry_url unset, got %s from %s", v, src)
+	}
+
+	if err := os.WriteFile(path, []byte("offline: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, src, _ := cfg.Get("offline"); v != "true" || src != SourceFile {
+		t.Errorf("Expected offline true from file, got %s from %s", v, src)
+	}
+
+	if err := os.WriteFile(path, []byte("output: json\nfoo: bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `"foo"`) {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+
+	t.Setenv("OSSA_CONFIG", "/etc/ossa.yaml")
+	if p, _ := Path(); p != "/etc/ossa.yaml" {
+		t.Errorf("Expected OSSA_CONFIG path, got %s", p)
+	}
+}
@@ -0,0 +1,406 @@
+package gentypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Binding languages accepted by GenerateBindings.
+const (
+	LangTypeScript = "ts"
+	LangPython     = "python"
+)
+
+// Langs lists the binding languages.
+var Langs = []string{LangTypeScript, LangPython}
+
+// GenerateBindings returns type definitions for schema in lang: TypeScript
+// interfaces with matching Zod schemas, or Pydantic v2 models. Type names
+// are the same as in the Go output so the SDKs stay recognisably aligned.
+func GenerateBindings(schema []byte, lang string, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+	m, err := buildModel(schema, opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	switch lang {
+	case LangTypeScript:
+		return emitTypeScript(m), nil
+	case LangPython:
+		return emitPython(m), nil
+	}
+	return nil, fmt.Errorf("unsupported language: %s (want %s)", lang, strings.Join(Langs, " or "))
+}
+
+var jsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func emitTypeScript(m *model) []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by ossa gen bindings; DO NOT EDIT.\n\n")
+	b.WriteString("import { z } from \"zod\";\n")
+
+	for _, def := range m.types {
+		b.WriteString("\n")
+		writeJSDoc(&b, "", def.doc)
+		switch def.kind {
+		case defStruct:
+			fmt.Fprintf(&b, "export interface %s {\n", def.name)
+			for _, f := range def.fields {
+				writeJSDoc(&b, "  ", f.doc)
+				opt := "?"
+				if f.required {
+					opt = ""
+				}
+				fmt.Fprintf(&b, "  %s%s: %s;\n", jsKey(f.json), opt, tsFieldType(f))
+			}
+			if def.open {
+				b.WriteString("  [key: string]: unknown;\n")
+			}
+			b.WriteString("}\n\n")
+
+			fmt.Fprintf(&b, "export const %sSchema: z.ZodType<%s> = z.lazy(() =>\n  z\n    .object({\n", def.name, def.name)
+			for _, f := range def.fields {
+				expr := zodFieldType(f)
+				if !f.required {
+					expr += ".optional()"
+				}
+				fmt.Fprintf(&b, "      %s: %s,\n", jsKey(f.json), expr)
+			}
+			if def.open {
+				b.WriteString("    })\n    .passthrough(),\n);\n")
+			} else {
+				b.WriteString("    })\n    .strict(),\n);\n")
+			}
+		case defEnum:
+			quoted := make([]string, len(def.values))
+			for i, v := range def.values {
+				quoted[i] = jsonString(v)
+			}
+			fmt.Fprintf(&b, "export type %s = %s;\n\n", def.name, strings.Join(quoted, " | "))
+			fmt.Fprintf(&b, "export const %sSchema = z.enum([%s]);\n", def.name, strings.Join(quoted, ", "))
+		case defAlias:
+			fmt.Fprintf(&b, "export type %s = %s;\n\n", def.name, tsType(def.alias))
+			fmt.Fprintf(&b, "export const %sSchema: z.ZodType<%s> = z.lazy(() => %s);\n", def.name, def.name, zodType(def.alias, nil))
+		}
+	}
+	return b.Bytes()
+}
+
+func tsFieldType(f field) string {
+	if len(f.variants) > 0 {
+		alts := make([]string, len(f.variants))
+		for i, v := range f.variants {
+			alts[i] = v.typ
+		}
+		return strings.Join(alts, " | ")
+	}
+	return tsType(f.typ)
+}
+
+func tsType(t typeRef) string {
+	switch t.kind {
+	case refNamed:
+		return t.name
+	case refString:
+		return "string"
+	case refInt, refNumber:
+		return "number"
+	case refBool:
+		return "boolean"
+	case refArray:
+		elem := tsType(*t.elem)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case refMap:
+		return "Record<string, " + tsType(*t.elem) + ">"
+	case refObject:
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+func zodFieldType(f field) string {
+	if len(f.variants) > 0 {
+		alts := make([]string, len(f.variants))
+		for i, v := range f.variants {
+			alts[i] = v.typ + "Schema"
+		}
+		return "z.union([" + strings.Join(alts, ", ") + "])"
+	}
+	return zodType(f.typ, f.schema)
+}
+
+// zodType renders t as a Zod schema, with constraints from s when given.
+func zodType(t typeRef, s map[string]interface{}) string {
+	var expr string
+	switch t.kind {
+	case refNamed:
+		return t.name + "Schema"
+	case refString:
+		expr = "z.string()"
+		if p, ok := s["pattern"].(string); ok {
+			expr += ".regex(new RegExp(" + jsonString(p) + "))"
+		}
+		expr += zodBounds(s, "minLength", "maxLength")
+	case refInt:
+		expr = "z.number().int()" + zodBounds(s, "minimum", "maximum")
+	case refNumber:
+		expr = "z.number()" + zodBounds(s, "minimum", "maximum")
+	case refBool:
+		expr = "z.boolean()"
+	case refArray:
+		expr = "z.array(" + zodType(*t.elem, nil) + ")" + zodBounds(s, "minItems", "maxItems")
+	case refMap:
+		expr = "z.record(" + zodType(*t.elem, nil) + ")"
+	case refObject:
+		expr = "z.record(z.unknown())"
+	default:
+		expr = "z.unknown()"
+	}
+	return expr
+}
+
+func zodBounds(s map[string]interface{}, minKey, maxKey string) string {
+	var out string
+	if n, ok := number(s, minKey); ok {
+		out += ".min(" + n + ")"
+	}
+	if n, ok := number(s, maxKey); ok {
+		out += ".max(" + n + ")"
+	}
+	return out
+}
+
+func jsKey(name string) string {
+	if jsIdent.MatchString(name) {
+		return name
+	}
+	return jsonString(name)
+}
+
+func writeJSDoc(b *bytes.Buffer, indent, text string) {
+	if text == "" {
+		return
+	}
+	lines := wrap(strings.ReplaceAll(text, "*/", "* /"), 76)
+	if len(lines) == 1 {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, lines[0])
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
+
+var pythonReserved = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true, "def": true,
+	"del": true, "elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+	// BaseModel attributes a field must not shadow.
+	"copy": true, "dict": true, "json": true, "schema": true, "construct": true,
+	"validate": true, "fields": true,
+	// Builtins used in annotations, which a class attribute would shadow.
+	"str": true, "int": true, "float": true, "bool": true,
+}
+
+func emitPython(m *model) []byte {
+	var enums, models, aliases bytes.Buffer
+	var modelNames []string
+	for _, def := range m.types {
+		switch def.kind {
+		case defEnum:
+			fmt.Fprintf(&enums, "\n\nclass %s(str, Enum):\n", def.name)
+			writePyDocstring(&enums, def.doc)
+			seen := map[string]bool{}
+			for _, v := range def.values {
+				member := strings.ToUpper(snake(v))
+				switch {
+				case member == "":
+					member = "EMPTY"
+				case member[0] >= '0' && member[0] <= '9':
+					member = "V_" + member
+				}
+				candidate := member
+				for i := 2; seen[candidate]; i++ {
+					candidate = fmt.Sprintf("%s_%d", member, i)
+				}
+				seen[candidate] = true
+				fmt.Fprintf(&enums, "    %s = %s\n", candidate, jsonString(v))
+			}
+		case defStruct:
+			modelNames = append(modelNames, def.name)
+			fmt.Fprintf(&models, "\n\nclass %s(BaseModel):\n", def.name)
+			writePyDocstring(&models, def.doc)
+			extra := "forbid"
+			if def.open {
+				extra = "allow"
+			}
+			fmt.Fprintf(&models, "    model_config = ConfigDict(extra=%q, populate_by_name=True, protected_namespaces=())\n", extra)
+			if len(def.fields) > 0 {
+				models.WriteString("\n")
+			}
+			used := map[string]bool{}
+			for _, f := range def.fields {
+				name := snake(f.json)
+				if name == "" || name[0] >= '0' && name[0] <= '9' {
+					name = "x_" + name
+				}
+				if pythonReserved[name] {
+					name += "_"
+				}
+				for base, i := name, 2; used[name]; i++ {
+					name = fmt.Sprintf("%s_%d", base, i)
+				}
+				used[name] = true
+
+				for _, line := range wrap(f.doc, 74) {
+					if line != "" {
+						fmt.Fprintf(&models, "    # %s\n", line)
+					}
+				}
+				typ := pyFieldType(f)
+				var args []string
+				if name != f.json {
+					args = append(args, "alias="+jsonString(f.json))
+				}
+				args = append(args, pyConstraints(f)...)
+				if !f.required {
+					typ = "Optional[" + typ + "]"
+					args = append([]string{"default=None"}, args...)
+				}
+				switch {
+				case len(args) == 0:
+					fmt.Fprintf(&models, "    %s: %s\n", name, typ)
+				case len(args) == 1 && args[0] == "default=None":
+					fmt.Fprintf(&models, "    %s: %s = None\n", name, typ)
+				default:
+					fmt.Fprintf(&models, "    %s: %s = Field(%s)\n", name, typ, strings.Join(args, ", "))
+				}
+			}
+		case defAlias:
+			// Aliases are evaluated at import time, after the classes
+			// they may name.
+			fmt.Fprintf(&aliases, "\n%s = %s\n", def.name, pyType(def.alias))
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("# Code generated by ossa gen bindings; DO NOT EDIT.\n\n")
+	b.WriteString("from __future__ import annotations\n\n")
+	b.WriteString("from enum import Enum\n")
+	b.WriteString("from typing import Any, Dict, List, Optional, Union\n\n")
+	b.WriteString("from pydantic import BaseModel, ConfigDict, Field\n")
+	b.Write(enums.Bytes())
+	b.Write(models.Bytes())
+	if aliases.Len() > 0 {
+		b.WriteString("\n")
+		b.Write(aliases.Bytes())
+	}
+	if len(modelNames) > 0 {
+		b.WriteString("\n\n")
+		for _, name := range modelNames {
+			fmt.Fprintf(&b, "%s.model_rebuild()\n", name)
+		}
+	}
+	return b.Bytes()
+}
+
+func pyFieldType(f field) string {
+	if len(f.variants) > 0 {
+		alts := make([]string, len(f.variants))
+		for i, v := range f.variants {
+			alts[i] = v.typ
+		}
+		return "Union[" + strings.Join(alts, ", ") + "]"
+	}
+	return pyType(f.typ)
+}
+
+func pyType(t typeRef) string {
+	switch t.kind {
+	case refNamed:
+		return t.name
+	case refString:
+		return "str"
+	case refInt:
+		return "int"
+	case refNumber:
+		return "float"
+	case refBool:
+		return "bool"
+	case refArray:
+		return "List[" + pyType(*t.elem) + "]"
+	case refMap:
+		return "Dict[str, " + pyType(*t.elem) + "]"
+	case refObject:
+		return "Dict[str, Any]"
+	}
+	return "Any"
+}
+
+func pyConstraints(f field) []string {
+	var args []string
+	add := func(key, arg string) {
+		if n, ok := number(f.schema, key); ok {
+			args = append(args, arg+"="+n)
+		}
+	}
+	switch f.typ.kind {
+	case refString:
+		if p, ok := f.schema["pattern"].(string); ok {
+			args = append(args, "pattern="+jsonString(p))
+		}
+		add("minLength", "min_length")
+		add("maxLength", "max_length")
+	case refInt, refNumber:
+		add("minimum", "ge")
+		add("maximum", "le")
+	case refArray:
+		add("minItems", "min_length")
+		add("maxItems", "max_length")
+	}
+	return args
+}
+
+func writePyDocstring(b *bytes.Buffer, text string) {
+	if text == "" {
+		return
+	}
+	lines := wrap(strings.ReplaceAll(text, `"""`, `'''`), 72)
+	if len(lines) == 1 {
+		fmt.Fprintf(b, "    \"\"\"%s\"\"\"\n\n", strings.TrimRight(lines[0], `"`))
+		return
+	}
+	b.WriteString("    \"\"\"\n")
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+	}
+	b.WriteString("    \"\"\"\n\n")
+}
+
+// snake converts an identifier to snake_case.
+func snake(s string) string {
+	ws := words(s)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+	return strings.Join(ws, "_")
+}
+
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
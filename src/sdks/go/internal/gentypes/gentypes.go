@@ -0,0 +1,172 @@
+// Package gentypes generates types from an OSSA JSON Schema.
+//
+// It backs `ossa gen types`, which maintainers run when a new spec version
+// lands so that Go types follow the schema instead of being edited by hand,
+// and `ossa gen bindings`, which emits the same types for TypeScript (with
+// Zod schemas) and Python (Pydantic models). Objects become structs (nested
+// objects are hoisted into named types), string enums become named types
+// with constants, and schema constraints carry over as validate tags, Zod
+// checks or Pydantic field constraints.
+package gentypes
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// Options configures Generate.
+type Options struct {
+	// Package is the generated package name; empty means "ossa".
+	Package string
+	// Root names the type generated for the schema root; empty means
+	// "Manifest".
+	Root string
+}
+
+func (o Options) withDefaults() Options {
+	if o.Package == "" {
+		o.Package = "ossa"
+	}
+	if o.Root == "" {
+		o.Root = "Manifest"
+	}
+	return o
+}
+
+// Generate returns gofmt-formatted Go source for schema. Optional nested
+// structs are referenced by pointer and constraints become validate tags in
+// the go-playground/validator syntax.
+func Generate(schema []byte, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+	m, err := buildModel(schema, opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by ossa gen types; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	for _, def := range m.types {
+		writeGoDoc(&src, def)
+		switch def.kind {
+		case defStruct:
+			fmt.Fprintf(&src, "type %s struct {\n", def.name)
+			for _, f := range def.fields {
+				writeComment(&src, "\t// ", f.doc)
+				if p, ok := f.schema["pattern"].(string); ok {
+					fmt.Fprintf(&src, "\t// Pattern: %s\n", p)
+				}
+				if len(f.variants) > 0 {
+					alts := make([]string, len(f.variants))
+					for i, v := range f.variants {
+						alts[i] = fmt.Sprintf("%s when %s is %v", v.typ, v.field, v.value)
+					}
+					fmt.Fprintf(&src, "\t// One of: %s.\n", strings.Join(alts, "; "))
+				}
+				tag := f.json
+				if !f.required {
+					tag += ",omitempty"
+				}
+				tags := fmt.Sprintf("json:%q yaml:%q", tag, tag)
+				if v := validateTag(f.schema, f.required); v != "" {
+					tags += fmt.Sprintf(" validate:%q", v)
+				}
+				fmt.Fprintf(&src, "\t%s %s `%s`\n", f.name, goType(f.typ, f.required), tags)
+			}
+			src.WriteString("}\n\n")
+		case defEnum:
+			fmt.Fprintf(&src, "type %s string\n\n", def.name)
+			src.WriteString("const (\n")
+			for _, v := range def.values {
+				fmt.Fprintf(&src, "\t%s %s = %q\n", m.reserve(def.name+constSuffix(v)), def.name, v)
+			}
+			src.WriteString(")\n\n")
+			valid := m.reserve("Valid" + def.name)
+			fmt.Fprintf(&src, "// %s lists the values of %s.\n", valid, def.name)
+			fmt.Fprintf(&src, "var %s = map[%s]bool{\n", valid, def.name)
+			for _, v := range def.values {
+				fmt.Fprintf(&src, "\t%q: true,\n", v)
+			}
+			src.WriteString("}\n\n")
+		case defAlias:
+			fmt.Fprintf(&src, "type %s %s\n\n", def.name, goType(def.alias, true))
+		}
+	}
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go source: %w", err)
+	}
+	return formatted, nil
+}
+
+func writeGoDoc(b *bytes.Buffer, def *typeDef) {
+	if def.doc != "" {
+		writeComment(b, "// ", def.name+": "+def.doc)
+	} else {
+		fmt.Fprintf(b, "// %s is generated from %s.\n", def.name, def.origin)
+	}
+}
+
+func goType(t typeRef, required bool) string {
+	switch t.kind {
+	case refNamed:
+		if t.object && !required {
+			return "*" + t.name
+		}
+		return t.name
+	case refString:
+		return "string"
+	case refInt:
+		return "int"
+	case refNumber:
+		return "float64"
+	case refBool:
+		return "bool"
+	case refArray:
+		return "[]" + goType(*t.elem, true)
+	case refMap:
+		return "map[string]" + goType(*t.elem, true)
+	case refObject:
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// validateTag renders schema constraints as a validator tag.
+func validateTag(s map[string]interface{}, required bool) string {
+	var rules []string
+	if required {
+		rules = append(rules, "required")
+	} else {
+		rules = append(rules, "omitempty")
+	}
+	if enum := stringEnum(s); len(enum) > 0 && !strings.ContainsAny(strings.Join(enum, ""), " ,|") {
+		rules = append(rules, "oneof="+strings.Join(enum, " "))
+	}
+	for _, b := range []struct{ key, rule string }{
+		{"minimum", "min"}, {"maximum", "max"},
+		{"minLength", "min"}, {"maxLength", "max"},
+		{"minItems", "min"}, {"maxItems", "max"},
+	} {
+		if n, ok := number(s, b.key); ok {
+			rules = append(rules, b.rule+"="+n)
+		}
+	}
+	if len(rules) == 1 && !required {
+		return ""
+	}
+	return strings.Join(rules, ",")
+}
+
+// writeComment writes text as comment lines wrapped at 76 columns.
+func writeComment(b *bytes.Buffer, prefix, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range wrap(text, 76) {
+		b.WriteString(strings.TrimRight(prefix+line, " ") + "\n")
+	}
+}
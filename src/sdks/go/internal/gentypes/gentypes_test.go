@@ -0,0 +1,189 @@
+package gentypes
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "types.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v", err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("ossa", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated source does not type-check: %v", err)
+	}
+	return pkg
+}
+
+func TestGenerate(t *testing.T) {
+	schema := []byte(`{
+  "type": "object",
+  "required": ["apiVersion", "kind"],
+  "properties": {
+    "apiVersion": {"type": "string", "pattern": "^ossa/v"},
+    "kind": {"type": "string", "enum": ["Agent", "Task"]},
+    "spec": {}
+  },
+  "allOf": [{"if": {"properties": {"kind": {"const": "Agent"}}}, "then": {"properties": {"spec": {"$ref": "#/definitions/AgentSpec"}}}}],
+  "definitions": {
+    "AgentSpec": {
+      "type": "object",
+      "required": ["llm"],
+      "properties": {
+        "llm": {"$ref": "#/definitions/LLMConfig"},
+        "tools": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string", "maxLength": 64}}}},
+        "max_turns": {"type": "integer", "minimum": 1, "maximum": 50},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    },
+    "LLMConfig": {
+      "type": "object",
+      "properties": {"provider": {"type": "string", "enum": ["openai", "anthropic"]}, "fallback": {"$ref": "#/definitions/LLMConfig"}}
+    }
+  }
+}`)
+	src, err := Generate(schema, Options{})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	pkg := typeCheck(t, src)
+	for _, name := range []string{"Manifest", "ManifestKind", "ManifestKindAgent", "ValidManifestKind", "AgentSpec", "AgentSpecTool", "LLMConfig", "LLMConfigProviderOpenai"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("Expected %s in generated package", name)
+		}
+	}
+
+	out := string(src)
+	for _, want := range []string{
+		"APIVersion string       `json:\"apiVersion\" yaml:\"apiVersion\" validate:\"required\"`",
+		"Kind       ManifestKind `json:\"kind\" yaml:\"kind\" validate:\"required,oneof=Agent Task\"`",
+		"// One of: AgentSpec when kind is Agent.",
+		"LLM      LLMConfig",
+		"Fallback *LLMConfig",
+		"MaxTurns int",
+		"validate:\"omitempty,min=1,max=50\"",
+		"Labels   map[string]string",
+		"Tools    []AgentSpecTool",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerateEmbeddedSchema(t *testing.T) {
+	src, err := Generate(ossa.EmbeddedSchema(), Options{Package: "spec"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	pkg := typeCheck(t, src)
+	for _, name := range []string{"Manifest", "AgentSpec", "TaskSpec", "WorkflowSpec", "Metadata"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("Expected %s in generated package", name)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"apiVersion":     "APIVersion",
+		"max_tokens":     "MaxTokens",
+		"x-ossa-in":      "XOssaIn",
+		"llm":            "LLM",
+		"requestURL":     "RequestURL",
+		"HTTPEndpoint":   "HTTPEndpoint",
+		"3d":             "X3d",
+		"openai_swarm":   "OpenaiSwarm",
+		"semanticKernel": "SemanticKernel",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateBindings(t *testing.T) {
+	schema := []byte(`{
+  "type": "object",
+  "required": ["kind"],
+  "properties": {
+    "kind": {"type": "string", "enum": ["Agent", "Task"]},
+    "spec": {},
+    "x-notes": {"type": "string", "maxLength": 10}
+  },
+  "allOf": [{"if": {"properties": {"kind": {"const": "Agent"}}}, "then": {"properties": {"spec": {"$ref": "#/definitions/AgentSpec"}}}}],
+  "definitions": {
+    "AgentSpec": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["role"],
+      "properties": {
+        "role": {"type": "string", "pattern": "^[a-z]+$"},
+        "maxTurns": {"type": "integer", "minimum": 1},
+        "from": {"type": "array", "items": {"type": "string"}, "minItems": 1}
+      }
+    }
+  }
+}`)
+
+	ts, err := GenerateBindings(schema, LangTypeScript, Options{})
+	if err != nil {
+		t.Fatalf("GenerateBindings(ts) failed: %v", err)
+	}
+	for _, want := range []string{
+		`import { z } from "zod";`,
+		"export interface Manifest {",
+		"  kind: ManifestKind;",
+		"  spec?: AgentSpec;",
+		`  "x-notes"?: string;`,
+		"  [key: string]: unknown;",
+		`export type ManifestKind = "Agent" | "Task";`,
+		`export const ManifestKindSchema = z.enum(["Agent", "Task"]);`,
+		`role: z.string().regex(new RegExp("^[a-z]+$")),`,
+		"maxTurns: z.number().int().min(1).optional(),",
+		"from: z.array(z.string()).min(1).optional(),",
+		".strict(),",
+	} {
+		if !strings.Contains(string(ts), want) {
+			t.Errorf("Expected %q in TypeScript:\n%s", want, ts)
+		}
+	}
+
+	py, err := GenerateBindings(schema, LangPython, Options{})
+	if err != nil {
+		t.Fatalf("GenerateBindings(python) failed: %v", err)
+	}
+	for _, want := range []string{
+		"from pydantic import BaseModel, ConfigDict, Field",
+		"class ManifestKind(str, Enum):\n    AGENT = \"Agent\"",
+		"class Manifest(BaseModel):",
+		`model_config = ConfigDict(extra="allow"`,
+		"spec: Optional[Union[AgentSpec]] = None",
+		`x_notes: Optional[str] = Field(default=None, alias="x-notes", max_length=10)`,
+		`model_config = ConfigDict(extra="forbid"`,
+		`role: str = Field(pattern="^[a-z]+$")`,
+		`max_turns: Optional[int] = Field(default=None, alias="maxTurns", ge=1)`,
+		`from_: Optional[List[str]] = Field(default=None, alias="from", min_length=1)`,
+		"AgentSpec.model_rebuild()",
+	} {
+		if !strings.Contains(string(py), want) {
+			t.Errorf("Expected %q in Python:\n%s", want, py)
+		}
+	}
+
+	if _, err := GenerateBindings(schema, "rust", Options{}); err == nil {
+		t.Error("Expected unsupported language error")
+	}
+}
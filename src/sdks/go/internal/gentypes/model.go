@@ -0,0 +1,429 @@
+package gentypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The builder turns a schema into language-neutral type definitions that
+// the Go, TypeScript and Python emitters render.
+
+type defKind int
+
+const (
+	defStruct defKind = iota
+	defEnum
+	defAlias
+)
+
+type typeDef struct {
+	name   string
+	doc    string
+	origin string
+	kind   defKind
+	fields []field  // defStruct
+	open   bool     // defStruct: additionalProperties is not false
+	values []string // defEnum
+	alias  typeRef  // defAlias
+}
+
+type field struct {
+	json     string
+	name     string
+	doc      string
+	required bool
+	typ      typeRef
+	// schema is the property schema, read for constraints.
+	schema map[string]interface{}
+	// variants are conditional alternatives, e.g. spec by kind.
+	variants []variant
+}
+
+// variant is a type a field takes when another field has a given value.
+type variant struct {
+	typ   string
+	field string
+	value interface{}
+}
+
+type refKind int
+
+const (
+	refAny refKind = iota
+	refNamed
+	refString
+	refInt
+	refNumber
+	refBool
+	refArray
+	refMap
+	refObject // free-form object
+)
+
+type typeRef struct {
+	kind refKind
+	name string   // refNamed
+	elem *typeRef // refArray, refMap
+	// object reports whether a refNamed type is a struct.
+	object bool
+}
+
+type model struct {
+	root  string
+	types []*typeDef
+	// taken holds every type name, so emitters can pick free names for
+	// constants.
+	taken map[string]bool
+}
+
+// reserve returns name, or name with a numeric suffix if already taken.
+func (m *model) reserve(name string) string {
+	candidate := name
+	for i := 2; m.taken[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	m.taken[candidate] = true
+	return candidate
+}
+
+func buildModel(schema []byte, rootName string) (*model, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	b := &builder{defs: map[string]map[string]interface{}{}, names: map[string]string{}, taken: map[string]bool{}}
+	if defs, ok := root["definitions"].(map[string]interface{}); ok {
+		for name, def := range defs {
+			if m, ok := def.(map[string]interface{}); ok {
+				b.defs[name] = m
+			}
+		}
+	}
+	// Reserve definition names first so hoisted types never take them.
+	b.taken[rootName] = true
+	for _, name := range sortedKeys(b.defs) {
+		b.names[name] = b.reserve(goName(name))
+	}
+
+	b.buildRoot(rootName, root)
+	for _, name := range sortedKeys(b.defs) {
+		b.buildNamed(b.names[name], b.defs[name], "definitions/"+name)
+	}
+	return &model{root: rootName, types: b.types, taken: b.taken}, nil
+}
+
+type builder struct {
+	defs  map[string]map[string]interface{}
+	names map[string]string // definition name -> type name
+	taken map[string]bool
+	types []*typeDef
+	// pending holds hoisted nested types, added after their parent.
+	pending []pendingType
+}
+
+type pendingType struct {
+	name   string
+	schema map[string]interface{}
+	origin string
+}
+
+func (b *builder) reserve(name string) string {
+	return (&model{taken: b.taken}).reserve(name)
+}
+
+func (b *builder) buildRoot(name string, root map[string]interface{}) {
+	// The OSSA root picks spec by kind through allOf if/then branches;
+	// record the variants since none of the targets has a tagged union.
+	variants := map[string][]variant{}
+	for _, branch := range asSlice(root["allOf"]) {
+		br, _ := branch.(map[string]interface{})
+		cond, _ := br["if"].(map[string]interface{})
+		then, _ := br["then"].(map[string]interface{})
+		condProps, _ := cond["properties"].(map[string]interface{})
+		thenProps, _ := then["properties"].(map[string]interface{})
+		for field, c := range condProps {
+			want, _ := c.(map[string]interface{})["const"]
+			for prop, s := range thenProps {
+				if t := b.refName(s); t != "" {
+					variants[prop] = append(variants[prop], variant{typ: t, field: field, value: want})
+				}
+			}
+		}
+	}
+	b.buildStruct(name, root, "root", variants)
+	b.flush()
+}
+
+func (b *builder) buildNamed(name string, schema map[string]interface{}, origin string) {
+	switch {
+	case isObject(schema):
+		b.buildStruct(name, schema, origin, nil)
+	case len(stringEnum(schema)) > 0:
+		b.buildEnum(name, schema, origin)
+	default:
+		def := b.add(name, schema, origin, defAlias)
+		def.alias = b.ref(name, schema)
+	}
+	b.flush()
+}
+
+func (b *builder) flush() {
+	for len(b.pending) > 0 {
+		p := b.pending[0]
+		b.pending = b.pending[1:]
+		if isObject(p.schema) {
+			b.buildStruct(p.name, p.schema, p.origin, nil)
+		} else {
+			b.buildEnum(p.name, p.schema, p.origin)
+		}
+	}
+}
+
+func (b *builder) add(name string, schema map[string]interface{}, origin string, kind defKind) *typeDef {
+	desc, _ := schema["description"].(string)
+	def := &typeDef{name: name, doc: desc, origin: origin, kind: kind}
+	b.types = append(b.types, def)
+	return def
+}
+
+func (b *builder) buildStruct(name string, schema map[string]interface{}, origin string, variants map[string][]variant) {
+	def := b.add(name, schema, origin, defStruct)
+	if ap, ok := schema["additionalProperties"].(bool); !ok || ap {
+		def.open = true
+	}
+	props, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	for _, r := range asSlice(schema["required"]) {
+		if s, ok := r.(string); ok {
+			required[s] = true
+		}
+	}
+
+	names := map[string]bool{}
+	for _, prop := range sortedKeys(props) {
+		child, _ := props[prop].(map[string]interface{})
+		fieldName := goName(prop)
+		for i := 2; names[fieldName]; i++ {
+			fieldName = goName(prop) + strconv.Itoa(i)
+		}
+		names[fieldName] = true
+
+		desc, _ := child["description"].(string)
+		def.fields = append(def.fields, field{
+			json:     prop,
+			name:     fieldName,
+			doc:      desc,
+			required: required[prop],
+			typ:      b.ref(name+fieldName, child),
+			schema:   child,
+			variants: variants[prop],
+		})
+	}
+}
+
+func (b *builder) buildEnum(name string, schema map[string]interface{}, origin string) {
+	def := b.add(name, schema, origin, defEnum)
+	def.values = stringEnum(schema)
+}
+
+// refName returns the type name a $ref points at, or "".
+func (b *builder) refName(s interface{}) string {
+	m, _ := s.(map[string]interface{})
+	ref, _ := m["$ref"].(string)
+	if name := strings.TrimPrefix(ref, "#/definitions/"); name != ref {
+		return b.names[name]
+	}
+	return ""
+}
+
+// ref maps a property schema to a type reference, hoisting nested objects
+// and enums into named types called hint.
+func (b *builder) ref(hint string, s map[string]interface{}) typeRef {
+	if t := b.refName(s); t != "" {
+		ref := strings.TrimPrefix(s["$ref"].(string), "#/definitions/")
+		return typeRef{kind: refNamed, name: t, object: isObject(b.defs[ref])}
+	}
+	if len(asSlice(s["oneOf"])) > 0 || len(asSlice(s["anyOf"])) > 0 {
+		return typeRef{kind: refAny}
+	}
+	if len(stringEnum(s)) > 0 {
+		name := b.reserve(hint)
+		b.pending = append(b.pending, pendingType{name: name, schema: s, origin: "an inline enum"})
+		return typeRef{kind: refNamed, name: name}
+	}
+
+	typ, _ := s["type"].(string)
+	switch typ {
+	case "string":
+		return typeRef{kind: refString}
+	case "integer":
+		return typeRef{kind: refInt}
+	case "number":
+		return typeRef{kind: refNumber}
+	case "boolean":
+		return typeRef{kind: refBool}
+	case "array":
+		elem := typeRef{kind: refAny}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			elem = b.ref(singular(hint), items)
+		}
+		return typeRef{kind: refArray, elem: &elem}
+	case "object", "":
+		if isObject(s) {
+			name := b.reserve(hint)
+			b.pending = append(b.pending, pendingType{name: name, schema: s, origin: "an inline object"})
+			return typeRef{kind: refNamed, name: name, object: true}
+		}
+		if extra, ok := s["additionalProperties"].(map[string]interface{}); ok {
+			elem := b.ref(hint+"Value", extra)
+			return typeRef{kind: refMap, elem: &elem}
+		}
+		if typ == "object" {
+			return typeRef{kind: refObject}
+		}
+	}
+	return typeRef{kind: refAny}
+}
+
+func isObject(s map[string]interface{}) bool {
+	if s == nil {
+		return false
+	}
+	props, _ := s["properties"].(map[string]interface{})
+	return len(props) > 0
+}
+
+func stringEnum(s map[string]interface{}) []string {
+	var out []string
+	for _, v := range asSlice(s["enum"]) {
+		str, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		out = append(out, str)
+	}
+	return out
+}
+
+func number(s map[string]interface{}, key string) (string, bool) {
+	n, ok := s[key].(float64)
+	if !ok {
+		return "", false
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64), true
+}
+
+// initialisms are kept upper-case in Go names, as in types.go.
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "CSRF": true, "DNS": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"JSON": true, "JWT": true, "LLM": true, "MCP": true, "SQL": true, "SSH": true, "TLS": true,
+	"TTL": true, "UI": true, "URI": true, "URL": true, "UUID": true, "YAML": true,
+}
+
+// goName converts snake_case, kebab-case and camelCase to an exported Go
+// identifier.
+func goName(s string) string {
+	name := camel(s)
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+func camel(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		rs := []rune(w)
+		b.WriteRune(unicode.ToUpper(rs[0]))
+		b.WriteString(string(rs[1:]))
+	}
+	return b.String()
+}
+
+// words splits an identifier at separators and case changes.
+func words(s string) []string {
+	var out []string
+	var cur []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(cur) > 0 {
+				out = append(out, string(cur))
+				cur = nil
+			}
+			continue
+		case unicode.IsUpper(r) && len(cur) > 0 && (unicode.IsLower(cur[len(cur)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			out = append(out, string(cur))
+			cur = nil
+		}
+		cur = append(cur, r)
+	}
+	if len(cur) > 0 {
+		out = append(out, string(cur))
+	}
+	return out
+}
+
+func constSuffix(v string) string {
+	if s := camel(v); s != "" {
+		return s
+	}
+	return "Empty"
+}
+
+// singular names array element types: Tools -> Tool, Entries -> Entry.
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return strings.TrimSuffix(s, "ies") + "y"
+	case strings.HasSuffix(s, "ses"), strings.HasSuffix(s, "ss"):
+		return s + "Item"
+	case strings.HasSuffix(s, "s"):
+		return strings.TrimSuffix(s, "s")
+	}
+	return s + "Item"
+}
+
+// wrap splits text into lines of at most width columns.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,296 @@
+// Package selfupdate backs ossa upgrade: it finds the latest release,
+// downloads the binary for this platform, verifies it and replaces the
+// running executable.
+//
+// A release publishes one binary per platform named ossa_<os>_<arch> (with
+// .exe on Windows), a checksums.txt listing "<sha256>  <name>" per file
+// after a first "version <version>" line, and checksums.txt.sig, the base64
+// Ed25519 signature of checksums.txt. The signed version must be the
+// release's, so an older signed release cannot be served as a newer one.
+// The release endpoint returns GitHub-style release JSON.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/semver"
+)
+
+// DefaultEndpoint is the latest-release URL used when none is configured.
+const DefaultEndpoint = "https://api.github.com/repos/blueflyio/openstandardagents/releases/latest"
+
+// PublicKey is the base64 Ed25519 key release checksums are signed with.
+// It is set at build time:
+//
+//	-ldflags "-X github.com/blueflyio/ossa-go/internal/selfupdate.PublicKey=..."
+var PublicKey string
+
+// ErrNoPublicKey is returned by Download when the binary was built without
+// a release signing key, so downloads cannot be verified.
+var ErrNoPublicKey = errors.New("this build has no release signing key; download and verify the release manually")
+
+// maxDownload bounds asset downloads.
+const maxDownload = 256 << 20
+
+// defaultClient is used when an Updater has no Client; its timeout covers
+// a whole download.
+var defaultClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Release is a published release.
+type Release struct {
+	Version string
+	// Assets maps file names to download URLs.
+	Assets map[string]string
+}
+
+// Updater checks for and installs releases.
+type Updater struct {
+	Endpoint string
+	// PublicKey verifies checksums.txt; nil uses the build's PublicKey.
+	PublicKey ed25519.PublicKey
+	// Client fetches releases; nil means a client with a 5 minute timeout.
+	Client *http.Client
+	// OS and Arch select the asset; empty means the running platform.
+	OS, Arch string
+}
+
+// Latest fetches the latest release from the endpoint.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	data, err := u.get(ctx, u.endpoint(), 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for releases: %w", err)
+	}
+	var body struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if body.TagName == "" {
+		return nil, fmt.Errorf("release has no tag_name")
+	}
+	rel := &Release{Version: strings.TrimPrefix(body.TagName, "v"), Assets: map[string]string{}}
+	for _, a := range body.Assets {
+		rel.Assets[a.Name] = a.URL
+	}
+	return rel, nil
+}
+
+// AssetName is the binary asset for a platform.
+func AssetName(goos, goarch string) string {
+	name := "ossa_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Download fetches this platform's binary from rel and verifies it: the
+// signature of checksums.txt against the public key, its version against
+// rel's, then the binary's SHA-256 against its checksums.txt entry.
+func (u *Updater) Download(ctx context.Context, rel *Release) ([]byte, error) {
+	key, err := u.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	name := AssetName(u.platform())
+	fetch := func(asset string, limit int64) ([]byte, error) {
+		url, ok := rel.Assets[asset]
+		if !ok {
+			return nil, fmt.Errorf("release %s has no %s", rel.Version, asset)
+		}
+		data, err := u.get(ctx, url, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", asset, err)
+		}
+		return data, nil
+	}
+
+	sums, err := fetch("checksums.txt", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := fetch("checksums.txt.sig", 1<<10)
+	if err != nil {
+		return nil, err
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(key, sums, rawSig) {
+		return nil, fmt.Errorf("checksums.txt signature does not verify")
+	}
+	if v := signedVersion(sums); strings.TrimPrefix(v, "v") != rel.Version {
+		return nil, fmt.Errorf("checksums.txt is signed for version %q, not %s", v, rel.Version)
+	}
+	want, err := checksum(sums, name)
+	if err != nil {
+		return nil, err
+	}
+
+	bin, err := fetch(name, maxDownload)
+	if err != nil {
+		return nil, err
+	}
+	got := sha256.Sum256(bin)
+	if hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s", name)
+	}
+	return bin, nil
+}
+
+// signedVersion returns the version of a checksums.txt file's first line.
+func signedVersion(sums []byte) string {
+	line, _, _ := bytes.Cut(sums, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) != 2 || fields[0] != "version" {
+		return ""
+	}
+	return fields[1]
+}
+
+// checksum finds name's SHA-256 in a checksums.txt file.
+func checksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("checksums.txt has no entry for %s", name)
+}
+
+// Replace atomically replaces the executable at path with data: it writes
+// a temporary file next to it and renames it into place, so a failure
+// leaves the old binary intact. On Windows, where a running executable
+// cannot be overwritten, the old binary is first moved to path.old.
+func Replace(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ossa-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return fmt.Errorf("failed to move old binary aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+	return nil
+}
+
+// Newer reports whether latest is a later semantic version than current.
+// A current version that does not parse, such as a dev build, is older
+// than any release.
+func Newer(current, latest string) bool {
+	l, err := semver.Parse(latest)
+	if err != nil {
+		return false
+	}
+	c, err := semver.Parse(current)
+	if err != nil {
+		return true
+	}
+	return l.Compare(c) > 0
+}
+
+func (u *Updater) endpoint() string {
+	if u.Endpoint != "" {
+		return u.Endpoint
+	}
+	return DefaultEndpoint
+}
+
+func (u *Updater) platform() (string, string) {
+	goos, goarch := u.OS, u.Arch
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return goos, goarch
+}
+
+func (u *Updater) publicKey() (ed25519.PublicKey, error) {
+	if u.PublicKey != nil {
+		return u.PublicKey, nil
+	}
+	if PublicKey == "" {
+		return nil, ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key")
+	}
+	return key, nil
+}
+
+func (u *Updater) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := u.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response over %d bytes", url, limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,158 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newReleaseServer serves release 1.2.0 with a checksums.txt for bin
+// signed for version.
+func newReleaseServer(t *testing.T, priv ed25519.PrivateKey, bin []byte, version string) *httptest.Server {
+	t.Helper()
+	name := AssetName("linux", "amd64")
+	sum := sha256.Sum256(bin)
+	sums := []byte("version " + version + "\n" + hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	files := map[string][]byte{
+		name:                bin,
+		"checksums.txt":     sums,
+		"checksums.txt.sig": []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums))),
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest" {
+			var assets []map[string]string
+			for n := range files {
+				assets = append(assets, map[string]string{"name": n, "browser_download_url": srv.URL + "/dl/" + n})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"tag_name": "v1.2.0", "assets": assets})
+			return
+		}
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/dl/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestUpgrade(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := []byte("#!/bin/sh\necho new\n")
+	srv := newReleaseServer(t, priv, bin, "1.2.0")
+	u := &Updater{Endpoint: srv.URL + "/latest", PublicKey: pub, OS: "linux", Arch: "amd64"}
+
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != "1.2.0" || len(rel.Assets) != 3 {
+		t.Fatalf("Unexpected release: %+v", rel)
+	}
+	got, err := u.Download(context.Background(), rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ossa")
+	if err := os.WriteFile(path, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(path, got); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != string(bin) || info.Mode().Perm() != 0o755 {
+		t.Errorf("Unexpected binary %q (mode %v)", data, info.Mode())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no leftover temporary files, got %d entries", len(entries))
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	u.PublicKey = other
+	if _, err := u.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("Expected signature failure, got %v", err)
+	}
+
+	u.PublicKey = pub
+	u.Arch = "arm64"
+	if _, err := u.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "no entry") {
+		t.Errorf("Expected missing checksum entry, got %v", err)
+	}
+
+	u = &Updater{Endpoint: srv.URL + "/latest"}
+	if _, err := u.Download(context.Background(), rel); !errors.Is(err, ErrNoPublicKey) {
+		t.Errorf("Expected ErrNoPublicKey, got %v", err)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := newReleaseServer(t, priv, []byte("good"), "1.2.0")
+	u := &Updater{Endpoint: srv.URL + "/latest", PublicKey: pub, OS: "linux", Arch: "amd64"}
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("evil")) }))
+	defer tampered.Close()
+	rel.Assets[AssetName("linux", "amd64")] = tampered.URL
+	if _, err := u.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestReplayedRelease(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	srv := newReleaseServer(t, priv, []byte("old"), "1.1.0")
+	u := &Updater{Endpoint: srv.URL + "/latest", PublicKey: pub, OS: "linux", Arch: "amd64"}
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "signed for version") {
+		t.Errorf("Expected a release signed for another version to be refused, got %v", err)
+	}
+}
+
+func TestNewer(t *testing.T) {
+	for _, tc := range []struct {
+		current, latest string
+		want            bool
+	}{
+		{"1.0.0", "1.0.1", true},
+		{"v1.2.0", "1.10.0", true},
+		{"1.2.0", "1.2.0", false},
+		{"2.0.0", "1.9.9", false},
+		{"1.2.0-rc.1", "1.2.0", true},
+		{"1.2.0", "1.2.0-rc.1", false},
+		{"1.2.0-rc.9", "1.2.0-rc.10", true},
+		{"1.2.0-rc.10", "1.2.0-rc.9", false},
+		{"0.0.0-dev", "0.1.0", true},
+		{"dev", "0.1.0", true},
+		{"1.0.0", "nightly", false},
+	} {
+		if got := Newer(tc.current, tc.latest); got != tc.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tc.current, tc.latest, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+// Package intoto verifies in-toto supply chain metadata: a layout, signed
+// by the project's owners, names the steps of the chain (authoring a
+// manifest, reviewing it, signing a release), who may perform each and
+// which artifacts each may consume and produce; each functionary records
+// what they did in a signed link. Verify checks the links satisfy the
+// layout, proving the chain of custody of the final artifacts.
+//
+// Metadata follows the in-toto 0.9 JSON format with ed25519 keys: an
+// envelope of {"signed": ..., "signatures": [{"keyid", "sig"}]}, hex
+// signatures over the canonical JSON of signed, and key IDs the sha256 of
+// a key's canonical JSON. Inspections, which run commands at
+// verification time, are not supported; layouts with any are rejected.
+package intoto
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Envelope is signed metadata: a layout or a link.
+type Envelope struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []Signature     `json:"signatures"`
+}
+
+// Signature is a hex ed25519 signature by the key with ID KeyID.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Key is a public key as layouts list them.
+type Key struct {
+	KeyType string `json:"keytype"`
+	Scheme  string `json:"scheme"`
+	KeyVal  KeyVal `json:"keyval"`
+}
+
+// KeyVal holds a key's hex public half.
+type KeyVal struct {
+	Public string `json:"public"`
+}
+
+// NewKey returns the layout form of an ed25519 public key.
+func NewKey(pub ed25519.PublicKey) Key {
+	return Key{KeyType: "ed25519", Scheme: "ed25519", KeyVal: KeyVal{Public: hex.EncodeToString(pub)}}
+}
+
+// ID returns the key's ID, the hex sha256 of its canonical JSON.
+func (k Key) ID() string {
+	data, _ := json.Marshal(k)
+	canonical, _ := ossa.CanonicalJSON(data)
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// PublicKey decodes the key.
+func (k Key) PublicKey() (ed25519.PublicKey, error) {
+	if k.KeyType != "ed25519" {
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+	raw, err := hex.DecodeString(k.KeyVal.Public)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Layout is the owners' definition of a supply chain.
+type Layout struct {
+	Type    string         `json:"_type"`
+	Expires string         `json:"expires"`
+	Readme  string         `json:"readme,omitempty"`
+	Keys    map[string]Key `json:"keys"`
+	Steps   []Step         `json:"steps"`
+	Inspect []interface{}  `json:"inspect"`
+}
+
+// Step is one step of a layout: who performs it and the rules the
+// artifacts it consumes and produces must follow.
+type Step struct {
+	Type              string     `json:"_type"`
+	Name              string     `json:"name"`
+	ExpectedMaterials [][]string `json:"expected_materials"`
+	ExpectedProducts  [][]string `json:"expected_products"`
+	PubKeys           []string   `json:"pubkeys"`
+	ExpectedCommand   []string   `json:"expected_command"`
+	Threshold         int        `json:"threshold"`
+}
+
+// Link is a functionary's record of performing a step.
+type Link struct {
+	Type        string                 `json:"_type"`
+	Name        string                 `json:"name"`
+	Materials   map[string]Hashes      `json:"materials"`
+	Products    map[string]Hashes      `json:"products"`
+	ByProducts  map[string]interface{} `json:"byproducts"`
+	Command     []string               `json:"command"`
+	Environment map[string]interface{} `json:"environment"`
+}
+
+// Hashes are an artifact's digests by algorithm; sha256 is checked.
+type Hashes map[string]string
+
+// NewLink returns the link of step with the given materials and
+// products, each a path and its content.
+func NewLink(step string, materials, products map[string][]byte) *Link {
+	digest := func(files map[string][]byte) map[string]Hashes {
+		out := map[string]Hashes{}
+		for p, data := range files {
+			sum := sha256.Sum256(data)
+			out[p] = Hashes{"sha256": hex.EncodeToString(sum[:])}
+		}
+		return out
+	}
+	return &Link{
+		Type: "link", Name: step,
+		Materials: digest(materials), Products: digest(products),
+		ByProducts: map[string]interface{}{}, Command: []string{}, Environment: map[string]interface{}{},
+	}
+}
+
+// LinkName is the conventional file name of step's link by key keyID.
+func LinkName(step, keyID string) string {
+	if len(keyID) > 8 {
+		keyID = keyID[:8]
+	}
+	return step + "." + keyID + ".link"
+}
+
+// Sign returns v, a *Layout or *Link, in an envelope signed by keys.
+func Sign(v interface{}, keys ...ed25519.PrivateKey) (*Envelope, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := ossa.CanonicalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	e := &Envelope{Signed: signed, Signatures: []Signature{}}
+	for _, key := range keys {
+		e.Signatures = append(e.Signatures, Signature{
+			KeyID: NewKey(key.Public().(ed25519.PublicKey)).ID(),
+			Sig:   hex.EncodeToString(ed25519.Sign(key, signed)),
+		})
+	}
+	return e, nil
+}
+
+// Parse reads an envelope.
+func Parse(data []byte) (*Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid in-toto metadata: %w", err)
+	}
+	if len(e.Signed) == 0 {
+		return nil, fmt.Errorf("invalid in-toto metadata: no signed section")
+	}
+	return &e, nil
+}
+
+// signedBy reports whether the envelope has a valid signature by key.
+func (e *Envelope) signedBy(key Key) bool {
+	pub, err := key.PublicKey()
+	if err != nil {
+		return false
+	}
+	signed, err := ossa.CanonicalJSON(e.Signed)
+	if err != nil {
+		return false
+	}
+	id := key.ID()
+	for _, s := range e.Signatures {
+		sig, err := hex.DecodeString(s.Sig)
+		if s.KeyID == id && err == nil && ed25519.Verify(pub, signed, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// decode unmarshals the signed section and checks its _type.
+func (e *Envelope) decode(v interface{}, want string) error {
+	var head struct {
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(e.Signed, &head); err != nil {
+		return fmt.Errorf("invalid in-toto metadata: %w", err)
+	}
+	if head.Type != want {
+		return fmt.Errorf("in-toto metadata is a %q, want a %s", head.Type, want)
+	}
+	return json.Unmarshal(e.Signed, v)
+}
+
+// Functionary is a key that performed a step.
+type Functionary struct {
+	Step  string
+	KeyID string
+}
+
+// Result is a verified supply chain.
+type Result struct {
+	Layout *Layout
+	// Links is the accepted link of each step.
+	Links map[string]*Link
+	// Functionaries are the keys whose links met each step's threshold,
+	// in layout order.
+	Functionaries []Functionary
+}
+
+// Verify checks a layout against its owners' keys and now, and the links
+// against the layout: each step needs links, agreeing on their artifacts,
+// from at least its threshold of its functionaries, and every link's
+// materials and products must follow the step's artifact rules.
+func Verify(layout *Envelope, owners []ed25519.PublicKey, links []*Envelope, now time.Time) (*Result, error) {
+	if len(owners) == 0 {
+		return nil, fmt.Errorf("no layout keys to verify against")
+	}
+	for _, owner := range owners {
+		if !layout.signedBy(NewKey(owner)) {
+			return nil, fmt.Errorf("layout is not signed by key %s", NewKey(owner).ID())
+		}
+	}
+	var l Layout
+	if err := layout.decode(&l, "layout"); err != nil {
+		return nil, err
+	}
+	expires, err := time.Parse(time.RFC3339, l.Expires)
+	if err != nil {
+		return nil, fmt.Errorf("layout has invalid expiry %q", l.Expires)
+	}
+	if now.After(expires) {
+		return nil, fmt.Errorf("layout expired %s", l.Expires)
+	}
+	if len(l.Inspect) > 0 {
+		return nil, fmt.Errorf("layout inspections are not supported")
+	}
+	for id, k := range l.Keys {
+		if k.ID() != id {
+			return nil, fmt.Errorf("layout key %s does not match its ID", id)
+		}
+	}
+
+	res := &Result{Layout: &l, Links: map[string]*Link{}}
+	for _, step := range l.Steps {
+		threshold := step.Threshold
+		if threshold < 1 {
+			threshold = 1
+		}
+		var accepted []*Link
+		var signers []string
+		for _, id := range step.PubKeys {
+			key, ok := l.Keys[id]
+			if !ok {
+				return nil, fmt.Errorf("step %s: functionary %s is not a layout key", step.Name, id)
+			}
+			for _, e := range links {
+				var link Link
+				if e.decode(&link, "link") != nil || link.Name != step.Name || !e.signedBy(key) {
+					continue
+				}
+				accepted = append(accepted, &link)
+				signers = append(signers, id)
+				break
+			}
+		}
+		if len(accepted) < threshold {
+			return nil, fmt.Errorf("step %s: %d of %d required functionaries signed a link", step.Name, len(accepted), threshold)
+		}
+		for _, link := range accepted[1:] {
+			if !sameArtifacts(link.Materials, accepted[0].Materials) || !sameArtifacts(link.Products, accepted[0].Products) {
+				return nil, fmt.Errorf("step %s: functionaries' links disagree on the artifacts", step.Name)
+			}
+		}
+		res.Links[step.Name] = accepted[0]
+		for _, id := range signers {
+			res.Functionaries = append(res.Functionaries, Functionary{Step: step.Name, KeyID: id})
+		}
+	}
+	for _, step := range l.Steps {
+		link := res.Links[step.Name]
+		if err := applyRules(step.ExpectedMaterials, link, true, res.Links); err != nil {
+			return nil, fmt.Errorf("step %s materials: %w", step.Name, err)
+		}
+		if err := applyRules(step.ExpectedProducts, link, false, res.Links); err != nil {
+			return nil, fmt.Errorf("step %s products: %w", step.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// DecodeLink returns an envelope's link, unverified.
+func DecodeLink(e *Envelope) (*Link, error) {
+	var link Link
+	if err := e.decode(&link, "link"); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func sameArtifacts(a, b map[string]Hashes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, h := range a {
+		if b[p]["sha256"] != h["sha256"] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRules runs a step's artifact rules over its materials or products.
+// Rules consume the artifacts they match in order; DISALLOW fails on any
+// artifact left that it matches, and artifacts no rule consumes are
+// allowed.
+func applyRules(rules [][]string, link *Link, materials bool, links map[string]*Link) error {
+	artifacts := link.Products
+	if materials {
+		artifacts = link.Materials
+	}
+	queue := map[string]bool{}
+	for p := range artifacts {
+		queue[p] = true
+	}
+	for _, rule := range rules {
+		if len(rule) < 2 {
+			return fmt.Errorf("invalid rule %q", strings.Join(rule, " "))
+		}
+		op, pattern := strings.ToUpper(rule[0]), rule[1]
+		matched := func(keep func(p string) bool) []string {
+			var out []string
+			for p := range queue {
+				if fnmatch(pattern, p) && keep(p) {
+					out = append(out, p)
+				}
+			}
+			sort.Strings(out)
+			return out
+		}
+		all := func(p string) bool { return true }
+		var consumed []string
+		switch op {
+		case "ALLOW":
+			consumed = matched(all)
+		case "DISALLOW":
+			if bad := matched(all); len(bad) > 0 {
+				return fmt.Errorf("%s disallowed by %q", bad[0], strings.Join(rule, " "))
+			}
+		case "REQUIRE":
+			if _, ok := artifacts[pattern]; !ok {
+				return fmt.Errorf("%s required but absent", pattern)
+			}
+		case "CREATE":
+			consumed = matched(func(p string) bool { _, had := link.Materials[p]; return !had })
+		case "DELETE":
+			consumed = matched(func(p string) bool { _, has := link.Products[p]; return !has })
+		case "MODIFY":
+			consumed = matched(func(p string) bool {
+				m, had := link.Materials[p]
+				q, has := link.Products[p]
+				return had && has && m["sha256"] != q["sha256"]
+			})
+		case "MATCH":
+			keep, prefix, err := matchRule(rule, artifacts, links)
+			if err != nil {
+				return err
+			}
+			pattern = prefix + pattern
+			consumed = matched(keep)
+		default:
+			return fmt.Errorf("unsupported rule %q", strings.Join(rule, " "))
+		}
+		for _, p := range consumed {
+			delete(queue, p)
+		}
+	}
+	return nil
+}
+
+// matchRule reads MATCH pattern [IN prefix] WITH (MATERIALS|PRODUCTS)
+// [IN prefix] FROM step, returning whether an artifact has the same
+// sha256 as its counterpart in that step's link, and the prefix the
+// pattern applies below.
+func matchRule(rule []string, artifacts map[string]Hashes, links map[string]*Link) (func(p string) bool, string, error) {
+	words := rule[2:]
+	invalid := fmt.Errorf("invalid rule %q", strings.Join(rule, " "))
+	srcPrefix, dstPrefix := "", ""
+	if len(words) >= 2 && strings.EqualFold(words[0], "IN") {
+		srcPrefix, words = words[1], words[2:]
+	}
+	if srcPrefix != "" {
+		srcPrefix = strings.TrimSuffix(srcPrefix, "/") + "/"
+	}
+	if len(words) < 2 || !strings.EqualFold(words[0], "WITH") {
+		return nil, "", invalid
+	}
+	kind := strings.ToUpper(words[1])
+	words = words[2:]
+	if len(words) >= 2 && strings.EqualFold(words[0], "IN") {
+		dstPrefix, words = words[1], words[2:]
+	}
+	if len(words) != 2 || !strings.EqualFold(words[0], "FROM") || (kind != "MATERIALS" && kind != "PRODUCTS") {
+		return nil, "", invalid
+	}
+	other, ok := links[words[1]]
+	if !ok {
+		return nil, "", fmt.Errorf("rule %q names an unknown step", strings.Join(rule, " "))
+	}
+	dst := other.Products
+	if kind == "MATERIALS" {
+		dst = other.Materials
+	}
+	return func(p string) bool {
+		rel := strings.TrimPrefix(p, srcPrefix)
+		if dstPrefix != "" {
+			rel = strings.TrimSuffix(dstPrefix, "/") + "/" + rel
+		}
+		h, ok := dst[rel]
+		return ok && h["sha256"] != "" && h["sha256"] == artifacts[p]["sha256"]
+	}, srcPrefix, nil
+}
+
+// fnmatch matches name against a shell pattern as in-toto does: * and ?
+// match any characters, including /, and [...] a character class.
+func fnmatch(pattern, name string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	ok, _ := regexp.MatchString(re.String(), name)
+	return ok
+}
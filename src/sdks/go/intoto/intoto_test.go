@@ -0,0 +1,130 @@
+package intoto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+type chain struct {
+	owner, alice, bob ed25519.PrivateKey
+	ownerPub          ed25519.PublicKey
+	layout            *Layout
+}
+
+func newChain(t *testing.T) *chain {
+	ownerPub, owner := key(t)
+	alicePub, alice := key(t)
+	bobPub, bob := key(t)
+	a, b := NewKey(alicePub), NewKey(bobPub)
+	return &chain{
+		owner: owner, alice: alice, bob: bob, ownerPub: ownerPub,
+		layout: &Layout{
+			Type:    "layout",
+			Expires: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			Keys:    map[string]Key{a.ID(): a, b.ID(): b},
+			Steps: []Step{
+				{Type: "step", Name: "author", PubKeys: []string{a.ID()}, Threshold: 1,
+					ExpectedProducts: [][]string{{"CREATE", "agents/*"}, {"DISALLOW", "*"}}},
+				{Type: "step", Name: "review", PubKeys: []string{b.ID()}, Threshold: 1,
+					ExpectedMaterials: [][]string{{"MATCH", "*", "WITH", "PRODUCTS", "FROM", "author"}, {"DISALLOW", "*"}},
+					ExpectedProducts:  [][]string{{"MATCH", "*", "WITH", "PRODUCTS", "FROM", "author"}, {"DISALLOW", "*"}}},
+			},
+			Inspect: []interface{}{},
+		},
+	}
+}
+
+func (c *chain) verify(t *testing.T, files map[string][]byte, reviewed map[string][]byte) (*Result, error) {
+	layout, err := Sign(c.layout, c.owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	author, _ := Sign(NewLink("author", nil, files), c.alice)
+	review, _ := Sign(NewLink("review", reviewed, reviewed), c.bob)
+	return Verify(layout, []ed25519.PublicKey{c.ownerPub}, []*Envelope{author, review}, time.Now())
+}
+
+func TestVerify(t *testing.T) {
+	c := newChain(t)
+	files := map[string][]byte{"agents/support.ossa.yaml": []byte("kind: Agent\n")}
+	res, err := c.verify(t, files, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Functionaries) != 2 || res.Functionaries[1].Step != "review" {
+		t.Errorf("Unexpected functionaries %+v", res.Functionaries)
+	}
+
+	// The reviewer saw different content from what was authored.
+	changed := map[string][]byte{"agents/support.ossa.yaml": []byte("kind: Agent\nchanged: true\n")}
+	if _, err := c.verify(t, files, changed); err == nil || !strings.Contains(err.Error(), "step review materials") {
+		t.Errorf("Expected the review's materials rejected, got %v", err)
+	}
+
+	// Authoring outside agents/ is disallowed.
+	stray := map[string][]byte{"run.sh": []byte("rm -rf /\n")}
+	if _, err := c.verify(t, stray, stray); err == nil || !strings.Contains(err.Error(), "run.sh disallowed") {
+		t.Errorf("Expected run.sh disallowed, got %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	c := newChain(t)
+	files := map[string][]byte{"agents/a.yaml": []byte("a")}
+	author, _ := Sign(NewLink("author", nil, files), c.alice)
+	review, _ := Sign(NewLink("review", files, files), c.bob)
+	links := []*Envelope{author, review}
+
+	layout, _ := Sign(c.layout, c.owner)
+	otherPub, _ := key(t)
+	if _, err := Verify(layout, []ed25519.PublicKey{otherPub}, links, time.Now()); err == nil {
+		t.Error("Expected a layout signed by another key rejected")
+	}
+	if _, err := Verify(layout, []ed25519.PublicKey{c.ownerPub}, links, time.Now().Add(2*time.Hour)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected an expired layout, got %v", err)
+	}
+	// A review signed by the author does not count.
+	selfReview, _ := Sign(NewLink("review", files, files), c.alice)
+	if _, err := Verify(layout, []ed25519.PublicKey{c.ownerPub}, []*Envelope{author, selfReview}, time.Now()); err == nil || !strings.Contains(err.Error(), "step review: 0 of 1") {
+		t.Errorf("Expected the self-review rejected, got %v", err)
+	}
+
+	// A tampered layout no longer verifies.
+	var raw map[string]interface{}
+	json.Unmarshal(layout.Signed, &raw)
+	raw["expires"] = "2999-01-01T00:00:00Z"
+	layout.Signed, _ = json.Marshal(raw)
+	if _, err := Verify(layout, []ed25519.PublicKey{c.ownerPub}, links, time.Now()); err == nil {
+		t.Error("Expected a tampered layout rejected")
+	}
+}
+
+func TestFnmatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "agents/a.yaml", true},
+		{"agents/*.yaml", "agents/x/a.yaml", true},
+		{"agents/?.yaml", "agents/a.yaml", true},
+		{"agents/[!a].yaml", "agents/a.yaml", false},
+		{"*.json", "a.yaml", false},
+		{"a+b", "a+b", true},
+	} {
+		if got := fnmatch(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("fnmatch(%q, %q) = %v", tc.pattern, tc.name, got)
+		}
+	}
+}
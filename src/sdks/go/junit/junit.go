@@ -0,0 +1,101 @@
+// Package junit writes JUnit XML test reports, the format CI systems such
+// as GitLab, GitHub Actions and Jenkins render natively. ossa validate,
+// vet, conformance run and eval write them with --format junit: a
+// testsuite per run and a testcase per manifest, fixture or eval case.
+package junit
+
+import (
+	"encoding/xml"
+	"math"
+	"time"
+)
+
+// Report is a <testsuites> document. Add keeps its totals.
+type Report struct {
+	XMLName  xml.Name `xml:"testsuites"`
+	Name     string   `xml:"name,attr,omitempty"`
+	Tests    int      `xml:"tests,attr"`
+	Failures int      `xml:"failures,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Time     float64  `xml:"time,attr"`
+	Suites   []Suite  `xml:"testsuite"`
+}
+
+// Suite is a <testsuite>. Add counts its cases.
+type Suite struct {
+	Name       string      `xml:"name,attr"`
+	Tests      int         `xml:"tests,attr"`
+	Failures   int         `xml:"failures,attr"`
+	Errors     int         `xml:"errors,attr"`
+	Time       float64     `xml:"time,attr"`
+	Properties *Properties `xml:"properties,omitempty"`
+	Cases      []Case      `xml:"testcase"`
+}
+
+// Properties describe a suite.
+type Properties struct {
+	Items []Property `xml:"property"`
+}
+
+// Property is a name and value describing a suite.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Case is a <testcase>: passed, failed (a check did not hold) or errored
+// (it could not run).
+type Case struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Problem `xml:"failure,omitempty"`
+	Error     *Problem `xml:"error,omitempty"`
+	// SystemOut is shown beside the result, such as the warnings of a
+	// valid manifest.
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+// Problem is why a case failed or errored.
+type Problem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Seconds is d in seconds to the millisecond, as time attributes hold it.
+func Seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+// Add appends s, counting its tests, failures and errors into both s and
+// the report.
+func (r *Report) Add(s Suite) {
+	s.Tests, s.Failures, s.Errors = len(s.Cases), 0, 0
+	for _, c := range s.Cases {
+		switch {
+		case c.Error != nil:
+			s.Errors++
+		case c.Failure != nil:
+			s.Failures++
+		}
+	}
+	r.Tests += s.Tests
+	r.Failures += s.Failures
+	r.Errors += s.Errors
+	r.Time = math.Round((r.Time+s.Time)*1000) / 1000
+	r.Suites = append(r.Suites, s)
+}
+
+// Failed reports whether any case failed or errored.
+func (r *Report) Failed() bool {
+	return r.Failures+r.Errors > 0
+}
+
+// Marshal renders the report as an indented XML document.
+func (r *Report) Marshal() ([]byte, error) {
+	data, err := xml.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,42 @@
+package junit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	r := &Report{}
+	r.Add(Suite{Name: "ossa vet", Time: 1.5, Cases: []Case{
+		{Name: "agents/a.ossa.yaml", ClassName: "ossa.validate", SystemOut: "warnings:\n  no tools"},
+		{Name: "agents/b.ossa.yaml", ClassName: "ossa.validate", Failure: &Problem{Message: "1 errors", Text: "spec.llm: required & <missing>"}},
+		{Name: "agents/c.ossa.yaml", ClassName: "ossa.validate", Error: &Problem{Message: "could not validate", Text: "no such file"}},
+	}})
+	r.Add(Suite{Name: "ossa conformance", Cases: []Case{{Name: "valid-minimal", ClassName: "ossa.conformance"}}})
+	if r.Tests != 4 || r.Failures != 1 || r.Errors != 1 || !r.Failed() || r.Suites[0].Tests != 3 || r.Suites[1].Failures != 0 {
+		t.Errorf("Unexpected totals %+v", r)
+	}
+	data, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuites tests="4" failures="1" errors="1" time="1.5">`,
+		`<testsuite name="ossa vet" tests="3" failures="1" errors="1" time="1.5">`,
+		`<failure message="1 errors">spec.llm: required &amp; &lt;missing&gt;</failure>`,
+		`<error message="could not validate">no such file</error>`,
+		`<system-out>warnings:&#xA;  no tools</system-out>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "<properties>") {
+		t.Errorf("Expected no empty properties in\n%s", data)
+	}
+
+	if (&Report{}).Failed() {
+		t.Error("Expected an empty report to pass")
+	}
+}
@@ -0,0 +1,231 @@
+// Package labels implements Kubernetes-style label selectors over
+// manifest metadata.labels.
+//
+// A selector is a comma-separated list of requirements, all of which must
+// hold:
+//
+//	team=ops            equality (== is the same)
+//	tier!=policy        inequality; also matches when the label is absent
+//	env in (prod,stage) set membership
+//	env notin (dev)     set exclusion; also matches when the label is absent
+//	owner               the label exists
+//	!deprecated         the label does not exist
+//
+// Keys follow the Kubernetes grammar: an optional DNS subdomain prefix and
+// a slash, then a name of up to 63 alphanumerics, '-', '_' and '.', which
+// begins and ends with an alphanumeric. Values follow the same rule for
+// names and may be empty.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Operator is how a requirement compares a label.
+type Operator string
+
+// The operators Parse produces.
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is one condition on a label.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	// Values has one entry for Equals and NotEquals, one or more for In and
+	// NotIn, and none for Exists and DoesNotExist.
+	Values []string
+}
+
+// Matches reports whether the requirement holds for the labels.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	case Equals, In:
+		return ok && contains(r.Values, value)
+	default: // NotEquals, NotIn
+		return !ok || !contains(r.Values, value)
+	}
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return r.Key + string(r.Operator) + r.Values[0]
+}
+
+// Selector matches label sets. The zero value matches everything.
+type Selector struct {
+	Requirements []Requirement
+}
+
+// Everything returns a selector matching every label set.
+func Everything() Selector { return Selector{} }
+
+// Empty reports whether the selector has no requirements.
+func (s Selector) Empty() bool { return len(s.Requirements) == 0 }
+
+// Matches reports whether every requirement holds for the labels.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s.Requirements {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector in the syntax Parse accepts.
+func (s Selector) String() string {
+	parts := make([]string, len(s.Requirements))
+	for i, r := range s.Requirements {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+var (
+	namePattern   = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)$`)
+	prefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidKey reports whether key is a valid label key.
+func ValidKey(key string) bool {
+	prefix, name, qualified := strings.Cut(key, "/")
+	if !qualified {
+		name = prefix
+	} else if len(prefix) > 253 || !prefixPattern.MatchString(prefix) {
+		return false
+	}
+	return namePattern.MatchString(name)
+}
+
+// ValidValue reports whether value is a valid label value.
+func ValidValue(value string) bool {
+	return value == "" || namePattern.MatchString(value)
+}
+
+// Parse parses a selector such as "team=ops,tier!=policy". An empty
+// string selects everything.
+func Parse(s string) (Selector, error) {
+	var sel Selector
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return sel, err
+	}
+	for _, part := range parts {
+		r, err := parseRequirement(part)
+		if err != nil {
+			return Selector{}, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel.Requirements = append(sel.Requirements, r)
+	}
+	return sel, nil
+}
+
+// splitRequirements splits on commas outside parentheses.
+func splitRequirements(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("invalid selector %q: unbalanced ')'", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid selector %q: unbalanced '('", s)
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	if s == "" {
+		return Requirement{}, fmt.Errorf("empty requirement")
+	}
+	if strings.HasPrefix(s, "!") && !strings.Contains(s, "=") {
+		return newRequirement(strings.TrimSpace(s[1:]), DoesNotExist, nil)
+	}
+	for _, op := range []struct {
+		text string
+		op   Operator
+	}{{"!=", NotEquals}, {"==", Equals}, {"=", Equals}} {
+		if key, value, ok := strings.Cut(s, op.text); ok {
+			return newRequirement(strings.TrimSpace(key), op.op, []string{strings.TrimSpace(value)})
+		}
+	}
+	if open := strings.Index(s, "("); open >= 0 {
+		if !strings.HasSuffix(s, ")") {
+			return Requirement{}, fmt.Errorf("%q: expected ')' at the end", s)
+		}
+		fields := strings.Fields(s[:open])
+		if len(fields) != 2 || (fields[1] != string(In) && fields[1] != string(NotIn)) {
+			return Requirement{}, fmt.Errorf("%q: expected <key> in (...) or <key> notin (...)", s)
+		}
+		var values []string
+		for _, v := range strings.Split(s[open+1:len(s)-1], ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		return newRequirement(fields[0], Operator(fields[1]), values)
+	}
+	return newRequirement(s, Exists, nil)
+}
+
+func newRequirement(key string, op Operator, values []string) (Requirement, error) {
+	if !ValidKey(key) {
+		return Requirement{}, fmt.Errorf("invalid label key %q", key)
+	}
+	for _, v := range values {
+		if !ValidValue(v) {
+			return Requirement{}, fmt.Errorf("invalid label value %q for %s", v, key)
+		}
+	}
+	if op == In || op == NotIn {
+		if len(values) == 1 && values[0] == "" {
+			return Requirement{}, fmt.Errorf("%s %s needs at least one value", key, op)
+		}
+		sort.Strings(values)
+	}
+	return Requirement{Key: key, Operator: op, Values: values}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
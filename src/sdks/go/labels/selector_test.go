@@ -0,0 +1,55 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	set := map[string]string{"team": "ops", "tier": "standard", "app.kubernetes.io/name": "pager"}
+	for selector, want := range map[string]bool{
+		"":                                   true,
+		"team=ops":                           true,
+		"team==ops,tier!=policy":             true,
+		"team=ops,tier=policy":               false,
+		"tier in (standard, premium)":        true,
+		"tier notin (standard)":              false,
+		"env notin (dev),env!=prod":          true,
+		"env in (prod)":                      false,
+		"team,!deprecated":                   true,
+		"!team":                              false,
+		"app.kubernetes.io/name=pager":       true,
+		" team = ops , tier in (a,standard)": true,
+	} {
+		sel, err := Parse(selector)
+		if err != nil {
+			t.Errorf("%q: %v", selector, err)
+			continue
+		}
+		if got := sel.Matches(set); got != want {
+			t.Errorf("%q: expected %v, got %v", selector, want, got)
+		}
+	}
+
+	sel, _ := Parse("team=ops,tier in (b,a),!old,owner")
+	if got := sel.String(); got != "team=ops,tier in (a,b),!old,owner" {
+		t.Errorf("Unexpected String %q", got)
+	}
+	if again, err := Parse(sel.String()); err != nil || again.String() != sel.String() {
+		t.Errorf("Expected String to round-trip, got %v %v", again, err)
+	}
+
+	for selector, want := range map[string]string{
+		"team=ops,":          "empty requirement",
+		"-team=ops":          `invalid label key "-team"`,
+		"team=o p s":         `invalid label value "o p s"`,
+		"tier in (a":         "unbalanced '('",
+		"tier within (a)":    "expected <key> in (...)",
+		"tier in ()":         "needs at least one value",
+		"Example.com/team=a": `invalid label key`,
+	} {
+		if _, err := Parse(selector); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", selector, want, err)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// Diagnostic is one finding in a document.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// CompletionItem is one completion suggestion.
+type CompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Completion item kinds.
+const (
+	completionProperty = 10
+	completionEnum     = 20
+)
+
+var (
+	schema    *gojsonschema.Schema
+	schemaErr error
+
+	yamlErrLine = regexp.MustCompile(`line (\d+)`)
+	// messagePath finds the field a validator message is about.
+	messagePath = regexp.MustCompile(`\b(?:apiVersion|kind|metadata|spec|extensions)(?:\.[A-Za-z0-9_]+|\[\d+\])*`)
+	keyLine     = regexp.MustCompile(`^["']?([A-Za-z_$][\w.$/-]*)["']?\s*:(?:\s+(.*)|$)`)
+)
+
+func init() {
+	schema, schemaErr = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(ossa.EmbeddedSchema()))
+}
+
+// Diagnose parses and validates a manifest. YAML errors are reported on
+// their line; schema, semantic and lint findings on the field they name,
+// falling back to the nearest enclosing field and then the first line.
+// Schema validation runs only when the structural checks pass, as in
+// ossa.Validator.
+func Diagnose(data []byte) []Diagnostic {
+	diags := []Diagnostic{}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return append(diags, Diagnostic{Range: errorLine(err), Severity: SeverityError, Source: "ossa", Message: err.Error()})
+	}
+	m, err := ossa.ParseManifest(data, ".yaml")
+	if err != nil {
+		return append(diags, Diagnostic{Range: errorLine(err), Severity: SeverityError, Source: "ossa", Message: err.Error()})
+	}
+
+	add := func(path, msg string, severity int) {
+		diags = append(diags, Diagnostic{Range: locate(&root, path), Severity: severity, Source: "ossa", Message: msg})
+	}
+	result := ossa.ValidateManifest(m)
+	for _, msg := range result.Errors {
+		add(messagePath.FindString(msg), msg, SeverityError)
+	}
+	for _, msg := range result.Warnings {
+		add(messagePath.FindString(msg), msg, SeverityWarning)
+	}
+
+	if result.Valid && schemaErr == nil {
+		doc, err := json.Marshal(m.Localize(ossa.DefaultLocale).WithoutExtensions())
+		if err != nil {
+			return diags
+		}
+		res, err := schema.Validate(gojsonschema.NewBytesLoader(doc))
+		if err != nil {
+			return diags
+		}
+		for _, desc := range res.Errors() {
+			field := desc.Field()
+			if field == gojsonschema.STRING_CONTEXT_ROOT {
+				field = ""
+			}
+			add(field, "Schema: "+desc.Description(), SeverityError)
+		}
+	}
+	return diags
+}
+
+// errorLine returns the range of the line named in a YAML error.
+func errorLine(err error) Range {
+	line := 0
+	if m := yamlErrLine.FindStringSubmatch(err.Error()); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		line--
+	}
+	return Range{Start: Position{Line: line}, End: Position{Line: line + 1}}
+}
+
+// locate returns the range of the key for a dotted path such as
+// spec.tools[0].name or spec.tools.0.name, or of its longest prefix.
+func locate(root *yaml.Node, path string) Range {
+	found := Range{End: Position{Line: 1}}
+	if len(root.Content) == 0 || path == "" {
+		return found
+	}
+	n := root.Content[0]
+	for _, seg := range strings.Split(strings.NewReplacer("[", ".", "]", "").Replace(path), ".") {
+		var next, mark *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == seg {
+					mark, next = n.Content[i], n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(n.Content) {
+				mark, next = n.Content[i], n.Content[i]
+			}
+		}
+		if next == nil {
+			break
+		}
+		found = nodeRange(mark)
+		n = next
+	}
+	return found
+}
+
+func nodeRange(n *yaml.Node) Range {
+	start := Position{Line: n.Line - 1, Character: n.Column - 1}
+	end := start
+	if n.Kind == yaml.ScalarNode {
+		end.Character += utf16Len(n.Value)
+	} else {
+		end = Position{Line: start.Line + 1}
+	}
+	return Range{Start: start, End: end}
+}
+
+// Hover returns Markdown documentation for the field at pos, or "".
+func Hover(text string, pos Position) string {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) || parseLine(lines[pos.Line]).key == "" {
+		return ""
+	}
+	path := strings.Join(keyPath(lines, pos.Line), ".")
+	info, err := ossa.ExplainPath(ossa.EmbeddedSchema(), path, docKind(lines))
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** `%s`", info.Path, info.Type)
+	if info.Required {
+		b.WriteString(" (required)")
+	}
+	if info.Description != "" {
+		fmt.Fprintf(&b, "\n\n%s", info.Description)
+	}
+	if len(info.Enum) > 0 {
+		values := make([]string, len(info.Enum))
+		for i, v := range info.Enum {
+			values[i] = fmt.Sprintf("`%v`", v)
+		}
+		fmt.Fprintf(&b, "\n\nValues: %s", strings.Join(values, ", "))
+	}
+	if info.Default != nil {
+		fmt.Fprintf(&b, "\n\nDefault: `%v`", info.Default)
+	}
+	for _, c := range info.Constraints {
+		fmt.Fprintf(&b, "\n\n%s", c)
+	}
+	return b.String()
+}
+
+// Complete returns completions at pos: enum values after "key:", and the
+// schema's field names for the enclosing object elsewhere.
+func Complete(text string, pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return items
+	}
+	line := lines[pos.Line]
+	lines[pos.Line] = line[:byteOffset(line, pos.Character)]
+	kind := docKind(lines)
+	path := keyPath(lines, pos.Line)
+
+	if parseLine(lines[pos.Line]).key != "" {
+		for _, v := range enumValues(strings.Join(path, "."), kind) {
+			items = append(items, CompletionItem{Label: v, Kind: completionEnum})
+		}
+		return items
+	}
+	for _, f := range fieldsOf(strings.Join(path, "."), kind) {
+		items = append(items, CompletionItem{Label: f.Name, Kind: completionProperty, Detail: f.Type, Documentation: f.Description})
+	}
+	return items
+}
+
+// enumValues returns the allowed values of a field. Access tiers come
+// from the SDK, which also accepts shorthand the schema predates.
+func enumValues(path string, kind ossa.Kind) []string {
+	var values []string
+	if strings.HasSuffix(path, "access_tier") {
+		for t := range ossa.ValidAccessTiers {
+			values = append(values, string(t))
+		}
+		sort.Strings(values)
+		return values
+	}
+	info, err := ossa.ExplainPath(ossa.EmbeddedSchema(), path, kind)
+	if err != nil {
+		return nil
+	}
+	for _, v := range info.Enum {
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
+
+// fieldsOf returns the fields of the object at path; empty is the root.
+func fieldsOf(path string, kind ossa.Kind) []ossa.FieldSummary {
+	if path != "" {
+		info, err := ossa.ExplainPath(ossa.EmbeddedSchema(), path, kind)
+		if err != nil {
+			return nil
+		}
+		return info.Fields
+	}
+	var root struct {
+		Properties map[string]struct {
+			Description string `json:"description"`
+		} `json:"properties"`
+	}
+	json.Unmarshal(ossa.EmbeddedSchema(), &root)
+	var fields []ossa.FieldSummary
+	for name := range root.Properties {
+		info, err := ossa.ExplainPath(ossa.EmbeddedSchema(), name, kind)
+		if err == nil {
+			fields = append(fields, ossa.FieldSummary{Name: name, Type: info.Type, Required: info.Required, Description: info.Description})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// Definition resolves the workflow step ref at pos. A ref is a manifest
+// path relative to the document; failing that, it is looked up by
+// metadata.name among the manifests below root, without any @version
+// constraint.
+func Definition(text string, pos Position, docPath, root string) (Location, bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return Location{}, false
+	}
+	li := parseLine(lines[pos.Line])
+	path := keyPath(lines, pos.Line)
+	if li.key != "ref" || li.value == "" || !containsSeg(path, "steps") {
+		return Location{}, false
+	}
+
+	target := li.value
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(docPath), target)
+	}
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		return Location{URI: pathURI(target)}, true
+	}
+	if root == "" {
+		return Location{}, false
+	}
+	entries, err := ossa.ScanWorkspace(root)
+	if err != nil {
+		return Location{}, false
+	}
+	name, _, _ := strings.Cut(li.value, "@")
+	if _, n, ok := strings.Cut(name, "/"); ok {
+		name = n
+	}
+	for _, e := range entries {
+		if e.Err == nil && e.Name == name {
+			return Location{URI: pathURI(e.Path)}, true
+		}
+	}
+	return Location{}, false
+}
+
+// lineInfo is the key on one line of YAML. indent is the column the key
+// starts at, after any "- " list markers.
+type lineInfo struct {
+	indent int
+	key    string
+	value  string
+}
+
+func parseLine(s string) lineInfo {
+	i := 0
+	for {
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i < len(s) && s[i] == '-' && (i+1 == len(s) || s[i+1] == ' ') {
+			i++
+			continue
+		}
+		break
+	}
+	li := lineInfo{indent: i}
+	if m := keyLine.FindStringSubmatch(strings.TrimRight(s[i:], "\r")); m != nil {
+		li.key = m[1]
+		value := m[2]
+		if j := strings.Index(value, " #"); j >= 0 {
+			value = value[:j]
+		}
+		li.value = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return li
+}
+
+// keyPath returns the keys enclosing line n by indentation, ending with
+// the key on line n if it has one. It works on incomplete documents that
+// do not parse.
+func keyPath(lines []string, n int) []string {
+	cur := parseLine(lines[n])
+	var path []string
+	if cur.key != "" {
+		path = []string{cur.key}
+	}
+	limit := cur.indent
+	for i := n - 1; i >= 0 && limit > 0; i-- {
+		li := parseLine(lines[i])
+		if li.key != "" && li.indent < limit {
+			path = append([]string{li.key}, path...)
+			limit = li.indent
+		}
+	}
+	return path
+}
+
+// docKind returns the document's top-level kind.
+func docKind(lines []string) ossa.Kind {
+	for _, line := range lines {
+		if li := parseLine(line); li.indent == 0 && li.key == "kind" {
+			return ossa.Kind(li.value)
+		}
+	}
+	return ""
+}
+
+func containsSeg(path []string, seg string) bool {
+	for _, s := range path {
+		if s == seg {
+			return true
+		}
+	}
+	return false
+}
+
+// byteOffset converts a UTF-16 character offset in line to a byte offset.
+func byteOffset(line string, char int) int {
+	units := 0
+	for i, r := range line {
+		if units >= char {
+			return i
+		}
+		units += utf16Len(string(r))
+	}
+	return len(line)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,307 @@
+// Package lsp implements ossa lsp: a Language Server Protocol server for
+// OSSA manifests, speaking JSON-RPC over stdio.
+//
+// It supports the subset of the protocol editors need for manifests:
+//
+//	textDocument/publishDiagnostics  parse, schema, semantic and lint findings
+//	textDocument/hover               field documentation from the schema
+//	textDocument/completion          field names and enum values
+//	textDocument/definition          workflow step refs to their manifests
+//
+// Documents are synced in full on every change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Server is a language server for one client connection.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	mu   sync.Mutex
+	docs map[string]string
+	// root is the workspace directory from initialize, searched when
+	// resolving workflow refs by name.
+	root     string
+	shutdown bool
+}
+
+// New returns a Server reading requests from in and writing to out.
+func New(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: map[string]string{}}
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes used in responses.
+const (
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInvalidRequest = -32600
+)
+
+// read reads one Content-Length framed message.
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	return &msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}) {
+	if result == nil {
+		// A null result must still be sent.
+		result = json.RawMessage("null")
+	}
+	s.write(&message{ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, msg string) {
+	s.write(&message{ID: id, Error: &responseError{Code: code, Message: msg}})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	data, _ := json.Marshal(params)
+	s.write(&message{Method: method, Params: data})
+}
+
+func (s *Server) handle(msg *message) {
+	if msg.ID == nil {
+		s.handleNotification(msg)
+		return
+	}
+	if s.shutdown {
+		s.replyError(msg.ID, codeInvalidRequest, "server is shut down")
+		return
+	}
+
+	var result interface{}
+	var err error
+	switch msg.Method {
+	case "initialize":
+		result, err = s.initialize(msg.Params)
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/hover":
+		result, err = withPosition(msg.Params, s.hover)
+	case "textDocument/completion":
+		result, err = withPosition(msg.Params, s.completion)
+	case "textDocument/definition":
+		result, err = withPosition(msg.Params, s.definition)
+	default:
+		s.replyError(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+		return
+	}
+	if err != nil {
+		s.replyError(msg.ID, codeInvalidParams, err.Error())
+		return
+	}
+	s.reply(msg.ID, result)
+}
+
+func (s *Server) handleNotification(msg *message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &p) == nil {
+			s.update(p.TextDocument.URI, p.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   textDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(msg.Params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument textDocument `json:"textDocument"`
+		}
+		if json.Unmarshal(msg.Params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+			s.publish(p.TextDocument.URI, []Diagnostic{})
+		}
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		RootURI string `json:"rootUri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RootURI != "" {
+		s.root = uriPath(p.RootURI)
+	}
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1, // full
+			"hoverProvider":      true,
+			"definitionProvider": true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{":", " "},
+			},
+		},
+		"serverInfo": map[string]string{"name": "ossa-lsp"},
+	}, nil
+}
+
+func (s *Server) update(uri, text string) {
+	s.docs[uri] = text
+	s.publish(uri, Diagnose([]byte(text)))
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) {
+	s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diags,
+	})
+}
+
+type textDocument struct {
+	URI string `json:"uri"`
+}
+
+// Position is a zero-based line and UTF-16 character offset.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span of a document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// withPosition decodes TextDocumentPositionParams and calls f with them.
+func withPosition(params json.RawMessage, f func(uri string, pos Position) interface{}) (interface{}, error) {
+	var p struct {
+		TextDocument textDocument `json:"textDocument"`
+		Position     Position     `json:"position"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return f(p.TextDocument.URI, p.Position), nil
+}
+
+func (s *Server) hover(uri string, pos Position) interface{} {
+	h := Hover(s.docs[uri], pos)
+	if h == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"contents": map[string]string{"kind": "markdown", "value": h},
+	}
+}
+
+func (s *Server) completion(uri string, pos Position) interface{} {
+	return Complete(s.docs[uri], pos)
+}
+
+func (s *Server) definition(uri string, pos Position) interface{} {
+	loc, ok := Definition(s.docs[uri], pos, uriPath(uri), s.root)
+	if !ok {
+		return nil
+	}
+	return loc
+}
+
+// uriPath returns the file path of a file:// URI.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file:// URI of a path.
+func pathURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,171 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testManifest = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: reviewer
+spec:
+  role: You review code.
+  access_tier: tier_1_read
+  llm:
+    provider: openai
+    model: gpt-4o
+  tools:
+    - type: mcp
+      name: search
+`
+
+func TestDiagnose(t *testing.T) {
+	if diags := Diagnose([]byte(testManifest)); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", diags)
+	}
+
+	diags := Diagnose([]byte("apiVersion: ossa/v0.3.3\nkind: Robot\nmetadata:\n  name: x\n"))
+	if len(diags) == 0 || diags[0].Severity != SeverityError || diags[0].Range.Start.Line != 1 {
+		t.Errorf("Expected an error on the kind line, got %+v", diags)
+	}
+
+	diags = Diagnose([]byte("kind: Agent\nmetadata: [\n"))
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "yaml") {
+		t.Errorf("Expected one YAML error, got %+v", diags)
+	}
+
+	diags = Diagnose([]byte(strings.Replace(testManifest, "tier_1_read", "read", 1)))
+	if len(diags) != 1 || diags[0].Severity != SeverityWarning || diags[0].Range.Start.Line != 6 {
+		t.Errorf("Expected a shorthand warning on the access_tier line, got %+v", diags)
+	}
+}
+
+func TestHover(t *testing.T) {
+	h := Hover(testManifest, Position{Line: 8, Character: 5})
+	if !strings.Contains(h, "**spec.llm.provider**") || !strings.Contains(h, "anthropic") {
+		t.Errorf("Unexpected hover: %q", h)
+	}
+	if h := Hover(testManifest, Position{Line: 100}); h != "" {
+		t.Errorf("Expected no hover past the end, got %q", h)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	labels := func(items []CompletionItem) string {
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Label)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := labels(Complete("apiVersion: ossa/v0.3.3\nkind: ", Position{Line: 1, Character: 6})); got != "Agent,Task,Workflow" {
+		t.Errorf("Unexpected kind completions: %s", got)
+	}
+	text := strings.Replace(testManifest, "provider: openai", "provider: ", 1)
+	if got := labels(Complete(text, Position{Line: 8, Character: 14})); !strings.Contains(got, "anthropic") {
+		t.Errorf("Unexpected provider completions: %s", got)
+	}
+	text = strings.Replace(testManifest, "access_tier: tier_1_read", "access_tier: ", 1)
+	if got := labels(Complete(text, Position{Line: 6, Character: 15})); !strings.Contains(got, "tier_2_write_limited") {
+		t.Errorf("Unexpected tier completions: %s", got)
+	}
+	text = strings.Replace(testManifest, "    model: gpt-4o", "    ", 1)
+	if got := labels(Complete(text, Position{Line: 9, Character: 4})); !strings.Contains(got, "temperature") {
+		t.Errorf("Unexpected llm field completions: %s", got)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "agents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "agents", "reviewer.ossa.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workflow := "kind: Workflow\nspec:\n  steps:\n    - id: review\n      ref: ./agents/reviewer.ossa.yaml\n    - id: again\n      ref: reviewer\n    - id: pinned\n      ref: reviewer@^1.2\n"
+	docPath := filepath.Join(dir, "flow.ossa.yaml")
+
+	want := pathURI(filepath.Join(dir, "agents", "reviewer.ossa.yaml"))
+	for _, line := range []int{4, 6, 8} {
+		loc, ok := Definition(workflow, Position{Line: line, Character: 8}, docPath, dir)
+		if !ok || loc.URI != want {
+			t.Errorf("Line %d: expected %s, got %+v", line, want, loc)
+		}
+	}
+	if _, ok := Definition(workflow, Position{Line: 3, Character: 8}, docPath, dir); ok {
+		t.Error("Expected no definition for a step id")
+	}
+}
+
+func TestServe(t *testing.T) {
+	var in strings.Builder
+	send := func(id int, method string, params interface{}) {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	uri := "file:///tmp/agent.ossa.yaml"
+	send(1, "initialize", map[string]interface{}{"rootUri": "file:///tmp"})
+	send(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "text": "kind: Robot\n"},
+	})
+	send(2, "textDocument/hover", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": 0, "character": 1},
+	})
+	send(3, "workspace/symbol", map[string]interface{}{})
+	send(4, "shutdown", nil)
+	send(0, "exit", nil)
+
+	var out strings.Builder
+	if err := New(strings.NewReader(in.String()), &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []map[string]interface{}
+	var ids []string
+	r := New(strings.NewReader(out.String()), nil)
+	for {
+		msg, err := r.read()
+		if err != nil {
+			break
+		}
+		data, _ := json.Marshal(msg)
+		var m map[string]interface{}
+		json.Unmarshal(data, &m)
+		msgs = append(msgs, m)
+		id := ""
+		if msg.ID != nil {
+			id = string(*msg.ID)
+		}
+		ids = append(ids, id)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("Expected 5 messages, got %d: %s", len(msgs), out.String())
+	}
+	if caps, _ := msgs[0]["result"].(map[string]interface{}); caps["capabilities"] == nil {
+		t.Errorf("Expected capabilities, got %v", msgs[0])
+	}
+	if msgs[1]["method"] != "textDocument/publishDiagnostics" {
+		t.Errorf("Expected diagnostics, got %v", msgs[1])
+	}
+	if result, _ := msgs[2]["result"].(map[string]interface{}); result["contents"] == nil {
+		t.Errorf("Expected hover contents, got %v", msgs[2])
+	}
+	if e, _ := msgs[3]["error"].(map[string]interface{}); e["code"] != float64(codeMethodNotFound) {
+		t.Errorf("Expected method not found, got %v", msgs[3])
+	}
+	if ids[4] != "4" || msgs[4]["error"] != nil {
+		t.Errorf("Expected a shutdown result, got %v", msgs[4])
+	}
+}
@@ -0,0 +1,92 @@
+package ossa
+
+import "fmt"
+
+// AccessTier is an agent privilege level.
+type AccessTier string
+
+const (
+	TierRead          AccessTier = "tier_1_read"
+	TierWriteLimited  AccessTier = "tier_2_write_limited"
+	TierWriteElevated AccessTier = "tier_3_write_elevated"
+	TierPolicy        AccessTier = "tier_4_policy"
+
+	// Shorthand forms.
+	TierReadShort     AccessTier = "read"
+	TierLimitedShort  AccessTier = "limited"
+	TierElevatedShort AccessTier = "elevated"
+	TierPolicyShort   AccessTier = "policy"
+)
+
+// ValidAccessTiers are the accepted access tier values, including shorthand.
+var ValidAccessTiers = map[AccessTier]bool{
+	TierRead:          true,
+	TierWriteLimited:  true,
+	TierWriteElevated: true,
+	TierPolicy:        true,
+	TierReadShort:     true,
+	TierLimitedShort:  true,
+	TierElevatedShort: true,
+	TierPolicyShort:   true,
+}
+
+// Normalize expands a shorthand tier to its full name.
+func (t AccessTier) Normalize() AccessTier {
+	switch t {
+	case TierReadShort:
+		return TierRead
+	case TierLimitedShort:
+		return TierWriteLimited
+	case TierElevatedShort:
+		return TierWriteElevated
+	case TierPolicyShort:
+		return TierPolicy
+	}
+	return t
+}
+
+// CanWrite reports whether the tier permits write operations. Policy-tier
+// agents define policy and do not act, so they cannot write.
+func (t AccessTier) CanWrite() bool {
+	switch t.Normalize() {
+	case TierWriteLimited, TierWriteElevated:
+		return true
+	}
+	return false
+}
+
+// Identity contains agent identity configuration.
+type Identity struct {
+	Provider   string     `json:"provider,omitempty" yaml:"provider,omitempty"`
+	AccessTier AccessTier `json:"access_tier,omitempty" yaml:"access_tier,omitempty"`
+}
+
+// GetAccessTier returns the normalized access tier from spec.access_tier or
+// spec.identity.access_tier, or "" when neither is set.
+func (m *Manifest) GetAccessTier() AccessTier {
+	tier := m.Spec.AccessTier
+	if tier == "" && m.Spec.Identity != nil {
+		tier = m.Spec.Identity.AccessTier
+	}
+	return tier.Normalize()
+}
+
+// EffectiveAccessTier is GetAccessTier with undeclared tiers treated as
+// tier_1_read, the least-privileged default used for enforcement.
+func (m *Manifest) EffectiveAccessTier() AccessTier {
+	if tier := m.GetAccessTier(); tier != "" {
+		return tier
+	}
+	return TierRead
+}
+
+func validateAccessTier(m *Manifest, result *ValidationResult) {
+	if t := m.Spec.AccessTier; t != "" && !ValidAccessTiers[t] {
+		result.addError(fmt.Sprintf("Invalid access tier: %s", t))
+	}
+	if m.Spec.Identity != nil {
+		if t := m.Spec.Identity.AccessTier; t != "" && !ValidAccessTiers[t] {
+			result.addError(fmt.Sprintf("Invalid access tier: %s", t))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ossa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Well-known annotations: metadata that ossa tooling writes and reads to
+// coordinate, through the typed accessors below.
+const (
+	// LastAppliedAnnotation holds the JSON of the manifest ossa apply last
+	// applied, in the target's copy.
+	LastAppliedAnnotation = "ossa.dev/last-applied-configuration"
+	// DigestAnnotation holds "sha256:" and the hex sha256 of the manifest's
+	// canonical JSON without this annotation; see StampDigest.
+	DigestAnnotation = "ossa.dev/digest"
+	// SourceURLAnnotation holds where the manifest was published: the
+	// registry URL ossa publish and ossa apply write it to.
+	SourceURLAnnotation = "ossa.dev/source-url"
+	// SignedByAnnotation holds the comma-separated IDs of the keys that
+	// signed the manifest. ossa bundle does not stamp it: its signatures,
+	// in-toto links and lock files pin the manifests' bytes, which the
+	// stamp would change.
+	SignedByAnnotation = "ossa.dev/signed-by"
+)
+
+// ErrDigestMismatch is returned by VerifyDigest for manifests changed
+// since their digest was stamped.
+var ErrDigestMismatch = errors.New("manifest does not match its digest annotation")
+
+// Annotation returns the annotation key, or "" if it is unset.
+func (m *Manifest) Annotation(key string) string {
+	return m.Metadata.Annotations[key]
+}
+
+// SetAnnotation sets the annotation key to value, or removes it if value
+// is empty.
+func (m *Manifest) SetAnnotation(key, value string) {
+	if value == "" {
+		delete(m.Metadata.Annotations, key)
+		return
+	}
+	if m.Metadata.Annotations == nil {
+		m.Metadata.Annotations = map[string]string{}
+	}
+	m.Metadata.Annotations[key] = value
+}
+
+// LastApplied returns the manifest recorded in LastAppliedAnnotation, or
+// nil if there is none. Since the apply stored the annotation too, the
+// returned manifest carries it.
+func (m *Manifest) LastApplied() (*Manifest, error) {
+	data := m.Annotation(LastAppliedAnnotation)
+	if data == "" {
+		return nil, nil
+	}
+	var last Manifest
+	if err := json.Unmarshal([]byte(data), &last); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", LastAppliedAnnotation, err)
+	}
+	last.SetAnnotation(LastAppliedAnnotation, data)
+	return &last, nil
+}
+
+// SetLastApplied records applied, without its own LastAppliedAnnotation,
+// in LastAppliedAnnotation.
+func (m *Manifest) SetLastApplied(applied *Manifest) error {
+	c := applied.DeepCopy()
+	c.SetAnnotation(LastAppliedAnnotation, "")
+	data, err := c.ToCanonicalJSON()
+	if err != nil {
+		return err
+	}
+	m.SetAnnotation(LastAppliedAnnotation, string(data))
+	return nil
+}
+
+// StampDigest sets DigestAnnotation to the manifest's digest, computed
+// without the annotation, so tooling can tell whether it was changed
+// since. Stamp after the other annotations, which the digest covers.
+func (m *Manifest) StampDigest() error {
+	digest, err := m.unstampedDigest()
+	if err != nil {
+		return err
+	}
+	m.SetAnnotation(DigestAnnotation, digest)
+	return nil
+}
+
+// VerifyDigest returns an error wrapping ErrDigestMismatch if the manifest
+// has a DigestAnnotation it no longer matches. Manifests without one pass.
+func (m *Manifest) VerifyDigest() error {
+	stamped := m.Annotation(DigestAnnotation)
+	if stamped == "" {
+		return nil
+	}
+	digest, err := m.unstampedDigest()
+	if err != nil {
+		return err
+	}
+	if digest != stamped {
+		return WrapError(fmt.Sprintf("%s is %s, not %s", DigestAnnotation, stamped, digest), ErrDigestMismatch)
+	}
+	return nil
+}
+
+func (m *Manifest) unstampedDigest() (string, error) {
+	c := m.DeepCopy()
+	c.SetAnnotation(DigestAnnotation, "")
+	digest, err := c.Digest()
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + digest, nil
+}
+
+// SourceURL returns SourceURLAnnotation.
+func (m *Manifest) SourceURL() string {
+	return m.Annotation(SourceURLAnnotation)
+}
+
+// SetSourceURL sets SourceURLAnnotation.
+func (m *Manifest) SetSourceURL(u string) {
+	m.SetAnnotation(SourceURLAnnotation, u)
+}
+
+// SignedBy returns the key IDs in SignedByAnnotation.
+func (m *Manifest) SignedBy() []string {
+	var ids []string
+	for _, id := range strings.Split(m.Annotation(SignedByAnnotation), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AddSignedBy adds key IDs to SignedByAnnotation, keeping it sorted and
+// without duplicates.
+func (m *Manifest) AddSignedBy(ids ...string) {
+	seen := map[string]bool{}
+	var all []string
+	for _, id := range append(m.SignedBy(), ids...) {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			all = append(all, id)
+		}
+	}
+	sort.Strings(all)
+	m.SetAnnotation(SignedByAnnotation, strings.Join(all, ","))
+}
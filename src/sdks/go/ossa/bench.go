@@ -0,0 +1,167 @@
+package ossa
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchManifest is a representative agent for benchmarks: an LLM, tools
+// with handlers, safety and constraints.
+const BenchManifest = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: bench-triage
+  version: 1.4.2
+  description: Triages support tickets and routes them to a team
+  labels:
+    team: support
+    tier: production
+spec:
+  role: |
+    You triage support tickets. Read the ticket, look up the customer and
+    their recent orders, and answer with the ticket's priority and team.
+  llm:
+    provider: openai
+    model: gpt-4o
+    temperature: 0.2
+    maxTokens: 1024
+  tools:
+    - type: function
+      name: lookup_customer
+      description: Look a customer up by email
+      inputSchema:
+        type: object
+        properties:
+          email: {type: string, format: email}
+        required: [email]
+    - type: function
+      name: recent_orders
+      description: List a customer's orders from the last 90 days
+      inputSchema:
+        type: object
+        properties:
+          customer_id: {type: string}
+          limit: {type: integer, minimum: 1, maximum: 50}
+        required: [customer_id]
+    - type: http
+      name: create_ticket
+      description: Open a ticket in the helpdesk
+      endpoint: https://helpdesk.example.com/api/tickets
+  safety:
+    content_filtering:
+      enabled: true
+      categories: [hate_speech, violence]
+    pii_detection:
+      enabled: true
+      types: [email, phone, credit_card]
+  constraints:
+    cost:
+      maxTokensPerRequest: 8000
+    performance:
+      maxLatencySeconds: 30
+      maxConcurrentRequests: 4
+`
+
+// BenchDoc is a manifest document to benchmark, named for its format as
+// ParseManifest's ext is.
+type BenchDoc struct {
+	Name string
+	Data []byte
+}
+
+// BenchResult is the throughput of BenchValidate.
+type BenchResult struct {
+	N       int `json:"n"`
+	Workers int `json:"workers"`
+	// Invalid counts iterations whose document failed to parse or
+	// validate; they are timed all the same.
+	Invalid int   `json:"invalid"`
+	Bytes   int64 `json:"bytes"`
+	// Duration is the wall time of the whole run; Parse and Validate are
+	// the mean time an iteration spent in each.
+	Duration time.Duration `json:"duration_ns"`
+	Parse    time.Duration `json:"parse_ns"`
+	Validate time.Duration `json:"validate_ns"`
+	// P50 and P99 are percentiles of an iteration's parse and validate.
+	P50 time.Duration `json:"p50_ns"`
+	P99 time.Duration `json:"p99_ns"`
+	// AllocsPerOp and BytesPerOp are heap allocations per iteration.
+	AllocsPerOp uint64 `json:"allocs_per_op"`
+	BytesPerOp  uint64 `json:"bytes_per_op"`
+}
+
+// OpsPerSecond is the manifests parsed and validated per second.
+func (r *BenchResult) OpsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.N) / r.Duration.Seconds()
+}
+
+// BenchValidate parses and validates docs n times, round robin, on workers
+// goroutines sharing v. It measures what serving validations costs: the
+// schema is compiled once, before timing starts.
+func BenchValidate(docs []BenchDoc, v *Validator, n, workers int) (*BenchResult, error) {
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no documents to benchmark")
+	}
+	if n < 1 || workers < 1 {
+		return nil, fmt.Errorf("n and workers must be at least 1")
+	}
+	if workers > n {
+		workers = n
+	}
+	res := &BenchResult{N: n, Workers: workers}
+	times := make([]time.Duration, n)
+	var parse, validate, invalid, bytes int64
+	var next int64 = -1
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				doc := docs[i%len(docs)]
+				t0 := time.Now()
+				m, err := ParseManifest(doc.Data, doc.Name)
+				t1 := time.Now()
+				valid := err == nil && v.Validate(m).Valid
+				t2 := time.Now()
+				if !valid {
+					atomic.AddInt64(&invalid, 1)
+				}
+				atomic.AddInt64(&parse, int64(t1.Sub(t0)))
+				atomic.AddInt64(&validate, int64(t2.Sub(t1)))
+				atomic.AddInt64(&bytes, int64(len(doc.Data)))
+				times[i] = t2.Sub(t0)
+			}
+		}()
+	}
+	wg.Wait()
+	res.Duration = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	res.Invalid = int(invalid)
+	res.Bytes = bytes
+	res.Parse = time.Duration(parse / int64(n))
+	res.Validate = time.Duration(validate / int64(n))
+	res.AllocsPerOp = (after.Mallocs - before.Mallocs) / uint64(n)
+	res.BytesPerOp = (after.TotalAlloc - before.TotalAlloc) / uint64(n)
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	res.P50 = times[n/2]
+	res.P99 = times[(n*99)/100]
+	return res, nil
+}
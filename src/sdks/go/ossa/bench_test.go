@@ -0,0 +1,99 @@
+package ossa
+
+import "testing"
+
+func TestBenchValidate(t *testing.T) {
+	v, err := NewValidator(WithSchema(EmbeddedSchema()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseManifest([]byte(BenchManifest), ".yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := v.Validate(m); !result.Valid {
+		t.Fatalf("Expected BenchManifest to be valid, got %v", result.Errors)
+	}
+
+	docs := []BenchDoc{{Name: ".yaml", Data: []byte(BenchManifest)}, {Name: ".yaml", Data: []byte("kind: Agent\n")}}
+	res, err := BenchValidate(docs, v, 20, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.N != 20 || res.Workers != 4 || res.Invalid != 10 || res.Bytes != 10*int64(len(BenchManifest)+len("kind: Agent\n")) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if res.Parse <= 0 || res.P50 > res.P99 || res.OpsPerSecond() <= 0 || res.BytesPerOp == 0 {
+		t.Errorf("Unexpected timings %+v", res)
+	}
+	if _, err := BenchValidate(nil, v, 1, 1); err == nil {
+		t.Error("Expected no documents to fail")
+	}
+}
+
+func BenchmarkParseManifest(b *testing.B) {
+	data := []byte(BenchManifest)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseManifest(data, ".yaml"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	m, err := ParseManifest([]byte(BenchManifest), ".yaml")
+	if err != nil {
+		b.Fatal(err)
+	}
+	v := DefaultValidator()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v.Validate(m)
+	}
+}
+
+func BenchmarkValidateSchema(b *testing.B) {
+	m, err := ParseManifest([]byte(BenchManifest), ".yaml")
+	if err != nil {
+		b.Fatal(err)
+	}
+	v, err := NewValidator(WithSchema(EmbeddedSchema()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v.Validate(m)
+	}
+}
+
+func BenchmarkCompileSchema(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := compileSchema(EmbeddedSchema()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseValidateParallel(b *testing.B) {
+	data := []byte(BenchManifest)
+	v, err := NewValidator(WithSchema(EmbeddedSchema()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m, err := ParseManifest(data, ".yaml")
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			v.Validate(m)
+		}
+	})
+}
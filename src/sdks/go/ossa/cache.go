@@ -0,0 +1,193 @@
+package ossa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache backends accepted in handler.cache.backend.
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// CacheBackend stores tool results. Get returns ok=false on a miss.
+type CacheBackend interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// MemoryCache is an in-process CacheBackend. Expired entries are dropped
+// when read, and swept on Set once any has expired, so the cache holds at
+// most the entries set within their TTL.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+	// sweepAt is the earliest expiry among the entries.
+	sweepAt time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Get implements CacheBackend.
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements CacheBackend.
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if !c.sweepAt.IsZero() && !now.Before(c.sweepAt) {
+		c.sweep(now)
+	}
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+		if c.sweepAt.IsZero() || e.expires.Before(c.sweepAt) {
+			c.sweepAt = e.expires
+		}
+	}
+	c.entries[key] = e
+	return nil
+}
+
+// sweep drops the entries expired at now.
+func (c *MemoryCache) sweep(now time.Time) {
+	c.sweepAt = time.Time{}
+	for key, e := range c.entries {
+		switch {
+		case e.expires.IsZero():
+		case !now.Before(e.expires):
+			delete(c.entries, key)
+		case c.sweepAt.IsZero() || e.expires.Before(c.sweepAt):
+			c.sweepAt = e.expires
+		}
+	}
+}
+
+// RedisClient is the subset of a Redis client the Redis backend needs.
+// Adapt go-redis or any other client to it; Get returns ok=false for a
+// missing key.
+type RedisClient interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	SetEx(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// RedisCache is a CacheBackend stored in Redis under a key prefix.
+type RedisCache struct {
+	Client RedisClient
+	Prefix string
+}
+
+// Get implements CacheBackend.
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return c.Client.Get(ctx, c.Prefix+key)
+}
+
+// Set implements CacheBackend.
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.Client.SetEx(ctx, c.Prefix+key, value, ttl)
+}
+
+// ToolCache caches results of tools that opt in with handler.cache.
+type ToolCache struct {
+	Backend CacheBackend
+}
+
+// NewToolCache creates a tool cache on a backend.
+func NewToolCache(backend CacheBackend) *ToolCache {
+	return &ToolCache{Backend: backend}
+}
+
+// Do returns a cached result for the call if one exists, otherwise calls fn
+// and caches its result. Tools without handler.cache always call fn. Keys
+// are scoped to the session and built from the cache's key_fields, or from
+// all arguments when key_fields is empty.
+func (c *ToolCache) Do(ctx context.Context, session string, tool ToolConfig, args map[string]interface{}, fn func(context.Context) ([]byte, error)) ([]byte, error) {
+	if tool.Handler == nil || tool.Handler.Cache == nil {
+		return fn(ctx)
+	}
+	cfg := tool.Handler.Cache
+
+	key, err := CacheKey(session, tool.Name, args, cfg.KeyFields)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok, err := c.Backend.Get(ctx, key); err != nil {
+		return nil, WrapError("cache lookup failed", err)
+	} else if ok {
+		return value, nil
+	}
+
+	value, err := fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Backend.Set(ctx, key, value, cfg.TTL.Std()); err != nil {
+		return nil, WrapError("cache store failed", err)
+	}
+	return value, nil
+}
+
+// CacheKey builds the cache key for a tool call.
+func CacheKey(session, tool string, args map[string]interface{}, keyFields []string) (string, error) {
+	selected := args
+	if len(keyFields) > 0 {
+		selected = make(map[string]interface{}, len(keyFields))
+		for _, f := range keyFields {
+			selected[f] = args[f]
+		}
+	}
+	// encoding/json sorts map keys, so equal arguments give equal keys.
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(session))
+	h.Write([]byte{0})
+	h.Write([]byte(tool))
+	h.Write([]byte{0})
+	h.Write(data)
+	return "ossa:tool:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func validateCache(path string, cfg *CacheConfig, result *ValidationResult) {
+	if cfg == nil {
+		return
+	}
+	if cfg.TTL <= 0 {
+		result.addError(fmt.Sprintf("%s.ttl: must be positive", path))
+	}
+	switch cfg.Backend {
+	case "", CacheBackendMemory, CacheBackendRedis:
+	default:
+		result.addError(fmt.Sprintf("%s.backend: invalid backend: %s", path, cfg.Backend))
+	}
+}
@@ -0,0 +1,92 @@
+package ossa
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestToolCache(t *testing.T) {
+	backend := NewMemoryCache()
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+	cache := NewToolCache(backend)
+
+	tool := ToolConfig{
+		Type: "http",
+		Name: "search",
+		Handler: &ToolHandler{Cache: &CacheConfig{
+			TTL:       Duration(5 * time.Minute),
+			KeyFields: []string{"query"},
+		}},
+	}
+
+	calls := 0
+	call := func(context.Context) ([]byte, error) {
+		calls++
+		return []byte("result"), nil
+	}
+	ctx := context.Background()
+
+	cache.Do(ctx, "s1", tool, map[string]interface{}{"query": "go", "trace_id": "a"}, call)
+	cache.Do(ctx, "s1", tool, map[string]interface{}{"query": "go", "trace_id": "b"}, call)
+	if calls != 1 {
+		t.Errorf("Expected fields outside key_fields to be ignored, got %d calls", calls)
+	}
+
+	cache.Do(ctx, "s2", tool, map[string]interface{}{"query": "go"}, call)
+	if calls != 2 {
+		t.Errorf("Expected cache to be scoped per session, got %d calls", calls)
+	}
+
+	now = now.Add(6 * time.Minute)
+	cache.Do(ctx, "s1", tool, map[string]interface{}{"query": "go"}, call)
+	if calls != 3 {
+		t.Errorf("Expected expired entry to be refreshed, got %d calls", calls)
+	}
+
+	tool.Handler.Cache = nil
+	cache.Do(ctx, "s1", tool, map[string]interface{}{"query": "go"}, call)
+	cache.Do(ctx, "s1", tool, map[string]interface{}{"query": "go"}, call)
+	if calls != 5 {
+		t.Errorf("Expected uncached tool to always call through, got %d calls", calls)
+	}
+}
+
+func TestMemoryCacheSweep(t *testing.T) {
+	c := NewMemoryCache()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		c.Set(ctx, fmt.Sprint("short", i), []byte("x"), time.Minute)
+	}
+	c.Set(ctx, "long", []byte("x"), time.Hour)
+	c.Set(ctx, "forever", []byte("x"), 0)
+
+	now = now.Add(2 * time.Minute)
+	c.Set(ctx, "new", []byte("x"), time.Minute)
+	if len(c.entries) != 3 {
+		t.Errorf("Expected expired entries swept on Set, got %d entries", len(c.entries))
+	}
+	now = now.Add(2 * time.Minute)
+	c.Set(ctx, "newer", []byte("x"), time.Minute)
+	if _, ok := c.entries["new"]; ok || len(c.entries) != 3 {
+		t.Errorf("Expected the next expiry swept too, got %d entries", len(c.entries))
+	}
+}
+
+func TestCacheValidation(t *testing.T) {
+	manifest := NewManifest("cache-agent", KindAgent)
+	manifest.Spec.Tools = []ToolConfig{{
+		Type:    "http",
+		Name:    "lookup",
+		Handler: &ToolHandler{Cache: &CacheConfig{Backend: "memcached"}},
+	}}
+
+	result := ValidateManifest(manifest)
+	if len(result.Errors) != 2 {
+		t.Errorf("Expected ttl and backend errors, got %v", result.Errors)
+	}
+}
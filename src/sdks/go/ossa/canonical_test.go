@@ -0,0 +1,82 @@
+package ossa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		// RFC 8785, section 3.2.2.3 and appendix B
+		{`[333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001, -0, 1e21, 1e20, 1e-7, 1e-6, 9007199254740993, -1.5e-10]`,
+			`[333333333.3333333,1e+30,4.5,0.002,1e-27,0,1e+21,100000000000000000000,1e-7,0.000001,9007199254740992,-1.5e-10]`},
+		{`"\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/<>"`, "\"€$\\u000f\\nA'B\\\"\\\\\\\\\\\"/<>\""},
+		{`{"\u20ac": 1, "\r": 2, "1": 3, "\ud83d\ude00": 4, "\u0080": 5, "\u00f6": 6, "\ufb33": 7}`,
+			"{\"\\r\":2,\"1\":3,\"\u0080\":5,\"ö\":6,\"€\":1,\"😀\":4,\"\ufb33\":7}"},
+		{` { "b" : [ true , null , { "d" : 1 , "c" : "x" } ] , "a" : {} } `, `{"a":{},"b":[true,null,{"c":"x","d":1}]}`},
+	} {
+		got, err := CanonicalJSON([]byte(tc.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("CanonicalJSON(%s)\n got %s\nwant %s", tc.in, got, tc.want)
+		}
+	}
+	for _, in := range []string{`1e400`, `{"a": 1} {}`, `{`} {
+		if _, err := CanonicalJSON([]byte(in)); err == nil {
+			t.Errorf("Expected %s to fail", in)
+		}
+	}
+}
+
+func TestManifestCanonicalJSON(t *testing.T) {
+	a, err := ParseManifest([]byte(`
+apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: triage
+  labels: {team: support, tier: "1"}
+spec:
+  role: Route <urgent> tickets & escalate
+  llm: {provider: openai, model: gpt-4o, temperature: 0.50}
+`), ".yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseManifest([]byte(`{"kind": "Agent", "spec": {"llm": {"temperature": 0.5, "model": "gpt-4o", "provider": "openai"}, "role": "Route <urgent> tickets & escalate"},
+		"metadata": {"labels": {"tier": "1", "team": "support"}, "name": "triage"}, "apiVersion": "ossa/v0.3.3"}`), ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := a.ToCanonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, _ := b.ToCanonicalJSON()
+	if string(ca) != string(cb) {
+		t.Errorf("Expected the same bytes, got\n%s\n%s", ca, cb)
+	}
+	if !strings.HasPrefix(string(ca), `{"apiVersion":"ossa/v0.3.3","kind":"Agent","metadata":{"labels":{"team":"support","tier":"1"}`) {
+		t.Errorf("Expected sorted members, got %s", ca)
+	}
+	da, _ := a.Digest()
+	db, _ := b.Digest()
+	if da != db || len(da) != 64 {
+		t.Errorf("Expected equal digests, got %s and %s", da, db)
+	}
+
+	min, err := a.ToMinifiedJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(min), "\n") || !strings.Contains(string(min), "Route <urgent> tickets & escalate") {
+		t.Errorf("Expected minified JSON with HTML unescaped, got %s", min)
+	}
+	if got := string(unescapeHTML([]byte(`"\\u003c \u003c\u0026\u003e \u00e9"`))); got != `"\\u003c <&> \u00e9"` {
+		t.Errorf("Expected only HTML escapes undone, got %s", got)
+	}
+	if !strings.HasPrefix(string(min), `{"apiVersion":"ossa/v0.3.3","kind":"Agent","metadata":{"name":"triage"`) {
+		t.Errorf("Expected declaration order, got %s", min)
+	}
+}
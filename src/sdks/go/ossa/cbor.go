@@ -0,0 +1,408 @@
+package ossa
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CBORExt is the file extension of CBOR manifests, which LoadManifest
+// decodes with FromCBOR and SaveManifest writes for format "cbor".
+const CBORExt = ".cbor"
+
+// ToCBOR encodes the manifest as CBOR (RFC 8949) in its core
+// deterministic form, so the same manifest is the same bytes: definite
+// lengths, the shortest integer and float heads that keep each value, and
+// map keys in bytewise order of their encoding. Whole numbers are
+// integers; the data model is the manifest's JSON form.
+func (m *Manifest) ToCBOR() ([]byte, error) {
+	data, err := m.ToMinifiedJSON()
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode CBOR: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := encodeCBOR(&buf, doc); err != nil {
+		return nil, fmt.Errorf("failed to encode CBOR: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// FromCBOR decodes a CBOR manifest. Any well-formed CBOR describing the
+// manifest's JSON form is accepted, including indefinite lengths; tags are
+// ignored, byte strings become base64 strings and undefined is null.
+// DefaultParseLimits apply.
+func FromCBOR(data []byte) (*Manifest, error) {
+	limits := DefaultParseLimits
+	if len(data) > limits.MaxBytes {
+		return nil, newLimitError(LimitBytes, limits.MaxBytes, len(data))
+	}
+	d := &cborDecoder{data: data, maxDepth: limits.MaxDepth}
+	doc, err := d.value(0)
+	if err == nil && d.pos != len(d.data) {
+		err = fmt.Errorf("%d bytes after the manifest", len(d.data)-d.pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CBOR: %w", err)
+	}
+	asJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CBOR: %w", err)
+	}
+	var m Manifest
+	if err := decodeJSON(asJSON, &m, limits); err != nil {
+		return nil, fmt.Errorf("failed to parse CBOR: %w", err)
+	}
+	return &m, nil
+}
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func encodeCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		return encodeCBORNumber(buf, string(v))
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, e := range v {
+			if err := encodeCBOR(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		type entry struct{ key, value []byte }
+		entries := make([]entry, 0, len(v))
+		for k, e := range v {
+			var key, value bytes.Buffer
+			writeCBORHead(&key, cborText, uint64(len(k)))
+			key.WriteString(k)
+			if err := encodeCBOR(&value, e); err != nil {
+				return err
+			}
+			entries = append(entries, entry{key.Bytes(), value.Bytes()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		writeCBORHead(buf, cborMap, uint64(len(entries)))
+		for _, e := range entries {
+			buf.Write(e.key)
+			buf.Write(e.value)
+		}
+	default:
+		return fmt.Errorf("cannot encode %T", v)
+	}
+	return nil
+}
+
+func encodeCBORNumber(buf *bytes.Buffer, s string) error {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		writeCBORHead(buf, cborUint, n)
+		return nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		writeCBORHead(buf, cborNegInt, uint64(-1-n))
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("number %s is out of range", s)
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		if f >= 0 {
+			writeCBORHead(buf, cborUint, uint64(f))
+		} else {
+			writeCBORHead(buf, cborNegInt, uint64(-1-int64(f)))
+		}
+		return nil
+	}
+	if h, ok := float16Bits(f); ok {
+		buf.WriteByte(0xf9)
+		binary.Write(buf, binary.BigEndian, h)
+	} else if float64(float32(f)) == f {
+		buf.WriteByte(0xfa)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(float32(f)))
+	} else {
+		buf.WriteByte(0xfb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	}
+	return nil
+}
+
+// float16Bits returns f as IEEE 754 half precision, if that holds it
+// exactly.
+func float16Bits(f float64) (uint16, bool) {
+	f32 := float32(f)
+	if float64(f32) != f {
+		return 0, false
+	}
+	bits := math.Float32bits(f32)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	mant := bits & 0x7fffff
+	switch {
+	case exp >= -14 && exp <= 15:
+		// Normal: 10 mantissa bits.
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		// Subnormal: the implicit 1 joins the mantissa.
+		shift := uint(-14 - exp + 13)
+		full := mant | 0x800000
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+	return 0, false
+}
+
+func float16Value(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h >> 10 & 0x1f)
+	mant := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(mant+1024, exp-25)
+}
+
+var errCBORTruncated = errors.New("unexpected end of data")
+
+type cborDecoder struct {
+	data     []byte
+	pos      int
+	maxDepth int
+}
+
+// head reads an initial byte and its argument. indefinite is set for
+// additional information 31.
+func (d *cborDecoder) head() (major byte, info byte, n uint64, indefinite bool, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, false, errCBORTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if d.pos+size > len(d.data) {
+			return 0, 0, 0, false, errCBORTruncated
+		}
+		for _, c := range d.data[d.pos : d.pos+size] {
+			n = n<<8 | uint64(c)
+		}
+		d.pos += size
+		return major, info, n, false, nil
+	case info == 31 && major >= cborBytes && major != cborTag:
+		return major, info, 0, true, nil
+	}
+	return 0, 0, 0, false, fmt.Errorf("malformed CBOR head 0x%02x at byte %d", b, d.pos-1)
+}
+
+// length checks a declared length of items of at least one byte each
+// against the data left.
+func (d *cborDecoder) length(n uint64) (int, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, errCBORTruncated
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > d.maxDepth {
+		return nil, newLimitError(LimitDepth, d.maxDepth, depth)
+	}
+	major, info, n, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case cborNegInt:
+		v := new(big.Int).SetUint64(n)
+		return json.Number(v.Neg(v.Add(v, big.NewInt(1))).String()), nil
+	case cborBytes, cborText:
+		s, err := d.str(major, n, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return base64.StdEncoding.EncodeToString(s), nil
+		}
+		if !utf8.Valid(s) {
+			return nil, fmt.Errorf("text string is not UTF-8")
+		}
+		return string(s), nil
+	case cborArray:
+		if !indefinite {
+			if _, err := d.length(n); err != nil {
+				return nil, err
+			}
+		}
+		arr := []interface{}{}
+		for i := 0; indefinite || i < int(n); i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			e, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, e)
+		}
+		return arr, nil
+	case cborMap:
+		if !indefinite {
+			if _, err := d.length(n); err != nil {
+				return nil, err
+			}
+		}
+		obj := map[string]interface{}{}
+		for i := 0; indefinite || i < int(n); i++ {
+			if indefinite && d.atBreak() {
+				break
+			}
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a text string", k)
+			}
+			if obj[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case cborTag:
+		return d.value(depth + 1)
+	}
+	// Major type 7: simple values and floats.
+	var f float64
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		f = float16Value(uint16(n))
+	case 26:
+		f = float64(math.Float32frombits(uint32(n)))
+	case 27:
+		f = math.Float64frombits(n)
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d", n)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%v has no JSON form", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+// str reads a byte or text string, joining the chunks of an indefinite
+// one.
+func (d *cborDecoder) str(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		size, err := d.length(n)
+		if err != nil {
+			return nil, err
+		}
+		s := d.data[d.pos : d.pos+size]
+		d.pos += size
+		return s, nil
+	}
+	var s []byte
+	for !d.atBreak() {
+		m, _, n, indef, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || indef {
+			return nil, fmt.Errorf("invalid chunk in an indefinite-length string")
+		}
+		chunk, err := d.str(major, n, false)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+	return s, nil
+}
+
+// atBreak consumes the break ending an indefinite-length item, if next.
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == 0xff {
+		d.pos++
+		return true
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package ossa
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCBORNumbers(t *testing.T) {
+	// RFC 8949, appendix A
+	for in, want := range map[string]string{
+		"0":                      "00",
+		"23":                     "17",
+		"24":                     "1818",
+		"1000":                   "1903e8",
+		"18446744073709551615":   "1bffffffffffffffff",
+		"-1":                     "20",
+		"-1000":                  "3903e7",
+		"1.5":                    "f93e00",
+		"-4.1":                   "fbc010666666666666",
+		"0.00006103515625":       "f90400",
+		"5.960464477539063e-8":   "f90001",
+		"100000.0":               "1a000186a0",
+		"3.4028234663852886e+38": "fa7f7fffff",
+		"1.1":                    "fb3ff199999999999a",
+		"1.0e+300":               "fb7e37e43c8800759c",
+		"65504.5":                "fa477fe080",
+	} {
+		var buf bytes.Buffer
+		if err := encodeCBORNumber(&buf, in); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	for in, want := range map[string]interface{}{
+		"bf6346756ef563416d7421ff":                     map[string]interface{}{"Fun": true, "Amt": "-2"},
+		"9f018202039f0405ffff":                         []interface{}{"1", []interface{}{"2", "3"}, []interface{}{"4", "5"}},
+		"7f657374726561646d696e67ff":                   "streaming",
+		"c074323031332d30332d32315432303a30343a30305a": "2013-03-21T20:04:00Z",
+		"4401020304":                                   "AQIDBA==",
+		"f7":                                           nil,
+		"3bffffffffffffffff":                           "-18446744073709551616",
+		"f90001":                                       "5.960464477539063e-08",
+	} {
+		data, _ := hex.DecodeString(in)
+		d := &cborDecoder{data: data, maxDepth: 10}
+		got, err := d.value(0)
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if !reflect.DeepEqual(normalizeNumbers(got), want) {
+			t.Errorf("%s: got %#v, want %#v", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"a1":                       "unexpected end of data",
+		"9b00000000ffffffff":       "unexpected end of data",
+		"a10102":                   "map key 1 is not a text string",
+		"f97c00":                   "has no JSON form",
+		"1c":                       "malformed CBOR head",
+		"818181818181818181818181": "limit",
+	} {
+		data, _ := hex.DecodeString(in)
+		d := &cborDecoder{data: data, maxDepth: 10}
+		if _, err := d.value(0); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", in, want, err)
+		}
+	}
+}
+
+// normalizeNumbers turns json.Numbers into strings for comparison.
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+	case interface{ String() string }:
+		return v.String()
+	}
+	return v
+}
+
+func TestCBORManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(BenchManifest), ".yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.ToCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	asJSON, _ := m.ToMinifiedJSON()
+	if len(data) >= len(asJSON) {
+		t.Errorf("Expected CBOR (%d bytes) smaller than JSON (%d)", len(data), len(asJSON))
+	}
+	back, err := FromCBOR(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := m.ToCanonicalJSON()
+	got, _ := back.ToCanonicalJSON()
+	if !bytes.Equal(got, want) {
+		t.Errorf("Round trip changed the manifest:\n%s\n%s", got, want)
+	}
+	again, _ := back.ToCBOR()
+	if !bytes.Equal(again, data) {
+		t.Error("Expected deterministic CBOR")
+	}
+
+	path := filepath.Join(t.TempDir(), "agent.ossa"+CBORExt)
+	if err := SaveManifest(m, path, "cbor"); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadManifest(path)
+	if err != nil || loaded.Metadata.Name != "bench-triage" {
+		t.Fatalf("Unexpected load %+v %v", loaded, err)
+	}
+
+	if _, err := FromCBOR(append(data, 0x00)); err == nil || !strings.Contains(err.Error(), "1 bytes after the manifest") {
+		t.Errorf("Expected trailing data to fail, got %v", err)
+	}
+	_, err = FromCBOR(bytes.Repeat([]byte{0x81}, MaxYAMLDepth+10))
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitDepth {
+		t.Errorf("Expected a depth limit, got %v", err)
+	}
+}
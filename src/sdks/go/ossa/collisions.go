@@ -0,0 +1,114 @@
+package ossa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultNamespace is the namespace of manifests without
+// metadata.namespace.
+const DefaultNamespace = "default"
+
+// Collision is a conflict between manifests of a workspace, found by
+// CheckCollisions.
+type Collision struct {
+	// Key is what collides: the manifests' kind/namespace/name, or the
+	// lowercased label key.
+	Key string
+	// Paths are the manifests involved, sorted.
+	Paths   []string
+	Message string
+	// Warning is set for label collisions, which only make selectors miss
+	// manifests; the others are errors, since only one of the manifests
+	// can be published or applied.
+	Warning bool
+}
+
+func (c Collision) String() string {
+	return c.Message
+}
+
+// CheckCollisions finds manifests among entries that would overwrite each
+// other: two manifests with the same kind, namespace and name, reported
+// as conflicting versions if their metadata.versions differ. It also
+// warns about label keys spelled differently only in case, such as Team
+// and team, which selectors tell apart. Entries that failed to load are
+// skipped. Collisions are sorted by Key.
+func CheckCollisions(entries []CatalogEntry) []Collision {
+	byKey := map[string][]CatalogEntry{}
+	labelKeys := map[string]map[string][]string{}
+	for _, e := range entries {
+		if e.Manifest == nil {
+			continue
+		}
+		m := e.Manifest
+		namespace := m.Metadata.Namespace
+		if namespace == "" {
+			namespace = DefaultNamespace
+		}
+		key := fmt.Sprintf("%s/%s/%s", m.Kind, namespace, m.Metadata.Name)
+		byKey[key] = append(byKey[key], e)
+		for k := range m.Metadata.Labels {
+			lower := strings.ToLower(k)
+			if labelKeys[lower] == nil {
+				labelKeys[lower] = map[string][]string{}
+			}
+			labelKeys[lower][k] = append(labelKeys[lower][k], e.Path)
+		}
+	}
+
+	var collisions []Collision
+	for key, group := range byKey {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+		c := Collision{Key: key}
+		first := group[0]
+		var conflicting []string
+		for _, e := range group {
+			c.Paths = append(c.Paths, e.Path)
+			if e.Manifest.Metadata.Version != first.Manifest.Metadata.Version {
+				conflicting = append(conflicting, fmt.Sprintf("%s in %s", versionOrUnversioned(e.Manifest), e.Path))
+			}
+		}
+		later := strings.Join(c.Paths[1:], ", ")
+		if len(conflicting) > 0 {
+			c.Message = fmt.Sprintf("%s: %s has conflicting versions: %s in %s, %s", later, key, versionOrUnversioned(first.Manifest), first.Path, strings.Join(conflicting, ", "))
+		} else {
+			c.Message = fmt.Sprintf("%s: %s is also defined in %s", later, key, first.Path)
+		}
+		collisions = append(collisions, c)
+	}
+	for lower, spellings := range labelKeys {
+		if len(spellings) < 2 {
+			continue
+		}
+		c := Collision{Key: lower, Warning: true}
+		keys := make([]string, 0, len(spellings))
+		seen := map[string]bool{}
+		for k, paths := range spellings {
+			keys = append(keys, k)
+			for _, p := range paths {
+				if !seen[p] {
+					seen[p] = true
+					c.Paths = append(c.Paths, p)
+				}
+			}
+		}
+		sort.Strings(keys)
+		sort.Strings(c.Paths)
+		c.Message = fmt.Sprintf("%s: label keys %s differ only in case, so selectors on one miss the others", strings.Join(c.Paths, ", "), strings.Join(keys, ", "))
+		collisions = append(collisions, c)
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Key < collisions[j].Key })
+	return collisions
+}
+
+func versionOrUnversioned(m *Manifest) string {
+	if m.Metadata.Version == "" {
+		return "unversioned"
+	}
+	return m.Metadata.Version
+}
@@ -0,0 +1,181 @@
+package ossa
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SpecVersion is a spec release line the SDK reads.
+type SpecVersion struct {
+	// Version is major.minor, e.g. "0.3".
+	Version string
+	// Deprecated lines still load but warn; see Deprecations.
+	Deprecated bool
+}
+
+// specVersions are the release lines the SDK loads, oldest first.
+var specVersions = []SpecVersion{
+	{Version: "0.2", Deprecated: true},
+	{Version: "0.3"},
+	{Version: "0.4"},
+}
+
+// Feature is a manifest feature introduced by a spec version, used by
+// CheckCompatibility to tell whether a manifest can target an older one.
+type Feature struct {
+	Name string
+	// Path is the dotted field path the feature lives at.
+	Path string
+	// Since is the spec version that introduced it.
+	Since string
+	// Detect reports whether the manifest uses the feature.
+	Detect func(m *Manifest) bool
+}
+
+var (
+	featuresMu sync.RWMutex
+	features   = []Feature{
+		{
+			Name:   "Task and Workflow kinds",
+			Path:   "kind",
+			Since:  "0.3.3",
+			Detect: func(m *Manifest) bool { return m.Kind == KindTask || m.Kind == KindWorkflow },
+		},
+		{
+			Name:   "spec.access_tier",
+			Path:   "spec.access_tier",
+			Since:  "0.3.0",
+			Detect: func(m *Manifest) bool { return m.Spec.AccessTier != "" },
+		},
+	}
+)
+
+// RegisterFeature adds f to the feature table, so extensions can declare
+// when their own fields appeared. f.Detect is required.
+func RegisterFeature(f Feature) {
+	if f.Detect == nil {
+		panic("ossa: RegisterFeature with nil Detect")
+	}
+	featuresMu.Lock()
+	defer featuresMu.Unlock()
+	features = append(features, f)
+}
+
+// SupportedVersions returns the spec release lines the SDK loads, oldest
+// first.
+func SupportedVersions() []SpecVersion {
+	return append([]SpecVersion(nil), specVersions...)
+}
+
+var apiVersionParts = regexp.MustCompile(`^ossa/v(\d+)\.(\d+)(?:\.(\d+))?(?:-[0-9A-Za-z.]+)?$`)
+
+// IsCompatible reports whether the SDK can load manifests declaring
+// apiVersion, e.g. ossa/v0.3.3. Deprecated lines are compatible.
+func IsCompatible(apiVersion string) bool {
+	parts := apiVersionParts.FindStringSubmatch(apiVersion)
+	if parts == nil {
+		return false
+	}
+	line := parts[1] + "." + parts[2]
+	for _, v := range specVersions {
+		if v.Version == line {
+			return true
+		}
+	}
+	return false
+}
+
+// CompatibilityIssue is one reason a manifest cannot target a version.
+type CompatibilityIssue struct {
+	Path   string
+	Reason string
+}
+
+func (i CompatibilityIssue) String() string {
+	return i.Path + ": " + i.Reason
+}
+
+// CompatibilityReport says whether a manifest can be served to consumers
+// of a target spec version.
+type CompatibilityReport struct {
+	Target     string
+	Compatible bool
+	Issues     []CompatibilityIssue
+}
+
+// CheckCompatibility compares the features the manifest uses against
+// target, a spec version such as "0.3.0": features introduced after
+// target and deprecated fields removed by target are issues, as is an
+// apiVersion newer than target.
+func (m *Manifest) CheckCompatibility(target string) (*CompatibilityReport, error) {
+	t, ok := parseSpecVersion(target)
+	if !ok {
+		return nil, NewError(fmt.Sprintf("invalid spec version %q", target))
+	}
+	if !IsCompatible("ossa/v" + target) {
+		return nil, NewError(fmt.Sprintf("unsupported spec version %s", target))
+	}
+	report := &CompatibilityReport{Target: target}
+
+	if parts := apiVersionParts.FindStringSubmatch(m.APIVersion); parts != nil {
+		declared := parts[1] + "." + parts[2]
+		if parts[3] != "" {
+			declared += "." + parts[3]
+		}
+		if v, _ := parseSpecVersion(declared); compareSpecVersions(v, t) > 0 {
+			report.Issues = append(report.Issues, CompatibilityIssue{Path: "apiVersion",
+				Reason: fmt.Sprintf("declares %s, newer than %s", m.APIVersion, target)})
+		}
+	}
+
+	featuresMu.RLock()
+	defer featuresMu.RUnlock()
+	for _, f := range features {
+		since, ok := parseSpecVersion(f.Since)
+		if ok && compareSpecVersions(since, t) > 0 && f.Detect(m) {
+			report.Issues = append(report.Issues, CompatibilityIssue{Path: f.Path,
+				Reason: fmt.Sprintf("%s requires %s", f.Name, f.Since)})
+		}
+	}
+	for _, d := range m.FindDeprecations() {
+		removal, ok := parseSpecVersion(d.Removal)
+		if ok && compareSpecVersions(removal, t) <= 0 {
+			report.Issues = append(report.Issues, CompatibilityIssue{Path: d.Path,
+				Reason: fmt.Sprintf("removed in %s; use %s", d.Removal, d.Replacement)})
+		}
+	}
+	report.Compatible = len(report.Issues) == 0
+	return report, nil
+}
+
+// parseSpecVersion parses major.minor[.patch].
+func parseSpecVersion(s string) ([3]int, bool) {
+	var v [3]int
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func compareSpecVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,368 @@
+package ossa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// ConformanceExpectedFile is the index written next to the fixtures.
+const ConformanceExpectedFile = "expected.json"
+
+// ConformanceCase is one fixture of the conformance corpus with the outcome
+// the JSON Schema gives for it.
+type ConformanceCase struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Description string `json:"description"`
+	Valid       bool   `json:"valid"`
+
+	Manifest map[string]interface{} `json:"-"`
+}
+
+// ConformanceResult compares one SDK's verdict with the expected outcome.
+type ConformanceResult struct {
+	Case ConformanceCase
+	Got  bool
+	Err  error
+}
+
+// Pass reports whether the SDK agreed with the schema.
+func (r ConformanceResult) Pass() bool {
+	return r.Err == nil && r.Got == r.Case.Valid
+}
+
+// conformanceBases are valid manifests of each kind that the generator
+// mutates.
+var conformanceBases = map[string]string{
+	"agent": `
+apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: conformance-agent
+  version: 1.0.0
+  description: Agent used by the conformance corpus
+spec:
+  role: You answer questions about orders.
+  llm:
+    provider: openai
+    model: gpt-4o
+  tools:
+    - type: function
+      name: lookup_order
+`,
+	"task": `
+apiVersion: ossa/v0.3.3
+kind: Task
+metadata:
+  name: conformance-task
+spec:
+  execution:
+    type: deterministic
+`,
+	"workflow": `
+apiVersion: ossa/v0.3.3
+kind: Workflow
+metadata:
+  name: conformance-workflow
+spec:
+  steps:
+    - id: fetch
+      kind: Task
+      ref: ./tasks/fetch.yaml
+`,
+}
+
+// GenerateConformanceCases builds the conformance corpus from a JSON
+// Schema, usually EmbeddedSchema(). Starting from a valid manifest of each
+// kind, it walks the schema and derives one fixture per required field
+// removed, enum value replaced, pattern broken and type changed. Each
+// case's expected outcome is the schema's verdict, so the corpus stays in
+// step with the spec rather than with any one SDK.
+func GenerateConformanceCases(schema []byte) ([]ConformanceCase, error) {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	g := &conformanceGen{root: root}
+
+	kinds := make([]string, 0, len(conformanceBases))
+	for k := range conformanceBases {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		var base map[string]interface{}
+		if err := yaml.Unmarshal([]byte(conformanceBases[kind]), &base); err != nil {
+			return nil, err
+		}
+		g.add(kind+"-valid", "valid "+kind+" manifest", base)
+		g.walk(kind, base, root, base, nil)
+	}
+
+	seen := map[string]bool{}
+	var cases []ConformanceCase
+	for _, c := range g.cases {
+		if seen[c.Name] {
+			continue
+		}
+		seen[c.Name] = true
+		data, err := json.Marshal(c.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		result, err := compiled.Validate(gojsonschema.NewBytesLoader(data))
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", c.Name, err)
+		}
+		c.Valid = result.Valid()
+		c.File = c.Name + ".yaml"
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// WriteConformanceCorpus writes each case as YAML plus the expected.json
+// index into dir.
+func WriteConformanceCorpus(dir string, cases []ConformanceCase) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create corpus directory: %w", err)
+	}
+	for _, c := range cases {
+		data, err := yaml.Marshal(c.Manifest)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, c.File), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write fixture: %w", err)
+		}
+	}
+	index, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ConformanceExpectedFile), append(index, '\n'), 0o644)
+}
+
+// LoadConformanceCorpus reads the expected.json index of a corpus.
+func LoadConformanceCorpus(dir string) ([]ConformanceCase, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ConformanceExpectedFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus index: %w", err)
+	}
+	var cases []ConformanceCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse corpus index: %w", err)
+	}
+	return cases, nil
+}
+
+// RunConformance asks validate for a verdict on every fixture in dir.
+func RunConformance(ctx context.Context, dir string, validate func(ctx context.Context, path string) (bool, error)) ([]ConformanceResult, error) {
+	cases, err := LoadConformanceCorpus(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ConformanceResult, len(cases))
+	for i, c := range cases {
+		got, err := validate(ctx, filepath.Join(dir, c.File))
+		results[i] = ConformanceResult{Case: c, Got: got, Err: err}
+		if ctx.Err() != nil {
+			return results[:i+1], ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+type conformanceGen struct {
+	root  map[string]interface{}
+	cases []ConformanceCase
+}
+
+func (g *conformanceGen) add(name, desc string, doc map[string]interface{}) {
+	g.cases = append(g.cases, ConformanceCase{Name: name, Description: desc, Manifest: doc})
+}
+
+// walk derives mutations of value (found at path inside doc) from node.
+func (g *conformanceGen) walk(kind string, doc map[string]interface{}, node map[string]interface{}, value interface{}, path []string) {
+	node = g.deref(node)
+	where := strings.Join(path, ".")
+	if where == "" {
+		where = "root"
+	}
+	name := kind + "-" + strings.ReplaceAll(strings.Join(path, "-"), "_", "-")
+
+	if enum, ok := node["enum"].([]interface{}); ok && len(enum) > 0 {
+		g.add(name+"-bad-enum", fmt.Sprintf("%s set to a value outside its enum", where), mutate(doc, path, "not-a-valid-value"))
+	}
+	if _, ok := node["pattern"].(string); ok {
+		if _, isString := value.(string); isString {
+			g.add(name+"-bad-pattern", fmt.Sprintf("%s does not match its pattern", where), mutate(doc, path, "Not A Valid Value!"))
+		}
+	}
+	if typ, ok := node["type"].(string); ok && len(path) > 0 {
+		wrong := map[string]interface{}{"string": 42, "object": "not-an-object", "array": "not-an-array", "integer": "1", "number": "1", "boolean": "true"}[typ]
+		if wrong != nil {
+			g.add(name+"-bad-type", fmt.Sprintf("%s is not of type %s", where, typ), mutate(doc, path, wrong))
+		}
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		if arr, ok := value.([]interface{}); ok && len(arr) > 0 {
+			if items, ok := node["items"].(map[string]interface{}); ok {
+				g.walk(kind, doc, items, arr[0], append(append([]string{}, path...), "0"))
+			}
+		}
+		return
+	}
+
+	props, required := g.objectSchema(node, obj)
+	for _, field := range required {
+		if _, present := obj[field]; present {
+			g.add(kind+"-missing-"+strings.ReplaceAll(strings.Join(append(append([]string{}, path...), field), "-"), "_", "-"),
+				fmt.Sprintf("required field %s removed", strings.Join(append(append([]string{}, path...), field), ".")),
+				mutate(doc, append(append([]string{}, path...), field), nil))
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if child, ok := props[k]; ok {
+			g.walk(kind, doc, child, obj[k], append(append([]string{}, path...), k))
+		}
+	}
+}
+
+// objectSchema returns an object node's property schemas and required
+// fields, including those from allOf branches whose if condition matches.
+func (g *conformanceGen) objectSchema(node map[string]interface{}, obj map[string]interface{}) (map[string]map[string]interface{}, []string) {
+	props := map[string]map[string]interface{}{}
+	var required []string
+	merge := func(n map[string]interface{}) {
+		if p, ok := n["properties"].(map[string]interface{}); ok {
+			for k, v := range p {
+				if m, ok := v.(map[string]interface{}); ok {
+					props[k] = m
+				}
+			}
+		}
+		for _, r := range asSlice(n["required"]) {
+			if s, ok := r.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+	merge(node)
+	for _, branch := range asSlice(node["allOf"]) {
+		b, _ := branch.(map[string]interface{})
+		cond, _ := b["if"].(map[string]interface{})
+		then, _ := b["then"].(map[string]interface{})
+		if then != nil && matchesConst(cond, obj) {
+			merge(then)
+		}
+	}
+	sort.Strings(required)
+	return props, required
+}
+
+// matchesConst evaluates the simple {properties: {x: {const: v}}} if
+// conditions the OSSA schema uses.
+func matchesConst(cond map[string]interface{}, obj map[string]interface{}) bool {
+	props, _ := cond["properties"].(map[string]interface{})
+	if len(props) == 0 {
+		return false
+	}
+	for k, v := range props {
+		c, _ := v.(map[string]interface{})
+		if want, ok := c["const"]; !ok || obj[k] != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *conformanceGen) deref(node map[string]interface{}) map[string]interface{} {
+	for i := 0; i < maxRefDepth; i++ {
+		ref, ok := node["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return node
+		}
+		var cur interface{} = g.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			m, _ := cur.(map[string]interface{})
+			cur = m[part]
+		}
+		next, ok := cur.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// mutate returns a deep copy of doc with the value at path replaced, or
+// removed when value is nil.
+func mutate(doc map[string]interface{}, path []string, value interface{}) map[string]interface{} {
+	out := deepCopy(doc).(map[string]interface{})
+	var parent interface{} = out
+	for i, key := range path {
+		last := i == len(path)-1
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if last {
+				if value == nil {
+					delete(p, key)
+				} else {
+					p[key] = value
+				}
+				return out
+			}
+			parent = p[key]
+		case []interface{}:
+			var idx int
+			fmt.Sscan(key, &idx)
+			if last {
+				p[idx] = value
+				return out
+			}
+			parent = p[idx]
+		}
+	}
+	return out
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = deepCopy(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = deepCopy(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
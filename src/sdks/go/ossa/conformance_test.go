@@ -0,0 +1,91 @@
+package ossa
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateConformanceCases(t *testing.T) {
+	cases, err := GenerateConformanceCases(EmbeddedSchema())
+	if err != nil {
+		t.Fatalf("GenerateConformanceCases failed: %v", err)
+	}
+
+	byName := map[string]ConformanceCase{}
+	for _, c := range cases {
+		if _, dup := byName[c.Name]; dup {
+			t.Errorf("Duplicate case %s", c.Name)
+		}
+		byName[c.Name] = c
+	}
+	for _, kind := range []string{"agent", "task", "workflow"} {
+		if c, ok := byName[kind+"-valid"]; !ok || !c.Valid {
+			t.Errorf("Expected valid %s base case, got %+v", kind, c)
+		}
+	}
+	for _, name := range []string{"agent-missing-metadata", "agent-kind-bad-enum", "workflow-missing-spec-steps"} {
+		if c, ok := byName[name]; !ok || c.Valid {
+			t.Errorf("Expected invalid case %s, got %+v", name, c)
+		}
+	}
+}
+
+func TestRunConformance(t *testing.T) {
+	cases, err := GenerateConformanceCases(EmbeddedSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := WriteConformanceCorpus(dir, cases); err != nil {
+		t.Fatalf("WriteConformanceCorpus failed: %v", err)
+	}
+
+	schema, err := compileSchema(EmbeddedSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	validate := func(ctx context.Context, path string) (bool, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return false, err
+		}
+		result, err := schema.Validate(gojsonschema.NewGoLoader(doc))
+		if err != nil {
+			return false, err
+		}
+		return result.Valid(), nil
+	}
+
+	results, err := RunConformance(context.Background(), dir, validate)
+	if err != nil {
+		t.Fatalf("RunConformance failed: %v", err)
+	}
+	if len(results) != len(cases) {
+		t.Fatalf("Expected %d results, got %d", len(cases), len(results))
+	}
+	for _, r := range results {
+		if !r.Pass() {
+			t.Errorf("Case %s: expected valid=%t, got %t (%v)", r.Case.Name, r.Case.Valid, r.Got, r.Err)
+		}
+	}
+
+	// A validator that accepts everything must fail every invalid case.
+	results, _ = RunConformance(context.Background(), dir, func(context.Context, string) (bool, error) { return true, nil })
+	failed := 0
+	for _, r := range results {
+		if !r.Pass() {
+			failed++
+		}
+	}
+	if failed == 0 {
+		t.Error("Expected permissive validator to fail some cases")
+	}
+}
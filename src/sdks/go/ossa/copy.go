@@ -0,0 +1,135 @@
+package ossa
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// DeepCopy returns a copy of m that shares no pointers, slices or maps
+// with it, so either can be changed without affecting the other. Values in
+// spec.extensions and vendor fields are copied too and keep their types.
+func (m *Manifest) DeepCopy() *Manifest {
+	if m == nil {
+		return nil
+	}
+	out := deepCopyValue(reflect.ValueOf(m).Elem()).Interface().(Manifest)
+	return &out
+}
+
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(deepCopyValue(v.Elem()))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopyValue(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return out
+	case reflect.Struct:
+		// Unexported fields, such as time.Time's, are copied as they are.
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(deepCopyValue(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
+
+// EqualOptions relaxes ManifestsEqual.
+type EqualOptions struct {
+	// IgnoreAnnotations ignores metadata.annotations, which tools such as
+	// ossa apply write.
+	IgnoreAnnotations bool
+	// IgnoreOrder compares lists, such as spec.tools, regardless of the
+	// order of their items.
+	IgnoreOrder bool
+}
+
+// ManifestsEqual reports whether a and b are the same manifest. Unlike
+// reflect.DeepEqual it compares their JSON forms, as DiffManifests does,
+// so a nil and an empty map are equal, as are 1 from YAML and 1.0 from
+// JSON. Manifests that fail to marshal are compared with reflect.DeepEqual.
+func ManifestsEqual(a, b *Manifest, opts EqualOptions) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	docA, errA := manifestDoc(a)
+	docB, errB := manifestDoc(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	if opts.IgnoreAnnotations {
+		for _, doc := range []interface{}{docA, docB} {
+			if meta, ok := doc.(map[string]interface{})["metadata"].(map[string]interface{}); ok {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	if opts.IgnoreOrder {
+		docA, docB = sortLists(docA), sortLists(docB)
+	}
+	return reflect.DeepEqual(docA, docB)
+}
+
+// sortLists sorts the lists in a decoded JSON value by their items' JSON.
+func sortLists(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = sortLists(child)
+		}
+	case []interface{}:
+		type item struct {
+			key   string
+			value interface{}
+		}
+		items := make([]item, len(v))
+		for i, child := range v {
+			child = sortLists(child)
+			data, _ := json.Marshal(child)
+			items[i] = item{string(data), child}
+		}
+		sort.SliceStable(items, func(i, j int) bool { return items[i].key < items[j].key })
+		for i, it := range items {
+			v[i] = it.value
+		}
+	}
+	return v
+}
@@ -0,0 +1,352 @@
+package ossa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportCUE translates a JSON Schema, usually EmbeddedSchema(), into CUE
+// definitions in package ossa: one definition per schema definition, plus
+// #Agent, #Task and #Workflow for the kinds and #Manifest for any of them.
+// Objects that the schema leaves open stay open; patterns Go cannot compile
+// are dropped, since CUE uses the same RE2 syntax.
+func ExportCUE(schema []byte) ([]byte, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	w := &cueWriter{imports: map[string]bool{}}
+	defs, _ := root["definitions"].(map[string]interface{})
+
+	var body strings.Builder
+	kinds := cueKinds(root)
+	names := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		if _, clash := defs[k.name]; clash {
+			return nil, NewError(fmt.Sprintf("schema definition %s collides with kind definition", k.name))
+		}
+		names = append(names, "#"+k.name)
+	}
+	if len(names) > 0 {
+		body.WriteString("// #Manifest is a manifest of any kind.\n")
+		fmt.Fprintf(&body, "#Manifest: %s\n", strings.Join(names, " | "))
+	}
+	for _, k := range kinds {
+		fmt.Fprintf(&body, "\n// #%s is a manifest of kind %s.\n", k.name, k.name)
+		fmt.Fprintf(&body, "#%s: %s\n", k.name, w.expr(k.node, 0))
+	}
+
+	defNames := make([]string, 0, len(defs))
+	for name := range defs {
+		defNames = append(defNames, name)
+	}
+	sort.Strings(defNames)
+	for _, name := range defNames {
+		node, _ := defs[name].(map[string]interface{})
+		body.WriteString("\n")
+		writeCUEComment(&body, node["description"], 0)
+		fmt.Fprintf(&body, "#%s: %s\n", cueIdent(name), w.expr(node, 0))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by ossa gen cue; DO NOT EDIT.\n\n")
+	writeCUEComment(&out, root["title"], 0)
+	out.WriteString("package ossa\n")
+	if len(w.imports) > 0 {
+		pkgs := make([]string, 0, len(w.imports))
+		for p := range w.imports {
+			pkgs = append(pkgs, p)
+		}
+		sort.Strings(pkgs)
+		out.WriteString("\nimport (\n")
+		for _, p := range pkgs {
+			fmt.Fprintf(&out, "\t%q\n", p)
+		}
+		out.WriteString(")\n")
+	}
+	out.WriteString("\n")
+	out.WriteString(body.String())
+	return out.Bytes(), nil
+}
+
+type cueKind struct {
+	name string
+	node map[string]interface{}
+}
+
+// cueKinds splits the root schema into one object schema per kind using its
+// allOf: [{if: {properties: {kind: {const}}}, then: ...}] branches.
+func cueKinds(root map[string]interface{}) []cueKind {
+	var kinds []cueKind
+	for _, branch := range asSlice(root["allOf"]) {
+		b, _ := branch.(map[string]interface{})
+		cond, _ := b["if"].(map[string]interface{})
+		then, _ := b["then"].(map[string]interface{})
+		condProps, _ := cond["properties"].(map[string]interface{})
+		kindCond, _ := condProps["kind"].(map[string]interface{})
+		name, ok := kindCond["const"].(string)
+		if !ok || then == nil {
+			continue
+		}
+
+		props := map[string]interface{}{}
+		rootProps, _ := root["properties"].(map[string]interface{})
+		for k, v := range rootProps {
+			props[k] = v
+		}
+		thenProps, _ := then["properties"].(map[string]interface{})
+		for k, v := range thenProps {
+			props[k] = v
+		}
+		props["kind"] = map[string]interface{}{"const": name}
+		node := map[string]interface{}{
+			"type":       "object",
+			"properties": props,
+			"required":   append(append([]interface{}{}, asSlice(root["required"])...), asSlice(then["required"])...),
+		}
+		if ap, ok := root["additionalProperties"]; ok {
+			node["additionalProperties"] = ap
+		}
+		kinds = append(kinds, cueKind{name: name, node: node})
+	}
+	return kinds
+}
+
+type cueWriter struct {
+	imports map[string]bool
+}
+
+// expr renders a schema node as a CUE expression at the given indent.
+func (w *cueWriter) expr(node map[string]interface{}, indent int) string {
+	if ref, ok := node["$ref"].(string); ok {
+		if name := strings.TrimPrefix(ref, "#/definitions/"); name != ref {
+			return "#" + cueIdent(name)
+		}
+		return "_"
+	}
+	if c, ok := node["const"]; ok {
+		return cueLiteral(c)
+	}
+	if enum := asSlice(node["enum"]); len(enum) > 0 {
+		def, hasDefault := node["default"]
+		alts := make([]string, len(enum))
+		for i, v := range enum {
+			alts[i] = cueLiteral(v)
+			if hasDefault && cueLiteral(def) == alts[i] {
+				alts[i] = "*" + alts[i]
+			}
+		}
+		return strings.Join(alts, " | ")
+	}
+
+	var alts []string
+	for _, key := range []string{"oneOf", "anyOf"} {
+		for _, branch := range asSlice(node[key]) {
+			if b, ok := branch.(map[string]interface{}); ok {
+				alts = append(alts, w.expr(b, indent))
+			}
+		}
+	}
+
+	var types []string
+	switch t := node["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if len(types) == 0 && (node["properties"] != nil || node["additionalProperties"] != nil || node["required"] != nil) {
+		types = []string{"object"}
+	}
+
+	var base string
+	if len(types) > 0 {
+		parts := make([]string, len(types))
+		for i, t := range types {
+			parts[i] = w.typeExpr(t, node, indent)
+		}
+		base = strings.Join(parts, " | ")
+	}
+
+	expr := base
+	switch {
+	case len(alts) > 0 && base != "":
+		if len(types) > 1 {
+			base = "(" + base + ")"
+		}
+		expr = base + " & (" + strings.Join(alts, " | ") + ")"
+	case len(alts) > 0:
+		expr = strings.Join(alts, " | ")
+	case base == "":
+		expr = "_"
+	}
+
+	if def, ok := node["default"]; ok && len(types) == 1 && len(alts) == 0 {
+		switch def.(type) {
+		case string, bool, float64:
+			expr += " & (*" + cueLiteral(def) + " | _)"
+		}
+	}
+	return expr
+}
+
+func (w *cueWriter) typeExpr(typ string, node map[string]interface{}, indent int) string {
+	var parts []string
+	switch typ {
+	case "string":
+		parts = append(parts, "string")
+		if p, ok := node["pattern"].(string); ok {
+			if _, err := regexp.Compile(p); err == nil {
+				parts = append(parts, "=~"+cueLiteral(p))
+			}
+		}
+		if n, ok := node["minLength"].(float64); ok {
+			w.imports["strings"] = true
+			parts = append(parts, fmt.Sprintf("strings.MinRunes(%d)", int(n)))
+		}
+		if n, ok := node["maxLength"].(float64); ok {
+			w.imports["strings"] = true
+			parts = append(parts, fmt.Sprintf("strings.MaxRunes(%d)", int(n)))
+		}
+	case "integer", "number":
+		parts = append(parts, map[string]string{"integer": "int", "number": "number"}[typ])
+		if n, ok := node["minimum"].(float64); ok {
+			parts = append(parts, ">="+cueNumber(n))
+		}
+		if n, ok := node["maximum"].(float64); ok {
+			parts = append(parts, "<="+cueNumber(n))
+		}
+	case "boolean":
+		parts = append(parts, "bool")
+	case "null":
+		parts = append(parts, "null")
+	case "array":
+		item := "_"
+		if items, ok := node["items"].(map[string]interface{}); ok {
+			item = w.expr(items, indent)
+		}
+		if n, ok := node["minItems"].(float64); ok {
+			w.imports["list"] = true
+			parts = append(parts, fmt.Sprintf("list.MinItems(%d)", int(n)))
+		}
+		if n, ok := node["maxItems"].(float64); ok {
+			w.imports["list"] = true
+			parts = append(parts, fmt.Sprintf("list.MaxItems(%d)", int(n)))
+		}
+		parts = append(parts, "[..."+item+"]")
+	case "object":
+		parts = append(parts, w.structExpr(node, indent))
+	default:
+		parts = append(parts, "_")
+	}
+	return strings.Join(parts, " & ")
+}
+
+func (w *cueWriter) structExpr(node map[string]interface{}, indent int) string {
+	props, _ := node["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	for _, r := range asSlice(node["required"]) {
+		if s, ok := r.(string); ok {
+			required[s] = true
+		}
+	}
+	names := make([]string, 0, len(props)+len(required))
+	for name := range props {
+		names = append(names, name)
+	}
+	for name := range required {
+		if _, ok := props[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var lines strings.Builder
+	pad := strings.Repeat("\t", indent+1)
+	for _, name := range names {
+		child, _ := props[name].(map[string]interface{})
+		marker := "?"
+		if required[name] {
+			marker = "!"
+		}
+		value := "_"
+		if child != nil {
+			writeCUEComment(&lines, child["description"], indent+1)
+			value = w.expr(child, indent+1)
+		}
+		fmt.Fprintf(&lines, "%s%s%s: %s\n", pad, cueIdent(name), marker, value)
+	}
+	open := true
+	switch ap := node["additionalProperties"].(type) {
+	case bool:
+		open = ap
+	case map[string]interface{}:
+		open = false
+		fmt.Fprintf(&lines, "%s[string]: %s\n", pad, w.expr(ap, indent+1))
+	}
+	if open {
+		if lines.Len() == 0 {
+			return "{...}"
+		}
+		lines.WriteString(pad + "...\n")
+	}
+	return "{\n" + lines.String() + strings.Repeat("\t", indent) + "}"
+}
+
+var (
+	cueIdentPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	cueKeywords     = map[string]bool{
+		"package": true, "import": true, "for": true, "in": true, "if": true, "let": true,
+		"true": true, "false": true, "null": true, "div": true, "mod": true, "quo": true,
+		"rem": true, "func": true,
+	}
+)
+
+// cueIdent returns name as a CUE label, quoting it when needed.
+func cueIdent(name string) string {
+	if cueIdentPattern.MatchString(name) && !cueKeywords[name] {
+		return name
+	}
+	return cueLiteral(name)
+}
+
+func cueLiteral(v interface{}) string {
+	switch t := v.(type) {
+	case float64:
+		return cueNumber(t)
+	case nil:
+		return "null"
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "_"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func cueNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeCUEComment(b io.StringWriter, desc interface{}, indent int) {
+	text, ok := desc.(string)
+	if !ok || text == "" {
+		return
+	}
+	pad := strings.Repeat("\t", indent)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString(strings.TrimRight(pad+"// "+line, " ") + "\n")
+	}
+}
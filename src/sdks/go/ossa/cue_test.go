@@ -0,0 +1,71 @@
+package ossa
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportCUE(t *testing.T) {
+	schema := []byte(`{
+  "title": "Test",
+  "required": ["kind"],
+  "properties": {"kind": {"type": "string"}, "name": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 8}},
+  "allOf": [{"if": {"properties": {"kind": {"const": "Agent"}}}, "then": {"properties": {"spec": {"$ref": "#/definitions/Spec"}}, "required": ["spec"]}}],
+  "definitions": {
+    "Spec": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "mode": {"type": "string", "enum": ["a", "b"], "default": "b"},
+        "retries": {"type": "integer", "minimum": 0, "default": 3},
+        "x-tags": {"type": "array", "items": {"type": "string"}, "minItems": 1}
+      }
+    }
+  }
+}`)
+	out, err := ExportCUE(schema)
+	if err != nil {
+		t.Fatalf("ExportCUE failed: %v", err)
+	}
+	cue := string(out)
+	for _, want := range []string{
+		"package ossa",
+		`"list"`,
+		`"strings"`,
+		"#Manifest: #Agent",
+		`kind!: "Agent"`,
+		`name?: string & =~"^[a-z]+$" & strings.MaxRunes(8)`,
+		"spec!: #Spec",
+		`mode?: "a" | *"b"`,
+		"retries?: int & >=0 & (*3 | _)",
+		`"x-tags"?: list.MinItems(1) & [...string]`,
+	} {
+		if !strings.Contains(cue, want) {
+			t.Errorf("Expected %q in output:\n%s", want, cue)
+		}
+	}
+	// Spec is closed, the root manifest is not.
+	spec := cue[strings.Index(cue, "#Spec: {"):]
+	if strings.Contains(spec, "\t...\n") {
+		t.Errorf("Expected #Spec to be closed:\n%s", spec)
+	}
+	if agent := cue[strings.Index(cue, "#Agent: {"):strings.Index(cue, "#Spec: {")]; !strings.Contains(agent, "\t...\n") {
+		t.Errorf("Expected #Agent to stay open:\n%s", agent)
+	}
+}
+
+func TestExportCUEUpToDate(t *testing.T) {
+	want, err := os.ReadFile("schema/ossa-0.3.3.cue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ExportCUE(EmbeddedSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("schema/ossa-0.3.3.cue is stale; run: ossa gen cue -o ossa/schema/ossa-0.3.3.cue")
+	}
+}
@@ -0,0 +1,151 @@
+package ossa
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Deprecation describes a manifest field or value that a spec version
+// deprecated. The validator reports each one found as a WarningDeprecated
+// warning and `ossa migrate --fix-deprecations` applies Fix.
+type Deprecation struct {
+	// Path is the dotted field path, e.g. spec.identity.access_tier.
+	Path string
+	// Value narrows the deprecation to some values of the field; empty
+	// means the field itself is deprecated.
+	Value string
+	// Since is the spec version that deprecated it.
+	Since string
+	// Removal is the spec version that removes it.
+	Removal string
+	// Replacement says what to write instead.
+	Replacement string
+
+	// Detect reports whether the manifest uses the deprecated form.
+	Detect func(m *Manifest) bool
+	// Fix rewrites the manifest to the replacement; nil if it needs manual
+	// changes.
+	Fix func(m *Manifest)
+}
+
+// Message returns the warning text reported for d.
+func (d Deprecation) Message() string {
+	what := d.Path
+	if d.Value != "" {
+		what += " " + d.Value
+	}
+	msg := fmt.Sprintf("%s: %s (since %s", WarningDeprecated, what, d.Since)
+	if d.Removal != "" {
+		msg += ", removed in " + d.Removal
+	}
+	msg += ")"
+	if d.Replacement != "" {
+		msg += "; use " + d.Replacement
+	}
+	return msg
+}
+
+var (
+	deprecationsMu sync.RWMutex
+	deprecations   = []Deprecation{
+		{
+			Path:        "apiVersion",
+			Value:       "ossa/v0.2.x and earlier",
+			Since:       "0.3.0",
+			Removal:     "0.5.0",
+			Replacement: "ossa/v" + OSSAVersion,
+			Detect: func(m *Manifest) bool {
+				return deprecatedAPIVersion.MatchString(m.APIVersion)
+			},
+			Fix: func(m *Manifest) {
+				m.APIVersion = "ossa/v" + OSSAVersion
+			},
+		},
+		{
+			Path:        "spec.identity.access_tier",
+			Since:       "0.3.0",
+			Removal:     "0.5.0",
+			Replacement: "spec.access_tier",
+			Detect: func(m *Manifest) bool {
+				return m.Spec.Identity != nil && m.Spec.Identity.AccessTier != ""
+			},
+			Fix: func(m *Manifest) {
+				// spec.access_tier already wins when both are set.
+				if m.Spec.AccessTier == "" {
+					m.Spec.AccessTier = m.Spec.Identity.AccessTier
+				}
+				m.Spec.Identity.AccessTier = ""
+				if *m.Spec.Identity == (Identity{}) {
+					m.Spec.Identity = nil
+				}
+			},
+		},
+		{
+			Path:        "spec.llm.fallback_models",
+			Since:       "0.3.3",
+			Removal:     "0.5.0",
+			Replacement: "spec.llm.fallbacks",
+			Detect: func(m *Manifest) bool {
+				return m.Spec.LLM != nil && len(m.Spec.LLM.FallbackModels) > 0
+			},
+			Fix: func(m *Manifest) {
+				m.Spec.LLM.Fallbacks = append(m.Spec.LLM.Fallbacks, m.Spec.LLM.FallbackModels...)
+				m.Spec.LLM.FallbackModels = nil
+			},
+		},
+	}
+)
+
+// deprecatedAPIVersion matches apiVersions before ossa/v0.3, whose
+// manifests predate the current schema layout.
+var deprecatedAPIVersion = regexp.MustCompile(`^ossa/v0\.[0-2]\.\d+$`)
+
+// RegisterDeprecation adds d to the registry, so extensions can deprecate
+// their own fields. d.Detect is required.
+func RegisterDeprecation(d Deprecation) {
+	if d.Detect == nil {
+		panic("ossa: RegisterDeprecation with nil Detect")
+	}
+	deprecationsMu.Lock()
+	defer deprecationsMu.Unlock()
+	deprecations = append(deprecations, d)
+}
+
+// Deprecations returns the registered deprecations.
+func Deprecations() []Deprecation {
+	deprecationsMu.RLock()
+	defer deprecationsMu.RUnlock()
+	return append([]Deprecation(nil), deprecations...)
+}
+
+// FindDeprecations returns the registered deprecations the manifest uses.
+func (m *Manifest) FindDeprecations() []Deprecation {
+	var found []Deprecation
+	for _, d := range Deprecations() {
+		if d.Detect(m) {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// FixDeprecations rewrites every deprecated form that has a Fix and returns
+// the deprecations it fixed. Deprecations without a Fix are left for the
+// author and still reported by the validator.
+func (m *Manifest) FixDeprecations() []Deprecation {
+	var fixed []Deprecation
+	for _, d := range m.FindDeprecations() {
+		if d.Fix != nil {
+			d.Fix(m)
+			fixed = append(fixed, d)
+		}
+	}
+	return fixed
+}
+
+func validateDeprecations(m *Manifest, result *ValidationResult) {
+	for _, d := range m.FindDeprecations() {
+		result.addWarning(d.Message())
+	}
+}
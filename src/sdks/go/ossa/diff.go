@@ -0,0 +1,90 @@
+package ossa
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is one difference between two manifests. Old is nil for an
+// added field and New is nil for a removed one.
+type FieldChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// String renders the change as "path: old -> new".
+func (c FieldChange) String() string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case c.New == nil:
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// DiffManifests compares the JSON forms of two manifests and returns the
+// changed leaf fields sorted by path. Paths are dotted with list indices,
+// e.g. spec.tools.0.endpoint, as used by Document.
+func DiffManifests(a, b *Manifest) ([]FieldChange, error) {
+	docA, err := manifestDoc(a)
+	if err != nil {
+		return nil, err
+	}
+	docB, err := manifestDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffValues(docA, docB), nil
+}
+
+// DiffValues compares two decoded JSON values, such as agent outputs, the
+// way DiffManifests compares manifests.
+func DiffValues(a, b interface{}) []FieldChange {
+	var changes []FieldChange
+	diffValues("", a, b, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffValues(path string, a, b interface{}, changes *[]FieldChange) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			for k, v := range av {
+				diffValues(join(k), v, bv[k], changes)
+			}
+			for k, v := range bv {
+				if _, ok := av[k]; !ok {
+					diffValues(join(k), nil, v, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			for i := 0; i < len(av) || i < len(bv); i++ {
+				var x, y interface{}
+				if i < len(av) {
+					x = av[i]
+				}
+				if i < len(bv) {
+					y = bv[i]
+				}
+				diffValues(join(fmt.Sprint(i)), x, y, changes)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, FieldChange{Path: path, Old: a, New: b})
+	}
+}
@@ -0,0 +1,239 @@
+package ossa
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a manifest held as a YAML node tree, so programmatic edits
+// keep the author's comments and key order. Paths are dotted with numeric
+// segments for list elements, e.g. spec.tools.0.endpoint. Editing a value
+// reached through an alias edits the anchored value it refers to.
+type Document struct {
+	root yaml.Node
+}
+
+// LoadDocument reads a manifest file as a Document.
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParseDocument(data)
+}
+
+// ParseDocument parses YAML (or JSON) manifest data as a Document.
+func ParseDocument(data []byte) (*Document, error) {
+	data, err := NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	d := &Document{}
+	if err := yaml.Unmarshal(data, &d.root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if d.root.Kind == 0 {
+		d.root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if d.root.Content[0].Kind != yaml.MappingNode {
+		return nil, NewError("manifest must be a mapping")
+	}
+	return d, nil
+}
+
+// Get returns the value at path decoded into generic Go values.
+func (d *Document) Get(path string) (interface{}, bool) {
+	n, err := d.lookup(splitDocPath(path), false)
+	if err != nil || n == nil {
+		return nil, false
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// Set stores v at path, creating missing mappings along the way; a list
+// index equal to the list length appends. Comments on the replaced node are
+// kept.
+func (d *Document) Set(path string, v interface{}) error {
+	var value yaml.Node
+	if err := value.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	n, err := d.lookup(splitDocPath(path), true)
+	if err != nil {
+		return err
+	}
+	value.HeadComment, value.LineComment, value.FootComment = n.HeadComment, n.LineComment, n.FootComment
+	if n.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && value.Tag == "!!str" && n.Tag == "!!str" && value.Style == 0 {
+		value.Style = n.Style
+	}
+	*n = value
+	return nil
+}
+
+// Delete removes the value at path. Deleting a missing path is not an
+// error.
+func (d *Document) Delete(path string) error {
+	segs := splitDocPath(path)
+	if len(segs) == 0 {
+		return NewError("cannot delete the document root")
+	}
+	parent, err := d.lookup(segs[:len(segs)-1], false)
+	if err != nil || parent == nil {
+		return err
+	}
+	last := segs[len(segs)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == last {
+				parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+				return nil
+			}
+		}
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 {
+			return NewError(fmt.Sprintf("%s: invalid list index %q", path, last))
+		}
+		if i < len(parent.Content) {
+			parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of elements of the list at path, or 0.
+func (d *Document) Len(path string) int {
+	n, err := d.lookup(splitDocPath(path), false)
+	if err != nil || n == nil || n.Kind != yaml.SequenceNode {
+		return 0
+	}
+	return len(n.Content)
+}
+
+// Manifest decodes the document as ParseManifest would.
+func (d *Document) Manifest() (*Manifest, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data, ".yaml")
+}
+
+// Validate decodes the document and validates the result.
+func (d *Document) Validate() (*ValidationResult, error) {
+	m, err := d.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	return ValidateManifest(m), nil
+}
+
+// Bytes returns the document as YAML with two-space indentation.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&d.root); err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the document to path.
+func (d *Document) Save(path string) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func splitDocPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookup walks segs from the root mapping. With create, missing keys and
+// an index one past the end of a list are added; otherwise a missing path
+// returns nil without error.
+func (d *Document) lookup(segs []string, create bool) (*yaml.Node, error) {
+	n := d.root.Content[0]
+	for i, seg := range segs {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		where := strings.Join(segs[:i], ".")
+		if create && n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+			// Turn a null into the container the path needs.
+			shaped := newDocNode(segs, i-1)
+			n.Kind, n.Tag, n.Value, n.Style = shaped.Kind, shaped.Tag, "", 0
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			var child *yaml.Node
+			for j := 0; j+1 < len(n.Content); j += 2 {
+				if n.Content[j].Value == seg {
+					child = n.Content[j+1]
+					break
+				}
+			}
+			if child == nil {
+				if !create {
+					return nil, nil
+				}
+				child = newDocNode(segs, i)
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}, child)
+			}
+			n = child
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 {
+				return nil, NewError(fmt.Sprintf("%s: invalid list index %q", where, seg))
+			}
+			switch {
+			case idx < len(n.Content):
+				n = n.Content[idx]
+			case idx == len(n.Content) && create:
+				child := newDocNode(segs, i)
+				n.Content = append(n.Content, child)
+				n = child
+			case create:
+				return nil, NewError(fmt.Sprintf("%s: list index %d out of range", where, idx))
+			default:
+				return nil, nil
+			}
+		default:
+			if create {
+				return nil, NewError(fmt.Sprintf("%s is not a mapping or list", where))
+			}
+			return nil, nil
+		}
+	}
+	return n, nil
+}
+
+// newDocNode returns an empty node for segs[i], shaped for segs[i+1].
+func newDocNode(segs []string, i int) *yaml.Node {
+	if i+1 >= len(segs) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	if _, err := strconv.Atoi(segs[i+1]); err == nil {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
@@ -0,0 +1,98 @@
+package ossa
+
+import (
+	"strings"
+	"testing"
+)
+
+const documentYAML = `# Team agent
+apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: doc-agent # keep this name
+spec:
+  role: "You review code."
+  llm:
+    provider: openai
+    model: gpt-4o
+  tools:
+    - type: mcp
+      name: search
+`
+
+func TestDocumentEditPreservesComments(t *testing.T) {
+	d, err := ParseDocument([]byte(documentYAML))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	if v, ok := d.Get("spec.tools.0.name"); !ok || v != "search" {
+		t.Errorf("Expected tool name search, got %v", v)
+	}
+	if _, ok := d.Get("spec.safety.guardrails"); ok {
+		t.Error("Expected missing path")
+	}
+
+	for path, v := range map[string]interface{}{
+		"metadata.name":                         "renamed",
+		"spec.llm.temperature":                  0.3,
+		"spec.safety.guardrails.max_tool_calls": 5,
+		"spec.tools.1":                          map[string]interface{}{"type": "http", "name": "fetch"},
+		"metadata.labels.team":                  "core",
+	} {
+		if err := d.Set(path, v); err != nil {
+			t.Fatalf("Set(%s) failed: %v", path, err)
+		}
+	}
+	if err := d.Set("spec.tools.5.name", "x"); err == nil {
+		t.Error("Expected out of range error")
+	}
+	if err := d.Delete("spec.llm.model"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	data, err := d.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{"# Team agent\n", "name: renamed # keep this name", "role: \"You review code.\"", "max_tool_calls: 5", "name: fetch"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "gpt-4o") {
+		t.Errorf("Expected model deleted:\n%s", out)
+	}
+	if d.Len("spec.tools") != 2 {
+		t.Errorf("Expected 2 tools, got %d", d.Len("spec.tools"))
+	}
+
+	m, err := d.Manifest()
+	if err != nil {
+		t.Fatalf("Manifest failed: %v", err)
+	}
+	if m.Metadata.Name != "renamed" || m.Spec.LLM.Temperature != 0.3 || m.Metadata.Labels["team"] != "core" {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+	result, err := d.Validate()
+	if err != nil || !result.Valid {
+		t.Errorf("Expected valid document, got %+v, %v", result, err)
+	}
+}
+
+func TestDocumentSetThroughScalar(t *testing.T) {
+	d, err := ParseDocument([]byte("spec:\n  role: reviewer\n  safety:\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("spec.role.en", "x"); err == nil {
+		t.Error("Expected error setting below a string")
+	}
+	if err := d.Set("spec.safety.guardrails.enabled", true); err != nil {
+		t.Errorf("Expected null to become a mapping, got %v", err)
+	}
+	if v, _ := d.Get("spec.safety.guardrails.enabled"); v != true {
+		t.Errorf("Expected true, got %v", v)
+	}
+}
@@ -0,0 +1,119 @@
+package ossa
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Drupal authentication modes for handler.drupal.auth.
+const (
+	DrupalAuthNone    = "none"
+	DrupalAuthSession = "session"
+	DrupalAuthOAuth   = "oauth"
+)
+
+// JSON:API operations used as the last part of a Drupal capability.
+const (
+	DrupalOpList   = "list"
+	DrupalOpGet    = "get"
+	DrupalOpCreate = "create"
+	DrupalOpUpdate = "update"
+	DrupalOpDelete = "delete"
+)
+
+var drupalOpMethods = map[string]string{
+	DrupalOpList:   http.MethodGet,
+	DrupalOpGet:    http.MethodGet,
+	DrupalOpCreate: http.MethodPost,
+	DrupalOpUpdate: http.MethodPatch,
+	DrupalOpDelete: http.MethodDelete,
+}
+
+var drupalMachineName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// DrupalRoute is where a capability is served.
+type DrupalRoute struct {
+	Method string
+	// Path is relative to base_url and may hold {name} placeholders.
+	Path string
+	// JSONAPI is set for JSON:API resources, which take and return
+	// application/vnd.api+json documents.
+	JSONAPI bool
+	// Type is the JSON:API resource type, such as "node--article".
+	Type string
+}
+
+// Route resolves a capability. Capabilities listed in endpoints map to that
+// custom REST resource; others must have the form entity.bundle.operation,
+// for example node.article.create, and map to the JSON:API resource.
+func (c *DrupalConfig) Route(capability string) (DrupalRoute, error) {
+	if ep, ok := c.Endpoints[capability]; ok {
+		method := strings.ToUpper(ep.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		return DrupalRoute{Method: method, Path: ep.Path}, nil
+	}
+
+	parts := strings.Split(capability, ".")
+	if len(parts) != 3 || !drupalMachineName.MatchString(parts[0]) || !drupalMachineName.MatchString(parts[1]) {
+		return DrupalRoute{}, NewError(fmt.Sprintf("capability %q is not a custom endpoint or entity.bundle.operation", capability))
+	}
+	method, ok := drupalOpMethods[parts[2]]
+	if !ok {
+		return DrupalRoute{}, NewError(fmt.Sprintf("capability %q has unknown operation %s", capability, parts[2]))
+	}
+	path := "/jsonapi/" + parts[0] + "/" + parts[1]
+	switch parts[2] {
+	case DrupalOpGet, DrupalOpUpdate, DrupalOpDelete:
+		path += "/{id}"
+	}
+	return DrupalRoute{Method: method, Path: path, JSONAPI: true, Type: parts[0] + "--" + parts[1]}, nil
+}
+
+func validateDrupal(path string, h *ToolHandler, result *ValidationResult) {
+	cfg := h.Drupal
+	if cfg == nil {
+		result.addError(path + ".drupal: required for drupal runtime")
+		return
+	}
+	if u, err := url.Parse(cfg.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		result.addError(fmt.Sprintf("%s.drupal.base_url: invalid URL: %s", path, cfg.BaseURL))
+	} else if u.Scheme == "http" && cfg.Auth != "" && cfg.Auth != DrupalAuthNone {
+		result.addWarning(fmt.Sprintf("%s.drupal.base_url: credentials will be sent over plain http", path))
+	}
+
+	switch cfg.Auth {
+	case "", DrupalAuthNone:
+	case DrupalAuthSession:
+		if cfg.UsernameEnv == "" || cfg.PasswordEnv == "" {
+			result.addError(path + ".drupal: session auth requires username_env and password_env")
+		}
+	case DrupalAuthOAuth:
+		if cfg.ClientIDEnv == "" || cfg.ClientSecretEnv == "" {
+			result.addError(path + ".drupal: oauth auth requires client_id_env and client_secret_env")
+		}
+	default:
+		result.addError(fmt.Sprintf("%s.drupal.auth: invalid auth: %s", path, cfg.Auth))
+	}
+
+	capabilities := make([]string, 0, len(cfg.Endpoints))
+	for capability := range cfg.Endpoints {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	for _, capability := range capabilities {
+		if ep := cfg.Endpoints[capability]; !strings.HasPrefix(ep.Path, "/") {
+			result.addError(fmt.Sprintf("%s.drupal.endpoints.%s.path: must start with /", path, capability))
+		}
+	}
+	if h.Capability == "" {
+		result.addError(path + ".capability: required for drupal runtime")
+	} else if _, err := cfg.Route(h.Capability); err != nil {
+		result.addError(fmt.Sprintf("%s.capability: %v", path, err))
+	}
+}
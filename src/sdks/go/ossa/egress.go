@@ -0,0 +1,177 @@
+package ossa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EgressFile is the workspace egress config, found in the manifest's
+// directory or any above it, as VendorDir is.
+const EgressFile = ".ossa/egress.yaml"
+
+// ProxyDirect as EgressConfig.Proxy connects directly, ignoring
+// HTTPS_PROXY and HTTP_PROXY.
+const ProxyDirect = "direct"
+
+// EgressConfig routes the HTTP requests tool runtimes make in networks
+// that only reach the outside through a proxy. Without a Proxy, requests
+// use HTTPS_PROXY, HTTP_PROXY and NO_PROXY from the environment.
+type EgressConfig struct {
+	// Proxy is the http://, https:// or socks5:// proxy URL, "direct", or
+	// a ${VAR} reference to either.
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// NoProxy are hosts reached directly: names, *.example.com
+	// subdomains, IP addresses or CIDRs. They add to $NO_PROXY.
+	NoProxy []string `json:"no_proxy,omitempty" yaml:"no_proxy,omitempty"`
+}
+
+// LoadEgress reads an egress config from a YAML or JSON file. Unknown
+// fields are errors.
+func LoadEgress(file string) (*EgressConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read egress config: %w", err)
+	}
+	data, err = NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode egress config: %w", err)
+	}
+	e := &EgressConfig{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(e); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse egress config %s: %w", file, err)
+	}
+	result := &ValidationResult{Valid: true}
+	validateEgress("egress", e, result)
+	if len(result.Errors) > 0 {
+		return nil, NewError(fmt.Sprintf("%s: %s", file, strings.Join(result.Errors, "; ")))
+	}
+	return e, nil
+}
+
+// FindEgress returns the EgressFile in start or the nearest directory
+// above it, or nil if there is none.
+func FindEgress(start string) (*EgressConfig, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		candidate := filepath.Join(dir, EgressFile)
+		if _, err := os.Stat(candidate); err == nil {
+			return LoadEgress(candidate)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// EgressFor returns the egress config of m's tools: the workspace's, found
+// from baseDir, with spec.egress overriding its proxy and adding to its
+// no_proxy. It is nil when neither is set.
+func (m *Manifest) EgressFor(baseDir string) (*EgressConfig, error) {
+	var e *EgressConfig
+	if baseDir != "" {
+		ws, err := FindEgress(baseDir)
+		if err != nil {
+			return nil, err
+		}
+		e = ws
+	}
+	if spec := m.Spec.Egress; spec != nil {
+		merged := &EgressConfig{}
+		if e != nil {
+			*merged = *e
+			merged.NoProxy = append([]string{}, e.NoProxy...)
+		}
+		if spec.Proxy != "" {
+			merged.Proxy = spec.Proxy
+		}
+		merged.NoProxy = append(merged.NoProxy, spec.NoProxy...)
+		e = merged
+	}
+	return e, nil
+}
+
+// ProxyFunc returns the http.Transport Proxy function routing requests as
+// e says.
+func (e *EgressConfig) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxy := os.ExpandEnv(e.Proxy)
+	var fixed *url.URL
+	if proxy != "" && proxy != ProxyDirect {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, NewError(fmt.Sprintf("invalid proxy %q", e.Proxy))
+		}
+		fixed = u
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if e.bypasses(req.URL.Hostname()) {
+			return nil, nil
+		}
+		switch {
+		case fixed != nil:
+			return fixed, nil
+		case proxy == ProxyDirect:
+			return nil, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+// bypasses reports whether host is in NoProxy.
+func (e *EgressConfig) bypasses(host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range e.NoProxy {
+		entry = strings.ToLower(entry)
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entry == host || (strings.HasPrefix(entry, ".") && strings.HasSuffix(host, entry)) {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEgress(path string, e *EgressConfig, result *ValidationResult) {
+	if p := e.Proxy; p != "" && p != ProxyDirect && !strings.Contains(p, "${") {
+		u, err := url.Parse(p)
+		switch {
+		case err != nil || u.Host == "":
+			result.addError(fmt.Sprintf("%s.proxy: invalid proxy URL %q", path, p))
+		case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5":
+			result.addError(fmt.Sprintf("%s.proxy: expected an http, https or socks5 proxy, got %s", path, u.Scheme))
+		}
+	}
+	for i, entry := range e.NoProxy {
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				result.addError(fmt.Sprintf("%s.no_proxy[%d]: invalid CIDR %q", path, i, entry))
+			}
+		} else if entry == "" || strings.Contains(entry, "://") {
+			result.addError(fmt.Sprintf("%s.no_proxy[%d]: expected a host, domain or CIDR, got %q", path, i, entry))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ossa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// NormalizeEncoding converts manifest bytes to BOM-less UTF-8 with LF line
+// endings. UTF-16 input is detected by its byte order mark, or heuristically
+// by NUL byte placement when the BOM is missing.
+func NormalizeEncoding(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		data = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		decoded, err := decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	case bytes.HasPrefix(data, bomUTF16BE):
+		decoded, err := decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	default:
+		if order := guessUTF16(data); order != nil {
+			decoded, err := decodeUTF16(data, order)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded
+		}
+	}
+
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("manifest is not valid UTF-8 or UTF-16")
+	}
+
+	if bytes.IndexByte(data, '\r') >= 0 {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	}
+
+	return data, nil
+}
+
+// guessUTF16 detects BOM-less UTF-16 from the first characters, which in a
+// manifest are always ASCII (a key, comment, or brace).
+func guessUTF16(data []byte) binary.ByteOrder {
+	if len(data) < 4 || len(data)%2 != 0 {
+		return nil
+	}
+	switch {
+	case data[0] != 0 && data[1] == 0 && data[2] != 0 && data[3] == 0:
+		return binary.LittleEndian
+	case data[0] == 0 && data[1] != 0 && data[2] == 0 && data[3] != 0:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid UTF-16 manifest: odd byte length %d", len(data))
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	runes := utf16.Decode(units)
+
+	buf := make([]byte, 0, len(runes))
+	for _, r := range runes {
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf, nil
+}
@@ -0,0 +1,70 @@
+package ossa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync/atomic"
+)
+
+// EncryptedHeader begins manifests encrypted at rest, in the age format
+// package crypt reads and writes.
+const EncryptedHeader = "age-encryption.org/v1\n"
+
+// ErrEncrypted is returned for encrypted data when no decrypter is set.
+var ErrEncrypted = errors.New("encrypted, and no identity is configured to decrypt it")
+
+var decrypter atomic.Pointer[func([]byte) ([]byte, error)]
+
+// SetDecrypter sets the function that decrypts encrypted manifests as
+// LoadManifest and ReadManifestFile read them, such as a crypt.Keyring's
+// Decrypt. nil leaves encrypted manifests unreadable, the default.
+func SetDecrypter(f func(data []byte) ([]byte, error)) {
+	if f == nil {
+		decrypter.Store(nil)
+		return
+	}
+	decrypter.Store(&f)
+}
+
+// IsEncrypted reports whether data is encrypted at rest.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EncryptedHeader))
+}
+
+// Decrypt returns data decrypted by the SetDecrypter function if it is
+// encrypted, and as it is otherwise.
+func Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	f := decrypter.Load()
+	if f == nil {
+		return nil, ErrEncrypted
+	}
+	plain, err := (*f)(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+// ReadManifestFile reads a file, decrypting it if it is encrypted.
+func ReadManifestFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data)
+}
+
+// ReadManifestFileFS is ReadManifestFile for the file name in fsys.
+func ReadManifestFileFS(fsys fs.FS, name string) ([]byte, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data)
+}
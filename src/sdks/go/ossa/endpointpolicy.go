@@ -0,0 +1,178 @@
+package ossa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointPolicyRule is the name of the rule an EndpointPolicy registers
+// as.
+const EndpointPolicyRule = "ossa/endpoint-policy"
+
+// EndpointPolicy is an organization's allowlist for the endpoints tools
+// may call. Registered with RegisterRule(p.Rule()), it fails validation of
+// manifests whose tool endpoints, handler endpoints or Drupal base URLs it
+// does not allow; Transport enforces the same allowlist on the requests
+// tool runtimes make, in case a manifest skipped validation or a script
+// builds its URLs at run time.
+type EndpointPolicy struct {
+	// AllowedDomains are host names, matched exactly or, written as
+	// "*.example.com", any subdomain.
+	AllowedDomains []string `json:"allowed_domains,omitempty" yaml:"allowed_domains,omitempty"`
+	// AllowedCIDRs are the networks endpoints given as IP addresses may
+	// be in, such as 10.0.0.0/8.
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty" yaml:"allowed_cidrs,omitempty"`
+	// AllowedSchemes default to https only.
+	AllowedSchemes []string `json:"allowed_schemes,omitempty" yaml:"allowed_schemes,omitempty"`
+}
+
+// LoadEndpointPolicy reads an endpoint policy from a YAML or JSON file.
+// Unknown fields are errors, as in LoadModelPolicy.
+func LoadEndpointPolicy(file string) (*EndpointPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoint policy: %w", err)
+	}
+	data, err = NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode endpoint policy: %w", err)
+	}
+	p := &EndpointPolicy{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse endpoint policy %s: %w", file, err)
+	}
+	if err := p.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return p, nil
+}
+
+func (p *EndpointPolicy) check() error {
+	for _, cidr := range p.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return NewError(fmt.Sprintf("invalid CIDR %q", cidr))
+		}
+	}
+	for _, domain := range p.AllowedDomains {
+		if strings.ContainsAny(domain, "/:") || strings.Contains(strings.TrimPrefix(domain, "*."), "*") {
+			return NewError(fmt.Sprintf("invalid domain %q: want a host name or *.example.com", domain))
+		}
+	}
+	return nil
+}
+
+// Rule returns the rule enforcing p.
+func (p *EndpointPolicy) Rule() Rule {
+	return Rule{Name: EndpointPolicyRule, Check: p.Check}
+}
+
+// Check reports the tool endpoints of m that p does not allow. Endpoints
+// taken from the environment (${VAR}) are warned about, as they can only
+// be checked once expanded at run time.
+func (p *EndpointPolicy) Check(m *Manifest, r *RuleReport) {
+	for i, tool := range m.Spec.Tools {
+		at := fmt.Sprintf("spec.tools[%d]", i)
+		p.checkEndpoint(at+".endpoint", tool.Endpoint, r)
+		if h := tool.Handler; h != nil {
+			p.checkEndpoint(at+".handler.endpoint", h.Endpoint, r)
+			if h.Drupal != nil {
+				p.checkEndpoint(at+".handler.drupal.base_url", h.Drupal.BaseURL, r)
+			}
+		}
+	}
+}
+
+func (p *EndpointPolicy) checkEndpoint(at, endpoint string, r *RuleReport) {
+	switch {
+	case endpoint == "":
+	case strings.Contains(endpoint, "${"):
+		r.Warn(at, fmt.Sprintf("%s is read from the environment and is only checked when called", endpoint))
+	default:
+		if err := p.Allows(endpoint); err != nil {
+			r.Error(at, err.Error())
+		}
+	}
+}
+
+// Allows returns an error unless p allows requests to rawURL: its scheme
+// must be allowed and, if p lists any domains or CIDRs, its host must
+// match one. IP addresses only match CIDRs.
+func (p *EndpointPolicy) Allows(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return NewError(fmt.Sprintf("endpoint %q is not an absolute URL", rawURL))
+	}
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	allowed := false
+	for _, s := range schemes {
+		allowed = allowed || strings.EqualFold(s, u.Scheme)
+	}
+	if !allowed {
+		return NewError(fmt.Sprintf("scheme %s of %s is not allowed (allowed: %s)", u.Scheme, u.Redacted(), strings.Join(schemes, ", ")))
+	}
+	if len(p.AllowedDomains) == 0 && len(p.AllowedCIDRs) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		for _, cidr := range p.AllowedCIDRs {
+			if _, n, err := net.ParseCIDR(cidr); err == nil && n.Contains(ip) {
+				return nil
+			}
+		}
+		return NewError(fmt.Sprintf("address %s is not in an allowed network", host))
+	}
+	if (&SandboxPolicy{AllowedHosts: p.AllowedDomains}).AllowsHost(host) {
+		return nil
+	}
+	return NewError(fmt.Sprintf("host %s is not an allowed domain", host))
+}
+
+// Transport returns base, or http.DefaultTransport if nil, refusing
+// requests, redirects included, that p does not allow.
+func (p *EndpointPolicy) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &endpointTransport{policy: p, base: base}
+}
+
+// Client returns a copy of c, or of a zero client if nil, whose transport
+// enforces p.
+func (p *EndpointPolicy) Client(c *http.Client) *http.Client {
+	out := &http.Client{}
+	if c != nil {
+		*out = *c
+	}
+	out.Transport = p.Transport(out.Transport)
+	return out
+}
+
+type endpointTransport struct {
+	policy *EndpointPolicy
+	base   http.RoundTripper
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.Allows(req.URL.String()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, WrapError("endpoint policy", err)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,110 @@
+package ossa
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+)
+
+// Escalation targets used in spec.escalation.severity.
+const (
+	EscalateOnCall = "oncall_channel"
+	EscalateEmail  = "email"
+	EscalatePager  = "pager"
+)
+
+// Severity levels accepted in spec.escalation.severity.
+var ValidSeverities = map[string]bool{
+	"critical": true,
+	"high":     true,
+	"medium":   true,
+	"low":      true,
+	"info":     true,
+}
+
+// ValidPagerProviders are the supported pager integrations.
+var ValidPagerProviders = map[string]bool{
+	"pagerduty": true,
+	"opsgenie":  true,
+	"victorops": true,
+}
+
+var oncallChannelPattern = regexp.MustCompile(`^#[a-z0-9][a-z0-9._-]{0,79}$`)
+
+// EscalationTarget is the resolved contact for a severity.
+type EscalationTarget struct {
+	Type    string
+	Address string
+}
+
+// TargetFor returns the contact to notify for a severity. Severities without
+// a mapping fall back to the on-call channel, then email.
+func (e *EscalationConfig) TargetFor(severity string) (EscalationTarget, bool) {
+	if target, ok := e.Severity[severity]; ok {
+		return e.resolve(target)
+	}
+	if e.OnCallChannel != "" {
+		return e.resolve(EscalateOnCall)
+	}
+	return e.resolve(EscalateEmail)
+}
+
+func (e *EscalationConfig) resolve(target string) (EscalationTarget, bool) {
+	switch target {
+	case EscalateOnCall:
+		return EscalationTarget{Type: target, Address: e.OnCallChannel}, e.OnCallChannel != ""
+	case EscalateEmail:
+		return EscalationTarget{Type: target, Address: e.Email}, e.Email != ""
+	case EscalatePager:
+		if e.Pager == nil || e.Pager.Service == "" {
+			return EscalationTarget{Type: target}, false
+		}
+		return EscalationTarget{Type: target, Address: e.Pager.Provider + ":" + e.Pager.Service}, true
+	}
+	return EscalationTarget{}, false
+}
+
+func validateEscalation(e *EscalationConfig, result *ValidationResult) {
+	if e == nil {
+		return
+	}
+
+	if e.OnCallChannel == "" && e.Email == "" && e.Pager == nil {
+		result.addError("spec.escalation: at least one of oncall_channel, email or pager is required")
+	}
+	if e.OnCallChannel != "" && !oncallChannelPattern.MatchString(e.OnCallChannel) {
+		result.addError(fmt.Sprintf("spec.escalation.oncall_channel: invalid channel: %s", e.OnCallChannel))
+	}
+	if e.Email != "" {
+		if addr, err := mail.ParseAddress(e.Email); err != nil || addr.Address != e.Email {
+			result.addError(fmt.Sprintf("spec.escalation.email: invalid address: %s", e.Email))
+		}
+	}
+	if e.Pager != nil {
+		if !ValidPagerProviders[e.Pager.Provider] {
+			result.addError(fmt.Sprintf("spec.escalation.pager.provider: invalid provider: %s", e.Pager.Provider))
+		}
+		if e.Pager.Service == "" {
+			result.addError("spec.escalation.pager: missing service")
+		}
+	}
+
+	severities := make([]string, 0, len(e.Severity))
+	for severity := range e.Severity {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+	for _, severity := range severities {
+		target := e.Severity[severity]
+		if !ValidSeverities[severity] {
+			result.addError(fmt.Sprintf("spec.escalation.severity: invalid severity: %s", severity))
+			continue
+		}
+		if target != EscalateOnCall && target != EscalateEmail && target != EscalatePager {
+			result.addError(fmt.Sprintf("spec.escalation.severity.%s: invalid target: %s", severity, target))
+		} else if _, ok := e.resolve(target); !ok {
+			result.addError(fmt.Sprintf("spec.escalation.severity.%s: target %q is not configured", severity, target))
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldExplanation describes one schema field, as printed by ossa explain.
+type FieldExplanation struct {
+	Path        string
+	Type        string
+	Description string
+	Required    bool
+	Enum        []interface{}
+	Default     interface{}
+	// Constraints are rendered keyword: value pairs such as "minimum: 1".
+	Constraints []string
+	Examples    []interface{}
+	// Fields lists the properties of an object field.
+	Fields []FieldSummary
+}
+
+// FieldSummary is one property of an object field.
+type FieldSummary struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+}
+
+// explainConstraints are the keywords reported as constraints, in order.
+var explainConstraints = []string{
+	"pattern", "format", "minLength", "maxLength", "minimum", "maximum",
+	"exclusiveMinimum", "exclusiveMaximum", "minItems", "maxItems",
+	"uniqueItems", "minProperties", "maxProperties", "const",
+}
+
+// ExplainPath looks up a dotted field path such as spec.safety.guardrails
+// in a JSON Schema, usually EmbeddedSchema(). Lists are entered
+// implicitly, so spec.tools.name is the name of each tool; a [] suffix on a
+// segment is also accepted. kind picks the spec definition for that kind;
+// empty combines the definitions of all kinds.
+func ExplainPath(schema []byte, path string, kind Kind) (*FieldExplanation, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	e := &explainer{root: root, kind: kind}
+
+	nodes := []map[string]interface{}{root}
+	required := false
+	var walked []string
+	for _, seg := range strings.Split(path, ".") {
+		seg = strings.TrimSuffix(seg, "[]")
+		if seg == "" {
+			return nil, NewError(fmt.Sprintf("invalid path %q", path))
+		}
+		var next []map[string]interface{}
+		var available []string
+		required = false
+		for _, n := range nodes {
+			for _, c := range e.expand(e.items(n), 0) {
+				props, _ := c["properties"].(map[string]interface{})
+				if child, ok := props[seg].(map[string]interface{}); ok {
+					next = append(next, child)
+					required = required || containsString(asSlice(c["required"]), seg)
+				}
+				for name := range props {
+					available = append(available, name)
+				}
+			}
+		}
+		if len(next) == 0 {
+			where := "the manifest root"
+			if len(walked) > 0 {
+				where = strings.Join(walked, ".")
+			}
+			msg := fmt.Sprintf("field %q not found in %s", seg, where)
+			if names := uniqueSorted(available); len(names) > 0 {
+				msg += "; fields: " + strings.Join(names, ", ")
+			}
+			return nil, NewError(msg)
+		}
+		nodes = next
+		walked = append(walked, seg)
+	}
+
+	// A field can be defined in several places, e.g. spec once per kind.
+	all := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		all[i] = n
+	}
+	field := map[string]interface{}{"allOf": all}
+	out := &FieldExplanation{Path: path, Type: e.typeName(field), Required: required}
+	for _, c := range e.expand(field, 0) {
+		if out.Description == "" {
+			out.Description, _ = c["description"].(string)
+		}
+		if out.Enum == nil {
+			out.Enum = asSlice(c["enum"])
+		}
+		if out.Default == nil {
+			out.Default = c["default"]
+		}
+		if out.Examples == nil {
+			out.Examples = asSlice(c["examples"])
+		}
+		for _, key := range explainConstraints {
+			if v, ok := c[key]; ok {
+				out.Constraints = append(out.Constraints, fmt.Sprintf("%s: %v", key, v))
+			}
+		}
+	}
+	out.Fields = e.fields(e.items(field))
+	return out, nil
+}
+
+type explainer struct {
+	root map[string]interface{}
+	kind Kind
+}
+
+// expand returns node and every schema it is combined with: $ref targets,
+// allOf members, oneOf/anyOf alternatives and if/then branches for the
+// selected kind.
+func (e *explainer) expand(node map[string]interface{}, depth int) []map[string]interface{} {
+	if node == nil || depth > 32 {
+		return nil
+	}
+	out := []map[string]interface{}{node}
+	if ref, ok := node["$ref"].(string); ok {
+		out = append(out, e.expand(e.resolve(ref), depth+1)...)
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		for _, sub := range asSlice(node[key]) {
+			if m, ok := sub.(map[string]interface{}); ok {
+				out = append(out, e.expand(m, depth+1)...)
+			}
+		}
+	}
+	if then, ok := node["then"].(map[string]interface{}); ok && e.matchesKind(node) {
+		out = append(out, e.expand(then, depth+1)...)
+	}
+	return out
+}
+
+// matchesKind reports whether an if/then branch applies to the selected
+// kind; branches not conditioned on kind always apply.
+func (e *explainer) matchesKind(branch map[string]interface{}) bool {
+	cond, _ := branch["if"].(map[string]interface{})
+	props, _ := cond["properties"].(map[string]interface{})
+	kindCond, _ := props["kind"].(map[string]interface{})
+	want, ok := kindCond["const"].(string)
+	return !ok || e.kind == "" || Kind(want) == e.kind
+}
+
+func (e *explainer) resolve(ref string) map[string]interface{} {
+	name := strings.TrimPrefix(ref, "#/definitions/")
+	if name == ref {
+		return nil
+	}
+	defs, _ := e.root["definitions"].(map[string]interface{})
+	def, _ := defs[name].(map[string]interface{})
+	return def
+}
+
+// items returns the item schema of an array node, or node itself.
+func (e *explainer) items(node map[string]interface{}) map[string]interface{} {
+	for _, c := range e.expand(node, 0) {
+		if items, ok := c["items"].(map[string]interface{}); ok {
+			return items
+		}
+	}
+	return node
+}
+
+func (e *explainer) typeName(node map[string]interface{}) string {
+	var kinds []string
+	ref := ""
+	for _, c := range e.expand(node, 0) {
+		if r, ok := c["$ref"].(string); ok && ref == "" {
+			ref = strings.TrimPrefix(r, "#/definitions/")
+		}
+		switch t := c["type"].(type) {
+		case string:
+			kinds = append(kinds, t)
+		case []interface{}:
+			for _, v := range t {
+				if s, ok := v.(string); ok {
+					kinds = append(kinds, s)
+				}
+			}
+		}
+		if len(kinds) > 0 {
+			break
+		}
+	}
+	if len(kinds) == 0 {
+		for _, c := range e.expand(node, 0) {
+			if c["properties"] != nil {
+				kinds = []string{"object"}
+				break
+			}
+		}
+	}
+
+	name := strings.Join(uniqueSorted(kinds), " | ")
+	switch name {
+	case "":
+		name = "any"
+	case "array":
+		name = "[]" + e.typeName(e.items(node))
+	case "object":
+		for _, c := range e.expand(node, 0) {
+			if extra, ok := c["additionalProperties"].(map[string]interface{}); ok && c["properties"] == nil {
+				return "map[string]" + e.typeName(extra)
+			}
+		}
+	}
+	if ref != "" && name == "object" {
+		return "object (" + ref + ")"
+	}
+	return name
+}
+
+func (e *explainer) fields(node map[string]interface{}) []FieldSummary {
+	byName := map[string]FieldSummary{}
+	for _, c := range e.expand(node, 0) {
+		props, _ := c["properties"].(map[string]interface{})
+		for name, p := range props {
+			child, _ := p.(map[string]interface{})
+			f, seen := byName[name]
+			if !seen {
+				f = FieldSummary{Name: name, Type: e.typeName(child)}
+				for _, cc := range e.expand(child, 0) {
+					if f.Description, _ = cc["description"].(string); f.Description != "" {
+						break
+					}
+				}
+			}
+			f.Required = f.Required || containsString(asSlice(c["required"]), name)
+			byName[name] = f
+		}
+	}
+	out := make([]FieldSummary, 0, len(byName))
+	for _, name := range uniqueSorted(keysOf(byName)) {
+		out = append(out, byName[name])
+	}
+	return out
+}
+
+func containsString(list []interface{}, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(s []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func keysOf(m map[string]FieldSummary) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,51 @@
+package ossa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainPath(t *testing.T) {
+	e, err := ExplainPath(EmbeddedSchema(), "spec.safety.guardrails", KindAgent)
+	if err != nil {
+		t.Fatalf("ExplainPath failed: %v", err)
+	}
+	if e.Type != "object" || e.Description != "Guardrails for agent behavior" {
+		t.Errorf("Unexpected explanation: %+v", e)
+	}
+	names := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		names[i] = f.Name
+	}
+	if !strings.Contains(strings.Join(names, ","), "max_tool_calls") {
+		t.Errorf("Expected guardrails fields, got %v", names)
+	}
+
+	e, err = ExplainPath(EmbeddedSchema(), "apiVersion", "")
+	if err != nil {
+		t.Fatalf("ExplainPath failed: %v", err)
+	}
+	if e.Type != "string" || !e.Required || len(e.Examples) == 0 || len(e.Constraints) != 1 || !strings.HasPrefix(e.Constraints[0], "pattern: ") {
+		t.Errorf("Unexpected apiVersion explanation: %+v", e)
+	}
+
+	e, err = ExplainPath(EmbeddedSchema(), "spec.tools[].type", KindAgent)
+	if err != nil {
+		t.Fatalf("ExplainPath failed: %v", err)
+	}
+	if e.Type != "string" || !e.Required || len(e.Enum) == 0 {
+		t.Errorf("Expected tool type enum, got %+v", e)
+	}
+
+	if e, err := ExplainPath(EmbeddedSchema(), "metadata.labels", ""); err != nil || e.Type != "map[string]string" {
+		t.Errorf("Expected labels map, got %+v, %v", e, err)
+	}
+
+	_, err = ExplainPath(EmbeddedSchema(), "spec.safety.guardrails.max_actions_per_minute", KindAgent)
+	if err == nil || !strings.Contains(err.Error(), "not found in spec.safety.guardrails; fields: enabled,") {
+		t.Errorf("Expected not found error listing fields, got %v", err)
+	}
+	if _, err := ExplainPath(EmbeddedSchema(), "spec..role", ""); err == nil {
+		t.Error("Expected error for empty segment")
+	}
+}
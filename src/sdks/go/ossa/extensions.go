@@ -0,0 +1,273 @@
+package ossa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// VendorPrefix marks organization-specific fields, e.g. x-acme-cost-center.
+// Vendor fields are allowed at the top level of a manifest and in spec;
+// they are kept in VendorFields and written back unchanged.
+const VendorPrefix = "x-"
+
+// IsVendorField reports whether key is a vendor field name.
+func IsVendorField(key string) bool {
+	return strings.HasPrefix(key, VendorPrefix) && len(key) > len(VendorPrefix)
+}
+
+// Extensions returns every extension the manifest carries: the entries of
+// spec.extensions keyed by name, and the vendor fields of spec and of the
+// manifest keyed with their x- prefix. Top-level vendor fields win over
+// spec ones of the same name. It returns nil if there are none.
+func (m *Manifest) Extensions() map[string]interface{} {
+	n := len(m.Spec.Extensions) + len(m.Spec.VendorFields) + len(m.VendorFields)
+	if n == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, n)
+	for _, src := range []map[string]interface{}{m.Spec.Extensions, m.Spec.VendorFields, m.VendorFields} {
+		for k, v := range src {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+var (
+	extensionSchemasMu sync.RWMutex
+	extensionSchemas   = map[string]*gojsonschema.Schema{}
+)
+
+// RegisterExtensionSchema registers a JSON Schema that the extension named
+// name (a spec.extensions key, or a vendor field such as x-acme) must match
+// wherever a manifest carries it. Validators then report mismatches as
+// errors. Registering a name again replaces its schema.
+func RegisterExtensionSchema(name string, schema []byte) error {
+	if name == "" {
+		return NewError("extension schema needs a name")
+	}
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return WrapError("extension "+name, err)
+	}
+	extensionSchemasMu.Lock()
+	defer extensionSchemasMu.Unlock()
+	extensionSchemas[name] = compiled
+	return nil
+}
+
+func validateExtensions(m *Manifest, result *ValidationResult) {
+	for key := range m.Spec.Extensions {
+		if key == "" {
+			result.addError("spec.extensions: empty extension name")
+		}
+	}
+
+	exts := m.Extensions()
+	if len(exts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(exts))
+	for name := range exts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	extensionSchemasMu.RLock()
+	defer extensionSchemasMu.RUnlock()
+	for _, name := range names {
+		schema, ok := extensionSchemas[name]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(exts[name])
+		if err != nil {
+			result.addError(fmt.Sprintf("extension %s: %v", name, err))
+			continue
+		}
+		res, err := schema.Validate(gojsonschema.NewBytesLoader(data))
+		if err != nil {
+			result.addError(fmt.Sprintf("extension %s: %v", name, err))
+			continue
+		}
+		for _, desc := range res.Errors() {
+			result.addError(fmt.Sprintf("extension %s: %s", name, desc))
+		}
+	}
+}
+
+// WithoutExtensions returns a shallow copy of m without spec.extensions or
+// vendor fields, for checks such as the base schema that do not know about
+// them.
+func (m *Manifest) WithoutExtensions() *Manifest {
+	out := *m
+	out.VendorFields = nil
+	out.Spec.VendorFields = nil
+	out.Spec.Extensions = nil
+	return &out
+}
+
+// manifestAlias has Manifest's fields without its marshaling methods.
+type manifestAlias Manifest
+
+// MarshalJSON implements json.Marshaler, writing VendorFields after the
+// standard fields.
+func (m Manifest) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(manifestAlias(m))
+	if err != nil {
+		return nil, err
+	}
+	return appendVendorJSON(data, m.VendorFields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting vendor fields.
+func (m *Manifest) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*manifestAlias)(m)); err != nil {
+		return err
+	}
+	vendor, err := vendorFieldsJSON(data)
+	if err != nil {
+		return err
+	}
+	m.VendorFields = vendor
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (m Manifest) MarshalYAML() (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode(manifestAlias(m)); err != nil {
+		return nil, err
+	}
+	if err := appendVendorYAML(&node, m.VendorFields); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, collecting vendor fields.
+func (m *Manifest) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode((*manifestAlias)(m)); err != nil {
+		return err
+	}
+	vendor, err := vendorFieldsYAML(node)
+	if err != nil {
+		return err
+	}
+	m.VendorFields = vendor
+	return nil
+}
+
+// vendorFieldsJSON returns the vendor fields of a JSON object, or nil.
+func vendorFieldsJSON(data []byte) (map[string]interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var vendor map[string]interface{}
+	for k, raw := range fields {
+		if !IsVendorField(k) {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		if vendor == nil {
+			vendor = map[string]interface{}{}
+		}
+		vendor[k] = v
+	}
+	return vendor, nil
+}
+
+// appendVendorJSON adds vendor fields, sorted by name, to the end of a
+// marshaled JSON object.
+func appendVendorJSON(data []byte, vendor map[string]interface{}) ([]byte, error) {
+	if len(vendor) == 0 {
+		return data, nil
+	}
+	end := bytes.LastIndexByte(data, '}')
+	if end < 0 {
+		return nil, NewError("vendor fields need a JSON object")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:end])
+	first := bytes.TrimSpace(data[:end])
+	sep := len(first) > 1
+	for _, k := range sortedVendorKeys(vendor) {
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(vendor[k])
+		if err != nil {
+			return nil, err
+		}
+		if sep {
+			buf.WriteByte(',')
+		}
+		sep = true
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.Write(data[end:])
+	return buf.Bytes(), nil
+}
+
+// vendorFieldsYAML returns the vendor fields of a YAML mapping, or nil.
+func vendorFieldsYAML(node *yaml.Node) (map[string]interface{}, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var vendor map[string]interface{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k := node.Content[i].Value
+		if !IsVendorField(k) {
+			continue
+		}
+		var v interface{}
+		if err := node.Content[i+1].Decode(&v); err != nil {
+			return nil, err
+		}
+		if vendor == nil {
+			vendor = map[string]interface{}{}
+		}
+		vendor[k] = v
+	}
+	return vendor, nil
+}
+
+// appendVendorYAML adds vendor fields, sorted by name, to the end of an
+// encoded YAML mapping.
+func appendVendorYAML(node *yaml.Node, vendor map[string]interface{}) error {
+	if len(vendor) == 0 {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return NewError("vendor fields need a YAML mapping")
+	}
+	for _, k := range sortedVendorKeys(vendor) {
+		var value yaml.Node
+		if err := value.Encode(vendor[k]); err != nil {
+			return err
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+		node.Content = append(node.Content, key, &value)
+	}
+	return nil
+}
+
+func sortedVendorKeys(vendor map[string]interface{}) []string {
+	keys := make([]string, 0, len(vendor))
+	for k := range vendor {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FuzzSeed is one seed input for fuzzing manifest ingestion.
+type FuzzSeed struct {
+	Name string
+	Data []byte
+}
+
+// ParseManifestBytes parses manifest data of unknown format, trying YAML
+// and then JSON. It is meant for fuzz harnesses and untrusted input: it
+// does not retain data after returning, and a panic in a decoder is
+// returned as an error instead of crashing the caller.
+func ParseManifestBytes(data []byte) (*Manifest, error) {
+	return parseManifestSafe(data, "")
+}
+
+// ParseManifestYAML is ParseManifestBytes for input known to be YAML.
+func ParseManifestYAML(data []byte) (*Manifest, error) {
+	return parseManifestSafe(data, ".yaml")
+}
+
+// ParseManifestJSON is ParseManifestBytes for input known to be JSON.
+func ParseManifestJSON(data []byte) (*Manifest, error) {
+	return parseManifestSafe(data, ".json")
+}
+
+func parseManifestSafe(data []byte, ext string) (m *Manifest, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m, err = nil, NewError(fmt.Sprintf("failed to parse manifest: decoder panic: %v", r))
+		}
+	}()
+	// Copy so the decoders never alias the caller's buffer, which fuzzing
+	// engines reuse between runs.
+	return ParseManifest(append([]byte(nil), data...), ext)
+}
+
+// FuzzParseManifest is a go-fuzz / libFuzzer entry point. It returns 1 when
+// the input parsed and survived a JSON round trip, 0 otherwise, and panics
+// only when a parsed manifest fails to round-trip, which is a bug.
+func FuzzParseManifest(data []byte) int {
+	m, err := ParseManifestBytes(data)
+	if err != nil {
+		return 0
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return 0
+	}
+	if _, err := ParseManifestJSON(out); err != nil {
+		panic(fmt.Sprintf("parsed manifest does not round-trip: %v", err))
+	}
+	return 1
+}
+
+// FuzzSeeds returns seed manifests for fuzzing ingestion paths: the
+// conformance fixtures, JSON and re-encoded variants of the valid bases, and
+// inputs that stress the decoders (anchors, deep nesting, odd encodings).
+func FuzzSeeds() ([]FuzzSeed, error) {
+	cases, err := GenerateConformanceCases(EmbeddedSchema())
+	if err != nil {
+		return nil, err
+	}
+	var seeds []FuzzSeed
+	for _, c := range cases {
+		data, err := json.Marshal(c.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, FuzzSeed{Name: c.Name + ".json", Data: data})
+	}
+
+	agent := strings.TrimPrefix(conformanceBases["agent"], "\n")
+	seeds = append(seeds,
+		FuzzSeed{Name: "agent.yaml", Data: []byte(agent)},
+		FuzzSeed{Name: "agent-bom.yaml", Data: append(append([]byte(nil), bomUTF8...), agent...)},
+		FuzzSeed{Name: "agent-crlf.yaml", Data: []byte(strings.ReplaceAll(agent, "\n", "\r\n"))},
+		FuzzSeed{Name: "agent-utf16le.yaml", Data: encodeUTF16LE(agent)},
+		FuzzSeed{Name: "anchors.yaml", Data: []byte(fuzzAnchors)},
+		FuzzSeed{Name: "deep-nesting.yaml", Data: []byte("spec:\n  x: " + strings.Repeat("[", 64) + strings.Repeat("]", 64) + "\n")},
+		FuzzSeed{Name: "wrong-types.yaml", Data: []byte("apiVersion: [1]\nkind: {a: b}\nmetadata: 3\nspec: ~\n")},
+		FuzzSeed{Name: "empty", Data: nil},
+	)
+	return seeds, nil
+}
+
+const fuzzAnchors = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: anchored
+  labels: &labels
+    team: platform
+  annotations: *labels
+spec:
+  role: assistant
+  llm: &llm
+    provider: openai
+    model: gpt-4o
+  tools:
+    - &tool
+      type: function
+      name: first
+    - <<: *tool
+      name: second
+`
+
+func encodeUTF16LE(s string) []byte {
+	out := append([]byte(nil), bomUTF16LE...)
+	for _, r := range s {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		out = append(out, byte(r), byte(r>>8))
+	}
+	return out
+}
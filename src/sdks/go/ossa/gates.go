@@ -0,0 +1,92 @@
+package ossa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Red-team gate requirements.
+const (
+	RedTeamRequired = "required"
+	RedTeamOptional = "optional"
+)
+
+// PublishGates are release requirements checked before a manifest is
+// published:
+//
+//	gates:
+//	  eval: regression-suite
+//	  min_score: 0.85
+//	  redteam: required
+type PublishGates struct {
+	Eval     string  `json:"eval,omitempty" yaml:"eval,omitempty"`
+	MinScore float64 `json:"min_score,omitempty" yaml:"min_score,omitempty"`
+	RedTeam  string  `json:"redteam,omitempty" yaml:"redteam,omitempty"`
+}
+
+// GateEvidence is what the eval and red-team runs produced for a release
+// candidate.
+type GateEvidence struct {
+	// EvalScores maps suite name to its score in [0, 1].
+	EvalScores map[string]float64
+	// RedTeamPassed is nil when no red-team run was recorded.
+	RedTeamPassed *bool
+}
+
+// GateError lists the gates a release candidate failed.
+type GateError struct {
+	OSSAError
+	Failures []string
+}
+
+// CheckGates returns a *GateError if the evidence does not satisfy the gates.
+func (g *PublishGates) CheckGates(evidence GateEvidence) error {
+	if g == nil {
+		return nil
+	}
+	var failures []string
+
+	if g.Eval != "" {
+		score, ok := evidence.EvalScores[g.Eval]
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Sprintf("eval suite %s has no recorded result", g.Eval))
+		case score < g.MinScore:
+			failures = append(failures, fmt.Sprintf("eval suite %s scored %.2f, below minimum %.2f", g.Eval, score, g.MinScore))
+		}
+	}
+
+	if g.RedTeam == RedTeamRequired {
+		switch {
+		case evidence.RedTeamPassed == nil:
+			failures = append(failures, "red-team run is required but none was recorded")
+		case !*evidence.RedTeamPassed:
+			failures = append(failures, "red-team run failed")
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return &GateError{
+		OSSAError: OSSAError{Message: "publish gates failed: " + strings.Join(failures, "; ")},
+		Failures:  failures,
+	}
+}
+
+// Validate checks the gate configuration itself.
+func (g *PublishGates) Validate() *ValidationResult {
+	result := &ValidationResult{Valid: true}
+	if g.MinScore < 0 || g.MinScore > 1 {
+		result.addError("gates.min_score: must be between 0 and 1")
+	}
+	if g.MinScore > 0 && g.Eval == "" {
+		result.addError("gates.min_score: requires gates.eval")
+	}
+	switch g.RedTeam {
+	case "", RedTeamRequired, RedTeamOptional:
+	default:
+		result.addError(fmt.Sprintf("gates.redteam: invalid value: %s", g.RedTeam))
+	}
+	return result
+}
@@ -0,0 +1,184 @@
+package ossa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultGitLabURL is the API root used when no base URL is given.
+const DefaultGitLabURL = "https://gitlab.com/api/v4"
+
+// GitLab personal access token scopes a generated tool needs.
+const (
+	GitLabScopeReadAPI = "read_api"
+	GitLabScopeAPI     = "api"
+)
+
+// GitLabImportOptions selects the generated GitLab tools.
+type GitLabImportOptions struct {
+	// Scopes are tool groups: issues, mrs, pipelines. Empty means all.
+	Scopes []string
+	// BaseURL is the API root; empty means DefaultGitLabURL.
+	BaseURL string
+	// Tier drops write tools when it cannot write. Empty keeps all tools.
+	Tier AccessTier
+	// TokenEnv names the variable holding the access token; empty means
+	// GITLAB_TOKEN.
+	TokenEnv string
+}
+
+type gitlabParam struct {
+	name, in, typ, desc string
+	required            bool
+}
+
+type gitlabTool struct {
+	name, desc, method, path string
+	params                   []gitlabParam
+}
+
+var gitlabProjectParam = gitlabParam{"project_id", "path", "string", "Project ID or URL-encoded path", true}
+
+// gitlabToolPack is the curated GitLab tool set, by scope.
+var gitlabToolPack = map[string][]gitlabTool{
+	"issues": {
+		{"gitlab_list_issues", "List issues in a project", "GET", "/projects/{project_id}/issues", []gitlabParam{
+			gitlabProjectParam,
+			{"state", "query", "string", "opened, closed or all", false},
+			{"labels", "query", "string", "Comma-separated label names", false},
+			{"search", "query", "string", "Search title and description", false},
+		}},
+		{"gitlab_get_issue", "Get a single issue", "GET", "/projects/{project_id}/issues/{issue_iid}", []gitlabParam{
+			gitlabProjectParam,
+			{"issue_iid", "path", "integer", "Issue IID within the project", true},
+		}},
+		{"gitlab_create_issue", "Create an issue", "POST", "/projects/{project_id}/issues", []gitlabParam{
+			gitlabProjectParam,
+			{"title", "body", "string", "Issue title", true},
+			{"description", "body", "string", "Issue description in Markdown", false},
+			{"labels", "body", "string", "Comma-separated label names", false},
+		}},
+		{"gitlab_comment_on_issue", "Add a comment to an issue", "POST", "/projects/{project_id}/issues/{issue_iid}/notes", []gitlabParam{
+			gitlabProjectParam,
+			{"issue_iid", "path", "integer", "Issue IID within the project", true},
+			{"body", "body", "string", "Comment in Markdown", true},
+		}},
+	},
+	"mrs": {
+		{"gitlab_list_merge_requests", "List merge requests in a project", "GET", "/projects/{project_id}/merge_requests", []gitlabParam{
+			gitlabProjectParam,
+			{"state", "query", "string", "opened, closed, merged or all", false},
+			{"target_branch", "query", "string", "Filter by target branch", false},
+		}},
+		{"gitlab_get_merge_request", "Get a single merge request", "GET", "/projects/{project_id}/merge_requests/{merge_request_iid}", []gitlabParam{
+			gitlabProjectParam,
+			{"merge_request_iid", "path", "integer", "Merge request IID within the project", true},
+		}},
+		{"gitlab_get_merge_request_changes", "Get the diff of a merge request", "GET", "/projects/{project_id}/merge_requests/{merge_request_iid}/diffs", []gitlabParam{
+			gitlabProjectParam,
+			{"merge_request_iid", "path", "integer", "Merge request IID within the project", true},
+		}},
+		{"gitlab_comment_on_merge_request", "Add a comment to a merge request", "POST", "/projects/{project_id}/merge_requests/{merge_request_iid}/notes", []gitlabParam{
+			gitlabProjectParam,
+			{"merge_request_iid", "path", "integer", "Merge request IID within the project", true},
+			{"body", "body", "string", "Comment in Markdown", true},
+		}},
+	},
+	"pipelines": {
+		{"gitlab_list_pipelines", "List pipelines in a project", "GET", "/projects/{project_id}/pipelines", []gitlabParam{
+			gitlabProjectParam,
+			{"ref", "query", "string", "Filter by branch or tag", false},
+			{"status", "query", "string", "Filter by status", false},
+		}},
+		{"gitlab_trigger_pipeline", "Run a pipeline for a ref", "POST", "/projects/{project_id}/pipeline", []gitlabParam{
+			gitlabProjectParam,
+			{"ref", "body", "string", "Branch or tag to run", true},
+		}},
+	},
+}
+
+// GitLabScopes returns the available tool groups.
+func GitLabScopes() []string {
+	scopes := make([]string, 0, len(gitlabToolPack))
+	for s := range gitlabToolPack {
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// GitLabTools returns the curated GitLab tools for the requested scopes.
+// Parameter schemas use the same x-ossa-in markers as ImportOpenAPI, and
+// each tool's config records the token scope it needs (read_api or api).
+// The tools run on the http runtime.
+func GitLabTools(opts GitLabImportOptions) ([]ToolConfig, error) {
+	scopes := opts.Scopes
+	if len(scopes) == 0 {
+		scopes = GitLabScopes()
+	}
+	base := strings.TrimSuffix(opts.BaseURL, "/")
+	if base == "" {
+		base = DefaultGitLabURL
+	}
+	tokenEnv := opts.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "GITLAB_TOKEN"
+	}
+	readOnly := opts.Tier != "" && !opts.Tier.CanWrite()
+
+	var tools []ToolConfig
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		pack, ok := gitlabToolPack[scope]
+		if !ok {
+			return nil, NewError(fmt.Sprintf("unknown GitLab scope: %s (available: %s)", scope, strings.Join(GitLabScopes(), ", ")))
+		}
+		for _, t := range pack {
+			write := t.method != "GET"
+			if seen[t.name] || (write && readOnly) {
+				continue
+			}
+			seen[t.name] = true
+
+			tokenScope := GitLabScopeReadAPI
+			if write {
+				tokenScope = GitLabScopeAPI
+			}
+			tools = append(tools, ToolConfig{
+				Type:        "http",
+				Name:        t.name,
+				Description: t.desc,
+				Parameters:  t.schema(),
+				Config:      map[string]interface{}{"token_scope": tokenScope},
+				Handler: &ToolHandler{
+					Runtime:  RuntimeHTTP,
+					Endpoint: base + t.path,
+					Method:   t.method,
+					Headers:  map[string]string{"PRIVATE-TOKEN": "${" + tokenEnv + "}"},
+				},
+			})
+		}
+	}
+	return tools, nil
+}
+
+func (t gitlabTool) schema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []interface{}
+	for _, p := range t.params {
+		properties[p.name] = map[string]interface{}{
+			"type":        p.typ,
+			"description": p.desc,
+			"x-ossa-in":   p.in,
+		}
+		if p.required {
+			required = append(required, p.name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
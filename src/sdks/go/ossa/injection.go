@@ -0,0 +1,158 @@
+package ossa
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Sensitivity levels for injection detection.
+const (
+	SensitivityLow    = "low"
+	SensitivityMedium = "medium"
+	SensitivityHigh   = "high"
+)
+
+// InjectionFinding is a single suspected prompt-injection pattern match.
+type InjectionFinding struct {
+	Rule    string  `json:"rule"`
+	Match   string  `json:"match"`
+	Score   float64 `json:"score"`
+	Encoded bool    `json:"encoded,omitempty"`
+}
+
+// InjectionReport is the result of scanning one piece of untrusted content.
+type InjectionReport struct {
+	Source   string             `json:"source,omitempty"`
+	Score    float64            `json:"score"`
+	Detected bool               `json:"detected"`
+	Findings []InjectionFinding `json:"findings,omitempty"`
+}
+
+type injectionRule struct {
+	name    string
+	score   float64
+	pattern *regexp.Regexp
+}
+
+// Scores are summed per report and compared to the sensitivity threshold.
+var injectionRules = []injectionRule{
+	{"ignore-instructions", 0.9, regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+)?(the\s+)?(previous|prior|above|earlier)\s+(instructions|prompts|rules|directions)`)},
+	{"new-instructions", 0.6, regexp.MustCompile(`(?i)\b(new|updated|real)\s+instructions\s*:`)},
+	{"role-override", 0.6, regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|the|in)\b`)},
+	{"system-prompt-leak", 0.7, regexp.MustCompile(`(?i)\b(reveal|print|show|repeat)\s+(your|the)\s+(system\s+prompt|instructions|initial\s+prompt)`)},
+	{"fake-role-marker", 0.5, regexp.MustCompile(`(?im)^\s*(system|assistant)\s*:|<\|?(im_start|system)\|?>|\[/?INST\]`)},
+	{"tool-invocation", 0.4, regexp.MustCompile(`(?i)\b(call|invoke|execute|run)\s+the\s+\w+\s+tool\b`)},
+	{"exfiltration", 0.5, regexp.MustCompile(`(?i)\b(send|post|upload|forward)\b.{0,40}\b(to|at)\s+https?://`)},
+}
+
+var base64Candidate = regexp.MustCompile(`[A-Za-z0-9+/]{24,}={0,2}`)
+
+var sensitivityThresholds = map[string]float64{
+	SensitivityLow:    1.2,
+	SensitivityMedium: 0.8,
+	SensitivityHigh:   0.4,
+}
+
+// InjectionAnalyzer scans tool outputs and retrieved documents for
+// prompt-injection patterns before they re-enter the prompt.
+type InjectionAnalyzer struct {
+	threshold float64
+	allowlist []*regexp.Regexp
+}
+
+// NewInjectionAnalyzer creates an analyzer from the manifest config.
+// Allowlist entries match whole sources and findings, ignoring case, with
+// * matching any text: "call the * tool" allows "Call the search tool" but
+// not a paragraph that merely contains it.
+func NewInjectionAnalyzer(cfg *InjectionDetectionConfig) *InjectionAnalyzer {
+	a := &InjectionAnalyzer{threshold: sensitivityThresholds[SensitivityMedium]}
+	if cfg == nil {
+		return a
+	}
+	if t, ok := sensitivityThresholds[cfg.Sensitivity]; ok {
+		a.threshold = t
+	}
+	for _, entry := range cfg.Allowlist {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSpace(entry)), `\*`, `.*`)
+		a.allowlist = append(a.allowlist, regexp.MustCompile(`(?is)^`+pattern+`$`))
+	}
+	return a
+}
+
+// Analyze scans content from the named source.
+func (a *InjectionAnalyzer) Analyze(source, content string) *InjectionReport {
+	report := &InjectionReport{Source: source}
+	if source != "" && a.allowed(source) {
+		return report
+	}
+	a.scan(content, false, report)
+
+	// Decode base64-looking payloads and scan them too.
+	for _, candidate := range base64Candidate.FindAllString(content, -1) {
+		decoded, err := base64.StdEncoding.DecodeString(candidate)
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(candidate, "="))
+		}
+		if err == nil && isPrintable(decoded) {
+			a.scan(string(decoded), true, report)
+		}
+	}
+
+	report.Detected = report.Score >= a.threshold
+	return report
+}
+
+func (a *InjectionAnalyzer) scan(content string, encoded bool, report *InjectionReport) {
+	for _, rule := range injectionRules {
+		for _, match := range rule.pattern.FindAllString(content, -1) {
+			if a.allowed(match) {
+				continue
+			}
+			report.Findings = append(report.Findings, InjectionFinding{
+				Rule:    rule.name,
+				Match:   match,
+				Score:   rule.score,
+				Encoded: encoded,
+			})
+			report.Score += rule.score
+		}
+	}
+}
+
+func (a *InjectionAnalyzer) allowed(match string) bool {
+	match = strings.TrimSpace(match)
+	for _, entry := range a.allowlist {
+		if entry.MatchString(match) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrintable(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if (b < 0x20 && b != '\n' && b != '\r' && b != '\t') || b == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+func validateInjectionDetection(cfg *InjectionDetectionConfig, result *ValidationResult) {
+	if cfg == nil {
+		return
+	}
+	if _, ok := sensitivityThresholds[cfg.Sensitivity]; cfg.Sensitivity != "" && !ok {
+		result.addError(fmt.Sprintf("spec.safety.injection_detection: invalid sensitivity: %s", cfg.Sensitivity))
+	}
+	switch cfg.Action {
+	case "", ModerationBlock, ModerationRedact, ModerationFlag:
+	default:
+		result.addError(fmt.Sprintf("spec.safety.injection_detection: invalid action: %s", cfg.Action))
+	}
+}
@@ -0,0 +1,169 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Limit names reported in LimitError.Limit.
+const (
+	LimitBytes      = "bytes"
+	LimitDepth      = "depth"
+	LimitAliasNodes = "alias_nodes"
+	LimitTools      = "tools"
+	LimitSteps      = "steps"
+	LimitAgents     = "agents"
+)
+
+// ParseLimits bounds the work ParseManifest does on untrusted input.
+// Tools, steps and agents are counted across the whole document, including
+// nested workflow steps and extension agent lists, after alias expansion.
+type ParseLimits struct {
+	MaxBytes      int
+	MaxDepth      int
+	MaxAliasNodes int
+	MaxTools      int
+	MaxSteps      int
+	MaxAgents     int
+}
+
+// DefaultParseLimits are used by ParseManifest and LoadManifest, and fill
+// zero fields passed to ParseManifestWithLimits.
+var DefaultParseLimits = ParseLimits{
+	MaxBytes:      4 << 20,
+	MaxDepth:      MaxYAMLDepth,
+	MaxAliasNodes: MaxYAMLAliasNodes,
+	MaxTools:      1000,
+	MaxSteps:      1000,
+	MaxAgents:     100,
+}
+
+func (l ParseLimits) withDefaults() ParseLimits {
+	def := DefaultParseLimits
+	for _, f := range []struct{ v, d *int }{
+		{&l.MaxBytes, &def.MaxBytes},
+		{&l.MaxDepth, &def.MaxDepth},
+		{&l.MaxAliasNodes, &def.MaxAliasNodes},
+		{&l.MaxTools, &def.MaxTools},
+		{&l.MaxSteps, &def.MaxSteps},
+		{&l.MaxAgents, &def.MaxAgents},
+	} {
+		if *f.v <= 0 {
+			*f.v = *f.d
+		}
+	}
+	return l
+}
+
+// LimitError reports manifest input that exceeds one of its ParseLimits.
+type LimitError struct {
+	OSSAError
+	Limit string
+	Max   int
+	// Actual is the observed size. Depth and alias expansion stop at the
+	// first node over the limit, so for those it is Max+1.
+	Actual int
+}
+
+func newLimitError(limit string, max, actual int) *LimitError {
+	return &LimitError{
+		OSSAError: OSSAError{Message: fmt.Sprintf("manifest exceeds %s limit (%d > %d)", limit, actual, max)},
+		Limit:     limit,
+		Max:       max,
+		Actual:    actual,
+	}
+}
+
+// Is matches ErrYAMLAliasLimit and ErrYAMLDepthLimit for the corresponding
+// limits.
+func (e *LimitError) Is(target error) bool {
+	switch target {
+	case ErrYAMLAliasLimit:
+		return e.Limit == LimitAliasNodes
+	case ErrYAMLDepthLimit:
+		return e.Limit == LimitDepth
+	}
+	return false
+}
+
+// countedKeys maps the document keys whose list lengths are limited.
+var countedKeys = map[string]string{
+	"tools":  LimitTools,
+	"steps":  LimitSteps,
+	"agents": LimitAgents,
+}
+
+func (l ParseLimits) checkCounts(counts map[string]int) error {
+	for _, c := range []struct {
+		limit string
+		max   int
+	}{{LimitTools, l.MaxTools}, {LimitSteps, l.MaxSteps}, {LimitAgents, l.MaxAgents}} {
+		if counts[c.limit] > c.max {
+			return newLimitError(c.limit, c.max, counts[c.limit])
+		}
+	}
+	return nil
+}
+
+// decodeJSON checks limits on a JSON document, then decodes it into v.
+func decodeJSON(data []byte, v interface{}, limits ParseLimits) error {
+	if depth := jsonDepth(data); depth > limits.MaxDepth {
+		return newLimitError(LimitDepth, limits.MaxDepth, depth)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	countJSON(doc, counts)
+	if err := limits.checkCounts(counts); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jsonDepth returns the deepest bracket nesting outside string literals.
+func jsonDepth(data []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			if depth++; depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return max
+}
+
+func countJSON(v interface{}, counts map[string]int) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if list, ok := child.([]interface{}); ok {
+				if limit, ok := countedKeys[k]; ok {
+					counts[limit] += len(list)
+				}
+			}
+			countJSON(child, counts)
+		}
+	case []interface{}:
+		for _, child := range t {
+			countJSON(child, counts)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ossa
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+
+	"github.com/blueflyio/ossa-go/labels"
+)
+
+// ErrInvalidContinue is returned for continue tokens a listing did not
+// issue.
+var ErrInvalidContinue = errors.New("invalid continue token")
+
+// ListOptions filters and pages a manifest listing. The zero value lists
+// everything in one page.
+type ListOptions struct {
+	// Namespace, if set, keeps manifests whose metadata.namespace is
+	// Namespace.
+	Namespace string
+	// Kind, if set, keeps manifests of that kind.
+	Kind Kind
+	// Selector keeps manifests whose labels it matches.
+	Selector labels.Selector
+	// Match, if set, keeps the entries it returns true for, after the
+	// filters above.
+	Match func(CatalogEntry) bool
+	// Limit caps the items in a page; 0 means no limit.
+	Limit int
+	// Continue is the previous page's ManifestList.Continue, to list the
+	// entries after it.
+	Continue string
+}
+
+// Matches reports whether e passes o's filters. Entries that failed to
+// load have no namespace or labels, so pass only filters that allow that.
+func (o ListOptions) Matches(e CatalogEntry) bool {
+	var namespace string
+	var set map[string]string
+	if e.Manifest != nil {
+		namespace, set = e.Manifest.Metadata.Namespace, e.Manifest.Metadata.Labels
+	}
+	switch {
+	case o.Namespace != "" && namespace != o.Namespace:
+		return false
+	case o.Kind != "" && e.Kind != o.Kind:
+		return false
+	case !o.Selector.Matches(set):
+		return false
+	}
+	return o.Match == nil || o.Match(e)
+}
+
+// ManifestList is a page of a manifest listing, from ListWorkspace,
+// ListManifests or a registry.
+type ManifestList struct {
+	// Items are the page's entries, sorted by Path.
+	Items []CatalogEntry
+	// Continue, if not empty, is passed as ListOptions.Continue to fetch
+	// the next page. Tokens point past the last item's Path, so pages
+	// neither repeat nor skip entries added or removed in between.
+	Continue string
+}
+
+// NewManifestList filters entries with opts and returns the page it asks
+// for.
+func NewManifestList(entries []CatalogEntry, opts ListOptions) (*ManifestList, error) {
+	after, err := decodeContinue(opts.Continue)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]CatalogEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	list := &ManifestList{Items: []CatalogEntry{}}
+	for _, e := range sorted {
+		if opts.Continue != "" && e.Path <= after || !opts.Matches(e) {
+			continue
+		}
+		if opts.Limit > 0 && len(list.Items) == opts.Limit {
+			list.Continue = encodeContinue(list.Items[len(list.Items)-1].Path)
+			break
+		}
+		list.Items = append(list.Items, e)
+	}
+	return list, nil
+}
+
+// ListWorkspace lists the manifests ScanWorkspace finds below dir.
+func ListWorkspace(dir string, opts ListOptions) (*ManifestList, error) {
+	entries, err := ScanWorkspace(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewManifestList(entries, opts)
+}
+
+// ListManifests lists the manifests in store.
+func ListManifests(ctx context.Context, store ManifestStore, opts ListOptions) (*ManifestList, error) {
+	entries, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewManifestList(entries, opts)
+}
+
+func encodeContinue(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+func decodeContinue(token string) (string, error) {
+	path, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || token != "" && len(path) == 0 {
+		return "", ErrInvalidContinue
+	}
+	return string(path), nil
+}
@@ -0,0 +1,243 @@
+package ossa
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Patterns of the provider-specific spec.llm fields, as in the schema.
+var (
+	bedrockModelPattern    = regexp.MustCompile(`^(arn:aws[a-z-]*:bedrock:[a-z0-9-]+:[0-9]*:[a-z-]+/[A-Za-z0-9._:/-]+|([a-z]{2,4}\.)?[a-z0-9-]+\.[A-Za-z0-9._:-]+)$`)
+	awsRegionPattern       = regexp.MustCompile(`^([a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-[0-9]+|[$]\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\})$`)
+	endpointPattern        = regexp.MustCompile(`^(https://|[$]\{)`)
+	azureAPIVersionPattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}(-preview)?$`)
+	envNamePattern         = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	gcpProjectPattern      = regexp.MustCompile(`^([a-z][a-z0-9-]{4,28}[a-z0-9]|[$]\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\})$`)
+	vertexLocationPattern  = regexp.MustCompile(`^(global|[a-z]+-[a-z]+[0-9]+|[$]\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\})$`)
+	schemaNamePattern      = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// MaxStopSequences is how many stop sequences spec.llm.stop may have, the
+// fewest any provider accepts.
+const MaxStopSequences = 4
+
+// validateLLM checks the generation parameters, that the azure and
+// bedrock providers, of the model and of each fallback, have the settings
+// their adapters need, and the routing policy.
+func validateLLM(llm *LLMConfig, result *ValidationResult) {
+	if llm == nil {
+		return
+	}
+	validateGeneration("spec.llm", llm, result)
+	validateModel("spec.llm", llm.Provider, llm.Model, llm.Azure, llm.Bedrock, llm.Vertex, result)
+	for i, f := range llm.Fallbacks {
+		path := fmt.Sprintf("spec.llm.fallbacks[%d]", i)
+		if f.Provider == "" || f.Model == "" {
+			result.addError(path + ": fallback requires provider and model")
+		}
+		validateModel(path, f.Provider, f.Model, f.Azure, f.Bedrock, f.Vertex, result)
+	}
+	validateContext(llm, result)
+	if r := llm.Routing; r != nil {
+		switch r.Policy {
+		case "", RoutingFailover, RoutingCostOptimized, RoutingLatencyOptimized:
+		default:
+			result.addError(fmt.Sprintf("spec.llm.routing: invalid policy: %s", r.Policy))
+		}
+		if r.Policy == RoutingCostOptimized {
+			for _, f := range llm.Fallbacks {
+				if llm.Pricing == nil || f.Pricing == nil {
+					result.addWarning("spec.llm.routing: cost-optimized routing tries models without pricing last")
+					break
+				}
+			}
+		}
+	}
+}
+
+// validateModel checks one model at path, spec.llm or a fallback.
+func validateModel(path, provider, model string, azure *AzureConfig, bedrock *BedrockConfig, vertex *VertexConfig, result *ValidationResult) {
+	switch provider {
+	case "azure":
+		if azure == nil {
+			result.addError(path + ": azure provider requires azure")
+		}
+	case "bedrock":
+		if bedrock == nil {
+			result.addError(path + ": bedrock provider requires bedrock")
+		}
+		if !bedrockModelPattern.MatchString(model) {
+			result.addError(fmt.Sprintf("%s.model: invalid Bedrock model ID: %s", path, model))
+		}
+	}
+	if a := azure; a != nil {
+		if !endpointPattern.MatchString(a.Endpoint) {
+			result.addError(fmt.Sprintf("%s.azure.endpoint: invalid endpoint: %q", path, a.Endpoint))
+		}
+		if a.APIVersion != "" && !azureAPIVersionPattern.MatchString(a.APIVersion) {
+			result.addError(fmt.Sprintf("%s.azure.api_version: invalid version: %s", path, a.APIVersion))
+		}
+		if a.APIKeyEnv != "" && !envNamePattern.MatchString(a.APIKeyEnv) {
+			result.addError(fmt.Sprintf("%s.azure.api_key_env: invalid variable name: %s", path, a.APIKeyEnv))
+		}
+	}
+	if b := bedrock; b != nil {
+		if !awsRegionPattern.MatchString(b.Region) {
+			result.addError(fmt.Sprintf("%s.bedrock.region: invalid region: %q", path, b.Region))
+		}
+		if b.Endpoint != "" && !endpointPattern.MatchString(b.Endpoint) {
+			result.addError(fmt.Sprintf("%s.bedrock.endpoint: invalid endpoint: %s", path, b.Endpoint))
+		}
+	}
+	if v := vertex; v != nil {
+		if v.Project != "" && !gcpProjectPattern.MatchString(v.Project) {
+			result.addError(fmt.Sprintf("%s.vertex.project: invalid project ID: %s", path, v.Project))
+		}
+		if v.Location != "" && !vertexLocationPattern.MatchString(v.Location) {
+			result.addError(fmt.Sprintf("%s.vertex.location: invalid location: %s", path, v.Location))
+		}
+		if v.Endpoint != "" && !endpointPattern.MatchString(v.Endpoint) {
+			result.addError(fmt.Sprintf("%s.vertex.endpoint: invalid endpoint: %s", path, v.Endpoint))
+		}
+	}
+}
+
+// validateGeneration checks the sampling and output parameters of llm,
+// spec.llm or a tool's override at path, against the ranges providers
+// accept.
+func validateGeneration(path string, llm *LLMConfig, result *ValidationResult) {
+	if llm.TopP < 0 || llm.TopP > 1 {
+		result.addError(fmt.Sprintf("%s.topP: %g is not between 0 and 1", path, llm.TopP))
+	}
+	if v := llm.FrequencyPenalty; v < -2 || v > 2 {
+		result.addError(fmt.Sprintf("%s.frequencyPenalty: %g is not between -2 and 2", path, v))
+	}
+	if v := llm.PresencePenalty; v < -2 || v > 2 {
+		result.addError(fmt.Sprintf("%s.presencePenalty: %g is not between -2 and 2", path, v))
+	}
+	if len(llm.Stop) > MaxStopSequences {
+		result.addError(fmt.Sprintf("%s.stop: %d sequences exceed %d", path, len(llm.Stop), MaxStopSequences))
+	}
+	for i, s := range llm.Stop {
+		if s == "" {
+			result.addError(fmt.Sprintf("%s.stop[%d]: must not be empty", path, i))
+		}
+	}
+	if f := llm.ResponseFormat; f != nil {
+		switch f.Type {
+		case ResponseFormatText, ResponseFormatJSONObject:
+		case ResponseFormatJSONSchema:
+			if f.Schema == nil {
+				result.addError(path + ".responseFormat: json_schema requires schema")
+			}
+		default:
+			result.addError(fmt.Sprintf("%s.responseFormat.type: invalid type: %q", path, f.Type))
+		}
+		if f.Name != "" && !schemaNamePattern.MatchString(f.Name) {
+			result.addError(fmt.Sprintf("%s.responseFormat.name: invalid name: %s", path, f.Name))
+		}
+	}
+}
+
+// validateLLMOverride checks the llm of a tool, at path, both itself and
+// merged into spec.llm.
+func validateLLMOverride(path string, llm, override *LLMConfig, result *ValidationResult) {
+	validateGeneration(path, override, result)
+	if override.Provider != "" && llm != nil && override.Provider != llm.Provider && override.Model == "" {
+		result.addError(path + ": an override changing provider requires model")
+		return
+	}
+	merged, err := llm.Merge(override)
+	if err != nil {
+		result.addError(fmt.Sprintf("%s: %v", path, err))
+		return
+	}
+	if merged.Provider == "" || merged.Model == "" {
+		result.addError(path + ": override requires provider and model without spec.llm")
+		return
+	}
+	validateModel(path, merged.Provider, merged.Model, merged.Azure, merged.Bedrock, merged.Vertex, result)
+}
+
+// validateContext checks spec.llm.context, warning when the window it
+// budgets within is unknown.
+func validateContext(llm *LLMConfig, result *ValidationResult) {
+	c := llm.Context
+	if c == nil {
+		return
+	}
+	switch c.Truncation {
+	case "", TruncateOldest, TruncateMiddle, TruncateError:
+	default:
+		result.addError(fmt.Sprintf("spec.llm.context.truncation: invalid strategy: %s", c.Truncation))
+	}
+	if c.Window < 0 || c.ReserveTokens < 0 {
+		result.addError("spec.llm.context: window and reserve_tokens must not be negative")
+	}
+	window := c.Window
+	if window == 0 {
+		if w, ok := ContextWindow(llm.Model); ok {
+			window = w
+		} else {
+			result.addWarning(fmt.Sprintf("spec.llm.context: context window of %s is unknown; set window", llm.Model))
+		}
+	}
+	if b := c.Budgets; b != nil {
+		if b.System < 0 || b.Tools < 0 || b.Memory < 0 || b.History < 0 {
+			result.addError("spec.llm.context.budgets: budgets must not be negative")
+		}
+		if sum := b.System + b.Tools + b.Memory + b.History; window > 0 && sum > window {
+			result.addError(fmt.Sprintf("spec.llm.context.budgets: %d tokens exceed the %d token window", sum, window))
+		}
+	}
+}
+
+// validateEmbeddings checks spec.embeddings as validateModel does a chat
+// model.
+func validateEmbeddings(e *EmbeddingsConfig, result *ValidationResult) {
+	if e == nil {
+		return
+	}
+	if e.Provider == "" || e.Model == "" {
+		result.addError("spec.embeddings: embeddings requires provider and model")
+	}
+	if e.Dimensions < 0 {
+		result.addError("spec.embeddings.dimensions: must be at least 1")
+	}
+	validateModel("spec.embeddings", e.Provider, e.Model, e.Azure, e.Bedrock, e.Vertex, result)
+}
+
+// modelRef is one model of a manifest and the path it is configured at.
+type modelRef struct {
+	path, provider, model string
+}
+
+// models lists the models m may call: spec.llm, its fallbacks and the
+// llm overrides of tools and the summarizer, merged into spec.llm.
+func (m *Manifest) models() []modelRef {
+	llm := m.Spec.LLM
+	if llm == nil {
+		return nil
+	}
+	refs := []modelRef{{"spec.llm", llm.Provider, llm.Model}}
+	for i, f := range llm.Fallbacks {
+		refs = append(refs, modelRef{fmt.Sprintf("spec.llm.fallbacks[%d]", i), f.Provider, f.Model})
+	}
+	for i, f := range llm.FallbackModels {
+		refs = append(refs, modelRef{fmt.Sprintf("spec.llm.fallback_models[%d]", i), f.Provider, f.Model})
+	}
+	for i, t := range m.Spec.Tools {
+		if t.LLM == nil {
+			continue
+		}
+		if o, err := llm.Merge(t.LLM); err == nil {
+			refs = append(refs, modelRef{fmt.Sprintf("spec.tools[%d].llm", i), o.Provider, o.Model})
+		}
+	}
+	if s := m.Spec.State; s != nil && s.ContextWindow != nil && s.ContextWindow.Summarizer != nil {
+		if o, err := llm.Merge(s.ContextWindow.Summarizer); err == nil {
+			refs = append(refs, modelRef{"spec.state.context_window.summarizer", o.Provider, o.Model})
+		}
+	}
+	return refs
+}
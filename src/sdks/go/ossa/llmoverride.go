@@ -0,0 +1,71 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// modelBound are the LLMConfig fields describing one provider's model,
+// which an override naming another provider does not inherit.
+var modelBound = []string{"azure", "bedrock", "vertex", "pricing", "fallbacks", "fallback_models"}
+
+// Merge returns l with override applied, the model a tool's llm selects.
+// Fields override sets win and the rest are inherited from l: objects
+// merge key by key and lists replace l's, as Manifest.Merge with
+// MergeReplace. An override naming a different provider inherits none of
+// l's azure, bedrock, vertex, pricing or fallbacks, since those describe
+// l's model. Neither l nor override is changed; either may be nil.
+func (l *LLMConfig) Merge(override *LLMConfig) (*LLMConfig, error) {
+	if override == nil {
+		override = &LLMConfig{}
+	}
+	if l == nil {
+		l = &LLMConfig{}
+	}
+	base, err := llmDoc(l)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := llmDoc(override)
+	if err != nil {
+		return nil, err
+	}
+	if override.Provider != "" && override.Provider != l.Provider {
+		for _, k := range modelBound {
+			delete(base, k)
+		}
+	}
+	data, err := json.Marshal(mergeValue(base, overlay, "", MergeReplace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal llm: %w", err)
+	}
+	merged := &LLMConfig{}
+	if err := json.Unmarshal(data, merged); err != nil {
+		return nil, fmt.Errorf("failed to decode llm: %w", err)
+	}
+	return merged, nil
+}
+
+// LLMFor returns the model for the turns handling results of the named
+// tool: spec.llm merged with the tool's llm, or spec.llm itself when the
+// tool has none.
+func (m *Manifest) LLMFor(tool string) (*LLMConfig, error) {
+	for _, t := range m.Spec.Tools {
+		if t.Name == tool && t.LLM != nil {
+			return m.Spec.LLM.Merge(t.LLM)
+		}
+	}
+	return m.Spec.LLM, nil
+}
+
+func llmDoc(l *LLMConfig) (map[string]interface{}, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal llm: %w", err)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal llm: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,281 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is used when a localized value has no variant for the
+// requested locale.
+const DefaultLocale = "en"
+
+// localePattern accepts BCP 47 language tags of the form language[-Script][-REGION].
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// IsValidLocale reports whether tag is a well-formed BCP 47 language tag.
+func IsValidLocale(tag string) bool {
+	return localePattern.MatchString(tag)
+}
+
+// ParseAcceptLanguage returns the preferred well-formed locale of an HTTP
+// Accept-Language header, such as "de-AT" for "de-AT, en;q=0.8", or "" if
+// it names none.
+func ParseAcceptLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if IsValidLocale(tag) && q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	return best
+}
+
+// LocalizedText is a string that may be given either as a plain value or as
+// a map keyed by locale:
+//
+//	template: "You are a support agent."
+//	template: {en: "You are a support agent.", de: "Du bist ein Support-Agent."}
+type LocalizedText struct {
+	Text    string
+	Locales map[string]string
+}
+
+// For returns the variant for locale, falling back to the base language
+// ("de-AT" to "de"), then DefaultLocale, then the plain value.
+func (l LocalizedText) For(locale string) string {
+	return selectLocale(l.Text, l.Locales, locale)
+}
+
+// IsZero reports whether no value is set.
+func (l LocalizedText) IsZero() bool {
+	return l.Text == "" && len(l.Locales) == 0
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l LocalizedText) MarshalJSON() ([]byte, error) {
+	if len(l.Locales) > 0 {
+		return json.Marshal(l.Locales)
+	}
+	return json.Marshal(l.Text)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *LocalizedText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*l = LocalizedText{Text: text}
+		return nil
+	}
+	var locales map[string]string
+	if err := json.Unmarshal(data, &locales); err != nil {
+		return fmt.Errorf("expected string or map of locale to string")
+	}
+	*l = LocalizedText{Text: defaultVariant(locales), Locales: locales}
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (l LocalizedText) MarshalYAML() (interface{}, error) {
+	if len(l.Locales) > 0 {
+		return l.Locales, nil
+	}
+	return l.Text, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *LocalizedText) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = LocalizedText{Text: node.Value}
+		return nil
+	case yaml.MappingNode:
+		var locales map[string]string
+		if err := node.Decode(&locales); err != nil {
+			return err
+		}
+		*l = LocalizedText{Text: defaultVariant(locales), Locales: locales}
+		return nil
+	}
+	return fmt.Errorf("line %d: expected string or map of locale to string", node.Line)
+}
+
+// RoleFor returns the agent role for locale. See LocalizedText.For for the
+// fallback order.
+func (m *Manifest) RoleFor(locale string) string {
+	return selectLocale(m.Spec.Role, m.Spec.RoleLocales, locale)
+}
+
+// Localize returns a copy of the manifest with the role and system prompt
+// resolved to a single locale, for runtimes that expect plain strings.
+func (m *Manifest) Localize(locale string) *Manifest {
+	out := *m
+	out.Spec.Role = m.RoleFor(locale)
+	out.Spec.RoleLocales = nil
+	if p := m.Spec.Prompts; p != nil && p.System != nil {
+		system := *p.System
+		system.Template = LocalizedText{Text: p.System.Template.For(locale)}
+		prompts := *p
+		prompts.System = &system
+		out.Spec.Prompts = &prompts
+	}
+	return &out
+}
+
+func selectLocale(text string, locales map[string]string, locale string) string {
+	if len(locales) == 0 {
+		return text
+	}
+	if v, ok := lookupLocale(locales, locale); ok {
+		return v
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if v, ok := lookupLocale(locales, base); ok {
+			return v
+		}
+	}
+	if v, ok := lookupLocale(locales, DefaultLocale); ok {
+		return v
+	}
+	return text
+}
+
+func lookupLocale(locales map[string]string, locale string) (string, bool) {
+	if v, ok := locales[locale]; ok {
+		return v, true
+	}
+	for k, v := range locales {
+		if strings.EqualFold(k, locale) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// defaultVariant picks the value used as the plain string for a localized
+// map: DefaultLocale if present, otherwise the first locale in sort order.
+func defaultVariant(locales map[string]string) string {
+	if v, ok := locales[DefaultLocale]; ok {
+		return v
+	}
+	keys := make([]string, 0, len(locales))
+	for k := range locales {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return ""
+	}
+	return locales[keys[0]]
+}
+
+func validateLocales(path string, locales map[string]string, result *ValidationResult) {
+	keys := make([]string, 0, len(locales))
+	for k := range locales {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !IsValidLocale(k) {
+			result.addError(fmt.Sprintf("%s: invalid locale tag: %s", path, k))
+		} else if locales[k] == "" {
+			result.addError(fmt.Sprintf("%s.%s: empty value", path, k))
+		}
+	}
+}
+
+// specAlias has Spec's fields without its marshaling methods.
+type specAlias Spec
+
+// MarshalJSON implements json.Marshaler, writing role as a locale map when
+// RoleLocales is set.
+func (s Spec) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Role LocalizedText `json:"role"`
+		specAlias
+	}{LocalizedText{Text: s.Role, Locales: s.RoleLocales}, specAlias(s)})
+	if err != nil {
+		return nil, err
+	}
+	return appendVendorJSON(data, s.VendorFields)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting role as a string or
+// a locale map.
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*specAlias
+		Role LocalizedText `json:"role"`
+	}{specAlias: (*specAlias)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	vendor, err := vendorFieldsJSON(data)
+	if err != nil {
+		return err
+	}
+	s.Role, s.RoleLocales, s.VendorFields = aux.Role.Text, aux.Role.Locales, vendor
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (s Spec) MarshalYAML() (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode(specAlias(s)); err != nil {
+		return nil, err
+	}
+	if len(s.RoleLocales) > 0 {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "role" {
+				if err := node.Content[i+1].Encode(s.RoleLocales); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := appendVendorYAML(&node, s.VendorFields); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
+	var role LocalizedText
+	rest := node
+	if node.Kind == yaml.MappingNode {
+		copied := *node
+		copied.Content = nil
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "role" {
+				if err := node.Content[i+1].Decode(&role); err != nil {
+					return err
+				}
+				continue
+			}
+			copied.Content = append(copied.Content, node.Content[i], node.Content[i+1])
+		}
+		rest = &copied
+	}
+	if err := rest.Decode((*specAlias)(s)); err != nil {
+		return err
+	}
+	vendor, err := vendorFieldsYAML(node)
+	if err != nil {
+		return err
+	}
+	s.Role, s.RoleLocales, s.VendorFields = role.Text, role.Locales, vendor
+	return nil
+}
@@ -0,0 +1,118 @@
+package ossa
+
+import (
+	"strings"
+	"testing"
+)
+
+const localizedYAML = `
+apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: global-agent
+spec:
+  role:
+    en: "You are a support agent."
+    de: "Du bist ein Support-Agent."
+    pt-BR: "Você é um agente de suporte."
+  prompts:
+    system:
+      template:
+        en: "Answer in English."
+        de: "Antworte auf Deutsch."
+`
+
+func TestLocalizedRole(t *testing.T) {
+	manifest, err := ParseManifest([]byte(localizedYAML), ".yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	if manifest.Spec.Role != "You are a support agent." {
+		t.Errorf("Expected default role, got %q", manifest.Spec.Role)
+	}
+
+	tests := map[string]string{
+		"de":    "Du bist ein Support-Agent.",
+		"de-AT": "Du bist ein Support-Agent.",
+		"pt-br": "Você é um agente de suporte.",
+		"fr":    "You are a support agent.",
+	}
+	for locale, want := range tests {
+		if got := manifest.RoleFor(locale); got != want {
+			t.Errorf("RoleFor(%s): expected %q, got %q", locale, want, got)
+		}
+	}
+
+	localized := manifest.Localize("de")
+	if localized.Spec.Role != "Du bist ein Support-Agent." || localized.Spec.RoleLocales != nil {
+		t.Errorf("Unexpected localized role: %+v", localized.Spec)
+	}
+	if got := localized.Spec.Prompts.System.Template.Text; got != "Antworte auf Deutsch." {
+		t.Errorf("Unexpected localized template %q", got)
+	}
+	if manifest.Spec.RoleLocales == nil {
+		t.Error("Localize must not modify the original manifest")
+	}
+
+	if result := ValidateManifest(manifest); !result.Valid {
+		t.Errorf("Expected valid manifest, got %v", result.Errors)
+	}
+}
+
+func TestLocalizedRoundTrip(t *testing.T) {
+	manifest, err := ParseManifest([]byte(localizedYAML), ".yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		var data string
+		if format == "json" {
+			data, err = manifest.ToJSON()
+		} else {
+			data, err = manifest.ToYAML()
+		}
+		if err != nil {
+			t.Fatalf("%s: marshal failed: %v", format, err)
+		}
+		if !strings.Contains(data, "Du bist ein Support-Agent.") {
+			t.Errorf("%s: locale variants lost:\n%s", format, data)
+		}
+
+		parsed, err := ParseManifest([]byte(data), "."+format)
+		if err != nil {
+			t.Fatalf("%s: reparse failed: %v", format, err)
+		}
+		if parsed.RoleFor("pt-BR") != "Você é um agente de suporte." {
+			t.Errorf("%s: round trip lost pt-BR role", format)
+		}
+		if parsed.Metadata.Name != "global-agent" {
+			t.Errorf("%s: round trip lost metadata", format)
+		}
+	}
+}
+
+func TestInvalidLocaleTags(t *testing.T) {
+	manifest := NewManifest("bad-locales", KindAgent)
+	manifest.Spec.RoleLocales = map[string]string{"english": "Hi", "de": ""}
+
+	result := ValidateManifest(manifest)
+	if len(result.Errors) != 2 {
+		t.Errorf("Expected 2 locale errors, got %v", result.Errors)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	for header, want := range map[string]string{
+		"de-AT, de;q=0.9, en;q=0.8": "de-AT",
+		"en;q=0.5, fr":              "fr",
+		"*, pt-BR;q=0.7":            "pt-BR",
+		"":                          "",
+		"x-klingon":                 "",
+	} {
+		if got := ParseAcceptLanguage(header); got != want {
+			t.Errorf("%q: expected %q, got %q", header, want, got)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package ossa
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,31 +14,87 @@ import (
 
 // LoadManifest loads a manifest from a file.
 func LoadManifest(path string) (*Manifest, error) {
-	data, err := os.ReadFile(path)
+	m, err := loadManifest(path, os.Stat, os.ReadFile)
+	return logLoad(path, m, err)
+}
+
+// LoadManifestFS is LoadManifest for the file name in fsys, such as an
+// embed.FS of bundled manifests, a zip.Reader or a testing/fstest.MapFS.
+func LoadManifestFS(fsys fs.FS, name string) (*Manifest, error) {
+	stat := func(name string) (fs.FileInfo, error) { return fs.Stat(fsys, name) }
+	read := func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) }
+	m, err := loadManifest(name, stat, read)
+	return logLoad(name, m, err)
+}
+
+func logLoad(path string, m *Manifest, err error) (*Manifest, error) {
+	if err != nil {
+		Logger().Debug("manifest load failed", "path", path, "error", err)
+		return nil, err
+	}
+	Logger().Debug("manifest loaded", "path", path, "kind", m.Kind, "name", m.Metadata.Name)
+	return m, nil
+}
+
+func loadManifest(path string, stat func(string) (fs.FileInfo, error), read func(string) ([]byte, error)) (*Manifest, error) {
+	if info, err := stat(path); err == nil && info.Size() > int64(DefaultParseLimits.MaxBytes) {
+		return nil, newLimitError(LimitBytes, DefaultParseLimits.MaxBytes, int(info.Size()))
+	}
+	data, err := read(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	if data, err = Decrypt(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 
+	if strings.EqualFold(filepath.Ext(path), CBORExt) {
+		return FromCBOR(data)
+	}
 	return ParseManifest(data, filepath.Ext(path))
 }
 
 // ParseManifest parses manifest data.
+// Input is normalized first, so UTF-8 BOMs, UTF-16 and CRLF line endings
+// are accepted. YAML anchors, aliases and merge keys are expanded as
+// described in ResolveYAML. DefaultParseLimits apply.
 func ParseManifest(data []byte, ext string) (*Manifest, error) {
+	return ParseManifestWithLimits(data, ext, DefaultParseLimits)
+}
+
+// ParseManifestWithLimits is ParseManifest with explicit size and
+// complexity limits; zero fields take their DefaultParseLimits value.
+// Input over a limit fails with a *LimitError.
+func ParseManifestWithLimits(data []byte, ext string, limits ParseLimits) (*Manifest, error) {
 	var manifest Manifest
 
+	limits = limits.withDefaults()
+	if len(data) > limits.MaxBytes {
+		return nil, newLimitError(LimitBytes, limits.MaxBytes, len(data))
+	}
+
+	data, err := NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
 	switch strings.ToLower(ext) {
 	case ".json":
-		if err := json.Unmarshal(data, &manifest); err != nil {
+		if err := decodeJSON(data, &manifest, limits); err != nil {
 			return nil, fmt.Errorf("failed to parse JSON: %w", err)
 		}
 	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, &manifest); err != nil {
+		if err := decodeYAML(data, &manifest, limits); err != nil {
 			return nil, fmt.Errorf("failed to parse YAML: %w", err)
 		}
 	default:
 		// Try YAML first, then JSON
-		if err := yaml.Unmarshal(data, &manifest); err != nil {
-			if err := json.Unmarshal(data, &manifest); err != nil {
+		if err := decodeYAML(data, &manifest, limits); err != nil {
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				return nil, fmt.Errorf("failed to parse YAML: %w", err)
+			}
+			if err := decodeJSON(data, &manifest, limits); err != nil {
 				return nil, fmt.Errorf("failed to parse manifest: %w", err)
 			}
 		}
@@ -47,6 +105,16 @@ func ParseManifest(data []byte, ext string) (*Manifest, error) {
 
 // SaveManifest saves a manifest to a file.
 func SaveManifest(manifest *Manifest, path string, format string) error {
+	data, err := MarshalManifest(manifest, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// MarshalManifest encodes a manifest as SaveManifest writes it: format is
+// json, yaml or cbor.
+func MarshalManifest(manifest *Manifest, format string) ([]byte, error) {
 	var data []byte
 	var err error
 
@@ -55,14 +123,15 @@ func SaveManifest(manifest *Manifest, path string, format string) error {
 		data, err = json.MarshalIndent(manifest, "", "  ")
 	case "yaml":
 		data, err = yaml.Marshal(manifest)
+	case "cbor":
+		data, err = manifest.ToCBOR()
 	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal manifest: %w", err)
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
 	}
-
-	return os.WriteFile(path, data, 0644)
+	return data, nil
 }
 
 // ToYAML converts manifest to YAML string.
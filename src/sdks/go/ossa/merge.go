@@ -0,0 +1,253 @@
+package ossa
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MergeStrategy selects how Manifest.Merge combines lists.
+type MergeStrategy string
+
+const (
+	// MergeStrategic merges tools lists by tool name and steps lists by
+	// position; any other list in other replaces the one in the manifest.
+	MergeStrategic MergeStrategy = "strategic"
+	// MergeReplace replaces every list in the manifest with the one in
+	// other when other's is not empty.
+	MergeReplace MergeStrategy = "replace"
+)
+
+// Merge overlays other onto the manifest. Objects merge key by key, with
+// other's values winning; empty strings in other leave the manifest's value
+// alone, since typed manifests cannot tell an unset field from an empty
+// one. Lists follow strategy:
+//
+//   - tools (MergeStrategic): a tool in other replaces fields of the tool
+//     with the same name and is appended otherwise; unnamed tools are
+//     appended.
+//   - steps (MergeStrategic): element i of other merges into element i of
+//     the manifest; extra elements are appended.
+//   - everything else: other's list, when not empty, replaces the
+//     manifest's.
+//
+// The manifest is only updated if the merge succeeds.
+func (m *Manifest) Merge(other *Manifest, strategy MergeStrategy) error {
+	switch strategy {
+	case MergeStrategic, MergeReplace:
+	default:
+		return NewError(fmt.Sprintf("unsupported merge strategy: %s", strategy))
+	}
+	if other == nil {
+		return nil
+	}
+
+	base, err := manifestDoc(m)
+	if err != nil {
+		return err
+	}
+	overlay, err := manifestDoc(other)
+	if err != nil {
+		return err
+	}
+	return m.setDoc(mergeValue(base, overlay, "", strategy))
+}
+
+// mergeValue merges overlay into base; key is the field both were found
+// under and picks the list rule.
+func mergeValue(base, overlay interface{}, key string, strategy MergeStrategy) interface{} {
+	switch o := overlay.(type) {
+	case nil:
+		return base
+	case string:
+		if o == "" {
+			return base
+		}
+	case map[string]interface{}:
+		b, ok := base.(map[string]interface{})
+		if !ok {
+			return overlay
+		}
+		for k, v := range o {
+			b[k] = mergeValue(b[k], v, k, strategy)
+		}
+		return b
+	case []interface{}:
+		b, ok := base.([]interface{})
+		if !ok {
+			return overlay
+		}
+		if strategy == MergeStrategic {
+			switch key {
+			case "tools":
+				return mergeByName(b, o, strategy)
+			case "steps":
+				return mergeByPosition(b, o, strategy)
+			}
+		}
+		if len(o) == 0 {
+			return base
+		}
+	}
+	return overlay
+}
+
+func mergeByName(base, overlay []interface{}, strategy MergeStrategy) []interface{} {
+	index := map[string]int{}
+	for i, item := range base {
+		if name := itemName(item); name != "" {
+			index[name] = i
+		}
+	}
+	for _, item := range overlay {
+		name := itemName(item)
+		if i, ok := index[name]; ok && name != "" {
+			base[i] = mergeValue(base[i], item, "", strategy)
+			continue
+		}
+		if name != "" {
+			index[name] = len(base)
+		}
+		base = append(base, item)
+	}
+	return base
+}
+
+func mergeByPosition(base, overlay []interface{}, strategy MergeStrategy) []interface{} {
+	for i, item := range overlay {
+		if i < len(base) {
+			base[i] = mergeValue(base[i], item, "", strategy)
+			continue
+		}
+		base = append(base, item)
+	}
+	return base
+}
+
+func itemName(item interface{}) string {
+	obj, _ := item.(map[string]interface{})
+	name, _ := obj["name"].(string)
+	return name
+}
+
+// ThreeWayMerge computes what applying desired over live should store,
+// given lastApplied, the desired manifest of the previous apply (nil if
+// unknown), as kubectl apply does:
+//
+//   - fields desired sets take its values;
+//   - fields lastApplied set and desired no longer does are removed;
+//   - fields only live has, such as edits made on the target, are kept.
+//
+// Objects merge field by field and tools merge by name; other lists are
+// replaced whole. Conflicts lists the paths live changed since lastApplied
+// that the result overrides, sorted.
+func ThreeWayMerge(live, lastApplied, desired *Manifest) (*Manifest, []string, error) {
+	liveDoc, err := manifestDoc(live)
+	if err != nil {
+		return nil, nil, err
+	}
+	var lastDoc interface{}
+	if lastApplied != nil {
+		if lastDoc, err = manifestDoc(lastApplied); err != nil {
+			return nil, nil, err
+		}
+	}
+	desiredDoc, err := manifestDoc(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conflicts []string
+	merged := merge3(liveDoc, lastDoc, desiredDoc, "", "", &conflicts)
+	sort.Strings(conflicts)
+	out := &Manifest{}
+	if err := out.setDoc(merged); err != nil {
+		return nil, nil, err
+	}
+	return out, conflicts, nil
+}
+
+func merge3(live, last, desired interface{}, key, path string, conflicts *[]string) interface{} {
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			break
+		}
+		prev, _ := last.(map[string]interface{})
+		out := map[string]interface{}{}
+		for k, v := range l {
+			_, wanted := d[k]
+			_, applied := prev[k]
+			if wanted || !applied {
+				out[k] = v
+				continue
+			}
+			if !reflect.DeepEqual(v, prev[k]) {
+				*conflicts = append(*conflicts, join(k))
+			}
+		}
+		for k, v := range d {
+			out[k] = merge3(l[k], prev[k], v, k, join(k), conflicts)
+		}
+		return out
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if key == "tools" && ok && namedItems(d) && namedItems(l) {
+			prev, _ := last.([]interface{})
+			return merge3ByName(l, prev, d, path, conflicts)
+		}
+	}
+	if live != nil && !reflect.DeepEqual(live, last) && !reflect.DeepEqual(live, desired) {
+		*conflicts = append(*conflicts, path)
+	}
+	return desired
+}
+
+// merge3ByName merges lists of named objects: live's order is kept, items
+// dropped from desired since lastApplied are removed, and new ones are
+// appended in desired's order.
+func merge3ByName(live, last, desired []interface{}, path string, conflicts *[]string) []interface{} {
+	byName := func(items []interface{}) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, item := range items {
+			m[itemName(item)] = item
+		}
+		return m
+	}
+	wanted, applied := byName(desired), byName(last)
+	out := []interface{}{}
+	seen := map[string]bool{}
+	for _, item := range live {
+		name := itemName(item)
+		seen[name] = true
+		if d, ok := wanted[name]; ok {
+			out = append(out, merge3(item, applied[name], d, "", path+"."+name, conflicts))
+		} else if _, ok := applied[name]; !ok {
+			out = append(out, item)
+		} else if !reflect.DeepEqual(item, applied[name]) {
+			*conflicts = append(*conflicts, path+"."+name)
+		}
+	}
+	for _, item := range desired {
+		if !seen[itemName(item)] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// namedItems reports whether every item is an object with a name.
+func namedItems(items []interface{}) bool {
+	for _, item := range items {
+		if itemName(item) == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+package ossa
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Input modalities for InputConfig.Modalities.
+const (
+	ModalityText  = "text"
+	ModalityImage = "image"
+	ModalityAudio = "audio"
+)
+
+// Default per-attachment limits, the largest the providers accept.
+const (
+	DefaultMaxImageBytes = 20 << 20
+	DefaultMaxAudioBytes = 25 << 20
+)
+
+// DefaultMIMETypes are the media types accepted for each modality when
+// spec.input.mime_types is not set.
+var DefaultMIMETypes = map[string][]string{
+	ModalityImage: {"image/png", "image/jpeg", "image/gif", "image/webp"},
+	ModalityAudio: {"audio/wav", "audio/mpeg"},
+}
+
+// InputConfig declares the input an agent accepts besides text. Validation
+// checks its modalities against the agent's models; the engine enforces
+// the limits on each run's attachments.
+type InputConfig struct {
+	Modalities []string `json:"modalities,omitempty" yaml:"modalities,omitempty"`
+	// MaxImageBytes and MaxAudioBytes limit each attachment; 0 is the
+	// default for the modality.
+	MaxImageBytes int `json:"max_image_bytes,omitempty" yaml:"max_image_bytes,omitempty"`
+	MaxAudioBytes int `json:"max_audio_bytes,omitempty" yaml:"max_audio_bytes,omitempty"`
+	// MaxAttachments limits the attachments of a run; 0 is no limit.
+	MaxAttachments int `json:"max_attachments,omitempty" yaml:"max_attachments,omitempty"`
+	// MIMETypes, if set, are the only media types accepted.
+	MIMETypes []string `json:"mime_types,omitempty" yaml:"mime_types,omitempty"`
+}
+
+// Accepts reports whether modality is declared. Text always is, and a nil
+// config accepts nothing else.
+func (c *InputConfig) Accepts(modality string) bool {
+	if modality == ModalityText {
+		return true
+	}
+	return c != nil && contains(c.Modalities, modality)
+}
+
+// AcceptsMIMEType reports whether attachments of mimeType are accepted:
+// its modality is declared and it is in MIMETypes, or DefaultMIMETypes
+// when that is not set.
+func (c *InputConfig) AcceptsMIMEType(mimeType string) bool {
+	modality := MIMEModality(mimeType)
+	if modality == "" || !c.Accepts(modality) {
+		return false
+	}
+	allowed := c.MIMETypes
+	if len(allowed) == 0 {
+		allowed = DefaultMIMETypes[modality]
+	}
+	return contains(allowed, mimeType)
+}
+
+// MaxBytes is the size limit of one attachment of modality.
+func (c *InputConfig) MaxBytes(modality string) int {
+	if modality == ModalityAudio {
+		if c.MaxAudioBytes > 0 {
+			return c.MaxAudioBytes
+		}
+		return DefaultMaxAudioBytes
+	}
+	if c.MaxImageBytes > 0 {
+		return c.MaxImageBytes
+	}
+	return DefaultMaxImageBytes
+}
+
+// MIMEModality is the modality of a media type: image for image/*, audio
+// for audio/*, else "".
+func MIMEModality(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return ModalityImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return ModalityAudio
+	}
+	return ""
+}
+
+// ProviderModalities are the input modalities each provider's API, and
+// its engine adapter, accepts.
+var ProviderModalities = map[string][]string{
+	"openai":     {ModalityText, ModalityImage, ModalityAudio},
+	"azure":      {ModalityText, ModalityImage, ModalityAudio},
+	"litellm":    {ModalityText, ModalityImage, ModalityAudio},
+	"openrouter": {ModalityText, ModalityImage, ModalityAudio},
+	"google":     {ModalityText, ModalityImage, ModalityAudio},
+	"ollama":     {ModalityText, ModalityImage},
+	"bedrock":    {ModalityText, ModalityImage},
+	"anthropic":  {ModalityText, ModalityImage},
+	"mistral":    {ModalityText, ModalityImage},
+	"groq":       {ModalityText, ModalityImage},
+	"together":   {ModalityText, ModalityImage},
+	"fireworks":  {ModalityText, ModalityImage},
+	"cohere":     {ModalityText, ModalityImage},
+	"deepseek":   {ModalityText},
+}
+
+// KnownModalities are the input modalities of common models, keyed by
+// model name pattern as KnownContextWindows is; the longest match wins.
+var KnownModalities = map[string][]string{
+	"gpt-4o*":            {ModalityText, ModalityImage},
+	"gpt-4o-audio*":      {ModalityText, ModalityAudio},
+	"gpt-4o-mini-audio*": {ModalityText, ModalityAudio},
+	"gpt-4.1*":           {ModalityText, ModalityImage},
+	"gpt-4-turbo*":       {ModalityText, ModalityImage},
+	"gpt-4":              {ModalityText},
+	"gpt-3.5-turbo*":     {ModalityText},
+	"gpt-5*":             {ModalityText, ModalityImage},
+	"o1*":                {ModalityText, ModalityImage},
+	"o1-mini*":           {ModalityText},
+	"o3*":                {ModalityText, ModalityImage},
+	"o3-mini*":           {ModalityText},
+	"o4-mini*":           {ModalityText, ModalityImage},
+	"*claude-*":          {ModalityText, ModalityImage},
+	"gemini*":            {ModalityText, ModalityImage, ModalityAudio},
+	"*llava*":            {ModalityText, ModalityImage},
+	"*llama3*":           {ModalityText},
+	"*llama3.2-vision*":  {ModalityText, ModalityImage},
+	"*llama4*":           {ModalityText, ModalityImage},
+	"gemma3*":            {ModalityText, ModalityImage},
+	"*qwen2.5vl*":        {ModalityText, ModalityImage},
+	"mistral*":           {ModalityText},
+	"*mistral-large*":    {ModalityText},
+	"pixtral*":           {ModalityText, ModalityImage},
+	"*nova-pro*":         {ModalityText, ModalityImage},
+	"*nova-lite*":        {ModalityText, ModalityImage},
+	"*nova-micro*":       {ModalityText},
+	"*command-r*":        {ModalityText},
+	"deepseek*":          {ModalityText},
+}
+
+// ModelModalities returns the input modalities of model from
+// KnownModalities, matching as ModelPolicy.ContextWindow does.
+func ModelModalities(model string) ([]string, bool) {
+	if m, ok := KnownModalities[model]; ok {
+		return m, true
+	}
+	name := model[strings.LastIndex(model, "/")+1:]
+	best := ""
+	for pattern := range KnownModalities {
+		if ok, _ := path.Match(pattern, name); ok && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+	return KnownModalities[best], true
+}
+
+// validateInput checks spec.input and that every model of m, and its
+// provider, accepts the declared modalities.
+func validateInput(m *Manifest, result *ValidationResult) {
+	in := m.Spec.Input
+	if in == nil {
+		return
+	}
+	for i, modality := range in.Modalities {
+		switch modality {
+		case ModalityText, ModalityImage, ModalityAudio:
+		default:
+			result.addError(fmt.Sprintf("spec.input.modalities[%d]: invalid modality: %s", i, modality))
+		}
+	}
+	if in.MaxImageBytes < 0 || in.MaxAudioBytes < 0 || in.MaxAttachments < 0 {
+		result.addError("spec.input: limits must not be negative")
+	}
+	for i, t := range in.MIMETypes {
+		if modality := MIMEModality(t); modality == "" || !in.Accepts(modality) {
+			result.addError(fmt.Sprintf("spec.input.mime_types[%d]: %s is not of a declared modality", i, t))
+		}
+	}
+	for _, ref := range m.models() {
+		for _, modality := range in.Modalities {
+			if modality != ModalityImage && modality != ModalityAudio {
+				continue
+			}
+			if accepted, ok := ProviderModalities[ref.provider]; ok && !contains(accepted, modality) {
+				result.addError(fmt.Sprintf("%s.provider: %s does not accept %s input", ref.path, ref.provider, modality))
+				continue
+			}
+			accepted, ok := ModelModalities(ref.model)
+			switch {
+			case !ok:
+				result.addWarning(fmt.Sprintf("%s.model: input modalities of %s are unknown; %s input may be rejected", ref.path, ref.model, modality))
+			case !contains(accepted, modality):
+				result.addError(fmt.Sprintf("%s.model: %s does not accept %s input", ref.path, ref.model, modality))
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
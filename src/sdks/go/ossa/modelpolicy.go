@@ -0,0 +1,177 @@
+package ossa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelPolicyRule is the name of the rule a ModelPolicy registers as.
+const ModelPolicyRule = "ossa/model-policy"
+
+// ModelPolicy is an organization's rules for the models agents may use.
+// Registered with RegisterRule(p.Rule()), it fails validation of
+// manifests whose spec.llm, any of its fallbacks or a tool's llm override
+// breaks them.
+//
+// Models are matched as written or as provider/model, against names or
+// path.Match patterns such as gpt-4o* or openai/*.
+type ModelPolicy struct {
+	// AllowedProviders, if set, are the only providers agents may use.
+	AllowedProviders []string `json:"allowed_providers,omitempty" yaml:"allowed_providers,omitempty"`
+	// AllowedModels, if set, are the only models agents may use.
+	AllowedModels []string `json:"allowed_models,omitempty" yaml:"allowed_models,omitempty"`
+	// BannedModels are never allowed, even when AllowedModels match.
+	BannedModels []string `json:"banned_models,omitempty" yaml:"banned_models,omitempty"`
+	// MinContextTokens is the smallest context window a model may have.
+	MinContextTokens int `json:"min_context_tokens,omitempty" yaml:"min_context_tokens,omitempty"`
+	// ContextWindows adds to and overrides KnownContextWindows.
+	ContextWindows map[string]int `json:"context_windows,omitempty" yaml:"context_windows,omitempty"`
+}
+
+// KnownContextWindows are the context windows, in tokens, of common
+// models, keyed by model name pattern; the longest matching pattern wins.
+var KnownContextWindows = map[string]int{
+	"gpt-4o*":            128000,
+	"gpt-4.1*":           1047576,
+	"gpt-4-turbo*":       128000,
+	"gpt-4":              8192,
+	"gpt-3.5-turbo*":     16385,
+	"o1*":                200000,
+	"o3*":                200000,
+	"o4-mini*":           200000,
+	"*claude-*":          200000,
+	"gemini-1.5-pro*":    2097152,
+	"gemini-1.5-flash*":  1048576,
+	"gemini-2*":          1048576,
+	"*llama3.1*":         131072,
+	"*llama3.2*":         131072,
+	"*llama3-3*":         131072,
+	"*mistral-large*":    131072,
+	"mistral*":           32768,
+	"*command-r*":        128000,
+	"deepseek-chat*":     65536,
+	"deepseek-reasoner*": 65536,
+}
+
+// LoadModelPolicy reads a model policy from a YAML or JSON file. Unknown
+// fields are errors, so a misspelt rule is not silently ignored.
+func LoadModelPolicy(file string) (*ModelPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model policy: %w", err)
+	}
+	data, err = NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode model policy: %w", err)
+	}
+	p := &ModelPolicy{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse model policy %s: %w", file, err)
+	}
+	if err := p.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return p, nil
+}
+
+func (p *ModelPolicy) check() error {
+	for _, list := range [][]string{p.AllowedModels, p.BannedModels} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return NewError(fmt.Sprintf("invalid model pattern %q", pattern))
+			}
+		}
+	}
+	if p.MinContextTokens < 0 {
+		return NewError("min_context_tokens must not be negative")
+	}
+	return nil
+}
+
+// Rule returns the rule enforcing p.
+func (p *ModelPolicy) Rule() Rule {
+	return Rule{Name: ModelPolicyRule, Check: p.Check}
+}
+
+// Check reports the models of m that p does not allow.
+func (p *ModelPolicy) Check(m *Manifest, r *RuleReport) {
+	for _, ref := range m.models() {
+		p.checkModel(ref.path, ref.provider, ref.model, r)
+	}
+}
+
+func (p *ModelPolicy) checkModel(at, provider, model string, r *RuleReport) {
+	if provider == "" && model == "" {
+		return
+	}
+	allowed := len(p.AllowedProviders) == 0
+	for _, a := range p.AllowedProviders {
+		allowed = allowed || a == provider
+	}
+	if !allowed {
+		r.Error(at+".provider", fmt.Sprintf("provider %s is not approved (allowed: %s)", provider, strings.Join(p.AllowedProviders, ", ")))
+	}
+	if matchModel(p.BannedModels, provider, model) {
+		r.Error(at+".model", fmt.Sprintf("model %s is banned", model))
+	} else if len(p.AllowedModels) > 0 && !matchModel(p.AllowedModels, provider, model) {
+		r.Error(at+".model", fmt.Sprintf("model %s is not approved", model))
+	}
+	if p.MinContextTokens > 0 {
+		window, ok := p.ContextWindow(model)
+		switch {
+		case !ok:
+			r.Warn(at+".model", fmt.Sprintf("context window of %s is unknown; add it to the policy's context_windows", model))
+		case window < p.MinContextTokens:
+			r.Error(at+".model", fmt.Sprintf("context window of %s is %d tokens, below the minimum %d", model, window, p.MinContextTokens))
+		}
+	}
+}
+
+// ContextWindow returns the context window of model from p's
+// ContextWindows, else KnownContextWindows. Only the name after the last
+// slash is matched, so vendor/model names and ARNs are found too.
+func (p *ModelPolicy) ContextWindow(model string) (int, bool) {
+	name := model[strings.LastIndex(model, "/")+1:]
+	for _, table := range []map[string]int{p.ContextWindows, KnownContextWindows} {
+		if window, ok := table[model]; ok {
+			return window, true
+		}
+		best := ""
+		for pattern := range table {
+			if ok, _ := path.Match(pattern, name); ok && len(pattern) > len(best) {
+				best = pattern
+			}
+		}
+		if best != "" {
+			return table[best], true
+		}
+	}
+	return 0, false
+}
+
+// ContextWindow returns the context window of model from
+// KnownContextWindows.
+func ContextWindow(model string) (int, bool) {
+	return (&ModelPolicy{}).ContextWindow(model)
+}
+
+// matchModel reports whether model, or provider/model, matches a pattern.
+func matchModel(patterns []string, provider, model string) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{model, provider + "/" + model} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
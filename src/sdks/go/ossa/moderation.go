@@ -0,0 +1,386 @@
+package ossa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ModerationAction is the action taken when content is flagged.
+type ModerationAction string
+
+const (
+	ModerationBlock  ModerationAction = "block"
+	ModerationRedact ModerationAction = "redact"
+	ModerationFlag   ModerationAction = "flag"
+)
+
+// moderationClient posts to moderation endpoints for providers without a
+// Client.
+var moderationClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxModerationResponse bounds a moderation endpoint's response.
+const maxModerationResponse = 1 << 20
+
+// ErrContentBlocked is returned for prompts and answers that moderation
+// blocked.
+var ErrContentBlocked = errors.New("content blocked by moderation")
+
+// Moderation provider names accepted in spec.safety.moderation.provider.
+const (
+	ModerationProviderOpenAI   = "openai"
+	ModerationProviderKeywords = "keywords"
+	ModerationProviderHTTP     = "http"
+)
+
+// ModerationStage identifies where moderated content came from.
+type ModerationStage string
+
+const (
+	StagePrompt     ModerationStage = "prompt"
+	StageCompletion ModerationStage = "completion"
+)
+
+// ModerationVerdict is a provider's judgement on a piece of content.
+type ModerationVerdict struct {
+	Flagged    bool     `json:"flagged"`
+	Categories []string `json:"categories,omitempty"`
+	// Spans are substrings to remove when the action is redact.
+	Spans []string `json:"spans,omitempty"`
+}
+
+// ModerationProvider classifies content. Implementations must be safe for
+// concurrent use.
+type ModerationProvider interface {
+	Name() string
+	Moderate(ctx context.Context, text string) (*ModerationVerdict, error)
+}
+
+// ModerationResult is the outcome of moderating one piece of content,
+// suitable for recording in an audit log.
+type ModerationResult struct {
+	Stage      ModerationStage  `json:"stage"`
+	Provider   string           `json:"provider"`
+	Flagged    bool             `json:"flagged"`
+	Blocked    bool             `json:"blocked"`
+	Action     ModerationAction `json:"action,omitempty"`
+	Categories []string         `json:"categories,omitempty"`
+	Text       string           `json:"-"`
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// Moderator applies a manifest's moderation config using a provider.
+type Moderator struct {
+	Config   *ModerationConfig
+	Provider ModerationProvider
+}
+
+// NewModerator creates a moderator for the config, building the provider it
+// names. A nil config or unset enabled flag yields a nil moderator.
+func NewModerator(cfg *ModerationConfig) (*Moderator, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	provider, err := NewModerationProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Moderator{Config: cfg, Provider: provider}, nil
+}
+
+// NewModerationProvider builds the built-in provider named by the config.
+func NewModerationProvider(cfg *ModerationConfig) (ModerationProvider, error) {
+	switch cfg.Provider {
+	case ModerationProviderKeywords:
+		return NewKeywordModerator(cfg.Keywords), nil
+	case ModerationProviderOpenAI:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = "https://api.openai.com/v1/moderations"
+		}
+		keyEnv := cfg.APIKeyEnv
+		if keyEnv == "" {
+			keyEnv = "OPENAI_API_KEY"
+		}
+		return &OpenAIModerator{Endpoint: endpoint, APIKey: os.Getenv(keyEnv)}, nil
+	case ModerationProviderHTTP:
+		if cfg.Endpoint == "" {
+			return nil, NewError("moderation provider http requires endpoint")
+		}
+		return &HTTPModerator{Endpoint: cfg.Endpoint, APIKey: apiKeyFromEnv(cfg.APIKeyEnv)}, nil
+	default:
+		return nil, NewError(fmt.Sprintf("unknown moderation provider: %s", cfg.Provider))
+	}
+}
+
+func apiKeyFromEnv(name string) string {
+	if name == "" {
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// Check moderates text for the given stage. Stages not listed in the config
+// pass through unchanged. The returned text is redacted when the action is
+// redact; when Blocked is set the caller must not use the content.
+func (m *Moderator) Check(ctx context.Context, stage ModerationStage, text string) (*ModerationResult, error) {
+	result := &ModerationResult{
+		Stage:     stage,
+		Provider:  m.Provider.Name(),
+		Text:      text,
+		Timestamp: time.Now().UTC(),
+	}
+	if !m.Config.appliesTo(stage) {
+		return result, nil
+	}
+
+	verdict, err := m.Provider.Moderate(ctx, text)
+	if err != nil {
+		return nil, WrapError("moderation failed", err)
+	}
+	if !verdict.Flagged {
+		return result, nil
+	}
+
+	result.Flagged = true
+	result.Categories = verdict.Categories
+	result.Action = m.Config.action()
+	switch result.Action {
+	case ModerationBlock:
+		result.Blocked = true
+		result.Text = ""
+	case ModerationRedact:
+		for _, span := range verdict.Spans {
+			result.Text = strings.ReplaceAll(result.Text, span, "[REDACTED]")
+		}
+	}
+	return result, nil
+}
+
+// Record logs a flagged result of agent's content as a warning and, if
+// audit is set, sends audit an event with its stage, provider, action and
+// categories. Results that are not flagged are not recorded.
+func (r *ModerationResult) Record(audit *slog.Logger, agent string) {
+	if !r.Flagged {
+		return
+	}
+	attrs := []interface{}{"agent", agent, "stage", r.Stage, "provider", r.Provider, "moderation", r.Action, "categories", r.Categories}
+	Logger().Warn("moderation flagged content", attrs...)
+	if audit != nil {
+		audit.Info("audit", append([]interface{}{"action", "moderate"}, attrs...)...)
+	}
+}
+
+// Moderates reports whether Check moderates stage. A nil Moderator
+// moderates none.
+func (m *Moderator) Moderates(stage ModerationStage) bool {
+	return m != nil && m.Config.appliesTo(stage)
+}
+
+func (c *ModerationConfig) appliesTo(stage ModerationStage) bool {
+	if len(c.Stages) == 0 {
+		return true
+	}
+	for _, s := range c.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ModerationConfig) action() ModerationAction {
+	if c.Action == "" {
+		return ModerationBlock
+	}
+	return c.Action
+}
+
+// KeywordModerator flags content containing any of a list of keywords,
+// matched case-insensitively on word boundaries at the ends that are word
+// characters.
+type KeywordModerator struct {
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// NewKeywordModerator creates a keyword moderator.
+func NewKeywordModerator(keywords []string) *KeywordModerator {
+	k := &KeywordModerator{}
+	for _, kw := range keywords {
+		if kw == "" {
+			continue
+		}
+		k.keywords = append(k.keywords, kw)
+		k.patterns = append(k.patterns, regexp.MustCompile(`(?i)`+wordBoundary(kw[0])+regexp.QuoteMeta(kw)+wordBoundary(kw[len(kw)-1])))
+	}
+	return k
+}
+
+// wordBoundary is \b if c is a word character, so keywords such as "c++"
+// still match where they begin or end with another character.
+func wordBoundary(c byte) string {
+	if c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+		return `\b`
+	}
+	return ""
+}
+
+// Name returns the provider name.
+func (k *KeywordModerator) Name() string { return ModerationProviderKeywords }
+
+// Moderate implements ModerationProvider.
+func (k *KeywordModerator) Moderate(_ context.Context, text string) (*ModerationVerdict, error) {
+	verdict := &ModerationVerdict{}
+	for i, re := range k.patterns {
+		matches := re.FindAllString(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		verdict.Flagged = true
+		verdict.Categories = append(verdict.Categories, k.keywords[i])
+		verdict.Spans = append(verdict.Spans, matches...)
+	}
+	return verdict, nil
+}
+
+// HTTPModerator posts {"input": text} to a custom endpoint that responds
+// with a ModerationVerdict.
+type HTTPModerator struct {
+	Endpoint string
+	APIKey   string
+	// Client posts the content; nil means a client with a 30s timeout.
+	Client *http.Client
+}
+
+// Name returns the provider name.
+func (h *HTTPModerator) Name() string { return ModerationProviderHTTP }
+
+// Moderate implements ModerationProvider.
+func (h *HTTPModerator) Moderate(ctx context.Context, text string) (*ModerationVerdict, error) {
+	var verdict ModerationVerdict
+	if err := postModeration(ctx, h.Client, h.Endpoint, h.APIKey, text, &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+// OpenAIModerator uses the OpenAI moderation endpoint.
+type OpenAIModerator struct {
+	Endpoint string
+	APIKey   string
+	// Client posts the content; nil means a client with a 30s timeout.
+	Client *http.Client
+}
+
+// Name returns the provider name.
+func (o *OpenAIModerator) Name() string { return ModerationProviderOpenAI }
+
+// Moderate implements ModerationProvider.
+func (o *OpenAIModerator) Moderate(ctx context.Context, text string) (*ModerationVerdict, error) {
+	var resp struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := postModeration(ctx, o.Client, o.Endpoint, o.APIKey, text, &resp); err != nil {
+		return nil, err
+	}
+
+	verdict := &ModerationVerdict{}
+	for _, r := range resp.Results {
+		if !r.Flagged {
+			continue
+		}
+		verdict.Flagged = true
+		for category, hit := range r.Categories {
+			if hit {
+				verdict.Categories = append(verdict.Categories, category)
+			}
+		}
+	}
+	// OpenAI does not return spans, so redaction replaces the whole text.
+	if verdict.Flagged {
+		verdict.Spans = []string{text}
+	}
+	return verdict, nil
+}
+
+func postModeration(ctx context.Context, client *http.Client, endpoint, apiKey, text string, out interface{}) error {
+	body, err := json.Marshal(map[string]string{"input": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	if client == nil {
+		client = moderationClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("moderation endpoint returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxModerationResponse)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	return nil
+}
+
+func validateModeration(cfg *ModerationConfig, result *ValidationResult) {
+	if cfg == nil {
+		return
+	}
+	switch cfg.Provider {
+	case ModerationProviderKeywords:
+		if len(cfg.Keywords) == 0 {
+			result.addError("spec.safety.moderation: keywords provider requires keywords")
+		}
+	case ModerationProviderHTTP:
+		if cfg.Endpoint == "" {
+			result.addError("spec.safety.moderation: http provider requires endpoint")
+		}
+	case ModerationProviderOpenAI:
+	case "":
+		result.addError("spec.safety.moderation: missing provider")
+	default:
+		result.addError(fmt.Sprintf("spec.safety.moderation: invalid provider: %s", cfg.Provider))
+	}
+
+	if cfg.APIKeyEnv != "" && !envNamePattern.MatchString(cfg.APIKeyEnv) {
+		result.addError(fmt.Sprintf("spec.safety.moderation.api_key_env: invalid variable name: %s", cfg.APIKeyEnv))
+	}
+
+	switch cfg.Action {
+	case "", ModerationBlock, ModerationRedact, ModerationFlag:
+	default:
+		result.addError(fmt.Sprintf("spec.safety.moderation: invalid action: %s", cfg.Action))
+	}
+
+	for _, s := range cfg.Stages {
+		if s != StagePrompt && s != StageCompletion {
+			result.addError(fmt.Sprintf("spec.safety.moderation: invalid stage: %s", s))
+		}
+	}
+}
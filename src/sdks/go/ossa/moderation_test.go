@@ -0,0 +1,149 @@
+package ossa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeywordModeration(t *testing.T) {
+	tests := []struct {
+		action      ModerationAction
+		wantBlocked bool
+		wantText    string
+	}{
+		{ModerationBlock, true, ""},
+		{ModerationRedact, false, "my [REDACTED] is hunter2"},
+		{ModerationFlag, false, "my Password is hunter2"},
+	}
+
+	for _, tt := range tests {
+		mod, err := NewModerator(&ModerationConfig{
+			Enabled:  true,
+			Provider: ModerationProviderKeywords,
+			Keywords: []string{"password"},
+			Action:   tt.action,
+		})
+		if err != nil {
+			t.Fatalf("NewModerator failed: %v", err)
+		}
+
+		res, err := mod.Check(context.Background(), StagePrompt, "my Password is hunter2")
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if !res.Flagged {
+			t.Errorf("%s: expected content to be flagged", tt.action)
+		}
+		if res.Blocked != tt.wantBlocked {
+			t.Errorf("%s: expected blocked=%v, got %v", tt.action, tt.wantBlocked, res.Blocked)
+		}
+		if res.Text != tt.wantText {
+			t.Errorf("%s: expected text %q, got %q", tt.action, tt.wantText, res.Text)
+		}
+	}
+}
+
+func TestKeywordBoundaries(t *testing.T) {
+	k := NewKeywordModerator([]string{"c++", "$$", "pass"})
+	for text, want := range map[string]bool{
+		"we write C++ here": true,
+		"it costs $$":       true,
+		"a passport":        false,
+		"pass it on":        true,
+	} {
+		if v, _ := k.Moderate(context.Background(), text); v.Flagged != want {
+			t.Errorf("%q: expected flagged=%v, got %+v", text, want, v)
+		}
+	}
+}
+
+func TestHTTPModerationLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat(" ", maxModerationResponse) + `{"flagged": false}`))
+	}))
+	defer srv.Close()
+	h := &HTTPModerator{Endpoint: srv.URL}
+	if _, err := h.Moderate(context.Background(), "hello"); err == nil {
+		t.Error("Expected a response over the limit to fail")
+	}
+}
+
+func TestModerationStages(t *testing.T) {
+	mod, _ := NewModerator(&ModerationConfig{
+		Enabled:  true,
+		Provider: ModerationProviderKeywords,
+		Keywords: []string{"secret"},
+		Stages:   []ModerationStage{StageCompletion},
+	})
+
+	res, err := mod.Check(context.Background(), StagePrompt, "a secret")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if res.Flagged {
+		t.Error("Expected prompt stage to be skipped")
+	}
+
+	if m, _ := NewModerator(&ModerationConfig{Provider: ModerationProviderKeywords}); m != nil {
+		t.Error("Expected nil moderator when moderation is disabled")
+	}
+}
+
+func TestOpenAIModeration(t *testing.T) {
+	t.Setenv("TEST_MODERATION_KEY", "test-key")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"flagged": true, "categories": map[string]bool{"violence": true, "hate": false}},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	mod, err := NewModerator(&ModerationConfig{
+		Enabled:   true,
+		Provider:  ModerationProviderOpenAI,
+		Endpoint:  srv.URL,
+		APIKeyEnv: "TEST_MODERATION_KEY",
+		Action:    ModerationRedact,
+	})
+	if err != nil {
+		t.Fatalf("NewModerator failed: %v", err)
+	}
+
+	res, err := mod.Check(context.Background(), StageCompletion, "something violent")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !res.Flagged || len(res.Categories) != 1 || res.Categories[0] != "violence" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if res.Text != "[REDACTED]" {
+		t.Errorf("Expected fully redacted text, got %q", res.Text)
+	}
+}
+
+func TestValidateModeration(t *testing.T) {
+	m := NewManifest("mod-agent", KindAgent)
+	m.Spec.Safety = &SafetyConfig{Moderation: &ModerationConfig{
+		Enabled:   true,
+		Provider:  "telepathy",
+		APIKeyEnv: "${AWS_SECRET_ACCESS_KEY}",
+		Action:    "ignore",
+	}}
+
+	result := ValidateManifest(m)
+	if result.Valid {
+		t.Fatal("Expected invalid moderation config to fail validation")
+	}
+	if len(result.Errors) != 3 {
+		t.Errorf("Expected 3 errors, got %v", result.Errors)
+	}
+}
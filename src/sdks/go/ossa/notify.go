@@ -0,0 +1,135 @@
+package ossa
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// ErrApprovalDenied is returned when a human declines an action.
+var ErrApprovalDenied = NewError("action was not approved")
+
+// ApprovalRequest describes an action waiting for a human decision.
+type ApprovalRequest struct {
+	Agent  string
+	Tool   string
+	CallID string
+	// Summary is what will happen, for example the rendered message.
+	Summary string
+}
+
+// ApprovalFunc asks a human to approve an action and reports the decision.
+type ApprovalFunc func(ctx context.Context, req ApprovalRequest) (bool, error)
+
+// RequiresApproval reports whether calls to a tool need a human decision,
+// either because autonomy.approvalRequired is set or because the tool is
+// listed (or "*" is listed) in safety.guardrails.require_human_approval_for.
+func (m *Manifest) RequiresApproval(tool string) bool {
+	if m.Spec.Autonomy != nil && m.Spec.Autonomy.ApprovalRequired {
+		return true
+	}
+	if m.Spec.Safety == nil || m.Spec.Safety.Guardrails == nil {
+		return false
+	}
+	for _, name := range m.Spec.Safety.Guardrails.RequireHumanApprovalFor {
+		if name == tool || name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestApproval calls approve and returns ErrApprovalDenied unless the
+// request was approved.
+func RequestApproval(ctx context.Context, approve ApprovalFunc, req ApprovalRequest) error {
+	ok, err := approve(ctx, req)
+	if err != nil {
+		return WrapError("approval request failed", err)
+	}
+	if !ok {
+		return ErrApprovalDenied
+	}
+	return nil
+}
+
+// RenderTemplate executes a text/template template against call arguments.
+// Referencing an argument that was not passed is an error.
+func RenderTemplate(text string, args map[string]interface{}) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, args); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
+
+var slackChannelPattern = regexp.MustCompile(`^(#[a-z0-9][a-z0-9._-]{0,79}|[CGD][A-Z0-9]{8,})$`)
+
+func validateNotify(path string, m *Manifest, h *ToolHandler, result *ValidationResult) {
+	if tier := m.EffectiveAccessTier(); !tier.CanWrite() {
+		result.addError(fmt.Sprintf("%s: %s runtime is not allowed for %s agents", path, h.Runtime, tier))
+	}
+
+	var templates map[string]string
+	switch h.Runtime {
+	case RuntimeSMTP:
+		cfg := h.SMTP
+		if cfg == nil {
+			result.addError(path + ".smtp: required for smtp runtime")
+			return
+		}
+		if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+			result.addError(fmt.Sprintf("%s.smtp.addr: expected host:port, got %s", path, cfg.Addr))
+		}
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			result.addError(fmt.Sprintf("%s.smtp.from: invalid address: %s", path, cfg.From))
+		}
+		if len(cfg.To) == 0 {
+			result.addError(path + ".smtp.to: at least one recipient is required")
+		}
+		for _, to := range cfg.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				result.addError(fmt.Sprintf("%s.smtp.to: invalid address: %s", path, to))
+			}
+		}
+		if (cfg.UsernameEnv == "") != (cfg.PasswordEnv == "") {
+			result.addError(path + ".smtp: username_env and password_env must be set together")
+		}
+		templates = map[string]string{"subject": cfg.Subject, "body": cfg.Body}
+	case RuntimeSlack:
+		cfg := h.Slack
+		if cfg == nil {
+			result.addError(path + ".slack: required for slack runtime")
+			return
+		}
+		switch {
+		case (cfg.TokenEnv == "") == (cfg.WebhookEnv == ""):
+			result.addError(path + ".slack: exactly one of token_env or webhook_env is required")
+		case cfg.TokenEnv != "" && cfg.Channel == "":
+			result.addError(path + ".slack.channel: required with token_env")
+		}
+		if cfg.Channel != "" && !slackChannelPattern.MatchString(cfg.Channel) {
+			result.addError(fmt.Sprintf("%s.slack.channel: invalid channel: %s", path, cfg.Channel))
+		}
+		templates = map[string]string{"text": cfg.Text}
+	}
+
+	for _, field := range []string{"subject", "body", "text"} {
+		text, ok := templates[field]
+		if !ok {
+			continue
+		}
+		if text == "" {
+			result.addError(fmt.Sprintf("%s.%s.%s: required", path, h.Runtime, field))
+		} else if _, err := template.New("").Parse(text); err != nil {
+			result.addError(fmt.Sprintf("%s.%s.%s: %v", path, h.Runtime, field, err))
+		}
+	}
+}
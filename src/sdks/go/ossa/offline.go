@@ -0,0 +1,106 @@
+package ossa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrOffline is wrapped by the errors of anything that would need the
+// network in offline mode.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+var offline atomic.Bool
+
+// SetOffline turns offline mode on or off. In offline mode the SDK makes no
+// network calls: schemas with remote $refs fail to compile, and registry
+// lookups, KMS decryption, smtp tools and NATS connections fail, rather
+// than reaching out. Validation against the embedded or vendored schemas
+// is unaffected. DisableNetwork extends the guarantee to HTTP clients the
+// SDK does not build.
+func SetOffline(on bool) {
+	offline.Store(on)
+}
+
+// Offline reports whether offline mode is on.
+func Offline() bool {
+	return offline.Load()
+}
+
+// RequireNetwork returns an error wrapping ErrOffline, naming what needs
+// the network, in offline mode, and nil otherwise. Code that is about to
+// make a network call checks it to fail fast.
+func RequireNetwork(what string) error {
+	if !Offline() {
+		return nil
+	}
+	return refuseNetwork(what)
+}
+
+// DisableNetwork makes t, and transports cloned from it afterwards, refuse
+// to dial, logging each attempt. Called on http.DefaultTransport it stops
+// every client that uses the default, so a program can audit, rather than
+// trust, that a run made no network calls.
+func DisableNetwork(t *http.Transport) {
+	t.Proxy = nil
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, refuseNetwork("dial " + addr)
+	}
+	t.DialTLSContext = nil
+}
+
+func refuseNetwork(what string) error {
+	Logger().Warn("network call refused", "for", what)
+	return WrapError(what, ErrOffline)
+}
+
+// remoteRefs returns the http(s) $refs in a JSON Schema, which
+// gojsonschema would download while compiling it.
+func remoteRefs(schema []byte) []string {
+	var doc interface{}
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && (strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")) {
+				seen[ref] = true
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// checkSchemaOffline fails schemas that would be downloaded from in
+// offline mode.
+func checkSchemaOffline(schema []byte) error {
+	if !Offline() {
+		return nil
+	}
+	if refs := remoteRefs(schema); len(refs) > 0 {
+		return RequireNetwork(fmt.Sprintf("schema references %s", strings.Join(refs, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package ossa
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIImportOptions selects which operations become tools.
+type OpenAPIImportOptions struct {
+	// Operations limits the import to these operationIds.
+	Operations []string
+	// Tags limits the import to operations with any of these tags.
+	Tags []string
+	// Server overrides the base URL taken from the document's servers list.
+	Server string
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// maxRefDepth bounds $ref resolution so recursive schemas terminate.
+const maxRefDepth = 16
+
+// ImportOpenAPI converts operations of an OpenAPI 3 document (YAML or JSON)
+// into http tools. Each tool's parameters are a JSON Schema object built
+// from the operation's path, query and header parameters, with the JSON
+// request body under "body". Security schemes become header placeholders
+// such as "Bearer ${PETSTORE_TOKEN}" to be filled from the environment.
+// The tools run on the http runtime.
+func ImportOpenAPI(data []byte, opts OpenAPIImportOptions) ([]ToolConfig, error) {
+	data, err := NormalizeEncoding(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	version, _ := doc["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, NewError(fmt.Sprintf("unsupported OpenAPI version: %q (only 3.x is supported)", version))
+	}
+
+	imp := &openAPIImporter{doc: doc}
+	base := opts.Server
+	if base == "" {
+		if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+			if s, ok := servers[0].(map[string]interface{}); ok {
+				base, _ = s["url"].(string)
+			}
+		}
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	wantOps := toSet(opts.Operations)
+	wantTags := toSet(opts.Tags)
+	globalSecurity, _ := doc["security"].([]interface{})
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathKeys := make([]string, 0, len(paths))
+	for p := range paths {
+		pathKeys = append(pathKeys, p)
+	}
+	sort.Strings(pathKeys)
+
+	var tools []ToolConfig
+	for _, path := range pathKeys {
+		item, _ := imp.resolve(paths[path], 0).(map[string]interface{})
+		shared, _ := item["parameters"].([]interface{})
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			opID, _ := op["operationId"].(string)
+			if len(wantOps) > 0 && !wantOps[opID] {
+				continue
+			}
+			if len(wantTags) > 0 && !hasAnyTag(op, wantTags) {
+				continue
+			}
+
+			security := globalSecurity
+			if s, ok := op["security"].([]interface{}); ok {
+				security = s
+			}
+			params := append(append([]interface{}{}, shared...), asSlice(op["parameters"])...)
+
+			tools = append(tools, ToolConfig{
+				Type:        "http",
+				Name:        openAPIToolName(opID, method, path),
+				Description: firstString(op["summary"], op["description"]),
+				Parameters:  imp.parameterSchema(params, op["requestBody"]),
+				Handler: &ToolHandler{
+					Runtime:  RuntimeHTTP,
+					Endpoint: base + path,
+					Method:   strings.ToUpper(method),
+					Headers:  imp.authHeaders(security),
+				},
+			})
+		}
+	}
+
+	if len(wantOps) > 0 {
+		found := make(map[string]bool, len(tools))
+		for _, t := range tools {
+			found[t.Name] = true
+		}
+		for op := range wantOps {
+			if !found[openAPIToolName(op, "", "")] {
+				return nil, NewError(fmt.Sprintf("operation not found: %s", op))
+			}
+		}
+	}
+	return tools, nil
+}
+
+type openAPIImporter struct {
+	doc map[string]interface{}
+}
+
+// resolve follows local "#/..." references.
+func (imp *openAPIImporter) resolve(v interface{}, depth int) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok || depth >= maxRefDepth || !strings.HasPrefix(ref, "#/") {
+		return v
+	}
+	var cur interface{} = imp.doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		node, ok := cur.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		cur = node[part]
+	}
+	return imp.resolve(cur, depth+1)
+}
+
+// inline returns a copy of a schema with local references expanded.
+func (imp *openAPIImporter) inline(v interface{}, depth int) interface{} {
+	if depth >= maxRefDepth {
+		return map[string]interface{}{}
+	}
+	switch t := imp.resolve(v, 0).(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = imp.inline(val, depth+1)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = imp.inline(val, depth+1)
+		}
+		return out
+	default:
+		return t
+	}
+}
+
+func (imp *openAPIImporter) parameterSchema(params []interface{}, body interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []interface{}
+
+	for _, p := range params {
+		param, ok := imp.resolve(p, 0).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" || in == "cookie" {
+			continue
+		}
+		schema, _ := imp.inline(param["schema"], 0).(map[string]interface{})
+		if schema == nil {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		if desc, ok := param["description"].(string); ok {
+			schema["description"] = desc
+		}
+		schema["x-ossa-in"] = in
+		properties[name] = schema
+		if req, _ := param["required"].(bool); req || in == "path" {
+			required = append(required, name)
+		}
+	}
+
+	if rb, ok := imp.resolve(body, 0).(map[string]interface{}); ok {
+		content, _ := rb["content"].(map[string]interface{})
+		if media, ok := content["application/json"].(map[string]interface{}); ok {
+			if schema, ok := imp.inline(media["schema"], 0).(map[string]interface{}); ok {
+				properties["body"] = schema
+				if req, _ := rb["required"].(bool); req {
+					required = append(required, "body")
+				}
+			}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (imp *openAPIImporter) authHeaders(security []interface{}) map[string]string {
+	components, _ := imp.doc["components"].(map[string]interface{})
+	schemes, _ := components["securitySchemes"].(map[string]interface{})
+	if len(security) == 0 || len(schemes) == 0 {
+		return nil
+	}
+
+	// Only the first requirement is used; alternatives are not combined.
+	req, _ := security[0].(map[string]interface{})
+	names := make([]string, 0, len(req))
+	for name := range req {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := map[string]string{}
+	for _, name := range names {
+		scheme, ok := imp.resolve(schemes[name], 0).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		env := envVarName(name)
+		typ, _ := scheme["type"].(string)
+		switch typ {
+		case "apiKey":
+			if in, _ := scheme["in"].(string); in == "header" {
+				header, _ := scheme["name"].(string)
+				headers[header] = "${" + env + "}"
+			}
+		case "http":
+			switch s, _ := scheme["scheme"].(string); strings.ToLower(s) {
+			case "bearer":
+				headers["Authorization"] = "Bearer ${" + env + "}"
+			case "basic":
+				headers["Authorization"] = "Basic ${" + env + "}"
+			}
+		case "oauth2", "openIdConnect":
+			headers["Authorization"] = "Bearer ${" + env + "}"
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func openAPIToolName(opID, method, path string) string {
+	if opID == "" {
+		opID = method + "_" + path
+	}
+	// camelCase to snake_case, then collapse other separators.
+	var b strings.Builder
+	for i, r := range opID {
+		if r >= 'A' && r <= 'Z' && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	name := nonIdentChars.ReplaceAllString(strings.ToLower(b.String()), "_")
+	return strings.Trim(strings.ReplaceAll(name, "__", "_"), "_")
+}
+
+func envVarName(s string) string {
+	return strings.Trim(strings.ToUpper(nonIdentChars.ReplaceAllString(openAPIToolName(s, "", ""), "_")), "_")
+}
+
+func hasAnyTag(op map[string]interface{}, tags map[string]bool) bool {
+	for _, t := range asSlice(op["tags"]) {
+		if s, ok := t.(string); ok && tags[s] {
+			return true
+		}
+	}
+	return false
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func firstString(values ...interface{}) string {
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,104 @@
+package ossa
+
+import (
+	"os"
+	"testing"
+)
+
+func TestImportOpenAPI(t *testing.T) {
+	data, err := os.ReadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := ImportOpenAPI(data, OpenAPIImportOptions{Tags: []string{"pets"}})
+	if err != nil {
+		t.Fatalf("ImportOpenAPI failed: %v", err)
+	}
+	if len(tools) != 3 {
+		t.Fatalf("Expected 3 pets tools, got %d", len(tools))
+	}
+
+	byName := map[string]ToolConfig{}
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+
+	list := byName["list_pets"]
+	if list.Handler.Method != "GET" || list.Handler.Endpoint != "https://petstore.example.com/v1/pets" {
+		t.Errorf("Unexpected list_pets handler: %+v", list.Handler)
+	}
+	if list.Handler.Headers["Authorization"] != "Bearer ${PETSTORE_TOKEN}" {
+		t.Errorf("Expected bearer placeholder, got %v", list.Handler.Headers)
+	}
+	props := list.Parameters["properties"].(map[string]interface{})
+	if limit := props["limit"].(map[string]interface{}); limit["type"] != "integer" || limit["x-ossa-in"] != "query" {
+		t.Errorf("Unexpected limit parameter: %v", limit)
+	}
+
+	create := byName["create_pet"]
+	body := create.Parameters["properties"].(map[string]interface{})["body"].(map[string]interface{})
+	if body["required"] == nil || create.Parameters["required"] == nil {
+		t.Errorf("Expected inlined required request body, got %v", create.Parameters)
+	}
+
+	show := byName["show_pet_by_id"]
+	if show.Handler.Headers["X-API-Key"] != "${API_KEY}" {
+		t.Errorf("Expected operation-level apiKey placeholder, got %v", show.Handler.Headers)
+	}
+	if req := show.Parameters["required"].([]interface{}); len(req) != 1 || req[0] != "petId" {
+		t.Errorf("Expected path parameter to be required, got %v", req)
+	}
+
+	manifest := NewManifest("petstore-agent", KindAgent)
+	manifest.Spec.Tools = tools
+	if result := ValidateManifest(manifest); !result.Valid {
+		t.Errorf("Expected imported tools to validate, got %v", result.Errors)
+	}
+}
+
+func TestImportOpenAPISelection(t *testing.T) {
+	data, _ := os.ReadFile("testdata/petstore.yaml")
+
+	tools, err := ImportOpenAPI(data, OpenAPIImportOptions{Operations: []string{"getInventory"}, Server: "http://localhost:8080"})
+	if err != nil {
+		t.Fatalf("ImportOpenAPI failed: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Handler.Endpoint != "http://localhost:8080/store/inventory" {
+		t.Errorf("Unexpected tools: %+v", tools)
+	}
+
+	if _, err := ImportOpenAPI(data, OpenAPIImportOptions{Operations: []string{"deletePet"}}); err == nil {
+		t.Error("Expected unknown operation to fail")
+	}
+	if _, err := ImportOpenAPI([]byte("swagger: '2.0'"), OpenAPIImportOptions{}); err == nil {
+		t.Error("Expected Swagger 2.0 to be rejected")
+	}
+}
+
+func TestGitLabTools(t *testing.T) {
+	tools, err := GitLabTools(GitLabImportOptions{Scopes: []string{"issues", "mrs"}, BaseURL: "https://gitlab.example.com/api/v4/"})
+	if err != nil {
+		t.Fatalf("GitLabTools failed: %v", err)
+	}
+	if len(tools) != 8 {
+		t.Fatalf("Expected 8 tools, got %d", len(tools))
+	}
+	create := tools[2]
+	if create.Name != "gitlab_create_issue" || create.Handler.Method != "POST" ||
+		create.Handler.Endpoint != "https://gitlab.example.com/api/v4/projects/{project_id}/issues" ||
+		create.Config["token_scope"] != GitLabScopeAPI {
+		t.Errorf("Unexpected create tool: %+v %+v", create, create.Handler)
+	}
+
+	readOnly, _ := GitLabTools(GitLabImportOptions{Tier: TierRead})
+	for _, tool := range readOnly {
+		if tool.Handler.Method != "GET" || tool.Config["token_scope"] != GitLabScopeReadAPI {
+			t.Errorf("Expected only read tools for read tier, got %s %s", tool.Handler.Method, tool.Name)
+		}
+	}
+
+	if _, err := GitLabTools(GitLabImportOptions{Scopes: []string{"wikis"}}); err == nil {
+		t.Error("Expected unknown scope to fail")
+	}
+}
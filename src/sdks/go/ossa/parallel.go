@@ -0,0 +1,176 @@
+package ossa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolCall is one tool invocation requested by the model.
+type ToolCall struct {
+	ID        string
+	Tool      string
+	Arguments map[string]interface{}
+}
+
+// ToolCallResult is the outcome of a ToolCall.
+type ToolCallResult struct {
+	ID     string
+	Tool   string
+	Output []byte
+	Err    error
+}
+
+// ToolExecFunc executes a single tool call.
+type ToolExecFunc func(ctx context.Context, call ToolCall) ([]byte, error)
+
+// ToolCallErrors reports the calls that failed in a batch.
+type ToolCallErrors struct {
+	Failed []ToolCallResult
+	Total  int
+}
+
+func (e *ToolCallErrors) Error() string {
+	parts := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		parts[i] = fmt.Sprintf("%s (%s): %v", r.Tool, r.ID, r.Err)
+	}
+	return fmt.Sprintf("%d of %d tool calls failed: %s", len(e.Failed), e.Total, strings.Join(parts, "; "))
+}
+
+// ToolLimits holds the semaphores bounding manifests' tool calls, one set
+// per manifest, so that the limits hold across every ExecuteToolCalls
+// sharing it: the turns and parallel workflow steps of one run. Carry it in
+// the run's context with WithToolLimits. A ToolLimits is safe for
+// concurrent use.
+type ToolLimits struct {
+	mu   sync.Mutex
+	sems map[string]*toolSems
+}
+
+// toolSems are the semaphores of one manifest; nil ones do not limit.
+type toolSems struct {
+	global  chan struct{}
+	perTool map[string]chan struct{}
+}
+
+type toolLimitsKey struct{}
+
+// NewToolLimits returns an empty ToolLimits.
+func NewToolLimits() *ToolLimits {
+	return &ToolLimits{sems: map[string]*toolSems{}}
+}
+
+// WithToolLimits returns a copy of ctx carrying l.
+func WithToolLimits(ctx context.Context, l *ToolLimits) context.Context {
+	return context.WithValue(ctx, toolLimitsKey{}, l)
+}
+
+// ToolLimitsFrom returns the ToolLimits of ctx, or nil.
+func ToolLimitsFrom(ctx context.Context) *ToolLimits {
+	l, _ := ctx.Value(toolLimitsKey{}).(*ToolLimits)
+	return l
+}
+
+// of returns m's semaphores, sized by the manifest first seen under its
+// kind, namespace, name and version.
+func (l *ToolLimits) of(m *Manifest) *toolSems {
+	key := string(m.Kind) + "/" + m.Metadata.Namespace + "/" + m.Metadata.Name + "@" + m.Metadata.Version
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.sems[key]; ok {
+		return s
+	}
+	s := &toolSems{perTool: map[string]chan struct{}{}}
+	if p := m.Spec.Constraints; p != nil && p.Performance != nil && p.Performance.MaxConcurrentRequests > 0 {
+		s.global = make(chan struct{}, p.Performance.MaxConcurrentRequests)
+	}
+	for _, t := range m.Spec.Tools {
+		if t.Handler != nil && t.Handler.MaxConcurrency > 0 {
+			s.perTool[t.Name] = make(chan struct{}, t.Handler.MaxConcurrency)
+		}
+	}
+	l.sems[key] = s
+	return s
+}
+
+// ExecuteToolCalls runs the calls of one model turn concurrently and
+// returns their results in request order. Concurrency is bounded overall by
+// spec.constraints.performance.maxConcurrentRequests and per tool by
+// handler.max_concurrency, across all calls under the ToolLimits of ctx, or
+// within this batch if ctx has none. Every call runs even if others fail;
+// when any fail, the returned error is a *ToolCallErrors and the successful
+// results are still returned.
+func (m *Manifest) ExecuteToolCalls(ctx context.Context, calls []ToolCall, exec ToolExecFunc) ([]ToolCallResult, error) {
+	results := make([]ToolCallResult, len(calls))
+
+	limits := ToolLimitsFrom(ctx)
+	if limits == nil {
+		limits = NewToolLimits()
+	}
+	sems := limits.of(m)
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			results[i] = ToolCallResult{ID: call.ID, Tool: call.Tool}
+
+			release, err := acquire(ctx, sems.perTool[call.Tool], sems.global)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			defer release()
+
+			start := time.Now()
+			results[i].Output, results[i].Err = exec(ctx, call)
+			attrs := []interface{}{"agent", m.Metadata.Name, "tool", call.Tool, "id", call.ID, "duration", time.Since(start)}
+			if results[i].Err != nil {
+				Logger().Warn("tool call failed", append(attrs, "error", results[i].Err)...)
+			} else {
+				Logger().Info("tool call", attrs...)
+			}
+		}(i, call)
+	}
+	wg.Wait()
+
+	var failed []ToolCallResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		return results, &ToolCallErrors{Failed: failed, Total: len(calls)}
+	}
+	return results, nil
+}
+
+// acquire takes a slot from each non-nil semaphore in order. Callers pass
+// the per-tool semaphore first, so calls queued behind a busy tool do not
+// hold global slots other tools could use.
+func acquire(ctx context.Context, sems ...chan struct{}) (func(), error) {
+	var held []chan struct{}
+	release := func() {
+		for _, s := range held {
+			<-s
+		}
+	}
+	for _, s := range sems {
+		if s == nil {
+			continue
+		}
+		select {
+		case s <- struct{}{}:
+			held = append(held, s)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
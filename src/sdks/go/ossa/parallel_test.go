@@ -0,0 +1,108 @@
+package ossa
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteToolCalls(t *testing.T) {
+	manifest := NewManifest("parallel-agent", KindAgent)
+	manifest.Spec.Tools = []ToolConfig{
+		{Type: "http", Name: "search", Handler: &ToolHandler{MaxConcurrency: 1}},
+		{Type: "http", Name: "lookup"},
+	}
+
+	var inSearch, maxSearch int32
+	exec := func(ctx context.Context, call ToolCall) ([]byte, error) {
+		if call.Tool == "search" {
+			n := atomic.AddInt32(&inSearch, 1)
+			defer atomic.AddInt32(&inSearch, -1)
+			if n > atomic.LoadInt32(&maxSearch) {
+				atomic.StoreInt32(&maxSearch, n)
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if call.ID == "3" {
+			return nil, errors.New("upstream 503")
+		}
+		return []byte("ok-" + call.ID), nil
+	}
+
+	calls := []ToolCall{
+		{ID: "1", Tool: "search"},
+		{ID: "2", Tool: "search"},
+		{ID: "3", Tool: "lookup"},
+		{ID: "4", Tool: "lookup"},
+	}
+	results, err := manifest.ExecuteToolCalls(context.Background(), calls, exec)
+
+	var callErrs *ToolCallErrors
+	if !errors.As(err, &callErrs) || len(callErrs.Failed) != 1 || callErrs.Failed[0].ID != "3" {
+		t.Fatalf("Expected one partial failure for call 3, got %v", err)
+	}
+	for i, r := range results {
+		if r.ID != calls[i].ID {
+			t.Errorf("Result %d out of order: got %s", i, r.ID)
+		}
+	}
+	if string(results[3].Output) != "ok-4" {
+		t.Errorf("Expected successful results alongside failures, got %q", results[3].Output)
+	}
+	if maxSearch > 1 {
+		t.Errorf("Expected search max_concurrency 1 to be honored, saw %d", maxSearch)
+	}
+}
+
+func TestToolLimitsAcrossBatches(t *testing.T) {
+	manifest := NewManifest("parallel-agent", KindAgent)
+	manifest.Spec.Tools = []ToolConfig{
+		{Type: "http", Name: "search", Handler: &ToolHandler{MaxConcurrency: 1}},
+		{Type: "http", Name: "lookup"},
+	}
+	manifest.Spec.Constraints = &Constraints{Performance: &PerformanceConstraints{MaxConcurrentRequests: 2}}
+
+	var inSearch, maxSearch, lookups int32
+	exec := func(ctx context.Context, call ToolCall) ([]byte, error) {
+		if call.Tool == "lookup" {
+			atomic.AddInt32(&lookups, 1)
+			return nil, nil
+		}
+		n := atomic.AddInt32(&inSearch, 1)
+		defer atomic.AddInt32(&inSearch, -1)
+		if n > atomic.LoadInt32(&maxSearch) {
+			atomic.StoreInt32(&maxSearch, n)
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil, nil
+	}
+
+	// Two steps of a run, each a batch of searches, share the limits.
+	ctx := WithToolLimits(context.Background(), NewToolLimits())
+	calls := []ToolCall{{ID: "1", Tool: "search"}, {ID: "2", Tool: "search"}, {ID: "3", Tool: "search"}}
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			manifest.ExecuteToolCalls(ctx, calls, exec)
+			done <- struct{}{}
+		}()
+	}
+
+	// Searches waiting for their tool hold no global slot, so a lookup
+	// gets one while they queue.
+	time.Sleep(5 * time.Millisecond)
+	start := time.Now()
+	if _, err := manifest.ExecuteToolCalls(ctx, []ToolCall{{ID: "4", Tool: "lookup"}}, exec); err != nil || lookups != 1 {
+		t.Fatalf("Expected the lookup to run, got %v", err)
+	}
+	if waited := time.Since(start); waited > 30*time.Millisecond {
+		t.Errorf("Expected the lookup not to wait behind queued searches, waited %v", waited)
+	}
+	<-done
+	<-done
+	if maxSearch != 1 {
+		t.Errorf("Expected search max_concurrency 1 to hold across batches, saw %d", maxSearch)
+	}
+}
@@ -0,0 +1,314 @@
+package ossa
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is one RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch document to the manifest.
+// Paths address the manifest's JSON form, e.g. /spec/tools/0/endpoint or
+// /metadata/labels/team. Operations apply in order and the manifest is only
+// updated if all of them succeed, including any test operations. As with
+// ParseManifest, fields the Manifest type does not model are dropped.
+func (m *Manifest) ApplyJSONPatch(patch []byte) error {
+	var ops []PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("failed to parse JSON patch: %w", err)
+	}
+	// Value is omitempty for marshaling, so see which ops actually had one.
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &raw); err != nil {
+		return fmt.Errorf("failed to parse JSON patch: %w", err)
+	}
+
+	doc, err := manifestDoc(m)
+	if err != nil {
+		return err
+	}
+	for i, op := range ops {
+		_, hasValue := raw[i]["value"]
+		if doc, err = applyPatchOp(doc, op, hasValue); err != nil {
+			return WrapError(fmt.Sprintf("json patch operation %d (%s %s) failed", i, op.Op, op.Path), err)
+		}
+	}
+	return m.setDoc(doc)
+}
+
+func applyPatchOp(doc interface{}, op PatchOperation, hasValue bool) (interface{}, error) {
+	path, err := pointerTokens(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, NewError("missing value")
+		}
+	case "move", "copy":
+		if _, err := pointerTokens(op.From); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, op.Value)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return op.Value, nil
+		}
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		doc, _, err := pointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, op.Value)
+	case "move":
+		from, _ := pointerTokens(op.From)
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, NewError("cannot move a value into one of its children")
+		}
+		doc, value, err := pointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, value)
+	case "copy":
+		from, _ := pointerTokens(op.From)
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopyJSON(value))
+	case "test":
+		value, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, normalizeJSON(op.Value)) {
+			return nil, NewError("test failed: value differs")
+		}
+		return doc, nil
+	}
+	return nil, NewError(fmt.Sprintf("unsupported operation %q", op.Op))
+}
+
+// pointerTokens splits an RFC 6901 JSON Pointer into unescaped tokens.
+func pointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, NewError(fmt.Sprintf("invalid JSON pointer %q", pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, tok := range path {
+		switch c := doc.(type) {
+		case map[string]interface{}:
+			v, ok := c[tok]
+			if !ok {
+				return nil, NewError(fmt.Sprintf("path not found: %s", tok))
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(tok, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, NewError(fmt.Sprintf("path not found: %s", tok))
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd returns doc with value added at path; maps are updated in
+// place, arrays are rebuilt.
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return normalizeJSON(value), nil
+	}
+	tok, rest := path[0], path[1:]
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			c[tok] = normalizeJSON(value)
+			return c, nil
+		}
+		child, ok := c[tok]
+		if !ok {
+			return nil, NewError(fmt.Sprintf("path not found: %s", tok))
+		}
+		updated, err := pointerAdd(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		c[tok] = updated
+		return c, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			i := len(c)
+			if tok != "-" {
+				var err error
+				if i, err = arrayIndex(tok, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			out := make([]interface{}, 0, len(c)+1)
+			out = append(out, c[:i]...)
+			out = append(out, normalizeJSON(value))
+			return append(out, c[i:]...), nil
+		}
+		i, err := arrayIndex(tok, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := pointerAdd(c[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = updated
+		return c, nil
+	}
+	return nil, NewError(fmt.Sprintf("path not found: %s", tok))
+}
+
+// pointerRemove returns doc without the value at path, and that value.
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, NewError("cannot remove the document root")
+	}
+	tok, rest := path[0], path[1:]
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		child, ok := c[tok]
+		if !ok {
+			return nil, nil, NewError(fmt.Sprintf("path not found: %s", tok))
+		}
+		if len(rest) == 0 {
+			delete(c, tok)
+			return c, child, nil
+		}
+		updated, removed, err := pointerRemove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[tok] = updated
+		return c, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(tok, len(c)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := c[i]
+			return append(append([]interface{}{}, c[:i]...), c[i+1:]...), removed, nil
+		}
+		updated, removed, err := pointerRemove(c[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[i] = updated
+		return c, removed, nil
+	}
+	return nil, nil, NewError(fmt.Sprintf("path not found: %s", tok))
+}
+
+// arrayIndex parses an array token, which must be between 0 and max.
+func arrayIndex(tok string, max int) (int, error) {
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (len(tok) > 1 && tok[0] == '0') {
+		return 0, NewError(fmt.Sprintf("invalid array index %q", tok))
+	}
+	if i > max {
+		return 0, NewError(fmt.Sprintf("array index %d out of range", i))
+	}
+	return i, nil
+}
+
+// manifestDoc returns the manifest's JSON form as generic values.
+func manifestDoc(m *Manifest) (interface{}, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return doc, nil
+}
+
+// setDoc replaces the manifest with doc decoded into a Manifest.
+func (m *Manifest) setDoc(doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	var updated Manifest
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	*m = updated
+	return nil
+}
+
+// normalizeJSON converts a caller-supplied value to the generic types
+// encoding/json decodes into, so comparisons and copies behave.
+func normalizeJSON(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, float64, string:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(c))
+		for k, child := range c {
+			out[k] = deepCopyJSON(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(c))
+		for i, child := range c {
+			out[i] = deepCopyJSON(child)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+package ossa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyBundle is an organization's policies in one file, for
+// distributing them together:
+//
+//	models:
+//	  allowed_providers: [openai, azure]
+//	endpoints:
+//	  allowed_domains: ["*.example.com"]
+//
+// Use it with WithPolicyBundle, or register its Rules.
+type PolicyBundle struct {
+	Models    *ModelPolicy    `json:"models,omitempty" yaml:"models,omitempty"`
+	Endpoints *EndpointPolicy `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+}
+
+// LoadPolicyBundle reads a policy bundle from a YAML or JSON file. Unknown
+// fields are errors, as in LoadModelPolicy.
+func LoadPolicyBundle(file string) (*PolicyBundle, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy bundle: %w", err)
+	}
+	data, err = NormalizeEncoding(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode policy bundle: %w", err)
+	}
+	b := &PolicyBundle{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(b); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy bundle %s: %w", file, err)
+	}
+	if b.Models != nil {
+		if err := b.Models.check(); err != nil {
+			return nil, fmt.Errorf("%s: models: %w", file, err)
+		}
+	}
+	if b.Endpoints != nil {
+		if err := b.Endpoints.check(); err != nil {
+			return nil, fmt.Errorf("%s: endpoints: %w", file, err)
+		}
+	}
+	return b, nil
+}
+
+// Rules returns the rules enforcing the bundle's policies.
+func (b *PolicyBundle) Rules() []Rule {
+	var rules []Rule
+	if b.Models != nil {
+		rules = append(rules, b.Models.Rule())
+	}
+	if b.Endpoints != nil {
+		rules = append(rules, b.Endpoints.Rule())
+	}
+	return rules
+}
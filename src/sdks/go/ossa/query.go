@@ -0,0 +1,711 @@
+package ossa
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled manifest query: a boolean expression in a small
+// CEL-like language evaluated against a manifest's JSON form.
+//
+//	spec.llm.provider == "anthropic" && metadata.labels.team == "ops"
+//	kind in ["Agent", "Workflow"] && !has(spec.safety)
+//	spec.tools.exists(t, t.type == "mcp" && t.endpoint.startsWith("http:"))
+//	size(spec.tools) > 5 || metadata.labels["app.kubernetes.io/part-of"] == "billing"
+//
+// Fields are reached with dots or ["key"] and list elements with [i];
+// missing fields are null. The operators are == != < <= > >= in && || ! and
+// unary -, comparing numbers with numbers and strings with strings; any
+// ordering with null is false. Functions are size(x) and has(x), and
+// strings have contains, startsWith, endsWith and matches (a regular
+// expression). Lists have contains, and the exists(v, pred) and
+// all(v, pred) macros, which bind v to each element.
+type Query struct {
+	src  string
+	expr queryNode
+}
+
+// CompileQuery parses a query expression.
+func CompileQuery(expr string) (*Query, error) {
+	p := &queryParser{src: expr}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Query{src: expr, expr: node}, nil
+}
+
+// String returns the query's source.
+func (q *Query) String() string { return q.src }
+
+// Eval evaluates the query against the manifest and returns its value.
+func (q *Query) Eval(m *Manifest) (interface{}, error) {
+	doc, err := manifestDoc(m)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := doc.(map[string]interface{})
+	return q.expr.eval(&queryEnv{root: root})
+}
+
+// Match reports whether the query holds for the manifest. A query that
+// does not evaluate to a boolean is an error.
+func (q *Query) Match(m *Manifest) (bool, error) {
+	v, err := q.Eval(m)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	}
+	return false, NewError(fmt.Sprintf("query %q is a %s, not a boolean", q.src, queryType(v)))
+}
+
+type queryEnv struct {
+	root map[string]interface{}
+	vars map[string]interface{}
+}
+
+func (e *queryEnv) with(name string, v interface{}) *queryEnv {
+	vars := map[string]interface{}{name: v}
+	for k, v := range e.vars {
+		if k != name {
+			vars[k] = v
+		}
+	}
+	return &queryEnv{root: e.root, vars: vars}
+}
+
+type queryNode interface {
+	eval(env *queryEnv) (interface{}, error)
+}
+
+type (
+	literalNode struct{ value interface{} }
+	identNode   struct{ name string }
+	listNode    struct{ items []queryNode }
+	fieldNode   struct {
+		target queryNode
+		name   string
+	}
+	indexNode struct{ target, index queryNode }
+	unaryNode struct {
+		op      string
+		operand queryNode
+	}
+	binaryNode struct {
+		op          string
+		left, right queryNode
+	}
+	callNode struct {
+		name   string
+		target queryNode // nil for size and has
+		args   []queryNode
+	}
+	macroNode struct {
+		name   string // exists or all
+		target queryNode
+		v      string
+		pred   queryNode
+	}
+)
+
+func (n literalNode) eval(*queryEnv) (interface{}, error) { return n.value, nil }
+
+func (n identNode) eval(env *queryEnv) (interface{}, error) {
+	if v, ok := env.vars[n.name]; ok {
+		return v, nil
+	}
+	return env.root[n.name], nil
+}
+
+func (n listNode) eval(env *queryEnv) (interface{}, error) {
+	out := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (n fieldNode) eval(env *queryEnv) (interface{}, error) {
+	v, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	obj, _ := v.(map[string]interface{})
+	return obj[n.name], nil
+}
+
+func (n indexNode) eval(env *queryEnv) (interface{}, error) {
+	v, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		key, ok := idx.(string)
+		if !ok {
+			return nil, NewError(fmt.Sprintf("cannot index an object with a %s", queryType(idx)))
+		}
+		return v[key], nil
+	case []interface{}:
+		f, ok := idx.(float64)
+		if !ok || f != float64(int(f)) {
+			return nil, NewError(fmt.Sprintf("cannot index a list with %v", idx))
+		}
+		if i := int(f); i >= 0 && i < len(v) {
+			return v[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (n unaryNode) eval(env *queryEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "-" {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, NewError(fmt.Sprintf("cannot negate a %s", queryType(v)))
+		}
+		return -f, nil
+	}
+	b, err := queryBool(v, "!")
+	return !b, err
+}
+
+func (n binaryNode) eval(env *queryEnv) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		l, err := queryBool(left, n.op)
+		if err != nil || l == (n.op == "||") {
+			return l, err
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return queryBool(right, n.op)
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return queryEqual(left, right), nil
+	case "!=":
+		return !queryEqual(left, right), nil
+	case "in":
+		return queryContains(right, left), nil
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, NewError(fmt.Sprintf("cannot compare number %s %s", n.op, queryType(right)))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, NewError(fmt.Sprintf("cannot compare string %s %s", n.op, queryType(right)))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, NewError(fmt.Sprintf("cannot order a %s", queryType(left)))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+func (n callNode) eval(env *queryEnv) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if n.target == nil {
+		switch n.name {
+		case "has":
+			return args[0] != nil, nil
+		default: // size
+			switch v := args[0].(type) {
+			case nil:
+				return float64(0), nil
+			case string:
+				return float64(len([]rune(v))), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			}
+			return nil, NewError(fmt.Sprintf("size of a %s", queryType(args[0])))
+		}
+	}
+
+	recv, err := n.target.eval(env)
+	if err != nil || recv == nil {
+		return false, err
+	}
+	if list, ok := recv.([]interface{}); ok && n.name == "contains" {
+		return queryContains(list, args[0]), nil
+	}
+	s, ok1 := recv.(string)
+	arg, ok2 := args[0].(string)
+	if !ok1 || !ok2 {
+		return nil, NewError(fmt.Sprintf("%s needs strings, got %s and %s", n.name, queryType(recv), queryType(args[0])))
+	}
+	switch n.name {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	}
+	re, err := regexp.Compile(arg)
+	if err != nil {
+		return nil, WrapError("invalid pattern in matches", err)
+	}
+	return re.MatchString(s), nil
+}
+
+func (n macroNode) eval(env *queryEnv) (interface{}, error) {
+	v, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		for k := range v {
+			items = append(items, k)
+		}
+	default:
+		return nil, NewError(fmt.Sprintf("%s over a %s", n.name, queryType(v)))
+	}
+	want := n.name == "exists"
+	for _, item := range items {
+		r, err := n.pred.eval(env.with(n.v, item))
+		if err != nil {
+			return nil, err
+		}
+		b, err := queryBool(r, n.name)
+		if err != nil {
+			return nil, err
+		}
+		if b == want {
+			return want, nil
+		}
+	}
+	return !want, nil
+}
+
+// queryBool reads an operand of a logical operator; null is false.
+func queryBool(v interface{}, op string) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	}
+	return false, NewError(fmt.Sprintf("%s needs booleans, got a %s", op, queryType(v)))
+}
+
+func queryEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// queryContains implements in: list membership, object keys and substrings.
+func queryContains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, v := range c {
+			if queryEqual(v, item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if key, ok := item.(string); ok {
+			_, found := c[key]
+			return found
+		}
+	case string:
+		if s, ok := item.(string); ok {
+			return strings.Contains(c, s)
+		}
+	}
+	return false
+}
+
+func queryType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	}
+	return "object"
+}
+
+// Parsing.
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type queryToken struct {
+	kind tokKind
+	text string
+	pos  int
+	num  float64
+}
+
+type queryParser struct {
+	src  string
+	toks []queryToken
+	i    int
+}
+
+var queryOps = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "-", "(", ")", "[", "]", ".", ","}
+
+func (p *queryParser) lex() error {
+	src := p.src
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && rune(src[j]) != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return NewError(fmt.Sprintf("query: position %d: unterminated string", i+1))
+			}
+			raw := src[i : j+1]
+			if c == '\'' {
+				raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+			}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				return NewError(fmt.Sprintf("query: position %d: invalid string %s", i+1, src[i:j+1]))
+			}
+			p.toks = append(p.toks, queryToken{kind: tokString, text: s, pos: i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return NewError(fmt.Sprintf("query: position %d: invalid number %s", i+1, src[i:j]))
+			}
+			p.toks = append(p.toks, queryToken{kind: tokNumber, text: src[i:j], pos: i, num: f})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, queryToken{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+		default:
+			matched := ""
+			for _, op := range queryOps {
+				if strings.HasPrefix(src[i:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return NewError(fmt.Sprintf("query: position %d: unexpected %q", i+1, c))
+			}
+			p.toks = append(p.toks, queryToken{kind: tokOp, text: matched, pos: i})
+			i += len(matched)
+		}
+	}
+	p.toks = append(p.toks, queryToken{kind: tokEOF, pos: len(src)})
+	return nil
+}
+
+func (p *queryParser) peek() queryToken { return p.toks[p.i] }
+
+func (p *queryParser) next() queryToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword text.
+func (p *queryParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == tokOp || t.kind == tokIdent) && t.text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		if t.kind == tokEOF {
+			return p.errorf(t, "expected %q at end of query", text)
+		}
+		return p.errorf(t, "expected %q, found %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *queryParser) errorf(t queryToken, format string, args ...interface{}) error {
+	return NewError(fmt.Sprintf("query: position %d: %s", t.pos+1, fmt.Sprintf(format, args...)))
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right queryNode
+		if right, err = p.parseAnd(); err == nil {
+			left = binaryNode{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseCompare()
+	for err == nil && p.accept("&&") {
+		var right queryNode
+		if right, err = p.parseCompare(); err == nil {
+			left = binaryNode{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *queryParser) parseCompare() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return unaryNode{op: op, operand: operand}, nil
+		}
+	}
+	return p.parsePostfix()
+}
+
+func (p *queryParser) parsePostfix() (queryNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, p.errorf(t, "expected a field name after '.'")
+			}
+			if !p.accept("(") {
+				node = fieldNode{target: node, name: t.text}
+				continue
+			}
+			if node, err = p.parseMethod(node, t); err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = indexNode{target: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// parseMethod parses the arguments of target.name( after the parenthesis.
+func (p *queryParser) parseMethod(target queryNode, name queryToken) (queryNode, error) {
+	switch name.text {
+	case "exists", "all":
+		v := p.next()
+		if v.kind != tokIdent {
+			return nil, p.errorf(v, "%s needs a variable name first", name.text)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return macroNode{name: name.text, target: target, v: v.text, pred: pred}, p.expect(")")
+	case "contains", "startsWith", "endsWith", "matches":
+		args, err := p.parseArgs(name, 1)
+		return callNode{name: name.text, target: target, args: args}, err
+	}
+	return nil, p.errorf(name, "unknown method %s", name.text)
+}
+
+// parseArgs parses want comma-separated arguments and the closing
+// parenthesis.
+func (p *queryParser) parseArgs(name queryToken, want int) ([]queryNode, error) {
+	var args []queryNode
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) != want {
+		return nil, p.errorf(name, "%s takes %d argument(s), got %d", name.text, want, len(args))
+	}
+	return args, nil
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return literalNode{t.num}, nil
+	case tokString:
+		return literalNode{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return literalNode{t.text == "true"}, nil
+		case "null":
+			return literalNode{nil}, nil
+		case "size", "has":
+			if p.accept("(") {
+				args, err := p.parseArgs(t, 1)
+				return callNode{name: t.text, args: args}, err
+			}
+		}
+		return identNode{t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			var items []queryNode
+			for !p.accept("]") {
+				if len(items) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return listNode{items}, nil
+		}
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of query")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
@@ -0,0 +1,292 @@
+package ossa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backoff strategies for RetryConfig.BackoffStrategy.
+const (
+	BackoffNone        = "none"
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+)
+
+// Limits used to flag unreasonable handler settings.
+const (
+	MinHandlerTimeout  = 100 * time.Millisecond
+	MaxHandlerTimeout  = 10 * time.Minute
+	MaxRetryAttempts   = 10
+	maxReasonableTotal = 15 * time.Minute
+)
+
+// ErrCircuitOpen is returned when a call is rejected by an open circuit breaker.
+var ErrCircuitOpen = NewError("circuit breaker open")
+
+// CallPolicy enforces a tool handler's timeout, retry and circuit-breaker
+// settings around calls. A CallPolicy is safe for concurrent use and should
+// be shared by all calls to the same tool so the breaker sees every failure.
+type CallPolicy struct {
+	timeout time.Duration
+	retries RetryConfig
+	breaker *CircuitBreaker
+	sleep   func(context.Context, time.Duration) error
+}
+
+// NewCallPolicy creates a call policy for a tool handler. A nil handler
+// yields a policy that calls through once with no timeout.
+func NewCallPolicy(h *ToolHandler) *CallPolicy {
+	if h == nil {
+		return NewRetryPolicy(0, nil)
+	}
+	p := NewRetryPolicy(h.Timeout.Std(), h.Retries)
+	if h.CircuitBreaker != nil {
+		p.breaker = NewCircuitBreaker(h.CircuitBreaker)
+	}
+	return p
+}
+
+// NewRetryPolicy creates a call policy with only a per-attempt timeout,
+// none if 0, and retries, none if nil; spec.llm.retry_config uses it.
+func NewRetryPolicy(timeout time.Duration, retries *RetryConfig) *CallPolicy {
+	p := &CallPolicy{timeout: timeout, retries: RetryConfig{MaxAttempts: 1}, sleep: sleepContext}
+	if retries != nil {
+		p.retries = *retries
+		if p.retries.MaxAttempts < 1 {
+			p.retries.MaxAttempts = 1
+		}
+	}
+	return p
+}
+
+// Do calls fn, applying the per-attempt timeout, retrying failed attempts
+// with backoff and short-circuiting while the breaker is open. Errors
+// wrapped with Permanent are not retried.
+func (p *CallPolicy) Do(ctx context.Context, fn func(context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= p.retries.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			if serr := p.sleep(ctx, p.retries.delay(attempt-1)); serr != nil {
+				return serr
+			}
+		}
+
+		if p.breaker != nil && !p.breaker.Allow() {
+			return ErrCircuitOpen
+		}
+
+		err = p.attempt(ctx, fn)
+		if p.breaker != nil {
+			p.breaker.Record(err == nil)
+		}
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return WrapError(fmt.Sprintf("call failed after %d attempts", p.retries.MaxAttempts), err)
+}
+
+func (p *CallPolicy) attempt(ctx context.Context, fn func(context.Context) error) error {
+	if p.timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not retryable.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func (r RetryConfig) delay(retry int) time.Duration {
+	initial := time.Duration(r.InitialDelayMs) * time.Millisecond
+	var d time.Duration
+	switch r.BackoffStrategy {
+	case BackoffNone:
+		d = initial
+	case BackoffLinear:
+		d = initial * time.Duration(retry)
+	default:
+		d = initial << (retry - 1)
+	}
+	if max := time.Duration(r.MaxDelayMs) * time.Millisecond; max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Circuit breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitBreaker opens after consecutive failures, rejects calls until the
+// reset timeout passes, then lets a limited number of trial calls through.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	cfg      CircuitBreakerConfig
+	state    string
+	failures int
+	openedAt time.Time
+	halfOpen int
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(cfg *CircuitBreakerConfig) *CircuitBreaker {
+	c := *cfg
+	if c.FailureThreshold < 1 {
+		c.FailureThreshold = 5
+	}
+	if c.ResetTimeout <= 0 {
+		c.ResetTimeout = Duration(30 * time.Second)
+	}
+	if c.HalfOpenMaxCalls < 1 {
+		c.HalfOpenMaxCalls = 1
+	}
+	return &CircuitBreaker{cfg: c, state: CircuitClosed, now: time.Now}
+}
+
+// State returns the current breaker state.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow reports whether a call may proceed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.halfOpen >= b.cfg.HalfOpenMaxCalls {
+			return false
+		}
+		b.halfOpen++
+	}
+	return true
+}
+
+// Record reports the outcome of an allowed call.
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		b.halfOpen = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+		b.halfOpen = 0
+	}
+}
+
+func (b *CircuitBreaker) advance() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.cfg.ResetTimeout.Std() {
+		b.state = CircuitHalfOpen
+		b.halfOpen = 0
+	}
+}
+
+func validateToolHandler(path string, h *ToolHandler, result *ValidationResult) {
+	if h == nil {
+		return
+	}
+
+	if h.Timeout < 0 {
+		result.addError(fmt.Sprintf("%s.timeout: must be positive", path))
+	} else if h.Timeout > 0 && h.Timeout.Std() < MinHandlerTimeout {
+		result.addWarning(fmt.Sprintf("%s.timeout: %s is shorter than %s", path, h.Timeout, MinHandlerTimeout))
+	} else if h.Timeout.Std() > MaxHandlerTimeout {
+		result.addWarning(fmt.Sprintf("%s.timeout: %s exceeds %s", path, h.Timeout, MaxHandlerTimeout))
+	}
+
+	if r := h.Retries; r != nil {
+		switch {
+		case r.MaxAttempts < 0:
+			result.addError(fmt.Sprintf("%s.retries.max_attempts: must be at least 1", path))
+		case r.MaxAttempts > MaxRetryAttempts:
+			result.addError(fmt.Sprintf("%s.retries.max_attempts: %d exceeds %d", path, r.MaxAttempts, MaxRetryAttempts))
+		}
+		switch r.BackoffStrategy {
+		case "", BackoffNone, BackoffLinear, BackoffExponential:
+		default:
+			result.addError(fmt.Sprintf("%s.retries.backoff_strategy: invalid strategy: %s", path, r.BackoffStrategy))
+		}
+		if r.InitialDelayMs < 0 || r.MaxDelayMs < 0 {
+			result.addError(fmt.Sprintf("%s.retries: delays must not be negative", path))
+		} else if r.MaxDelayMs > 0 && r.MaxDelayMs < r.InitialDelayMs {
+			result.addError(fmt.Sprintf("%s.retries.max_delay_ms: must not be less than initial_delay_ms", path))
+		}
+
+		if h.Timeout > 0 && r.MaxAttempts > 1 {
+			total := h.Timeout.Std() * time.Duration(r.MaxAttempts)
+			for i := 1; i < r.MaxAttempts; i++ {
+				total += r.delay(i)
+			}
+			if total > maxReasonableTotal {
+				result.addWarning(fmt.Sprintf("%s: worst-case call time %s with retries exceeds %s", path, total, maxReasonableTotal))
+			}
+		}
+	}
+
+	validateCache(path+".cache", h.Cache, result)
+	if h.MaxConcurrency < 0 {
+		result.addError(fmt.Sprintf("%s.max_concurrency: must not be negative", path))
+	}
+
+	if cb := h.CircuitBreaker; cb != nil {
+		if cb.FailureThreshold < 0 {
+			result.addError(fmt.Sprintf("%s.circuit_breaker.failure_threshold: must be at least 1", path))
+		}
+		if cb.ResetTimeout < 0 {
+			result.addError(fmt.Sprintf("%s.circuit_breaker.reset_timeout: must be positive", path))
+		}
+		if cb.HalfOpenMaxCalls < 0 {
+			result.addError(fmt.Sprintf("%s.circuit_breaker.half_open_max_calls: must be at least 1", path))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ossa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallPolicyRetries(t *testing.T) {
+	p := NewCallPolicy(&ToolHandler{
+		Timeout: Duration(50 * time.Millisecond),
+		Retries: &RetryConfig{MaxAttempts: 3, InitialDelayMs: 10},
+	})
+	var delays []time.Duration
+	p.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected attempt context to carry the handler timeout")
+		}
+		if calls < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success on third attempt, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(delays) != 2 || delays[0] != 10*time.Millisecond || delays[1] != 20*time.Millisecond {
+		t.Errorf("Unexpected exponential backoff delays: %v", delays)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(errors.New("bad request"))
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected permanent error without retry, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 2, ResetTimeout: Duration(time.Minute)})
+	b.now = func() time.Time { return now }
+
+	b.Record(false)
+	if b.State() != CircuitClosed {
+		t.Fatal("Expected breaker to stay closed below threshold")
+	}
+	b.Record(false)
+	if b.Allow() {
+		t.Fatal("Expected open breaker to reject calls")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatal("Expected half-open breaker to allow a trial call")
+	}
+	if b.Allow() {
+		t.Error("Expected half-open breaker to limit trial calls")
+	}
+	b.Record(true)
+	if b.State() != CircuitClosed {
+		t.Errorf("Expected breaker to close after a successful trial, got %s", b.State())
+	}
+
+	p := NewCallPolicy(&ToolHandler{CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 1}})
+	p.Do(context.Background(), func(context.Context) error { return errors.New("down") })
+	if err := p.Do(context.Background(), func(context.Context) error { return nil }); err != ErrCircuitOpen {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestToolHandlerValidation(t *testing.T) {
+	yamlContent := `
+apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: handler-agent
+spec:
+  role: "You call tools."
+  tools:
+    - type: http
+      name: search
+      handler:
+        timeout: 30s
+        retries:
+          max_attempts: 3
+          backoff_strategy: exponential
+          initial_delay_ms: 500
+        circuit_breaker:
+          failure_threshold: 5
+          reset_timeout: 1m
+    - type: http
+      name: slow
+      handler:
+        timeout: 2h
+        retries:
+          max_attempts: 50
+          backoff_strategy: random
+`
+	manifest, err := ParseManifest([]byte(yamlContent), ".yaml")
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if got := manifest.Spec.Tools[0].Handler.Timeout.Std(); got != 30*time.Second {
+		t.Errorf("Expected 30s timeout, got %s", got)
+	}
+
+	result := ValidateManifest(manifest)
+	if len(result.Errors) != 2 {
+		t.Errorf("Expected 2 handler errors, got %v", result.Errors)
+	}
+	found := false
+	for _, w := range result.Warnings {
+		if w == "spec.tools[1].handler.timeout: 2h0m0s exceeds 10m0s" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected timeout warning, got %v", result.Warnings)
+	}
+}
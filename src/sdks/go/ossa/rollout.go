@@ -0,0 +1,85 @@
+package ossa
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// FlagSource reports whether a named feature flag is on.
+type FlagSource interface {
+	FlagEnabled(name string) bool
+}
+
+// FlagSet is a static FlagSource.
+type FlagSet map[string]bool
+
+// FlagEnabled implements FlagSource.
+func (f FlagSet) FlagEnabled(name string) bool {
+	return f[name]
+}
+
+// EnabledFor reports whether the tool is visible to the model in a session.
+// A tool is hidden when enabled is false, when its flag is off in flags (a
+// nil source turns every flag off), or when the session falls outside
+// rollout_percent. Bucketing is stable per session and tool name.
+func (t ToolConfig) EnabledFor(session string, flags FlagSource) bool {
+	if t.Enabled != nil && !*t.Enabled {
+		return false
+	}
+	if t.Flag != "" && (flags == nil || !flags.FlagEnabled(t.Flag)) {
+		return false
+	}
+	if t.RolloutPercent != nil {
+		return rolloutBucket(session, t.Name) < *t.RolloutPercent
+	}
+	return true
+}
+
+// IsGated reports whether the tool's visibility depends on the session.
+func (t ToolConfig) IsGated() bool {
+	return (t.Enabled != nil && !*t.Enabled) || t.Flag != "" || t.RolloutPercent != nil
+}
+
+// RolloutStatus describes the tool's gating for display, e.g. "25% rollout,
+// flag new-search".
+func (t ToolConfig) RolloutStatus() string {
+	if t.Enabled != nil && !*t.Enabled {
+		return "disabled"
+	}
+	status := "enabled"
+	if t.RolloutPercent != nil {
+		status = fmt.Sprintf("%d%% rollout", *t.RolloutPercent)
+	}
+	if t.Flag != "" {
+		status += ", flag " + t.Flag
+	}
+	return status
+}
+
+// ToolsFor returns the tools visible to the model in a session.
+func (m *Manifest) ToolsFor(session string, flags FlagSource) []ToolConfig {
+	var tools []ToolConfig
+	for _, t := range m.Spec.Tools {
+		if t.EnabledFor(session, flags) {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+func rolloutBucket(session, tool string) int {
+	h := fnv.New32a()
+	h.Write([]byte(tool))
+	h.Write([]byte{0})
+	h.Write([]byte(session))
+	return int(h.Sum32() % 100)
+}
+
+func validateRollout(path string, t ToolConfig, result *ValidationResult) {
+	if t.RolloutPercent != nil && (*t.RolloutPercent < 0 || *t.RolloutPercent > 100) {
+		result.addError(fmt.Sprintf("%s.rollout_percent: must be between 0 and 100", path))
+	}
+	if t.RolloutPercent != nil && t.Name == "" {
+		result.addWarning(fmt.Sprintf("%s: rollout_percent without a name buckets every unnamed tool together", path))
+	}
+}
@@ -0,0 +1,82 @@
+package ossa
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Rule is a custom validation check, such as a company naming convention
+// or an allow-list of endpoints, run by every Validator after the structural
+// checks. Findings are reported through the ValidationResult like any
+// other: errors read "<path>: <message> (rule <name>)" and warnings carry
+// the WarningRule prefix.
+type Rule struct {
+	// Name identifies the rule in messages, e.g. acme/naming.
+	Name string
+	// Check inspects the manifest and reports findings to r.
+	Check func(m *Manifest, r *RuleReport)
+}
+
+// RuleReport collects the findings of one rule.
+type RuleReport struct {
+	rule   string
+	result *ValidationResult
+}
+
+// Error reports a finding that makes the manifest invalid. path is the
+// dotted field path, or empty for the whole manifest.
+func (r *RuleReport) Error(path, msg string) {
+	r.result.addError(r.format(path, msg))
+}
+
+// Warn reports a non-fatal finding.
+func (r *RuleReport) Warn(path, msg string) {
+	r.result.addWarning(WarningRule + ": " + r.format(path, msg))
+}
+
+func (r *RuleReport) format(path, msg string) string {
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	return fmt.Sprintf("%s (rule %s)", msg, r.rule)
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   []Rule
+)
+
+// RegisterRule adds r to the rules every Validator runs. r.Check is
+// required.
+func RegisterRule(r Rule) {
+	if r.Check == nil {
+		panic("ossa: RegisterRule with nil Check")
+	}
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules = append(rules, r)
+}
+
+// Rules returns the registered rules.
+func Rules() []Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return append([]Rule(nil), rules...)
+}
+
+func validateRules(m *Manifest, result *ValidationResult) {
+	for _, rule := range Rules() {
+		runRule(rule, m, &RuleReport{rule: rule.Name, result: result})
+	}
+}
+
+// runRule runs one rule, reporting a panic as an error so one broken rule
+// does not take down the caller.
+func runRule(rule Rule, m *Manifest, report *RuleReport) {
+	defer func() {
+		if p := recover(); p != nil {
+			report.Error("", fmt.Sprintf("panicked: %v", p))
+		}
+	}()
+	rule.Check(m, report)
+}
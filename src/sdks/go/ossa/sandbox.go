@@ -0,0 +1,201 @@
+package ossa
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Tool handler runtimes. An empty runtime means the tool is called over
+// its endpoint.
+const (
+	RuntimeWASM   = "wasm"
+	RuntimeScript = "script"
+	RuntimeSQL    = "sql"
+	RuntimeFS     = "fs"
+	RuntimeExec   = "exec"
+	RuntimeDrupal = "drupal"
+	RuntimeSMTP   = "smtp"
+	RuntimeSlack  = "slack"
+	RuntimeQueue  = "queue"
+	RuntimeHTTP   = "http"
+)
+
+// SandboxPolicy is a tool's sandbox after the agent's access tier has been
+// applied to what the handler declares.
+type SandboxPolicy struct {
+	Tier          AccessTier
+	Mounts        []SandboxMount
+	Env           map[string]string
+	MemoryLimitMB int
+	AllowedHosts  []string
+}
+
+// SandboxPolicy computes the effective sandbox for a tool. Agents whose
+// tier cannot write (read, policy, or undeclared) only get read-only mounts;
+// requesting a writable mount at those tiers is an error.
+func (m *Manifest) SandboxPolicy(tool ToolConfig) (*SandboxPolicy, error) {
+	policy := &SandboxPolicy{Tier: m.EffectiveAccessTier()}
+	if tool.Handler == nil || tool.Handler.Sandbox == nil {
+		return policy, nil
+	}
+	sb := tool.Handler.Sandbox
+
+	for _, mount := range sb.Mounts {
+		if !mount.ReadOnly && !policy.Tier.CanWrite() {
+			return nil, NewError(fmt.Sprintf("tool %s: writable mount %s is not allowed for %s agents", tool.Name, mount.Guest, policy.Tier))
+		}
+		policy.Mounts = append(policy.Mounts, mount)
+	}
+	policy.Env = sb.Env
+	policy.MemoryLimitMB = sb.MemoryLimitMB
+	policy.AllowedHosts = sb.AllowedHosts
+	return policy, nil
+}
+
+// AllowsHost reports whether outbound requests to host are permitted.
+// Entries match exactly or, written as "*.example.com", any subdomain.
+func (p *SandboxPolicy) AllowsHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMethod reports whether an HTTP method is permitted at the tier;
+// tiers that cannot write are limited to safe methods.
+func (p *SandboxPolicy) AllowsMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return p.Tier.CanWrite()
+}
+
+func validateSandbox(path string, m *Manifest, tool ToolConfig, result *ValidationResult) {
+	h := tool.Handler
+	if h == nil {
+		return
+	}
+	switch h.Runtime {
+	case RuntimeWASM:
+		if h.Module == "" {
+			result.addError(fmt.Sprintf("%s.handler.module: required for wasm runtime", path))
+		}
+	case RuntimeScript:
+		if (h.Script == "") == (h.Module == "") {
+			result.addError(fmt.Sprintf("%s.handler: script runtime requires exactly one of script or module", path))
+		}
+	case RuntimeSQL:
+		validateSQL(path+".handler.sql", m, tool, result)
+	case RuntimeFS:
+		if h.Sandbox == nil || len(h.Sandbox.Mounts) == 0 {
+			result.addError(fmt.Sprintf("%s.handler.sandbox.mounts: fs runtime requires at least one mount", path))
+		}
+	case RuntimeExec:
+		validateExec(path+".handler.exec", m, h.Exec, result)
+	case RuntimeDrupal:
+		validateDrupal(path+".handler", h, result)
+	case RuntimeSMTP, RuntimeSlack:
+		validateNotify(path+".handler", m, h, result)
+	case RuntimeHTTP:
+		if h.Endpoint == "" {
+			result.addError(path + ".handler.endpoint: required for http runtime")
+		}
+	case RuntimeQueue:
+		if h.Queue == nil {
+			result.addError(path + ".handler.queue: required for queue runtime")
+		} else {
+			validateQueue(path+".handler.queue", h.Queue, result)
+			if h.Queue.Reply != "" || h.Queue.Group != "" {
+				result.addWarning(path + ".handler.queue: reply and group only apply to triggers")
+			}
+		}
+	}
+	if h.TLS != nil {
+		validateTLS(path+".handler.tls", h.TLS, result)
+	}
+	if h.Sandbox == nil {
+		return
+	}
+	for i, mount := range h.Sandbox.Mounts {
+		mpath := fmt.Sprintf("%s.handler.sandbox.mounts[%d]", path, i)
+		if mount.Host == "" || mount.Guest == "" {
+			result.addError(mpath + ": host and guest paths are required")
+		}
+		if mount.Guest != "" && !strings.HasPrefix(mount.Guest, "/") {
+			result.addError(fmt.Sprintf("%s.guest: must be absolute: %s", mpath, mount.Guest))
+		}
+		if mount.Host != "" && strings.HasPrefix(filepath.Clean(mount.Host), "..") {
+			result.addWarning(fmt.Sprintf("%s.host: escapes the manifest directory: %s", mpath, mount.Host))
+		}
+	}
+	for _, host := range h.Sandbox.AllowedHosts {
+		if strings.Contains(host, "/") || strings.Contains(host, ":") {
+			result.addError(fmt.Sprintf("%s.handler.sandbox.allowed_hosts: expected a host name, got %s", path, host))
+		}
+	}
+	if h.Sandbox.MemoryLimitMB < 0 {
+		result.addError(fmt.Sprintf("%s.handler.sandbox.memory_limit_mb: must not be negative", path))
+	}
+	if _, err := m.SandboxPolicy(tool); err != nil {
+		result.addError(fmt.Sprintf("%s.handler.sandbox: %v", path, err))
+	}
+}
+
+var execPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ExpandArgs fills the {{name}} placeholders in Args from call arguments.
+// A missing argument is an error, as is an expanded argument beginning with
+// "-" whose template does not, so callers cannot inject options.
+func (c *ExecConfig) ExpandArgs(args map[string]interface{}) ([]string, error) {
+	out := make([]string, len(c.Args))
+	for i, tmpl := range c.Args {
+		var err error
+		out[i] = execPlaceholder.ReplaceAllStringFunc(tmpl, func(match string) string {
+			name := execPlaceholder.FindStringSubmatch(match)[1]
+			v, ok := args[name]
+			if !ok {
+				err = NewError(fmt.Sprintf("missing argument %s", name))
+				return ""
+			}
+			return fmt.Sprint(v)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(out[i], "-") && !strings.HasPrefix(tmpl, "-") {
+			return nil, NewError(fmt.Sprintf("argument %s must not expand to a value starting with '-'", tmpl))
+		}
+	}
+	return out, nil
+}
+
+func validateExec(path string, m *Manifest, cfg *ExecConfig, result *ValidationResult) {
+	if tier := m.EffectiveAccessTier(); !tier.CanWrite() {
+		result.addError(fmt.Sprintf("%s: exec runtime is not allowed for %s agents", path, tier))
+	}
+	if cfg == nil {
+		result.addError(path + ": required for exec runtime")
+		return
+	}
+	if cfg.Command == "" {
+		result.addError(path + ".command: required")
+	} else if execPlaceholder.MatchString(cfg.Command) {
+		result.addError(path + ".command: must not contain placeholders")
+	}
+	if cfg.WorkingDir != "" && (filepath.IsAbs(cfg.WorkingDir) || strings.HasPrefix(filepath.Clean(cfg.WorkingDir), "..")) {
+		result.addError(fmt.Sprintf("%s.working_dir: must be relative to the manifest directory: %s", path, cfg.WorkingDir))
+	}
+	if cfg.MaxOutputBytes < 0 {
+		result.addError(path + ".max_output_bytes: must not be negative")
+	}
+}
@@ -0,0 +1,27 @@
+package ossa
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:embed schema/ossa-0.3.3.schema.json
+var embeddedSchema []byte
+
+// EmbeddedSchema returns the OSSA JSON Schema bundled with the SDK.
+func EmbeddedSchema() []byte {
+	return embeddedSchema
+}
+
+func compileSchema(data []byte) (*gojsonschema.Schema, error) {
+	if err := checkSchemaOffline(data); err != nil {
+		return nil, err
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	return schema, nil
+}
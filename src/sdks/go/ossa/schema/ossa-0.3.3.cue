@@ -0,0 +1,4318 @@
+// Code generated by ossa gen cue; DO NOT EDIT.
+
+// OSSA v0.3.3 Manifest Schema
+package ossa
+
+import (
+	"list"
+	"strings"
+)
+
+// #Manifest is a manifest of any kind.
+#Manifest: #Agent | #Task | #Workflow
+
+// #Agent is a manifest of kind Agent.
+#Agent: {
+	// OSSA API version (v0.3.3+ supports Task and Workflow kinds)
+	apiVersion!: string & =~"^ossa/v(0\\.3\\.[0-9]+(-[a-zA-Z0-9]+)?|0\\.2\\.[2-9](-dev)?|1)(\\.[0-9]+)?(-[a-zA-Z0-9]+)?$"
+	// Framework-specific extensions
+	extensions?: {
+		agents_md?: #AgentsMdExtension
+		autogen?: #AutoGenExtension
+		autogpt?: #AutoGPTExtension
+		bedrock?: #BedrockAgentsExtension
+		crewai?: #CrewAIExtension
+		dify?: #DifyExtension
+		haystack?: #HaystackExtension
+		instructor?: #InstructorExtension
+		kagent?: #KagentExtension
+		kubernetes?: #KubernetesExtension
+		langchain?: #LangChainExtension
+		langflow?: #LangFlowExtension
+		langgraph?: #LangGraphExtension
+		llamaindex?: #LlamaIndexExtension
+		llms_txt?: #LlmsTxtExtension
+		mcp?: #MCPExtension
+		metagpt?: #MetaGPTExtension
+		openai_assistants?: #OpenAIAssistantsExtension
+		openai_swarm?: #OpenAISwarmExtension
+		phidata?: #PhidataExtension
+		pydantic_ai?: #PydanticAIExtension
+		semanticKernel?: #SemanticKernelExtension
+		skills?: #SkillsExtension
+		smolagents?: #SmolagentsExtension
+		vercel_ai?: #VercelAIExtension
+		vertexai?: #VertexAIExtension
+		...
+	}
+	kind!: "Agent"
+	metadata!: #Metadata
+	// Runtime-specific capability bindings (for Task and Workflow kinds)
+	runtime?: #RuntimeBinding
+	spec!: #AgentSpec
+	...
+}
+
+// #Task is a manifest of kind Task.
+#Task: {
+	// OSSA API version (v0.3.3+ supports Task and Workflow kinds)
+	apiVersion!: string & =~"^ossa/v(0\\.3\\.[0-9]+(-[a-zA-Z0-9]+)?|0\\.2\\.[2-9](-dev)?|1)(\\.[0-9]+)?(-[a-zA-Z0-9]+)?$"
+	// Framework-specific extensions
+	extensions?: {
+		agents_md?: #AgentsMdExtension
+		autogen?: #AutoGenExtension
+		autogpt?: #AutoGPTExtension
+		bedrock?: #BedrockAgentsExtension
+		crewai?: #CrewAIExtension
+		dify?: #DifyExtension
+		haystack?: #HaystackExtension
+		instructor?: #InstructorExtension
+		kagent?: #KagentExtension
+		kubernetes?: #KubernetesExtension
+		langchain?: #LangChainExtension
+		langflow?: #LangFlowExtension
+		langgraph?: #LangGraphExtension
+		llamaindex?: #LlamaIndexExtension
+		llms_txt?: #LlmsTxtExtension
+		mcp?: #MCPExtension
+		metagpt?: #MetaGPTExtension
+		openai_assistants?: #OpenAIAssistantsExtension
+		openai_swarm?: #OpenAISwarmExtension
+		phidata?: #PhidataExtension
+		pydantic_ai?: #PydanticAIExtension
+		semanticKernel?: #SemanticKernelExtension
+		skills?: #SkillsExtension
+		smolagents?: #SmolagentsExtension
+		vercel_ai?: #VercelAIExtension
+		vertexai?: #VertexAIExtension
+		...
+	}
+	kind!: "Task"
+	metadata!: #Metadata
+	// Runtime-specific capability bindings (for Task and Workflow kinds)
+	runtime?: #RuntimeBinding
+	spec!: #TaskSpec
+	...
+}
+
+// #Workflow is a manifest of kind Workflow.
+#Workflow: {
+	// OSSA API version (v0.3.3+ supports Task and Workflow kinds)
+	apiVersion!: string & =~"^ossa/v(0\\.3\\.[0-9]+(-[a-zA-Z0-9]+)?|0\\.2\\.[2-9](-dev)?|1)(\\.[0-9]+)?(-[a-zA-Z0-9]+)?$"
+	// Framework-specific extensions
+	extensions?: {
+		agents_md?: #AgentsMdExtension
+		autogen?: #AutoGenExtension
+		autogpt?: #AutoGPTExtension
+		bedrock?: #BedrockAgentsExtension
+		crewai?: #CrewAIExtension
+		dify?: #DifyExtension
+		haystack?: #HaystackExtension
+		instructor?: #InstructorExtension
+		kagent?: #KagentExtension
+		kubernetes?: #KubernetesExtension
+		langchain?: #LangChainExtension
+		langflow?: #LangFlowExtension
+		langgraph?: #LangGraphExtension
+		llamaindex?: #LlamaIndexExtension
+		llms_txt?: #LlmsTxtExtension
+		mcp?: #MCPExtension
+		metagpt?: #MetaGPTExtension
+		openai_assistants?: #OpenAIAssistantsExtension
+		openai_swarm?: #OpenAISwarmExtension
+		phidata?: #PhidataExtension
+		pydantic_ai?: #PydanticAIExtension
+		semanticKernel?: #SemanticKernelExtension
+		skills?: #SkillsExtension
+		smolagents?: #SmolagentsExtension
+		vercel_ai?: #VercelAIExtension
+		vertexai?: #VertexAIExtension
+		...
+	}
+	kind!: "Workflow"
+	metadata!: #Metadata
+	// Runtime-specific capability bindings (for Task and Workflow kinds)
+	runtime?: #RuntimeBinding
+	spec!: #WorkflowSpec
+	...
+}
+
+// Access tier configuration for separation of duties (v0.3.3+). Defines privilege levels and role separations for agents.
+#AccessTier: {
+	// Approval workflow to use when requires_approval is true
+	approval_chain?: "standard" | "elevated" | "critical"
+	// Audit logging level: standard (30 days), detailed (90 days), comprehensive (365 days)
+	audit_level?: *"standard" | "detailed" | "comprehensive"
+	// Isolation level: none (default), standard (limited delegation), strict (no execution, policy only)
+	isolation?: *"none" | "standard" | "strict"
+	// Explicit permissions granted to this agent
+	permissions?: [..."read_code" | "read_configs" | "read_metrics" | "read_logs" | "read_issues" | "read_mrs" | "execute_queries" | "write_docs" | "write_tests" | "write_scaffolds" | "write_configs_draft" | "create_issues" | "create_mrs_draft" | "execute_sandboxed" | "write_production_code" | "merge_mrs" | "delete_branches" | "modify_infrastructure" | "modify_secrets" | "execute_deployments" | "modify_pipelines" | "modify_configs" | "execute_commands" | "define_policies" | "publish_policies" | "audit_compliance" | "report_violations"]
+	// Explicitly prohibited actions for this agent
+	prohibited?: [...string]
+	// Whether operations require approval chain
+	requires_approval?: bool & (*false | _)
+	// Access tier level: tier_1_read (analyzers), tier_2_write_limited (workers), tier_3_write_elevated (operators), tier_4_policy (governors)
+	tier!: "tier_1_read" | "tier_2_write_limited" | "tier_3_write_elevated" | "tier_4_policy"
+}
+
+// Comprehensive agent identity configuration for service accounts, authentication, and observability
+#AgentIdentity: {
+	// Authentication method configuration
+	authentication?: {
+		// Automatically refresh token before expiry
+		auto_refresh?: bool & (*false | _)
+		// Days before expiry to warn about token rotation
+		expiry_warning_days?: int & >=1 & <=90 & (*7 | _)
+		// Authentication method type
+		method?: *"personal_access_token" | "project_access_token" | "group_access_token" | "deploy_token" | "oauth2" | "ssh_key" | "mtls" | "github_app" | "azure_service_principal"
+		rotation_policy?: {
+			enabled?: bool & (*false | _)
+			interval_days?: int & >=7 & <=365 & (*90 | _)
+			notify_on_rotation?: bool & (*true | _)
+			...
+		}
+		// Required token scopes (provider-specific)
+		scopes?: [...string]
+		...
+	}
+	// DORA metrics tracking configuration
+	dora_tracking?: {
+		enabled?: bool & (*false | _)
+		// Additional labels for metrics
+		labels?: {
+			[string]: string
+		}
+		metrics?: [..."deployment_frequency" | "lead_time" | "change_failure_rate" | "mttr"]
+		prometheus?: {
+			job_name?: string
+			push_gateway?: string
+			...
+		}
+		...
+	}
+	// Fallback identity chain for high availability
+	fallback?: [...{
+		condition?: {
+			pattern_match?: [...string]
+			platform_unavailable?: bool
+			...
+		}
+		provider!: "gitlab" | "github" | "azure-devops" | "bitbucket" | "generic"
+		service_account!: #"AgentIdentity/properties/service_account"
+		token_source?: #"AgentIdentity/properties/token_source"
+		...
+	}]
+	// OpenTelemetry service identity for distributed tracing
+	observability?: {
+		resource_attributes?: {
+			[string]: string
+		}
+		service_instance_id?: string
+		service_name?: string
+		service_namespace?: string
+		service_version?: string & =~"^[0-9]+\\.[0-9]+\\.[0-9]+(-[a-zA-Z0-9.]+)?$"
+		...
+	}
+	// Glob patterns for auto-detection based on working directory (picomatch syntax)
+	patterns?: [...string]
+	// Identity provider type for service account integration
+	provider?: "gitlab" | "github" | "azure-devops" | "bitbucket" | "generic"
+	// Security policies for identity management
+	security?: {
+		minimum_token_length?: int & (*32 | _)
+		prohibited_actions?: [...string]
+		rate_limits?: {
+			git_operations_per_hour?: int & (*100 | _)
+			requests_per_hour?: int & (*1000 | _)
+			requests_per_minute?: int & (*60 | _)
+			...
+		}
+		required_approvals?: {
+			delete_protected_branch?: bool & (*true | _)
+			force_push?: bool & (*true | _)
+			modify_ci_config?: bool & (*false | _)
+			...
+		}
+		token_encryption?: "none" | "at_rest" | "in_transit" | *"both"
+		...
+	}
+	// Service account details for automated operations
+	service_account?: {
+		// Human-readable display name
+		display_name?: string
+		// Service account email for git attribution
+		email!: string
+		// Provider-specific account ID
+		id?: int | string
+		// Roles assigned to this service account
+		roles?: [..."developer" | "maintainer" | "owner" | "reporter" | "guest"]
+		// Service account username
+		username!: string & =~"^[a-z0-9_\\[\\]-]+$" & strings.MinRunes(1) & strings.MaxRunes(64)
+		...
+	}
+	// Session management for Claude Code integration
+	session?: {
+		git_attribution?: bool & (*true | _)
+		heartbeat_interval?: int & >=30 & <=3600 & (*300 | _)
+		hooks?: {
+			post_session?: bool & (*false | _)
+			pre_prompt_submit?: bool & (*true | _)
+			...
+		}
+		init_on_start?: bool & (*true | _)
+		propagate_to_subprocesses?: bool & (*true | _)
+		timeout?: int & >=60 & <=86400 & (*3600 | _)
+		...
+	}
+	// Token/credential source configuration with priority order
+	token_source?: {
+		// Environment variable name containing the token (highest priority)
+		env_var?: string & =~"^[A-Z][A-Z0-9_]*$"
+		// Path to token file (second priority, supports ~ expansion)
+		file_path?: string
+		kubernetes_secret?: {
+			key?: string & (*"token" | _)
+			name?: string
+			namespace?: string
+			...
+		}
+		vault?: {
+			key?: string & (*"value" | _)
+			// Vault secret path
+			path?: string
+			// Vault role for authentication
+			role?: string
+			...
+		}
+		...
+	}
+}
+
+// Agent observability configuration
+#AgentObservability: {
+	// Alerting configuration
+	alerting?: {
+		channels?: [...string]
+		enabled?: bool
+		rules?: [...{...}]
+		...
+	}
+	// Logging configuration
+	logging?: {
+		// Log format
+		format?: "json" | "text"
+		// Log level
+		level?: "debug" | "info" | "warn" | "error"
+		...
+	}
+	// Metrics collection configuration
+	metrics?: {
+		// Custom metrics definitions
+		customMetrics?: [...{
+			description?: string
+			name?: string
+			type?: "counter" | "gauge" | "histogram"
+			...
+		}]
+		enabled?: bool
+		// Metrics endpoint
+		endpoint?: string
+		// Metrics exporter (e.g., prometheus, otlp)
+		exporter?: string
+		// Metrics server port
+		port?: int
+		...
+	}
+	// Service level objectives
+	slo?: {
+		availability?: number
+		error_budget?: number
+		latency_p95_ms?: int
+		...
+	}
+	// Distributed tracing configuration
+	tracing?: {
+		enabled?: bool
+		// Trace collector endpoint
+		endpoint?: string
+		// Trace exporter (e.g., otlp, jaeger, zipkin)
+		exporter?: string
+		...
+	}
+	...
+}
+
+// Specification for agentic loops with LLM (kind: Agent) - inherits from v0.2.9. Either 'role' or 'prompts.system.template' is required.
+#AgentSpec: {
+	// Access tier configuration for separation of duties (v0.3.3+). Defines privilege level and allowed operations.
+	access?: #AccessTier
+	autonomy?: #Autonomy
+	// Regulatory compliance and data governance configuration
+	compliance?: {
+		// Audit logging requirement level
+		audit_logging?: "required" | *"optional" | "disabled"
+		// Required data residency region (e.g., us-east-1, eu-west-1)
+		data_residency?: string
+		// Applicable compliance frameworks
+		frameworks?: [..."SOC2" | "HIPAA" | "GDPR" | "FedRAMP" | "PCI-DSS" | "ISO27001"]
+		// Personally Identifiable Information handling strategy
+		pii_handling?: "encrypt_at_rest" | "anonymize" | "redact" | *"none"
+	}
+	constraints?: #Constraints
+	// Delegation configuration for multi-agent hierarchies (v0.3.3+)
+	delegation?: #DelegationConfig
+	egress?: #EgressConfig
+	embeddings?: #EmbeddingsConfig
+	// A2A/OpenAI-style function definitions for structured tool calling
+	functions?: [...#FunctionDefinition]
+	// Agent identity configuration including service accounts, authentication, and observability (v0.3.3+)
+	identity?: #AgentIdentity
+	input?: #InputConfig
+	// Agent lifecycle, execution, and environment management configuration
+	lifecycle?: {
+		// Checkpoint state every N turns
+		checkpoint_interval?: int & >=1
+		// External dependencies required by this agent
+		dependencies?: [...{
+			// Dependency name
+			name!: string
+			// Dependency source (e.g., npm, pip, composer, docker)
+			source?: string
+			// Dependency type
+			type?: *"runtime" | "build" | "optional"
+			// Dependency version (semver recommended)
+			version!: string
+		}]
+		// Environment-specific configurations
+		environments?: {
+			[string]: {...}
+		}
+		// Idle timeout before agent suspension (e.g., '30m')
+		idle_timeout?: string & =~"^[0-9]+(s|m|h)$"
+		// Maximum number of agentic turns/iterations
+		max_turns?: int & >=1
+		// Ordered lifecycle phases for turn execution
+		phases?: [..."init" | "normalize" | "resolve" | "infer" | "execute" | "assemble" | "emit"]
+		// Timeout per turn (e.g., '300s', '5m')
+		turn_timeout?: string & =~"^[0-9]+(s|m|h)$"
+	}
+	llm?: #LLMConfig
+	// Agent-to-agent messaging configuration (v0.3.3+)
+	messaging?: #MessagingExtension
+	observability?: #AgentObservability
+	// Structured prompts configuration (alternative to role)
+	prompts?: {
+		system?: {
+			// System prompt template
+			template?: string
+			// Prompt version
+			version?: string
+			...
+		}
+		...
+	}
+	// Agent role/system prompt (alternative: use prompts.system.template)
+	role?: string & strings.MinRunes(1)
+	safety?: #Safety
+	// Role separation configuration to prevent conflicts of interest (v0.3.3+)
+	separation?: #SeparationOfDuties
+	state?: #State
+	// Taxonomy classification for domain and cross-cutting concerns (v0.3.3+)
+	taxonomy?: #TaxonomyClassification
+	tools?: [...#Tool]
+	// Queue triggers that run the agent on each message
+	triggers?: [...#Trigger]
+	// Agent type classification aligned with access tiers (v0.3.3+)
+	type?: "analyzer" | "worker" | "operator" | "supervisor" | "orchestrator" | "governor" | "specialist" | "critic"
+	...
+}
+
+// Agents.md extension for bidirectional markdown/OSSA conversion - enables generation, parsing, synchronization, and validation between AGENTS.md files and OSSA manifests
+#AgentsMdExtension: {
+	// Auto-discover agents from AGENTS.md sections
+	auto_discover?: bool & (*false | _)
+	// Generate Cursor-compatible content
+	cursor_integration?: bool & (*false | _)
+	// Enable agents_md extension
+	enabled?: bool & (*false | _)
+	// Path to AGENTS.md file (relative to repository root)
+	file_path?: string & (*"AGENTS.md" | _)
+	// Auto-generate AGENTS.md from manifest
+	generate?: bool & (*true | _)
+	// Include OSSA metadata in generated AGENTS.md
+	include_metadata?: bool & (*true | _)
+	// Explicit mapping between OSSA and agents.md
+	mapping?: {
+		// Map spec.autonomy to PR Instructions section
+		autonomy_to_pr_instructions?: bool & (*true | _)
+		// Map spec.constraints to Testing section
+		constraints_to_testing?: bool & (*true | _)
+		// Map spec.role to Overview section
+		role_to_overview?: bool & (*true | _)
+		// Map spec.safety to Security section
+		safety_to_security?: bool & (*true | _)
+		// Map spec.tools to Development Environment section
+		tools_to_dev_environment?: bool & (*true | _)
+	}
+	// Section-level configuration for AGENTS.md
+	sections?: {
+		architecture?: #AgentsMdSection
+		code_style?: #AgentsMdSection
+		// Additional custom sections
+		custom?: [...#AgentsMdSection]
+		dev_environment?: #AgentsMdSection
+		pr_instructions?: #AgentsMdSection
+		security?: #AgentsMdSection
+		testing?: #AgentsMdSection
+		[string]: #AgentsMdSection
+	}
+	// Synchronization configuration
+	sync?: {
+		// Include generation comments in output
+		include_comments?: bool & (*true | _)
+		// Regenerate AGENTS.md when manifest changes
+		on_manifest_change?: bool & (*true | _)
+		// Preserve custom sections not mapped to manifest
+		preserve_custom?: bool & (*true | _)
+		// Watch for file changes
+		watch?: bool & (*false | _)
+	}
+}
+
+// Configuration for an individual AGENTS.md section
+#AgentsMdSection: {
+	// Content to append after auto-generated content
+	append?: string
+	// Custom markdown content for this section
+	custom?: string
+	// Whether this section is enabled
+	enabled?: bool & (*true | _)
+	// Content to prepend before auto-generated content
+	prepend?: string
+	// OSSA manifest path to derive content from
+	source?: string
+	// Override default section title
+	title?: string
+	// Format string for section title
+	title_format?: string
+}
+
+// AutoGPT autonomous agent mapping configuration
+#AutoGPTExtension: {
+	// AI personality settings
+	ai_settings?: {
+		// Agent goals
+		ai_goals?: [...string]
+		// Agent name (mapped from metadata.name)
+		ai_name?: string
+		// Agent role (mapped from spec.role)
+		ai_role?: string
+		...
+	}
+	// Built-in command toggles
+	builtin_commands?: {
+		execute_code?: bool & (*false | _)
+		execute_shell?: bool & (*false | _)
+		list_agents?: bool & (*true | _)
+		message_agent?: bool & (*true | _)
+		read_file?: bool & (*true | _)
+		start_agent?: bool & (*true | _)
+		web_browse?: bool & (*true | _)
+		web_search?: bool & (*true | _)
+		write_file?: bool & (*true | _)
+		...
+	}
+	// Enabled commands (mapped from spec.tools)
+	commands?: [...{
+		category?: "web_search" | "web_browse" | "file_operations" | "code_execution" | "system" | "user_interaction"
+		enabled?: bool & (*true | _)
+		name?: string
+		...
+	}]
+	// Max continuous iterations (0 = unlimited)
+	continuous_limit?: int & (*0 | _)
+	// Run without user confirmation
+	continuous_mode?: bool & (*false | _)
+	debug_mode?: bool & (*false | _)
+	enabled?: bool & (*false | _)
+	// LLM configuration (mapped from spec.llm)
+	llm?: {
+		api_key_env?: string
+		model?: string
+		provider?: "openai" | "anthropic" | "groq" | "llamafile"
+		...
+	}
+	// Memory backend configuration
+	memory?: {
+		backend?: "local" | "pinecone" | "redis" | "milvus" | "weaviate"
+		index?: string
+		...
+	}
+	// AutoGPT plugins to load
+	plugins?: [...{
+		config?: {...}
+		enabled?: bool
+		name?: string
+		...
+	}]
+	skip_reprompt?: bool & (*false | _)
+	speak_mode?: bool & (*false | _)
+	temperature?: number & >=0 & <=2 & (*0 | _)
+	// Workspace configuration
+	workspace?: {
+		restrict_to_workspace?: bool & (*true | _)
+		workspace_path?: string
+		...
+	}
+	...
+}
+
+// Microsoft AutoGen multi-agent framework extension for OSSA v0.3.3 - supports ConversableAgent, AssistantAgent, UserProxyAgent, GroupChat, and nested delegation patterns
+#AutoGenExtension: {
+	// AutoGen agent class type - determines base behavior and capabilities
+	agent_type?: "conversable_agent" | "assistant_agent" | "user_proxy_agent" | "group_chat_manager" | "teachable_agent" | "reasoning_agent" | "captain_agent" | "custom_agent"
+	// Code execution configuration or false to disable
+	code_execution_config?: {
+		// Languages allowed for code execution
+		allowed_languages?: [..."python" | "bash" | "shell" | "javascript" | "powershell"]
+		// Type of code executor to use
+		executor_type?: *"local_command_line" | "docker_command_line" | "jupyter" | "azure_container_instance"
+		// Number of messages to scan for code blocks
+		last_n_messages?: int & >=1 & (*1 | _)
+		// Code execution timeout in seconds
+		timeout?: int & >=1 & (*60 | _)
+		// Docker image for sandboxed execution (true, false, or image name)
+		use_docker?: bool | string
+		// Working directory for code execution
+		work_dir?: string & (*"workspace" | _)
+		...
+	} | false
+	// Short description for speaker selection in group chat
+	description?: string
+	// Mapping of function names to implementations (module.function or class.method)
+	function_map?: {
+		[string]: string
+	}
+	// GroupChat configuration for multi-agent orchestration
+	group_chat_config?: {
+		// Name of the admin agent
+		admin_name?: string & (*"Admin" | _)
+		// References to OSSA agents participating in group chat
+		agents?: [...string]
+		// Allow same speaker consecutive turns (true/false or list of agent names)
+		allow_repeat_speaker?: bool | [...string]
+		// Reference to custom speaker selection function
+		custom_speaker_selection_func?: string
+		// Maximum conversation rounds
+		max_round?: int & >=1 & (*10 | _)
+		// Send agent introductions at conversation start
+		send_introductions?: bool & (*false | _)
+		// Method for selecting next speaker
+		speaker_selection_method?: *"auto" | "manual" | "random" | "round_robin" | "custom"
+		...
+	}
+	// When to request human input: ALWAYS (after every response), NEVER (fully autonomous), TERMINATE (on termination conditions)
+	human_input_mode?: "ALWAYS" | *"NEVER" | "TERMINATE"
+	// LLM configuration for the agent (mirrors AutoGen's llm_config)
+	llm_config?: {
+		// Seed for response caching (null disables caching)
+		cache_seed?: int | null
+		// List of LLM configurations (model, api_key reference, base_url)
+		config_list?: [...{
+			// Environment variable containing API key (never store keys directly)
+			api_key_env?: string & =~"^[A-Z][A-Z0-9_]*$"
+			// API provider type
+			api_type?: "openai" | "azure" | "anthropic" | "ollama" | "litellm"
+			// API version (required for Azure)
+			api_version?: string
+			// Custom API endpoint (for Azure, local models, etc.)
+			base_url?: string
+			// Model identifier (e.g., gpt-4, claude-3-opus)
+			model?: string
+			...
+		}]
+		// Sampling temperature
+		temperature?: number & >=0 & <=2 & (*0.7 | _)
+		// Request timeout in seconds
+		timeout?: int & >=1 & (*600 | _)
+		...
+	}
+	// Maximum auto-replies before requiring human input
+	max_consecutive_auto_reply?: int & >=0 & (*10 | _)
+	// Configuration for nested agent conversations (delegation)
+	nested_chat_config?: {
+		// Enable nested chat delegation
+		enabled?: bool & (*false | _)
+		// Agents that can be delegated to
+		inner_agents?: [...{
+			// Reference to inner OSSA agent
+			agent_ref?: string
+			// Maximum turns for nested conversation
+			max_turns?: int & >=1
+			// Trigger condition (regex pattern or keyword)
+			trigger?: string
+			...
+		}]
+		// Function to transform messages between outer/inner chats
+		message_transformer?: string
+		...
+	}
+	// Function names that this agent can execute
+	register_for_execution?: [...string]
+	// Functions registered for LLM to call (tool definitions)
+	register_for_llm?: [...{
+		// Function description for LLM
+		description?: string
+		// Function name
+		name!: string
+		// Function parameter schema
+		parameters?: #JSONSchemaDefinition
+		...
+	}]
+	// System message/prompt for the agent (maps to OSSA spec.instructions)
+	system_message?: string
+	// Configuration for TeachableAgent learning capabilities
+	teachability_config?: {
+		// Enable teachability/learning
+		enabled?: bool & (*false | _)
+		// Path to teachability database directory
+		path_to_db_dir?: string
+		// Similarity threshold for memory recall
+		recall_threshold?: number & >=0 & <=2 & (*1.5 | _)
+		// Reset teachability database on start
+		reset_db?: bool & (*false | _)
+		// Logging verbosity level
+		verbosity?: int & >=0 & <=3 & (*0 | _)
+		...
+	}
+	// Conversation termination settings
+	termination_config?: {
+		// Python lambda or function reference to check for termination
+		is_termination_msg?: string
+		// Maximum conversation turns before termination
+		max_turns?: int & >=1
+		// Keywords that trigger conversation termination
+		termination_keywords?: [...string]
+		...
+	}
+	...
+}
+
+// Agent autonomy configuration
+#Autonomy: {
+	// Actions the agent is allowed to perform
+	allowed_actions?: [...string]
+	// Whether human approval is required, or list of specific actions requiring approval
+	approval_required?: bool | [...string]
+	// Actions the agent is explicitly blocked from
+	blocked_actions?: [...string]
+	// Policy for escalating to humans or other agents
+	escalation_policy?: {
+		// Notification channels (e.g., slack:#channel, gitlab:@team)
+		notify?: [...string]
+		// Conditions that trigger escalation
+		triggers?: [...string]
+		...
+	}
+	// Autonomy level: supervised (all approval), assisted (guidance with approval), semi_autonomous (limited autonomy), autonomous (self-directed), fully_autonomous (no oversight)
+	level?: "supervised" | "assisted" | "semi_autonomous" | "autonomous" | "fully_autonomous"
+	...
+}
+
+// Azure OpenAI deployment an agent calls
+#AzureOpenAIConfig: {
+	// Environment variable holding the API key
+	api_key_env?: string & =~"^[A-Za-z_][A-Za-z0-9_]*$" & (*"AZURE_OPENAI_API_KEY" | _)
+	// Azure OpenAI REST API version
+	api_version?: string & =~"^[0-9]{4}-[0-9]{2}-[0-9]{2}(-preview)?$" & (*"2024-10-21" | _)
+	// Deployment name; defaults to model
+	deployment?: string & strings.MinRunes(1)
+	// Resource endpoint (e.g., https://my-resource.openai.azure.com) or an environment variable reference
+	endpoint!: string & =~"^(https://|[$]\\{)"
+}
+
+// AWS Bedrock Agents extension configuration for deploying OSSA agents as Amazon Bedrock Agents
+#BedrockAgentsExtension: {
+	// Bedrock action groups (mapped from spec.tools)
+	action_groups?: [...{
+		action_group_executor?: {
+			// Lambda ARN for action execution
+			lambda?: string
+			...
+		}
+		action_group_name!: string
+		// OpenAPI schema for action group
+		api_schema?: {...}
+		description?: string
+		// Function schema for action group
+		function_schema?: {...}
+		...
+	}]
+	// Bedrock Agent alias ID for versioning
+	agent_alias_id?: string
+	// Existing Bedrock Agent ID to use
+	agent_id?: string
+	// Enable Bedrock Agents integration
+	enabled?: bool & (*false | _)
+	// Bedrock foundation model ID (e.g., anthropic.claude-3-sonnet-20240229-v1:0)
+	foundation_model?: string
+	// Bedrock Guardrail configuration (mapped from spec.safety)
+	guardrail?: {
+		guardrail_identifier?: string
+		guardrail_version?: string
+		...
+	}
+	// Session timeout in seconds
+	idle_session_ttl_seconds?: int & >=60 & <=3600 & (*600 | _)
+	// Agent instructions (mapped from spec.role if not provided)
+	instruction?: string
+	// Knowledge base configurations for RAG
+	knowledge_bases?: [...{
+		description?: string
+		knowledge_base_id!: string
+		retrieval_configuration?: {
+			vector_search_configuration?: {
+				number_of_results?: int & >=1 & <=100 & (*5 | _)
+				override_search_type?: "HYBRID" | "SEMANTIC"
+				...
+			}
+			...
+		}
+		...
+	}]
+	// Agent memory settings
+	memory_configuration?: {
+		enabled_memory_types?: [..."SESSION_SUMMARY"]
+		storage_days?: int & >=1 & <=365 & (*30 | _)
+		...
+	}
+	// Override default prompts
+	prompt_override_configuration?: {
+		prompt_configurations?: [...{
+			base_prompt_template?: string
+			inference_configuration?: {
+				maximum_length?: int & >=1 & <=4096
+				stop_sequences?: [...string]
+				temperature?: number & >=0 & <=1
+				top_k?: int & >=0 & <=500
+				top_p?: number & >=0 & <=1
+				...
+			}
+			prompt_state?: "ENABLED" | "DISABLED"
+			prompt_type?: "PRE_PROCESSING" | "ORCHESTRATION" | "POST_PROCESSING" | "KNOWLEDGE_BASE_RESPONSE_GENERATION"
+			...
+		}]
+		...
+	}
+	...
+}
+
+// Amazon Bedrock region and endpoint an agent calls
+#BedrockConfig: {
+	// bedrock-runtime endpoint override, such as a VPC endpoint
+	endpoint?: string & =~"^(https://|[$]\\{)"
+	// AWS region (e.g., us-east-1) or an environment variable reference
+	region!: string & =~"^([a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-[0-9]+|[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\\})$"
+}
+
+// Capability definition - can be a simple string name or a detailed object
+#Capability: string | {
+	description?: string
+	// Evidence capture for compliance
+	evidence?: {
+		// Log all invocations to audit trail
+		audit_log?: bool
+		// Capture input/output for evidence
+		capture_io?: bool
+		// Evidence retention period in days
+		retention_days?: int
+		...
+	}
+	input_schema?: {...}
+	name!: string
+	output_schema?: {...}
+	// Policy hooks for governance
+	policy?: {
+		// Minimum tier required for approval
+		approval_tier?: "operator" | "admin" | "security"
+		// Audit logging level
+		audit_level?: "none" | "basic" | "full"
+		// Requires human approval before execution
+		requires_approval?: bool
+		...
+	}
+	// Required scopes/permissions
+	scopes?: [...string]
+	// Side effects this capability produces (for policy evaluation)
+	side_effects?: [...string]
+	// Transport binding configuration
+	transport?: {...}
+	// Capability version
+	version?: string
+	...
+}
+
+// RPC-style command that an agent accepts
+#Command: {
+	// Whether the command executes asynchronously
+	async?: bool & (*false | _)
+	// Human-readable description
+	description?: string
+	// Whether the command is idempotent
+	idempotent?: bool & (*false | _)
+	// JSON Schema for command input (camelCase)
+	inputSchema?: {...}
+	// JSON Schema for command input (snake_case alias)
+	input_schema?: {...}
+	// Command name (snake_case)
+	name!: string & =~"^[a-z][a-z0-9_]*$"
+	// JSON Schema for command output (camelCase)
+	outputSchema?: {...}
+	// JSON Schema for command output (snake_case alias)
+	output_schema?: {...}
+	// Command execution timeout in seconds
+	timeoutSeconds?: int & >=1 & <=3600
+	// Command execution timeout (snake_case alias)
+	timeout_seconds?: int & >=1 & <=3600
+	...
+}
+
+// Agent resource and operational constraints
+#Constraints: {
+	// Cost constraints for LLM usage
+	cost?: {
+		// Currency for cost tracking (e.g., USD, EUR)
+		currency?: string
+		// Maximum cost per day
+		maxCostPerDay?: number
+		// Maximum tokens per day
+		maxTokensPerDay?: int
+		// Maximum tokens per request
+		maxTokensPerRequest?: int
+		...
+	}
+	// Performance constraints
+	performance?: {
+		// Maximum concurrent requests
+		maxConcurrentRequests?: int
+		// Maximum response latency in seconds
+		maxLatencySeconds?: number
+		// Request timeout in seconds
+		timeoutSeconds?: number
+		...
+	}
+	// Compute resource constraints (Kubernetes-style)
+	resources?: {
+		// CPU limit (e.g., '1', '500m')
+		cpu?: string
+		// Memory limit (e.g., '2Gi', '512Mi')
+		memory?: string
+		...
+	}
+	...
+}
+
+// Token budgets for each prompt: the system prompt and tool schemas are kept whole, while conversation memory and the run's turns are truncated until the prompt fits
+#ContextConfig: {
+	// Most tokens for each part of the prompt
+	budgets?: {
+		history?: int & >=1
+		memory?: int & >=1
+		system?: int & >=1
+		tools?: int & >=1
+	}
+	// Input field holding conversation memory, a list of entries
+	memory_field?: string & (*"history" | _)
+	// Tokens kept free for the answer; defaults to maxTokens, else 4096
+	reserve_tokens?: int & >=1
+	// oldest drops the oldest memory entries, then turns; middle keeps the first of each; error fails the turn instead
+	truncation?: *"oldest" | "middle" | "error"
+	// Context window in tokens; defaults to the smallest known window of the model and its fallbacks
+	window?: int & >=1
+}
+
+// Cost governance and allocation tracking for LLM usage
+#CostTracking: {
+	// Alert threshold in dollars for budget monitoring
+	budget_alert_threshold?: number & >=0
+	// Tags for cost allocation and chargeback (e.g., project, team, service)
+	cost_allocation_tags?: {
+		[string]: string
+	}
+	// Enable cost tracking for this agent
+	enabled?: bool & (*false | _)
+}
+
+// CrewAI agent configuration mapped to OSSA Agent
+#CrewAIAgentConfig: {
+	// Allow code execution capability
+	allow_code_execution?: bool & (*false | _)
+	// Can this agent delegate to others
+	allow_delegation?: bool & (*true | _)
+	// Background context for the agent persona
+	backstory?: string
+	// Enable response caching
+	cache?: bool & (*true | _)
+	// Code execution security mode
+	code_execution_mode?: *"safe" | "unsafe"
+	// Agent's primary objective
+	goal!: string & strings.MinRunes(1)
+	// LLM configuration for this agent
+	llm?: {
+		model?: string
+		provider?: "openai" | "anthropic" | "google" | "azure" | "ollama" | "groq" | "together" | "fireworks"
+		temperature?: number & >=0 & <=2
+		...
+	}
+	// Maximum execution time in seconds
+	max_execution_time?: int & >=1
+	// Maximum reasoning iterations
+	max_iter?: int & >=1 & (*25 | _)
+	// Rate limit for this agent
+	max_rpm?: int & >=1
+	// Reference to OSSA Agent manifest file
+	ossa_agent_ref?: string
+	// Custom prompt template
+	prompt_template?: string
+	// Custom response template
+	response_template?: string
+	// Agent's role/persona (e.g., 'Senior Research Analyst')
+	role!: string & strings.MinRunes(1)
+	// Custom system prompt template
+	system_template?: string
+	// Tools available to this agent (capability names)
+	tools?: [...string]
+	// Enable verbose logging
+	verbose?: bool & (*false | _)
+	...
+}
+
+// CrewAI callback configuration mapped to OSSA observability
+#CrewAICallbacksConfig: {
+	// Agent action callback
+	on_agent_action?: {
+		handler?: string
+		otel_export?: bool & (*true | _)
+		...
+	}
+	// Crew completion callback
+	on_crew_end?: {
+		handler?: string
+		include_final_output?: bool & (*true | _)
+		otel_export?: bool & (*true | _)
+		...
+	}
+	// Crew start callback
+	on_crew_start?: {
+		handler?: string
+		otel_export?: bool & (*true | _)
+		...
+	}
+	// Step completion callback
+	on_step?: {
+		// Handler function/class reference
+		handler?: string
+		// Include step output in trace
+		include_output?: bool & (*false | _)
+		// Export to OpenTelemetry
+		otel_export?: bool & (*true | _)
+		...
+	}
+	// Task completion callback
+	on_task?: {
+		handler?: string
+		include_output?: bool & (*false | _)
+		otel_export?: bool & (*true | _)
+		...
+	}
+	// Tool usage callback
+	on_tool_use?: {
+		handler?: string
+		otel_export?: bool & (*true | _)
+		track_latency?: bool & (*true | _)
+		...
+	}
+	...
+}
+
+// CrewAI multi-agent orchestration framework extension for OSSA v0.3.3. Provides bidirectional mapping between CrewAI crews, agents, tasks, processes, and OSSA primitives including workflows, agents, tasks, delegation, memory, and observability.
+#CrewAIExtension: {
+	// CrewAI agents mapped to OSSA agents
+	agents?: [...#CrewAIAgentConfig]
+	// Callback hooks for observability integration
+	callbacks?: #CrewAICallbacksConfig
+	// Name of the CrewAI crew (DNS-1123 compatible)
+	crew_name?: string & =~"^[a-z][a-z0-9_-]*$"
+	// Enable agent-to-agent delegation
+	delegation_enabled?: bool & (*true | _)
+	// Primary language for agent interactions
+	language?: string & (*"en" | _)
+	// LLM configuration for hierarchical manager (when process_type=hierarchical)
+	manager_llm?: {
+		model?: string
+		provider?: "openai" | "anthropic" | "google" | "azure" | "ollama" | "groq" | "together" | "fireworks"
+		temperature?: number & >=0 & <=2
+		...
+	}
+	// Maximum requests per minute for rate limiting
+	max_rpm?: int & >=1
+	// Memory system configuration
+	memory_config?: #CrewAIMemoryConfig
+	// Path to output log file
+	output_log_file?: string
+	// Enable planning phase before execution
+	planning?: bool & (*false | _)
+	// LLM configuration for planning (if different from default)
+	planning_llm?: {
+		model?: string
+		provider?: string
+		...
+	}
+	// Process execution pattern - maps to OSSA workflow step ordering
+	process_type?: *"sequential" | "hierarchical" | "consensual"
+	// Share telemetry data with CrewAI platform
+	share_crew?: bool & (*false | _)
+	// CrewAI tasks mapped to OSSA workflow steps
+	tasks?: [...#CrewAITaskConfig]
+	// Enable verbose logging
+	verbose?: bool & (*false | _)
+	...
+}
+
+// CrewAI memory system configuration mapped to OSSA state
+#CrewAIMemoryConfig: {
+	// Contextual memory (RAG-based)
+	contextual?: {
+		enabled?: bool & (*false | _)
+		retriever?: {
+			// Number of results to retrieve
+			k?: int & (*5 | _)
+			// Similarity threshold
+			threshold?: number & (*0.7 | _)
+			...
+		}
+		...
+	}
+	// Enable memory system
+	enabled?: bool & (*false | _)
+	// Entity memory (extracted entities)
+	entity?: {
+		enabled?: bool & (*false | _)
+		provider?: "rag" | "spacy" | "custom"
+		...
+	}
+	// Long-term memory (persistent)
+	long_term?: {
+		provider?: *"rag" | "sqlite" | "custom"
+		storage?: {
+			// Connection string or path
+			connection?: string
+			type?: "chroma" | "qdrant" | "pinecone" | "weaviate" | "pgvector" | "milvus" | "faiss"
+			...
+		}
+		...
+	}
+	// Short-term memory (within session)
+	short_term?: {
+		embedder?: {
+			config?: {...}
+			model?: string
+			provider?: "openai" | "cohere" | "google" | "huggingface"
+			...
+		}
+		provider?: *"rag" | "simple" | "custom"
+		...
+	}
+	...
+}
+
+// CrewAI task configuration mapped to OSSA Task/WorkflowStep
+#CrewAITaskConfig: {
+	// Agent role assigned to this task
+	agent!: string
+	// Execute asynchronously
+	async_execution?: bool & (*false | _)
+	// References to other tasks providing context
+	context?: [...string]
+	// Custom output converter class
+	converter_cls?: string
+	// Detailed task description
+	description!: string & strings.MinRunes(1)
+	// Description of expected output format
+	expected_output?: string
+	// Require human input before completion
+	human_input?: bool & (*false | _)
+	// Reference to OSSA Task manifest file
+	ossa_task_ref?: string
+	// File path for output
+	output_file?: string
+	// JSON schema for structured output
+	output_json?: {...}
+	// Pydantic model class name for validation
+	output_pydantic?: string
+	// Specific tools for this task
+	tools?: [...string]
+	...
+}
+
+// Configuration for delegating tasks to other agents
+#DelegationConfig: {
+	// Operations that can be delegated
+	allowed_operations?: [...string]
+	// Tiers this agent can delegate to
+	allowed_tiers?: [..."tier_1_read" | "tier_2_write_limited" | "tier_3_write_elevated"]
+	// Whether this agent can delegate to others
+	enabled?: bool & (*true | _)
+	// Requirements for delegation
+	requires?: [..."delegation_token" | "audit_trail" | "violation_report" | "task_specification" | "approval"]
+}
+
+// Dify LLMOps platform integration for OSSA agents. Supports Chat, Completion, Agent, and Workflow app types with bidirectional mapping.
+#DifyExtension: {
+	// Dify annotation and observability settings
+	annotation_config?: {
+		annotation_storage?: {
+			enabled?: bool & (*false | _)
+			format?: *"jsonl" | "parquet" | "csv"
+			// Storage path with template variables
+			path?: string
+			...
+		}
+		citation_tracking?: {
+			enabled?: bool & (*false | _)
+			format?: *"inline" | "footnote" | "endnote"
+			...
+		}
+		feedback?: {
+			enabled?: bool & (*true | _)
+			types?: [..."like" | "dislike" | "regenerate" | "report_error"]
+			...
+		}
+		log_export?: {
+			destination?: "opentelemetry" | "elasticsearch" | "loki"
+			enabled?: bool & (*false | _)
+			endpoint?: string
+			...
+		}
+		thought_logging?: {
+			detail_level?: "minimal" | *"standard" | "comprehensive"
+			enabled?: bool & (*true | _)
+			...
+		}
+		usage_tracking?: {
+			enabled?: bool & (*true | _)
+			export_format?: *"prometheus" | "opentelemetry" | "json"
+			labels?: {
+				[string]: string
+			}
+			...
+		}
+	}
+	// Dify API integration settings
+	api_config?: {
+		// Environment variable name containing API key
+		api_key_ref?: string & =~"^[A-Z][A-Z0-9_]*$"
+		// Vault-based API key reference
+		api_key_secret?: {
+			key?: string & (*"value" | _)
+			// Vault secret path
+			vault_path?: string
+			...
+		}
+		// Dify API base URL
+		base_url?: string & (*"https://api.dify.ai/v1" | _)
+		// Path to OpenAPI spec for tool generation
+		openapi_spec?: string
+		rate_limits?: {
+			concurrent_requests?: int & (*10 | _)
+			requests_per_minute?: int & (*60 | _)
+			tokens_per_minute?: int & (*100000 | _)
+			...
+		}
+		retry?: {
+			backoff?: "fixed" | *"exponential" | "linear"
+			initial_delay_ms?: int & >=100 & (*1000 | _)
+			max_attempts?: int & >=1 & <=10 & (*3 | _)
+			max_delay_ms?: int & (*30000 | _)
+			...
+		}
+		timeout_seconds?: int & >=1 & <=600 & (*120 | _)
+	}
+	// Dify application UUID for API operations
+	app_id?: string
+	// Dify application type: chat (conversational), completion (single-shot), agent (autonomous with tools), workflow (DAG-based)
+	app_type!: "chat" | "completion" | "agent" | "workflow"
+	// Dify conversation/messaging settings for chat app type
+	conversation_config?: {
+		memory?: {
+			summary_enabled?: bool & (*false | _)
+			type?: "buffer" | *"buffer_window" | "summary" | "conversation_kg"
+			window_size?: int & >=1 & (*10 | _)
+			...
+		}
+		persistence?: {
+			enabled?: bool & (*true | _)
+			max_messages?: int & >=1 & (*100 | _)
+			ttl_hours?: int & >=1 & (*24 | _)
+			...
+		}
+		streaming?: {
+			// Token chunk size for streaming
+			chunk_size?: int
+			enabled?: bool & (*true | _)
+			...
+		}
+		suggested_questions?: {
+			enabled?: bool & (*true | _)
+			max_suggestions?: int & >=1 & <=10 & (*3 | _)
+			...
+		}
+		// Conversation-scoped variable types
+		variables?: {
+			[string]: string
+		}
+	}
+	// Dify Knowledge/Dataset UUIDs for RAG retrieval
+	dataset_ids?: [...string]
+	// RAG retrieval configuration for datasets
+	retrieval_config?: {
+		chunking?: {
+			max_tokens?: int & >=100 & (*500 | _)
+			mode?: *"automatic" | "custom"
+			overlap_tokens?: int & >=0 & (*50 | _)
+			...
+		}
+		embedding?: {
+			// Embedding vector dimensions
+			dimensions?: int
+			// Embedding model identifier
+			model?: string
+			// Embedding provider
+			provider?: string
+			...
+		}
+		mode?: "semantic" | "keyword" | *"hybrid"
+		// Reranking model identifier
+		rerank_model?: string
+		score_threshold?: number & >=0 & <=1 & (*0.7 | _)
+		top_k?: int & >=1 & <=100 & (*5 | _)
+	}
+	// Mapping of OSSA tool names to Dify tool identifiers
+	tool_mapping?: {
+		[string]: string
+	}
+	// Dify workflow configuration for workflow app type
+	workflow_config?: {
+		// Workflow DAG definition with nodes and edges
+		graph?: {
+			edges?: [...{
+				// Edge condition for branching
+				condition?: bool | string
+				// Source node ID
+				source!: string
+				// Target node ID
+				target!: string
+				...
+			}]
+			nodes?: [...{
+				// Node-specific configuration data
+				data?: {...}
+				// Unique node identifier
+				id!: string
+				// Visual position in workflow editor
+				position?: {
+					x?: number
+					y?: number
+					...
+				}
+				// Dify workflow node type
+				type!: "start" | "end" | "llm" | "knowledge-retrieval" | "code" | "template" | "http-request" | "tool" | "if-else" | "iterator" | "parameter-extractor" | "variable-aggregator" | "answer" | "human-review"
+				...
+			}]
+		}
+		// Names of workflow output variables
+		output_variables?: [...string]
+		// Workflow input variable definitions
+		variables?: {
+			[string]: {
+				// Default value
+				default?: _
+				// Maximum length for text variables
+				max_length?: int
+				// Options for select type
+				options?: [...string]
+				required?: bool & (*false | _)
+				// Dify variable type
+				type?: "text" | "paragraph" | "select" | "number" | "file" | "file-list"
+				...
+			}
+		}
+	}
+}
+
+// How the agent's tools reach the network: a proxy and the hosts reached directly, overriding the workspace's .ossa/egress.yaml
+#EgressConfig: {
+	// Hosts, *.domains, IP addresses or CIDRs reached without the proxy, in addition to $NO_PROXY
+	no_proxy?: [...string & strings.MinRunes(1)]
+	// http://, https:// or socks5:// proxy URL, direct, or an environment variable reference; default $HTTPS_PROXY/$HTTP_PROXY
+	proxy?: string
+}
+
+// Embedding model of a RAG-enabled agent, used to vectorize documents and queries
+#EmbeddingsConfig: {
+	azure?: #AzureOpenAIConfig
+	bedrock?: #BedrockConfig
+	// Vector dimensions, for models that can shorten their output
+	dimensions?: int & >=1
+	// Embedding model identifier (e.g., text-embedding-3-small, amazon.titan-embed-text-v2:0)
+	model!: string & strings.MinRunes(1)
+	// Embedding provider - literal value or environment variable with default
+	provider!: "openai" | "azure" | "google" | "bedrock" | "ollama" | "litellm" | "cohere" | "mistral" | "voyage" | "custom" | string & =~"^[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-zA-Z0-9_-]+)?\\}$"
+	vertex?: #VertexAIConfig
+}
+
+// Individual execution profile configuration
+#ExecutionProfileConfig: {
+	// Enable detailed audit logging
+	audit_log?: bool & (*false | _)
+	// Profile description
+	description?: string
+	// Maximum tokens for this profile
+	maxTokens?: int & >=1
+	// Enable extended thinking/reasoning
+	reasoning_enabled?: bool & (*false | _)
+	// Sampling temperature
+	temperature?: number & >=0 & <=2
+	// Require output validation
+	validation_required?: bool & (*false | _)
+}
+
+// Execution profile presets for different use cases
+#ExecutionProfiles: {
+	// Default profile name
+	default?: string
+	profiles?: {
+		[string]: #ExecutionProfileConfig
+	}
+	...
+}
+
+// Fallback LLM configuration for resilience
+#FallbackLLM: {
+	azure?: #AzureOpenAIConfig
+	bedrock?: #BedrockConfig
+	// Maximum tokens in response; defaults to the agent's
+	maxTokens?: int & >=1
+	// Model identifier
+	model!: string
+	pricing?: #ModelPricing
+	// LLM provider - literal value or environment variable
+	provider!: "openai" | "anthropic" | "google" | "azure" | "ollama" | "mistral" | "cohere" | "groq" | "together" | "fireworks" | "deepseek" | "bedrock" | "litellm" | "openrouter" | "custom" | string & =~"^[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-zA-Z0-9_-]+)?\\}$"
+	temperature?: number & >=0 & <=2
+	// Failures of the call tried before this fallback that hand over to it; a timeout always does
+	trigger?: {
+		// HTTP statuses to trigger on
+		error_codes?: [...int]
+		// Trigger when the call takes longer, overriding routing.timeout_ms
+		latency_threshold_ms?: int & >=1
+		// Retries of the call before falling back, overriding retry_config
+		max_retries?: int & >=0
+		// Trigger on any error; ignored when error_codes is set
+		on_error?: bool & (*true | _)
+		...
+	}
+	vertex?: #VertexAIConfig
+	...
+}
+
+// Function definition in A2A/OpenAI format
+#FunctionDefinition: {
+	// What the function does
+	description!: string
+	// Function name
+	name!: string & =~"^[a-zA-Z_][a-zA-Z0-9_]*$"
+	// JSON Schema for function parameters
+	parameters!: #JSONSchemaDefinition
+	// JSON Schema for return value
+	returns?: #JSONSchemaDefinition
+	// Enforce strict schema validation
+	strict?: bool & (*true | _)
+}
+
+// deepset Haystack pipeline mapping configuration
+#HaystackExtension: {
+	// Chat generator configuration (mapped from spec.llm)
+	chat_generator?: {
+		generation_kwargs?: {...}
+		model?: string
+		type?: "OpenAIChatGenerator" | "AnthropicChatGenerator" | "AzureOpenAIChatGenerator" | "HuggingFaceLocalChatGenerator" | "HuggingFaceAPIChatGenerator" | "OllamaChatGenerator" | "GoogleAIGeminiChatGenerator" | "CohereGenerator"
+		...
+	}
+	// Pipeline components
+	components?: [...{
+		// Component initialization parameters
+		init_parameters?: {...}
+		name!: string
+		// Haystack component class
+		type!: string
+		...
+	}]
+	// Component connections
+	connections?: [...{
+		receiver!: string
+		receiver_input?: string
+		sender!: string
+		sender_output?: string
+		...
+	}]
+	// Document store configuration
+	document_store?: {
+		connection_params?: {...}
+		type?: "InMemoryDocumentStore" | "ElasticsearchDocumentStore" | "OpenSearchDocumentStore" | "PineconeDocumentStore" | "QdrantDocumentStore" | "WeaviateDocumentStore" | "ChromaDocumentStore" | "PgvectorDocumentStore"
+		...
+	}
+	// Embedding model configuration
+	embedder?: {
+		model?: string
+		type?: "SentenceTransformersTextEmbedder" | "OpenAITextEmbedder" | "CohereTextEmbedder" | "HuggingFaceAPITextEmbedder" | "AzureOpenAITextEmbedder"
+		...
+	}
+	enabled?: bool & (*false | _)
+	metadata?: {
+		max_runs_per_component?: int
+		...
+	}
+	output_adapter?: {
+		output_type?: string
+		template?: string
+		...
+	}
+	// Haystack pipeline type
+	pipeline_type?: "indexing" | "query" | "chat" | "agent"
+	prompt_builder?: {
+		required_variables?: [...string]
+		template?: string
+		...
+	}
+	retriever?: {
+		filters?: {...}
+		top_k?: int & (*10 | _)
+		type?: "InMemoryEmbeddingRetriever" | "InMemoryBM25Retriever" | "ElasticsearchEmbeddingRetriever" | "ElasticsearchBM25Retriever" | "PineconeEmbeddingRetriever" | "QdrantEmbeddingRetriever"
+		...
+	}
+	// Haystack tools (mapped from spec.tools)
+	tools?: [...{
+		description?: string
+		name?: string
+		parameters?: {...}
+		pipeline_ref?: string
+		...
+	}]
+	...
+}
+
+// Input the agent accepts besides text, checked against the modalities of its models and enforced on each run's attachments
+#InputConfig: {
+	// Most attachments per run; unlimited when unset
+	max_attachments?: int & >=1
+	// Largest audio attachment; defaults to 25 MiB
+	max_audio_bytes?: int & >=1
+	// Largest image attachment; defaults to 20 MiB
+	max_image_bytes?: int & >=1
+	// Only accepted media types; defaults to image/png, image/jpeg, image/gif, image/webp, audio/wav and audio/mpeg
+	mime_types?: [...string & =~"^(image|audio)/[A-Za-z0-9.+-]+$"]
+	// Accepted input modalities; text is always accepted
+	modalities?: [..."text" | "image" | "audio"]
+}
+
+// Instructor structured output mapping configuration
+#InstructorExtension: {
+	// Caching configuration
+	cache?: {
+		enabled?: bool
+		ttl?: int
+		type?: "redis" | "diskcache" | "memory"
+		...
+	}
+	// Client configuration (mapped from spec.llm)
+	client?: {
+		// Instructor mode for structured output
+		mode?: "FUNCTIONS" | "TOOLS" | "JSON" | "MD_JSON" | "JSON_SCHEMA" | "TOOLS_STRICT" | "ANTHROPIC_TOOLS" | "ANTHROPIC_JSON" | "COHERE_TOOLS" | "GEMINI_JSON" | "GEMINI_TOOLS" | "VERTEXAI_TOOLS" | "VERTEXAI_JSON" | "MISTRAL_TOOLS"
+		model?: string
+		// Instructor client type
+		type?: "openai" | "anthropic" | "litellm" | "cohere" | "google" | "vertexai" | "mistral" | "groq" | "cerebras" | "fireworks" | "together" | "ollama"
+		...
+	}
+	enabled?: bool & (*false | _)
+	// Lifecycle hooks
+	hooks?: {
+		on_completion?: [...string]
+		on_exception?: [...string]
+		on_parse?: [...string]
+		...
+	}
+	// Enable streaming iterable response
+	iterable?: bool & (*false | _)
+	// Max retries for validation errors
+	max_retries?: int & (*3 | _)
+	// Enable partial streaming
+	partial?: bool & (*false | _)
+	// Pydantic model for response validation
+	response_model?: {
+		fields?: {
+			[string]: {
+				default?: _
+				description?: string
+				type?: string
+				...
+			}
+		}
+		name?: string
+		...
+	}
+	// Enable strict mode
+	strict?: bool
+	// Context passed to validators
+	validation_context?: {...}
+	...
+}
+
+// JSON Schema definition for input/output validation
+#JSONSchemaDefinition: {
+	additionalProperties?: bool | {...}
+	items?: {...}
+	properties?: {...}
+	required?: [...string]
+	type?: "object" | "array" | "string" | "number" | "integer" | "boolean" | "null"
+	...
+}
+
+// kagent.dev Kubernetes-native AI agent orchestration mapping configuration. Enables declarative agent deployment on K8s clusters with CRD-based lifecycle management.
+#KagentExtension: {
+	// Agent CRD configuration
+	agent?: {
+		// Model configuration (mapped from spec.llm)
+		model?: {
+			apiKeySecretRef?: {
+				key?: string
+				name?: string
+				...
+			}
+			// Custom API endpoint
+			endpoint?: string
+			// Model name
+			name?: string
+			// LLM provider
+			provider?: "openai" | "anthropic" | "azure" | "ollama" | "litellm" | "custom"
+			...
+		}
+		// Agent resource name (DNS-1123 subdomain)
+		name?: string
+		// Namespace for agent deployment
+		namespace?: string & (*"kagent-system" | _)
+		// System prompt (mapped from spec.role or spec.prompts)
+		systemPrompt?: string
+		// Tool references (mapped from spec.tools)
+		tools?: [...{
+			mcpServer?: {
+				name?: string
+				namespace?: string
+				tools?: [...string]
+				...
+			}
+			name?: string
+			toolRef?: {
+				kind?: string
+				name?: string
+				...
+			}
+			...
+		}]
+		...
+	}
+	// kagent CRD API version
+	apiVersion?: string & (*"kagent.dev/v1alpha1" | _)
+	// Deployment overrides
+	deployment?: {
+		annotations?: {
+			[string]: string
+		}
+		labels?: {
+			[string]: string
+		}
+		nodeSelector?: {
+			[string]: string
+		}
+		tolerations?: [...{
+			effect?: string
+			key?: string
+			operator?: string
+			value?: string
+			...
+		}]
+		...
+	}
+	enabled?: bool & (*false | _)
+	// MCP server deployments (mapped from spec.tools where type=mcp)
+	mcpServers?: [...{
+		command?: [...string]
+		env?: [...{
+			name?: string
+			value?: string
+			valueFrom?: {
+				secretKeyRef?: {
+					key?: string
+					name?: string
+					...
+				}
+				...
+			}
+			...
+		}]
+		image!: string
+		name!: string
+		resources?: {
+			limits?: {
+				cpu?: string
+				memory?: string
+				...
+			}
+			requests?: {
+				cpu?: string
+				memory?: string
+				...
+			}
+			...
+		}
+		...
+	}]
+	// Memory/state management configuration (mapped from spec.state)
+	memory?: {
+		connectionSecretRef?: {
+			key?: string
+			name?: string
+			...
+		}
+		maxMessages?: int
+		provider?: "in-memory" | "redis" | "postgresql" | "qdrant" | "pinecone" | "custom"
+		ttlSeconds?: int
+		...
+	}
+	// Observability configuration (mapped from spec.observability)
+	observability?: {
+		logging?: {
+			format?: "json" | "text"
+			level?: "debug" | "info" | "warn" | "error"
+			...
+		}
+		metrics?: {
+			enabled?: bool
+			path?: string & (*"/metrics" | _)
+			port?: int & (*9090 | _)
+			...
+		}
+		tracing?: {
+			enabled?: bool
+			endpoint?: string
+			samplingRate?: number
+			...
+		}
+		...
+	}
+	// Auto-scaling configuration
+	scaling?: {
+		enabled?: bool & (*false | _)
+		maxReplicas?: int & (*10 | _)
+		metrics?: [...{
+			averageUtilization?: int
+			type?: "cpu" | "memory" | "custom"
+			...
+		}]
+		minReplicas?: int & (*1 | _)
+		...
+	}
+	// Multi-agent team configuration
+	team?: {
+		name?: string
+		participants?: [...{
+			agentRef?: {
+				name?: string
+				namespace?: string
+				...
+			}
+			role?: string
+			...
+		}]
+		selector?: {
+			selectorRef?: {
+				name?: string
+				...
+			}
+			type?: "RoundRobin" | "Random" | "Selector"
+			...
+		}
+		terminationCondition?: {
+			maxMessages?: int
+			text?: string
+			type?: "MaxMessages" | "TextMention" | "External"
+			...
+		}
+		...
+	}
+	// Custom tool server configuration
+	toolServer?: {
+		enabled?: bool
+		image?: string
+		port?: int & (*8080 | _)
+		protocol?: "http" | "grpc" | "mcp"
+		...
+	}
+	...
+}
+
+// Kubernetes-specific runtime configuration (KAS-inspired)
+#KubernetesConfig: {
+	// Kubernetes API server URL (similar to KAS private API URL)
+	api_server_url?: string
+	// Reference to Kubernetes ConfigMap
+	config_map_ref?: string
+	// Health check endpoint URL
+	health_check_endpoint?: string
+	// Kubernetes namespace (DNS-1123 subdomain)
+	namespace?: string & =~"^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	// Network family (KAS pattern: tcp, tcp4, tcp6)
+	network_family?: *"tcp" | "tcp4" | "tcp6"
+	rbac?: {
+		// Kubernetes ClusterRole name
+		cluster_role?: string
+		// Kubernetes Role name
+		role?: string
+		// Kubernetes RoleBinding name
+		role_binding?: string
+	}
+	// Reference to Kubernetes Secret
+	secret_ref?: string
+	// Kubernetes service account name
+	service_account?: string & =~"^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+}
+
+// Kubernetes deployment mapping configuration for OSSA agents. Supports kind, k3s, minikube, and production clusters.
+#KubernetesExtension: {
+	// Cluster configuration
+	cluster?: {
+		// kubectl context to use
+		context?: string
+		// Path to kubeconfig file or env var reference
+		kubeconfig?: string
+		// Cluster name (for kind: cluster name, for cloud: cluster identifier)
+		name?: string
+		// Kubernetes cluster type
+		type?: "kind" | "k3s" | "minikube" | "eks" | "gke" | "aks" | "openshift" | "rancher" | "custom"
+		...
+	}
+	// Deployment configuration
+	deployment?: {
+		// Pod affinity/anti-affinity rules
+		affinity?: {...}
+		image?: {
+			pullPolicy?: "Always" | *"IfNotPresent" | "Never"
+			repository?: string
+			tag?: string
+			...
+		}
+		nodeSelector?: {
+			[string]: string
+		}
+		// Number of agent pod replicas
+		replicas?: int & (*1 | _)
+		// Resource requests and limits
+		resources?: {
+			limits?: {
+				cpu?: string
+				gpu?: string
+				memory?: string
+				...
+			}
+			requests?: {
+				cpu?: string
+				gpu?: string
+				memory?: string
+				...
+			}
+			...
+		}
+		strategy?: *"RollingUpdate" | "Recreate"
+		tolerations?: [...{
+			effect?: string
+			key?: string
+			operator?: string
+			value?: string
+			...
+		}]
+		...
+	}
+	enabled?: bool & (*false | _)
+	// Helm chart configuration for agent deployment
+	helm?: {
+		chart?: string
+		releaseName?: string
+		repository?: string
+		// Helm values override
+		values?: {...}
+		valuesFiles?: [...string]
+		version?: string
+		...
+	}
+	// Ingress configuration
+	ingress?: {
+		className?: string
+		enabled?: bool & (*false | _)
+		host?: string
+		path?: string & (*"/" | _)
+		tls?: {
+			enabled?: bool
+			secretName?: string
+			...
+		}
+		...
+	}
+	// Namespace configuration
+	namespace?: {
+		// Create namespace if it doesn't exist
+		create?: bool & (*true | _)
+		// Labels to apply to namespace
+		labels?: {
+			[string]: string
+		}
+		// Kubernetes namespace for agents
+		name?: string & (*"ossa-agents" | _)
+		...
+	}
+	// Health check probes
+	probes?: {
+		liveness?: {
+			enabled?: bool & (*true | _)
+			initialDelaySeconds?: int & (*30 | _)
+			path?: string & (*"/health" | _)
+			periodSeconds?: int & (*10 | _)
+			...
+		}
+		readiness?: {
+			enabled?: bool & (*true | _)
+			initialDelaySeconds?: int & (*5 | _)
+			path?: string & (*"/ready" | _)
+			periodSeconds?: int & (*5 | _)
+			...
+		}
+		...
+	}
+	// RBAC configuration for agent permissions
+	rbac?: {
+		create?: bool & (*true | _)
+		rules?: [...{
+			apiGroups?: [...string]
+			resources?: [...string]
+			verbs?: [...string]
+			...
+		}]
+		...
+	}
+	// Secret management for API keys and credentials
+	secrets?: {
+		// Secret keys to mount as environment variables
+		keys?: [...{
+			envVar!: string
+			name!: string
+			secretKey?: string
+			...
+		}]
+		provider?: *"kubernetes" | "vault" | "aws-secrets-manager" | "azure-keyvault" | "gcp-secret-manager" | "external-secrets"
+		// Kubernetes secret name containing API keys
+		secretName?: string
+		...
+	}
+	// Service configuration for agent networking
+	service?: {
+		annotations?: {
+			[string]: string
+		}
+		port?: int & (*8080 | _)
+		targetPort?: int
+		type?: *"ClusterIP" | "NodePort" | "LoadBalancer"
+		...
+	}
+	// Service account configuration
+	serviceAccount?: {
+		annotations?: {
+			[string]: string
+		}
+		create?: bool & (*true | _)
+		name?: string
+		...
+	}
+	// Volume mounts for agent pods
+	volumes?: [...{
+		mountPath!: string
+		name!: string
+		readOnly?: bool
+		source?: string
+		type!: "emptyDir" | "configMap" | "secret" | "persistentVolumeClaim" | "hostPath"
+		...
+	}]
+	...
+}
+
+#LLMConfig: {
+	// Azure OpenAI deployment, required when provider is azure
+	azure?: #AzureOpenAIConfig
+	// Amazon Bedrock settings, required when provider is bedrock
+	bedrock?: #BedrockConfig
+	// How prompts are fitted to the model's context window
+	context?: #ContextConfig
+	// Cost governance and allocation tracking
+	cost_tracking?: #CostTracking
+	// Custom execution profile definitions
+	execution_profiles?: #ExecutionProfiles
+	// Deprecated since 0.3.3, removed in 0.5.0: use fallbacks
+	fallback_models?: [...#FallbackLLM]
+	// Models tried when this one fails or times out, in the order routing picks
+	fallbacks?: [...#FallbackLLM]
+	// Penalizes tokens by how often they appeared so far
+	frequencyPenalty?: number & >=-2 & <=2
+	// Maximum tokens in response
+	maxTokens?: int & >=1
+	// Model identifier (e.g., gpt-4o, claude-sonnet-4.5-20250929)
+	model!: string
+	// Penalizes tokens that appeared so far
+	presencePenalty?: number & >=-2 & <=2
+	// Model price, for cost-optimized routing
+	pricing?: #ModelPricing
+	// Execution profile for task-specific optimization (A2A compatible)
+	profile?: "fast" | "balanced" | "deep" | "safe" | string & =~"^[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-zA-Z0-9_-]+)?\\}$"
+	// LLM provider - literal value or environment variable with default (e.g., ${LLM_PROVIDER:-anthropic})
+	provider!: "openai" | "anthropic" | "google" | "azure" | "ollama" | "mistral" | "cohere" | "groq" | "together" | "fireworks" | "deepseek" | "bedrock" | "litellm" | "openrouter" | "custom" | string & =~"^[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-zA-Z0-9_-]+)?\\}$"
+	responseFormat?: #ResponseFormat
+	// Retry and backoff configuration for transient failures
+	retry_config?: #RetryConfig
+	// Order in which the model and its fallbacks are tried
+	routing?: #RoutingConfig
+	// Seed for reproducible sampling, where the provider supports it
+	seed?: int
+	// Sequences that end the response where they appear
+	stop?: list.MaxItems(4) & [...string & strings.MinRunes(1)]
+	// Sampling temperature for response generation
+	temperature?: number & >=0 & <=2
+	// Nucleus sampling: only tokens within this cumulative probability are sampled
+	topP?: number & >=0 & <=1
+	// Google Cloud project and location; with provider google, calls Gemini through Vertex AI instead of the Gemini API
+	vertex?: #VertexAIConfig
+	...
+}
+
+// Fields of spec.llm a tool overrides for the turns that handle its results; unset fields are inherited, except that a different provider inherits no azure, bedrock, vertex, pricing or fallbacks
+#LLMOverride: {
+	// Azure OpenAI deployment, required when provider is azure
+	azure?: #AzureOpenAIConfig
+	// Amazon Bedrock settings, required when provider is bedrock
+	bedrock?: #BedrockConfig
+	// Penalizes tokens by how often they appeared so far
+	frequencyPenalty?: number & >=-2 & <=2
+	// Maximum tokens in response
+	maxTokens?: int & >=1
+	// Model identifier (e.g., gpt-4o, claude-sonnet-4.5-20250929)
+	model?: string
+	// Penalizes tokens that appeared so far
+	presencePenalty?: number & >=-2 & <=2
+	// Model price, for cost-optimized routing
+	pricing?: #ModelPricing
+	// LLM provider - literal value or environment variable with default (e.g., ${LLM_PROVIDER:-anthropic})
+	provider?: "openai" | "anthropic" | "google" | "azure" | "ollama" | "mistral" | "cohere" | "groq" | "together" | "fireworks" | "deepseek" | "bedrock" | "litellm" | "openrouter" | "custom" | string & =~"^[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-zA-Z0-9_-]+)?\\}$"
+	responseFormat?: #ResponseFormat
+	// Seed for reproducible sampling, where the provider supports it
+	seed?: int
+	// Sequences that end the response where they appear
+	stop?: list.MaxItems(4) & [...string & strings.MinRunes(1)]
+	// Sampling temperature for response generation
+	temperature?: number & >=0 & <=2
+	// Nucleus sampling: only tokens within this cumulative probability are sampled
+	topP?: number & >=0 & <=1
+	// Google Cloud project and location; with provider google, calls Gemini through Vertex AI instead of the Gemini API
+	vertex?: #VertexAIConfig
+	...
+}
+
+// LangChain callback handler configuration
+#LangChainCallbackConfig: {
+	// Handler-specific configuration
+	config?: {...}
+	// Events to handle (empty = all events)
+	events?: [..."on_llm_start" | "on_llm_end" | "on_llm_error" | "on_chain_start" | "on_chain_end" | "on_chain_error" | "on_tool_start" | "on_tool_end" | "on_tool_error" | "on_agent_action" | "on_agent_finish" | "on_retriever_start" | "on_retriever_end" | "on_text" | "on_retry"]
+	// Callback handler type
+	type!: "langchain_tracer" | "langsmith" | "stdout" | "file" | "opentelemetry" | "phoenix" | "wandb" | "mlflow" | "custom"
+	...
+}
+
+// LCEL chain configuration for LangChain integration
+#LangChainChainConfig: {
+	// Batch processing configuration
+	batch_config?: {
+		// Maximum concurrent batch executions
+		max_concurrency?: int & >=1
+		// Whether to return exceptions in batch results
+		return_exceptions?: bool & (*false | _)
+		...
+	}
+	// Chain components for sequential chains
+	components?: [...{
+		// Component-specific configuration
+		config?: {...}
+		// Reference to component definition
+		ref?: string
+		// Component type
+		type?: "prompt" | "llm" | "parser" | "retriever" | "tool" | "function" | "passthrough" | "itemgetter"
+		...
+	}]
+	// Fallback chain configuration
+	fallback?: {
+		// Reference to fallback chain
+		chain_ref?: string
+		// Exception types that trigger fallback
+		exceptions?: [...string]
+		...
+	}
+	// JSON Schema for chain input
+	input_schema?: #JSONSchemaDefinition
+	// LCEL expression string (e.g., 'prompt | llm | parser')
+	lcel_expression?: string
+	// Chain identifier
+	name!: string & =~"^[a-z][a-z0-9_-]*$"
+	// JSON Schema for chain output
+	output_schema?: #JSONSchemaDefinition
+	// Chain type
+	type!: "llm" | "prompt_template" | "retrieval" | "stuff_documents" | "map_reduce" | "refine" | "map_rerank" | "conversational_retrieval" | "sql_database" | "api" | "transformation" | "sequential" | "router" | "custom"
+	...
+}
+
+// LangChain/LangGraph integration extension for OSSA v0.3.3. Provides bidirectional mapping between LangChain constructs (LCEL chains, LangGraph state machines, agents, memory, callbacks) and OSSA primitives.
+#LangChainExtension: {
+	// Agent-type-specific configuration
+	agent_config?: {
+		// Method for early stopping
+		early_stopping_method?: "force" | *"generate"
+		// Whether to handle parsing errors gracefully
+		handle_parsing_errors?: bool & (*true | _)
+		// Maximum agent iterations
+		max_iterations?: int & >=1 & <=100 & (*15 | _)
+		// Whether to return intermediate agent steps
+		return_intermediate_steps?: bool & (*false | _)
+		// Keep only last N intermediate steps
+		trim_intermediate_steps?: int & >=1
+		...
+	}
+	// LangChain agent architecture type
+	agent_type?: "react" | "openai_functions" | "openai_tools" | "xml" | "structured_chat" | "tool_calling" | "langgraph_react" | "custom"
+	// LangChain callback handlers mapped to OSSA observability
+	callbacks?: [...#LangChainCallbackConfig]
+	// LCEL chain definitions mapped to OSSA constructs
+	chains?: [...#LangChainChainConfig]
+	// Enable LangChain extension
+	enabled?: bool & (*true | _)
+	// LangGraph state machine definitions
+	graphs?: [...#LangGraphConfig]
+	// Memory-specific configuration
+	memory_config?: {
+		// Window size for conversation_buffer_window
+		k?: int & >=1
+		// Maximum tokens for memory buffer
+		max_token_limit?: int & >=100 & <=128000
+		// Vector store configuration for vector_store memory type
+		vector_store?: {
+			// Collection/index name in the vector store
+			collection_name?: string
+			// Connection string reference (use env var)
+			connection_string?: string
+			// Embedding model for vectorization
+			embedding_model?: string
+			// Vector store backend type
+			type?: "chroma" | "qdrant" | "pinecone" | "weaviate" | "milvus" | "pgvector" | "faiss"
+			...
+		}
+		...
+	}
+	// Memory backend type for stateful interactions
+	memory_type?: "conversation_buffer" | "conversation_buffer_window" | "conversation_summary" | "vector_store" | "entity" | "combined" | *"none"
+	// Global RunnableConfig settings for all runnables
+	runnable_config?: {
+		// Configurable fields for runtime configuration
+		configurable?: {...}
+		// Maximum concurrent runnable executions
+		max_concurrency?: int & >=1 & <=100 & (*5 | _)
+		// Arbitrary metadata passed to callbacks
+		metadata?: {...}
+		// Maximum recursion depth for nested runnables
+		recursion_limit?: int & >=1 & <=100 & (*25 | _)
+		// Tags for filtering callbacks
+		tags?: [...string]
+		...
+	}
+	// LangChain version compatibility (e.g., 0.3.0)
+	version?: string & =~"^[0-9]+\\.[0-9]+\\.[0-9]+$"
+	...
+}
+
+// LangFlow visual flow builder integration - enables bidirectional mapping between LangFlow flows and OSSA manifests
+#LangFlowExtension: {
+	// LangFlow API configuration
+	api_endpoint?: {
+		// Environment variable containing API key
+		api_key_env?: string & =~"^[A-Z][A-Z0-9_]*$" & (*"LANGFLOW_API_KEY" | _)
+		// Authentication method
+		auth_method?: *"api_key" | "bearer_token" | "none"
+		// LangFlow server base URL
+		base_url!: string
+		// Custom endpoint name (if configured in LangFlow)
+		custom_endpoint?: string
+		// API request timeout in seconds
+		timeout_seconds?: int & >=1 & <=600 & (*120 | _)
+	}
+	// LangFlow component mappings to OSSA capabilities
+	components?: [...{
+		// LangFlow component type
+		component_type!: "ChatInput" | "ChatOutput" | "TextInput" | "TextOutput" | "OpenAIModel" | "AnthropicModel" | "OllamaModel" | "Agent" | "Tool" | "VectorStore" | "Retriever" | "Memory" | "Prompt" | "Parser" | "Chain" | "Embeddings" | "Document" | "Custom"
+		// Component-specific configuration
+		config?: {...}
+		// LangFlow node ID (format: ComponentType-UUID)
+		node_id!: string & =~"^[A-Za-z]+-[A-Za-z0-9]+$"
+		// Mapped OSSA capability identifier
+		ossa_capability?: string & =~"^[a-z][a-z0-9_]*$"
+	}]
+	// Flow execution mode: sync (blocking), async (non-blocking), stream (SSE), batch (parallel)
+	execution_mode?: *"sync" | "async" | "stream" | "batch"
+	// LangFlow flow UUID (from flow URL or API)
+	flow_id!: string
+	// Map OSSA input_schema fields to LangFlow inputs
+	input_mapping?: {
+		// Map OSSA input fields to component parameters
+		field_mappings?: {
+			[string]: {
+				// Target component ID
+				component!: string
+				// Component parameter name
+				parameter!: string
+			}
+		}
+		// Component IDs to receive input
+		target_components?: [...string]
+	}
+	// OpenAPI specification for flow endpoints
+	openapi_spec?: {
+		// Enable OpenAPI spec generation/discovery
+		enabled?: bool & (*true | _)
+		// URL to generated OpenAPI spec
+		spec_url?: string
+		// Generate Zod validation schemas from OpenAPI
+		zod_schema?: bool & (*true | _)
+	}
+	// Map LangFlow outputs to OSSA output_schema
+	output_mapping?: {
+		// Map component outputs to OSSA output fields
+		field_mappings?: {
+			[string]: {
+				// Source component ID
+				component!: string
+				// Component output field name
+				output_field?: string
+			}
+		}
+		// Component IDs to extract output from
+		source_components?: [...string]
+	}
+	// Map flow variables to OSSA state
+	state_persistence?: {
+		// Memory component ID for conversation history
+		memory_component?: string
+		// Persist outputs across invocations
+		persist_outputs?: bool & (*true | _)
+		// Input field containing session identifier
+		session_id_field?: string & (*"session_id" | _)
+	}
+	// Runtime parameter overrides for flow execution. Keys are parameter names or component IDs.
+	tweaks?: {
+		[string]: string | number | bool | {...}
+	}
+	// Runtime validation configuration
+	validation?: {
+		// Validate against OpenAPI spec
+		openapi_validation?: bool & (*false | _)
+		// Validate inputs against OSSA input_schema
+		validate_inputs?: bool & (*true | _)
+		// Validate outputs against OSSA output_schema
+		validate_outputs?: bool & (*true | _)
+		// Use Zod for runtime validation
+		zod_runtime?: bool & (*true | _)
+	}
+}
+
+// LangGraph state machine configuration
+#LangGraphConfig: {
+	// State persistence configuration (MemorySaver, SqliteSaver, etc.)
+	checkpointer?: {
+		// Connection string for persistent checkpointers
+		connection_string?: string
+		// Checkpointer backend type
+		type?: "memory" | "sqlite" | "postgres" | "redis"
+		...
+	}
+	// Graph edges (transitions)
+	edges?: [...{
+		// Condition function reference for conditional edges
+		condition?: string
+		// Map of condition values to target nodes
+		condition_map?: {
+			[string]: string
+		}
+		// Source node ID or START
+		from!: string
+		// Target node ID or END
+		to!: string
+		...
+	}]
+	// Entry point node ID
+	entrypoint?: string & (*"START" | _)
+	// Nodes to interrupt after
+	interrupt_after?: [...string]
+	// Nodes to interrupt before (human-in-the-loop)
+	interrupt_before?: [...string]
+	// Graph identifier
+	name!: string & =~"^[a-z][a-z0-9_-]*$"
+	// Graph nodes
+	nodes!: [...{
+		// Handler function/class reference
+		handler?: string
+		// Node identifier
+		id!: string
+		// Reference to OSSA Task or Agent manifest
+		ossa_ref?: string
+		// Node type
+		type!: "agent" | "tool" | "function" | "subgraph" | "passthrough" | "conditional" | "human_in_loop"
+		...
+	}]
+	// TypedDict/Pydantic state schema
+	state_schema?: #JSONSchemaDefinition
+	...
+}
+
+// LangGraph state machine and graph orchestration mapping
+#LangGraphExtension: {
+	// State persistence configuration
+	checkpointer?: {
+		connection_string?: string
+		thread_id_key?: string
+		type?: "memory" | "sqlite" | "postgres" | "redis"
+		...
+	}
+	// Conditional branching edges
+	conditional_edges?: [...{
+		condition_function?: string
+		from?: string
+		path_map?: {
+			[string]: string
+		}
+		...
+	}]
+	debug?: bool & (*false | _)
+	// Graph edges defining flow
+	edges?: [...{
+		// Conditional edge function reference
+		condition?: string
+		from!: string
+		to!: string
+		...
+	}]
+	enabled?: bool & (*false | _)
+	// Graph entry node
+	entry_point?: string
+	// Graph finish node (END)
+	finish_point?: string
+	// LangGraph graph type
+	graph_type?: *"StateGraph" | "MessageGraph" | "CompiledGraph"
+	// Nodes to interrupt after
+	interrupt_after?: [...string]
+	// Nodes to interrupt before for human-in-the-loop
+	interrupt_before?: [...string]
+	// Graph nodes (mapped from workflow steps)
+	nodes?: [...{
+		// OSSA agent reference
+		agent_ref?: string
+		// Python function reference
+		function_ref?: string
+		name!: string
+		retry_policy?: {
+			backoff_factor?: number
+			max_attempts?: int
+			...
+		}
+		type?: "function" | "agent" | "tool" | "subgraph" | "conditional"
+		...
+	}]
+	// Maximum recursion depth
+	recursion_limit?: int & (*25 | _)
+	// TypedDict state schema definition
+	state_schema?: {
+		fields?: {
+			[string]: {
+				default?: _
+				reducer?: "replace" | "append" | "merge"
+				type?: string
+				...
+			}
+		}
+		name?: string
+		...
+	}
+	...
+}
+
+// LlamaIndex extension configuration for data-aware AI applications
+#LlamaIndexExtension: {
+	// LlamaIndex agent type
+	agent_type?: *"openai" | "react" | "structured_planner" | "function_calling" | "multi_document" | "sub_question"
+	// Callback configuration (mapped from spec.observability)
+	callbacks?: {
+		handlers?: [...{
+			config?: {...}
+			type?: "llama_debug" | "wandb" | "arize_phoenix" | "langfuse" | "opentelemetry"
+			...
+		}]
+		...
+	}
+	// Embedding model configuration
+	embed_model?: {
+		embed_batch_size?: int & >=1 & (*10 | _)
+		// Embedding model name
+		model_name?: string
+		// Embedding model type
+		type?: "openai" | "huggingface" | "cohere" | "bedrock" | "local"
+		...
+	}
+	// Enable LlamaIndex integration
+	enabled?: bool & (*false | _)
+	// Index configuration for RAG
+	index?: {
+		storage_context?: {
+			docstore?: {...}
+			index_store?: {...}
+			persist_dir?: string
+			...
+		}
+		type?: *"vector" | "list" | "tree" | "keyword" | "knowledge_graph" | "document_summary"
+		vector_store?: {
+			collection_name?: string
+			connection_string?: string
+			type?: "simple" | "faiss" | "chroma" | "pinecone" | "qdrant" | "weaviate" | "milvus"
+			...
+		}
+		...
+	}
+	// LLM configuration (mapped from spec.llm)
+	llm?: {
+		// Additional LLM parameters
+		additional_kwargs?: {...}
+		max_tokens?: int & >=1
+		// Model identifier
+		model?: string
+		temperature?: number & >=0 & <=2
+		// LLM provider type
+		type?: "openai" | "anthropic" | "huggingface" | "azure_openai" | "ollama" | "bedrock"
+		...
+	}
+	// Chat memory configuration (mapped from spec.state)
+	memory?: {
+		chat_store?: {
+			connection_string?: string
+			type?: "simple" | "redis" | "dynamodb"
+			...
+		}
+		token_limit?: int & >=1
+		type?: *"buffer" | "summary" | "buffer_window" | "entity"
+		...
+	}
+	// Query engine configuration
+	query_engine?: {
+		response_mode?: "refine" | *"compact" | "tree_summarize" | "simple_summarize" | "accumulate" | "compact_accumulate"
+		response_synthesizer?: {
+			refine_template?: string
+			text_qa_template?: string
+			type?: string
+			...
+		}
+		streaming?: bool & (*false | _)
+		type?: "simple" | "citation" | "sub_question" | "router" | "multi_step" | "flare"
+		...
+	}
+	// Retriever configuration
+	retriever?: {
+		node_postprocessors?: [...{
+			config?: {...}
+			type?: "similarity_cutoff" | "keyword" | "metadata" | "cohere_rerank" | "llm_rerank"
+			...
+		}]
+		similarity_top_k?: int & >=1 & (*5 | _)
+		type?: "vector_index" | "bm25" | "hybrid" | "auto_merging" | "recursive"
+		...
+	}
+	// Global service context configuration
+	service_context?: {
+		chunk_overlap?: int & >=0 & (*20 | _)
+		chunk_size?: int & >=1 & (*1024 | _)
+		context_window?: int & >=1
+		num_output?: int & >=1
+		...
+	}
+	// LlamaIndex tools (mapped from spec.tools)
+	tools?: [...{
+		description?: string
+		// Function schema for tool
+		fn_schema?: {...}
+		name!: string
+		return_direct?: bool & (*false | _)
+		type?: "function" | "query_engine" | "retriever" | "custom"
+		...
+	}]
+	...
+}
+
+// llms.txt extension for bidirectional markdown/OSSA conversion - enables generation, parsing, synchronization, and validation between llms.txt files and OSSA manifests
+#LlmsTxtExtension: {
+	// Auto-discover agents from llms.txt sections
+	auto_discover?: bool & (*false | _)
+	// Enable llms_txt extension
+	enabled?: bool & (*false | _)
+	// Path to llms.txt file (relative to repository root)
+	file_path?: string & (*"llms.txt" | _)
+	// llms.txt format configuration
+	format?: {
+		// Include blockquote summary
+		include_blockquote?: bool & (*true | _)
+		// Include H1 title
+		include_h1_title?: bool & (*true | _)
+		// Include H2 sections with file lists
+		include_h2_sections?: bool & (*true | _)
+		// Include Optional section for secondary info
+		include_optional?: bool & (*true | _)
+	}
+	// Auto-generate llms.txt from manifest
+	generate?: bool & (*true | _)
+	// Include OSSA metadata in generated llms.txt (usually false for clean output)
+	include_metadata?: bool & (*false | _)
+	// Explicit mapping between OSSA and llms.txt
+	mapping?: {
+		// Map metadata.description to blockquote
+		description_to_blockquote?: bool & (*true | _)
+		// Map examples to Examples section
+		examples_to_examples?: bool & (*true | _)
+		// Map metadata.name to H1 title
+		metadata_to_h1?: bool & (*true | _)
+		// Map migrations to Migration Guides section
+		migrations_to_migration_guides?: bool & (*true | _)
+		// Map spec to Core Specification section
+		spec_to_core_specification?: bool & (*true | _)
+		// Map spec.tools to CLI Tools section
+		tools_to_cli_tools?: bool & (*true | _)
+	}
+	// Section-level configuration for llms.txt
+	sections?: {
+		cli_tools?: #LlmsTxtSection
+		core_specification?: #LlmsTxtSection
+		// Additional custom sections
+		custom?: [...#LlmsTxtSection]
+		development?: #LlmsTxtSection
+		documentation?: #LlmsTxtSection
+		examples?: #LlmsTxtSection
+		migration_guides?: #LlmsTxtSection
+		openapi_specifications?: #LlmsTxtSection
+		optional?: #LlmsTxtSection
+		quick_start?: #LlmsTxtSection
+		sdks?: #LlmsTxtSection
+		specification_versions?: #LlmsTxtSection
+		[string]: #LlmsTxtSection
+	}
+	// Synchronization configuration
+	sync?: {
+		// Include generation comments in output (llms.txt should be clean)
+		include_comments?: bool & (*false | _)
+		// Regenerate llms.txt when manifest changes
+		on_manifest_change?: bool & (*true | _)
+		// Preserve custom sections not mapped to manifest
+		preserve_custom?: bool & (*true | _)
+		// Watch for file changes
+		watch?: bool & (*false | _)
+	}
+}
+
+// Configuration for an individual llms.txt section
+#LlmsTxtSection: {
+	// Content to append after auto-generated content
+	append?: string
+	// Custom markdown content for this section
+	custom?: string
+	// Whether this section is enabled
+	enabled?: bool & (*true | _)
+	// List of files/links to include in this section
+	file_list?: [...string]
+	// Content to prepend before auto-generated content
+	prepend?: string
+	// OSSA manifest path to derive content from
+	source?: string
+	// Override default section title
+	title?: string
+}
+
+// Model Context Protocol (MCP) extension for agents - supports tools, resources, and prompts
+#MCPExtension: {
+	// Whether MCP is enabled for this agent
+	enabled?: bool & (*true | _)
+	// MCP prompts (templated workflows and interactions)
+	prompts?: [...#MCPPrompt]
+	// MCP resources (read-only context/data sources)
+	resources?: [...#MCPResource]
+	// Name of the MCP server
+	server_name?: string
+	// MCP server transport mechanism
+	server_type?: "stdio" | "http" | "sse"
+	// MCP tools (functions/actions the agent can invoke)
+	tools?: [...#MCPTool]
+}
+
+// MCP prompt definition - templated messages and workflows
+#MCPPrompt: {
+	// Template arguments that can be substituted
+	arguments?: [...#MCPPromptArgument]
+	// Human-readable description of the prompt purpose
+	description?: string
+	// Unique prompt identifier
+	name!: string
+}
+
+// Argument definition for MCP prompts
+#MCPPromptArgument: {
+	// Description of what this argument represents
+	description?: string
+	// Argument name
+	name!: string
+	// Whether this argument is required
+	required?: bool & (*false | _)
+}
+
+// MCP resource definition - read-only context and data sources
+#MCPResource: {
+	// Description of the resource and its contents
+	description?: string
+	// Additional resource metadata
+	metadata?: {...}
+	// MIME type of the resource content
+	mimeType?: string
+	// Human-readable resource name
+	name!: string
+	// Unique resource identifier (URI)
+	uri!: string
+}
+
+// MCP tool definition - actions/functions the agent can invoke
+#MCPTool: {
+	// Human-readable description of what the tool does
+	description?: string
+	// JSON Schema for tool input parameters (camelCase - MCP SDK convention)
+	inputSchema?: #JSONSchemaDefinition
+	// JSON Schema for tool input parameters (snake_case)
+	input_schema?: #JSONSchemaDefinition
+	// Unique tool name
+	name!: string
+	...
+}
+
+// Agent-to-agent messaging configuration (v0.3.3+)
+#MessagingExtension: {
+	// Message broker configuration
+	broker?: {
+		// Broker type
+		type?: "redis" | "nats" | "memory" | "kafka"
+		// Broker connection URL
+		url?: string
+		...
+	}
+	// Commands this agent accepts (RPC-style operations)
+	commands?: [...#Command]
+	// Agent mesh endpoint URL for A2A communication
+	mesh_endpoint?: string
+	// Channels this agent publishes to
+	publishes?: [...#PublishedChannel]
+	// Message reliability configuration
+	reliability?: #ReliabilityConfig
+	// Channels this agent subscribes to
+	subscribes?: [...#Subscription]
+}
+
+// MetaGPT multi-agent software team mapping configuration
+#MetaGPTExtension: {
+	// Action configuration
+	actions?: {
+		debug_error?: bool
+		run_code?: bool
+		search_and_summarize?: bool
+		write_code?: bool
+		write_code_review?: bool
+		write_design?: bool
+		write_tasks?: bool
+		write_test?: bool
+		...
+	}
+	// Company/team configuration
+	company?: {
+		// Product idea/requirement
+		idea?: string
+		// Budget in dollars
+		investment?: number
+		// Company name
+		name?: string
+		...
+	}
+	// Context configuration
+	context?: {
+		code_review?: bool & (*true | _)
+		max_budget?: number
+		src_workspace?: string
+		...
+	}
+	enabled?: bool & (*false | _)
+	// Execution environment
+	environment?: {
+		git_reinit?: bool & (*true | _)
+		inc?: bool & (*false | _)
+		project_name?: string
+		reqa_file?: string
+		workspace_path?: string
+		...
+	}
+	// LLM configuration (mapped from spec.llm)
+	llm?: {
+		api_key_env?: string
+		base_url?: string
+		model?: string
+		provider?: "openai" | "anthropic" | "azure" | "zhipuai" | "ollama" | "fireworks" | "open_llm" | "gemini" | "ark" | "qianfan" | "dashscope" | "spark" | "moonshot"
+		...
+	}
+	// Output configuration
+	output?: {
+		code_path?: string
+		docs_path?: string
+		resources_path?: string
+		...
+	}
+	// Repair/debug configuration
+	repair?: {
+		enabled?: bool & (*true | _)
+		max_auto_fix?: int & (*3 | _)
+		...
+	}
+	// Team composition
+	team?: {
+		// Number of collaboration rounds
+		n_round?: int & (*3 | _)
+		// Team roles (mapped from multi-agent spec)
+		roles?: [...{
+			// OSSA agent reference for custom roles
+			agent_ref?: string
+			constraints?: string
+			goal?: string
+			name!: string
+			// MetaGPT role type
+			role_type!: "ProductManager" | "Architect" | "ProjectManager" | "Engineer" | "QAEngineer" | "Researcher" | "DataAnalyst" | "Custom"
+			...
+		}]
+		...
+	}
+	...
+}
+
+#Metadata: {
+	// Arbitrary metadata for tooling
+	annotations?: {
+		[string]: string
+	}
+	// Human-readable description
+	description?: string & strings.MaxRunes(2000)
+	// Key-value labels for organization and filtering
+	labels?: {
+		[string]: string & strings.MaxRunes(63)
+	}
+	// Resource identifier (DNS-1123 subdomain format for Kubernetes compatibility)
+	name!: string & =~"^[a-z0-9]([-a-z0-9]*[a-z0-9])?$" & strings.MaxRunes(253)
+	// Logical grouping namespace (like Kubernetes namespace)
+	namespace?: string & =~"^[a-z0-9]([-a-z0-9]*[a-z0-9])?$" & strings.MaxRunes(63)
+	// Semantic version (semver 2.0.0)
+	version?: string & =~"^(0|[1-9]\\d*)\\.(0|[1-9]\\d*)\\.(0|[1-9]\\d*)(?:-((?:0|[1-9]\\d*|\\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\\.(?:0|[1-9]\\d*|\\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\\+([0-9a-zA-Z-]+(?:\\.[0-9a-zA-Z-]+)*))?$"
+}
+
+// Model price in dollars per million tokens
+#ModelPricing: {
+	input_per_million?: number & >=0
+	output_per_million?: number & >=0
+}
+
+// Content part for multimodal messages
+#OpenAIAssistantContentPart: {
+	// Image file (when type=image_file)
+	image_file?: {
+		// Image detail level
+		detail?: *"auto" | "low" | "high"
+		// File ID of the image
+		file_id?: string
+	}
+	// Image URL (when type=image_url)
+	image_url?: {
+		// Image detail level
+		detail?: *"auto" | "low" | "high"
+		// Image URL
+		url?: string
+	}
+	// Text content (when type=text)
+	text?: string
+	// Content part type
+	type!: "text" | "image_url" | "image_file"
+}
+
+// OpenAI Assistant message definition
+#OpenAIAssistantMessage: {
+	// File attachments
+	attachments?: [...{
+		// File ID to attach
+		file_id?: string
+		tools?: [...{
+			type?: "code_interpreter" | "file_search"
+		}]
+	}]
+	// Message content
+	content!: string | [...#OpenAIAssistantContentPart]
+	// Message metadata (max 16 key-value pairs)
+	metadata?: {
+		[string]: string
+	}
+	// Message role
+	role!: "user" | "assistant"
+}
+
+// OpenAI Assistant tool definition
+#OpenAIAssistantTool: {
+	// Function tool definition (when type=function)
+	function?: {
+		// Function description
+		description?: string & strings.MaxRunes(1024)
+		// Function name
+		name!: string & =~"^[a-zA-Z_][a-zA-Z0-9_]*$" & strings.MaxRunes(64)
+		// JSON Schema for function parameters
+		parameters?: {...}
+		// Enable strict mode for structured outputs
+		strict?: bool & (*false | _)
+	}
+	// Tool type
+	type!: "code_interpreter" | "file_search" | "function"
+}
+
+// OpenAI Assistants API integration extension - enables bidirectional mapping between OSSA agents and OpenAI Assistants
+#OpenAIAssistantsExtension: {
+	// OpenAI API configuration
+	api_config?: {
+		// OpenAI API version
+		api_version?: string & =~"^v[0-9]+(\\.[0-9]+)?$"
+		// OpenAI API base URL
+		base_url?: string & (*"https://api.openai.com/v1" | _)
+		// OpenAI organization ID
+		organization_id?: string & =~"^org-[a-zA-Z0-9]+$"
+		// OpenAI project ID
+		project_id?: string
+		retry?: {
+			// Backoff multiplier for retries
+			backoff_factor?: number & (*2 | _)
+			// Maximum retry attempts
+			max_retries?: int & (*3 | _)
+		}
+		// API request timeout in milliseconds
+		timeout_ms?: int & (*60000 | _)
+	}
+	// OpenAI Assistant ID (e.g., asst_abc123)
+	assistant_id?: string & =~"^asst_[a-zA-Z0-9]+$"
+	// Legacy file IDs (deprecated, use tool_resources)
+	file_ids?: [...string & =~"^file-[a-zA-Z0-9]+$"]
+	// System instructions for the assistant (maps to spec.prompts.system)
+	instructions?: string & strings.MaxRunes(256000)
+	// OpenAI model to use for this assistant
+	model?: "gpt-4o" | "gpt-4o-mini" | "gpt-4-turbo" | "gpt-4-turbo-preview" | "gpt-4" | "gpt-3.5-turbo" | "gpt-3.5-turbo-16k"
+	// Run steps and observability configuration
+	observability?: {
+		// Include token usage in traces
+		include_usage?: bool & (*true | _)
+		// OpenTelemetry exporter type
+		otel_exporter?: *"otlp" | "console" | "none"
+		// Step types to trace
+		step_types?: [..."message_creation" | "tool_calls"]
+		// Enable run step tracing
+		trace_run_steps?: bool & (*true | _)
+	}
+	// Run execution configuration
+	run_config?: {
+		// Maximum completion tokens per run
+		max_completion_tokens?: int & >=256
+		// Maximum prompt tokens per run
+		max_prompt_tokens?: int & >=256
+		// Allow parallel tool execution
+		parallel_tool_calls?: bool & (*true | _)
+		// Response format configuration
+		response_format?: "auto" | "text" | {
+			// JSON Schema for structured output
+			json_schema?: {...}
+			type?: "json_object" | "json_schema"
+		}
+		// Run execution timeout
+		timeout_seconds?: int & >=1 & <=600 & (*300 | _)
+		truncation_strategy?: {
+			// Number of recent messages to keep
+			last_messages?: int & >=1
+			type?: *"auto" | "last_messages"
+		}
+	}
+	// SSE streaming configuration
+	streaming?: {
+		// SSE buffer size in bytes
+		buffer_size?: int & (*4096 | _)
+		// Enable streaming responses
+		enabled?: bool & (*true | _)
+		// Events to stream
+		events?: [..."thread.created" | "thread.run.created" | "thread.run.queued" | "thread.run.in_progress" | "thread.run.requires_action" | "thread.run.completed" | "thread.run.incomplete" | "thread.run.failed" | "thread.run.cancelling" | "thread.run.cancelled" | "thread.run.expired" | "thread.run.step.created" | "thread.run.step.in_progress" | "thread.run.step.delta" | "thread.run.step.completed" | "thread.run.step.expired" | "thread.message.created" | "thread.message.in_progress" | "thread.message.delta" | "thread.message.completed" | "thread.message.incomplete" | "error" | "done"]
+	}
+	// Thread configuration for conversation state
+	thread_config?: {
+		// Automatically create threads for new conversations
+		auto_create?: bool & (*true | _)
+		// Initial messages to populate new threads
+		initial_messages?: [...#OpenAIAssistantMessage]
+		// JSON Schema for thread metadata
+		metadata_schema?: {...}
+		// Thread retention period in days
+		retention_days?: int & >=1 & <=365 & (*30 | _)
+	}
+	// Resources for tools (file_search, code_interpreter)
+	tool_resources?: {
+		code_interpreter?: {
+			// File IDs for code interpreter (max 20)
+			file_ids?: list.MaxItems(20) & [...string & =~"^file-[a-zA-Z0-9]+$"]
+		}
+		file_search?: {
+			// Vector store IDs for file search (max 1)
+			vector_store_ids?: list.MaxItems(1) & [...string & =~"^vs_[a-zA-Z0-9]+$"]
+		}
+	}
+	// OpenAI tools configuration
+	tools?: [...#OpenAIAssistantTool]
+}
+
+// OpenAI Swarm multi-agent framework extension - enables OSSA agents to operate within Swarm's handoff-based orchestration model
+#OpenAISwarmExtension: {
+	// Swarm agent definitions for multi-agent manifests
+	agents?: [...#SwarmAgentConfig]
+	// Shared context variable definitions
+	context_variables?: #SwarmContextVariables
+	// Enable Swarm compatibility mode
+	enabled?: bool & (*false | _)
+	// Handoff behavior configuration
+	handoff_config?: #SwarmHandoffConfig
+	// Run loop configuration
+	run_config?: #SwarmRunConfig
+	...
+}
+
+// Phidata agent mapping configuration
+#PhidataExtension: {
+	agent_type?: *"Agent" | "Assistant" | "Team"
+	builtin_tools?: [..."DuckDuckGo" | "Newspaper4k" | "Arxiv" | "PubMed" | "Wikipedia" | "Exa" | "Tavily" | "Firecrawl" | "Calculator" | "File" | "Shell" | "Sleep" | "Yfinance" | "Email" | "Apify" | "Zendesk" | "Jira" | "Linear" | "Slack" | "Discord" | "Zoom"]
+	debug_mode?: bool & (*false | _)
+	enabled?: bool & (*false | _)
+	// Agent instructions (mapped from spec.role)
+	instructions?: [...string]
+	// Knowledge base configuration
+	knowledge?: {
+		sources?: [...string]
+		type?: "PDFKnowledgeBase" | "PDFUrlKnowledgeBase" | "TextKnowledgeBase" | "JSONKnowledgeBase" | "CSVKnowledgeBase" | "DocxKnowledgeBase" | "CombinedKnowledgeBase" | "WebsiteKnowledgeBase" | "WikipediaKnowledgeBase" | "ArxivKnowledgeBase"
+		vector_db?: {
+			collection?: string
+			connection?: string
+			type?: "PgVector" | "Pinecone" | "Qdrant" | "Chroma" | "LanceDb" | "Weaviate"
+			...
+		}
+		...
+	}
+	markdown?: bool & (*true | _)
+	// Memory configuration (mapped from spec.state)
+	memory?: {
+		create_session_summary?: bool
+		create_user_memories?: bool
+		db?: {
+			connection?: string
+			type?: "PostgresMemoryDb" | "SqliteMemoryDb"
+			...
+		}
+		...
+	}
+	// Model configuration (mapped from spec.llm)
+	model?: {
+		api_key_env?: string
+		id?: string
+		// Phidata model class
+		type?: "OpenAIChat" | "Anthropic" | "Gemini" | "Groq" | "Cohere" | "Ollama" | "Together" | "Mistral" | "AWS Bedrock" | "Azure"
+		...
+	}
+	// Enable reasoning mode
+	reasoning?: bool & (*false | _)
+	show_tool_calls?: bool & (*false | _)
+	// Session storage configuration
+	storage?: {
+		connection?: string
+		table_name?: string
+		type?: "PostgresAgentStorage" | "SqliteAgentStorage" | "MongoAgentStorage" | "S3AgentStorage" | "YamlAgentStorage" | "JsonAgentStorage"
+		...
+	}
+	// Team configuration for multi-agent
+	team?: {
+		agents?: [...{
+			agent_ref?: string
+			name?: string
+			role?: string
+			...
+		}]
+		mode?: "route" | "collaborate" | "coordinate"
+		...
+	}
+	// Tool definitions (mapped from spec.tools)
+	tools?: [...{
+		function_ref?: string
+		name?: string
+		toolkit_class?: string
+		type?: "builtin" | "toolkit" | "function"
+		...
+	}]
+	...
+}
+
+// Channel that an agent publishes messages to
+#PublishedChannel: {
+	// Channel name (e.g., 'security.vulnerabilities')
+	channel!: string & =~"^[a-z][a-z0-9_]*(\\.[a-z][a-z0-9_]*)*$"
+	// Content type of messages
+	contentType?: string & (*"application/json" | _)
+	// Human-readable description of the channel
+	description?: string
+	// Example messages
+	examples?: [...{...}]
+	// JSON Schema for published messages
+	schema!: {...}
+	// Tags for categorization
+	tags?: [...string]
+}
+
+// Pydantic AI agent mapping configuration
+#PydanticAIExtension: {
+	// Custom Agent class name
+	agent_class?: string
+	defer_model_check?: bool & (*false | _)
+	// Dependencies type for dependency injection
+	deps_type?: {
+		fields?: {
+			[string]: {
+				description?: string
+				type?: string
+				...
+			}
+		}
+		name?: string
+		...
+	}
+	enabled?: bool & (*false | _)
+	end_strategy?: *"early" | "exhaustive"
+	// Instrumentation configuration (mapped from spec.observability)
+	instrument?: {
+		logfire?: bool
+		opentelemetry?: bool
+		...
+	}
+	// Model configuration (mapped from spec.llm)
+	model?: {
+		api_key_env?: string
+		base_url?: string
+		model_name?: string
+		// Pydantic AI model provider
+		type?: "openai" | "anthropic" | "gemini" | "groq" | "mistral" | "ollama" | "test"
+		...
+	}
+	// Retries for result validation
+	result_retries?: int
+	// Pydantic model for structured output
+	result_type?: {
+		fields?: {
+			[string]: {
+				default?: _
+				description?: string
+				type?: string
+				...
+			}
+		}
+		name?: string
+		...
+	}
+	// Result validation functions
+	result_validators?: [...{
+		function_ref?: string
+		...
+	}]
+	// Default retry count
+	retries?: int & (*1 | _)
+	// System prompt (mapped from spec.role)
+	system_prompt?: string
+	// Tool definitions (mapped from spec.tools)
+	tools?: [...{
+		description?: string
+		function_ref?: string
+		name!: string
+		prepare_ref?: string
+		retries?: int
+		...
+	}]
+	...
+}
+
+// Message delivery reliability configuration
+#ReliabilityConfig: {
+	// Message acknowledgment configuration
+	acknowledgment?: {
+		// Acknowledgment mode
+		mode?: "manual" | *"automatic"
+		// Acknowledgment timeout in seconds
+		timeoutSeconds?: int & >=1 & (*30 | _)
+	}
+	// Message delivery guarantee (alias for deliveryGuarantee)
+	delivery?: "at-least-once" | "at-most-once" | "exactly-once" | "at_least_once" | "at_most_once" | "exactly_once"
+	// Message delivery guarantee
+	deliveryGuarantee?: *"at-least-once" | "at-most-once" | "exactly-once" | "at_least_once" | "at_most_once" | "exactly_once"
+	// Dead letter queue configuration
+	dlq?: {
+		// Dead letter queue channel name
+		channel?: string
+		// Enable dead letter queue
+		enabled?: bool & (*false | _)
+		// Message retention in days
+		retentionDays?: int & >=1 & <=90 & (*7 | _)
+	}
+	// Message ordering configuration
+	ordering?: {
+		// Ordering guarantee level
+		guarantee?: *"per-source" | "global"
+		// Ordering mode (alias for guarantee)
+		mode?: "global" | "per_channel" | "per-channel" | "none"
+		// Ordering timeout in seconds
+		timeoutSeconds?: int & >=1
+		...
+	}
+	// Retry configuration
+	retry?: {
+		// Backoff strategy configuration
+		backoff?: {
+			// Initial delay in milliseconds
+			initialDelayMs?: int & >=0 & (*1000 | _)
+			// Maximum delay in milliseconds
+			maxDelayMs?: int & >=0 & (*60000 | _)
+			// Backoff multiplier
+			multiplier?: number & >=1 & (*2 | _)
+			// Backoff strategy
+			strategy?: *"exponential" | "linear" | "constant"
+		}
+		// Maximum retry attempts
+		maxAttempts?: int & >=0 & <=10 & (*3 | _)
+	}
+	...
+}
+
+// Compute resource constraints (Kubernetes-style)
+#ResourceLimits: {
+	// CPU limit in millicores (1000 = 1 CPU)
+	cpu_millicores?: int & >=100
+	// Requires GPU acceleration
+	gpu_required?: bool & (*false | _)
+	// Required GPU type (e.g., nvidia-a100, nvidia-h100)
+	gpu_type?: string
+	// Memory limit in megabytes
+	memory_mb?: int & >=64
+}
+
+// Constrains the response to text, any JSON object or JSON matching a schema
+#ResponseFormat: {
+	// Schema name, as OpenAI requires; defaults to response
+	name?: string & =~"^[a-zA-Z0-9_-]{1,64}$"
+	// JSON Schema the response must match
+	schema?: {...}
+	// Enforce the schema exactly, where the provider supports it
+	strict?: bool
+	type!: "text" | "json_object" | "json_schema"
+}
+
+// Retry and backoff configuration for LLM calls
+#RetryConfig: {
+	// Backoff strategy between retries
+	backoff_strategy?: "none" | "linear" | *"exponential"
+	// Initial delay in milliseconds before first retry
+	initial_delay_ms?: int & >=0 & (*1000 | _)
+	// Maximum number of retry attempts
+	max_attempts?: int & >=1 & <=10 & (*3 | _)
+	// Maximum delay between retries
+	max_delay_ms?: int & >=0 & (*30000 | _)
+}
+
+// Order in which an agent's model and its fallbacks are tried
+#RoutingConfig: {
+	// failover tries the model then its fallbacks in order; cost-optimized the cheapest by pricing first; latency-optimized the fastest so far first
+	policy?: *"failover" | "cost-optimized" | "latency-optimized"
+	// Per-call timeout; a call taking longer counts as failing
+	timeout_ms?: int & >=1
+}
+
+// Multi-runtime compatibility declaration
+#RuntimeBinding: {
+	// Map of capability names to runtime-specific handlers
+	bindings?: {
+		[string]: {
+			// Additional binding configuration
+			config?: {...}
+			// Handler class/function (e.g., 'Drupal\node\NodeQuery::getList')
+			handler?: string
+			// MCP server name for MCP-based bindings
+			mcp_server?: string
+			// Tool name within MCP server
+			tool?: string
+		}
+	}
+	// External runtime extensions
+	extensions?: [...#RuntimeExtension]
+	// Kubernetes-specific configuration (KAS-inspired)
+	kubernetes?: #KubernetesConfig
+	// Compute resource constraints
+	resource_limits?: #ResourceLimits
+	// Agent scheduling configuration
+	scheduling?: #SchedulingConfig
+	// List of compatible runtimes
+	supports?: [..."google-a2a" | "gitlab-duo" | "ossa-mesh" | "mcp" | "local-execution" | "kubernetes" | "serverless" | "lambda" | "cloudflare-workers" | "drupal" | "symfony"]
+	// Message transport for async runtimes
+	transport?: string
+	// Primary runtime type
+	type?: *"unified" | "google-a2a" | "gitlab-duo" | "ossa-mesh" | "mcp" | "local" | "drupal" | "symfony_messenger" | "kagent" | "temporal" | "node"
+}
+
+// External runtime extension (A2A compatible)
+#RuntimeExtension: {
+	// Reference to credentials for authentication
+	credentials_ref?: string
+	// Extension endpoint URL
+	endpoint?: string
+	// Extension name
+	name!: string
+	// Extension protocol type
+	type!: "http" | "grpc" | "mcp" | "websocket" | "kafka" | "pubsub"
+}
+
+// Agent safety and security configuration
+#Safety: {
+	// Content filtering for inputs/outputs
+	content_filtering?: {
+		// Action when content matches
+		action?: "warn" | "block" | "redact"
+		// Categories to filter (e.g., pii, credentials)
+		categories?: [...string]
+		enabled?: bool
+		// Filtering sensitivity
+		threshold?: "low" | "medium" | "high"
+		...
+	}
+	// Guardrails for agent behavior
+	guardrails?: {
+		enabled?: bool
+		// Maximum execution time
+		max_execution_time_seconds?: int
+		// Maximum tool calls per turn
+		max_tool_calls?: int
+		// Policy definitions
+		policies?: [...{...}]
+		...
+	}
+	// Human-in-the-loop configuration
+	human_in_loop?: {
+		enabled?: bool
+		// Conditions that require human approval
+		triggers?: [...string]
+		...
+	}
+	// PII detection and handling
+	pii_detection?: {
+		// Action when PII detected
+		action?: "warn" | "block" | "redact"
+		enabled?: bool
+		// PII types to detect (email, phone, ssn, api_key, etc.)
+		types?: [...string]
+		...
+	}
+	// Rate limiting configuration
+	rate_limiting?: {
+		burst_limit?: int
+		enabled?: bool
+		requests_per_minute?: int
+		...
+	}
+	...
+}
+
+// Agent scheduling configuration
+#SchedulingConfig: {
+	// Maximum concurrent executions
+	max_concurrent?: int & >=1 & (*10 | _)
+	// Execution priority
+	priority?: "critical" | "high" | *"normal" | "low" | "background"
+	// Scheduling strategy
+	strategy?: *"fair" | "priority" | "deadline" | "cost-optimized"
+	// Execution timeout in seconds
+	timeout_seconds?: int & >=1 & (*300 | _)
+}
+
+// AI service connectors configuration
+#SemanticKernelConnectors: {
+	// Audio to text (speech-to-text) service connector
+	audio_to_text?: #SemanticKernelServiceConnector
+	// Chat completion service connector
+	chat_completion?: #SemanticKernelServiceConnector
+	// Image generation service connector
+	image_generation?: #SemanticKernelServiceConnector
+	// Text embedding service connector
+	text_embedding?: #SemanticKernelServiceConnector
+	// Text to audio (text-to-speech) service connector
+	text_to_audio?: #SemanticKernelServiceConnector
+}
+
+// Microsoft Semantic Kernel integration extension for OSSA v0.3.3. Provides bidirectional mapping between Semantic Kernel constructs (Kernel, Plugins, Functions, Planners, Memory, Connectors, Filters, Agents) and OSSA primitives.
+#SemanticKernelExtension: {
+	// Semantic Kernel Agent configuration (ChatCompletionAgent, OpenAIAssistantAgent)
+	agent_config?: {
+		// Agent description
+		description?: string
+		execution_settings?: {
+			// Enable code interpreter (OpenAI Assistants)
+			enable_code_interpreter?: bool & (*false | _)
+			// Enable file search (OpenAI Assistants)
+			enable_file_search?: bool & (*false | _)
+			// Maximum agent loop iterations
+			max_iterations?: int & >=1 & <=100 & (*10 | _)
+			// Tool selection mode
+			tool_choice?: *"auto" | "required" | "none"
+		}
+		// Agent system instructions (alternative to spec.role)
+		instructions?: string
+		// Agent display name
+		name?: string
+		// Agent implementation type
+		type?: *"chat_completion" | "openai_assistant" | "azure_assistant"
+	}
+	// AI service connectors (chat completion, embeddings, image generation, audio)
+	connectors?: #SemanticKernelConnectors
+	// Enable Semantic Kernel runtime integration
+	enabled?: bool & (*true | _)
+	// Function, prompt, and auto-invocation filters for safety and observability
+	filters?: #SemanticKernelFilters
+	// Semantic memory store configuration
+	memory_store?: #SemanticKernelMemoryStore
+	// Planner-specific configuration options
+	planner_options?: {
+		// Allow looping in plans (stepwise planner)
+		allow_loops?: bool & (*false | _)
+		// Function names to exclude from planning (format: PluginName.FunctionName)
+		excluded_functions?: [...string]
+		// Plugin names to exclude from planning
+		excluded_plugins?: [...string]
+		// Maximum planner iterations
+		max_iterations?: int & >=1 & <=100 & (*10 | _)
+		// Maximum tokens for planner operations
+		max_tokens?: int & >=100 & <=128000 & (*4096 | _)
+		// Semantic memory configuration for planners
+		semantic_memory_config?: {
+			// Maximum memory results to retrieve
+			max_results?: int & >=1 & <=100 & (*5 | _)
+			// Minimum relevance score for memory retrieval
+			relevance_threshold?: number & >=0 & <=1 & (*0.7 | _)
+		}
+	}
+	// Planner strategy for goal decomposition and task orchestration
+	planner_type?: "sequential" | "stepwise" | "action" | "handlebars" | *"function_calling" | "none"
+	// Semantic Kernel plugins to load (native, semantic, OpenAPI, gRPC)
+	plugins?: [...#SemanticKernelPlugin]
+	// OpenTelemetry configuration for Semantic Kernel
+	telemetry?: {
+		// OpenTelemetry activity source name
+		activity_source_name?: string & (*"Microsoft.SemanticKernel" | _)
+		// Enable telemetry collection
+		enabled?: bool & (*true | _)
+		// Log function invocations
+		log_function_invocations?: bool & (*true | _)
+		// Log function results (caution: may contain sensitive data)
+		log_function_results?: bool & (*false | _)
+		// Log prompt template content (caution: may contain sensitive data)
+		log_prompt_template_content?: bool & (*false | _)
+		// OpenTelemetry meter name
+		meter_name?: string & (*"Microsoft.SemanticKernel" | _)
+	}
+}
+
+// Individual filter configuration
+#SemanticKernelFilter: {
+	// Filter-specific configuration
+	config?: {...}
+	// Handler class reference (e.g., Namespace.ClassName)
+	handler!: string
+	// Filter name (unique identifier)
+	name!: string
+	// Stop execution if filter matches (for auto-invoke filters)
+	terminate_on_match?: bool & (*false | _)
+	// Filter execution point
+	type?: "pre" | "post" | *"both" | "pre_render" | "post_render"
+}
+
+// Semantic Kernel filter configuration for safety and observability
+#SemanticKernelFilters: {
+	// Auto function invocation filters
+	auto_invoke_filters?: [...#SemanticKernelFilter]
+	// Pre/post function execution filters
+	function_filters?: [...#SemanticKernelFilter]
+	// Prompt rendering filters
+	prompt_filters?: [...#SemanticKernelFilter]
+}
+
+// Semantic memory store configuration
+#SemanticKernelMemoryStore: {
+	// Collection/index name for the memory store
+	collection?: string
+	// Connection configuration
+	connection?: {
+		// Reference to secret containing API key
+		api_key_ref?: string
+		// Service endpoint URL
+		endpoint?: string
+		// Backend-specific options
+		options?: {...}
+	}
+	// Memory store backend type
+	type?: *"volatile" | "azure_cognitive_search" | "qdrant" | "chroma" | "pinecone" | "redis" | "postgres" | "sqlite"
+}
+
+// Semantic Kernel plugin definition
+#SemanticKernelPlugin: {
+	// Authentication for OpenAPI/gRPC plugins
+	authentication?: {
+		// Custom header name for API key auth
+		header_name?: string
+		// Reference to secret containing authentication token
+		token_ref?: string
+		type?: *"none" | "bearer" | "api_key" | "oauth2" | "basic"
+	}
+	// Functions provided by this plugin
+	functions?: [...{
+		// Human-readable function description
+		description?: string
+		// Function name
+		name!: string
+		// Function parameters (JSON Schema)
+		parameters?: {...}
+		// Return type (string, number, object, etc.)
+		returnType?: string
+	}]
+	// Plugin name (unique identifier)
+	name!: string
+	// Plugin source: assembly name, directory path, or URL
+	source?: string
+	// Plugin type: native (C#/Python class), semantic (prompt-based), openapi (OpenAPI spec), grpc (gRPC service)
+	type?: *"native" | "semantic" | "openapi" | "grpc"
+}
+
+// AI service connector configuration
+#SemanticKernelServiceConnector: {
+	// Reference to secret containing API key
+	api_key_ref?: string
+	// Azure OpenAI deployment name
+	deployment_name?: string
+	// Embedding vector dimensions (for embedding models)
+	dimensions?: int & >=1
+	// Service endpoint URL
+	endpoint?: string
+	// Model identifier (e.g., gpt-4, text-embedding-ada-002)
+	model_id?: string
+	// Provider-specific options
+	options?: {
+		// Maximum tokens to generate
+		max_tokens?: int & >=1
+		// Sampling temperature
+		temperature?: number & >=0 & <=2
+		// Nucleus sampling parameter
+		top_p?: number & >=0 & <=1
+		...
+	}
+	// AI service provider
+	provider?: "azure_openai" | "openai" | "huggingface" | "ollama" | "anthropic" | "google" | "mistral"
+	// Service identifier for dependency injection
+	service_id?: string & (*"default" | _)
+}
+
+// Role separation rules for preventing conflicts of interest
+#SeparationOfDuties: {
+	// Roles/tiers this agent can delegate tasks to
+	can_delegate_to?: [...string]
+	// Roles that this agent must NOT also perform (separation of duties)
+	conflicts_with?: [...string]
+	// Specific actions this role must never perform
+	prohibited_actions?: [..."approve" | "merge" | "execute" | "deploy" | "delete" | "modify_production" | "define_policies" | "bypass_approvals"]
+	// Primary role of this agent
+	role?: "analyzer" | "auditor" | "scanner" | "reviewer" | "monitor" | "generator" | "scaffolder" | "documenter" | "test_writer" | "deployer" | "operator" | "executor" | "maintainer" | "governor" | "policy_definer" | "compliance_officer" | "approver" | "critic" | "remediator" | "enforcer"
+}
+
+// Anthropic/AgentSkills.io compatibility extension - enables OSSA agents to be packaged as Skills
+#SkillsExtension: {
+	// Pre-approved tools list (maps to spec.capabilities). Matches Skills allowed-tools field.
+	allowedTools?: [...string]
+	// Skills directory structure mapping
+	directories?: {
+		// Path to static resources
+		assets?: string
+		// Path to on-demand documentation
+		references?: string
+		...
+	}
+	// Enable Skills format export/import for this agent
+	enabled?: bool & (*false | _)
+	// License for the skill (e.g., Apache-2.0, MIT, GPL-2.0-or-later)
+	license?: string
+	// Compatible AI platforms/environments
+	platforms?: [...string]
+	// Token budget for progressive disclosure stages
+	progressiveDisclosure?: {
+		// Max tokens for instructions stage (full SKILL.md body)
+		instructionsTokens?: int & (*5000 | _)
+		// Max tokens for metadata stage (name + description)
+		metadataTokens?: int & (*100 | _)
+		...
+	}
+	...
+}
+
+// HuggingFace Smolagents mapping configuration
+#SmolagentsExtension: {
+	// Additional Python imports for CodeAgent
+	additional_authorized_imports?: [...string]
+	// Smolagents agent type
+	agent_type?: *"ToolCallingAgent" | "CodeAgent" | "ManagedAgent"
+	// Built-in smolagent tools to enable
+	builtin_tools?: [..."DuckDuckGoSearchTool" | "VisitWebpageTool" | "WikipediaSearchTool" | "PythonInterpreterTool" | "FinalAnswerTool" | "UserInputTool" | "SpeechToTextTool" | "TextToSpeechTool" | "TranslationTool" | "ImageGenerationTool"]
+	enabled?: bool & (*false | _)
+	// Output grammar constraints
+	grammar?: {...}
+	// Sub-agents for ManagedAgent
+	managed_agents?: [...{
+		// OSSA agent reference
+		agent_ref?: string
+		description?: string
+		name?: string
+		...
+	}]
+	max_steps?: int & (*20 | _)
+	// Model configuration (mapped from spec.llm)
+	model?: {
+		kwargs?: {...}
+		model_id?: string
+		token_env?: string
+		// Smolagents model class
+		type?: "HfApiModel" | "TransformersModel" | "LiteLLMModel" | "OpenAIServerModel" | "AzureOpenAIServerModel" | "AmazonBedrockServerModel" | "MLXModel"
+		...
+	}
+	// Steps between planning phases
+	planning_interval?: int
+	// System prompt (mapped from spec.role)
+	system_prompt?: string
+	// Tool definitions (mapped from spec.tools)
+	tools?: [...{
+		description?: string
+		function_ref?: string
+		// HuggingFace Hub tool repo
+		hub_repo_id?: string
+		inputs?: {
+			[string]: {
+				description?: string
+				type?: string
+				...
+			}
+		}
+		name!: string
+		output_type?: string
+		type?: "builtin" | "custom" | "hub" | "langchain" | "mcp" | "space"
+		...
+	}]
+	verbosity_level?: 0 | *1 | 2
+	...
+}
+
+// Agent state management configuration
+#State: {
+	// Context window management for conversation history
+	context_window?: {
+		// Most recent messages never summarized (default 4)
+		keep_messages?: int & >=0
+		// Maximum messages to retain
+		max_messages?: int
+		// Maximum tokens in context
+		max_tokens?: int
+		// Strategy for managing context overflow
+		strategy?: "truncation" | "summarization" | "sliding_window"
+		// Model that summarizes older turns for the summarization strategy, merged into spec.llm; typically a cheaper one
+		summarizer?: #LLMOverride
+		// Share of max_tokens memory may reach before it is summarized (default 0.8)
+		threshold?: number & >=0 & <=1
+		...
+	}
+	// State persistence mode
+	mode?: "stateless" | "session" | "long_running"
+	// State storage configuration
+	storage?: {
+		// Storage-specific configuration
+		config?: {
+			// Storage endpoint URL
+			endpoint?: string
+			// Key prefix for namespacing
+			prefix?: string
+			// Storage provider (e.g., redis, postgres, pinecone)
+			provider?: string
+			...
+		}
+		// Storage encryption configuration
+		encryption?: {
+			// Encryption algorithm (e.g., aes-256-gcm)
+			algorithm?: string
+			enabled?: bool
+			// Reference to encryption key (e.g., env:ENCRYPTION_KEY)
+			keyRef?: string
+			...
+		}
+		// Data retention period (e.g., '30d', '1h')
+		retention?: string
+		// Storage backend type
+		type?: "memory" | "vector-db" | "kv" | "rdbms" | "custom"
+		...
+	}
+	...
+}
+
+// Channel subscription configuration
+#Subscription: {
+	// Channel name to subscribe to
+	channel!: string & =~"^[a-z][a-z0-9_]*(\\.[a-z][a-z0-9_]*)*$"
+	// Human-readable description
+	description?: string
+	// Message filter configuration - supports JSONPath-style filtering
+	filter?: {
+		// Filter expression
+		expression?: string
+		// Field-based filters
+		fields?: {...}
+		...
+	}
+	// Handler function name
+	handler?: string
+	// Maximum concurrent message processing
+	maxConcurrency?: int & >=1
+	// Message priority
+	priority?: "low" | *"normal" | "high" | "critical"
+	// Expected message schema
+	schema?: {...}
+}
+
+// Swarm agent configuration within an OSSA manifest
+#SwarmAgentConfig: {
+	// Whether this is the primary/entry agent
+	is_primary?: bool & (*false | _)
+	// Whether this agent routes to other agents
+	is_router?: bool & (*false | _)
+	// Override the default model for this agent
+	model_override?: string
+	// Agent name identifier
+	name!: string & strings.MinRunes(1) & strings.MaxRunes(128)
+	// Enable parallel tool execution
+	parallel_tool_calls?: bool & (*true | _)
+	// Name of the routing function for router agents
+	routing_function?: string
+	// Tool selection strategy
+	tool_choice?: "auto" | "none" | "required"
+	...
+}
+
+// Swarm context variables configuration mapping to OSSA state
+#SwarmContextVariables: {
+	// Context variable lifecycle configuration
+	lifecycle?: {
+		// Clear context variables when session completes
+		clear_on_complete?: bool & (*false | _)
+		// Inherit context variables on agent handoff
+		inherit_on_handoff?: bool & (*true | _)
+		// Strategy for merging context variable updates
+		merge_strategy?: "shallow_merge" | *"deep_merge" | "replace"
+		...
+	}
+	// Mapping of Swarm context variable names to OSSA state paths
+	mapping?: {
+		[string]: string
+	}
+	// Whether agents can modify context variables
+	mutability?: "read_only" | *"read_write"
+	// How context variables propagate between agents
+	propagation?: *"all_agents" | "handoff_only" | "none"
+	...
+}
+
+// Configuration for agent handoff behavior
+#SwarmHandoffConfig: {
+	// Allow agents to hand off in cycles
+	allow_cycles?: bool & (*false | _)
+	// Explicit handoff definitions
+	handoffs?: [...{
+		// Conditions that trigger this handoff
+		conditions?: [...{
+			// Custom condition expression
+			expression?: string
+			// Condition type
+			type!: "explicit_request" | "capability_mismatch" | "billing_keyword_detected" | "technical_issue_detected" | "custom"
+			...
+		}]
+		// Function name that triggers the handoff
+		function!: string
+		// Target agent to hand off to
+		target_agent!: string
+		...
+	}]
+	// Maximum number of handoffs per session
+	max_handoffs?: int & >=1 & <=100 & (*10 | _)
+	// Preserve context variables during handoff
+	preserve_context?: bool & (*true | _)
+	// Handoff strategy: function_return (Swarm-native), workflow_transition (OSSA workflow), explicit (manual)
+	strategy?: *"function_return" | "workflow_transition" | "explicit"
+	...
+}
+
+// Swarm run loop configuration mapping to OSSA runtime lifecycle
+#SwarmRunConfig: {
+	// Enable debug mode
+	debug?: bool & (*false | _)
+	// Enable dynamic instruction generation from context
+	dynamic_instructions?: bool & (*false | _)
+	// Whether to execute tool calls
+	execute_tools?: bool & (*true | _)
+	// Source for agent instructions
+	instruction_source?: "system_prompt" | "system_prompt_template" | "external"
+	// Lifecycle hooks for run events
+	lifecycle?: {
+		// Handler called when run completes
+		on_complete?: string
+		// Handler called on error
+		on_error?: string
+		// Handler called on agent handoff
+		on_handoff?: string
+		// Handler called when run starts
+		on_start?: string
+		// Handler called on each turn
+		on_turn?: string
+		...
+	}
+	// Maximum conversation turns (maps to spec.behavior.maxIterations)
+	max_turns?: int & >=1 & <=1000 & (*10 | _)
+	// Result and output handling configuration
+	result_handling?: {
+		// Apply context variable updates from results
+		apply_context_updates?: bool & (*true | _)
+		// JSONPath mapping for result fields
+		output_mapping?: {
+			[string]: string
+		}
+		// Parse Result objects for agent handoff
+		parse_agent_handoff?: bool & (*true | _)
+		...
+	}
+	// Enable streaming responses
+	stream?: bool & (*false | _)
+	// Tool execution configuration
+	tool_execution?: {
+		// Maximum retry attempts
+		max_retries?: int & >=0 & <=10 & (*3 | _)
+		// Execute tools in parallel when possible
+		parallel?: bool & (*false | _)
+		// Retry failed tool calls
+		retry_on_failure?: bool & (*true | _)
+		// Timeout per tool execution in seconds
+		timeout_per_tool?: int & >=1 & <=3600 & (*30 | _)
+		...
+	}
+	...
+}
+
+// Specification for deterministic, non-agentic workflow steps (kind: Task)
+#TaskSpec: {
+	// Batch processing configuration
+	batch?: {
+		// Items per batch chunk
+		chunk_size?: int & >=1 & (*100 | _)
+		// Enable batch processing mode
+		enabled?: bool & (*false | _)
+		// Behavior when individual item fails
+		on_item_error?: "skip" | "fail" | *"retry"
+		// Maximum parallel executions
+		parallelism?: int & >=1 & <=1000 & (*10 | _)
+		retry?: {
+			backoff_strategy?: "fixed" | *"exponential" | "linear"
+			initial_delay_ms?: int & >=100 & (*1000 | _)
+			max_attempts?: int & >=1 & <=10 & (*3 | _)
+		}
+	}
+	// Abstract capabilities this task requires (bound at runtime)
+	capabilities?: [...string & =~"^[a-z][a-z0-9_]*$"]
+	// Other tasks or agents this task depends on
+	dependencies?: [...{
+		// Kind of dependency
+		kind?: "Task" | "Agent"
+		// Whether this dependency is optional
+		optional?: bool & (*false | _)
+		// Reference to dependency (file path or name)
+		ref!: string
+	}]
+	// Error handling configuration
+	error_handling?: {
+		// Map error codes to actions
+		error_mapping?: {
+			[string]: "fail" | "retry" | "fallback" | "ignore"
+		}
+		// Reference to fallback task on failure
+		fallback_task?: string
+		on_error?: *"fail" | "retry" | "fallback" | "ignore"
+	}
+	// Execution configuration for the task
+	execution!: {
+		// Entry point for execution (class::method, function name, or script path)
+		entrypoint?: string
+		// Target runtime environment
+		runtime?: string
+		// Maximum execution time in seconds
+		timeout_seconds?: int & >=1 & <=86400 & (*300 | _)
+		// Execution type: deterministic (pure function), idempotent (safe to retry), transactional (all-or-nothing)
+		type!: *"deterministic" | "idempotent" | "transactional"
+	}
+	// JSON Schema for task input validation
+	input?: #JSONSchemaDefinition
+	// Observability configuration
+	observability?: {
+		logging?: {
+			// Log input data (caution: may contain sensitive data)
+			include_input?: bool & (*false | _)
+			// Log output data
+			include_output?: bool & (*false | _)
+			level?: "debug" | *"info" | "warn" | "error"
+			...
+		}
+		metrics?: {
+			custom_labels?: {
+				[string]: string
+			}
+			enabled?: bool & (*true | _)
+			...
+		}
+		tracing?: {
+			enabled?: bool & (*true | _)
+			sample_rate?: number & >=0 & <=1 & (*1 | _)
+			...
+		}
+	}
+	// JSON Schema for task output validation
+	output?: #JSONSchemaDefinition
+	// Conditions that must be true after task execution
+	postconditions?: [...{
+		error_message?: string
+		// Condition expression
+		expression!: string
+	}]
+	// Conditions that must be true before task execution
+	preconditions?: [...{
+		// Error message if condition fails
+		error_message?: string
+		// Condition expression (e.g., '${{ input.status == "draft" }}')
+		expression!: string
+	}]
+	// Events that trigger task execution
+	triggers?: [...#Trigger]
+}
+
+// Hierarchical taxonomy classification for agents (v0.3.3+). Links to taxonomy.yaml spec.
+#TaxonomyClassification: {
+	// Primary capability this agent provides
+	capability?: string & =~"^[a-z][a-z0-9_]*$"
+	// Cross-cutting concerns that apply to this agent
+	concerns?: [..."quality" | "observability" | "governance" | "performance" | "architecture" | "cost" | "reliability"]
+	// Primary domain classification - every agent belongs to exactly one domain
+	domain!: "security" | "infrastructure" | "documentation" | "backend" | "frontend" | "data" | "agents" | "development" | "content"
+	// Recommended access tier based on domain defaults
+	recommended_tier?: "tier_1_read" | "tier_2_write_limited" | "tier_3_write_elevated" | "tier_4_policy"
+	// Subdomain within the primary domain (e.g., auth, ci-cd, api-docs)
+	subdomain?: string
+}
+
+#Tool: {
+	capabilities?: [...#Capability]
+	llm?: #LLMOverride
+	name?: string
+	// Tool/trigger type: mcp (Model Context Protocol), kubernetes (K8s API), http (HTTP endpoints), api (REST APIs), grpc (gRPC), function (local), a2a (agent-to-agent), webhook (event triggers), schedule (cron triggers), pipeline (CI/CD events), workflow (status changes), artifact (file outputs), git-commit (commit outputs), ci-status (pipeline status), comment (MR/issue comments), library (reusable logic), custom
+	type!: "mcp" | "kubernetes" | "http" | "api" | "grpc" | "function" | "a2a" | "webhook" | "schedule" | "pipeline" | "workflow" | "artifact" | "git-commit" | "ci-status" | "comment" | "library" | "custom"
+	...
+}
+
+// Agent, task and workflow trigger configuration
+#Trigger: {
+	// Event name (for type=event)
+	event?: string
+	// Event filter conditions
+	filter?: {...}
+	// Input the triggered run starts with
+	input?: {...}
+	// Webhook path (for type=webhook)
+	path?: string
+	// Files or glob patterns to watch, relative to the manifest (for type=file)
+	paths?: [...string]
+	// Message queue subject to consume (for type=queue)
+	queue?: {
+		// Consumer group sharing the messages
+		group?: string
+		// Subject each run's result is published to
+		reply?: string
+		// NATS subject, Kafka topic or SQS queue
+		subject!: string
+		// Queue transport
+		transport!: "nats" | "kafka" | "sqs" | "mem"
+		// Broker URL
+		url?: string
+		// Environment variable holding the broker URL
+		url_env?: string
+		...
+	}
+	// Cron expression (for type=cron)
+	schedule?: string
+	// Event source (for type=event)
+	source?: string
+	// Trigger type
+	type!: "webhook" | "cron" | "event" | "manual" | "file" | "queue"
+	...
+}
+
+// Vercel AI SDK extension for web AI applications - enables seamless integration with Next.js/React using useChat, useCompletion, useAssistant, and streamUI
+#VercelAIExtension: {
+	// React hook bindings for Vercel AI SDK
+	hooks?: {
+		// useAssistant hook configuration (OpenAI Assistants API)
+		use_assistant?: {
+			// OpenAI Assistant ID
+			assistant_id?: string
+			// Enable useAssistant hook
+			enabled?: bool & (*false | _)
+			// Thread persistence strategy
+			thread_persistence?: *"session" | "database" | "none"
+		}
+		// useChat hook configuration (maps to OSSA Agent kind)
+		use_chat?: {
+			// API endpoint for chat
+			api_endpoint?: string & (*"/api/chat" | _)
+			// Enable useChat hook
+			enabled?: bool & (*true | _)
+			// Initial conversation messages
+			initial_messages?: [...{
+				content!: string
+				role!: "user" | "assistant" | "system"
+			}]
+			// Maximum messages to keep in memory
+			max_messages?: int & >=1
+		}
+		// useCompletion hook configuration (maps to OSSA Task kind)
+		use_completion?: {
+			// API endpoint for completion
+			api_endpoint?: string & (*"/api/completion" | _)
+			// Enable useCompletion hook
+			enabled?: bool & (*false | _)
+			// Streaming mode
+			stream_mode?: *"stream" | "complete"
+		}
+	}
+	// Structured output configuration (maps to OSSA spec.output)
+	output_schema?: {
+		// Enable structured output with generateObject/streamObject
+		enabled?: bool & (*false | _)
+		// Output generation mode
+		mode?: "json" | "tool" | *"auto"
+		// Allow partial objects during streaming
+		partial?: bool & (*true | _)
+		// Zod/JSON schema for structured output
+		schema?: {...}
+	}
+	// LLM provider configuration (maps to OSSA spec.llm)
+	provider?: {
+		// Environment variable containing API key
+		api_key_env?: string & =~"^[A-Z][A-Z0-9_]*$"
+		// Custom API endpoint for OpenAI-compatible providers
+		base_url?: string
+		// Custom headers for API requests
+		headers?: {
+			[string]: string
+		}
+		// Model identifier (e.g., gpt-4o, claude-3-5-sonnet)
+		model?: string
+		// Provider identifier
+		name?: "openai" | "anthropic" | "google" | "mistral" | "groq" | "azure" | "amazon-bedrock" | "cohere" | "fireworks" | "custom"
+	}
+	// AI state management configuration (maps to OSSA spec.state)
+	state_config?: {
+		// Server Actions for state mutations
+		actions?: [...{
+			// Server action handler path
+			handler!: string
+			// JSON Schema for action input
+			input_schema?: {...}
+			// Server action name
+			name!: string
+		}]
+		// Server-side AI state configuration
+		ai_state?: {
+			// Initial AI state values
+			initial?: {...}
+			// Persist AI state across sessions
+			persist?: bool & (*false | _)
+			// JSON/Zod schema for AI state
+			schema?: {...}
+		}
+		// Client-side UI state configuration
+		ui_state?: {
+			// JSON/Zod schema for UI state
+			schema?: {...}
+			// Sync UI state from AI state changes
+			sync_with_ai_state?: bool & (*true | _)
+		}
+	}
+	// Streaming protocol: data (AI SDK format), text (plain), sse (Server-Sent Events)
+	stream_protocol?: *"data" | "text" | "sse"
+	// Tool calling configuration (maps to OSSA capabilities/tools)
+	tool_calling?: {
+		// Maximum agentic loop steps (maps to OSSA spec.autonomy.max_iterations)
+		max_steps?: int & >=1 & <=100 & (*5 | _)
+		// Tool calling mode
+		mode?: *"auto" | "required" | "none"
+		// Allow parallel tool execution
+		parallel_tool_calls?: bool & (*true | _)
+		// Tool definitions
+		tools?: [...{
+			// Require human confirmation before execution
+			confirmation?: bool & (*false | _)
+			// Tool description for LLM
+			description!: string
+			// Handler function path (e.g., @/lib/actions/orders#lookupOrder)
+			execute?: string
+			// Tool name
+			name!: string
+			// Zod/JSON schema for tool parameters
+			parameters?: {...}
+		}]
+	}
+	// React component bindings for generative UI with streamUI
+	ui_components?: {
+		// Component mappings for streamUI
+		components?: [...{
+			// Component identifier for tool calls
+			name!: string
+			// JSON Schema for component props
+			props_schema?: {...}
+			// React component path (e.g., @/components/ui/Weather)
+			render!: string
+		}]
+		// Enable generative UI with streamUI
+		enabled?: bool & (*false | _)
+		// Component to show on errors
+		error_component?: string
+		// Component to show during generation
+		loading_component?: string
+	}
+}
+
+// Vertex AI project and location an agent calls Gemini in, authenticated with Application Default Credentials
+#VertexAIConfig: {
+	// Vertex AI endpoint override, such as a Private Service Connect endpoint
+	endpoint?: string & =~"^(https://|[$]\\{)"
+	// Vertex AI location (e.g., us-central1 or global) or an environment variable reference
+	location?: string & =~"^(global|[a-z]+-[a-z]+[0-9]+|[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\\})$" & (*"us-central1" | _)
+	// Google Cloud project ID or an environment variable reference; defaults to GOOGLE_CLOUD_PROJECT
+	project?: string & =~"^([a-z][a-z0-9-]{4,28}[a-z0-9]|[$]\\{[A-Za-z_][A-Za-z0-9_]*(?::-[a-z0-9-]+)?\\})$"
+}
+
+// Google Vertex AI Agent Builder mapping configuration
+#VertexAIExtension: {
+	advanced_settings?: {
+		audio_export_gcs_destination?: {
+			uri?: string
+			...
+		}
+		logging_settings?: {
+			enable_interaction_logging?: bool
+			enable_stackdriver_logging?: bool
+			...
+		}
+		speech_settings?: {
+			endpointer_sensitivity?: int
+			no_speech_timeout?: string
+			use_timeout_based_endpointing?: bool
+			...
+		}
+		...
+	}
+	// Existing Vertex AI Agent ID
+	agent_id?: string
+	default_language_code?: string & (*"en" | _)
+	// Agent display name (mapped from metadata.name)
+	display_name?: string
+	enable_spell_correction?: bool & (*false | _)
+	enable_stackdriver_logging?: bool & (*true | _)
+	enabled?: bool & (*false | _)
+	generative_settings?: {
+		fallback_settings?: {
+			prompt_templates?: [...{
+				display_name?: string
+				prompt_text?: string
+				...
+			}]
+			selected_prompt?: string
+			...
+		}
+		generative_safety_settings?: {
+			banned_phrases?: [...string]
+			...
+		}
+		knowledge_connector_settings?: {
+			agent?: string
+			agent_identity?: string
+			business?: string
+			disable_data_store_fallback?: bool
+			...
+		}
+		llm_model_settings?: {
+			// Vertex AI model (mapped from spec.llm)
+			model?: string
+			prompt_text?: string
+			...
+		}
+		...
+	}
+	git_integration_settings?: {
+		github_settings?: {
+			branches?: [...string]
+			display_name?: string
+			repository_uri?: string
+			tracking_branch?: string
+			...
+		}
+		...
+	}
+	// GCP region
+	location?: string & (*"us-central1" | _)
+	locked?: bool & (*false | _)
+	// GCP project ID
+	project_id?: string
+	// Security settings resource name
+	security_settings?: string
+	// Starting flow reference
+	start_flow?: string
+	text_to_speech_settings?: {
+		synthesize_speech_configs?: {
+			[string]: {
+				voice?: {
+					name?: string
+					...
+				}
+				...
+			}
+		}
+		...
+	}
+	time_zone?: string & (*"America/Los_Angeles" | _)
+	...
+}
+
+// Specification for workflow composition (kind: Workflow) - composes Tasks and Agents into executable pipelines
+#WorkflowSpec: {
+	// Concurrency control
+	concurrency?: {
+		// Cancel running workflow if new one starts in same group
+		cancel_in_progress?: bool & (*false | _)
+		// Concurrency group name (workflows in same group are serialized)
+		group?: string
+	}
+	// Shared context available to all steps
+	context?: {
+		// Secret references available to steps
+		secrets?: [...{
+			// Secret name available to steps
+			name!: string
+			// Secret reference (e.g., vault://secret/api-key)
+			ref!: string
+			...
+		}]
+		// Workflow-level variables
+		variables?: {...}
+	}
+	// Workflow-level error handling
+	error_handling?: {
+		// Steps to run on rollback/compensate
+		compensation_steps?: [...#WorkflowStep]
+		// Notification on failure
+		notification?: {
+			channels?: [..."email" | "slack" | "webhook" | "pagerduty"]
+			// Notification template reference
+			template?: string
+			...
+		}
+		// Action on step failure
+		on_failure?: *"halt" | "continue" | "rollback" | "notify" | "compensate" | "compensation"
+		retry_policy?: {
+			backoff?: "fixed" | *"exponential" | "linear"
+			initial_delay_ms?: int & >=100 & (*1000 | _)
+			max_attempts?: int & >=1 & <=10 & (*3 | _)
+			max_delay_ms?: int & (*60000 | _)
+			...
+		}
+		...
+	}
+	// JSON Schema for workflow input validation
+	inputs?: #JSONSchemaDefinition
+	// Workflow observability configuration
+	observability?: {
+		logging?: {
+			level?: "debug" | *"info" | "warn" | "error"
+			...
+		}
+		metrics?: {
+			custom_labels?: {
+				[string]: string
+			}
+			enabled?: bool & (*true | _)
+			...
+		}
+		tracing?: {
+			enabled?: bool & (*true | _)
+			// Propagate trace context to steps
+			propagate_context?: bool & (*true | _)
+			...
+		}
+		...
+	}
+	// JSON Schema for workflow output validation
+	outputs?: #JSONSchemaDefinition
+	// Workflow steps (Tasks and/or Agents)
+	steps!: list.MinItems(1) & [...#WorkflowStep]
+	// Maximum workflow execution time in seconds
+	timeout_seconds?: int & >=1 & <=86400
+	// Events that trigger workflow execution
+	triggers?: [...#Trigger]
+	...
+}
+
+// A step in a workflow - can be a Task, Agent, or control structure
+#WorkflowStep: {
+	// Conditional branches (for kind: Conditional)
+	branches?: [...{
+		// Branch condition expression
+		condition!: string
+		steps!: [...#WorkflowStep]
+		...
+	}]
+	// Condition expression for conditional execution
+	condition?: string
+	// Continue workflow even if this step fails
+	continue_on_error?: bool & (*false | _)
+	// Explicit dependencies on other steps
+	depends_on?: [...string]
+	// Steps to run if no branch condition matches (for kind: Conditional)
+	else?: [...#WorkflowStep]
+	// Step identifier (unique within workflow)
+	id!: string & =~"^[a-z][a-z0-9_-]*$"
+	// Inline Task or Agent specification (alternative to ref)
+	inline?: {...}
+	// Input mapping using expression syntax
+	input?: {...}
+	// Step type
+	kind?: "Task" | "Agent" | "Parallel" | "Conditional" | "Loop"
+	// Step labels for filtering and organization
+	labels?: {
+		[string]: string
+	}
+	// Loop configuration (for kind: Loop)
+	loop?: {
+		// Variable name for current item
+		as?: string & (*"item" | _)
+		// Variable name for current index
+		index?: string & (*"index" | _)
+		// Expression returning array to iterate over
+		over!: string
+		// Maximum parallel iterations
+		parallelism?: int & >=1
+		...
+	}
+	// Human-readable step name for display
+	name?: string
+	// Step-specific error handling
+	on_error?: {
+		action?: *"fail" | "continue" | "goto" | "compensate"
+		// Compensation step to run on error
+		compensation?: #WorkflowStep
+		// Step ID to jump to on error (for action: goto)
+		goto?: string
+		...
+	}
+	// Output mapping to workflow context
+	output?: {
+		// Specific fields to extract from output
+		fields?: [...string]
+		// Variable name to store output
+		to?: string
+		...
+	}
+	// Steps to run in parallel (for kind: Parallel)
+	parallel?: [...#WorkflowStep]
+	// Reference to Task or Agent manifest file
+	ref?: string
+	// Step-specific retry configuration
+	retry?: {
+		backoff_strategy?: "fixed" | *"exponential" | "linear"
+		initial_delay_ms?: int & >=100 & (*1000 | _)
+		max_attempts?: int & >=1 & <=10 & (*3 | _)
+		// Error codes that should trigger retry
+		retryable_errors?: [...string]
+		...
+	}
+	// Nested steps (for Loop and Conditional kinds)
+	steps?: [...#WorkflowStep]
+	// Step-specific timeout
+	timeout_seconds?: int & >=1 & <=86400
+	...
+}
@@ -0,0 +1,181 @@
+package ossa
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SQL statement types accepted in handler.sql.statements.
+const (
+	StatementSelect = "select"
+	StatementInsert = "insert"
+	StatementUpdate = "update"
+	StatementDelete = "delete"
+)
+
+// ValidStatementTypes are the statement types a sql tool may allow.
+var ValidStatementTypes = map[string]bool{
+	StatementSelect: true,
+	StatementInsert: true,
+	StatementUpdate: true,
+	StatementDelete: true,
+}
+
+var sqlWriteKeyword = regexp.MustCompile(`(?i)\b(insert|update|delete|merge)\b`)
+
+// AllowedStatements returns the statement types a sql tool may run. The
+// handler's list defaults to select only, and agents whose tier cannot
+// write are limited to select whatever the handler declares.
+func (m *Manifest) AllowedStatements(tool ToolConfig) map[string]bool {
+	allowed := map[string]bool{StatementSelect: true}
+	if tool.Handler == nil || tool.Handler.SQL == nil || len(tool.Handler.SQL.Statements) == 0 {
+		return allowed
+	}
+	allowed = map[string]bool{}
+	canWrite := m.EffectiveAccessTier().CanWrite()
+	for _, s := range tool.Handler.SQL.Statements {
+		s = strings.ToLower(s)
+		if s == StatementSelect || (canWrite && ValidStatementTypes[s]) {
+			allowed[s] = true
+		}
+	}
+	return allowed
+}
+
+// ClassifyStatement returns the type of a single SQL statement. Queries
+// with more than one statement, and statements other than select, insert,
+// update and delete, are rejected. A WITH query is classified by the data
+// modifying statement it contains, if any. Quoted strings and comments are
+// read as stripSQL reads them.
+func ClassifyStatement(query string) (string, error) {
+	stripped, err := stripSQL(query)
+	if err != nil {
+		return "", err
+	}
+	stripped = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(stripped), ";"))
+	if strings.Contains(stripped, ";") {
+		return "", NewError("query contains multiple statements")
+	}
+
+	fields := strings.Fields(strings.TrimLeft(stripped, "("))
+	if len(fields) == 0 {
+		return "", NewError("empty query")
+	}
+	switch keyword := strings.ToLower(fields[0]); keyword {
+	case StatementSelect, StatementInsert, StatementUpdate, StatementDelete:
+		return keyword, nil
+	case "with":
+		if w := sqlWriteKeyword.FindString(stripped); w != "" {
+			if w = strings.ToLower(w); w == "merge" {
+				return "", NewError("unsupported statement: merge")
+			}
+			return strings.ToLower(w), nil
+		}
+		return StatementSelect, nil
+	default:
+		return "", NewError(fmt.Sprintf("unsupported statement: %s", keyword))
+	}
+}
+
+// stripSQL replaces the quoted strings and identifiers of query with empty
+// string literals and its comments with a space, reading it left to right
+// as a database does, so a quote inside a comment or a comment marker
+// inside a string cannot hide a statement. What SQL dialects read differently is rejected rather
+// than guessed at: backslashes in quoted strings, nested block comments,
+// "--" without a space after it, "#" and dollar-quoted strings.
+func stripSQL(query string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		rest := query[i:]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for {
+				n := strings.IndexByte(query[end:], c)
+				if n < 0 {
+					return "", NewError("unterminated quoted string")
+				}
+				end += n + 1
+				// A doubled quote is an escaped one.
+				if end == len(query) || query[end] != c {
+					break
+				}
+				end++
+			}
+			if strings.Contains(query[i:end], `\`) {
+				return "", NewError("backslash in a quoted string")
+			}
+			b.WriteString("''")
+			i = end
+		case strings.HasPrefix(rest, "--"):
+			if len(rest) > 2 && !strings.ContainsRune(" \t\r\n", rune(rest[2])) {
+				return "", NewError("-- must be followed by a space")
+			}
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			b.WriteByte(' ')
+			i += n
+		case strings.HasPrefix(rest, "/*"):
+			n := strings.Index(rest[2:], "*/")
+			if n < 0 {
+				return "", NewError("unterminated comment")
+			}
+			if strings.Contains(rest[2:2+n], "/*") {
+				return "", NewError("nested comment")
+			}
+			b.WriteByte(' ')
+			i += n + 4
+		case c == '#':
+			return "", NewError("# outside a quoted string")
+		case c == '$' && i+1 < len(query) && (query[i+1] < '0' || query[i+1] > '9'):
+			return "", NewError("dollar-quoted strings are not supported")
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+func validateSQL(path string, m *Manifest, tool ToolConfig, result *ValidationResult) {
+	cfg := tool.Handler.SQL
+	if cfg == nil {
+		result.addError(path + ": required for sql runtime")
+		return
+	}
+	if cfg.Driver == "" {
+		result.addError(path + ".driver: required")
+	}
+	if cfg.DSNEnv == "" {
+		result.addError(path + ".dsn_env: required")
+	}
+	if cfg.MaxRows < 0 {
+		result.addError(path + ".max_rows: must not be negative")
+	}
+
+	canWrite := m.EffectiveAccessTier().CanWrite()
+	for _, s := range cfg.Statements {
+		if !ValidStatementTypes[strings.ToLower(s)] {
+			result.addError(fmt.Sprintf("%s.statements: invalid statement type: %s", path, s))
+		} else if !canWrite && strings.ToLower(s) != StatementSelect {
+			result.addWarning(fmt.Sprintf("%s.statements: %s is not allowed for %s agents and will be rejected", path, s, m.EffectiveAccessTier()))
+		}
+	}
+
+	if cfg.Query == "" {
+		if len(cfg.Params) > 0 {
+			result.addError(path + ".params: requires query")
+		}
+		return
+	}
+	kind, err := ClassifyStatement(cfg.Query)
+	if err != nil {
+		result.addError(fmt.Sprintf("%s.query: %v", path, err))
+	} else if !m.AllowedStatements(tool)[kind] {
+		result.addError(fmt.Sprintf("%s.query: %s statements are not allowed", path, kind))
+	}
+}
@@ -0,0 +1,92 @@
+package ossa
+
+import "fmt"
+
+// Strategies for ContextWindowConfig.Strategy.
+const (
+	// StrategyTruncation and StrategySlidingWindow keep the most recent
+	// memory entries within max_messages and max_tokens.
+	StrategyTruncation    = "truncation"
+	StrategySlidingWindow = "sliding_window"
+	// StrategySummarization has a model summarize the oldest entries
+	// once memory nears max_tokens.
+	StrategySummarization = "summarization"
+)
+
+// Summarization defaults.
+const (
+	DefaultSummaryThreshold = 0.8
+	DefaultKeepMessages     = 4
+)
+
+// StateConfig is how an agent keeps state.
+type StateConfig struct {
+	// Mode is stateless, session or long_running.
+	Mode    string                 `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Storage map[string]interface{} `json:"storage,omitempty" yaml:"storage,omitempty"`
+	// ContextWindow keeps conversation memory, the entries of the input
+	// field spec.llm.context.memory_field names, within bounds.
+	ContextWindow *ContextWindowConfig `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+}
+
+// ContextWindowConfig bounds conversation memory.
+type ContextWindowConfig struct {
+	MaxMessages int    `json:"max_messages,omitempty" yaml:"max_messages,omitempty"`
+	MaxTokens   int    `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	Strategy    string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// Summarizer overrides spec.llm for summaries, as a tool's llm does,
+	// typically with a cheaper model.
+	Summarizer *LLMConfig `json:"summarizer,omitempty" yaml:"summarizer,omitempty"`
+	// Threshold is the share of MaxTokens memory may reach before it is
+	// summarized; 0 means DefaultSummaryThreshold.
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// KeepMessages are the most recent entries never summarized; 0 means
+	// DefaultKeepMessages.
+	KeepMessages int `json:"keep_messages,omitempty" yaml:"keep_messages,omitempty"`
+}
+
+// SummarizerLLM returns the model m summarizes memory with: spec.llm
+// merged with spec.state.context_window.summarizer.
+func (m *Manifest) SummarizerLLM() (*LLMConfig, error) {
+	if s := m.Spec.State; s != nil && s.ContextWindow != nil && s.ContextWindow.Summarizer != nil {
+		return m.Spec.LLM.Merge(s.ContextWindow.Summarizer)
+	}
+	return m.Spec.LLM, nil
+}
+
+func validateState(m *Manifest, result *ValidationResult) {
+	s := m.Spec.State
+	if s == nil {
+		return
+	}
+	switch s.Mode {
+	case "", "stateless", "session", "long_running":
+	default:
+		result.addError(fmt.Sprintf("spec.state.mode: invalid mode: %s", s.Mode))
+	}
+	c := s.ContextWindow
+	if c == nil {
+		return
+	}
+	switch c.Strategy {
+	case "", StrategyTruncation, StrategySlidingWindow:
+	case StrategySummarization:
+		if c.MaxTokens == 0 {
+			result.addError("spec.state.context_window: summarization requires max_tokens")
+		}
+	default:
+		result.addError(fmt.Sprintf("spec.state.context_window.strategy: invalid strategy: %s", c.Strategy))
+	}
+	if c.MaxMessages < 0 || c.MaxTokens < 0 || c.KeepMessages < 0 {
+		result.addError("spec.state.context_window: limits must not be negative")
+	}
+	if c.Threshold < 0 || c.Threshold > 1 {
+		result.addError(fmt.Sprintf("spec.state.context_window.threshold: %g is not between 0 and 1", c.Threshold))
+	}
+	if c.Summarizer != nil {
+		if c.Strategy != StrategySummarization {
+			result.addWarning("spec.state.context_window.summarizer: only used by the summarization strategy")
+		}
+		validateLLMOverride("spec.state.context_window.summarizer", m.Spec.LLM, c.Summarizer, result)
+	}
+}
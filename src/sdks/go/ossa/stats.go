@@ -0,0 +1,129 @@
+package ossa
+
+import "sort"
+
+// WorkspaceStats are aggregate numbers over the manifests in a workspace,
+// as reported by ossa stats. Counts by tier, provider, model and version
+// are keyed "(none)" where the field is unset.
+type WorkspaceStats struct {
+	Manifests   int            `json:"manifests"`
+	LoadErrors  int            `json:"load_errors"`
+	Kinds       map[Kind]int   `json:"kinds"`
+	Tiers       map[string]int `json:"tiers"`
+	Providers   map[string]int `json:"providers"`
+	Models      map[string]int `json:"models"`
+	APIVersions map[string]int `json:"api_versions"`
+	ToolTypes   map[string]int `json:"tool_types"`
+
+	Agents int `json:"agents"`
+	// ToolsPerAgent summarizes len(spec.tools) over agents.
+	ToolsPerAgent ToolCount `json:"tools_per_agent"`
+	// Coverage counts agents with each safety control configured.
+	Coverage SafetyCoverage `json:"safety_coverage"`
+}
+
+// ToolCount is the distribution of tool counts over agents.
+type ToolCount struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// SafetyCoverage counts agents with each safety control.
+type SafetyCoverage struct {
+	Guardrails         int `json:"guardrails"`
+	HumanApproval      int `json:"human_approval"`
+	Moderation         int `json:"moderation"`
+	InjectionDetection int `json:"injection_detection"`
+	// None is the number of agents with no safety control at all.
+	None int `json:"none"`
+}
+
+const statsNone = "(none)"
+
+// ComputeStats aggregates the loaded entries of a ScanWorkspace result;
+// entries that failed to load are only counted in LoadErrors.
+func ComputeStats(entries []CatalogEntry) *WorkspaceStats {
+	s := &WorkspaceStats{
+		Kinds:       map[Kind]int{},
+		Tiers:       map[string]int{},
+		Providers:   map[string]int{},
+		Models:      map[string]int{},
+		APIVersions: map[string]int{},
+		ToolTypes:   map[string]int{},
+	}
+	orNone := func(v string) string {
+		if v == "" {
+			return statsNone
+		}
+		return v
+	}
+	var toolCounts []int
+	for _, e := range entries {
+		if e.Err != nil {
+			s.LoadErrors++
+			continue
+		}
+		m := e.Manifest
+		s.Manifests++
+		s.Kinds[m.Kind]++
+		s.APIVersions[orNone(m.APIVersion)]++
+		for _, t := range m.Spec.Tools {
+			s.ToolTypes[orNone(t.Type)]++
+		}
+		if m.Kind != KindAgent {
+			continue
+		}
+
+		s.Agents++
+		s.Tiers[orNone(string(m.GetAccessTier()))]++
+		provider, model := "", ""
+		if m.Spec.LLM != nil {
+			provider, model = m.Spec.LLM.Provider, m.Spec.LLM.Model
+		}
+		s.Providers[orNone(provider)]++
+		s.Models[orNone(model)]++
+		toolCounts = append(toolCounts, len(m.Spec.Tools))
+		s.Coverage.add(m.Spec.Safety)
+	}
+
+	if n := len(toolCounts); n > 0 {
+		sort.Ints(toolCounts)
+		total := 0
+		for _, c := range toolCounts {
+			total += c
+		}
+		s.ToolsPerAgent = ToolCount{Min: toolCounts[0], Max: toolCounts[n-1], Mean: float64(total) / float64(n)}
+		if n%2 == 1 {
+			s.ToolsPerAgent.Median = float64(toolCounts[n/2])
+		} else {
+			s.ToolsPerAgent.Median = float64(toolCounts[n/2-1]+toolCounts[n/2]) / 2
+		}
+	}
+	return s
+}
+
+func (c *SafetyCoverage) add(safety *SafetyConfig) {
+	covered := false
+	if safety != nil {
+		if g := safety.Guardrails; g != nil {
+			c.Guardrails++
+			covered = true
+			if len(g.RequireHumanApprovalFor) > 0 {
+				c.HumanApproval++
+			}
+		}
+		if safety.Moderation != nil && safety.Moderation.Enabled {
+			c.Moderation++
+			covered = true
+		}
+		if safety.InjectionDetection != nil && safety.InjectionDetection.Enabled {
+			c.InjectionDetection++
+			covered = true
+		}
+	}
+	if !covered {
+		c.None++
+	}
+}
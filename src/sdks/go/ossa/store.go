@@ -0,0 +1,219 @@
+package ossa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrManifestNotFound is returned by ManifestStores for refs they do not
+// hold.
+var ErrManifestNotFound = errors.New("manifest not found")
+
+// ManifestLoader loads manifests by reference: a file path for FileLoader,
+// an object key or row ID for other backends. Its Load method fits
+// engine.Engine.Load.
+type ManifestLoader interface {
+	Load(ctx context.Context, ref string) (*Manifest, error)
+}
+
+// ManifestStore holds a catalog of manifests, such as the one ossa serve
+// publishes to. DirManifestStore keeps them as files; servers, operators
+// and tests can substitute in-memory, object storage or database
+// implementations. Implementations must be safe for concurrent use.
+type ManifestStore interface {
+	ManifestLoader
+	// List returns the store's manifests sorted by ref, in Path. Those
+	// that fail to load are listed with Err set.
+	List(ctx context.Context) ([]CatalogEntry, error)
+	// Get returns the manifest data at ref, decrypted if it was
+	// encrypted at rest.
+	Get(ctx context.Context, ref string) ([]byte, error)
+	// Put creates or replaces the manifest at ref with data, as
+	// MarshalManifest writes it, possibly encrypted. The ref's extension
+	// says its format.
+	Put(ctx context.Context, ref string, data []byte) error
+	// Delete removes the manifest at ref.
+	Delete(ctx context.Context, ref string) error
+}
+
+// FileLoader loads manifests from files, resolving relative refs against
+// Dir.
+type FileLoader struct {
+	Dir string
+}
+
+// Load loads the manifest at ref.
+func (l FileLoader) Load(ctx context.Context, ref string) (*Manifest, error) {
+	if l.Dir != "" && !filepath.IsAbs(ref) {
+		ref = filepath.Join(l.Dir, ref)
+	}
+	return LoadManifest(ref)
+}
+
+// FSLoader loads manifests from FS, such as an embed.FS of manifests
+// bundled into a binary. Refs are FS names.
+type FSLoader struct {
+	FS fs.FS
+}
+
+// Load loads the manifest named ref.
+func (l FSLoader) Load(ctx context.Context, ref string) (*Manifest, error) {
+	return LoadManifestFS(l.FS, ref)
+}
+
+// DirManifestStore is a ManifestStore of the manifest files below Dir, as
+// ScanWorkspace finds them. Its refs are file paths, as List returns
+// them; Put creates missing directories.
+type DirManifestStore struct {
+	Dir string
+}
+
+// Load loads the manifest file at ref.
+func (s *DirManifestStore) Load(ctx context.Context, ref string) (*Manifest, error) {
+	return LoadManifest(ref)
+}
+
+// List scans Dir with ScanWorkspace.
+func (s *DirManifestStore) List(ctx context.Context) ([]CatalogEntry, error) {
+	return ScanWorkspace(s.Dir)
+}
+
+// Get reads the file at ref.
+func (s *DirManifestStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	data, err := ReadManifestFile(ref)
+	if os.IsNotExist(err) {
+		return nil, WrapError(ref, ErrManifestNotFound)
+	}
+	return data, err
+}
+
+// Put writes data to the file at ref.
+func (s *DirManifestStore) Put(ctx context.Context, ref string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(ref), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(ref, data, 0o644)
+}
+
+// Delete removes the file at ref.
+func (s *DirManifestStore) Delete(ctx context.Context, ref string) error {
+	if err := os.Remove(ref); err != nil {
+		if os.IsNotExist(err) {
+			return WrapError(ref, ErrManifestNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// MemoryManifestStore is a ManifestStore in memory, for tests and
+// ephemeral servers. The zero value is empty and ready to use. Refs are
+// slash-separated; as in ScanWorkspace, refs with a hidden element such
+// as .versions/ are not listed.
+type MemoryManifestStore struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	data     []byte
+	modified time.Time
+}
+
+// Load parses the manifest at ref.
+func (s *MemoryManifestStore) Load(ctx context.Context, ref string) (*Manifest, error) {
+	data, err := s.Get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(path.Ext(ref), CBORExt) {
+		return FromCBOR(data)
+	}
+	return ParseManifest(data, path.Ext(ref))
+}
+
+// List parses every listed manifest.
+func (s *MemoryManifestStore) List(ctx context.Context) ([]CatalogEntry, error) {
+	s.mu.RLock()
+	refs := make([]string, 0, len(s.files))
+	for ref := range s.files {
+		if !hiddenRef(ref) {
+			refs = append(refs, ref)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(refs)
+
+	entries := make([]CatalogEntry, 0, len(refs))
+	for _, ref := range refs {
+		s.mu.RLock()
+		f, ok := s.files[ref]
+		s.mu.RUnlock()
+		if !ok {
+			continue // deleted meanwhile
+		}
+		entry := CatalogEntry{Path: ref, Modified: f.modified}
+		if m, err := s.Load(ctx, ref); err != nil {
+			entry.Err = err
+		} else {
+			entry.setManifest(m)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Get returns the data at ref, decrypted.
+func (s *MemoryManifestStore) Get(ctx context.Context, ref string) ([]byte, error) {
+	s.mu.RLock()
+	f, ok := s.files[ref]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, WrapError(ref, ErrManifestNotFound)
+	}
+	data, err := Decrypt(f.data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	return data, nil
+}
+
+// Put keeps a copy of data at ref.
+func (s *MemoryManifestStore) Put(ctx context.Context, ref string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = map[string]memoryFile{}
+	}
+	s.files[ref] = memoryFile{data: append([]byte(nil), data...), modified: time.Now()}
+	return nil
+}
+
+// Delete removes ref.
+func (s *MemoryManifestStore) Delete(ctx context.Context, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[ref]; !ok {
+		return WrapError(ref, ErrManifestNotFound)
+	}
+	delete(s.files, ref)
+	return nil
+}
+
+func hiddenRef(ref string) bool {
+	for _, elem := range strings.Split(ref, "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." && elem != ".." {
+			return true
+		}
+	}
+	return false
+}
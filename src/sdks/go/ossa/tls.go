@@ -0,0 +1,145 @@
+package ossa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// TLSConfig configures how a tool's runtime connects to endpoints behind
+// private PKI. Paths are relative to the manifest's directory. A client
+// key may instead come from the environment variable KeyEnv names, so it
+// need not sit next to the manifest.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system's.
+	CAFile string `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile (or KeyEnv) are the PEM client certificate and
+	// key for mTLS.
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	KeyEnv   string `json:"key_env,omitempty" yaml:"key_env,omitempty"`
+	// ServerName overrides the name verified in the server's certificate.
+	ServerName string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	// MinVersion is 1.2 (the default) or 1.3; 1.0 and 1.1 are warned about.
+	MinVersion string `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+	// InsecureSkipVerify disables certificate verification. Validation
+	// fails manifests setting it; runtimes honor it for local testing.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ClientConfig loads the CA bundle and client certificate, resolving
+// relative paths against baseDir, and returns the tls.Config to dial with.
+func (c *TLSConfig) ClientConfig(baseDir string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.ServerName, InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, NewError(fmt.Sprintf("unknown TLS version %q", c.MinVersion))
+		}
+		cfg.MinVersion = v
+	}
+	resolve := func(p string) string {
+		if baseDir != "" && !filepath.IsAbs(p) {
+			return filepath.Join(baseDir, p)
+		}
+		return p
+	}
+	if c.CAFile != "" {
+		data, err := os.ReadFile(resolve(c.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, NewError(fmt.Sprintf("%s holds no PEM certificates", c.CAFile))
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		certPEM, err := os.ReadFile(resolve(c.CertFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		var keyPEM []byte
+		if c.KeyEnv != "" {
+			v, ok := os.LookupEnv(c.KeyEnv)
+			if !ok || v == "" {
+				return nil, NewError(fmt.Sprintf("environment variable %s is not set", c.KeyEnv))
+			}
+			keyPEM = []byte(v)
+		} else if keyPEM, err = os.ReadFile(resolve(c.KeyFile)); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, WrapError("invalid client certificate", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ToolTransport returns the transport a tool's runtime makes HTTP requests
+// with: http.DefaultTransport, or a copy of it dialing with the handler's
+// TLS settings and routed by m.EgressFor(baseDir).
+func ToolTransport(m *Manifest, tool ToolConfig, baseDir string) (http.RoundTripper, error) {
+	egress, err := m.EgressFor(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	hasTLS := tool.Handler != nil && tool.Handler.TLS != nil
+	if !hasTLS && egress == nil {
+		return http.DefaultTransport, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if hasTLS {
+		if t.TLSClientConfig, err = tool.Handler.TLS.ClientConfig(baseDir); err != nil {
+			return nil, WrapError(fmt.Sprintf("tool %s: handler.tls", tool.Name), err)
+		}
+	}
+	if egress != nil {
+		if t.Proxy, err = egress.ProxyFunc(); err != nil {
+			return nil, WrapError("egress", err)
+		}
+	}
+	return t, nil
+}
+
+func validateTLS(path string, c *TLSConfig, result *ValidationResult) {
+	if c.InsecureSkipVerify {
+		result.addError(path + ".insecure_skip_verify: disables certificate verification; trust the endpoint's CA with ca_file instead")
+	}
+	if c.MinVersion != "" {
+		switch v, ok := tlsVersions[c.MinVersion]; {
+		case !ok:
+			result.addError(fmt.Sprintf("%s.min_version: expected 1.2 or 1.3, got %s", path, c.MinVersion))
+		case v < tls.VersionTLS12:
+			result.addWarning(fmt.Sprintf("%s.min_version: TLS %s is deprecated; use 1.2 or later", path, c.MinVersion))
+		}
+	}
+	hasKey := c.KeyFile != "" || c.KeyEnv != ""
+	switch {
+	case c.KeyFile != "" && c.KeyEnv != "":
+		result.addError(path + ": set one of key_file and key_env")
+	case c.CertFile != "" && !hasKey:
+		result.addError(path + ".cert_file: requires key_file or key_env")
+	case c.CertFile == "" && hasKey:
+		result.addError(path + ": key_file and key_env require cert_file")
+	}
+	if c.KeyEnv != "" && !envNamePattern.MatchString(c.KeyEnv) {
+		result.addError(fmt.Sprintf("%s.key_env: invalid environment variable name %q", path, c.KeyEnv))
+	}
+}
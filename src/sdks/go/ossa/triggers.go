@@ -0,0 +1,245 @@
+package ossa
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trigger types used in spec.triggers. ossa scheduler runs cron, webhook
+// and file triggers; event and manual triggers are started elsewhere.
+const (
+	TriggerCron    = "cron"
+	TriggerWebhook = "webhook"
+	TriggerFile    = "file"
+	TriggerEvent   = "event"
+	TriggerManual  = "manual"
+	TriggerQueue   = "queue"
+)
+
+// ValidTriggerTypes are the accepted spec.triggers types.
+var ValidTriggerTypes = map[string]bool{
+	TriggerCron:    true,
+	TriggerWebhook: true,
+	TriggerFile:    true,
+	TriggerEvent:   true,
+	TriggerManual:  true,
+	TriggerQueue:   true,
+}
+
+// ValidQueueTransports are the accepted queue transports. mem is an
+// in-process queue for tests and embedding.
+var ValidQueueTransports = map[string]bool{
+	"nats":  true,
+	"kafka": true,
+	"sqs":   true,
+	"mem":   true,
+}
+
+func validateTriggers(m *Manifest, result *ValidationResult) {
+	webhooks := map[string]bool{}
+	for i, t := range m.Spec.Triggers {
+		path := fmt.Sprintf("spec.triggers[%d]", i)
+		if !ValidTriggerTypes[t.Type] {
+			result.addError(fmt.Sprintf("%s.type: invalid trigger type: %s", path, t.Type))
+			continue
+		}
+		if m.Kind == KindAgent && t.Type != TriggerQueue {
+			result.addError(fmt.Sprintf("%s: Agents only have queue triggers, not %s", path, t.Type))
+		} else if m.Kind != KindAgent && m.Kind != KindTask && m.Kind != KindWorkflow {
+			result.addError(fmt.Sprintf("%s: only Agents, Tasks and Workflows have triggers, not %s", path, m.Kind))
+		}
+		switch t.Type {
+		case TriggerCron:
+			if t.Schedule == "" {
+				result.addError(path + ": cron trigger missing schedule")
+			} else if _, err := ParseCron(t.Schedule); err != nil {
+				result.addError(fmt.Sprintf("%s.schedule: %v", path, err))
+			}
+		case TriggerWebhook:
+			if !strings.HasPrefix(t.Path, "/") {
+				result.addError(fmt.Sprintf("%s.path: webhook path must start with /: %q", path, t.Path))
+			} else if webhooks[t.Path] {
+				result.addError(fmt.Sprintf("%s.path: duplicate webhook path: %s", path, t.Path))
+			}
+			webhooks[t.Path] = true
+		case TriggerFile:
+			if len(t.Paths) == 0 {
+				result.addError(path + ": file trigger missing paths")
+			}
+			for j, p := range t.Paths {
+				if _, err := filepath.Match(p, ""); err != nil {
+					result.addError(fmt.Sprintf("%s.paths[%d]: invalid pattern: %s", path, j, p))
+				}
+			}
+		case TriggerQueue:
+			if t.Queue == nil {
+				result.addError(path + ": queue trigger missing queue")
+			} else {
+				validateQueue(path+".queue", t.Queue, result)
+			}
+		}
+	}
+}
+
+func validateQueue(path string, q *QueueConfig, result *ValidationResult) {
+	if !ValidQueueTransports[q.Transport] {
+		result.addError(fmt.Sprintf("%s.transport: invalid transport: %s", path, q.Transport))
+	}
+	if q.Subject == "" {
+		result.addError(path + ".subject: required")
+	}
+	if q.URL != "" && q.URLEnv != "" {
+		result.addError(path + ": set url or url_env, not both")
+	}
+	if q.URL != "" {
+		if u, err := url.Parse(q.URL); err != nil {
+			result.addError(fmt.Sprintf("%s.url: invalid URL: %s", path, q.URL))
+		} else if u.User != nil {
+			result.addWarning(path + ".url: credentials belong in url_env, not the manifest")
+		}
+	}
+}
+
+// Cron is a parsed cron schedule.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// With both day fields restricted, either may match, as in crontab.
+	domAny, dowAny bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ParseCron parses a five-field cron expression (minute, hour, day of
+// month, month, day of week), such as "*/15 8-18 * * mon-fri", or a macro
+// such as @daily.
+func ParseCron(expr string) (*Cron, error) {
+	if macro, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields, got %d", expr, len(fields))
+	}
+	c := &Cron{domAny: strings.HasPrefix(fields[2], "*"), dowAny: strings.HasPrefix(fields[4], "*")}
+	var err error
+	if c.minute, err = cronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron minute: %w", err)
+	}
+	if c.hour, err = cronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron hour: %w", err)
+	}
+	if c.dom, err = cronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month: %w", err)
+	}
+	if c.month, err = cronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron month: %w", err)
+	}
+	if c.dow, err = cronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week: %w", err)
+	}
+	// 7 is Sunday too.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// cronField parses a comma-separated list of *, values, ranges and steps
+// into a bitset. names, if given, spell the values from min.
+func cronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], min, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], min, names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t the schedule fires, in t's location,
+// or the zero time if it never does (such as on February 30).
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -7,6 +7,8 @@ type Manifest struct {
 	Kind       Kind     `json:"kind" yaml:"kind"`
 	Metadata   Metadata `json:"metadata" yaml:"metadata"`
 	Spec       Spec     `json:"spec" yaml:"spec"`
+	// VendorFields holds top-level x- fields; see VendorPrefix.
+	VendorFields map[string]interface{} `json:"-" yaml:"-"`
 }
 
 // Kind represents the manifest kind.
@@ -21,6 +23,7 @@ const (
 // Metadata contains manifest metadata.
 type Metadata struct {
 	Name        string            `json:"name" yaml:"name"`
+	Namespace   string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 	Version     string            `json:"version,omitempty" yaml:"version,omitempty"`
 	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
@@ -28,12 +31,43 @@ type Metadata struct {
 }
 
 // Spec contains the agent specification.
+// Role may be written as a plain string or as a map keyed by locale; in the
+// map form RoleLocales holds the variants and Role the default one.
 type Spec struct {
-	Role         string           `json:"role" yaml:"role"`
-	LLM          *LLMConfig       `json:"llm,omitempty" yaml:"llm,omitempty"`
-	Tools        []ToolConfig     `json:"tools,omitempty" yaml:"tools,omitempty"`
-	Autonomy     *AutonomyConfig  `json:"autonomy,omitempty" yaml:"autonomy,omitempty"`
-	Constraints  *Constraints     `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Role        string            `json:"role" yaml:"role"`
+	RoleLocales map[string]string `json:"-" yaml:"-"`
+	Prompts     *PromptsConfig    `json:"prompts,omitempty" yaml:"prompts,omitempty"`
+	LLM         *LLMConfig        `json:"llm,omitempty" yaml:"llm,omitempty"`
+	// Embeddings is the model a RAG-enabled agent vectorizes text with.
+	Embeddings  *EmbeddingsConfig `json:"embeddings,omitempty" yaml:"embeddings,omitempty"`
+	Input       *InputConfig      `json:"input,omitempty" yaml:"input,omitempty"`
+	State       *StateConfig      `json:"state,omitempty" yaml:"state,omitempty"`
+	Tools       []ToolConfig      `json:"tools,omitempty" yaml:"tools,omitempty"`
+	Egress      *EgressConfig     `json:"egress,omitempty" yaml:"egress,omitempty"`
+	Autonomy    *AutonomyConfig   `json:"autonomy,omitempty" yaml:"autonomy,omitempty"`
+	Constraints *Constraints      `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Safety      *SafetyConfig     `json:"safety,omitempty" yaml:"safety,omitempty"`
+	Escalation  *EscalationConfig `json:"escalation,omitempty" yaml:"escalation,omitempty"`
+	AccessTier  AccessTier        `json:"access_tier,omitempty" yaml:"access_tier,omitempty"`
+	Identity    *Identity         `json:"identity,omitempty" yaml:"identity,omitempty"`
+	// Triggers start a Task or Workflow from ossa scheduler.
+	Triggers []Trigger `json:"triggers,omitempty" yaml:"triggers,omitempty"`
+	// Extensions holds organization-specific settings by name, checked
+	// against any schema registered with RegisterExtensionSchema.
+	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	// VendorFields holds x- fields of spec; see VendorPrefix.
+	VendorFields map[string]interface{} `json:"-" yaml:"-"`
+}
+
+// PromptsConfig contains structured prompts, an alternative to role.
+type PromptsConfig struct {
+	System *PromptTemplate `json:"system,omitempty" yaml:"system,omitempty"`
+}
+
+// PromptTemplate is a prompt template, optionally keyed by locale.
+type PromptTemplate struct {
+	Template LocalizedText `json:"template" yaml:"template"`
+	Version  string        `json:"version,omitempty" yaml:"version,omitempty"`
 }
 
 // LLMConfig contains LLM configuration.
@@ -43,17 +77,343 @@ type LLMConfig struct {
 	Temperature float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
 	MaxTokens   int     `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
 	TopP        float64 `json:"topP,omitempty" yaml:"topP,omitempty"`
+	// FrequencyPenalty and PresencePenalty, from -2 to 2, discourage
+	// repeating tokens by how often and whether they appeared so far.
+	FrequencyPenalty float64 `json:"frequencyPenalty,omitempty" yaml:"frequencyPenalty,omitempty"`
+	PresencePenalty  float64 `json:"presencePenalty,omitempty" yaml:"presencePenalty,omitempty"`
+	// Stop sequences end the response where they appear.
+	Stop []string `json:"stop,omitempty" yaml:"stop,omitempty"`
+	// Seed asks for reproducible sampling, where the provider supports it.
+	Seed           *int            `json:"seed,omitempty" yaml:"seed,omitempty"`
+	ResponseFormat *ResponseFormat `json:"responseFormat,omitempty" yaml:"responseFormat,omitempty"`
+	// Azure and Bedrock locate the model for those providers.
+	Azure   *AzureConfig   `json:"azure,omitempty" yaml:"azure,omitempty"`
+	Bedrock *BedrockConfig `json:"bedrock,omitempty" yaml:"bedrock,omitempty"`
+	// Vertex has provider google call Gemini through Vertex AI.
+	Vertex *VertexConfig `json:"vertex,omitempty" yaml:"vertex,omitempty"`
+	// Pricing is the model's price, for cost-optimized routing.
+	Pricing *ModelPricing `json:"pricing,omitempty" yaml:"pricing,omitempty"`
+	// Fallbacks are tried when the model fails, in the order Routing
+	// picks.
+	Fallbacks []FallbackLLM `json:"fallbacks,omitempty" yaml:"fallbacks,omitempty"`
+	// FallbackModels is the deprecated name of Fallbacks.
+	FallbackModels []FallbackLLM  `json:"fallback_models,omitempty" yaml:"fallback_models,omitempty"`
+	Routing        *RoutingConfig `json:"routing,omitempty" yaml:"routing,omitempty"`
+	// RetryConfig retries each model's failed calls before falling back.
+	RetryConfig *RetryConfig `json:"retry_config,omitempty" yaml:"retry_config,omitempty"`
+	// Context budgets the prompt within the model's context window.
+	Context *ContextConfig `json:"context,omitempty" yaml:"context,omitempty"`
+}
+
+// Truncation strategies for ContextConfig.Truncation.
+const (
+	// TruncateOldest drops the oldest memory entries, then the oldest
+	// turns of the run.
+	TruncateOldest = "oldest"
+	// TruncateMiddle keeps the first memory entry and turn, dropping those
+	// after them first.
+	TruncateMiddle = "middle"
+	// TruncateError fails the turn rather than drop anything.
+	TruncateError = "error"
+)
+
+// ContextConfig is how the runtime fits each prompt in the context
+// window: the system prompt and tool schemas are kept whole, while
+// conversation memory and the run's history are truncated, by Truncation,
+// until the prompt fits.
+type ContextConfig struct {
+	// Window is the context window in tokens; 0 looks the model, and the
+	// smallest of its fallbacks, up in KnownContextWindows.
+	Window int `json:"window,omitempty" yaml:"window,omitempty"`
+	// ReserveTokens are kept free for the answer; they default to
+	// maxTokens, else 4096.
+	ReserveTokens int    `json:"reserve_tokens,omitempty" yaml:"reserve_tokens,omitempty"`
+	Truncation    string `json:"truncation,omitempty" yaml:"truncation,omitempty"`
+	// MemoryField is the input field holding conversation memory, a list
+	// of entries; it defaults to history.
+	MemoryField string          `json:"memory_field,omitempty" yaml:"memory_field,omitempty"`
+	Budgets     *ContextBudgets `json:"budgets,omitempty" yaml:"budgets,omitempty"`
+}
+
+// ContextBudgets caps the tokens of each part of the prompt; 0 leaves a
+// part only the window's limit.
+type ContextBudgets struct {
+	System  int `json:"system,omitempty" yaml:"system,omitempty"`
+	Tools   int `json:"tools,omitempty" yaml:"tools,omitempty"`
+	Memory  int `json:"memory,omitempty" yaml:"memory,omitempty"`
+	History int `json:"history,omitempty" yaml:"history,omitempty"`
+}
+
+// EmbeddingsConfig is an agent's embedding model. Azure, Bedrock and
+// Vertex locate it as they do the chat model of LLMConfig.
+type EmbeddingsConfig struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Model    string `json:"model" yaml:"model"`
+	// Dimensions shortens the vectors of models that allow it; 0 leaves
+	// the model's own.
+	Dimensions int            `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+	Azure      *AzureConfig   `json:"azure,omitempty" yaml:"azure,omitempty"`
+	Bedrock    *BedrockConfig `json:"bedrock,omitempty" yaml:"bedrock,omitempty"`
+	Vertex     *VertexConfig  `json:"vertex,omitempty" yaml:"vertex,omitempty"`
+}
+
+// Response formats for ResponseFormat.Type.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat constrains the model's answer to text, any JSON object or
+// JSON matching Schema.
+type ResponseFormat struct {
+	Type string `json:"type" yaml:"type"`
+	// Name names the schema, as OpenAI requires; it defaults to response.
+	Name   string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Schema map[string]interface{} `json:"schema,omitempty" yaml:"schema,omitempty"`
+	// Strict has OpenAI enforce the schema exactly.
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+}
+
+// Routing policies for RoutingConfig.Policy.
+const (
+	// RoutingFailover tries the model, then its fallbacks in order.
+	RoutingFailover = "failover"
+	// RoutingCostOptimized tries the cheapest by pricing first.
+	RoutingCostOptimized = "cost-optimized"
+	// RoutingLatencyOptimized tries the fastest so far first.
+	RoutingLatencyOptimized = "latency-optimized"
+)
+
+// RoutingConfig says in which order an agent's model and its fallbacks
+// are tried.
+type RoutingConfig struct {
+	// Policy defaults to RoutingFailover.
+	Policy string `json:"policy,omitempty" yaml:"policy,omitempty"`
+	// TimeoutMs bounds each call; one taking longer counts as failing.
+	TimeoutMs int `json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+}
+
+// ModelPricing is a model's price in dollars per million tokens.
+type ModelPricing struct {
+	InputPerMillion  float64 `json:"input_per_million,omitempty" yaml:"input_per_million,omitempty"`
+	OutputPerMillion float64 `json:"output_per_million,omitempty" yaml:"output_per_million,omitempty"`
+}
+
+// FallbackLLM is a model tried when an agent's model fails. Settings it
+// leaves out are the agent's.
+type FallbackLLM struct {
+	Provider    string           `json:"provider" yaml:"provider"`
+	Model       string           `json:"model" yaml:"model"`
+	Temperature float64          `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	MaxTokens   int              `json:"maxTokens,omitempty" yaml:"maxTokens,omitempty"`
+	Azure       *AzureConfig     `json:"azure,omitempty" yaml:"azure,omitempty"`
+	Bedrock     *BedrockConfig   `json:"bedrock,omitempty" yaml:"bedrock,omitempty"`
+	Vertex      *VertexConfig    `json:"vertex,omitempty" yaml:"vertex,omitempty"`
+	Pricing     *ModelPricing    `json:"pricing,omitempty" yaml:"pricing,omitempty"`
+	Trigger     *FallbackTrigger `json:"trigger,omitempty" yaml:"trigger,omitempty"`
+}
+
+// FallbackTrigger says which failures of the call tried before a fallback
+// hand over to it. Without error_codes any error does, unless on_error
+// is false; a timeout always does.
+type FallbackTrigger struct {
+	OnError    *bool `json:"on_error,omitempty" yaml:"on_error,omitempty"`
+	MaxRetries int   `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	// ErrorCodes are the HTTP statuses that hand over.
+	ErrorCodes []int `json:"error_codes,omitempty" yaml:"error_codes,omitempty"`
+	// LatencyThresholdMs bounds the call before, overriding
+	// routing.timeout_ms.
+	LatencyThresholdMs int `json:"latency_threshold_ms,omitempty" yaml:"latency_threshold_ms,omitempty"`
+}
+
+// AzureConfig is the Azure OpenAI deployment of provider azure. Endpoint
+// may be an environment variable reference.
+type AzureConfig struct {
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// Deployment defaults to the model name.
+	Deployment string `json:"deployment,omitempty" yaml:"deployment,omitempty"`
+	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
+	// APIKeyEnv names the variable holding the key, AZURE_OPENAI_API_KEY
+	// by default.
+	APIKeyEnv string `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`
+}
+
+// BedrockConfig is the AWS region, and optionally the endpoint, of
+// provider bedrock. Either may be an environment variable reference.
+type BedrockConfig struct {
+	Region   string `json:"region" yaml:"region"`
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+// VertexConfig is the Google Cloud project and location provider google
+// calls Gemini in. Each may be an environment variable reference; the
+// project defaults to GOOGLE_CLOUD_PROJECT.
+type VertexConfig struct {
+	Project  string `json:"project,omitempty" yaml:"project,omitempty"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
 }
 
 // ToolConfig contains tool configuration.
 type ToolConfig struct {
 	Type         string                 `json:"type" yaml:"type"`
 	Name         string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Description  string                 `json:"description,omitempty" yaml:"description,omitempty"`
 	Server       string                 `json:"server,omitempty" yaml:"server,omitempty"`
 	Namespace    string                 `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 	Endpoint     string                 `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
 	Capabilities []string               `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
 	Config       map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Handler      *ToolHandler           `json:"handler,omitempty" yaml:"handler,omitempty"`
+	// LLM overrides spec.llm for the turns handling this tool's results;
+	// see LLMConfig.Merge.
+	LLM *LLMConfig `json:"llm,omitempty" yaml:"llm,omitempty"`
+
+	// Gradual enablement; see EnabledFor.
+	Enabled        *bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	RolloutPercent *int   `json:"rollout_percent,omitempty" yaml:"rollout_percent,omitempty"`
+	Flag           string `json:"flag,omitempty" yaml:"flag,omitempty"`
+}
+
+// ToolHandler contains settings for how the runtime calls a tool.
+type ToolHandler struct {
+	Runtime        string                `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	Capability     string                `json:"capability,omitempty" yaml:"capability,omitempty"`
+	Module         string                `json:"module,omitempty" yaml:"module,omitempty"`
+	Script         string                `json:"script,omitempty" yaml:"script,omitempty"`
+	Endpoint       string                `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Method         string                `json:"method,omitempty" yaml:"method,omitempty"`
+	Headers        map[string]string     `json:"headers,omitempty" yaml:"headers,omitempty"`
+	TLS            *TLSConfig            `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Sandbox        *SandboxConfig        `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	SQL            *SQLConfig            `json:"sql,omitempty" yaml:"sql,omitempty"`
+	Exec           *ExecConfig           `json:"exec,omitempty" yaml:"exec,omitempty"`
+	Drupal         *DrupalConfig         `json:"drupal,omitempty" yaml:"drupal,omitempty"`
+	SMTP           *SMTPConfig           `json:"smtp,omitempty" yaml:"smtp,omitempty"`
+	Slack          *SlackConfig          `json:"slack,omitempty" yaml:"slack,omitempty"`
+	Queue          *QueueConfig          `json:"queue,omitempty" yaml:"queue,omitempty"`
+	Timeout        Duration              `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Retries        *RetryConfig          `json:"retries,omitempty" yaml:"retries,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty" yaml:"circuit_breaker,omitempty"`
+	Cache          *CacheConfig          `json:"cache,omitempty" yaml:"cache,omitempty"`
+	MaxConcurrency int                   `json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+}
+
+// CacheConfig opts an idempotent tool into result caching.
+type CacheConfig struct {
+	TTL       Duration `json:"ttl" yaml:"ttl"`
+	KeyFields []string `json:"key_fields,omitempty" yaml:"key_fields,omitempty"`
+	Backend   string   `json:"backend,omitempty" yaml:"backend,omitempty"`
+}
+
+// SQLConfig configures a tool run with handler.runtime: sql. The DSN is
+// read from the environment variable named by DSNEnv, never the manifest.
+type SQLConfig struct {
+	Driver     string   `json:"driver" yaml:"driver"`
+	DSNEnv     string   `json:"dsn_env" yaml:"dsn_env"`
+	Query      string   `json:"query,omitempty" yaml:"query,omitempty"`
+	Params     []string `json:"params,omitempty" yaml:"params,omitempty"`
+	Statements []string `json:"statements,omitempty" yaml:"statements,omitempty"`
+	MaxRows    int      `json:"max_rows,omitempty" yaml:"max_rows,omitempty"`
+}
+
+// ExecConfig configures a tool run with handler.runtime: exec. Each entry
+// in Args is one argument; {{name}} placeholders are replaced with call
+// arguments and no shell is involved.
+type ExecConfig struct {
+	Command        string   `json:"command" yaml:"command"`
+	Args           []string `json:"args,omitempty" yaml:"args,omitempty"`
+	WorkingDir     string   `json:"working_dir,omitempty" yaml:"working_dir,omitempty"`
+	MaxOutputBytes int      `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+}
+
+// DrupalConfig configures a tool run with handler.runtime: drupal. The
+// handler's capability selects the JSON:API resource or a custom REST
+// endpoint; credentials are read from the named environment variables.
+type DrupalConfig struct {
+	BaseURL         string                    `json:"base_url" yaml:"base_url"`
+	Auth            string                    `json:"auth,omitempty" yaml:"auth,omitempty"`
+	UsernameEnv     string                    `json:"username_env,omitempty" yaml:"username_env,omitempty"`
+	PasswordEnv     string                    `json:"password_env,omitempty" yaml:"password_env,omitempty"`
+	ClientIDEnv     string                    `json:"client_id_env,omitempty" yaml:"client_id_env,omitempty"`
+	ClientSecretEnv string                    `json:"client_secret_env,omitempty" yaml:"client_secret_env,omitempty"`
+	Endpoints       map[string]DrupalEndpoint `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+}
+
+// DrupalEndpoint is a custom REST resource. Path may contain {name}
+// placeholders filled from call arguments.
+type DrupalEndpoint struct {
+	Path   string `json:"path" yaml:"path"`
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+}
+
+// SMTPConfig configures a tool run with handler.runtime: smtp. Subject
+// and Body are text/template templates over the call arguments.
+type SMTPConfig struct {
+	Addr        string   `json:"addr" yaml:"addr"`
+	From        string   `json:"from" yaml:"from"`
+	To          []string `json:"to" yaml:"to"`
+	Subject     string   `json:"subject" yaml:"subject"`
+	Body        string   `json:"body" yaml:"body"`
+	UsernameEnv string   `json:"username_env,omitempty" yaml:"username_env,omitempty"`
+	PasswordEnv string   `json:"password_env,omitempty" yaml:"password_env,omitempty"`
+}
+
+// SlackConfig configures a tool run with handler.runtime: slack. Messages
+// are posted with the bot token in TokenEnv, or to the incoming webhook URL
+// in WebhookEnv. Text is a text/template template over the call arguments.
+type SlackConfig struct {
+	Channel    string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Text       string `json:"text" yaml:"text"`
+	TokenEnv   string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	WebhookEnv string `json:"webhook_env,omitempty" yaml:"webhook_env,omitempty"`
+}
+
+// QueueConfig names a message queue subject (a NATS subject, Kafka topic
+// or SQS queue): where a queue trigger reads messages, or where a tool
+// with handler.runtime: queue publishes. The broker URL can be read from
+// the environment variable URLEnv instead, keeping credentials out of the
+// manifest. Group shares a trigger's messages among consumers; Reply is
+// where a trigger publishes each run's result.
+type QueueConfig struct {
+	Transport string `json:"transport" yaml:"transport"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	URLEnv    string `json:"url_env,omitempty" yaml:"url_env,omitempty"`
+	Subject   string `json:"subject" yaml:"subject"`
+	Group     string `json:"group,omitempty" yaml:"group,omitempty"`
+	Reply     string `json:"reply,omitempty" yaml:"reply,omitempty"`
+}
+
+// SandboxConfig declares what a sandboxed tool may access.
+type SandboxConfig struct {
+	Mounts        []SandboxMount    `json:"mounts,omitempty" yaml:"mounts,omitempty"`
+	Env           map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	MemoryLimitMB int               `json:"memory_limit_mb,omitempty" yaml:"memory_limit_mb,omitempty"`
+	AllowedHosts  []string          `json:"allowed_hosts,omitempty" yaml:"allowed_hosts,omitempty"`
+}
+
+// SandboxMount exposes a host directory to a sandboxed tool.
+type SandboxMount struct {
+	Host     string `json:"host" yaml:"host"`
+	Guest    string `json:"guest" yaml:"guest"`
+	ReadOnly bool   `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// RetryConfig contains retry and backoff settings.
+type RetryConfig struct {
+	MaxAttempts     int    `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	BackoffStrategy string `json:"backoff_strategy,omitempty" yaml:"backoff_strategy,omitempty"`
+	InitialDelayMs  int    `json:"initial_delay_ms,omitempty" yaml:"initial_delay_ms,omitempty"`
+	MaxDelayMs      int    `json:"max_delay_ms,omitempty" yaml:"max_delay_ms,omitempty"`
+}
+
+// CircuitBreakerConfig contains circuit breaker settings.
+type CircuitBreakerConfig struct {
+	FailureThreshold int      `json:"failure_threshold,omitempty" yaml:"failure_threshold,omitempty"`
+	ResetTimeout     Duration `json:"reset_timeout,omitempty" yaml:"reset_timeout,omitempty"`
+	HalfOpenMaxCalls int      `json:"half_open_max_calls,omitempty" yaml:"half_open_max_calls,omitempty"`
 }
 
 // AutonomyConfig contains autonomy settings.
@@ -84,3 +444,70 @@ type PerformanceConstraints struct {
 	MaxConcurrentRequests int     `json:"maxConcurrentRequests,omitempty" yaml:"maxConcurrentRequests,omitempty"`
 	TimeoutSeconds        int     `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
 }
+
+// SafetyConfig contains agent safety settings.
+type SafetyConfig struct {
+	Moderation         *ModerationConfig         `json:"moderation,omitempty" yaml:"moderation,omitempty"`
+	InjectionDetection *InjectionDetectionConfig `json:"injection_detection,omitempty" yaml:"injection_detection,omitempty"`
+	Guardrails         *Guardrails               `json:"guardrails,omitempty" yaml:"guardrails,omitempty"`
+}
+
+// Guardrails limit what the agent may do without a human.
+type Guardrails struct {
+	MaxActionsPerMinute     int      `json:"max_actions_per_minute,omitempty" yaml:"max_actions_per_minute,omitempty"`
+	RequireHumanApprovalFor []string `json:"require_human_approval_for,omitempty" yaml:"require_human_approval_for,omitempty"`
+	BlockedActions          []string `json:"blocked_actions,omitempty" yaml:"blocked_actions,omitempty"`
+	AuditAllActions         bool     `json:"audit_all_actions,omitempty" yaml:"audit_all_actions,omitempty"`
+}
+
+// ModerationConfig configures content moderation of prompts and completions.
+type ModerationConfig struct {
+	Enabled  bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// APIKeyEnv names the variable holding the provider's key,
+	// OPENAI_API_KEY by default for provider openai. Manifests name the
+	// variable rather than embed the key, so they cannot send other
+	// secrets to the endpoint.
+	APIKeyEnv string            `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`
+	Keywords  []string          `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	Action    ModerationAction  `json:"action,omitempty" yaml:"action,omitempty"`
+	Stages    []ModerationStage `json:"stages,omitempty" yaml:"stages,omitempty"`
+}
+
+// InjectionDetectionConfig configures prompt-injection scanning of tool
+// outputs and retrieved documents. Allowlist entries are phrases or source
+// names that are never reported, matched whole; see NewInjectionAnalyzer.
+type InjectionDetectionConfig struct {
+	Enabled     bool             `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Sensitivity string           `json:"sensitivity,omitempty" yaml:"sensitivity,omitempty"`
+	Allowlist   []string         `json:"allowlist,omitempty" yaml:"allowlist,omitempty"`
+	Action      ModerationAction `json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// EscalationConfig lists the humans to contact when the agent misbehaves.
+// Severity maps a severity level to one of oncall_channel, email or pager.
+type EscalationConfig struct {
+	OnCallChannel string            `json:"oncall_channel,omitempty" yaml:"oncall_channel,omitempty"`
+	Email         string            `json:"email,omitempty" yaml:"email,omitempty"`
+	Pager         *PagerConfig      `json:"pager,omitempty" yaml:"pager,omitempty"`
+	Severity      map[string]string `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// Trigger starts a Task or Workflow: on a cron schedule, on a webhook
+// request to Path, when a file matching Paths changes or, for Agents too,
+// on a message from Queue. Input is merged under what the event provides.
+type Trigger struct {
+	Type     string                 `json:"type" yaml:"type"`
+	Schedule string                 `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Path     string                 `json:"path,omitempty" yaml:"path,omitempty"`
+	Paths    []string               `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Queue    *QueueConfig           `json:"queue,omitempty" yaml:"queue,omitempty"`
+	Input    map[string]interface{} `json:"input,omitempty" yaml:"input,omitempty"`
+}
+
+// PagerConfig identifies a paging service.
+type PagerConfig struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Service  string `json:"service" yaml:"service"`
+}
@@ -5,48 +5,139 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sync"
 
 	"github.com/xeipuuv/gojsonschema"
 )
 
-// ValidationResult contains validation results.
+// ValidationResult contains validation results. Warnings are non-fatal
+// findings and do not affect Valid; see WarningDeprecated.
 type ValidationResult struct {
 	Valid    bool
 	Errors   []string
 	Warnings []string
 }
 
-// Validator validates OSSA manifests.
+// Validator validates OSSA manifests. It is immutable once built, so one
+// Validator, and the schema it compiled, can be reused across calls and
+// shared by any number of goroutines.
 type Validator struct {
-	schemaPath string
-	schema     *gojsonschema.Schema
+	schema *gojsonschema.Schema
+	strict bool
+	rules  []Rule
 }
 
-// NewValidator creates a new validator.
-// If schemaPath is provided, loads JSON Schema for validation.
-// If empty, only structural validation is performed.
-func NewValidator(schemaPath string) *Validator {
-	v := &Validator{schemaPath: schemaPath}
-	if schemaPath != "" {
-		v.loadSchema()
+// Option configures a Validator built by NewValidator.
+type Option func(*validatorOptions)
+
+type validatorOptions struct {
+	schema func() ([]byte, error)
+	store  SchemaStore
+	strict bool
+	rules  []Rule
+}
+
+// WithVersion validates against the JSON Schema of an OSSA version: the
+// one vendored in the WithSchemaStore store, or else the embedded one. ""
+// means OSSAVersion. Each version's schema is compiled once per process.
+func WithVersion(version string) Option {
+	return func(o *validatorOptions) {
+		o.schema = func() ([]byte, error) { return ResolveSchema(o.store, version) }
 	}
-	return v
 }
 
-func (v *Validator) loadSchema() {
-	if v.schemaPath == "" {
-		return
+// WithSchemaStore is where WithVersion looks for vendored schemas, such
+// as FindSchemaStore(dir).
+func WithSchemaStore(store SchemaStore) Option {
+	return func(o *validatorOptions) { o.store = store }
+}
+
+// WithSchema validates against the JSON Schema in schema.
+func WithSchema(schema []byte) Option {
+	return func(o *validatorOptions) {
+		o.schema = func() ([]byte, error) { return schema, nil }
 	}
-	data, err := os.ReadFile(v.schemaPath)
-	if err != nil {
-		return
+}
+
+// WithSchemaFile validates against the JSON Schema in a file.
+func WithSchemaFile(path string) Option {
+	return func(o *validatorOptions) {
+		o.schema = func() ([]byte, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read schema: %w", err)
+			}
+			return data, nil
+		}
 	}
-	loader := gojsonschema.NewBytesLoader(data)
-	schema, err := gojsonschema.NewSchema(loader)
+}
+
+// WithStrict makes warnings errors, as PromoteWarnings does.
+func WithStrict() Option {
+	return func(o *validatorOptions) { o.strict = true }
+}
+
+// WithSemanticRules adds rules this Validator runs after those added with
+// RegisterRule, without registering them for every Validator.
+func WithSemanticRules(rules ...Rule) Option {
+	return func(o *validatorOptions) {
+		for _, r := range rules {
+			if r.Check == nil {
+				panic("ossa: WithSemanticRules with nil Check")
+			}
+		}
+		o.rules = append(o.rules, rules...)
+	}
+}
+
+// WithPolicyBundle adds the rules of an organization's policies, as
+// WithSemanticRules does.
+func WithPolicyBundle(b *PolicyBundle) Option {
+	return WithSemanticRules(b.Rules()...)
+}
+
+// NewValidator builds a validator. Without a schema option only the
+// structural checks and rules run; the last schema option given wins.
+func NewValidator(opts ...Option) (*Validator, error) {
+	o := &validatorOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	v := &Validator{strict: o.strict, rules: o.rules}
+	if o.schema != nil {
+		data, err := o.schema()
+		if err != nil {
+			return nil, err
+		}
+		if v.schema, err = cachedSchema(data); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+var compiledSchemas sync.Map
+
+// cachedSchema compiles schema, or returns it compiled by an earlier call.
+func cachedSchema(data []byte) (*gojsonschema.Schema, error) {
+	key := sha256Hex(data)
+	if s, ok := compiledSchemas.Load(key); ok {
+		return s.(*gojsonschema.Schema), nil
+	}
+	s, err := compileSchema(data)
 	if err != nil {
-		return
+		return nil, err
 	}
-	v.schema = schema
+	compiledSchemas.Store(key, s)
+	return s, nil
+}
+
+var defaultValidator = &Validator{}
+
+// DefaultValidator returns the Validator ValidateManifest uses: the
+// structural checks and registered rules, without a schema.
+func DefaultValidator() *Validator {
+	return defaultValidator
 }
 
 // ValidKinds are the valid manifest kinds.
@@ -60,6 +151,16 @@ var apiVersionPattern = regexp.MustCompile(`^ossa/v\d+\.\d+\.\d+$`)
 
 // Validate validates a manifest.
 func (v *Validator) Validate(m *Manifest) *ValidationResult {
+	result := v.validate(m)
+	if v.strict {
+		result.PromoteWarnings()
+	}
+	Logger().Debug("manifest validated", "kind", m.Kind, "name", m.Metadata.Name,
+		"valid", result.Valid, "errors", len(result.Errors), "warnings", len(result.Warnings))
+	return result
+}
+
+func (v *Validator) validate(m *Manifest) *ValidationResult {
 	result := &ValidationResult{Valid: true}
 
 	// Required fields
@@ -83,9 +184,41 @@ func (v *Validator) Validate(m *Manifest) *ValidationResult {
 		result.addWarning("Agent should have spec.role")
 	}
 
+	validateAccessTier(m, result)
+	validateDeprecations(m, result)
+	validateShorthand(m, result)
+
+	validateLocales("spec.role", m.Spec.RoleLocales, result)
+	if p := m.Spec.Prompts; p != nil && p.System != nil {
+		validateLocales("spec.prompts.system.template", p.System.Template.Locales, result)
+	}
+
+	if m.Spec.Safety != nil {
+		validateModeration(m.Spec.Safety.Moderation, result)
+		validateInjectionDetection(m.Spec.Safety.InjectionDetection, result)
+	}
+
+	validateLLM(m.Spec.LLM, result)
+	validateEmbeddings(m.Spec.Embeddings, result)
+	validateInput(m, result)
+	if m.Spec.Egress != nil {
+		validateEgress("spec.egress", m.Spec.Egress, result)
+	}
+	validateState(m, result)
+	validateEscalation(m.Spec.Escalation, result)
+	validateTriggers(m, result)
+	validateTools(m, result)
+	validateExtensions(m, result)
+	validateRules(m, result)
+	for _, rule := range v.rules {
+		runRule(rule, m, &RuleReport{rule: rule.Name, result: result})
+	}
+
 	// JSON Schema validation if schema loaded
 	if v.schema != nil && result.Valid {
-		data, err := json.Marshal(m)
+		// The schema predates locale maps and extensions, so check the
+		// default variant without them.
+		data, err := json.Marshal(m.Localize(DefaultLocale).WithoutExtensions())
 		if err == nil {
 			docLoader := gojsonschema.NewBytesLoader(data)
 			schemaResult, err := v.schema.Validate(docLoader)
@@ -99,16 +232,28 @@ func (v *Validator) Validate(m *Manifest) *ValidationResult {
 
 	// Best practices
 	if m.Spec.LLM == nil {
-		result.addWarning("Best practice: Specify LLM configuration")
+		result.addWarning(WarningBestPractice + ": Specify LLM configuration")
 	}
 
 	if len(m.Spec.Tools) == 0 {
-		result.addWarning("Best practice: Define tools/capabilities")
+		result.addWarning(WarningBestPractice + ": Define tools/capabilities")
 	}
 
 	return result
 }
 
+func validateTools(m *Manifest, result *ValidationResult) {
+	for i, tool := range m.Spec.Tools {
+		path := fmt.Sprintf("spec.tools[%d]", i)
+		validateToolHandler(path+".handler", tool.Handler, result)
+		validateRollout(path, tool, result)
+		validateSandbox(path, m, tool, result)
+		if tool.LLM != nil {
+			validateLLMOverride(path+".llm", m.Spec.LLM, tool.LLM, result)
+		}
+	}
+}
+
 func (r *ValidationResult) addError(msg string) {
 	r.Valid = false
 	r.Errors = append(r.Errors, msg)
@@ -120,5 +265,24 @@ func (r *ValidationResult) addWarning(msg string) {
 
 // ValidateManifest validates a manifest (convenience function).
 func ValidateManifest(m *Manifest) *ValidationResult {
-	return NewValidator("").Validate(m)
+	return defaultValidator.Validate(m)
+}
+
+// ValidateFile loads the manifest at path and validates it against the JSON
+// Schema in schemaPath, or with ValidateManifest's checks if schemaPath is
+// "". The error reports a manifest or schema that could not be loaded.
+func ValidateFile(path, schemaPath string) (*ValidationResult, error) {
+	m, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if schemaPath != "" {
+		opts = append(opts, WithSchemaFile(schemaPath))
+	}
+	v, err := NewValidator(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return v.Validate(m), nil
 }
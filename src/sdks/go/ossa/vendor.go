@@ -0,0 +1,230 @@
+package ossa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VendorDir is where a project vendors schemas, relative to its root.
+const VendorDir = ".ossa/schemas"
+
+// vendorLock pins each vendored schema's source and sha256.
+const vendorLock = "lock.yaml"
+
+// ErrSchemaIntegrity is returned when a vendored schema no longer matches
+// its pinned sha256.
+var ErrSchemaIntegrity = errors.New("vendored schema does not match its pinned sha256")
+
+// ErrSchemaNotFound is returned by ResolveSchema for a version that is
+// neither vendored nor embedded.
+var ErrSchemaNotFound = errors.New("schema version not available")
+
+// VendoredSchema is one entry of the vendor lock file.
+type VendoredSchema struct {
+	Version string `yaml:"version"`
+	File    string `yaml:"file"`
+	Source  string `yaml:"source"`
+	SHA256  string `yaml:"sha256"`
+}
+
+// SchemaStore holds vendored schemas by version. DirSchemaStore, a
+// project's VendorDir, is the default; a registry or test can substitute
+// its own.
+type SchemaStore interface {
+	// List returns the vendored schemas sorted by version.
+	List() ([]VendoredSchema, error)
+	// Load returns the schema for version, or ErrSchemaNotFound.
+	Load(version string) ([]byte, error)
+	// Add vendors data as version's schema, pinned to its source.
+	Add(version, source string, data []byte) (VendoredSchema, error)
+}
+
+// DirSchemaStore is a project's vendored schema directory. A nil
+// *DirSchemaStore is an empty store.
+type DirSchemaStore struct {
+	Dir string
+}
+
+// FindSchemaStore looks for VendorDir in start and its parents, as git
+// looks for .git, and returns nil if there is none.
+func FindSchemaStore(start string) *DirSchemaStore {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil
+	}
+	for {
+		candidate := filepath.Join(dir, VendorDir)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return &DirSchemaStore{Dir: candidate}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// List returns the vendored schemas sorted by version.
+func (s *DirSchemaStore) List() ([]VendoredSchema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, vendorLock))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, WrapError("failed to read schema lock", err)
+	}
+	var lock struct {
+		Schemas []VendoredSchema `yaml:"schemas"`
+	}
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, WrapError("failed to parse schema lock", err)
+	}
+	return lock.Schemas, nil
+}
+
+// Add vendors a schema for version, pinning its sha256 and source (a URL
+// or path, kept so the schema can be updated later). Adding a version
+// again replaces it and re-pins.
+func (s *DirSchemaStore) Add(version, source string, data []byte) (VendoredSchema, error) {
+	if !schemaVersionPattern.MatchString(version) {
+		return VendoredSchema{}, NewError(fmt.Sprintf("invalid schema version %q", version))
+	}
+	if _, err := compileSchema(data); err != nil {
+		return VendoredSchema{}, err
+	}
+	entries, err := s.List()
+	if err != nil {
+		return VendoredSchema{}, err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return VendoredSchema{}, WrapError("failed to create schema directory", err)
+	}
+
+	entry := VendoredSchema{
+		Version: version,
+		File:    "ossa-" + version + ".schema.json",
+		Source:  source,
+		SHA256:  sha256Hex(data),
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, entry.File), data, 0o644); err != nil {
+		return VendoredSchema{}, WrapError("failed to write schema", err)
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.Version != version {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, entry)
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Version < kept[j].Version })
+
+	out, err := yaml.Marshal(map[string]interface{}{"schemas": kept})
+	if err != nil {
+		return VendoredSchema{}, WrapError("failed to marshal schema lock", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, vendorLock), out, 0o644); err != nil {
+		return VendoredSchema{}, WrapError("failed to write schema lock", err)
+	}
+	return entry, nil
+}
+
+// Load returns the vendored schema for version after checking it against
+// its pinned sha256. It returns ErrSchemaNotFound if the version is not
+// vendored and ErrSchemaIntegrity if the file was changed.
+func (s *DirSchemaStore) Load(version string) ([]byte, error) {
+	entry, err := s.entry(version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, entry.File))
+	if err != nil {
+		return nil, WrapError("failed to read vendored schema", err)
+	}
+	if sha256Hex(data) != entry.SHA256 {
+		return nil, WrapError(entry.File, ErrSchemaIntegrity)
+	}
+	return data, nil
+}
+
+// Path returns the file of the vendored schema for version.
+func (s *DirSchemaStore) Path(version string) (string, error) {
+	entry, err := s.entry(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, entry.File), nil
+}
+
+func (s *DirSchemaStore) entry(version string) (VendoredSchema, error) {
+	entries, err := s.List()
+	if err != nil {
+		return VendoredSchema{}, err
+	}
+	for _, e := range entries {
+		if e.Version == version {
+			return e, nil
+		}
+	}
+	return VendoredSchema{}, WrapError("version "+version, ErrSchemaNotFound)
+}
+
+// ResolveSchema returns the schema for version, preferring a vendored
+// schema in store (which may be nil) over the embedded one. An empty
+// version means OSSAVersion.
+func ResolveSchema(store SchemaStore, version string) ([]byte, error) {
+	if version == "" {
+		version = OSSAVersion
+	}
+	if store != nil {
+		data, err := store.Load(version)
+		if err == nil || !errors.Is(err, ErrSchemaNotFound) {
+			return data, err
+		}
+	}
+	if version == OSSAVersion {
+		return EmbeddedSchema(), nil
+	}
+	return nil, WrapError("version "+version, ErrSchemaNotFound)
+}
+
+var (
+	schemaVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+	schemaIDVersion      = regexp.MustCompile(`v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z]+)?)`)
+)
+
+// SchemaVersion guesses a schema's spec version from its $id or title,
+// e.g. https://openstandardagents.org/schemas/v0.3.3/manifest.json. It
+// returns "" if neither names one.
+func SchemaVersion(data []byte) string {
+	var head struct {
+		ID    string `json:"$id"`
+		Title string `json:"title"`
+	}
+	if json.Unmarshal(data, &head) != nil {
+		return ""
+	}
+	for _, s := range []string{head.ID, head.Title} {
+		if m := schemaIDVersion.FindStringSubmatch(s); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
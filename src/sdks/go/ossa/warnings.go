@@ -0,0 +1,43 @@
+package ossa
+
+import "fmt"
+
+// Warnings never make a manifest invalid. Validate reports them with a
+// prefix naming their kind so tooling can filter them:
+//
+//	Deprecated:     the manifest uses a form in Deprecations()
+//	Shorthand:      a short form was expanded; write the full form
+//	Best practice:  the manifest works but is missing something useful
+//	Rule:           a rule added with RegisterRule warned
+const (
+	WarningDeprecated   = "Deprecated"
+	WarningShorthand    = "Shorthand"
+	WarningBestPractice = "Best practice"
+	WarningRule         = "Rule"
+)
+
+func validateShorthand(m *Manifest, result *ValidationResult) {
+	check := func(path string, t AccessTier) {
+		if full := t.Normalize(); full != t {
+			result.addWarning(fmt.Sprintf("%s: %s %s; write %s", WarningShorthand, path, t, full))
+		}
+	}
+	check("spec.access_tier", m.Spec.AccessTier)
+	if m.Spec.Identity != nil {
+		check("spec.identity.access_tier", m.Spec.Identity.AccessTier)
+	}
+}
+
+// HasWarnings reports whether validation produced any warnings.
+func (r *ValidationResult) HasWarnings() bool {
+	return len(r.Warnings) > 0
+}
+
+// PromoteWarnings turns warnings into errors, for callers that treat any
+// finding as fatal (ossa validate --warnings-as-errors).
+func (r *ValidationResult) PromoteWarnings() {
+	for _, w := range r.Warnings {
+		r.addError(w)
+	}
+	r.Warnings = nil
+}
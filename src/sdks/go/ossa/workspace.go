@@ -0,0 +1,107 @@
+package ossa
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogEntry is one manifest found by ScanWorkspace or listed by a
+// ManifestStore.
+type CatalogEntry struct {
+	Path     string
+	Name     string
+	Kind     Kind
+	Tier     AccessTier
+	Model    string
+	Modified time.Time
+	// Manifest is nil and Err set when the file failed to load.
+	Manifest *Manifest
+	Err      error
+}
+
+// ScanWorkspace finds the manifests below dir. Files named *.ossa.yaml,
+// *.ossa.yml or *.ossa.json are always listed, with Err set if they fail to
+// load; other YAML and JSON files are listed only if they load with an ossa/
+// apiVersion. Hidden directories are skipped. Entries are sorted by path.
+func ScanWorkspace(dir string) ([]CatalogEntry, error) {
+	walk := func(fn fs.WalkDirFunc) error { return filepath.WalkDir(dir, fn) }
+	return scanWorkspace(dir, walk, LoadManifest)
+}
+
+// ScanWorkspaceFS is ScanWorkspace for the directory dir in fsys ("." for
+// its root). Entry paths are fsys names, such as agents/triage.ossa.yaml,
+// for LoadManifestFS; Modified is whatever fsys reports.
+func ScanWorkspaceFS(fsys fs.FS, dir string) ([]CatalogEntry, error) {
+	walk := func(fn fs.WalkDirFunc) error { return fs.WalkDir(fsys, dir, fn) }
+	load := func(name string) (*Manifest, error) { return LoadManifestFS(fsys, name) }
+	return scanWorkspace(dir, walk, load)
+}
+
+func scanWorkspace(dir string, walk func(fs.WalkDirFunc) error, load func(string) (*Manifest, error)) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	err := walk(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+		named := IsManifestName(d.Name())
+
+		entry := CatalogEntry{Path: path}
+		if info, err := d.Info(); err == nil {
+			entry.Modified = info.ModTime()
+		}
+		m, err := load(path)
+		switch {
+		case err != nil:
+			if !named {
+				return nil
+			}
+			entry.Err = err
+		case !named && !strings.HasPrefix(m.APIVersion, "ossa/"):
+			return nil
+		default:
+			entry.setManifest(m)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, WrapError("failed to scan workspace", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// setManifest fills in e from its loaded manifest.
+func (e *CatalogEntry) setManifest(m *Manifest) {
+	e.Manifest = m
+	e.Name = m.Metadata.Name
+	e.Kind = m.Kind
+	e.Tier = m.GetAccessTier()
+	if m.Spec.LLM != nil {
+		e.Model = m.Spec.LLM.Model
+	}
+}
+
+// IsManifestName reports whether a file name follows the *.ossa.yaml,
+// *.ossa.yml or *.ossa.json convention.
+func IsManifestName(name string) bool {
+	name = strings.ToLower(filepath.Base(name))
+	ext := filepath.Ext(name)
+	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSuffix(name, ext), ".ossa")
+}
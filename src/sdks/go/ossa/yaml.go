@@ -0,0 +1,157 @@
+package ossa
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MaxYAMLAliasNodes is the default cap on how many nodes alias
+	// expansion may copy, so a "billion laughs" document cannot exhaust
+	// memory.
+	MaxYAMLAliasNodes = 100000
+	// MaxYAMLDepth is the default cap on nesting after expansion; it also
+	// stops aliases that refer to their own ancestors.
+	MaxYAMLDepth = 256
+)
+
+var (
+	// ErrYAMLAliasLimit matches the LimitError returned when alias
+	// expansion exceeds ParseLimits.MaxAliasNodes.
+	ErrYAMLAliasLimit = NewError("yaml alias expansion limit exceeded")
+	// ErrYAMLDepthLimit matches the LimitError returned when a document
+	// nests deeper than ParseLimits.MaxDepth.
+	ErrYAMLDepthLimit = NewError("yaml nesting depth limit exceeded")
+)
+
+// ResolveYAML expands anchors, aliases and <<: merge keys in a YAML
+// document and returns the equivalent plain YAML. Merge keys follow the
+// YAML 1.1 merge type: keys written in the mapping override merged ones,
+// and with a sequence of sources the earlier source wins. ParseManifest
+// applies the same step before decoding.
+func ResolveYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	r := &yamlResolver{limits: DefaultParseLimits, counts: map[string]int{}}
+	resolved, err := r.expand(&doc, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Kind == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(resolved)
+}
+
+type yamlResolver struct {
+	limits ParseLimits
+	copied int
+	// counts holds list lengths under countedKeys.
+	counts map[string]int
+}
+
+func (r *yamlResolver) expand(n *yaml.Node, depth int, aliased bool) (*yaml.Node, error) {
+	if depth > r.limits.MaxDepth {
+		return nil, newLimitError(LimitDepth, r.limits.MaxDepth, depth)
+	}
+	if n.Kind == yaml.AliasNode {
+		if n.Alias == nil {
+			return nil, NewError(fmt.Sprintf("yaml line %d: unknown alias %s", n.Line, n.Value))
+		}
+		n, aliased = n.Alias, true
+	}
+	if aliased {
+		if r.copied++; r.copied > r.limits.MaxAliasNodes {
+			return nil, newLimitError(LimitAliasNodes, r.limits.MaxAliasNodes, r.copied)
+		}
+	}
+
+	out := *n
+	out.Anchor = ""
+	out.Alias = nil
+	out.Content = nil
+	if n.Kind != yaml.MappingNode {
+		for _, child := range n.Content {
+			c, err := r.expand(child, depth+1, aliased)
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, c)
+		}
+		return &out, nil
+	}
+
+	type pair struct{ key, value *yaml.Node }
+	var explicit, merged []pair
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		value, err := r.expand(n.Content[i+1], depth+1, aliased)
+		if err != nil {
+			return nil, err
+		}
+		if key := n.Content[i]; key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge" {
+			sources := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				sources = value.Content
+			}
+			for _, src := range sources {
+				if src.Kind != yaml.MappingNode {
+					return nil, NewError(fmt.Sprintf("yaml line %d: merge key value must be a mapping or a sequence of mappings", key.Line))
+				}
+				for j := 0; j+1 < len(src.Content); j += 2 {
+					merged = append(merged, pair{src.Content[j], src.Content[j+1]})
+				}
+			}
+			continue
+		}
+		key, err := r.expand(n.Content[i], depth+1, aliased)
+		if err != nil {
+			return nil, err
+		}
+		if limit, ok := countedKeys[key.Value]; ok && key.Kind == yaml.ScalarNode && value.Kind == yaml.SequenceNode {
+			r.counts[limit] += len(value.Content)
+		}
+		explicit = append(explicit, pair{key, value})
+	}
+
+	seen := map[string]bool{}
+	for _, p := range explicit {
+		if p.key.Kind == yaml.ScalarNode {
+			seen[p.key.Value] = true
+		}
+		out.Content = append(out.Content, p.key, p.value)
+	}
+	for _, p := range merged {
+		if p.key.Kind == yaml.ScalarNode {
+			if seen[p.key.Value] {
+				continue
+			}
+			seen[p.key.Value] = true
+		}
+		out.Content = append(out.Content, p.key, p.value)
+	}
+	return &out, nil
+}
+
+// decodeYAML resolves aliases and merge keys, checks limits, then decodes
+// into v.
+func decodeYAML(data []byte, v interface{}, limits ParseLimits) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	r := &yamlResolver{limits: limits, counts: map[string]int{}}
+	resolved, err := r.expand(&doc, 0, false)
+	if err != nil {
+		return err
+	}
+	if err := limits.checkCounts(r.counts); err != nil {
+		return err
+	}
+	if resolved.Kind == 0 {
+		return nil
+	}
+	return resolved.Decode(v)
+}
@@ -0,0 +1,161 @@
+// Package ossatest provides helpers for testing Go code that generates OSSA
+// manifests: validity assertions, golden files and fixture builders.
+//
+//	func TestGenerate(t *testing.T) {
+//		m := generate()
+//		ossatest.AssertValidManifest(t, m)
+//		ossatest.Golden(t, m)
+//	}
+//
+// Golden files live in testdata/<test name>.golden.yaml and are rewritten
+// by running the tests with -ossatest.update.
+package ossatest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+var update = flag.Bool("ossatest.update", false, "rewrite ossatest golden files")
+
+// AssertValid loads the manifest at path and fails t unless it validates.
+// It returns the manifest, or nil if it failed to load.
+func AssertValid(t testing.TB, path string) *ossa.Manifest {
+	t.Helper()
+	m, err := ossa.LoadManifest(path)
+	if err != nil {
+		t.Errorf("failed to load %s: %v", path, err)
+		return nil
+	}
+	assertValid(t, path, m)
+	return m
+}
+
+// AssertValidManifest fails t unless m validates.
+func AssertValidManifest(t testing.TB, m *ossa.Manifest) {
+	t.Helper()
+	assertValid(t, m.Metadata.Name, m)
+}
+
+func assertValid(t testing.TB, name string, m *ossa.Manifest) {
+	t.Helper()
+	result := ossa.ValidateManifest(m)
+	if !result.Valid {
+		t.Errorf("%s is invalid:\n  %s", name, strings.Join(result.Errors, "\n  "))
+	}
+}
+
+// AssertInvalid fails t unless m has a validation error containing want.
+func AssertInvalid(t testing.TB, m *ossa.Manifest, want string) {
+	t.Helper()
+	result := ossa.ValidateManifest(m)
+	for _, msg := range result.Errors {
+		if strings.Contains(msg, want) {
+			return
+		}
+	}
+	t.Errorf("expected a validation error containing %q, got %q", want, result.Errors)
+}
+
+// Golden compares the YAML form of m with testdata/<test name>.golden.yaml,
+// writing the file instead when -ossatest.update is set.
+func Golden(t testing.TB, m *ossa.Manifest) {
+	t.Helper()
+	got, err := m.ToYAML()
+	if err != nil {
+		t.Errorf("failed to marshal manifest: %v", err)
+		return
+	}
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	path := filepath.Join("testdata", name+".golden.yaml")
+
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Errorf("failed to write golden file: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Errorf("failed to write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("failed to read golden file (run with -ossatest.update to create it): %v", err)
+		return
+	}
+	if got != string(want) {
+		t.Errorf("manifest differs from %s (run with -ossatest.update to accept):\n--- want\n%s--- got\n%s", path, want, got)
+	}
+}
+
+// Option customizes a fixture.
+type Option func(*ossa.Manifest)
+
+// Agent returns a valid Agent manifest with a role and an LLM, changed by
+// opts.
+func Agent(name string, opts ...Option) *ossa.Manifest {
+	m := fixture(name, ossa.KindAgent, opts)
+	if m.Spec.LLM == nil {
+		m.Spec.LLM = &ossa.LLMConfig{Provider: "openai", Model: "gpt-4o"}
+	}
+	return m
+}
+
+// Task returns a valid Task manifest, changed by opts.
+func Task(name string, opts ...Option) *ossa.Manifest {
+	return fixture(name, ossa.KindTask, opts)
+}
+
+// Workflow returns a valid Workflow manifest, changed by opts.
+func Workflow(name string, opts ...Option) *ossa.Manifest {
+	return fixture(name, ossa.KindWorkflow, opts)
+}
+
+func fixture(name string, kind ossa.Kind, opts []Option) *ossa.Manifest {
+	m := &ossa.Manifest{
+		APIVersion: "ossa/v" + ossa.OSSAVersion,
+		Kind:       kind,
+		Metadata:   ossa.Metadata{Name: name, Version: "1.0.0"},
+		Spec:       ossa.Spec{Role: "You are a test " + strings.ToLower(string(kind)) + "."},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithRole sets spec.role.
+func WithRole(role string) Option {
+	return func(m *ossa.Manifest) { m.Spec.Role = role }
+}
+
+// WithLLM sets spec.llm.
+func WithLLM(provider, model string) Option {
+	return func(m *ossa.Manifest) { m.Spec.LLM = &ossa.LLMConfig{Provider: provider, Model: model} }
+}
+
+// WithTool appends a tool.
+func WithTool(tool ossa.ToolConfig) Option {
+	return func(m *ossa.Manifest) { m.Spec.Tools = append(m.Spec.Tools, tool) }
+}
+
+// WithAccessTier sets spec.access_tier.
+func WithAccessTier(tier ossa.AccessTier) Option {
+	return func(m *ossa.Manifest) { m.Spec.AccessTier = tier }
+}
+
+// WithLabel sets a metadata label.
+func WithLabel(key, value string) Option {
+	return func(m *ossa.Manifest) {
+		if m.Metadata.Labels == nil {
+			m.Metadata.Labels = map[string]string{}
+		}
+		m.Metadata.Labels[key] = value
+	}
+}
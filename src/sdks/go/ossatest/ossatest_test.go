@@ -0,0 +1,70 @@
+package ossatest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// recorder captures failures so helpers can be tested failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	m := Agent("reviewer", WithAccessTier(ossa.TierRead), WithTool(ossa.ToolConfig{Type: "mcp", Name: "search"}))
+	AssertValidManifest(t, m)
+
+	path := filepath.Join(t.TempDir(), "reviewer.ossa.yaml")
+	if err := ossa.SaveManifest(m, path, "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if got := AssertValid(t, path); got == nil || got.Metadata.Name != "reviewer" {
+		t.Errorf("Unexpected manifest: %+v", got)
+	}
+
+	r := &recorder{TB: t}
+	bad := Agent("", WithLabel("team", "platform"))
+	AssertValidManifest(r, bad)
+	AssertInvalid(r, bad, "metadata.name")
+	AssertInvalid(r, bad, "no such error")
+	AssertValid(r, filepath.Join(t.TempDir(), "missing.yaml"))
+	if len(r.errors) != 3 {
+		t.Errorf("Expected 3 failures, got %q", r.errors)
+	}
+	if bad.Metadata.Labels["team"] != "platform" {
+		t.Errorf("Expected label, got %v", bad.Metadata.Labels)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	for _, m := range []*ossa.Manifest{Agent("a"), Task("t"), Workflow("w", WithRole("Orchestrates."))} {
+		AssertValidManifest(t, m)
+	}
+	if m := Agent("a", WithLLM("anthropic", "claude")); m.Spec.LLM.Provider != "anthropic" {
+		t.Errorf("Expected LLM option to win, got %+v", m.Spec.LLM)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, Agent("golden", WithTool(ossa.ToolConfig{Type: "mcp", Name: "search"})))
+
+	r := &recorder{TB: t}
+	Golden(r, Agent("changed"))
+	if len(r.errors) != 1 {
+		t.Errorf("Expected a golden mismatch, got %q", r.errors)
+	}
+	if _, err := os.Stat(filepath.Join("testdata", "TestGolden.golden.yaml")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package plugin implements ossa CLI plugins: executables named ossa-<name>
+// on PATH run as ossa <name>, as kubectl plugins do.
+//
+// The CLI passes the plugin its arguments unchanged and, in the file named
+// by $OSSA_PLUGIN_HANDSHAKE, a JSON Handshake with the parsed manifests
+// among the arguments and the effective CLI settings:
+//
+//	{
+//	  "version": 1,
+//	  "ossa_version": "1.4.0",
+//	  "spec_version": "0.3.3",
+//	  "args": ["agent.ossa.yaml", "--dry-run"],
+//	  "workdir": "/home/me/agents",
+//	  "config": {"output": "text", "registry_url": "https://registry.example.com"},
+//	  "schema_dir": "/home/me/agents/.ossa/schemas",
+//	  "manifests": [{"path": "agent.ossa.yaml", "manifest": {"apiVersion": "ossa/v0.3.3", ...}}]
+//	}
+//
+// Go plugins read it with Read. The file is removed when the plugin exits.
+// A plugin's exit code becomes the CLI's.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Prefix starts the executable name of every plugin.
+const Prefix = "ossa-"
+
+// HandshakeEnv names the environment variable holding the handshake path.
+const HandshakeEnv = "OSSA_PLUGIN_HANDSHAKE"
+
+// HandshakeVersion is the handshake format version. Fields are only added
+// within a version.
+const HandshakeVersion = 1
+
+// Handshake is what the CLI tells a plugin about its invocation.
+type Handshake struct {
+	Version     int    `json:"version"`
+	OSSAVersion string `json:"ossa_version"`
+	// SpecVersion is the spec version of the embedded schema.
+	SpecVersion string   `json:"spec_version"`
+	Args        []string `json:"args"`
+	WorkDir     string   `json:"workdir"`
+	// Config holds the effective value of every CLI setting.
+	Config map[string]string `json:"config"`
+	// SchemaDir is the project's vendored schema directory, if any.
+	SchemaDir string           `json:"schema_dir,omitempty"`
+	Manifests []LoadedManifest `json:"manifests"`
+}
+
+// LoadedManifest is an argument naming a .yaml, .yml or .json file, with
+// the manifest parsed from it or the error parsing it.
+type LoadedManifest struct {
+	Path     string         `json:"path"`
+	Manifest *ossa.Manifest `json:"manifest,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// LoadManifests parses the arguments that name existing manifest files.
+func LoadManifests(args []string) []LoadedManifest {
+	out := []LoadedManifest{}
+	for _, arg := range args {
+		switch strings.ToLower(filepath.Ext(arg)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if info, err := os.Stat(arg); err != nil || info.IsDir() {
+			continue
+		}
+		lm := LoadedManifest{Path: arg}
+		if m, err := ossa.LoadManifest(arg); err != nil {
+			lm.Error = err.Error()
+		} else {
+			lm.Manifest = m
+		}
+		out = append(out, lm)
+	}
+	return out
+}
+
+// Write stores h in a new temporary file and returns its path; the caller
+// removes it.
+func Write(h *Handshake) (string, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal handshake: %w", err)
+	}
+	f, err := os.CreateTemp("", "ossa-plugin-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create handshake: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write handshake: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Read loads the handshake of the running plugin from $OSSA_PLUGIN_HANDSHAKE.
+func Read() (*Handshake, error) {
+	path := os.Getenv(HandshakeEnv)
+	if path == "" {
+		return nil, fmt.Errorf("%s is not set; run the plugin through ossa", HandshakeEnv)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handshake: %w", err)
+	}
+	var h Handshake
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse handshake: %w", err)
+	}
+	if h.Version != HandshakeVersion {
+		return nil, fmt.Errorf("unsupported handshake version %d (want %d)", h.Version, HandshakeVersion)
+	}
+	return &h, nil
+}
+
+// Plugin is an executable found on PATH.
+type Plugin struct {
+	// Name is the subcommand, the executable name without Prefix.
+	Name string
+	Path string
+	// Shadowed lists later PATH entries with the same name, which never run.
+	Shadowed []string
+}
+
+// Discover finds plugins in the directories of path, a PATH-style list.
+// The first executable of a name wins. Plugins are sorted by name.
+func Discover(path string) []Plugin {
+	byName := map[string]*Plugin{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name())
+			if !ok || e.IsDir() {
+				continue
+			}
+			full := filepath.Join(dir, e.Name())
+			if !executable(full) {
+				continue
+			}
+			if p, seen := byName[name]; seen {
+				p.Shadowed = append(p.Shadowed, full)
+				continue
+			}
+			byName[name] = &Plugin{Name: name, Path: full}
+		}
+	}
+	out := make([]Plugin, 0, len(byName))
+	for _, p := range byName {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// pluginName returns the subcommand for an executable file name.
+func pluginName(file string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if name == file || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+func executable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
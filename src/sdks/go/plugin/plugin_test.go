@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin names need .exe on Windows")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	write := func(dir, name string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first, "ossa-lint", 0o755)
+	write(first, "ossa-notes", 0o644)
+	write(first, "ossa-", 0o755)
+	write(second, "ossa-lint", 0o755)
+	write(second, "ossa-deploy", 0o755)
+	write(second, "kubectl-foo", 0o755)
+
+	plugins := Discover(first + string(os.PathListSeparator) + second)
+	if len(plugins) != 2 {
+		t.Fatalf("Expected 2 plugins, got %+v", plugins)
+	}
+	if plugins[0].Name != "deploy" || plugins[1].Name != "lint" {
+		t.Errorf("Expected deploy and lint, got %+v", plugins)
+	}
+	lint := plugins[1]
+	if lint.Path != filepath.Join(first, "ossa-lint") || len(lint.Shadowed) != 1 || lint.Shadowed[0] != filepath.Join(second, "ossa-lint") {
+		t.Errorf("Expected the first ossa-lint to win, got %+v", lint)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "agent.ossa.yaml")
+	if err := os.WriteFile(manifest, []byte("apiVersion: ossa/v0.3.3\nkind: Agent\nmetadata:\n  name: a\nspec:\n  role: r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(broken, []byte("kind: [\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := LoadManifests([]string{manifest, "--dry-run", broken, filepath.Join(dir, "missing.yaml")})
+	if len(loaded) != 2 || loaded[0].Manifest == nil || loaded[0].Manifest.Metadata.Name != "a" || loaded[1].Error == "" {
+		t.Fatalf("Unexpected manifests %+v", loaded)
+	}
+
+	path, err := Write(&Handshake{Version: HandshakeVersion, Args: []string{manifest}, Config: map[string]string{"output": "json"}, Manifests: loaded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	t.Setenv(HandshakeEnv, path)
+	h, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Config["output"] != "json" || len(h.Manifests) != 2 || h.Manifests[0].Manifest.Spec.Role != "r" {
+		t.Errorf("Unexpected handshake %+v", h)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(); err == nil {
+		t.Error("Expected unsupported version error")
+	}
+	t.Setenv(HandshakeEnv, "")
+	if _, err := Read(); err == nil {
+		t.Error("Expected error without a handshake")
+	}
+}
@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultNATSURL is the NATS server dialed for an empty URL.
+const DefaultNATSURL = "nats://localhost:4222"
+
+// NATS is a Transport speaking the NATS client protocol over one
+// connection. tls:// URLs connect over TLS; user:password@ or token@ in
+// the URL authenticate.
+type NATS struct {
+	conn net.Conn
+	wmu  sync.Mutex
+	w    *bufio.Writer
+
+	mu      sync.Mutex
+	subs    map[int]*natsSub
+	nextSID int
+	pong    chan error
+	err     error
+	done    chan struct{}
+}
+
+type natsSub struct {
+	ch   chan Message
+	quit chan struct{}
+}
+
+// DialNATS connects to the NATS server at rawurl and waits for it to accept
+// the connection.
+func DialNATS(ctx context.Context, rawurl string) (*NATS, error) {
+	if rawurl == "" {
+		rawurl = DefaultNATSURL
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL %q: %w", rawurl, err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if err := ossa.RequireNetwork("NATS " + host); err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", host, err)
+	}
+	r := bufio.NewReader(conn)
+	// The server opens with INFO; anything else is not NATS.
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not a NATS server", host)
+	}
+	if u.Scheme == "tls" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed TLS handshake with %s: %w", host, err)
+		}
+		conn, r = tc, bufio.NewReader(tc)
+	}
+
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "ossa", "lang": "go", "protocol": 1}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(opts)
+	n := &NATS{conn: conn, w: bufio.NewWriter(conn), subs: map[int]*natsSub{}, pong: make(chan error, 1), done: make(chan struct{})}
+	go n.read(r)
+	if err := n.write("CONNECT " + string(connect) + "\r\nPING\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The PONG answering our PING means the CONNECT was accepted.
+	select {
+	case err := <-n.pong:
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	case <-n.done:
+		return nil, n.closedErr()
+	case <-ctx.Done():
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	return n, nil
+}
+
+func (n *NATS) write(s string) error {
+	n.wmu.Lock()
+	defer n.wmu.Unlock()
+	if _, err := n.w.WriteString(s); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+func (n *NATS) read(r *bufio.Reader) {
+	err := n.readLoop(r)
+	n.mu.Lock()
+	if n.err == nil {
+		n.err = err
+	}
+	n.mu.Unlock()
+	close(n.done)
+}
+
+func (n *NATS) readLoop(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			f := strings.Fields(args)
+			if len(f) < 3 || len(f) > 4 {
+				return fmt.Errorf("NATS: malformed MSG %q", line)
+			}
+			size, err := strconv.Atoi(f[len(f)-1])
+			if err != nil {
+				return fmt.Errorf("NATS: malformed MSG %q", line)
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			msg := Message{Subject: f[0], Data: data[:size]}
+			if len(f) == 4 {
+				msg.Reply = f[2]
+			}
+			sid, _ := strconv.Atoi(f[1])
+			n.mu.Lock()
+			sub := n.subs[sid]
+			n.mu.Unlock()
+			if sub != nil {
+				select {
+				case sub.ch <- msg:
+				case <-sub.quit:
+				}
+			}
+		case "PING":
+			if err := n.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case "PONG":
+			select {
+			case n.pong <- nil:
+			default:
+			}
+		case "-ERR":
+			err := fmt.Errorf("NATS: %s", strings.Trim(args, "'"))
+			select {
+			case n.pong <- err:
+			default:
+			}
+			return err
+		}
+	}
+}
+
+func (n *NATS) closedErr() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err == nil || n.err == io.EOF {
+		return fmt.Errorf("NATS connection closed")
+	}
+	return n.err
+}
+
+// Publish sends data to subject.
+func (n *NATS) Publish(_ context.Context, subject string, data []byte) error {
+	if strings.ContainsAny(subject, " \t\r\n") || subject == "" {
+		return fmt.Errorf("invalid NATS subject %q", subject)
+	}
+	return n.write(fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(data), data))
+}
+
+// Subscribe handles the messages on subject; a group makes it a NATS
+// queue subscription.
+func (n *NATS) Subscribe(ctx context.Context, subject, group string, handle Handler) error {
+	sub := &natsSub{ch: make(chan Message, 64), quit: make(chan struct{})}
+	n.mu.Lock()
+	n.nextSID++
+	sid := n.nextSID
+	n.subs[sid] = sub
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.subs, sid)
+		n.mu.Unlock()
+		close(sub.quit)
+	}()
+
+	cmd := "SUB " + subject
+	if group != "" {
+		cmd += " " + group
+	}
+	if err := n.write(fmt.Sprintf("%s %d\r\n", cmd, sid)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case msg := <-sub.ch:
+			handle(ctx, msg)
+		case <-ctx.Done():
+			n.write(fmt.Sprintf("UNSUB %d\r\n", sid))
+			return nil
+		case <-n.done:
+			return n.closedErr()
+		}
+	}
+}
+
+// Close closes the connection.
+func (n *NATS) Close() error {
+	return n.conn.Close()
+}
@@ -0,0 +1,171 @@
+// Package queue connects agents to message queues behind the Transport
+// interface: queue triggers run an agent, Task or Workflow per message, and
+// tools with handler.runtime: queue publish to a subject.
+//
+// NATS (nats:// and tls:// URLs) and an in-process mem transport are built
+// in. Other brokers, such as Kafka or SQS, plug in with Register, the way
+// database/sql drivers do:
+//
+//	queue.Register("kafka", func(ctx context.Context, url string) (queue.Transport, error) {
+//		return newKafka(url)
+//	})
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Message is one message read from a subject.
+type Message struct {
+	Subject string
+	Data    []byte
+	// Reply is where the sender wants an answer, if anywhere.
+	Reply string
+}
+
+// Handler handles one message.
+type Handler func(ctx context.Context, msg Message)
+
+// Transport publishes and subscribes to subjects on one broker: NATS
+// subjects, Kafka topics or SQS queues. It is safe for concurrent use.
+type Transport interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe calls handle for each message on subject, one at a time,
+	// until ctx is done or the connection fails. With a group, each
+	// message goes to one of the group's subscribers.
+	Subscribe(ctx context.Context, subject, group string, handle Handler) error
+	Close() error
+}
+
+// OpenFunc connects to the broker at url.
+type OpenFunc func(ctx context.Context, url string) (Transport, error)
+
+var (
+	mu         sync.Mutex
+	transports = map[string]OpenFunc{
+		"nats": func(ctx context.Context, url string) (Transport, error) { return DialNATS(ctx, url) },
+		"mem":  func(_ context.Context, url string) (Transport, error) { return Mem(url), nil },
+	}
+)
+
+// Register makes a transport available to Open under name, replacing any
+// registered before.
+func Register(name string, open OpenFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	transports[name] = open
+}
+
+// Transports returns the registered transport names, sorted.
+func Transports() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open connects to the broker at url with the transport registered as name.
+func Open(ctx context.Context, name, url string) (Transport, error) {
+	mu.Lock()
+	open, ok := transports[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("queue transport %q is not built in; a program can add it with queue.Register", name)
+	}
+	return open(ctx, url)
+}
+
+var (
+	memMu     sync.Mutex
+	memQueues = map[string]*memTransport{}
+)
+
+// Mem returns the in-process transport named url. Every Mem call with the
+// same url shares its subjects, so tests and embedded programs can wire
+// triggers and tools together without a broker.
+func Mem(url string) Transport {
+	memMu.Lock()
+	defer memMu.Unlock()
+	t, ok := memQueues[url]
+	if !ok {
+		t = &memTransport{subs: map[string][]*memSub{}, next: map[string]int{}}
+		memQueues[url] = t
+	}
+	return t
+}
+
+type memTransport struct {
+	mu   sync.Mutex
+	subs map[string][]*memSub
+	// next is the round-robin position of each subject-and-group.
+	next map[string]int
+}
+
+type memSub struct {
+	group string
+	ch    chan Message
+}
+
+func (t *memTransport) Publish(ctx context.Context, subject string, data []byte) error {
+	t.mu.Lock()
+	var targets []*memSub
+	groups := map[string][]*memSub{}
+	for _, s := range t.subs[subject] {
+		if s.group == "" {
+			targets = append(targets, s)
+		} else {
+			groups[s.group] = append(groups[s.group], s)
+		}
+	}
+	for group, members := range groups {
+		key := subject + " " + group
+		targets = append(targets, members[t.next[key]%len(members)])
+		t.next[key]++
+	}
+	t.mu.Unlock()
+
+	msg := Message{Subject: subject, Data: append([]byte(nil), data...)}
+	for _, s := range targets {
+		select {
+		case s.ch <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (t *memTransport) Subscribe(ctx context.Context, subject, group string, handle Handler) error {
+	s := &memSub{group: group, ch: make(chan Message, 64)}
+	t.mu.Lock()
+	t.subs[subject] = append(t.subs[subject], s)
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		subs := t.subs[subject]
+		for i := range subs {
+			if subs[i] == s {
+				t.subs[subject] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-s.ch:
+			handle(ctx, msg)
+		}
+	}
+}
+
+func (t *memTransport) Close() error { return nil }
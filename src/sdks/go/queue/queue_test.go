@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// fakeNATS is a single-connection NATS server routing PUB to SUB.
+func fakeNATS(t *testing.T, token string) (string, *[]string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var connects []string
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+		r := bufio.NewReader(conn)
+		subs := map[string]string{} // subject -> sid
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			f := strings.Fields(line)
+			switch f[0] {
+			case "CONNECT":
+				mu.Lock()
+				connects = append(connects, line)
+				mu.Unlock()
+				if token != "" && !strings.Contains(line, `"auth_token":"`+token+`"`) {
+					fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+					return
+				}
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case "SUB":
+				subs[f[1]] = f[len(f)-1]
+			case "PUB":
+				size, _ := strconv.Atoi(f[len(f)-1])
+				data := make([]byte, size+2)
+				io.ReadFull(r, data)
+				if sid, ok := subs[f[1]]; ok {
+					fmt.Fprintf(conn, "MSG %s %s _INBOX.1 %d\r\n%s\r\n", f[1], sid, size, data[:size])
+				}
+			}
+		}
+	}()
+	return ln.Addr().String(), &connects
+}
+
+func TestNATS(t *testing.T) {
+	addr, connects := fakeNATS(t, "s3cr3t")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tr, err := Open(ctx, "nats", "nats://s3cr3t@"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	if !strings.Contains((*connects)[0], `"name":"ossa"`) {
+		t.Errorf("Unexpected CONNECT %q", (*connects)[0])
+	}
+
+	got := make(chan Message, 1)
+	subCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- tr.Subscribe(subCtx, "tickets.new", "", func(_ context.Context, msg Message) { got <- msg })
+	}()
+	// Publish until the subscription is in place.
+	var msg Message
+	for msg.Subject == "" {
+		if err := tr.Publish(ctx, "tickets.new", []byte(`{"ticket": 42}`)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg = <-got:
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for a message")
+		}
+	}
+	if string(msg.Data) != `{"ticket": 42}` || msg.Reply != "_INBOX.1" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	stop()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean unsubscribe, got %v", err)
+	}
+	if err := tr.Publish(ctx, "bad subject", nil); err == nil {
+		t.Error("Expected an invalid subject rejected")
+	}
+
+	addr, _ = fakeNATS(t, "s3cr3t")
+	if _, err := DialNATS(ctx, "nats://wrong@"+addr); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Expected the connection refused, got %v", err)
+	}
+
+	ossa.SetOffline(true)
+	defer ossa.SetOffline(false)
+	if _, err := DialNATS(ctx, "nats://s3cr3t@"+addr); !errors.Is(err, ossa.ErrOffline) {
+		t.Errorf("Expected no dial in offline mode, got %v", err)
+	}
+	ossa.SetOffline(false)
+	if _, err := Open(ctx, "kafka", ""); err == nil || !strings.Contains(err.Error(), "queue.Register") {
+		t.Errorf("Expected kafka unregistered, got %v", err)
+	}
+}
+
+func TestMem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	seen := map[string]int{}
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "solo"} {
+		group := "workers"
+		if name == "solo" {
+			group = ""
+		}
+		name := name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Mem("mem://test").Subscribe(ctx, "jobs", group, func(context.Context, Message) {
+				mu.Lock()
+				seen[name]++
+				mu.Unlock()
+			})
+		}()
+	}
+	// Wait for the three subscriptions.
+	for {
+		m := Mem("mem://test").(*memTransport)
+		m.mu.Lock()
+		n := len(m.subs["jobs"])
+		m.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 4; i++ {
+		Mem("mem://test").Publish(ctx, "jobs", []byte("x"))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		total := seen["a"] + seen["b"] + seen["solo"]
+		mu.Unlock()
+		if total == 8 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	// The group shares the messages; a subscriber outside it gets them all.
+	if seen["a"] != 2 || seen["b"] != 2 || seen["solo"] != 4 {
+		t.Errorf("Unexpected deliveries %v", seen)
+	}
+}
@@ -0,0 +1,97 @@
+package resolve
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the lock file ossa resolve reads and writes by default.
+const LockFile = "ossa.lock.yaml"
+
+// Lock pins refs to releases.
+type Lock struct {
+	Refs []Locked `yaml:"refs"`
+}
+
+// Locked is one pinned ref.
+type Locked struct {
+	Ref     string `yaml:"ref"`
+	Version string `yaml:"version"`
+	SHA256  string `yaml:"sha256"`
+}
+
+// LoadLock reads a lock file; a missing file is an empty Lock.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Lock{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+// Marshal returns the lock file's YAML, with refs sorted.
+func (l *Lock) Marshal() ([]byte, error) {
+	sort.Slice(l.Refs, func(i, j int) bool { return l.Refs[i].Ref < l.Refs[j].Ref })
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+	return data, nil
+}
+
+// Save writes the lock file, with refs sorted.
+func (l *Lock) Save(path string) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Find returns the pin for ref.
+func (l *Lock) Find(ref string) (Locked, bool) {
+	for _, e := range l.Refs {
+		if e.Ref == ref {
+			return e, true
+		}
+	}
+	return Locked{}, false
+}
+
+// Set adds or replaces a pin.
+func (l *Lock) Set(e Locked) {
+	for i := range l.Refs {
+		if l.Refs[i].Ref == e.Ref {
+			l.Refs[i] = e
+			return
+		}
+	}
+	l.Refs = append(l.Refs, e)
+}
+
+// Retain drops the pins of refs not in keep, returning how many it
+// dropped.
+func (l *Lock) Retain(keep map[string]bool) int {
+	kept := l.Refs[:0]
+	for _, e := range l.Refs {
+		if keep[e.Ref] {
+			kept = append(kept, e)
+		}
+	}
+	dropped := len(l.Refs) - len(kept)
+	l.Refs = kept
+	return dropped
+}
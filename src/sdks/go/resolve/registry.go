@@ -0,0 +1,147 @@
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Registry is a Source reading the releases of an ossa serve registry
+// through GET /api/agents/{namespace}/{name}/versions[/{version}].
+type Registry struct {
+	URL string
+	// Token is sent as a bearer token.
+	Token string
+	// Client defaults to one with a 30 second timeout.
+	Client *http.Client
+}
+
+func (r *Registry) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	var versions []string
+	found, err := r.get(ctx, namespace, name, "", &versions)
+	if err != nil || !found {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *Registry) Fetch(ctx context.Context, namespace, name, version string) (*ossa.Manifest, error) {
+	var agent struct {
+		Manifest *ossa.Manifest `json:"manifest"`
+	}
+	found, err := r.get(ctx, namespace, name, "/"+url.PathEscape(version), &agent)
+	if err != nil {
+		return nil, err
+	}
+	if !found || agent.Manifest == nil {
+		return nil, fmt.Errorf("%s/%s@%s is not released", namespace, name, version)
+	}
+	return agent.Manifest, nil
+}
+
+// List lists the registry's agents through GET /api/agents, a page at a
+// time when opts.Limit is set; opts.Match is not sent, so it filters the
+// page received. Items have the registry's {namespace}/{name} refs in Path
+// and no Manifest; Fetch or GET /api/agents/{namespace}/{name} loads one.
+func (r *Registry) List(ctx context.Context, opts ossa.ListOptions) (*ossa.ManifestList, error) {
+	q := url.Values{}
+	for k, v := range map[string]string{"namespace": opts.Namespace, "kind": string(opts.Kind), "selector": opts.Selector.String(), "continue": opts.Continue} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	u := strings.TrimSuffix(r.URL, "/") + "/api/agents"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var agents []struct {
+		Name      string          `json:"name"`
+		Namespace string          `json:"namespace"`
+		Kind      ossa.Kind       `json:"kind"`
+		Tier      ossa.AccessTier `json:"tier"`
+		Model     string          `json:"model"`
+		Modified  time.Time       `json:"modified"`
+	}
+	header, found, err := r.request(ctx, u, &agents)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("GET %s: %s", u, http.StatusText(http.StatusNotFound))
+	}
+	list := &ossa.ManifestList{Items: []ossa.CatalogEntry{}, Continue: nextContinue(header.Get("Link"))}
+	for _, a := range agents {
+		e := ossa.CatalogEntry{Path: a.Namespace + "/" + a.Name, Name: a.Name, Kind: a.Kind, Tier: a.Tier, Model: a.Model, Modified: a.Modified}
+		if opts.Match == nil || opts.Match(e) {
+			list.Items = append(list.Items, e)
+		}
+	}
+	return list, nil
+}
+
+// nextContinue returns the continue parameter of a Link header's
+// rel="next" URL.
+func nextContinue(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err == nil {
+			return u.Query().Get("continue")
+		}
+	}
+	return ""
+}
+
+// get decodes a versions response into out, returning false for 404.
+func (r *Registry) get(ctx context.Context, namespace, name, suffix string, out interface{}) (bool, error) {
+	u := strings.TrimSuffix(r.URL, "/") + "/api/agents/" + url.PathEscape(namespace) + "/" + url.PathEscape(name) + "/versions" + suffix
+	_, found, err := r.request(ctx, u, out)
+	return found, err
+}
+
+// request decodes the response to GET u into out, returning its header,
+// and false for 404.
+func (r *Registry) request(ctx context.Context, u string, out interface{}) (http.Header, bool, error) {
+	if err := ossa.RequireNetwork("registry " + r.URL); err != nil {
+		return nil, false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return resp.Header, false, nil
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, false, fmt.Errorf("failed to decode registry response: %w", err)
+		}
+		return resp.Header, true, nil
+	}
+	return nil, false, fmt.Errorf("GET %s: %s", u, resp.Status)
+}
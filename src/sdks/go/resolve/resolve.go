@@ -0,0 +1,264 @@
+// Package resolve resolves versioned agent refs in workflow steps, such as
+//
+//	steps:
+//	  - id: triage
+//	    ref: support-agent@^1.2
+//	  - id: bill
+//	    ref: payments/biller@~2.0.1
+//
+// against the releases a Source, normally an ossa serve Registry, holds. A
+// ref is [namespace/]name@constraint, with constraints as in package
+// semver; refs without @, such as manifest paths, are not versioned.
+//
+// The newest release matching each constraint is chosen and pinned, with
+// its digest, in a Lock, so later resolutions pick the same release until
+// the constraint stops matching it or Resolver.Update is set, and a
+// release changed after locking is reported as ErrLockMismatch.
+package resolve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultNamespace is the namespace of refs without one.
+const DefaultNamespace = ossa.DefaultNamespace
+
+// ErrLockMismatch is returned when a locked release no longer has its
+// locked digest.
+var ErrLockMismatch = errors.New("release does not match its locked sha256")
+
+// Ref is a versioned agent ref.
+type Ref struct {
+	Namespace  string
+	Name       string
+	Constraint *semver.Constraint
+	// Raw is the ref as written, which keys the Lock.
+	Raw string
+}
+
+func (r Ref) String() string { return r.Raw }
+
+// ParseRef parses [namespace/]name@constraint. It returns false for refs
+// without @, which are not versioned.
+func ParseRef(s string) (Ref, bool, error) {
+	id, constraint, versioned := strings.Cut(strings.TrimSpace(s), "@")
+	if !versioned {
+		return Ref{}, false, nil
+	}
+	ref := Ref{Namespace: DefaultNamespace, Name: id, Raw: strings.TrimSpace(s)}
+	if ns, name, ok := strings.Cut(id, "/"); ok {
+		ref.Namespace, ref.Name = ns, name
+	}
+	if ref.Namespace == "" || ref.Name == "" || strings.Contains(ref.Name, "/") {
+		return Ref{}, true, fmt.Errorf("invalid ref %q: want [namespace/]name@constraint", s)
+	}
+	c, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return Ref{}, true, fmt.Errorf("invalid ref %q: %w", s, err)
+	}
+	ref.Constraint = c
+	return ref, true, nil
+}
+
+// Source lists and fetches the releases of agents.
+type Source interface {
+	// Versions returns the released versions of namespace/name.
+	Versions(ctx context.Context, namespace, name string) ([]string, error)
+	// Fetch returns one release.
+	Fetch(ctx context.Context, namespace, name, version string) (*ossa.Manifest, error)
+}
+
+// Resolution is the release a ref resolved to.
+type Resolution struct {
+	Ref      Ref
+	Version  string
+	Manifest *ossa.Manifest
+	// Changed is set when the lock did not already pin this release.
+	Changed bool
+}
+
+// Resolver resolves refs against a Source.
+type Resolver struct {
+	Source Source
+	// Lock pins resolutions: Resolve prefers the locked release and records
+	// new ones. Nil always picks the newest match.
+	Lock *Lock
+	// Update ignores locked releases, moving each ref to its newest match.
+	Update bool
+}
+
+// Resolve returns the release ref resolves to.
+func (r *Resolver) Resolve(ctx context.Context, ref Ref) (*Resolution, error) {
+	var locked Locked
+	var isLocked bool
+	if r.Lock != nil {
+		locked, isLocked = r.Lock.Find(ref.Raw)
+	}
+	if isLocked && !r.Update {
+		if v, err := semver.Parse(locked.Version); err == nil && ref.Constraint.Check(v) {
+			m, err := r.fetch(ctx, ref, locked.Version)
+			if err != nil {
+				return nil, err
+			}
+			digest, err := Digest(m)
+			if err != nil {
+				return nil, err
+			}
+			if digest != locked.SHA256 {
+				if legacyDigest(m) != locked.SHA256 {
+					return nil, fmt.Errorf("%s: %s/%s@%s: %w", ref, ref.Namespace, ref.Name, locked.Version, ErrLockMismatch)
+				}
+				// Relock the same release under its canonical digest.
+				r.Lock.Set(Locked{Ref: ref.Raw, Version: locked.Version, SHA256: digest})
+				return &Resolution{Ref: ref, Version: locked.Version, Manifest: m, Changed: true}, nil
+			}
+			return &Resolution{Ref: ref, Version: locked.Version, Manifest: m}, nil
+		}
+	}
+
+	versions, err := r.Source.Versions(ctx, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to list versions: %w", ref, err)
+	}
+	best, ok := ref.Constraint.Best(versions)
+	if !ok {
+		have := "none"
+		if len(versions) > 0 {
+			have = strings.Join(versions, ", ")
+		}
+		return nil, fmt.Errorf("%s: no release of %s/%s matches %s (released: %s)", ref, ref.Namespace, ref.Name, ref.Constraint, have)
+	}
+	m, err := r.fetch(ctx, ref, best)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := Digest(m)
+	if err != nil {
+		return nil, err
+	}
+	res := &Resolution{Ref: ref, Version: best, Manifest: m, Changed: !isLocked || locked.Version != best || locked.SHA256 != digest}
+	if r.Lock != nil {
+		r.Lock.Set(Locked{Ref: ref.Raw, Version: best, SHA256: digest})
+	}
+	return res, nil
+}
+
+func (r *Resolver) fetch(ctx context.Context, ref Ref, version string) (*ossa.Manifest, error) {
+	m, err := r.Source.Fetch(ctx, ref.Namespace, ref.Name, version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to fetch %s: %w", ref, version, err)
+	}
+	return m, nil
+}
+
+// StepResolution is a workflow step's resolved ref.
+type StepResolution struct {
+	Step string
+	*Resolution
+}
+
+// ResolveWorkflow resolves the versioned refs among the steps of a
+// workflow document, reporting every step that fails.
+func (r *Resolver) ResolveWorkflow(ctx context.Context, data []byte) ([]StepResolution, error) {
+	steps, err := StepRefs(data)
+	if err != nil {
+		return nil, err
+	}
+	var out []StepResolution
+	var problems []string
+	for _, step := range steps {
+		ref, versioned, err := ParseRef(step.Ref)
+		if !versioned {
+			continue
+		}
+		var res *Resolution
+		if err == nil {
+			res, err = r.Resolve(ctx, ref)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("step %s: %v", step.Step, err))
+			continue
+		}
+		out = append(out, StepResolution{Step: step.Step, Resolution: res})
+	}
+	if len(problems) > 0 {
+		return out, fmt.Errorf("failed to resolve refs:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return out, nil
+}
+
+// StepRef is the ref of one workflow step.
+type StepRef struct {
+	Step string
+	Ref  string
+}
+
+// StepRefs returns the refs of the steps in a workflow document, including
+// steps nested in parallel, conditional and loop steps, in document order.
+func StepRefs(data []byte) ([]StepRef, error) {
+	data, err := ossa.NormalizeEncoding(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Spec map[string]interface{} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	var refs []StepRef
+	var walk func(v interface{}, inSteps bool)
+	walk = func(v interface{}, inSteps bool) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if inSteps {
+				if ref, ok := v["ref"].(string); ok && ref != "" {
+					id, _ := v["id"].(string)
+					refs = append(refs, StepRef{Step: id, Ref: ref})
+				}
+			}
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k], inSteps || k == "steps")
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, inSteps)
+			}
+		}
+	}
+	walk(doc.Spec["steps"], true)
+	return refs, nil
+}
+
+// Digest returns the sha256 of a manifest's canonical JSON (RFC 8785),
+// which Lock pins; every SDK computes the same digest for a manifest.
+func Digest(m *ossa.Manifest) (string, error) {
+	return m.Digest()
+}
+
+// legacyDigest is the sha256 of m's encoding/json form, which locks
+// written before digests were canonical pin.
+func legacyDigest(m *ossa.Manifest) string {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,197 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/server"
+)
+
+const workflow = `apiVersion: ossa/v0.3.3
+kind: Workflow
+metadata:
+  name: support
+spec:
+  context:
+    secrets:
+      - name: token
+        ref: vault://support/token
+  steps:
+    - id: triage
+      ref: support-agent@^1.2
+    - id: local
+      ref: ./agents/notes.ossa.yaml
+    - id: fanout
+      kind: Parallel
+      steps:
+        - id: bill
+          ref: payments/biller@~2.0
+`
+
+func TestParseRef(t *testing.T) {
+	ref, ok, err := ParseRef("payments/biller@>=2.0 <3")
+	if err != nil || !ok || ref.Namespace != "payments" || ref.Name != "biller" || ref.Constraint.String() != ">=2.0 <3" {
+		t.Errorf("Unexpected ref %+v %v %v", ref, ok, err)
+	}
+	if ref, _, _ := ParseRef("support-agent@^1"); ref.Namespace != DefaultNamespace {
+		t.Errorf("Expected the default namespace, got %q", ref.Namespace)
+	}
+	if _, ok, _ := ParseRef("./agents/notes.ossa.yaml"); ok {
+		t.Error("Expected a path ref to be unversioned")
+	}
+	for _, bad := range []string{"@^1", "a/b/c@1", "agent@^x"} {
+		if _, _, err := ParseRef(bad); err == nil {
+			t.Errorf("Expected %q rejected", bad)
+		}
+	}
+
+	refs, err := StepRefs([]byte(workflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.Step+"="+r.Ref)
+	}
+	if want := "triage=support-agent@^1.2 local=./agents/notes.ossa.yaml bill=payments/biller@~2.0"; strings.Join(got, " ") != want {
+		t.Errorf("Unexpected step refs %v", got)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	tenants := filepath.Join(t.TempDir(), "tenants.yaml")
+	os.WriteFile(tenants, []byte("tenants:\n  - namespace: default\n    tokens: [s3cr3t]\n  - namespace: payments\n    tokens: [s3cr3t]\n"), 0o644)
+	ts, err := server.LoadTenants(tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.New(server.Options{Dir: dir, Tenants: ts}))
+	defer srv.Close()
+	publish := func(namespace, name, version, role string) {
+		t.Helper()
+		m := ossa.NewManifest(name, ossa.KindAgent)
+		m.Metadata.Namespace, m.Metadata.Version, m.Spec.Role = namespace, version, role
+		body, _ := json.Marshal(m)
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/agents/"+namespace+"/"+name, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode >= 300 {
+			t.Fatalf("Failed to publish %s@%s: %s", name, version, resp.Status)
+		}
+	}
+	for _, v := range []string{"1.0.0", "1.2.0", "1.3.1", "2.0.0"} {
+		publish("default", "support-agent", v, "Supports "+v)
+	}
+	publish("payments", "biller", "2.0.4", "Bills.")
+
+	reg := &Registry{URL: srv.URL, Token: "s3cr3t"}
+	versions, err := reg.Versions(context.Background(), "default", "support-agent")
+	if err != nil || strings.Join(versions, " ") != "1.0.0 1.2.0 1.3.1 2.0.0" {
+		t.Fatalf("Unexpected versions %v %v", versions, err)
+	}
+	var listed []string
+	opts := ossa.ListOptions{Limit: 1}
+	for page := 0; page < 3; page++ {
+		list, err := reg.List(context.Background(), opts)
+		if err != nil || len(list.Items) != 1 {
+			t.Fatalf("Unexpected page %+v %v", list, err)
+		}
+		listed = append(listed, list.Items[0].Path)
+		if opts.Continue = list.Continue; opts.Continue == "" {
+			break
+		}
+	}
+	if strings.Join(listed, " ") != "default/support-agent payments/biller" {
+		t.Errorf("Expected a page per agent, got %v", listed)
+	}
+	if list, err := reg.List(context.Background(), ossa.ListOptions{Namespace: "payments"}); err != nil || len(list.Items) != 1 || list.Items[0].Name != "biller" || list.Continue != "" {
+		t.Errorf("Expected the payments agent, got %+v %v", list, err)
+	}
+
+	lock := &Lock{}
+	r := &Resolver{Source: reg, Lock: lock}
+	resolve := func() map[string]string {
+		t.Helper()
+		steps, err := r.ResolveWorkflow(context.Background(), []byte(workflow))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, s := range steps {
+			got[s.Step] = s.Version
+		}
+		return got
+	}
+	if got := resolve(); got["triage"] != "1.3.1" || got["bill"] != "2.0.4" || len(got) != 2 {
+		t.Fatalf("Unexpected resolution %v", got)
+	}
+	path := filepath.Join(t.TempDir(), LockFile)
+	if err := lock.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if r.Lock, err = LoadLock(path); err != nil || len(r.Lock.Refs) != 2 {
+		t.Fatalf("Unexpected lock %+v %v", r.Lock, err)
+	}
+
+	// A newer release waits for Update; the locked one is still used.
+	publish("default", "support-agent", "1.4.0", "Supports 1.4.0 & later")
+	if got := resolve(); got["triage"] != "1.3.1" {
+		t.Errorf("Expected the locked release, got %v", got)
+	}
+	r.Update = true
+	if got := resolve(); got["triage"] != "1.4.0" {
+		t.Errorf("Expected the update to 1.4.0, got %v", got)
+	}
+	r.Update = false
+
+	// Locks pinning the encoding/json digest of before canonical JSON are
+	// relocked, not rejected.
+	locked, _ := r.Lock.Find("support-agent@^1.2")
+	res, err := r.Resolve(context.Background(), mustRef(t, "support-agent@^1.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Lock.Set(Locked{Ref: locked.Ref, Version: locked.Version, SHA256: legacyDigest(res.Manifest)})
+	if res, err := r.Resolve(context.Background(), mustRef(t, "support-agent@^1.2")); err != nil || !res.Changed {
+		t.Errorf("Expected a relock, got %+v %v", res, err)
+	}
+	if relocked, _ := r.Lock.Find("support-agent@^1.2"); relocked != locked {
+		t.Errorf("Expected the canonical digest back in the lock, got %+v", relocked)
+	}
+
+	// Re-releasing a locked version with other content is caught.
+	publish("default", "support-agent", "1.4.0", "Tampered")
+	if _, err := r.Resolve(context.Background(), mustRef(t, "support-agent@^1.2")); !errors.Is(err, ErrLockMismatch) {
+		t.Errorf("Expected a lock mismatch, got %v", err)
+	}
+	if _, err := r.ResolveWorkflow(context.Background(), []byte(workflow)); err == nil || !strings.Contains(err.Error(), "step triage: ") {
+		t.Errorf("Expected the failing step named, got %v", err)
+	}
+
+	_, err = r.Resolve(context.Background(), mustRef(t, "support-agent@^3"))
+	if err == nil || !strings.Contains(err.Error(), "released: 1.0.0, 1.2.0, 1.3.1, 1.4.0, 2.0.0") {
+		t.Errorf("Expected no match with the releases listed, got %v", err)
+	}
+}
+
+func mustRef(t *testing.T, s string) Ref {
+	t.Helper()
+	ref, _, err := ParseRef(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
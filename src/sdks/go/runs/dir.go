@@ -0,0 +1,89 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirStore keeps each run as {ID}.json in Dir, which is created on first
+// save. It suits one machine's history; listing reads every file.
+type DirStore struct {
+	Dir string
+}
+
+func (s *DirStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid run ID %q", id)
+	}
+	return filepath.Join(s.Dir, id+".json"), nil
+}
+
+func (s *DirStore) Save(_ context.Context, r *Run) error {
+	path, err := s.path(r.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	// Write then rename, so a reader never sees half a run.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *DirStore) Get(_ context.Context, id string) (*Run, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	return readRun(path)
+}
+
+func (s *DirStore) List(_ context.Context, f Filter) ([]*Run, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Run
+	for _, path := range files {
+		r, err := readRun(path)
+		if err != nil {
+			return nil, err
+		}
+		if f.match(r) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.After(out[j].Started) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func readRun(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Run
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", path, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,135 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// ErrDiverged is returned when a replayed run asks for a model turn, tool
+// call or Task result the recording does not have, because the manifests,
+// the input or the engine now behave differently.
+var ErrDiverged = errors.New("replay diverged from the recording")
+
+// ErrFailedHere is returned when a replay of a failed run reaches the model
+// turn or Task step the run failed at, which the recording has no answer for.
+var ErrFailedHere = errors.New("recorded run failed here")
+
+// Replay returns a copy of e that answers from r instead of calling models,
+// tools and Task runners: a model turn gets the recorded answer to the same
+// conversation, a tool call the result recorded for it and a Task step the
+// output recorded for the same Task and input. Running r's manifest on it
+// with r's input reproduces the run exactly; anything else fails with
+// ErrDiverged. Shadows are not replayed.
+func (r *Run) Replay(e *engine.Engine) *engine.Engine {
+	c := *e
+	p := &player{agents: r.Agents(), failed: r.Status == StatusFailed}
+	for _, s := range r.Steps {
+		if s.Kind == string(ossa.KindTask) {
+			p.tasks = append(p.tasks, s)
+		}
+	}
+	c.Model = p
+	c.Tools = p.tools
+	c.Task = p.task
+	c.Shadows = nil
+	return &c
+}
+
+type player struct {
+	agents []*engine.Result
+	tasks  []engine.StepResult
+	failed bool
+}
+
+// Complete finds a recorded conversation of the agent that begins with the
+// request's messages and answers with its next one.
+func (p *player) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	for _, a := range p.agents {
+		if a.Agent != req.Agent || len(a.Messages) < len(req.Messages) || !samePrefix(a.Messages, req.Messages) {
+			continue
+		}
+		if len(a.Messages) == len(req.Messages) {
+			if p.failed && a.Output == nil {
+				return nil, fmt.Errorf("%w: %s turn", ErrFailedHere, req.Agent)
+			}
+			continue
+		}
+		next := a.Messages[len(req.Messages)]
+		if next.Role != engine.RoleAssistant {
+			continue
+		}
+		resp := &engine.Response{Content: next.Content, ToolCalls: next.ToolCalls}
+		if next.Usage != nil {
+			resp.Usage = *next.Usage
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("%w: %s has no recorded answer at message %d", ErrDiverged, req.Agent, len(req.Messages)+1)
+}
+
+func samePrefix(recorded, messages []engine.Message) bool {
+	for i, m := range messages {
+		if messageKey(recorded[i]) != messageKey(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// messageKey is what identifies a message, ignoring its usage.
+func messageKey(m engine.Message) string {
+	m.Usage = nil
+	data, _ := json.Marshal(m)
+	return string(data)
+}
+
+func (p *player) tools(m *ossa.Manifest) ossa.ToolExecFunc {
+	return func(_ context.Context, call ossa.ToolCall) ([]byte, error) {
+		for _, a := range p.agents {
+			if a.Agent != m.Metadata.Name {
+				continue
+			}
+			for _, rec := range a.ToolCalls {
+				if rec.ID == call.ID && rec.Tool == call.Tool && sameJSON(rec.Arguments, call.Arguments) {
+					if rec.Error != "" {
+						return nil, errors.New(rec.Error)
+					}
+					return []byte(rec.Output), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%w: %s has no recorded result for %s call %s", ErrDiverged, m.Metadata.Name, call.Tool, call.ID)
+	}
+}
+
+func (p *player) task(_ context.Context, m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+	for _, s := range p.tasks {
+		if s.Name != m.Metadata.Name || !sameJSON(s.Input, input) {
+			continue
+		}
+		if s.Output == nil {
+			if p.failed {
+				return nil, fmt.Errorf("%w: Task %s", ErrFailedHere, m.Metadata.Name)
+			}
+			continue
+		}
+		return s.Output, nil
+	}
+	return nil, fmt.Errorf("%w: Task %s has no recorded output for this input", ErrDiverged, m.Metadata.Name)
+}
+
+// sameJSON compares maps by their JSON, so a number recorded as a float
+// matches an int; nil matches empty, since empty maps are not recorded.
+func sameJSON(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,292 @@
+// Package runs records agent and workflow runs (their input, steps, tool
+// calls, tokens and outcome) in a Store, so what an agent actually did can
+// be looked at afterwards.
+//
+// A Recorder wraps an engine.Engine and saves each run as running when it
+// starts, so a crashed run still shows up, after each top-level workflow
+// step, so a workflow can be resumed where it stopped, and with its outcome.
+// DirStore keeps runs as JSON files; SQLStore keeps them in a database.
+package runs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// ErrNotFound is returned by Store.Get for an unknown run ID.
+var ErrNotFound = errors.New("run not found")
+
+// Run statuses.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Run is one recorded execution of an agent or workflow.
+type Run struct {
+	ID      string    `json:"id"`
+	Kind    ossa.Kind `json:"kind"`
+	Name    string    `json:"name"`
+	Version string    `json:"version,omitempty"`
+	// Source is the path of the manifest the run was started from.
+	Source   string                 `json:"source,omitempty"`
+	Status   string                 `json:"status"`
+	Error    string                 `json:"error,omitempty"`
+	Started  time.Time              `json:"started"`
+	Finished time.Time              `json:"finished"`
+	Input    map[string]interface{} `json:"input,omitempty"`
+	Output   map[string]interface{} `json:"output,omitempty"`
+	Usage    engine.Usage           `json:"usage"`
+	// Seed is the engine's seed, for repeating the run live.
+	Seed *int `json:"seed,omitempty"`
+	// Agent is the agent run of a kind: Agent run.
+	Agent *engine.Result `json:"agent,omitempty"`
+	// Steps are the steps of a kind: Workflow run.
+	Steps []engine.StepResult `json:"steps,omitempty"`
+	// Done lists the top-level workflow steps that completed, which a
+	// resumed run does not run again.
+	Done []string `json:"done,omitempty"`
+}
+
+// Checkpoint is where a workflow run got to, for resuming it: its done
+// steps, and the results up to the last of them. Results of a step that
+// failed after it are left out, so the step runs again.
+func (r *Run) Checkpoint() *engine.Checkpoint {
+	// A failed run's output is still that of its last done step.
+	cp := &engine.Checkpoint{Done: r.Done, Output: r.Output}
+	if len(r.Done) == 0 {
+		return cp
+	}
+	// A completed top-level step's own result is the last of its results.
+	last := r.Done[len(r.Done)-1]
+	for i := len(r.Steps) - 1; i >= 0; i-- {
+		if r.Steps[i].Step == last {
+			cp.Steps = r.Steps[:i+1]
+			break
+		}
+	}
+	return cp
+}
+
+// Duration is how long the run took, or has taken so far.
+func (r *Run) Duration() time.Duration {
+	if r.Finished.IsZero() {
+		return time.Since(r.Started)
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// Agents returns the agent runs of the run, in order.
+func (r *Run) Agents() []*engine.Result {
+	if r.Agent != nil {
+		return []*engine.Result{r.Agent}
+	}
+	var out []*engine.Result
+	for _, s := range r.Steps {
+		if s.Result != nil {
+			out = append(out, s.Result)
+		}
+	}
+	return out
+}
+
+// ToolCalls counts the tool calls of the run's agents.
+func (r *Run) ToolCalls() int {
+	n := 0
+	for _, a := range r.Agents() {
+		n += len(a.ToolCalls)
+	}
+	return n
+}
+
+// Filter selects runs to list. Zero fields match every run.
+type Filter struct {
+	Name   string
+	Status string
+	Since  time.Time
+	// Limit bounds the runs returned; 0 means no limit.
+	Limit int
+}
+
+func (f Filter) match(r *Run) bool {
+	return (f.Name == "" || r.Name == f.Name) &&
+		(f.Status == "" || r.Status == f.Status) &&
+		(f.Since.IsZero() || !r.Started.Before(f.Since))
+}
+
+// Store persists runs.
+type Store interface {
+	// Save creates or replaces the run with r's ID.
+	Save(ctx context.Context, r *Run) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (*Run, error)
+	// List returns the matching runs, newest first.
+	List(ctx context.Context, f Filter) ([]*Run, error)
+}
+
+// NewID returns a run ID that sorts by start time, such as
+// 20261014T093000-1a2b3c4d.
+func NewID(started time.Time) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return started.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// Recorder runs agents and workflows on Engine and saves them to Store. A
+// nil Store records nothing.
+type Recorder struct {
+	Engine *engine.Engine
+	Store  Store
+	// OnStart, if set, is called with each new run once it is first
+	// saved, before it runs.
+	OnStart func(run *Run)
+}
+
+// RunAgent runs m and records it. source is the manifest's path, kept so
+// the run can be replayed. The run is returned even when it failed.
+func (rec *Recorder) RunAgent(ctx context.Context, m *ossa.Manifest, source string, input map[string]interface{}) (*Run, error) {
+	run := rec.start(ossa.KindAgent, m.Metadata.Name, m.Metadata.Version, source, input)
+	if err := rec.saveNew(ctx, run); err != nil {
+		return nil, err
+	}
+	res, err := rec.Engine.RunAgent(ctx, m, input)
+	run.Agent = res
+	if res != nil {
+		run.Output, run.Usage = res.Output, res.Usage
+	}
+	return run, rec.finish(run, err)
+}
+
+// RunTask runs the kind: Task m with the engine's Task runner and records
+// it, with source as for RunAgent.
+func (rec *Recorder) RunTask(ctx context.Context, m *ossa.Manifest, source string, input map[string]interface{}) (*Run, error) {
+	run := rec.start(ossa.KindTask, m.Metadata.Name, m.Metadata.Version, source, input)
+	if err := rec.saveNew(ctx, run); err != nil {
+		return nil, err
+	}
+	var err error
+	if rec.Engine.Task == nil {
+		err = fmt.Errorf("no runner for Task %s", m.Metadata.Name)
+	} else {
+		run.Output, err = rec.Engine.Task(ctx, m, input)
+	}
+	return run, rec.finish(run, err)
+}
+
+// RunWorkflow runs w and records it, with source as for RunAgent.
+func (rec *Recorder) RunWorkflow(ctx context.Context, w *engine.Workflow, source string, input map[string]interface{}) (*Run, error) {
+	run := rec.start(ossa.KindWorkflow, w.Name, w.Version, source, input)
+	if err := rec.saveNew(ctx, run); err != nil {
+		return nil, err
+	}
+	return rec.runWorkflow(ctx, w, run, nil)
+}
+
+// ResumeWorkflow continues run, a failed or interrupted run of w, from its
+// last checkpoint: steps that completed are not run again. The run keeps
+// its ID and is recorded in place.
+func (rec *Recorder) ResumeWorkflow(ctx context.Context, w *engine.Workflow, run *Run) (*Run, error) {
+	if run.Kind != ossa.KindWorkflow {
+		return nil, fmt.Errorf("run %s is of an %s, not a workflow", run.ID, run.Kind)
+	}
+	if run.Status == StatusSucceeded {
+		return nil, fmt.Errorf("run %s already succeeded", run.ID)
+	}
+	cp := run.Checkpoint()
+	run.Status, run.Error, run.Finished = StatusRunning, "", time.Time{}
+	if err := rec.save(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to record run: %w", err)
+	}
+	return rec.runWorkflow(ctx, w, run, cp)
+}
+
+func (rec *Recorder) runWorkflow(ctx context.Context, w *engine.Workflow, run *Run, cp *engine.Checkpoint) (*Run, error) {
+	e := *rec.Engine
+	if e.Seed == nil {
+		// A resumed run keeps its seed.
+		e.Seed = run.Seed
+	}
+	e.Checkpoint = func(ctx context.Context, cp *engine.Checkpoint) error {
+		if rec.Engine.Checkpoint != nil {
+			if err := rec.Engine.Checkpoint(ctx, cp); err != nil {
+				return err
+			}
+		}
+		run.Done = append([]string(nil), cp.Done...)
+		run.Steps = append([]engine.StepResult(nil), cp.Steps...)
+		run.Output = cp.Output
+		// A lost checkpoint only costs redoing the step on resume.
+		if err := rec.save(ctx, run); err != nil {
+			ossa.Logger().Warn("failed to checkpoint run", "run", run.ID, "error", err)
+		}
+		return nil
+	}
+	res, err := e.ResumeWorkflow(ctx, w, run.Input, cp)
+	if res != nil {
+		run.Output, run.Usage, run.Steps = res.Output, res.Usage, res.Steps
+	}
+	return run, rec.finish(run, err)
+}
+
+func (rec *Recorder) start(kind ossa.Kind, name, version, source string, input map[string]interface{}) *Run {
+	started := time.Now()
+	return &Run{
+		ID:      NewID(started),
+		Kind:    kind,
+		Name:    name,
+		Version: version,
+		Source:  source,
+		Status:  StatusRunning,
+		Started: started,
+		Input:   input,
+		Seed:    rec.Engine.Seed,
+	}
+}
+
+// finish saves the run's outcome. The run's own error wins over one
+// saving it, which is logged instead.
+func (rec *Recorder) finish(run *Run, runErr error) error {
+	run.Finished = time.Now()
+	run.Status = StatusSucceeded
+	if runErr != nil {
+		run.Status, run.Error = StatusFailed, runErr.Error()
+	}
+	// Record the outcome even if the run was cancelled.
+	err := rec.save(context.Background(), run)
+	if runErr != nil {
+		if err != nil {
+			ossa.Logger().Warn("failed to record run", "run", run.ID, "error", err)
+		}
+		return runErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// saveNew saves a run as it starts and hands it to OnStart.
+func (rec *Recorder) saveNew(ctx context.Context, run *Run) error {
+	if err := rec.save(ctx, run); err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	if rec.OnStart != nil {
+		rec.OnStart(run)
+	}
+	return nil
+}
+
+func (rec *Recorder) save(ctx context.Context, run *Run) error {
+	if rec.Store == nil {
+		return nil
+	}
+	return rec.Store.Save(ctx, run)
+}
@@ -0,0 +1,392 @@
+package runs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+func lookupThenAnswer(req *engine.Request) (*engine.Response, error) {
+	if req.Messages[len(req.Messages)-1].Role == engine.RoleUser {
+		return &engine.Response{ToolCalls: []ossa.ToolCall{{ID: "c1", Tool: "lookup"}}, Usage: engine.Usage{InputTokens: 5}}, nil
+	}
+	return &engine.Response{Content: `{"priority": "high"}`, Usage: engine.Usage{InputTokens: 7, OutputTokens: 2}}, nil
+}
+
+func TestRecorder(t *testing.T) {
+	store := &DirStore{Dir: t.TempDir()}
+	var seen []string
+	e := &engine.Engine{
+		Model: modelFunc(lookupThenAnswer),
+		Tools: func(*ossa.Manifest) ossa.ToolExecFunc {
+			return func(ctx context.Context, _ ossa.ToolCall) ([]byte, error) {
+				// The run is saved as running before it starts.
+				runs, _ := store.List(ctx, Filter{})
+				for _, r := range runs {
+					seen = append(seen, r.Status)
+				}
+				return []byte("ticket 42"), nil
+			}
+		},
+	}
+	rec := &Recorder{Engine: e, Store: store}
+	m := ossa.NewManifest("triage", ossa.KindAgent)
+	m.Metadata.Version = "1.0.0"
+	run, err := rec.RunAgent(context.Background(), m, "agents/triage.ossa.yaml", map[string]interface{}{"ticket": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(seen, " ") != StatusRunning {
+		t.Errorf("Expected a running record during the run, saw %v", seen)
+	}
+
+	got, err := store.Get(context.Background(), run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusSucceeded || got.Name != "triage" || got.Version != "1.0.0" || got.Source != "agents/triage.ossa.yaml" {
+		t.Errorf("Unexpected run %+v", got)
+	}
+	if got.Output["priority"] != "high" || got.Usage.InputTokens != 12 || got.ToolCalls() != 1 || got.Input["ticket"] != float64(42) {
+		t.Errorf("Unexpected outcome %+v", got)
+	}
+	if len(got.Agent.Messages) != 5 || got.Agent.ToolCalls[0].Output != "ticket 42" {
+		t.Errorf("Expected the conversation recorded, got %+v", got.Agent)
+	}
+
+	// A failed run is recorded with its error.
+	e.Model = modelFunc(func(*engine.Request) (*engine.Response, error) { return nil, errors.New("rate limited") })
+	failed, err := rec.RunAgent(context.Background(), m, "", nil)
+	if err == nil || failed.Status != StatusFailed || !strings.Contains(failed.Error, "rate limited") {
+		t.Errorf("Expected a failed run, got %+v %v", failed, err)
+	}
+
+	list, err := store.List(context.Background(), Filter{Status: StatusFailed})
+	if err != nil || len(list) != 1 || list[0].ID != failed.ID {
+		t.Errorf("Unexpected failed runs %v %v", list, err)
+	}
+	if list, _ := store.List(context.Background(), Filter{}); len(list) != 2 || list[0].ID != failed.ID {
+		t.Errorf("Expected newest first, got %v", list)
+	}
+	// The engine's seed is recorded for live replays.
+	e.Model, e.Seed = modelFunc(lookupThenAnswer), new(int)
+	*e.Seed = 42
+	seeded, err := rec.RunAgent(context.Background(), m, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Get(context.Background(), seeded.ID); got == nil || got.Seed == nil || *got.Seed != 42 {
+		t.Errorf("Expected seed 42 recorded, got %+v", got)
+	}
+	e.Seed = nil
+
+	if _, err := store.Get(context.Background(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "../etc/passwd"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected an invalid ID rejected, got %v", err)
+	}
+}
+
+// fakeDriver keeps ossa_runs rows in memory and records list queries.
+type fakeDriver struct {
+	mu      sync.Mutex
+	rows    map[string][]driver.Value
+	queries []string
+	args    [][]driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("no transactions") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.d.rows[args[0].(string)] = args
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if strings.HasSuffix(s.query, "WHERE id = ?") {
+		if row, ok := s.d.rows[args[0].(string)]; ok {
+			return &fakeRows{cols: []string{"data"}, values: [][]driver.Value{{row[4]}}}, nil
+		}
+		return &fakeRows{cols: []string{"data"}}, nil
+	}
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	var values [][]driver.Value
+	for id, row := range s.d.rows {
+		values = append(values, []driver.Value{id, row[4]})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i][0].(string) > values[j][0].(string) })
+	return &fakeRows{cols: []string{"id", "data"}, values: values}, nil
+}
+
+type fakeRows struct {
+	cols   []string
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var testDriver = &fakeDriver{rows: map[string][]driver.Value{}}
+
+func init() {
+	sql.Register("ossarunsfake", testDriver)
+}
+
+func TestSQLStore(t *testing.T) {
+	db, err := sql.Open("ossarunsfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewSQLStore(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	run := &Run{ID: NewID(started), Kind: ossa.KindAgent, Name: "triage", Status: StatusRunning, Started: started}
+	if err := store.Save(context.Background(), run); err != nil {
+		t.Fatal(err)
+	}
+	run.Status = StatusSucceeded
+	if err := store.Save(context.Background(), run); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Get(context.Background(), run.ID)
+	if err != nil || got.Status != StatusSucceeded || !got.Started.Equal(started) {
+		t.Errorf("Unexpected run %+v %v", got, err)
+	}
+	if !strings.HasPrefix(run.ID, "20261014T093000-") {
+		t.Errorf("Expected a time-ordered ID, got %s", run.ID)
+	}
+	if _, err := store.Get(context.Background(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	list, err := store.List(context.Background(), Filter{Name: "triage", Since: started, Limit: 5})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("Unexpected list %v %v", list, err)
+	}
+	if want := "SELECT id, data FROM ossa_runs WHERE name = ? AND started >= ? ORDER BY started DESC LIMIT 5"; testDriver.queries[0] != want {
+		t.Errorf("Unexpected list query %q", testDriver.queries[0])
+	}
+	if args := testDriver.args[0]; args[0] != "triage" || args[1] != "2026-10-14T09:30:00.000000000Z" {
+		t.Errorf("Unexpected list args %v", args)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	w, err := engine.ParseWorkflow([]byte(`kind: Workflow
+metadata:
+  name: support
+spec:
+  steps:
+    - id: triage
+      ref: triage
+      input:
+        ticket: ${{ workflow.input.ticket }}
+    - id: notify
+      ref: notify
+      input:
+        priority: ${{ steps.triage.output.priority }}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := "Triage."
+	load := func(_ context.Context, ref string) (*ossa.Manifest, error) {
+		if ref == "notify" {
+			return ossa.NewManifest("notify", ossa.KindTask), nil
+		}
+		m := ossa.NewManifest(ref, ossa.KindAgent)
+		m.Spec.Role = role
+		return m, nil
+	}
+	live := 0
+	e := &engine.Engine{
+		Model: modelFunc(lookupThenAnswer),
+		Load:  load,
+		Tools: func(*ossa.Manifest) ossa.ToolExecFunc {
+			return func(context.Context, ossa.ToolCall) ([]byte, error) {
+				live++
+				return []byte("ticket 42"), nil
+			}
+		},
+		Task: func(_ context.Context, _ *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+			live++
+			return map[string]interface{}{"sent": input["priority"]}, nil
+		},
+	}
+	rec := &Recorder{Engine: e, Store: &DirStore{Dir: t.TempDir()}}
+	run, err := rec.RunWorkflow(context.Background(), w, "", map[string]interface{}{"ticket": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replay from the stored copy, as ossa runs replay does.
+	if run, err = rec.Store.Get(context.Background(), run.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	live = 0
+	replay := run.Replay(e)
+	res, err := replay.RunWorkflow(context.Background(), w, run.Input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live != 0 {
+		t.Errorf("Expected no live calls, got %d", live)
+	}
+	if res.Output["sent"] != "high" || res.Usage != run.Usage {
+		t.Errorf("Expected the recorded outcome, got %+v", res)
+	}
+
+	// A changed prompt is no longer the recorded conversation.
+	role = "Triage harder."
+	if _, err := replay.RunWorkflow(context.Background(), w, run.Input); !errors.Is(err, ErrDiverged) {
+		t.Errorf("Expected divergence, got %v", err)
+	}
+
+	// A failed run replays up to where it failed.
+	role = "Triage."
+	e.Task = func(context.Context, *ossa.Manifest, map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("smtp down")
+	}
+	failed, err := rec.RunWorkflow(context.Background(), w, "", map[string]interface{}{"ticket": 42})
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+	if failed, err = rec.Store.Get(context.Background(), failed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := failed.Replay(e).RunWorkflow(context.Background(), w, failed.Input); !errors.Is(err, ErrFailedHere) {
+		t.Errorf("Expected the recorded failure, got %v", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	w, err := engine.ParseWorkflow([]byte(`kind: Workflow
+metadata:
+  name: support
+spec:
+  steps:
+    - id: triage
+      ref: triage
+    - id: notify
+      ref: notify
+      input:
+        priority: ${{ steps.triage.output.priority }}
+    - id: close
+      ref: close
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &DirStore{Dir: t.TempDir()}
+	turns := 0
+	var checkpointed []string
+	e := &engine.Engine{
+		Model: modelFunc(func(req *engine.Request) (*engine.Response, error) {
+			turns++
+			return &engine.Response{Content: `{"priority": "high"}`, Usage: engine.Usage{InputTokens: 3}}, nil
+		}),
+		Load: func(_ context.Context, ref string) (*ossa.Manifest, error) {
+			if ref == "triage" {
+				return ossa.NewManifest(ref, ossa.KindAgent), nil
+			}
+			return ossa.NewManifest(ref, ossa.KindTask), nil
+		},
+		Task: func(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+			if m.Metadata.Name == "notify" {
+				list, _ := store.List(ctx, Filter{})
+				checkpointed = list[0].Done
+				return nil, errors.New("smtp down")
+			}
+			return map[string]interface{}{"closed": true}, nil
+		},
+	}
+	rec := &Recorder{Engine: e, Store: store}
+	run, err := rec.RunWorkflow(context.Background(), w, "", nil)
+	if err == nil {
+		t.Fatal("Expected notify to fail")
+	}
+	if strings.Join(checkpointed, " ") != "triage" {
+		t.Errorf("Expected a checkpoint after triage, got %v", checkpointed)
+	}
+	if run, err = store.Get(context.Background(), run.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified map[string]interface{}
+	e.Task = func(_ context.Context, m *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+		if m.Metadata.Name == "notify" {
+			notified = input
+		}
+		return map[string]interface{}{"closed": true}, nil
+	}
+	resumed, err := rec.ResumeWorkflow(context.Background(), w, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turns != 1 {
+		t.Errorf("Expected triage not run again, got %d turns", turns)
+	}
+	if notified["priority"] != "high" {
+		t.Errorf("Expected triage's output restored, got %v", notified)
+	}
+	got, _ := store.Get(context.Background(), run.ID)
+	if got.Status != StatusSucceeded || got.Error != "" || strings.Join(got.Done, " ") != "triage notify close" || len(got.Steps) != 3 {
+		t.Errorf("Unexpected resumed run %+v", got)
+	}
+	if resumed.Usage.InputTokens != 3 || resumed.Output["closed"] != true {
+		t.Errorf("Unexpected outcome %+v", resumed)
+	}
+	if _, err := rec.ResumeWorkflow(context.Background(), w, got); err == nil {
+		t.Error("Expected a succeeded run not resumed")
+	}
+}
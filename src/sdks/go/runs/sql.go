@@ -0,0 +1,110 @@
+package runs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLStore keeps runs in the ossa_runs table of a SQLite database, through
+// whichever database/sql driver the program registers (such as
+// modernc.org/sqlite). A run is stored as its JSON, beside the columns
+// List filters on.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates the ossa_runs table if it does not exist.
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS ossa_runs (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	status TEXT NOT NULL,
+	started TEXT NOT NULL,
+	data TEXT NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ossa_runs: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// sqlTime formats times so they sort as text.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+func (s *SQLStore) Save(ctx context.Context, r *Run) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO ossa_runs (id, name, status, started, data) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name, status = excluded.status, started = excluded.started, data = excluded.data`,
+		r.ID, r.Name, r.Status, sqlTime(r.Started), string(data))
+	return err
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (*Run, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM ossa_runs WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRun(id, data)
+}
+
+func (s *SQLStore) List(ctx context.Context, f Filter) ([]*Run, error) {
+	var where []string
+	var args []interface{}
+	if f.Name != "" {
+		where, args = append(where, "name = ?"), append(args, f.Name)
+	}
+	if f.Status != "" {
+		where, args = append(where, "status = ?"), append(args, f.Status)
+	}
+	if !f.Since.IsZero() {
+		where, args = append(where, "started >= ?"), append(args, sqlTime(f.Since))
+	}
+	query := "SELECT id, data FROM ossa_runs"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Run
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		r, err := decodeRun(id, data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func decodeRun(id, data string) (*Run, error) {
+	var r Run
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,380 @@
+// Package drupal runs OSSA tools declared with handler.runtime: drupal
+// against a Drupal site's JSON:API or custom REST resources.
+//
+// The handler's capability picks the resource (see ossa.DrupalConfig.Route).
+// JSON:API calls take these arguments:
+//
+//	id                 entity UUID for get, update and delete
+//	attributes         entity fields for create and update
+//	relationships      JSON:API relationships for create and update
+//	filter             map of field to value for list
+//
+// Custom endpoints fill {name} path placeholders from arguments, send
+// "body" as JSON and "query" as the query string.
+//
+// Session auth logs in through /user/login and sends the /session/token
+// CSRF token on unsafe requests; oauth auth uses the client credentials
+// grant against /oauth/token (Simple OAuth). Writes are refused for agents
+// whose tier cannot write.
+package drupal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultMaxResponseBytes bounds the response body read per call.
+const DefaultMaxResponseBytes = 1 << 20
+
+const jsonAPIType = "application/vnd.api+json"
+
+var pathPlaceholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Options configures the drupal runtime.
+type Options struct {
+	// Client performs requests; nil means a client with a 30s timeout
+	// dialing with the handler's tls settings.
+	Client *http.Client
+	// BaseDir resolves relative handler.tls paths and is where the
+	// workspace egress config (ossa.EgressFile) is looked for.
+	BaseDir string
+	// LookupEnv resolves credential variables; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// MaxResponseBytes bounds response bodies.
+	MaxResponseBytes int64
+}
+
+// Runtime executes one drupal tool. It is safe for concurrent use and
+// reuses its session or token across calls.
+type Runtime struct {
+	tool    string
+	cfg     ossa.DrupalConfig
+	route   ossa.DrupalRoute
+	base    string
+	client  *http.Client
+	creds   map[string]string
+	maxBody int64
+
+	mu        sync.Mutex
+	csrfToken string
+	token     string
+	expires   time.Time
+}
+
+// New resolves the tool's capability and credentials.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	h := tool.Handler
+	if h == nil || h.Runtime != ossa.RuntimeDrupal || h.Drupal == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a drupal tool", tool.Name))
+	}
+	route, err := h.Drupal.Route(h.Capability)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", tool.Name), err)
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.AllowsMethod(route.Method) {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: %s is not allowed for %s agents", tool.Name, route.Method, policy.Tier))
+	}
+
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	creds := map[string]string{}
+	var names []string
+	switch h.Drupal.Auth {
+	case ossa.DrupalAuthSession:
+		names = []string{h.Drupal.UsernameEnv, h.Drupal.PasswordEnv}
+	case ossa.DrupalAuthOAuth:
+		names = []string{h.Drupal.ClientIDEnv, h.Drupal.ClientSecretEnv}
+	}
+	for _, name := range names {
+		v, ok := opts.LookupEnv(name)
+		if !ok || v == "" {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: environment variable %s is not set", tool.Name, name))
+		}
+		creds[name] = v
+	}
+
+	var client *http.Client
+	if opts.Client == nil {
+		transport, err := ossa.ToolTransport(m, tool, opts.BaseDir)
+		if err != nil {
+			return nil, err
+		}
+		client = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	} else {
+		c := *opts.Client
+		client = &c
+	}
+	if h.Drupal.Auth == ossa.DrupalAuthSession {
+		client.Jar, _ = cookiejar.New(nil)
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	return &Runtime{
+		tool:    tool.Name,
+		cfg:     *h.Drupal,
+		route:   route,
+		base:    strings.TrimSuffix(h.Drupal.BaseURL, "/"),
+		client:  client,
+		creds:   creds,
+		maxBody: opts.MaxResponseBytes,
+	}, nil
+}
+
+// Execute performs the call and returns the response body.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	args := call.Arguments
+	target, err := r.target(args)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+	}
+	body, contentType, err := r.body(args)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.route.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.route.JSONAPI {
+		req.Header.Set("Accept", jsonAPIType)
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if err := r.authorize(ctx, req); err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: authentication failed", r.tool), err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: request failed", r.tool), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		r.reset()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: %s %s returned %d: %s", r.tool, r.route.Method, req.URL.Path, resp.StatusCode, truncate(data, 200)))
+	}
+	if len(data) == 0 {
+		return []byte("{}"), nil
+	}
+	return data, nil
+}
+
+func (r *Runtime) target(args map[string]interface{}) (string, error) {
+	var err error
+	path := pathPlaceholder.ReplaceAllStringFunc(r.route.Path, func(match string) string {
+		name := match[1 : len(match)-1]
+		v, ok := args[name]
+		if !ok {
+			err = fmt.Errorf("missing argument %s", name)
+			return ""
+		}
+		return url.PathEscape(fmt.Sprint(v))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	if r.route.JSONAPI {
+		if filter, ok := args["filter"].(map[string]interface{}); ok {
+			for k, v := range filter {
+				q.Set("filter["+k+"]", fmt.Sprint(v))
+			}
+		}
+	} else {
+		if query, ok := args["query"].(map[string]interface{}); ok {
+			for k, v := range query {
+				q.Set(k, fmt.Sprint(v))
+			}
+		}
+		q.Set("_format", "json")
+	}
+	target := r.base + path
+	if len(q) > 0 {
+		target += "?" + q.Encode()
+	}
+	return target, nil
+}
+
+func (r *Runtime) body(args map[string]interface{}) (io.Reader, string, error) {
+	switch r.route.Method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		return nil, "", nil
+	}
+	var doc interface{}
+	contentType := "application/json"
+	if r.route.JSONAPI {
+		data := map[string]interface{}{"type": r.route.Type}
+		if id, ok := args["id"]; ok {
+			data["id"] = id
+		}
+		if attrs, ok := args["attributes"]; ok {
+			data["attributes"] = attrs
+		}
+		if rels, ok := args["relationships"]; ok {
+			data["relationships"] = rels
+		}
+		doc, contentType = map[string]interface{}{"data": data}, jsonAPIType
+	} else {
+		doc = args["body"]
+	}
+	if doc == nil {
+		return nil, "", nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode body: %w", err)
+	}
+	return bytes.NewReader(data), contentType, nil
+}
+
+func (r *Runtime) authorize(ctx context.Context, req *http.Request) error {
+	switch r.cfg.Auth {
+	case ossa.DrupalAuthSession:
+		token, err := r.session(ctx)
+		if err != nil {
+			return err
+		}
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			req.Header.Set("X-CSRF-Token", token)
+		}
+	case ossa.DrupalAuthOAuth:
+		token, err := r.oauthToken(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// session logs in once and returns the CSRF token for the session.
+func (r *Runtime) session(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.csrfToken != "" {
+		return r.csrfToken, nil
+	}
+
+	login, _ := json.Marshal(map[string]string{
+		"name": r.creds[r.cfg.UsernameEnv],
+		"pass": r.creds[r.cfg.PasswordEnv],
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.base+"/user/login?_format=json", bytes.NewReader(login))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := r.send(req); err != nil {
+		return "", err
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, r.base+"/session/token", nil)
+	if err != nil {
+		return "", err
+	}
+	token, err := r.send(req)
+	if err != nil {
+		return "", err
+	}
+	r.csrfToken = strings.TrimSpace(string(token))
+	return r.csrfToken, nil
+}
+
+// oauthToken returns a cached access token, requesting a new one shortly
+// before the current one expires.
+func (r *Runtime) oauthToken(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token != "" && time.Now().Before(r.expires) {
+		return r.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {r.creds[r.cfg.ClientIDEnv]},
+		"client_secret": {r.creds[r.cfg.ClientSecretEnv]},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.base+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	data, err := r.send(req)
+	if err != nil {
+		return "", err
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("invalid token response")
+	}
+	r.token = tok.AccessToken
+	r.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - 30*time.Second)
+	return r.token, nil
+}
+
+func (r *Runtime) send(req *http.Request) ([]byte, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned %d", req.URL.Path, resp.StatusCode)
+	}
+	return data, nil
+}
+
+// reset drops the session or token after the site rejects it, so the next
+// call authenticates again. A new login replaces the session cookie.
+func (r *Runtime) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.csrfToken, r.token = "", ""
+}
+
+func truncate(data []byte, n int) string {
+	s := strings.TrimSpace(string(data))
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
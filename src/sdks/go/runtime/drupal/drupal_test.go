@@ -0,0 +1,135 @@
+package drupal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func drupalTool(base, capability string, cfg ossa.DrupalConfig) ossa.ToolConfig {
+	cfg.BaseURL = base
+	return ossa.ToolConfig{
+		Type: "function",
+		Name: "content",
+		Handler: &ossa.ToolHandler{
+			Runtime:    ossa.RuntimeDrupal,
+			Capability: capability,
+			Drupal:     &cfg,
+		},
+	}
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) { v, ok := vars[k]; return v, ok }
+}
+
+func writer() *ossa.Manifest {
+	m := ossa.NewManifest("content-agent", ossa.KindAgent)
+	m.Spec.AccessTier = ossa.TierWriteLimited
+	return m
+}
+
+func TestJSONAPICreateWithSession(t *testing.T) {
+	logins := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/login":
+			logins++
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["name"] != "bot" || body["pass"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SESS1", Value: "abc", Path: "/"})
+		case "/session/token":
+			w.Write([]byte("csrf-123"))
+		case "/jsonapi/node/article":
+			if c, err := r.Cookie("SESS1"); err != nil || c.Value != "abc" || r.Header.Get("X-CSRF-Token") != "csrf-123" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != jsonAPIType {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	tool := drupalTool(srv.URL, "node.article.create", ossa.DrupalConfig{
+		Auth: ossa.DrupalAuthSession, UsernameEnv: "DRUPAL_USER", PasswordEnv: "DRUPAL_PASS",
+	})
+	rt, err := New(writer(), tool, Options{LookupEnv: env(map[string]string{"DRUPAL_USER": "bot", "DRUPAL_PASS": "secret"})})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	call := ossa.ToolCall{Arguments: map[string]interface{}{"attributes": map[string]interface{}{"title": "Hello"}}}
+	for i := 0; i < 2; i++ {
+		out, err := rt.Execute(context.Background(), call)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if string(out) != `{"data":{"attributes":{"title":"Hello"},"type":"node--article"}}` {
+			t.Errorf("Unexpected output %s", out)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("Expected session to be reused, got %d logins", logins)
+	}
+}
+
+func TestCustomEndpointWithOAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_secret") != "s3" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token":"tok","expires_in":300}`))
+		case "/api/orders/42":
+			if r.Header.Get("Authorization") != "Bearer tok" || r.URL.Query().Get("_format") != "json" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"id":42,"status":"open"}`))
+		}
+	}))
+	defer srv.Close()
+
+	reader := ossa.NewManifest("reader", ossa.KindAgent)
+	reader.Spec.AccessTier = ossa.TierRead
+	tool := drupalTool(srv.URL, "commerce.order.get", ossa.DrupalConfig{
+		Auth: ossa.DrupalAuthOAuth, ClientIDEnv: "CID", ClientSecretEnv: "CSECRET",
+		Endpoints: map[string]ossa.DrupalEndpoint{"commerce.order.get": {Path: "/api/orders/{order_id}"}},
+	})
+	rt, err := New(reader, tool, Options{LookupEnv: env(map[string]string{"CID": "agent", "CSECRET": "s3"})})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{"order_id": 42}})
+	if err != nil || string(out) != `{"id":42,"status":"open"}` {
+		t.Errorf("Unexpected result %s, %v", out, err)
+	}
+}
+
+func TestWriteCapabilityNeedsWriteTier(t *testing.T) {
+	reader := ossa.NewManifest("reader", ossa.KindAgent)
+	reader.Spec.AccessTier = ossa.TierRead
+	tool := drupalTool("https://example.com", "node.article.delete", ossa.DrupalConfig{})
+	if _, err := New(reader, tool, Options{}); err == nil || !strings.Contains(err.Error(), "DELETE") {
+		t.Errorf("Expected delete to be refused for read tier, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package exec runs OSSA tools declared with handler.runtime: exec as local
+// processes.
+//
+// Only binaries in Options.AllowedCommands may run, arguments come from the
+// handler's templates rather than a shell, and the process runs in a
+// working directory inside Options.BaseDir with only the sandbox
+// environment. Every call is passed to Options.Audit, which is required.
+// Agents whose tier cannot write may not use this runtime at all.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultTimeout applies when the handler sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMaxOutputBytes bounds stdout and stderr when the handler sets no
+// max_output_bytes.
+const DefaultMaxOutputBytes = 1 << 20
+
+// AuditRecord describes one process run.
+type AuditRecord struct {
+	Time     time.Time
+	Agent    string
+	Tool     string
+	CallID   string
+	Command  string
+	Args     []string
+	Dir      string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// Options configures the exec runtime.
+type Options struct {
+	// BaseDir confines working directories, usually the manifest's
+	// directory.
+	BaseDir string
+	// AllowedCommands lists the binaries tools may run, by name or
+	// absolute path. Commands are resolved with PATH lookup before
+	// matching.
+	AllowedCommands []string
+	// Audit receives a record of every call and must be set.
+	Audit func(AuditRecord)
+}
+
+// Runtime executes one exec tool. It is safe for concurrent use.
+type Runtime struct {
+	agent   string
+	tool    string
+	cfg     ossa.ExecConfig
+	path    string
+	dir     string
+	env     []string
+	timeout time.Duration
+	audit   func(AuditRecord)
+}
+
+// New checks the tool against the host allowlist and the manifest's tier.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeExec || tool.Handler.Exec == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not an exec tool", tool.Name))
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Tier.CanWrite() {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: exec runtime is not allowed for %s agents", tool.Name, policy.Tier))
+	}
+	if opts.Audit == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: exec runtime requires an audit log", tool.Name))
+	}
+	cfg := *tool.Handler.Exec
+
+	path, err := allowed(cfg.Command, opts.AllowedCommands)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", tool.Name), err)
+	}
+
+	base, err := filepath.Abs(opts.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	// Compare real paths, so a symlink in working_dir cannot lead out.
+	if base, err = filepath.EvalSymlinks(base); err != nil {
+		return nil, err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Join(base, cfg.WorkingDir))
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: working_dir %s", tool.Name, cfg.WorkingDir), err)
+	}
+	if rel, err := filepath.Rel(base, dir); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: working_dir %s is outside %s", tool.Name, cfg.WorkingDir, base))
+	}
+
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	timeout := tool.Handler.Timeout.Std()
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	env := make([]string, 0, len(policy.Env))
+	for k, v := range policy.Env {
+		env = append(env, k+"="+v)
+	}
+	return &Runtime{
+		agent:   m.Metadata.Name,
+		tool:    tool.Name,
+		cfg:     cfg,
+		path:    path,
+		dir:     dir,
+		env:     env,
+		timeout: timeout,
+		audit:   opts.Audit,
+	}, nil
+}
+
+// Execute runs the command for one call and returns
+// {"exit_code", "stdout", "stderr"}. A non-zero exit is reported in the
+// result, not as an error; timeouts and output over the limit are errors.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	rec := AuditRecord{Time: time.Now(), Agent: r.agent, Tool: r.tool, CallID: call.ID, Command: r.path, Dir: r.dir, ExitCode: -1}
+	defer func() {
+		rec.Duration = time.Since(rec.Time)
+		r.audit(rec)
+	}()
+
+	args, err := r.cfg.ExpandArgs(call.Arguments)
+	if err != nil {
+		rec.Err = ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+		return nil, rec.Err
+	}
+	rec.Args = args
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cmd := osexec.CommandContext(ctx, r.path, args...)
+	cmd.Dir = r.dir
+	cmd.Env = r.env
+	stdout := &limitedBuffer{limit: r.cfg.MaxOutputBytes, cancel: cancel}
+	stderr := &limitedBuffer{limit: r.cfg.MaxOutputBytes, cancel: cancel}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err = cmd.Run()
+	rec.ExitCode = cmd.ProcessState.ExitCode()
+	switch {
+	case stdout.overflow || stderr.overflow:
+		rec.Err = ossa.NewError(fmt.Sprintf("tool %s: output exceeds %d bytes", r.tool, r.cfg.MaxOutputBytes))
+	case ctx.Err() != nil:
+		rec.Err = ossa.WrapError(fmt.Sprintf("tool %s: command did not finish", r.tool), ctx.Err())
+	case err != nil:
+		var exitErr *osexec.ExitError
+		if !errors.As(err, &exitErr) {
+			rec.Err = ossa.WrapError(fmt.Sprintf("tool %s: failed to run command", r.tool), err)
+		}
+	}
+	if rec.Err != nil {
+		return nil, rec.Err
+	}
+	return json.Marshal(map[string]interface{}{
+		"exit_code": rec.ExitCode,
+		"stdout":    stdout.String(),
+		"stderr":    stderr.String(),
+	})
+}
+
+func allowed(command string, allowlist []string) (string, error) {
+	path, err := osexec.LookPath(command)
+	if err != nil {
+		return "", fmt.Errorf("command %s not found: %w", command, err)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range allowlist {
+		p, err := osexec.LookPath(a)
+		if err != nil {
+			continue
+		}
+		if p, err = filepath.Abs(p); err == nil && p == path {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("command %s is not in the allowlist", command)
+}
+
+// limitedBuffer keeps the first limit bytes written and kills the process
+// on overflow. It does not embed bytes.Buffer, whose ReadFrom would let
+// os/exec copy output past the limit.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+	cancel   func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.buf.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.cancel()
+		p = p[:max(0, b.limit-b.buf.Len())]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,120 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+type auditLog struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (l *auditLog) record(r AuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, r)
+}
+
+func execTool(command string, args ...string) ossa.ToolConfig {
+	return ossa.ToolConfig{
+		Type: "function",
+		Name: "run",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeExec,
+			Exec:    &ossa.ExecConfig{Command: command, Args: args},
+		},
+	}
+}
+
+func writer() *ossa.Manifest {
+	m := ossa.NewManifest("ops-agent", ossa.KindAgent)
+	m.Spec.AccessTier = ossa.TierWriteLimited
+	return m
+}
+
+func TestExecute(t *testing.T) {
+	log := &auditLog{}
+	rt, err := New(writer(), execTool("echo", "hello", "{{name}}"), Options{
+		BaseDir:         t.TempDir(),
+		AllowedCommands: []string{"echo"},
+		Audit:           log.record,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{ID: "c1", Arguments: map[string]interface{}{"name": "world; rm -rf /"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != `{"exit_code":0,"stderr":"","stdout":"hello world; rm -rf /\n"}` {
+		t.Errorf("Unexpected output %s", out)
+	}
+
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{ID: "c2", Arguments: map[string]interface{}{"name": "--help"}}); err == nil {
+		t.Error("Expected option injection to be rejected")
+	}
+	if len(log.records) != 2 || log.records[0].CallID != "c1" || log.records[1].Err == nil {
+		t.Errorf("Expected both calls to be audited, got %+v", log.records)
+	}
+}
+
+func TestRestrictions(t *testing.T) {
+	audit := func(AuditRecord) {}
+	opts := Options{BaseDir: t.TempDir(), AllowedCommands: []string{"echo"}, Audit: audit}
+
+	reader := ossa.NewManifest("reader", ossa.KindAgent)
+	reader.Spec.AccessTier = ossa.TierRead
+	if _, err := New(reader, execTool("echo"), opts); err == nil {
+		t.Error("Expected exec to be blocked for read tier")
+	}
+	if _, err := New(writer(), execTool("sleep", "1"), opts); err == nil || !strings.Contains(err.Error(), "allowlist") {
+		t.Errorf("Expected unlisted command to be rejected, got %v", err)
+	}
+	if _, err := New(writer(), execTool("echo"), Options{AllowedCommands: []string{"echo"}}); err == nil {
+		t.Error("Expected missing audit log to be rejected")
+	}
+
+	tool := execTool("echo")
+	tool.Handler.Exec.WorkingDir = "../.."
+	if _, err := New(writer(), tool, opts); err == nil {
+		t.Error("Expected working_dir outside the base directory to be rejected")
+	}
+	if err := os.Symlink(t.TempDir(), filepath.Join(opts.BaseDir, "out")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+	tool.Handler.Exec.WorkingDir = "out"
+	if _, err := New(writer(), tool, opts); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("Expected working_dir linking outside the base directory to be rejected, got %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	opts := Options{BaseDir: t.TempDir(), AllowedCommands: []string{"sleep", "yes"}, Audit: func(AuditRecord) {}}
+
+	tool := execTool("sleep", "5")
+	tool.Handler.Timeout = ossa.Duration(100 * time.Millisecond)
+	rt, err := New(writer(), tool, opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	start := time.Now()
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{}); err == nil || time.Since(start) > 3*time.Second {
+		t.Errorf("Expected timeout, got %v after %s", err, time.Since(start))
+	}
+
+	tool = execTool("yes")
+	tool.Handler.Exec.MaxOutputBytes = 1024
+	rt, _ = New(writer(), tool, opts)
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{}); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected output limit error, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package fs runs OSSA tools declared with handler.runtime: fs, giving an
+// agent read, write and list access to the directories mounted in
+// handler.sandbox.mounts.
+//
+// A call names an operation and a guest path:
+//
+//	{"op": "read",  "path": "/docs/readme.md"}
+//	{"op": "write", "path": "/out/report.md", "content": "..."}
+//	{"op": "list",  "path": "/docs"}
+//
+// Paths are resolved against the mount with the longest matching guest
+// prefix and may not leave it, including through symlinks. Writes need a
+// writable mount, which the sandbox policy only grants to write tiers.
+package fs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultMaxReadBytes bounds the size of a file returned by read.
+const DefaultMaxReadBytes = 1 << 20
+
+// Operations accepted in a call's "op" argument.
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+	OpList  = "list"
+)
+
+// Options configures the fs runtime.
+type Options struct {
+	// BaseDir resolves relative mount host paths, usually the manifest's
+	// directory.
+	BaseDir string
+	// MaxReadBytes bounds read results; zero means DefaultMaxReadBytes.
+	MaxReadBytes int64
+}
+
+// Runtime executes one fs tool. It is safe for concurrent use.
+type Runtime struct {
+	tool   string
+	policy *ossa.SandboxPolicy
+	opts   Options
+}
+
+// Entry is one item in a list result.
+type Entry struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir"`
+	Size int64  `json:"size"`
+}
+
+// New prepares the tool's mounts under the manifest's sandbox policy.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeFS {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not an fs tool", tool.Name))
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.Mounts) == 0 {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: fs runtime requires at least one mount", tool.Name))
+	}
+	if opts.MaxReadBytes <= 0 {
+		opts.MaxReadBytes = DefaultMaxReadBytes
+	}
+	return &Runtime{tool: tool.Name, policy: policy, opts: opts}, nil
+}
+
+// Execute performs one operation.
+func (r *Runtime) Execute(_ context.Context, call ossa.ToolCall) ([]byte, error) {
+	op, _ := call.Arguments["op"].(string)
+	guest, _ := call.Arguments["path"].(string)
+	if guest == "" {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: missing path", r.tool))
+	}
+
+	var out interface{}
+	var err error
+	switch op {
+	case OpRead:
+		out, err = r.read(guest)
+	case OpList:
+		out, err = r.list(guest)
+	case OpWrite:
+		content, ok := call.Arguments["content"].(string)
+		if !ok {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: write requires content", r.tool))
+		}
+		out, err = r.write(guest, content)
+	default:
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: unknown operation %q", r.tool, op))
+	}
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: %s %s", r.tool, op, guest), err)
+	}
+	return json.Marshal(out)
+}
+
+func (r *Runtime) read(guest string) (interface{}, error) {
+	host, _, err := r.resolve(guest)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(host)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, r.opts.MaxReadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > r.opts.MaxReadBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", r.opts.MaxReadBytes)
+	}
+	return map[string]interface{}{"path": guest, "content": string(data)}, nil
+}
+
+func (r *Runtime) list(guest string) (interface{}, error) {
+	host, _, err := r.resolve(guest)
+	if err != nil {
+		return nil, err
+	}
+	dirents, err := os.ReadDir(host)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(dirents))
+	for _, d := range dirents {
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Name: d.Name(), Dir: d.IsDir(), Size: info.Size()})
+	}
+	return map[string]interface{}{"path": guest, "entries": entries}, nil
+}
+
+func (r *Runtime) write(guest, content string) (interface{}, error) {
+	host, mount, err := r.resolve(guest)
+	if err != nil {
+		return nil, err
+	}
+	if mount.ReadOnly || !r.policy.Tier.CanWrite() {
+		return nil, fmt.Errorf("mount %s is read-only", mount.Guest)
+	}
+	// Never follow a link planted after resolve looked: create new files
+	// exclusively and open existing ones without following symlinks.
+	flags := os.O_WRONLY | os.O_TRUNC | oNoFollow
+	if _, err := os.Lstat(host); os.IsNotExist(err) {
+		flags |= os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(host, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"path": guest, "bytes": len(content)}, nil
+}
+
+// resolve maps a guest path to a host path inside its mount.
+func (r *Runtime) resolve(guest string) (string, ossa.SandboxMount, error) {
+	guest = path.Clean("/" + guest)
+	var mount ossa.SandboxMount
+	found := false
+	for _, m := range r.policy.Mounts {
+		prefix := path.Clean(m.Guest)
+		if guest != prefix && !strings.HasPrefix(guest, strings.TrimSuffix(prefix, "/")+"/") {
+			continue
+		}
+		if !found || len(prefix) > len(path.Clean(mount.Guest)) {
+			mount, found = m, true
+		}
+	}
+	if !found {
+		return "", mount, fmt.Errorf("path is outside the mounted roots")
+	}
+
+	root := mount.Host
+	if r.opts.BaseDir != "" && !filepath.IsAbs(root) {
+		root = filepath.Join(r.opts.BaseDir, root)
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", mount, err
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(guest, path.Clean(mount.Guest)), "/")
+	host := filepath.Join(root, filepath.FromSlash(rel))
+
+	// Follow symlinks in the deepest existing ancestor so a link inside
+	// the mount cannot point outside it. A dangling link is refused, not
+	// skipped: writing through it would create its target.
+	real, rest := host, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(real)
+		if err == nil {
+			real = filepath.Join(resolved, rest)
+			break
+		}
+		if !os.IsNotExist(err) || real == root {
+			return "", mount, err
+		}
+		if info, err := os.Lstat(real); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", mount, fmt.Errorf("path %s is a dangling symlink", guest)
+		}
+		rest = filepath.Join(filepath.Base(real), rest)
+		real = filepath.Dir(real)
+	}
+	if real != root && !strings.HasPrefix(real, root+string(filepath.Separator)) {
+		return "", mount, fmt.Errorf("path escapes mount %s", mount.Guest)
+	}
+	return real, mount, nil
+}
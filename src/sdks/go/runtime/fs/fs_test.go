@@ -0,0 +1,109 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func fsTool(mounts ...ossa.SandboxMount) ossa.ToolConfig {
+	return ossa.ToolConfig{
+		Type: "function",
+		Name: "files",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeFS,
+			Sandbox: &ossa.SandboxConfig{Mounts: mounts},
+		},
+	}
+}
+
+func call(args map[string]interface{}) ossa.ToolCall {
+	return ossa.ToolCall{Tool: "files", Arguments: args}
+}
+
+func TestReadWriteList(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "readme.md"), []byte("hello"), 0o644)
+
+	manifest := ossa.NewManifest("writer", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierWriteLimited
+	rt, err := New(manifest, fsTool(ossa.SandboxMount{Host: dir, Guest: "/work"}), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+
+	out, err := rt.Execute(ctx, call(map[string]interface{}{"op": "read", "path": "/work/readme.md"}))
+	if err != nil || string(out) != `{"content":"hello","path":"/work/readme.md"}` {
+		t.Errorf("Unexpected read result %s, %v", out, err)
+	}
+
+	if _, err := rt.Execute(ctx, call(map[string]interface{}{"op": "write", "path": "/work/out.txt", "content": "done"})); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "done" {
+		t.Errorf("Expected written file, got %q", data)
+	}
+
+	out, err = rt.Execute(ctx, call(map[string]interface{}{"op": "list", "path": "/work"}))
+	if err != nil || !strings.Contains(string(out), `"name":"out.txt"`) || !strings.Contains(string(out), `"name":"readme.md"`) {
+		t.Errorf("Unexpected list result %s, %v", out, err)
+	}
+}
+
+func TestPathConfinement(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0o644)
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	manifest := ossa.NewManifest("writer", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierWriteLimited
+	rt, _ := New(manifest, fsTool(ossa.SandboxMount{Host: dir, Guest: "/work"}), Options{})
+
+	for _, p := range []string{"/work/../etc/passwd", "/etc/passwd", "/work/link/secret"} {
+		if _, err := rt.Execute(context.Background(), call(map[string]interface{}{"op": "read", "path": p})); err == nil {
+			t.Errorf("Expected %s to be rejected", p)
+		}
+	}
+	if _, err := rt.Execute(context.Background(), call(map[string]interface{}{"op": "write", "path": "/work/link/new", "content": "x"})); err == nil {
+		t.Error("Expected write through symlink to be rejected")
+	}
+
+	// A dangling link must not let a write create its target outside.
+	target := filepath.Join(outside, "planted")
+	os.Symlink(target, filepath.Join(dir, "dangling"))
+	os.Symlink(filepath.Join(outside, "newdir"), filepath.Join(dir, "danglingdir"))
+	for _, p := range []string{"/work/dangling", "/work/danglingdir/file"} {
+		if _, err := rt.Execute(context.Background(), call(map[string]interface{}{"op": "write", "path": p, "content": "x"})); err == nil {
+			t.Errorf("Expected write through dangling symlink %s to be rejected", p)
+		}
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing written outside the mount, got %v", err)
+	}
+}
+
+func TestReadTierIsReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	manifest := ossa.NewManifest("reader", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierRead
+
+	if _, err := New(manifest, fsTool(ossa.SandboxMount{Host: dir, Guest: "/work"}), Options{}); err == nil {
+		t.Fatal("Expected writable mount to be rejected for read tier")
+	}
+	rt, err := New(manifest, fsTool(ossa.SandboxMount{Host: dir, Guest: "/work", ReadOnly: true}), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	_, err = rt.Execute(context.Background(), call(map[string]interface{}{"op": "write", "path": "/work/x", "content": "x"}))
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Expected read-only error, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package fs
+
+// oNoFollow is not available; resolve's Lstat check stands alone.
+const oNoFollow = 0
@@ -0,0 +1,8 @@
+//go:build unix
+
+package fs
+
+import "syscall"
+
+// oNoFollow makes opening a symlink fail.
+const oNoFollow = syscall.O_NOFOLLOW
@@ -0,0 +1,237 @@
+// Package http runs OSSA tools declared with handler.runtime: http, such as
+// those ossa import openapi and ossa import gitlab generate, as requests to
+// handler.endpoint with handler.method.
+//
+// Each argument goes where its parameter's x-ossa-in marker says:
+//
+//	path     fills the {name} placeholder in the endpoint
+//	query    is added to the query string
+//	header   is sent as the request header of its name
+//	body     is a field of the JSON request body
+//
+// An unmarked "body" argument is the whole JSON body. Other unmarked
+// arguments go in the query string of GET, HEAD and DELETE requests and in
+// the JSON body of the rest.
+//
+// ${NAME} placeholders in handler.headers are filled from the environment,
+// so tokens stay out of manifests; an unset variable is an error. Agents
+// whose tier cannot write only make GET, HEAD and OPTIONS requests.
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdhttp "net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultMaxResponseBytes bounds the response body read per call.
+const DefaultMaxResponseBytes = 1 << 20
+
+var (
+	pathPlaceholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	envPlaceholder  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Options configures the http runtime.
+type Options struct {
+	// Client performs requests; nil means a client with a 30s timeout
+	// dialing with the handler's tls settings.
+	Client *stdhttp.Client
+	// BaseDir resolves relative handler.tls paths and is where the
+	// workspace egress config (ossa.EgressFile) is looked for.
+	BaseDir string
+	// LookupEnv fills header placeholders; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// MaxResponseBytes bounds response bodies.
+	MaxResponseBytes int64
+}
+
+// Runtime executes one http tool. It is safe for concurrent use.
+type Runtime struct {
+	tool     string
+	endpoint string
+	method   string
+	headers  map[string]string
+	in       map[string]string
+	client   *stdhttp.Client
+	maxBody  int64
+}
+
+// New checks the tool's method against the agent's tier and fills its
+// header placeholders.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	h := tool.Handler
+	if h == nil || h.Runtime != ossa.RuntimeHTTP {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not an http tool", tool.Name))
+	}
+	if h.Endpoint == "" {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s has no handler.endpoint", tool.Name))
+	}
+	method := strings.ToUpper(h.Method)
+	if method == "" {
+		method = stdhttp.MethodGet
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.AllowsMethod(method) {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: %s is not allowed for %s agents", tool.Name, method, policy.Tier))
+	}
+
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	headers := make(map[string]string, len(h.Headers))
+	for name, value := range h.Headers {
+		var missing string
+		headers[name] = envPlaceholder.ReplaceAllStringFunc(value, func(match string) string {
+			key := match[2 : len(match)-1]
+			v, ok := opts.LookupEnv(key)
+			if !ok || v == "" {
+				missing = key
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: environment variable %s is not set", tool.Name, missing))
+		}
+	}
+
+	in := map[string]string{}
+	properties, _ := tool.Parameters["properties"].(map[string]interface{})
+	for name, p := range properties {
+		if schema, ok := p.(map[string]interface{}); ok {
+			in[name], _ = schema["x-ossa-in"].(string)
+		}
+	}
+
+	client := opts.Client
+	if client == nil {
+		transport, err := ossa.ToolTransport(m, tool, opts.BaseDir)
+		if err != nil {
+			return nil, err
+		}
+		client = &stdhttp.Client{Timeout: 30 * time.Second, Transport: transport}
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	return &Runtime{
+		tool:     tool.Name,
+		endpoint: h.Endpoint,
+		method:   method,
+		headers:  headers,
+		in:       in,
+		client:   client,
+		maxBody:  opts.MaxResponseBytes,
+	}, nil
+}
+
+// Execute performs the call and returns the response body.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	req, err := r.request(ctx, call.Arguments)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: request failed", r.tool), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: %s %s returned %d: %s", r.tool, r.method, req.URL.Path, resp.StatusCode, truncate(data, 200)))
+	}
+	if len(data) == 0 {
+		return []byte("{}"), nil
+	}
+	return data, nil
+}
+
+// request builds the request of a call from its arguments.
+func (r *Runtime) request(ctx context.Context, args map[string]interface{}) (*stdhttp.Request, error) {
+	var err error
+	target := pathPlaceholder.ReplaceAllStringFunc(r.endpoint, func(match string) string {
+		name := match[1 : len(match)-1]
+		v, ok := args[name]
+		if !ok {
+			err = fmt.Errorf("missing argument %s", name)
+			return ""
+		}
+		return url.PathEscape(fmt.Sprint(v))
+	})
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	query := u.Query()
+	header := stdhttp.Header{}
+	fields := map[string]interface{}{}
+	var body interface{}
+	inQuery := r.method == stdhttp.MethodGet || r.method == stdhttp.MethodHead || r.method == stdhttp.MethodDelete
+	for name, v := range args {
+		switch in := r.in[name]; {
+		case in == "path":
+		case in == "query", in == "" && name != "body" && inQuery:
+			query.Set(name, fmt.Sprint(v))
+		case in == "header":
+			header.Set(name, fmt.Sprint(v))
+		case in == "" && name == "body":
+			body = v
+		default:
+			fields[name] = v
+		}
+	}
+	if body == nil && len(fields) > 0 {
+		body = fields
+	}
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := stdhttp.NewRequestWithContext(ctx, r.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	for name, value := range r.headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+func truncate(data []byte, n int) string {
+	s := strings.TrimSpace(string(data))
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) { v, ok := vars[k]; return v, ok }
+}
+
+func writer() *ossa.Manifest {
+	m := ossa.NewManifest("pets-agent", ossa.KindAgent)
+	m.Spec.AccessTier = ossa.TierWriteLimited
+	return m
+}
+
+func TestImportedOpenAPITools(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Authorization")+r.Header.Get("X-API-Key")+" "+string(body))
+		w.Write([]byte(`{"id": "7"}`))
+	}))
+	defer srv.Close()
+
+	data, err := os.ReadFile("../../ossa/testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools, err := ossa.ImportOpenAPI(data, ossa.OpenAPIImportOptions{Tags: []string{"pets"}, Server: srv.URL + "/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := writer()
+	m.Spec.Tools = tools
+	if result := ossa.ValidateManifest(m); !result.Valid {
+		t.Fatalf("Expected imported tools to validate, got %v", result.Errors)
+	}
+
+	vars := env(map[string]string{"PETSTORE_TOKEN": "s3cr3t", "API_KEY": "k3y"})
+	calls := map[string]map[string]interface{}{
+		"list_pets":      {"limit": 5},
+		"create_pet":     {"body": map[string]interface{}{"name": "Rex"}},
+		"show_pet_by_id": {"petId": "a/b"},
+	}
+	for _, tool := range tools {
+		rt, err := New(m, tool, Options{LookupEnv: vars})
+		if err != nil {
+			t.Fatalf("%s: New failed: %v", tool.Name, err)
+		}
+		out, err := rt.Execute(context.Background(), ossa.ToolCall{Tool: tool.Name, Arguments: calls[tool.Name]})
+		if err != nil || string(out) != `{"id": "7"}` {
+			t.Errorf("%s: unexpected result %s %v", tool.Name, out, err)
+		}
+	}
+	want := []string{
+		`GET /v1/pets?limit=5 Bearer s3cr3t `,
+		`POST /v1/pets Bearer s3cr3t {"name":"Rex"}`,
+		`GET /v1/pets/a%2Fb k3y `,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected requests:\n%s", strings.Join(got, "\n"))
+	}
+
+	if _, err := New(m, tools[0], Options{LookupEnv: env(nil)}); err == nil || !strings.Contains(err.Error(), "PETSTORE_TOKEN is not set") {
+		t.Errorf("Expected an unset header variable to fail, got %v", err)
+	}
+}
+
+func TestGitLabTools(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path != "/api/v4/projects/42/issues/3/notes" || r.Header.Get("PRIVATE-TOKEN") != "glpat" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(stdhttp.StatusCreated)
+	}))
+	defer srv.Close()
+
+	tools, err := ossa.GitLabTools(ossa.GitLabImportOptions{Scopes: []string{"issues"}, BaseURL: srv.URL + "/api/v4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comment ossa.ToolConfig
+	for _, tool := range tools {
+		if tool.Name == "gitlab_comment_on_issue" {
+			comment = tool
+		}
+	}
+	rt, err := New(writer(), comment, Options{LookupEnv: env(map[string]string{"GITLAB_TOKEN": "glpat"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{"project_id": "42", "issue_iid": 3, "body": "LGTM"}})
+	if err != nil || string(out) != "{}" {
+		t.Fatalf("Unexpected result %s %v", out, err)
+	}
+	if len(got) != 1 || got["body"] != "LGTM" {
+		t.Errorf("Expected body fields in the JSON body, got %v", got)
+	}
+
+	reader := ossa.NewManifest("reader", ossa.KindAgent)
+	reader.Spec.AccessTier = ossa.TierRead
+	if _, err := New(reader, comment, Options{LookupEnv: env(map[string]string{"GITLAB_TOKEN": "glpat"})}); err == nil {
+		t.Error("Expected a POST tool to be refused for a read agent")
+	}
+}
@@ -0,0 +1,110 @@
+// Package queue runs OSSA tools declared with handler.runtime: queue,
+// publishing the call arguments as a JSON message to the handler's subject.
+//
+// The subject is fixed by the manifest. Agents whose tier cannot write may
+// not publish, and tools under an approval guardrail wait for
+// Options.Approve before publishing. The broker is connected to on the
+// first call and the connection kept for later ones.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	mq "github.com/blueflyio/ossa-go/queue"
+)
+
+// Options configures the queue runtime.
+type Options struct {
+	// Open connects to the broker; nil means queue.Open.
+	Open func(ctx context.Context, transport, url string) (mq.Transport, error)
+	// LookupEnv resolves url_env; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// Approve is asked before publishing when the tool requires approval.
+	Approve ossa.ApprovalFunc
+}
+
+// Runtime executes one queue tool. It is safe for concurrent use.
+type Runtime struct {
+	agent    string
+	tool     string
+	cfg      ossa.QueueConfig
+	url      string
+	approval bool
+	opts     Options
+
+	mu sync.Mutex
+	tr mq.Transport
+}
+
+// New checks the tool against the manifest's tier and guardrails.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeQueue || tool.Handler.Queue == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a queue tool", tool.Name))
+	}
+	if tier := m.EffectiveAccessTier(); !tier.CanWrite() {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: queue runtime is not allowed for %s agents", tool.Name, tier))
+	}
+	approval := m.RequiresApproval(tool.Name)
+	if approval && opts.Approve == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: requires approval but no approver is configured", tool.Name))
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Open == nil {
+		opts.Open = mq.Open
+	}
+
+	cfg := *tool.Handler.Queue
+	r := &Runtime{agent: m.Metadata.Name, tool: tool.Name, cfg: cfg, url: cfg.URL, approval: approval, opts: opts}
+	if cfg.URLEnv != "" {
+		url, ok := opts.LookupEnv(cfg.URLEnv)
+		if !ok || url == "" {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: environment variable %s is not set", tool.Name, cfg.URLEnv))
+		}
+		r.url = url
+	}
+	return r, nil
+}
+
+// Execute publishes the arguments, returning {"published", "subject"}.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	data, err := json.Marshal(call.Arguments)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: arguments", r.tool), err)
+	}
+
+	if r.approval {
+		err := ossa.RequestApproval(ctx, r.opts.Approve, ossa.ApprovalRequest{
+			Agent:   r.agent,
+			Tool:    r.tool,
+			CallID:  call.ID,
+			Summary: fmt.Sprintf("Message to %s %s:\n\n%s", r.cfg.Transport, r.cfg.Subject, data),
+		})
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tr == nil {
+		tr, err := r.opts.Open(ctx, r.cfg.Transport, r.url)
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to connect", r.tool), err)
+		}
+		r.tr = tr
+	}
+	if err := r.tr.Publish(ctx, r.cfg.Subject, data); err != nil {
+		// Reconnect on the next call.
+		r.tr.Close()
+		r.tr = nil
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to publish", r.tool), err)
+	}
+	return json.Marshal(map[string]interface{}{"published": true, "subject": r.cfg.Subject})
+}
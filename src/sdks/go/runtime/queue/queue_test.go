@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	mq "github.com/blueflyio/ossa-go/queue"
+)
+
+func queueTool(cfg ossa.QueueConfig) ossa.ToolConfig {
+	return ossa.ToolConfig{
+		Type:    "function",
+		Name:    "escalate",
+		Handler: &ossa.ToolHandler{Runtime: ossa.RuntimeQueue, Queue: &cfg},
+	}
+}
+
+func writer() *ossa.Manifest {
+	m := ossa.NewManifest("triage-agent", ossa.KindAgent)
+	m.Spec.AccessTier = ossa.TierWriteLimited
+	return m
+}
+
+func TestPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := make(chan mq.Message, 1)
+	mem := mq.Mem("mem://runtime")
+	go mem.Subscribe(ctx, "tickets.escalated", "", func(_ context.Context, msg mq.Message) { got <- msg })
+
+	tool := queueTool(ossa.QueueConfig{Transport: "mem", URLEnv: "QUEUE_URL", Subject: "tickets.escalated"})
+	lookup := func(k string) (string, bool) { return "mem://runtime", k == "QUEUE_URL" }
+	rt, err := New(writer(), tool, Options{LookupEnv: lookup})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	// Publish until the subscriber is in place.
+	var msg mq.Message
+	for msg.Subject == "" {
+		out, err := rt.Execute(ctx, ossa.ToolCall{Arguments: map[string]interface{}{"ticket": 42}})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if string(out) != `{"published":true,"subject":"tickets.escalated"}` {
+			t.Errorf("Unexpected result %s", out)
+		}
+		select {
+		case msg = <-got:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if string(msg.Data) != `{"ticket":42}` {
+		t.Errorf("Unexpected message %s", msg.Data)
+	}
+
+	if _, err := New(writer(), tool, Options{LookupEnv: func(string) (string, bool) { return "", false }}); err == nil {
+		t.Error("Expected an unset url_env rejected")
+	}
+	if _, err := New(ossa.NewManifest("reader", ossa.KindAgent), tool, Options{LookupEnv: lookup}); err == nil {
+		t.Error("Expected a read-only agent refused")
+	}
+}
+
+func TestPublishWithApproval(t *testing.T) {
+	m := writer()
+	m.Spec.Autonomy = &ossa.AutonomyConfig{ApprovalRequired: true}
+	opened := 0
+	open := func(ctx context.Context, transport, url string) (mq.Transport, error) {
+		opened++
+		return mq.Open(ctx, transport, url)
+	}
+	approved := false
+	approve := func(context.Context, ossa.ApprovalRequest) (bool, error) { return approved, nil }
+	tool := queueTool(ossa.QueueConfig{Transport: "mem", URL: "mem://approval", Subject: "tickets.escalated"})
+	if _, err := New(m, tool, Options{Open: open}); err == nil {
+		t.Error("Expected a tool needing approval refused without an approver")
+	}
+
+	rt, err := New(m, tool, Options{Open: open, Approve: approve})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{}); !errors.Is(err, ossa.ErrApprovalDenied) || opened != 0 {
+		t.Errorf("Expected denied call not to publish, got %v after %d connections", err, opened)
+	}
+	approved = true
+	for i := 0; i < 2; i++ {
+		if _, err := rt.Execute(context.Background(), ossa.ToolCall{}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	if opened != 1 {
+		t.Errorf("Expected one connection kept, got %d", opened)
+	}
+}
@@ -0,0 +1,220 @@
+// Package script runs OSSA tools declared with handler.runtime: script as
+// JavaScript executed in an embedded goja interpreter.
+//
+// A script defines main(input) and returns the tool result, which is encoded
+// as JSON. The interpreter has no access to the host; the injected API is
+// limited to:
+//
+//	fetch(url, {method, headers, body})  synchronous HTTP, allowlisted hosts only
+//	JSON.parse / JSON.stringify          standard JSON utilities
+//	log(...args)                         appended to the call's log
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/dop251/goja"
+)
+
+// DefaultMaxResponseBytes bounds the body fetch reads from a response.
+const DefaultMaxResponseBytes = 1 << 20
+
+// DefaultTimeout bounds a call when the handler sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// maxRedirects is how many redirects fetch follows, as net/http does.
+const maxRedirects = 10
+
+// Options configures the script runtime.
+type Options struct {
+	// BaseDir resolves a relative handler.module path and is where the
+	// workspace egress config (ossa.EgressFile) is looked for.
+	BaseDir string
+	// Client performs fetch requests; nil means a client dialing with the
+	// handler's tls settings. Its redirects are followed only to hosts in
+	// allowed_hosts.
+	Client *http.Client
+	// MaxResponseBytes bounds fetch response bodies.
+	MaxResponseBytes int64
+	// Log receives log() output; nil discards it.
+	Log func(tool, msg string)
+}
+
+// Runtime executes one script tool. It is safe for concurrent use; each call
+// runs in a fresh interpreter.
+type Runtime struct {
+	tool    string
+	program *goja.Program
+	policy  *ossa.SandboxPolicy
+	opts    Options
+	timeout time.Duration
+}
+
+// New compiles the tool's script under the manifest's sandbox policy.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeScript {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a script tool", tool.Name))
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+
+	src, name := tool.Handler.Script, tool.Name+".js"
+	if tool.Handler.Module != "" {
+		path := tool.Handler.Module
+		if opts.BaseDir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(opts.BaseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read script: %w", err)
+		}
+		src, name = string(data), path
+	}
+
+	program, err := goja.Compile(name, src, true)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to compile script", tool.Name), err)
+	}
+
+	if opts.Client == nil {
+		transport, err := ossa.ToolTransport(m, tool, opts.BaseDir)
+		if err != nil {
+			return nil, err
+		}
+		opts.Client = &http.Client{Transport: transport}
+	}
+	client := *opts.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !policy.AllowsHost(req.URL.Hostname()) {
+			return fmt.Errorf("redirect to host %s, which is not in allowed_hosts", req.URL.Hostname())
+		}
+		if opts.Client.CheckRedirect != nil {
+			return opts.Client.CheckRedirect(req, via)
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+	opts.Client = &client
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	timeout := tool.Handler.Timeout.Std()
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runtime{tool: tool.Name, program: program, policy: policy, opts: opts, timeout: timeout}, nil
+}
+
+// Execute runs main(input) for one call. The script is interrupted when
+// ctx is done or after the handler's timeout, or DefaultTimeout.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+
+	stop := context.AfterFunc(ctx, func() { vm.Interrupt(ctx.Err()) })
+	defer stop()
+
+	vm.Set("fetch", func(target string, init map[string]interface{}) map[string]interface{} {
+		resp, err := r.fetch(ctx, target, init)
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return resp
+	})
+	vm.Set("log", func(args ...interface{}) {
+		if r.opts.Log != nil {
+			r.opts.Log(r.tool, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+		}
+	})
+
+	if _, err := vm.RunProgram(r.program); err != nil {
+		return nil, r.wrap(err)
+	}
+	main, ok := goja.AssertFunction(vm.Get("main"))
+	if !ok {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: script does not define main(input)", r.tool))
+	}
+
+	input := call.Arguments
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	value, err := main(goja.Undefined(), vm.ToValue(input))
+	if err != nil {
+		return nil, r.wrap(err)
+	}
+	return json.Marshal(value.Export())
+}
+
+func (r *Runtime) wrap(err error) error {
+	return ossa.WrapError(fmt.Sprintf("tool %s: script failed", r.tool), err)
+}
+
+func (r *Runtime) fetch(ctx context.Context, target string, init map[string]interface{}) (map[string]interface{}, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("fetch: invalid URL %q", target)
+	}
+	if !r.policy.AllowsHost(u.Hostname()) {
+		return nil, fmt.Errorf("fetch: host %s is not in allowed_hosts", u.Hostname())
+	}
+
+	method, _ := init["method"].(string)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !r.policy.AllowsMethod(method) {
+		return nil, fmt.Errorf("fetch: %s is not allowed for %s agents", method, r.policy.Tier)
+	}
+
+	var body io.Reader
+	if b, ok := init["body"].(string); ok {
+		body = strings.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if headers, ok := init["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			req.Header.Set(k, fmt.Sprint(v))
+		}
+	}
+
+	resp, err := r.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.opts.MaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+
+	headers := make(map[string]interface{}, len(resp.Header))
+	for k := range resp.Header {
+		headers[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	return map[string]interface{}{
+		"status":  resp.StatusCode,
+		"ok":      resp.StatusCode >= 200 && resp.StatusCode < 300,
+		"headers": headers,
+		"body":    string(data),
+	}, nil
+}
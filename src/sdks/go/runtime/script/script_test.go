@@ -0,0 +1,125 @@
+package script
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func scriptTool(src string, hosts ...string) ossa.ToolConfig {
+	return ossa.ToolConfig{
+		Type: "function",
+		Name: "glue",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeScript,
+			Script:  src,
+			Sandbox: &ossa.SandboxConfig{AllowedHosts: hosts},
+		},
+	}
+}
+
+func TestExecuteWithFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"temp": 12}`))
+	}))
+	defer srv.Close()
+
+	manifest := ossa.NewManifest("script-agent", ossa.KindAgent)
+	tool := scriptTool(`
+function main(input) {
+  const resp = fetch(input.url, {});
+  const data = JSON.parse(resp.body);
+  return { city: input.city, temp: data.temp, ok: resp.ok };
+}`, "127.0.0.1")
+
+	rt, err := New(manifest, tool, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{
+		"url":  srv.URL + "/weather",
+		"city": "Berlin",
+	}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != `{"city":"Berlin","ok":true,"temp":12}` {
+		t.Errorf("Unexpected output %s", out)
+	}
+}
+
+func TestFetchRestrictions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	manifest := ossa.NewManifest("reader", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierRead
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"host", `function main(i) { return fetch("https://evil.example.com/", {}); }`, "not in allowed_hosts"},
+		{"method", `function main(i) { return fetch(i.url, {method: "POST"}); }`, "POST is not allowed"},
+	}
+	for _, tt := range tests {
+		rt, err := New(manifest, scriptTool(tt.src, "127.0.0.1"), Options{})
+		if err != nil {
+			t.Fatalf("%s: New failed: %v", tt.name, err)
+		}
+		_, err = rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{"url": srv.URL}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q error, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	manifest := ossa.NewManifest("looper", ossa.KindAgent)
+	rt, err := New(manifest, scriptTool(`function main(i) { while (true) {} }`), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := rt.Execute(ctx, ossa.ToolCall{}); err == nil {
+		t.Error("Expected runaway script to be interrupted")
+	}
+
+	// Without a deadline of its own, the call stops at the handler's.
+	tool := scriptTool(`function main(i) { while (true) {} }`)
+	tool.Handler.Timeout = ossa.Duration(100 * time.Millisecond)
+	if rt, err = New(manifest, tool, Options{}); err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{}); err == nil {
+		t.Error("Expected runaway script to be interrupted at the handler timeout")
+	}
+}
+
+func TestFetchRedirect(t *testing.T) {
+	outside := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the redirect to a host outside allowed_hosts not to be followed")
+	}))
+	defer outside.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(outside.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	manifest := ossa.NewManifest("script-agent", ossa.KindAgent)
+	rt, err := New(manifest, scriptTool(`function main(i) { return fetch(i.url, {}); }`, "127.0.0.1"), Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	_, err = rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{"url": srv.URL}})
+	if err == nil || !strings.Contains(err.Error(), "redirect to host localhost") {
+		t.Errorf("Expected the redirect refused, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Package slack runs OSSA tools declared with handler.runtime: slack,
+// posting a message rendered from the handler's text template.
+//
+// The channel is fixed by the manifest; call arguments only fill the
+// template. Agents whose tier cannot write may not post, and tools under an
+// approval guardrail wait for Options.Approve before posting.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultAPIURL is the Slack Web API root.
+const DefaultAPIURL = "https://slack.com/api"
+
+// Options configures the slack runtime.
+type Options struct {
+	// Client performs requests; nil means a client with a 30s timeout
+	// dialing with the handler's tls settings.
+	Client *http.Client
+	// BaseDir resolves relative handler.tls paths and is where the
+	// workspace egress config (ossa.EgressFile) is looked for.
+	BaseDir string
+	// LookupEnv resolves token_env and webhook_env; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// Approve is asked before posting when the tool requires approval.
+	Approve ossa.ApprovalFunc
+	// APIURL overrides DefaultAPIURL.
+	APIURL string
+}
+
+// Runtime executes one slack tool. It is safe for concurrent use.
+type Runtime struct {
+	agent    string
+	tool     string
+	cfg      ossa.SlackConfig
+	token    string
+	webhook  string
+	approval bool
+	opts     Options
+}
+
+// New checks the tool against the manifest's tier and guardrails.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeSlack || tool.Handler.Slack == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a slack tool", tool.Name))
+	}
+	if tier := m.EffectiveAccessTier(); !tier.CanWrite() {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: slack runtime is not allowed for %s agents", tool.Name, tier))
+	}
+	approval := m.RequiresApproval(tool.Name)
+	if approval && opts.Approve == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: requires approval but no approver is configured", tool.Name))
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Client == nil {
+		transport, err := ossa.ToolTransport(m, tool, opts.BaseDir)
+		if err != nil {
+			return nil, err
+		}
+		opts.Client = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	}
+	if opts.APIURL == "" {
+		opts.APIURL = DefaultAPIURL
+	}
+
+	cfg := *tool.Handler.Slack
+	r := &Runtime{agent: m.Metadata.Name, tool: tool.Name, cfg: cfg, approval: approval, opts: opts}
+	name := cfg.TokenEnv
+	if name != "" {
+		r.token, _ = opts.LookupEnv(name)
+	} else {
+		name = cfg.WebhookEnv
+		r.webhook, _ = opts.LookupEnv(name)
+	}
+	if r.token == "" && r.webhook == "" {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: environment variable %s is not set", tool.Name, name))
+	}
+	return r, nil
+}
+
+// Execute renders and posts the message, returning {"sent", "channel"}.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	text, err := ossa.RenderTemplate(r.cfg.Text, call.Arguments)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: text", r.tool), err)
+	}
+
+	if r.approval {
+		err := ossa.RequestApproval(ctx, r.opts.Approve, ossa.ApprovalRequest{
+			Agent:   r.agent,
+			Tool:    r.tool,
+			CallID:  call.ID,
+			Summary: fmt.Sprintf("Slack message to %s:\n\n%s", r.channelName(), text),
+		})
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+		}
+	}
+
+	payload := map[string]interface{}{"text": text}
+	target := r.webhook
+	if r.token != "" {
+		payload["channel"] = r.cfg.Channel
+		target = r.opts.APIURL + "/chat.postMessage"
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.opts.Client.Do(req)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to post message", r.tool), err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: slack returned %d: %s", r.tool, resp.StatusCode, bytes.TrimSpace(data)))
+	}
+	// The Web API reports failures in the body with HTTP 200.
+	if r.token != "" {
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil || !result.OK {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: slack error: %s", r.tool, result.Error))
+		}
+	}
+	return json.Marshal(map[string]interface{}{"sent": true, "channel": r.channelName()})
+}
+
+func (r *Runtime) channelName() string {
+	if r.cfg.Channel != "" {
+		return r.cfg.Channel
+	}
+	return "webhook"
+}
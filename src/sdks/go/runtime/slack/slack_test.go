@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+func slackTool(cfg ossa.SlackConfig) ossa.ToolConfig {
+	cfg.Text = "Deploy of {{.service}} finished: {{.status}}"
+	return ossa.ToolConfig{
+		Type:    "function",
+		Name:    "announce",
+		Handler: &ossa.ToolHandler{Runtime: ossa.RuntimeSlack, Slack: &cfg},
+	}
+}
+
+func writer() *ossa.Manifest {
+	m := ossa.NewManifest("deploy-agent", ossa.KindAgent)
+	m.Spec.AccessTier = ossa.TierWriteLimited
+	return m
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) { v, ok := vars[k]; return v, ok }
+}
+
+var args = map[string]interface{}{"service": "api", "status": "ok"}
+
+func TestPostMessage(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			w.Write([]byte(`{"ok":false,"error":"not_authed"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	tool := slackTool(ossa.SlackConfig{Channel: "#deploys", TokenEnv: "SLACK_TOKEN"})
+	rt, err := New(writer(), tool, Options{APIURL: srv.URL, LookupEnv: env(map[string]string{"SLACK_TOKEN": "xoxb-1"})})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: args})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != `{"channel":"#deploys","sent":true}` || got["channel"] != "#deploys" || got["text"] != "Deploy of api finished: ok" {
+		t.Errorf("Unexpected result %s, posted %v", out, got)
+	}
+
+	rt, _ = New(writer(), tool, Options{APIURL: srv.URL, LookupEnv: env(map[string]string{"SLACK_TOKEN": "bad"})})
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: args}); err == nil {
+		t.Error("Expected Web API error to fail the call")
+	}
+}
+
+func TestWebhookWithApproval(t *testing.T) {
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	m := writer()
+	m.Spec.Autonomy = &ossa.AutonomyConfig{ApprovalRequired: true}
+	approved := false
+	approve := func(context.Context, ossa.ApprovalRequest) (bool, error) { return approved, nil }
+	tool := slackTool(ossa.SlackConfig{WebhookEnv: "SLACK_WEBHOOK"})
+
+	rt, err := New(m, tool, Options{Approve: approve, LookupEnv: env(map[string]string{"SLACK_WEBHOOK": srv.URL})})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: args}); !errors.Is(err, ossa.ErrApprovalDenied) || posts != 0 {
+		t.Errorf("Expected denied call not to post, got %v after %d posts", err, posts)
+	}
+	approved = true
+	if _, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: args}); err != nil || posts != 1 {
+		t.Errorf("Expected approved call to post, got %v after %d posts", err, posts)
+	}
+}
@@ -0,0 +1,145 @@
+// Package smtp runs OSSA tools declared with handler.runtime: smtp, sending
+// an email rendered from the handler's subject and body templates.
+//
+// Recipients and sender are fixed by the manifest; call arguments only fill
+// the templates. Agents whose tier cannot write may not send, and tools
+// under an approval guardrail wait for Options.Approve before sending.
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// SendFunc delivers a message; it has the signature of smtp.SendMail.
+type SendFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// Options configures the smtp runtime.
+type Options struct {
+	// LookupEnv resolves credential variables; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// Approve is asked before sending when the tool requires approval.
+	Approve ossa.ApprovalFunc
+	// Send delivers messages; nil means smtp.SendMail.
+	Send SendFunc
+}
+
+// Runtime executes one smtp tool. It is safe for concurrent use.
+type Runtime struct {
+	agent    string
+	tool     string
+	cfg      ossa.SMTPConfig
+	auth     smtp.Auth
+	approval bool
+	opts     Options
+}
+
+// New checks the tool against the manifest's tier and guardrails.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeSMTP || tool.Handler.SMTP == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not an smtp tool", tool.Name))
+	}
+	if tier := m.EffectiveAccessTier(); !tier.CanWrite() {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: smtp runtime is not allowed for %s agents", tool.Name, tier))
+	}
+	approval := m.RequiresApproval(tool.Name)
+	if approval && opts.Approve == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: requires approval but no approver is configured", tool.Name))
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Send == nil {
+		opts.Send = smtp.SendMail
+	}
+
+	cfg := *tool.Handler.SMTP
+	var auth smtp.Auth
+	if cfg.UsernameEnv != "" {
+		user, _ := opts.LookupEnv(cfg.UsernameEnv)
+		pass, _ := opts.LookupEnv(cfg.PasswordEnv)
+		if user == "" || pass == "" {
+			return nil, ossa.NewError(fmt.Sprintf("tool %s: %s and %s must be set", tool.Name, cfg.UsernameEnv, cfg.PasswordEnv))
+		}
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s: invalid addr", tool.Name), err)
+		}
+		auth = smtp.PlainAuth("", user, pass, host)
+	}
+	return &Runtime{agent: m.Metadata.Name, tool: tool.Name, cfg: cfg, auth: auth, approval: approval, opts: opts}, nil
+}
+
+// Execute renders and sends the message, returning {"sent", "to"}.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	subject, err := ossa.RenderTemplate(r.cfg.Subject, call.Arguments)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: subject", r.tool), err)
+	}
+	body, err := ossa.RenderTemplate(r.cfg.Body, call.Arguments)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: body", r.tool), err)
+	}
+	// Rendered values must not be able to add headers.
+	subject = strings.Join(strings.Fields(subject), " ")
+	if err := ossa.RequireNetwork("smtp " + r.cfg.Addr); err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+	}
+
+	if r.approval {
+		err := ossa.RequestApproval(ctx, r.opts.Approve, ossa.ApprovalRequest{
+			Agent:   r.agent,
+			Tool:    r.tool,
+			CallID:  call.ID,
+			Summary: fmt.Sprintf("Email to %s: %s\n\n%s", strings.Join(r.cfg.To, ", "), subject, body),
+		})
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s", r.tool), err)
+		}
+	}
+
+	from, err := mail.ParseAddress(r.cfg.From)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: invalid from address", r.tool), err)
+	}
+	to := make([]string, len(r.cfg.To))
+	for i, addr := range r.cfg.To {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s: invalid recipient", r.tool), err)
+		}
+		to[i] = a.Address
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(r.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mimeHeader(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	if err := r.opts.Send(r.cfg.Addr, r.auth, from.Address, to, []byte(msg.String())); err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to send email", r.tool), err)
+	}
+	return json.Marshal(map[string]interface{}{"sent": true, "to": to})
+}
+
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+// Package sql runs OSSA tools declared with handler.runtime: sql against a
+// database/sql driver.
+//
+// The driver is whatever the host program registers by import, for example
+// _ "github.com/lib/pq" for driver: postgres. The DSN comes from the
+// environment variable named by handler.sql.dsn_env.
+//
+// A tool either fixes its statement in handler.sql.query, binding the
+// arguments listed in handler.sql.params in order, or takes the statement
+// from the call's "query" argument with positional values in "params". In
+// both cases the statement type must be allowed for the agent's tier, and
+// agents that cannot write run inside a read-only transaction.
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// DefaultMaxRows bounds the rows returned when handler.sql.max_rows is unset.
+const DefaultMaxRows = 1000
+
+// Options configures the sql runtime.
+type Options struct {
+	// Open connects to the database; nil means database/sql.Open.
+	Open func(driver, dsn string) (*stdsql.DB, error)
+	// LookupEnv resolves dsn_env; nil means os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+}
+
+// Runtime executes one sql tool. It is safe for concurrent use.
+type Runtime struct {
+	tool     string
+	cfg      ossa.SQLConfig
+	allowed  map[string]bool
+	readOnly bool
+	db       *stdsql.DB
+}
+
+// New opens the tool's database under the manifest's access tier.
+func New(m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeSQL || tool.Handler.SQL == nil {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a sql tool", tool.Name))
+	}
+	cfg := *tool.Handler.SQL
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = DefaultMaxRows
+	}
+
+	r := &Runtime{
+		tool:     tool.Name,
+		cfg:      cfg,
+		allowed:  m.AllowedStatements(tool),
+		readOnly: !m.EffectiveAccessTier().CanWrite(),
+	}
+	if cfg.Query != "" {
+		if _, err := r.check(cfg.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	if opts.Open == nil {
+		opts.Open = stdsql.Open
+	}
+	dsn, ok := opts.LookupEnv(cfg.DSNEnv)
+	if !ok || dsn == "" {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s: environment variable %s is not set", tool.Name, cfg.DSNEnv))
+	}
+	db, err := opts.Open(cfg.Driver, dsn)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to open database", tool.Name), err)
+	}
+	r.db = db
+	return r, nil
+}
+
+// Execute runs the tool's statement for one call. Queries return
+// {"columns", "rows", "truncated"}; other statements return
+// {"rows_affected"}.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	query, args, err := r.bind(call.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := r.check(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, &stdsql.TxOptions{ReadOnly: r.readOnly})
+	if err != nil {
+		return nil, r.wrap(err)
+	}
+	defer tx.Rollback()
+
+	var out interface{}
+	if kind == ossa.StatementSelect {
+		out, err = r.query(ctx, tx, query, args)
+	} else {
+		out, err = r.exec(ctx, tx, query, args)
+	}
+	if err != nil {
+		return nil, r.wrap(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, r.wrap(err)
+	}
+	return json.Marshal(out)
+}
+
+// Close closes the database.
+func (r *Runtime) Close() error {
+	return r.db.Close()
+}
+
+// check classifies a statement and rejects types the tier does not allow.
+func (r *Runtime) check(query string) (string, error) {
+	kind, err := ossa.ClassifyStatement(query)
+	if err != nil {
+		return "", ossa.WrapError(fmt.Sprintf("tool %s: rejected query", r.tool), err)
+	}
+	if !r.allowed[kind] {
+		return "", ossa.NewError(fmt.Sprintf("tool %s: %s statements are not allowed", r.tool, kind))
+	}
+	return kind, nil
+}
+
+func (r *Runtime) bind(arguments map[string]interface{}) (string, []interface{}, error) {
+	if r.cfg.Query != "" {
+		args := make([]interface{}, len(r.cfg.Params))
+		for i, name := range r.cfg.Params {
+			v, ok := arguments[name]
+			if !ok {
+				return "", nil, ossa.NewError(fmt.Sprintf("tool %s: missing parameter %s", r.tool, name))
+			}
+			args[i] = v
+		}
+		return r.cfg.Query, args, nil
+	}
+
+	query, _ := arguments["query"].(string)
+	if query == "" {
+		return "", nil, ossa.NewError(fmt.Sprintf("tool %s: missing query", r.tool))
+	}
+	var args []interface{}
+	switch p := arguments["params"].(type) {
+	case nil:
+	case []interface{}:
+		args = p
+	default:
+		return "", nil, ossa.NewError(fmt.Sprintf("tool %s: params must be an array", r.tool))
+	}
+	return query, args, nil
+}
+
+func (r *Runtime) query(ctx context.Context, tx *stdsql.Tx, query string, args []interface{}) (interface{}, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]interface{}{}
+	truncated := false
+	for rows.Next() {
+		if len(result) == r.cfg.MaxRows {
+			truncated = true
+			break
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"columns":   columns,
+		"rows":      result,
+		"truncated": truncated,
+	}, nil
+}
+
+func (r *Runtime) exec(ctx context.Context, tx *stdsql.Tx, query string, args []interface{}) (interface{}, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"rows_affected": n}, nil
+}
+
+func (r *Runtime) wrap(err error) error {
+	return ossa.WrapError(fmt.Sprintf("tool %s: query failed", r.tool), err)
+}
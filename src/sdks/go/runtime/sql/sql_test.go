@@ -0,0 +1,178 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// fakeDriver records statements and returns three rows for every query.
+type fakeDriver struct {
+	mu       sync.Mutex
+	queries  []string
+	args     [][]driver.Value
+	readOnly []bool
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	c.d.readOnly = append(c.d.readOnly, opts.ReadOnly)
+	c.d.mu.Unlock()
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) record(args []driver.Value) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+}
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(2), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ n int }
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0], dest[1] = int64(r.n), []byte("row")
+	return nil
+}
+
+var testDriver = &fakeDriver{}
+
+func init() {
+	stdsql.Register("ossafake", testDriver)
+}
+
+func sqlTool(cfg ossa.SQLConfig) ossa.ToolConfig {
+	cfg.Driver, cfg.DSNEnv = "ossafake", "ORDERS_DSN"
+	return ossa.ToolConfig{
+		Type:    "function",
+		Name:    "orders",
+		Handler: &ossa.ToolHandler{Runtime: ossa.RuntimeSQL, SQL: &cfg},
+	}
+}
+
+func env(key string) (string, bool) { return "fake://orders", key == "ORDERS_DSN" }
+
+func TestExecuteQuery(t *testing.T) {
+	manifest := ossa.NewManifest("data-agent", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierRead
+	tool := sqlTool(ossa.SQLConfig{
+		Query:   "SELECT id, name FROM orders WHERE customer = ?",
+		Params:  []string{"customer"},
+		MaxRows: 2,
+	})
+
+	rt, err := New(manifest, tool, Options{LookupEnv: env})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer rt.Close()
+
+	out, err := rt.Execute(context.Background(), ossa.ToolCall{Arguments: map[string]interface{}{"customer": "acme"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := `{"columns":["id","name"],"rows":[{"id":1,"name":"row"},{"id":2,"name":"row"}],"truncated":true}`
+	if string(out) != want {
+		t.Errorf("Unexpected output %s", out)
+	}
+
+	testDriver.mu.Lock()
+	defer testDriver.mu.Unlock()
+	if last := testDriver.args[len(testDriver.args)-1]; len(last) != 1 || last[0] != "acme" {
+		t.Errorf("Expected bound customer parameter, got %v", last)
+	}
+	if !testDriver.readOnly[len(testDriver.readOnly)-1] {
+		t.Error("Expected read-tier query to run in a read-only transaction")
+	}
+}
+
+func TestStatementsByTier(t *testing.T) {
+	tool := sqlTool(ossa.SQLConfig{Statements: []string{"select", "update"}})
+	call := ossa.ToolCall{Arguments: map[string]interface{}{
+		"query":  "UPDATE orders SET status = ? WHERE id = ?",
+		"params": []interface{}{"shipped", 7},
+	}}
+
+	reader := ossa.NewManifest("reader", ossa.KindAgent)
+	reader.Spec.AccessTier = ossa.TierRead
+	rt, err := New(reader, tool, Options{LookupEnv: env})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := rt.Execute(context.Background(), call); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected update to be rejected for read tier, got %v", err)
+	}
+
+	writer := ossa.NewManifest("writer", ossa.KindAgent)
+	writer.Spec.AccessTier = ossa.TierWriteLimited
+	rt, err = New(writer, tool, Options{LookupEnv: env})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	out, err := rt.Execute(context.Background(), call)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != `{"rows_affected":2}` {
+		t.Errorf("Unexpected output %s", out)
+	}
+
+	call.Arguments["query"] = "DELETE FROM orders"
+	if _, err := rt.Execute(context.Background(), call); err == nil {
+		t.Error("Expected undeclared delete to be rejected")
+	}
+	call.Arguments["query"] = "SELECT 1; DROP TABLE orders"
+	if _, err := rt.Execute(context.Background(), call); err == nil {
+		t.Error("Expected stacked statements to be rejected")
+	}
+}
+
+func TestNewRequiresDSN(t *testing.T) {
+	manifest := ossa.NewManifest("data-agent", ossa.KindAgent)
+	tool := sqlTool(ossa.SQLConfig{})
+	lookup := func(string) (string, bool) { return "", false }
+	if _, err := New(manifest, tool, Options{LookupEnv: lookup}); err == nil {
+		t.Error("Expected missing DSN to fail")
+	}
+}
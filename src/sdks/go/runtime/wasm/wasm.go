@@ -0,0 +1,186 @@
+// Package wasm runs OSSA tools declared with handler.runtime: wasm as WASI
+// modules in a wazero sandbox.
+//
+// A tool module is a WASI command: it receives the call arguments as JSON on
+// stdin and writes its result to stdout. It sees only the mounts and
+// environment granted by the manifest's sandbox policy, and has no network
+// access since WASI preview 1 has no sockets.
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// pagesPerMB is the number of 64KiB wasm memory pages in a megabyte.
+const pagesPerMB = 16
+
+// DefaultMaxOutputBytes bounds what a module may write to stdout.
+const DefaultMaxOutputBytes = 1 << 20
+
+// DefaultTimeout applies when the handler sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Options configures module loading.
+type Options struct {
+	// BaseDir resolves relative module paths and mounts, usually the
+	// manifest's directory.
+	BaseDir string
+	// Resolve fetches modules given as OCI references (oci://...).
+	Resolve func(ctx context.Context, ref string) ([]byte, error)
+	// MaxOutputBytes bounds stdout; zero means DefaultMaxOutputBytes.
+	MaxOutputBytes int
+}
+
+// Runtime executes one wasm tool. It is safe for concurrent use; each call
+// gets a fresh module instance.
+type Runtime struct {
+	tool     string
+	policy   *ossa.SandboxPolicy
+	opts     Options
+	timeout  time.Duration
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// New compiles the tool's module under the manifest's sandbox policy.
+func New(ctx context.Context, m *ossa.Manifest, tool ossa.ToolConfig, opts Options) (*Runtime, error) {
+	if tool.Handler == nil || tool.Handler.Runtime != ossa.RuntimeWASM {
+		return nil, ossa.NewError(fmt.Sprintf("tool %s is not a wasm tool", tool.Name))
+	}
+	policy, err := m.SandboxPolicy(tool)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := loadModule(ctx, tool.Handler.Module, opts)
+	if err != nil {
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to load module", tool.Name), err)
+	}
+
+	cfg := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if policy.MemoryLimitMB > 0 {
+		cfg = cfg.WithMemoryLimitPages(uint32(policy.MemoryLimitMB * pagesPerMB))
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, cfg)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, ossa.WrapError(fmt.Sprintf("tool %s: failed to compile module", tool.Name), err)
+	}
+
+	if opts.MaxOutputBytes <= 0 {
+		opts.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	timeout := tool.Handler.Timeout.Std()
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runtime{tool: tool.Name, policy: policy, opts: opts, timeout: timeout, runtime: rt, compiled: compiled}, nil
+}
+
+// Execute runs the module for one call. A module still running after the
+// handler's timeout, or DefaultTimeout, or when ctx is done, is stopped.
+func (r *Runtime) Execute(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	input, err := json.Marshal(call.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout limitedBuffer
+	stdout.limit = r.opts.MaxOutputBytes
+	var stderr bytes.Buffer
+
+	fsConfig := wazero.NewFSConfig()
+	for _, mount := range r.policy.Mounts {
+		host := resolvePath(r.opts.BaseDir, mount.Host)
+		if mount.ReadOnly || !r.policy.Tier.CanWrite() {
+			fsConfig = fsConfig.WithReadOnlyDirMount(host, mount.Guest)
+		} else {
+			fsConfig = fsConfig.WithDirMount(host, mount.Guest)
+		}
+	}
+
+	cfg := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(r.tool).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithFSConfig(fsConfig)
+	for k, v := range r.policy.Env {
+		cfg = cfg.WithEnv(k, v)
+	}
+
+	mod, err := r.runtime.InstantiateModule(ctx, r.compiled, cfg)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ossa.WrapError(fmt.Sprintf("tool %s: module did not finish", r.tool), ctx.Err())
+		}
+		if exit, ok := err.(*sys.ExitError); !ok || exit.ExitCode() != 0 {
+			return nil, fmt.Errorf("tool %s: %w: %s", r.tool, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if stdout.overflow {
+		return nil, fmt.Errorf("tool %s: output exceeds %d bytes", r.tool, r.opts.MaxOutputBytes)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Close releases the compiled module.
+func (r *Runtime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+func loadModule(ctx context.Context, ref string, opts Options) ([]byte, error) {
+	if strings.HasPrefix(ref, "oci://") {
+		if opts.Resolve == nil {
+			return nil, fmt.Errorf("OCI module %s requires Options.Resolve", ref)
+		}
+		return opts.Resolve(ctx, ref)
+	}
+	return os.ReadFile(resolvePath(opts.BaseDir, ref))
+}
+
+func resolvePath(base, path string) string {
+	if base == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		p = p[:max(0, b.limit-b.Len())]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
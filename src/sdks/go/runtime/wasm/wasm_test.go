@@ -0,0 +1,147 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// echoModule is a minimal WASI command that copies up to 1024 bytes of
+// stdin to stdout using fd_read and fd_write.
+var echoModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60,
+	0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, 0x60, 0x00, 0x00, 0x02, 0x44,
+	0x02, 0x16, 0x77, 0x61, 0x73, 0x69, 0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x5f, 0x70, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x31,
+	0x07, 0x66, 0x64, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x00, 0x00, 0x16, 0x77,
+	0x61, 0x73, 0x69, 0x5f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
+	0x5f, 0x70, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x31, 0x08, 0x66, 0x64,
+	0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x00, 0x00, 0x03, 0x02, 0x01, 0x01,
+	0x05, 0x03, 0x01, 0x00, 0x01, 0x07, 0x13, 0x02, 0x06, 0x6d, 0x65, 0x6d,
+	0x6f, 0x72, 0x79, 0x02, 0x00, 0x06, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x00, 0x02, 0x0a, 0x33, 0x01, 0x31, 0x00, 0x41, 0x00, 0x41, 0x10, 0x36,
+	0x02, 0x00, 0x41, 0x04, 0x41, 0x80, 0x08, 0x36, 0x02, 0x00, 0x41, 0x00,
+	0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, 0x41, 0x04, 0x41,
+	0x08, 0x28, 0x02, 0x00, 0x36, 0x02, 0x00, 0x41, 0x01, 0x41, 0x00, 0x41,
+	0x01, 0x41, 0x0c, 0x10, 0x01, 0x1a, 0x0b,
+}
+
+// loopModule is a WASI command whose _start never returns.
+var loopModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x04, 0x01, 0x60,
+	0x00, 0x00, 0x03, 0x02, 0x01, 0x00, 0x07, 0x0a, 0x01, 0x06, 0x5f, 0x73,
+	0x74, 0x61, 0x72, 0x74, 0x00, 0x00, 0x0a, 0x09, 0x01, 0x07, 0x00, 0x03,
+	0x40, 0x0c, 0x00, 0x0b, 0x0b,
+}
+
+func writeModule(t *testing.T) string {
+	t.Helper()
+	return writeModuleCode(t, echoModule)
+}
+
+func writeModuleCode(t *testing.T, code []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tool.wasm")
+	if err := os.WriteFile(path, code, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecute(t *testing.T) {
+	ctx := context.Background()
+	module := writeModule(t)
+	manifest := ossa.NewManifest("wasm-agent", ossa.KindAgent)
+	tool := ossa.ToolConfig{
+		Type: "function",
+		Name: "echo",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeWASM,
+			Module:  filepath.Base(module),
+			Sandbox: &ossa.SandboxConfig{MemoryLimitMB: 1},
+		},
+	}
+
+	rt, err := New(ctx, manifest, tool, Options{BaseDir: filepath.Dir(module)})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer rt.Close(ctx)
+
+	out, err := rt.Execute(ctx, ossa.ToolCall{ID: "1", Tool: "echo", Arguments: map[string]interface{}{"q": "hi"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != `{"q":"hi"}` {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	rt.opts.MaxOutputBytes = 4
+	if _, err := rt.Execute(ctx, ossa.ToolCall{Arguments: map[string]interface{}{"q": "hi"}}); err == nil {
+		t.Error("Expected output limit to be enforced")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+	module := writeModuleCode(t, loopModule)
+	tool := ossa.ToolConfig{
+		Type: "function",
+		Name: "spin",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeWASM,
+			Module:  module,
+			Timeout: ossa.Duration(100 * time.Millisecond),
+		},
+	}
+	rt, err := New(ctx, ossa.NewManifest("wasm-agent", ossa.KindAgent), tool, Options{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer rt.Close(ctx)
+
+	start := time.Now()
+	_, err = rt.Execute(ctx, ossa.ToolCall{ID: "1", Tool: "spin"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the looping module to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the module stopped at its timeout, ran %v", elapsed)
+	}
+}
+
+func TestSandboxTierEnforcement(t *testing.T) {
+	ctx := context.Background()
+	manifest := ossa.NewManifest("reader", ossa.KindAgent)
+	manifest.Spec.AccessTier = ossa.TierRead
+	tool := ossa.ToolConfig{
+		Type: "function",
+		Name: "writer",
+		Handler: &ossa.ToolHandler{
+			Runtime: ossa.RuntimeWASM,
+			Module:  writeModule(t),
+			Sandbox: &ossa.SandboxConfig{Mounts: []ossa.SandboxMount{{Host: t.TempDir(), Guest: "/data"}}},
+		},
+	}
+
+	if _, err := New(ctx, manifest, tool, Options{}); err == nil {
+		t.Error("Expected writable mount to be rejected for a read-tier agent")
+	}
+
+	manifest.Spec.AccessTier = ossa.TierWriteLimited
+	rt, err := New(ctx, manifest, tool, Options{})
+	if err != nil {
+		t.Fatalf("Expected write tier to allow writable mount: %v", err)
+	}
+	rt.Close(ctx)
+
+	tool.Handler.Module = "oci://registry.example.com/tools/echo:1.0"
+	if _, err := New(ctx, manifest, tool, Options{}); err == nil {
+		t.Error("Expected OCI module without resolver to fail")
+	}
+}
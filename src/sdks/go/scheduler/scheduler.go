@@ -0,0 +1,447 @@
+// Package scheduler runs Tasks and Workflows on their spec.triggers: cron
+// schedules, webhook requests and changes to watched files. Agents, Tasks
+// and Workflows also run on messages from queue triggers. Runs are
+// recorded in a runs.Store, like those of ossa run.
+//
+// A job is not started again by its cron or file triggers while a run of
+// it from them is still going; webhook requests always start a run. Each
+// queue trigger handles its messages one at a time.
+package scheduler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/queue"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+// DefaultPoll is how often file triggers look at their files.
+const DefaultPoll = 2 * time.Second
+
+// maxWebhookBody bounds a webhook request's JSON input.
+const maxWebhookBody = 1 << 20
+
+// reconnectDelay is how long a queue trigger waits before reconnecting to
+// its broker.
+const reconnectDelay = 5 * time.Second
+
+// Job is an Agent, Task or Workflow the scheduler runs.
+type Job struct {
+	// Source is the manifest's path. File trigger paths are relative to
+	// its directory.
+	Source   string
+	Manifest *ossa.Manifest
+	// Workflow holds the steps of a kind: Workflow job.
+	Workflow *engine.Workflow
+}
+
+// Name is the job's metadata.name.
+func (j *Job) Name() string { return j.Manifest.Metadata.Name }
+
+// LoadJob reads and validates the Agent, Task or Workflow at path.
+func LoadJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ossa.ParseManifest(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.Kind != ossa.KindAgent && m.Kind != ossa.KindTask && m.Kind != ossa.KindWorkflow {
+		return nil, fmt.Errorf("%s: only Agents, Tasks and Workflows are scheduled, not %s", path, m.Kind)
+	}
+	if res := ossa.ValidateManifest(m); !res.Valid {
+		return nil, fmt.Errorf("%s: %s", path, strings.Join(res.Errors, "; "))
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	j := &Job{Source: path, Manifest: m}
+	if m.Kind == ossa.KindWorkflow {
+		if j.Workflow, err = engine.ParseWorkflow(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return j, nil
+}
+
+// Scheduler runs jobs on their triggers. Add jobs, then call Run, and serve
+// Handler for webhook triggers.
+type Scheduler struct {
+	// Engine returns the engine to run a job on, such as one loading step
+	// refs relative to the job's Source.
+	Engine func(j *Job) *engine.Engine
+	// Store records the runs; nil records nothing.
+	Store runs.Store
+	// Token, if set, is the bearer token webhook requests must carry.
+	Token string
+	// Poll is how often file triggers check their files; 0 means
+	// DefaultPoll.
+	Poll time.Duration
+	// Open connects queue triggers to their brokers; nil means queue.Open.
+	Open func(ctx context.Context, transport, url string) (queue.Transport, error)
+
+	mu   sync.Mutex
+	jobs []*Job
+	// hooks maps webhook paths to their jobs and triggers.
+	hooks map[string]hook
+	busy  map[*Job]bool
+	ctx   context.Context
+	wg    sync.WaitGroup
+}
+
+type hook struct {
+	job     *Job
+	trigger ossa.Trigger
+}
+
+// Add schedules j. Webhook paths must be unique across jobs.
+func (s *Scheduler) Add(j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hooks == nil {
+		s.hooks = map[string]hook{}
+	}
+	for _, t := range j.Manifest.Spec.Triggers {
+		switch t.Type {
+		case ossa.TriggerCron:
+			if _, err := ossa.ParseCron(t.Schedule); err != nil {
+				return fmt.Errorf("%s: %w", j.Name(), err)
+			}
+		case ossa.TriggerWebhook:
+			if other, ok := s.hooks[t.Path]; ok {
+				return fmt.Errorf("%s: webhook path %s is taken by %s", j.Name(), t.Path, other.job.Name())
+			}
+		}
+	}
+	for _, t := range j.Manifest.Spec.Triggers {
+		if t.Type == ossa.TriggerWebhook {
+			s.hooks[t.Path] = hook{job: j, trigger: t}
+		}
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Jobs returns the scheduled jobs.
+func (s *Scheduler) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Job(nil), s.jobs...)
+}
+
+// Run starts the cron, file and queue triggers of the jobs added so far and
+// blocks until ctx is done and the runs it started have ended.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	jobs := append([]*Job(nil), s.jobs...)
+	s.mu.Unlock()
+	for _, j := range jobs {
+		for _, t := range j.Manifest.Spec.Triggers {
+			switch t.Type {
+			case ossa.TriggerCron:
+				c, _ := ossa.ParseCron(t.Schedule)
+				s.wg.Add(1)
+				go s.cron(ctx, j, t, c)
+			case ossa.TriggerFile:
+				s.wg.Add(1)
+				go s.watch(ctx, j, t)
+			case ossa.TriggerQueue:
+				s.wg.Add(1)
+				go s.consume(ctx, j, t)
+			}
+		}
+	}
+	<-ctx.Done()
+	s.wg.Wait()
+	return nil
+}
+
+// Fire runs j once for trigger t, with input over t's input, and waits for
+// the run.
+func (s *Scheduler) Fire(ctx context.Context, j *Job, t ossa.Trigger, input map[string]interface{}) (*runs.Run, error) {
+	in := map[string]interface{}{}
+	for k, v := range t.Input {
+		in[k] = v
+	}
+	for k, v := range input {
+		in[k] = v
+	}
+	rec := &runs.Recorder{Engine: s.Engine(j), Store: s.Store}
+	var run *runs.Run
+	var err error
+	switch {
+	case j.Workflow != nil:
+		run, err = rec.RunWorkflow(ctx, j.Workflow, j.Source, in)
+	case j.Manifest.Kind == ossa.KindAgent:
+		run, err = rec.RunAgent(ctx, j.Manifest, j.Source, in)
+	default:
+		run, err = rec.RunTask(ctx, j.Manifest, j.Source, in)
+	}
+	log := ossa.Logger().With("job", j.Name(), "trigger", t.Type)
+	if run != nil {
+		log = log.With("run", run.ID, "status", run.Status)
+	}
+	if err != nil {
+		log.Warn("triggered run failed", "error", err)
+	} else {
+		log.Info("triggered run finished")
+	}
+	return run, err
+}
+
+// start runs j in the background, unless background is set and a
+// background run of j is still going. It reports whether it started.
+func (s *Scheduler) start(j *Job, t ossa.Trigger, input map[string]interface{}, background bool) bool {
+	s.mu.Lock()
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Err() != nil {
+		s.mu.Unlock()
+		return false
+	}
+	if background {
+		if s.busy[j] {
+			s.mu.Unlock()
+			ossa.Logger().Info("skipped trigger: job still running", "job", j.Name(), "trigger", t.Type)
+			return false
+		}
+		if s.busy == nil {
+			s.busy = map[*Job]bool{}
+		}
+		s.busy[j] = true
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.Fire(ctx, j, t, input)
+		if background {
+			s.mu.Lock()
+			delete(s.busy, j)
+			s.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+func (s *Scheduler) cron(ctx context.Context, j *Job, t ossa.Trigger, c *ossa.Cron) {
+	defer s.wg.Done()
+	for {
+		next := c.Next(time.Now())
+		if next.IsZero() {
+			ossa.Logger().Warn("cron schedule never fires", "job", j.Name(), "schedule", t.Schedule)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.start(j, t, map[string]interface{}{"scheduled": next.Format(time.RFC3339)}, true)
+		}
+	}
+}
+
+// watch polls the files of a file trigger and runs j with the changes as
+// {"files": [{"path": ..., "event": "created|modified|removed"}]}.
+// Changes seen while j is running are reported once it is done.
+func (s *Scheduler) watch(ctx context.Context, j *Job, t ossa.Trigger) {
+	defer s.wg.Done()
+	poll := s.Poll
+	if poll <= 0 {
+		poll = DefaultPoll
+	}
+	dir := filepath.Dir(j.Source)
+	seen := scan(dir, t.Paths)
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := scan(dir, t.Paths)
+		changes := diff(seen, now)
+		if len(changes) == 0 || s.start(j, t, map[string]interface{}{"files": changes}, true) {
+			seen = now
+		}
+	}
+}
+
+// consume runs j for each message on a queue trigger's subject, with the
+// JSON object in the message as input, or {"message": text} for anything
+// else. The run's {"run", "status", "output", "error"} is published to the
+// trigger's reply subject, or else to the message's. A lost connection is
+// retried every reconnectDelay.
+func (s *Scheduler) consume(ctx context.Context, j *Job, t ossa.Trigger) {
+	defer s.wg.Done()
+	q := t.Queue
+	url := q.URL
+	if q.URLEnv != "" {
+		url = os.Getenv(q.URLEnv)
+	}
+	open := s.Open
+	if open == nil {
+		open = queue.Open
+	}
+	log := ossa.Logger().With("job", j.Name(), "subject", q.Subject)
+	for {
+		tr, err := open(ctx, q.Transport, url)
+		if err == nil {
+			err = tr.Subscribe(ctx, q.Subject, q.Group, func(ctx context.Context, msg queue.Message) {
+				s.handle(ctx, tr, j, t, msg)
+			})
+			tr.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("queue trigger disconnected", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (s *Scheduler) handle(ctx context.Context, tr queue.Transport, j *Job, t ossa.Trigger, msg queue.Message) {
+	input := map[string]interface{}{}
+	if err := json.Unmarshal(msg.Data, &input); err != nil {
+		input = map[string]interface{}{"message": string(msg.Data)}
+	}
+	run, err := s.Fire(ctx, j, t, input)
+	reply := t.Queue.Reply
+	if reply == "" {
+		reply = msg.Reply
+	}
+	if reply == "" {
+		return
+	}
+	out := map[string]interface{}{"status": runs.StatusFailed}
+	if run != nil {
+		out["run"], out["status"], out["output"] = run.ID, run.Status, run.Output
+	}
+	if err != nil {
+		out["error"] = err.Error()
+	}
+	data, _ := json.Marshal(out)
+	if err := tr.Publish(ctx, reply, data); err != nil {
+		ossa.Logger().Warn("failed to publish run result", "job", j.Name(), "subject", reply, "error", err)
+	}
+}
+
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+func scan(dir string, patterns []string) map[string]fileState {
+	out := map[string]fileState{}
+	for _, p := range patterns {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		matches, _ := filepath.Glob(p)
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				out[path] = fileState{mod: info.ModTime(), size: info.Size()}
+			}
+		}
+	}
+	return out
+}
+
+func diff(before, after map[string]fileState) []interface{} {
+	var paths []string
+	events := map[string]string{}
+	for path, st := range after {
+		if old, ok := before[path]; !ok {
+			events[path] = "created"
+		} else if old != st {
+			events[path] = "modified"
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			events[path] = "removed"
+		}
+	}
+	for path := range events {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	out := make([]interface{}, len(paths))
+	for i, path := range paths {
+		out[i] = map[string]interface{}{"path": path, "event": events[path]}
+	}
+	return out
+}
+
+// Handler serves the webhook triggers: a POST to a trigger's path starts a
+// run of its job, with the JSON object in the body as input, and answers
+// 202 Accepted without waiting for it.
+func (s *Scheduler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		h, ok := s.hooks[r.URL.Path]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxWebhookBody {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		input := map[string]interface{}{}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := json.Unmarshal(body, &input); err != nil {
+				http.Error(w, "body is not a JSON object: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		s.start(h.job, h.trigger, input, false)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"job": h.job.Name(), "status": "accepted"})
+	})
+}
@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/queue"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+const workflow = `apiVersion: ossa/v0.3.3
+kind: Workflow
+metadata:
+  name: ingest
+spec:
+  triggers:
+    - type: webhook
+      path: /hooks/ingest
+      input: {source: hook}
+    - type: file
+      paths: ["inbox/*.csv"]
+  steps:
+    - id: load
+      ref: ./load.ossa.yaml
+      input:
+        source: ${{ workflow.input.source }}
+        files: ${{ workflow.input.files }}
+`
+
+const task = `apiVersion: ossa/v0.3.3
+kind: Task
+metadata:
+  name: load
+spec: {}
+`
+
+// waitRun waits for a finished run of the store matching ok.
+func waitRun(t *testing.T, store runs.Store, ok func(*runs.Run) bool) *runs.Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		list, _ := store.List(context.Background(), runs.Filter{})
+		for _, r := range list {
+			if r.Status != runs.StatusRunning && ok(r) {
+				return r
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for a run")
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ingest.ossa.yaml"), []byte(workflow), 0o644)
+	os.WriteFile(filepath.Join(dir, "load.ossa.yaml"), []byte(task), 0o644)
+	os.Mkdir(filepath.Join(dir, "inbox"), 0o755)
+
+	job, err := LoadJob(filepath.Join(dir, "ingest.ossa.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJob(filepath.Join(dir, "load.ossa.yaml")); err != nil {
+		t.Errorf("Expected a Task job, got %v", err)
+	}
+
+	store := &runs.DirStore{Dir: t.TempDir()}
+	e := &engine.Engine{
+		Load: func(_ context.Context, ref string) (*ossa.Manifest, error) {
+			return ossa.LoadManifest(filepath.Join(dir, ref))
+		},
+		Task: func(_ context.Context, _ *ossa.Manifest, input map[string]interface{}) (map[string]interface{}, error) {
+			return input, nil
+		},
+	}
+	s := &Scheduler{Engine: func(*Job) *engine.Engine { return e }, Store: store, Token: "s3cr3t", Poll: 10 * time.Millisecond}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(job); err == nil || !strings.Contains(err.Error(), "taken") {
+		t.Errorf("Expected a duplicate webhook path rejected, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	post := func(path, token, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post("/hooks/ingest", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", code)
+	}
+	if code := post("/hooks/nope", "s3cr3t", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", code)
+	}
+	if code := post("/hooks/ingest", "s3cr3t", "[1]"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", code)
+	}
+	if code := post("/hooks/ingest", "s3cr3t", `{"batch": 7}`); code != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", code)
+	}
+	hooked := waitRun(t, store, func(r *runs.Run) bool { return r.Input["batch"] != nil })
+	if hooked.Status != runs.StatusSucceeded || hooked.Input["source"] != "hook" || hooked.Output["source"] != "hook" {
+		t.Errorf("Unexpected webhook run %+v", hooked)
+	}
+
+	file := filepath.Join(dir, "inbox", "a.csv")
+	os.WriteFile(file, []byte("a,b\n"), 0o644)
+	watched := waitRun(t, store, func(r *runs.Run) bool { return r.Input["files"] != nil })
+	files, _ := watched.Output["files"].([]interface{})
+	if len(files) != 1 || files[0].(map[string]interface{})["path"] != file || files[0].(map[string]interface{})["event"] != "created" {
+		t.Errorf("Unexpected file run output %v", watched.Output)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+const agent = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: triage
+spec:
+  role: Triage tickets.
+  triggers:
+    - type: queue
+      queue: {transport: mem, url: mem://scheduler, subject: tickets.new, reply: tickets.triaged}
+`
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+func TestQueueTrigger(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "triage.ossa.yaml"), []byte(agent), 0o644)
+	job, err := LoadJob(filepath.Join(dir, "triage.ossa.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &engine.Engine{Model: modelFunc(func(req *engine.Request) (*engine.Response, error) {
+		return &engine.Response{Content: `{"priority": "high"}`}, nil
+	})}
+	store := &runs.DirStore{Dir: t.TempDir()}
+	s := &Scheduler{Engine: func(*Job) *engine.Engine { return e }, Store: store}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replies := make(chan queue.Message, 1)
+	mem := queue.Mem("mem://scheduler")
+	go mem.Subscribe(ctx, "tickets.triaged", "", func(_ context.Context, msg queue.Message) { replies <- msg })
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	// Publish until the trigger has subscribed.
+	var reply queue.Message
+	for reply.Subject == "" {
+		mem.Publish(ctx, "tickets.new", []byte("printer on fire"))
+		select {
+		case reply = <-replies:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(reply.Data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["status"] != runs.StatusSucceeded || out["output"].(map[string]interface{})["priority"] != "high" {
+		t.Errorf("Unexpected reply %v", out)
+	}
+	run, err := store.Get(ctx, out["run"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Kind != ossa.KindAgent || run.Input["message"] != "printer on fire" {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,326 @@
+// Package semver parses semantic versions and the constraints versioned
+// agent refs use, such as support-agent@^1.2.
+//
+// A constraint is one or more ranges separated by ||, any of which may
+// hold; a range is terms separated by spaces or commas, all of which must
+// hold:
+//
+//	1.2.3        exactly 1.2.3
+//	1.2, 1.2.x   any 1.2 patch; 1 and 1.x any 1 minor; * or x anything
+//	^1.2.3       compatible: >=1.2.3 <2.0.0 (^0.2.3 is <0.3.0, ^0.0.3 <0.0.4)
+//	~1.2.3       patches: >=1.2.3 <1.3.0 (~1 is <2.0.0)
+//	>=1.2 <2     comparisons with = != < <= > >=, versions possibly partial
+//
+// As with npm, a prerelease such as 1.3.0-rc.1 is only matched by a range
+// with a comparator naming a prerelease of the same 1.3.0.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Build metadata is dropped when parsing.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+// Parse parses MAJOR.MINOR.PATCH with an optional -prerelease and
+// +build, and an optional leading v.
+func Parse(s string) (Version, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if parts != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: want MAJOR.MINOR.PATCH", s)
+	}
+	return v, nil
+}
+
+// parsePartial parses a version of which only the major, or major and
+// minor, may be given, and returns how many numbers were. x, X and * end
+// the version early.
+func parsePartial(s string) (Version, int, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	text, _, _ = strings.Cut(text, "+")
+	core, pre, hasPre := strings.Cut(text, "-")
+	var v Version
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	fields := strings.Split(core, ".")
+	if len(fields) > 3 || core == "" {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	parts := 0
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			if hasPre {
+				return Version{}, 0, fmt.Errorf("invalid version %q: a prerelease needs a full version", s)
+			}
+			for _, rest := range fields[i+1:] {
+				if rest != "x" && rest != "X" && rest != "*" {
+					return Version{}, 0, fmt.Errorf("invalid version %q", s)
+				}
+			}
+			break
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || (len(f) > 1 && f[0] == '0') {
+			return Version{}, 0, fmt.Errorf("invalid version %q: %q is not a number", s, f)
+		}
+		*nums[i] = n
+		parts++
+	}
+	if hasPre {
+		if parts != 3 {
+			return Version{}, 0, fmt.Errorf("invalid version %q: a prerelease needs a full version", s)
+		}
+		for _, id := range strings.Split(pre, ".") {
+			if id == "" || strings.Trim(id, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-") != "" {
+				return Version{}, 0, fmt.Errorf("invalid version %q: bad prerelease %q", s, pre)
+			}
+		}
+		v.Prerelease = pre
+	}
+	return v, parts, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than
+// o, in semver precedence: a prerelease is lower than its release.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+// compareIdentifier compares prerelease identifiers: numbers numerically
+// and below words, words in ASCII order.
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return sign(na - nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// Sort sorts versions in ascending precedence.
+func Sort(versions []Version) {
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+}
+
+// comparator is one bound of a range.
+type comparator struct {
+	op string // = != < <= > >=
+	v  Version
+}
+
+func (c comparator) holds(v Version) bool {
+	d := v.Compare(c.v)
+	switch c.op {
+	case "!=":
+		return d != 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	}
+	return d == 0
+}
+
+// Constraint is a parsed version constraint.
+type Constraint struct {
+	text   string
+	ranges [][]comparator
+}
+
+// ParseConstraint parses a constraint in the syntax described in the
+// package documentation. An empty constraint matches every release.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{text: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(s, "||") {
+		terms := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		// Let ">= 1.2" mean ">=1.2".
+		for i := 0; i < len(terms)-1; i++ {
+			if strings.Trim(terms[i], "=<>!^~") == "" {
+				terms[i] += terms[i+1]
+				terms = append(terms[:i+1], terms[i+2:]...)
+			}
+		}
+		r := []comparator{}
+		for _, term := range terms {
+			cs, err := parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			r = append(r, cs...)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// parseTerm returns the comparators a term stands for.
+func parseTerm(term string) ([]comparator, error) {
+	op := term[:len(term)-len(strings.TrimLeft(term, "=<>!^~"))]
+	text := term[len(op):]
+	if text == "" {
+		return nil, fmt.Errorf("%q needs a version", term)
+	}
+	v, parts, err := parsePartial(text)
+	if err != nil {
+		return nil, err
+	}
+	if parts == 0 {
+		if op == "" || op == "=" || op == ">=" || op == "<=" {
+			return nil, nil // *, x
+		}
+		return nil, fmt.Errorf("%q matches nothing", term)
+	}
+	// next is the lowest version above every version the partial covers.
+	var next Version
+	switch parts {
+	case 1:
+		next = Version{Major: v.Major + 1}
+	case 2:
+		next = Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		next = Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+	lower := comparator{">=", v}
+	switch op {
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && (parts == 1 || v.Minor == 0 && parts == 2):
+			upper = next
+		case v.Major == 0 && v.Minor == 0:
+			upper = Version{Patch: v.Patch + 1}
+		case v.Major == 0:
+			upper = Version{Minor: v.Minor + 1}
+		}
+		return []comparator{lower, {"<", upper}}, nil
+	case "~":
+		if parts == 1 {
+			return []comparator{lower, {"<", next}}, nil
+		}
+		return []comparator{lower, {"<", Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	case "", "=":
+		if parts == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{lower, {"<", next}}, nil
+	case "!=":
+		if parts != 3 {
+			return nil, fmt.Errorf("%q needs a full version", term)
+		}
+		return []comparator{{"!=", v}}, nil
+	case ">=", "<":
+		return []comparator{{op, v}}, nil
+	case ">":
+		if parts == 3 {
+			return []comparator{{">", v}}, nil
+		}
+		return []comparator{{">=", next}}, nil
+	case "<=":
+		if parts == 3 {
+			return []comparator{{"<=", v}}, nil
+		}
+		return []comparator{{"<", next}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q in %q", op, term)
+}
+
+// String returns the constraint as written.
+func (c *Constraint) String() string { return c.text }
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	for _, r := range c.ranges {
+		if rangeHolds(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func rangeHolds(r []comparator, v Version) bool {
+	for _, c := range r {
+		if !c.holds(v) {
+			return false
+		}
+	}
+	if v.Prerelease == "" {
+		return true
+	}
+	for _, c := range r {
+		if c.v.Prerelease != "" && c.v.Major == v.Major && c.v.Minor == v.Minor && c.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the highest of versions satisfying the constraint, skipping
+// any that do not parse, and false if none does.
+func (c *Constraint) Best(versions []string) (string, bool) {
+	var best Version
+	found := ""
+	for _, s := range versions {
+		v, err := Parse(s)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if found == "" || v.Compare(best) > 0 {
+			best, found = v, s
+		}
+	}
+	return found, found != ""
+}
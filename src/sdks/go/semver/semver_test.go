@@ -0,0 +1,70 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+build.5")
+	if err != nil || v != (Version{1, 2, 3, "rc.1"}) || v.String() != "1.2.3-rc.1" {
+		t.Errorf("Unexpected parse %+v %v", v, err)
+	}
+	for _, bad := range []string{"", "1.2", "1.2.3.4", "01.2.3", "1.a.3", "1.2.3-", "1.2.3-rc..1"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Expected %q rejected", bad)
+		}
+	}
+
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0", "2.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := Parse(ordered[i-1])
+		b, _ := Parse(ordered[i])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("Expected %s < %s", a, b)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	for constraint, cases := range map[string]map[string]bool{
+		"^1.2":            {"1.2.0": true, "1.9.9": true, "1.1.9": false, "2.0.0": false, "1.3.0-rc.1": false},
+		"^0.2.3":          {"0.2.3": true, "0.2.9": true, "0.3.0": false},
+		"^0.0.3":          {"0.0.3": true, "0.0.4": false},
+		"~1.2.3":          {"1.2.3": true, "1.2.9": true, "1.3.0": false, "1.2.2": false},
+		"~1":              {"1.9.0": true, "2.0.0": false},
+		"1.2.x":           {"1.2.7": true, "1.3.0": false},
+		"1.2.3":           {"1.2.3": true, "1.2.4": false},
+		"*":               {"0.0.1": true, "9.9.9": true, "1.0.0-beta": false},
+		"":                {"3.1.4": true},
+		">=1.2 <2":        {"1.2.0": true, "1.99.0": true, "2.0.0": false, "1.1.0": false},
+		">= 1.2, < 2":     {"1.5.0": true},
+		">1.2":            {"1.2.9": false, "1.3.0": true},
+		"<=1.2":           {"1.2.9": true, "1.3.0": false},
+		"^1 || ^3":        {"1.4.0": true, "2.0.0": false, "3.0.1": true},
+		"!=1.2.3 ^1":      {"1.2.3": false, "1.2.4": true},
+		">=1.3.0-rc.1 <2": {"1.3.0-rc.2": true, "1.4.0-rc.1": false, "1.3.0": true},
+	} {
+		c, err := ParseConstraint(constraint)
+		if err != nil {
+			t.Errorf("%q: %v", constraint, err)
+			continue
+		}
+		for version, want := range cases {
+			v, _ := Parse(version)
+			if got := c.Check(v); got != want {
+				t.Errorf("%q on %s: expected %v, got %v", constraint, version, want, got)
+			}
+		}
+	}
+	for _, bad := range []string{"^", ">=x.1", "!=1.2", ">*", "^1.2.3.4"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("Expected %q rejected", bad)
+		}
+	}
+
+	c, _ := ParseConstraint("^1.2")
+	if best, ok := c.Best([]string{"1.1.0", "1.2.0", "1.10.2", "1.4.0", "2.0.0", "latest"}); !ok || best != "1.10.2" {
+		t.Errorf("Expected 1.10.2, got %q", best)
+	}
+	if _, ok := c.Best([]string{"0.9.0", "2.0.0"}); ok {
+		t.Error("Expected no match")
+	}
+}
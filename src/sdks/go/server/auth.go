@@ -0,0 +1,255 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is a permission granted to a caller.
+type Role string
+
+const (
+	// RoleRead lists and fetches agents and validates manifests.
+	RoleRead Role = "read"
+	// RolePublish also publishes manifests into the caller's namespaces.
+	RolePublish Role = "publish"
+	// RoleRun runs agents and workflows in the caller's namespaces and
+	// follows their events, when the server allows running.
+	RoleRun Role = "run"
+	// RoleAdmin may do anything in every namespace.
+	RoleAdmin Role = "admin"
+)
+
+// tokenCookie carries the token for UI pages, which browsers load without
+// an Authorization header. It is only honoured on GET requests.
+const tokenCookie = "ossa_token"
+
+// Auth configures authentication beyond tenant tokens. Credentials are
+// sent as "Authorization: Bearer <token>" or, for API keys, "X-API-Key".
+type Auth struct {
+	APIKeys []APIKey `yaml:"api_keys"`
+	// JWT, if set, accepts bearer JWTs; see JWTConfig.
+	JWT *JWTConfig `yaml:"jwt"`
+}
+
+// APIKey is a static credential for a service or user.
+type APIKey struct {
+	Key string `yaml:"key"`
+	// Subject names the key's owner in audit logs.
+	Subject string `yaml:"subject"`
+	Roles   []Role `yaml:"roles"`
+	// Namespaces limits the key; empty grants every namespace.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// LoadAuth reads an auth file:
+//
+//	api_keys:
+//	  - key: 9f2c...
+//	    subject: ci
+//	    roles: [read, publish]
+//	    namespaces: [payments]
+//	jwt:
+//	  issuer: https://accounts.example.com
+//	  audience: ossa
+//	  role_map: {ossa-admins: admin}
+func LoadAuth(path string) (*Auth, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth config: %w", err)
+	}
+	var a Auth
+	if err := yaml.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse auth config %s: %w", path, err)
+	}
+	for i, k := range a.APIKeys {
+		if k.Key == "" || k.Subject == "" {
+			return nil, fmt.Errorf("%s: api_keys[%d]: key and subject are required", path, i)
+		}
+		for _, r := range k.Roles {
+			if !validRole(r) {
+				return nil, fmt.Errorf("%s: api_keys[%d]: unknown role %q", path, i, r)
+			}
+		}
+	}
+	if j := a.JWT; j != nil {
+		if j.Issuer == "" && j.JWKSURL == "" && j.Secret == "" {
+			return nil, fmt.Errorf("%s: jwt: issuer, jwks_url or secret is required", path)
+		}
+		for value, r := range j.RoleMap {
+			if !validRole(r) {
+				return nil, fmt.Errorf("%s: jwt.role_map[%s]: unknown role %q", path, value, r)
+			}
+		}
+	}
+	return &a, nil
+}
+
+func validRole(r Role) bool {
+	return r == RoleRead || r == RolePublish || r == RoleRun || r == RoleAdmin
+}
+
+// caller is who made a request and what it may do: anonymous readers of
+// every namespace when no credentials are configured, otherwise what its
+// credential grants.
+type caller struct {
+	subject    string
+	all        bool
+	namespaces map[string]bool
+	roles      map[Role]bool
+}
+
+func (c *caller) allows(namespace string) bool {
+	return c.all || c.roles[RoleAdmin] || c.namespaces[namespace]
+}
+
+func (c *caller) can(role Role) bool {
+	return c.roles[RoleAdmin] || c.roles[role]
+}
+
+// authRequired reports whether requests must carry credentials.
+func (s *Server) authRequired() bool {
+	a := s.opts.Auth
+	return len(s.opts.Tenants) > 0 || (a != nil && (len(a.APIKeys) > 0 || a.JWT != nil))
+}
+
+// authed wraps a handler needing role, authenticating the request when
+// credentials are configured.
+func (s *Server) authed(role Role, h func(http.ResponseWriter, *http.Request, *caller)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authRequired() {
+			// Without credentials, anyone may read, and run if running
+			// is enabled.
+			c := &caller{subject: "anonymous", all: true, roles: map[Role]bool{RoleRead: true, RoleRun: s.opts.Engine != nil}}
+			if !c.can(role) {
+				writeError(w, http.StatusForbidden, "this server has no credentials configured")
+				return
+			}
+			h(w, r, c)
+			return
+		}
+		c, reason := s.authenticate(r)
+		if c == nil {
+			s.auditLog().Warn("audit", "action", "authenticate", "error", reason, "path", r.URL.Path, "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ossa"`)
+			writeError(w, http.StatusUnauthorized, reason)
+			return
+		}
+		if !c.can(role) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s role", c.subject, role))
+			return
+		}
+		h(w, r, c)
+	}
+}
+
+// authenticate identifies the caller from an API key, a tenant token or a
+// JWT, in that order. It returns nil and a reason on failure.
+func (s *Server) authenticate(r *http.Request) (*caller, string) {
+	token := r.Header.Get("X-API-Key")
+	if token == "" {
+		token = bearerToken(r)
+	}
+	if token == "" {
+		return nil, "missing token"
+	}
+
+	if a := s.opts.Auth; a != nil {
+		for _, k := range a.APIKeys {
+			if equalToken(token, k.Key) {
+				c := &caller{subject: k.Subject, all: len(k.Namespaces) == 0, namespaces: map[string]bool{}, roles: map[Role]bool{}}
+				for _, ns := range k.Namespaces {
+					c.namespaces[ns] = true
+				}
+				for _, role := range k.Roles {
+					c.roles[role] = true
+				}
+				return c, ""
+			}
+		}
+	}
+
+	c := &caller{namespaces: map[string]bool{}, roles: map[Role]bool{}}
+	for _, t := range s.opts.Tenants {
+		for _, want := range t.Tokens {
+			if equalToken(token, want) {
+				c.namespaces[t.Namespace] = true
+			}
+		}
+	}
+	if len(c.namespaces) > 0 {
+		names := make([]string, 0, len(c.namespaces))
+		for ns := range c.namespaces {
+			names = append(names, ns)
+		}
+		sort.Strings(names)
+		c.subject = "tenant:" + strings.Join(names, ",")
+		c.roles[RoleRead], c.roles[RolePublish], c.roles[RoleRun] = true, true, true
+		return c, ""
+	}
+
+	if s.jwt != nil && looksLikeJWT(token) {
+		claims, err := s.jwt.verify(r.Context(), token)
+		if err != nil {
+			return nil, err.Error()
+		}
+		return s.jwtCaller(claims), ""
+	}
+	return nil, "unknown token"
+}
+
+// jwtCaller maps verified claims to a caller.
+func (s *Server) jwtCaller(claims map[string]interface{}) *caller {
+	cfg := s.jwt.cfg
+	c := &caller{namespaces: map[string]bool{}, roles: map[Role]bool{}}
+	c.subject, _ = claims["sub"].(string)
+	if c.subject == "" {
+		c.subject, _ = claims["email"].(string)
+	}
+	c.subject = "jwt:" + c.subject
+	for _, value := range claimStrings(claims[cfg.RolesClaim]) {
+		if role, ok := cfg.RoleMap[value]; ok {
+			c.roles[role] = true
+		} else if validRole(Role(value)) {
+			c.roles[Role(value)] = true
+		}
+	}
+	for _, ns := range claimStrings(claims[cfg.NamespacesClaim]) {
+		c.namespaces[ns] = true
+	}
+	return c
+}
+
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if scheme, token, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if r.Method == http.MethodGet {
+		// The UI writes the cookie with encodeURIComponent.
+		if cookie, err := r.Cookie(tokenCookie); err == nil {
+			token, _ := url.QueryUnescape(cookie.Value)
+			return token
+		}
+	}
+	return ""
+}
+
+func equalToken(got, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// audit records who did what: one info event per validation and publish.
+func (s *Server) audit(r *http.Request, c *caller, action string, attrs ...interface{}) {
+	args := append([]interface{}{"subject", c.subject, "action", action, "remote", r.RemoteAddr}, attrs...)
+	s.auditLog().Info("audit", args...)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg, kid string, claims map[string]interface{}, sign func([]byte) []byte) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	return signed + "." + enc.EncodeToString(sign([]byte(signed)))
+}
+
+func TestAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": idp.URL, "jwks_uri": idp.URL + "/jwks"})
+		case "/jwks":
+			enc := base64.RawURLEncoding
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kid": "k1", "kty": "RSA",
+				"n": enc.EncodeToString(key.N.Bytes()),
+				"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer idp.Close()
+	rs256 := func(claims map[string]interface{}) string {
+		return signJWT(t, "RS256", "k1", claims, func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			return sig
+		})
+	}
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"iss": idp.URL, "aud": []string{"ossa"}, "sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.ossa.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	authFile := filepath.Join(t.TempDir(), "auth.yaml")
+	os.WriteFile(authFile, []byte(`api_keys:
+  - {key: reader-key, subject: dashboard, roles: [read]}
+  - {key: "b64+/key==", subject: browser, roles: [read]}
+  - {key: ci-key, subject: ci, roles: [read, publish], namespaces: [payments]}
+jwt:
+  issuer: `+idp.URL+`
+  audience: ossa
+  secret: shh
+  role_map: {ossa-admins: admin}
+`), 0644)
+	auth, err := LoadAuth(authFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var audit bytes.Buffer
+	srv := httptest.NewServer(New(Options{Dir: dir, Auth: auth, AuditLogger: slog.New(slog.NewJSONHandler(&audit, nil))}))
+	defer srv.Close()
+	do := func(method, path string, header map[string]string, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	bearer := func(token string) map[string]string { return map[string]string{"Authorization": "Bearer " + token} }
+	biller := strings.Replace(testManifest, "name: reviewer", "name: biller", 1)
+
+	for _, tc := range []struct {
+		name, method, path string
+		header             map[string]string
+		body               string
+		want               int
+	}{
+		{"no credentials", "GET", "/api/agents", nil, "", http.StatusUnauthorized},
+		{"api key header", "GET", "/api/agents", map[string]string{"X-API-Key": "reader-key"}, "", http.StatusOK},
+		{"api key bearer", "GET", "/api/agents/reviewer", bearer("reader-key"), "", http.StatusOK},
+		{"escaped cookie", "GET", "/api/agents", map[string]string{"Cookie": tokenCookie + "=b64%2B%2Fkey%3D%3D"}, "", http.StatusOK},
+		{"bad cookie escape", "GET", "/api/agents", map[string]string{"Cookie": tokenCookie + "=b64%2"}, "", http.StatusUnauthorized},
+		{"reader cannot publish", "PUT", "/api/agents/payments/biller", bearer("reader-key"), biller, http.StatusForbidden},
+		{"publisher outside its namespaces", "PUT", "/api/agents/search/biller", bearer("ci-key"), biller, http.StatusForbidden},
+		{"publisher", "PUT", "/api/agents/payments/biller", bearer("ci-key"), biller, http.StatusCreated},
+		{"jwt reader", "GET", "/api/agents", bearer(rs256(claims(map[string]interface{}{"roles": "read"}))), "", http.StatusOK},
+		{"jwt without roles", "GET", "/api/agents", bearer(rs256(claims(nil))), "", http.StatusForbidden},
+		{"jwt mapped admin", "PUT", "/api/agents/search/biller", bearer(rs256(claims(map[string]interface{}{"roles": []string{"ossa-admins"}}))), biller, http.StatusCreated},
+		{"jwt expired", "GET", "/api/agents", bearer(rs256(claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix(), "roles": "read"}))), "", http.StatusUnauthorized},
+		{"jwt wrong audience", "GET", "/api/agents", bearer(rs256(claims(map[string]interface{}{"aud": "other", "roles": "read"}))), "", http.StatusUnauthorized},
+		{"jwt wrong issuer", "GET", "/api/agents", bearer(rs256(claims(map[string]interface{}{"iss": "https://evil.example.com", "roles": "read"}))), "", http.StatusUnauthorized},
+		{"jwt hs256", "GET", "/api/agents", bearer(signJWT(t, "HS256", "", claims(map[string]interface{}{"roles": "read"}), func(data []byte) []byte {
+			mac := hmac.New(sha256.New, []byte("shh"))
+			mac.Write(data)
+			return mac.Sum(nil)
+		})), "", http.StatusOK},
+		{"jwt bad signature", "GET", "/api/agents", bearer(signJWT(t, "RS256", "k1", claims(map[string]interface{}{"roles": "read"}), func([]byte) []byte { return []byte("forged") })), "", http.StatusUnauthorized},
+		{"jwt alg none", "GET", "/api/agents", bearer(signJWT(t, "none", "", claims(map[string]interface{}{"roles": "admin"}), func([]byte) []byte { return nil })), "", http.StatusUnauthorized},
+	} {
+		if got := do(tc.method, tc.path, tc.header, tc.body); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	if got := do("POST", "/api/validate", bearer("ci-key"), testManifest); got != http.StatusOK {
+		t.Fatalf("Expected validation to succeed, got %d", got)
+	}
+	srv.Close() // waits for handlers to finish logging
+	for _, want := range []string{
+		`"subject":"ci","action":"validate"`,
+		`"subject":"ci","action":"publish"`,
+		`"subject":"jwt:alice","action":"publish","remote"`,
+		`"action":"authenticate","error":"token expired"`,
+		`"status":201`,
+	} {
+		if !strings.Contains(audit.String(), want) {
+			t.Errorf("Expected %s in audit log:\n%s", want, audit.String())
+		}
+	}
+}
+
+func TestOpenServerCannotPublish(t *testing.T) {
+	srv := newTestServer(t)
+	req, _ := http.NewRequest("PUT", srv.URL+"/api/agents/default/reviewer", strings.NewReader(testManifest))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	for _, bad := range []string{
+		"api_keys:\n  - {key: k}\n",
+		"api_keys:\n  - {key: k, subject: s, roles: [owner]}\n",
+		"jwt: {audience: ossa}\n",
+		"jwt: {issuer: https://idp, role_map: {g: root}}\n",
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadAuth(path); err == nil {
+			t.Errorf("Expected an error for:\n%s", bad)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// Release channels point at releases so rollouts can be staged: publish to
+// beta, try it, then promote the release to stable. The stable channel is
+// the catalog's manifest; a plain publish of a semantic version moves it.
+const (
+	StableChannel = "stable"
+	// ChannelAnnotation records on a manifest the channel it was published
+	// to or fetched from.
+	ChannelAnnotation = "ossa.dev/channel"
+	// channelsFile maps channels to versions beside the releases.
+	channelsFile = "channels.yaml"
+)
+
+// ChannelRequest is the body of PUT .../channels/{channel}: the version to
+// point the channel at, or another channel whose release to promote.
+type ChannelRequest struct {
+	Version string `json:"version,omitempty"`
+	From    string `json:"from,omitempty"`
+}
+
+// ChannelResponse is what a channel points at.
+type ChannelResponse struct {
+	Channel string `json:"channel"`
+	Version string `json:"version"`
+}
+
+// checkChannel returns why m cannot be published to channel, or "".
+func checkChannel(channel string, m *ossa.Manifest) string {
+	if !namespacePattern.MatchString(channel) {
+		return fmt.Sprintf("invalid channel %q", channel)
+	}
+	if _, err := semver.Parse(m.Metadata.Version); err != nil {
+		return "publishing to a channel needs a semantic metadata.version: " + err.Error()
+	}
+	return ""
+}
+
+// release archives m and, if its version is semantic, points channel (by
+// default stable) at it. Releases live in Dir, so a server whose catalog
+// is only in Options.Manifests keeps none.
+func (s *Server) release(m *ossa.Manifest, channel string) error {
+	if s.opts.Dir == "" {
+		return nil
+	}
+	if err := s.archive(m); err != nil {
+		return err
+	}
+	v, err := semver.Parse(m.Metadata.Version)
+	if err != nil {
+		return nil
+	}
+	if channel == "" {
+		channel = StableChannel
+	}
+	channels, err := s.channels(namespaceOf(m), m.Metadata.Name)
+	if err != nil {
+		return err
+	}
+	channels[channel] = v.String()
+	return s.saveChannels(namespaceOf(m), m.Metadata.Name, channels)
+}
+
+func (s *Server) channelsPath(namespace, name string) string {
+	return filepath.Join(s.opts.Dir, versionsDir, namespace, name, channelsFile)
+}
+
+// channels returns namespace/name's channels and the versions they point
+// at; empty if none were published to.
+func (s *Server) channels(namespace, name string) (map[string]string, error) {
+	channels := map[string]string{}
+	if s.opts.Dir == "" {
+		return channels, nil
+	}
+	data, err := os.ReadFile(s.channelsPath(namespace, name))
+	if os.IsNotExist(err) {
+		return channels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &channels); err != nil {
+		return nil, fmt.Errorf("%s/%s: invalid %s: %w", namespace, name, channelsFile, err)
+	}
+	return channels, nil
+}
+
+func (s *Server) saveChannels(namespace, name string, channels map[string]string) error {
+	data, err := yaml.Marshal(channels)
+	if err != nil {
+		return err
+	}
+	path := s.channelsPath(namespace, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// handleChannels serves .../channels, the channels and their versions;
+// GET .../channels/{channel}, the release a channel points at; and PUT
+// .../channels/{channel}, a promotion, which needs the publish role.
+func (s *Server) handleChannels(w http.ResponseWriter, r *http.Request, c *caller, namespace, name, channel string) {
+	if r.Method == http.MethodPut {
+		s.handlePromote(w, r, c, namespace, name, channel)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET or PUT")
+		return
+	}
+	if !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	channels, err := s.channels(namespace, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if channel == "" {
+		out := []ChannelResponse{}
+		for ch, v := range channels {
+			out = append(out, ChannelResponse{Channel: ch, Version: v})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Channel < out[j].Channel })
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	version, ok := channels[channel]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s/%s has no %s channel", namespace, name, channel))
+		return
+	}
+	e, err := s.releaseEntry(namespace, name, version)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	e.Manifest = withChannel(e.Manifest, channel)
+	result := ossa.ValidateManifest(e.Manifest)
+	writeJSON(w, http.StatusOK, AgentResponse{
+		AgentSummary: summarize(e),
+		Manifest:     e.Manifest,
+		Errors:       nonNil(result.Errors),
+		Warnings:     nonNil(result.Warnings),
+	})
+}
+
+// withChannel returns a copy of m annotated with channel.
+func withChannel(m *ossa.Manifest, channel string) *ossa.Manifest {
+	c := *m
+	c.Metadata.Annotations = map[string]string{ChannelAnnotation: channel}
+	for k, v := range m.Metadata.Annotations {
+		if k != ChannelAnnotation {
+			c.Metadata.Annotations[k] = v
+		}
+	}
+	return &c
+}
+
+// releaseEntry loads an archived release.
+func (s *Server) releaseEntry(namespace, name, version string) (ossa.CatalogEntry, error) {
+	e := ossa.CatalogEntry{Path: filepath.Join(s.opts.Dir, versionsDir, namespace, name, version+".ossa.yaml")}
+	m, err := ossa.LoadManifest(e.Path)
+	if err != nil {
+		return e, fmt.Errorf("version not found: %s/%s@%s", namespace, name, version)
+	}
+	e.Manifest, e.Name, e.Kind, e.Tier = m, m.Metadata.Name, m.Kind, m.GetAccessTier()
+	return e, nil
+}
+
+// handlePromote points a channel at a release. Promoting to stable also
+// makes the release the catalog's manifest.
+func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request, c *caller, namespace, name, channel string) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = rec
+	var req ChannelRequest
+	defer func() {
+		s.audit(r, c, "promote", "namespace", namespace, "name", name, "channel", channel,
+			"version", req.Version, "from", req.From, "status", rec.status)
+	}()
+
+	if !c.can(RolePublish) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s role", c.subject, RolePublish))
+		return
+	}
+	if !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	if !namespacePattern.MatchString(channel) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid channel %q", channel))
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Version == "") == (req.From == "") {
+		writeError(w, http.StatusBadRequest, `send {"version": "1.2.0"} or {"from": "beta"}`)
+		return
+	}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	channels, err := s.channels(namespace, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	version := req.Version
+	if req.From != "" {
+		if version = channels[req.From]; version == "" {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("%s/%s has no %s channel", namespace, name, req.From))
+			return
+		}
+	}
+	v, err := semver.Parse(version)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	e, err := s.releaseEntry(namespace, name, v.String())
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if channel == StableChannel {
+		var quota Quota
+		if t := s.tenant(namespace); t != nil {
+			quota = t.Quota
+		}
+		if _, ok := s.saveCurrent(w, withChannel(e.Manifest, StableChannel), namespace, name, quota); !ok {
+			return
+		}
+	}
+	channels[channel] = v.String()
+	if err := s.saveChannels(namespace, name, channels); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ChannelResponse{Channel: channel, Version: v.String()})
+}
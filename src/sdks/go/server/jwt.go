@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWTConfig verifies bearer JWTs, such as OIDC ID or access tokens.
+// Tokens must be signed with RS256 or ES256 by a key from the issuer's
+// JWKS, or with HS256 by Secret.
+type JWTConfig struct {
+	// Issuer must match the iss claim. Unless JWKSURL is set, keys are
+	// found through the issuer's /.well-known/openid-configuration.
+	Issuer string `yaml:"issuer"`
+	// Audience, if set, must be in the aud claim.
+	Audience string `yaml:"audience"`
+	JWKSURL  string `yaml:"jwks_url"`
+	// Secret enables HS256 tokens; prefer JWKS.
+	Secret string `yaml:"secret"`
+	// RolesClaim and NamespacesClaim name the claims holding the caller's
+	// roles and namespaces, as arrays or space-separated strings. They
+	// default to "roles" and "namespaces".
+	RolesClaim      string `yaml:"roles_claim"`
+	NamespacesClaim string `yaml:"namespaces_claim"`
+	// RoleMap maps claim values, such as group names, to roles; values
+	// naming a role directly need no entry.
+	RoleMap map[string]Role `yaml:"role_map"`
+	// Client fetches keys; nil uses a client with a 10 second timeout.
+	Client *http.Client `yaml:"-"`
+}
+
+// jwksRefresh bounds how often an unknown key ID triggers a key refetch.
+const jwksRefresh = time.Minute
+
+// clockSkew is the leeway allowed on exp and nbf.
+const clockSkew = time.Minute
+
+// jwtVerifier checks tokens against a JWTConfig, caching the JWKS.
+type jwtVerifier struct {
+	cfg     JWTConfig
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	now     func() time.Time
+}
+
+func newJWTVerifier(cfg JWTConfig) *jwtVerifier {
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	if cfg.NamespacesClaim == "" {
+		cfg.NamespacesClaim = "namespaces"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &jwtVerifier{cfg: cfg, now: time.Now}
+}
+
+// looksLikeJWT reports whether a bearer token has the three-part JWT form.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// verify checks the token's signature and registered claims and returns
+// its claims.
+func (v *jwtVerifier) verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := v.checkSignature(ctx, header.Alg, header.Kid, signed, sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token has no exp")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not yet valid")
+	}
+	if iss, _ := claims["iss"].(string); v.cfg.Issuer != "" && iss != v.cfg.Issuer {
+		return nil, fmt.Errorf("token issuer %q not accepted", iss)
+	}
+	if v.cfg.Audience != "" && !containsString(claimStrings(claims["aud"]), v.cfg.Audience) {
+		return nil, errors.New("token audience not accepted")
+	}
+	return claims, nil
+}
+
+func (v *jwtVerifier) checkSignature(ctx context.Context, alg, kid string, signed, sig []byte) error {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case "HS256":
+		if v.cfg.Secret == "" {
+			return errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, []byte(v.cfg.Secret))
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case "RS256", "ES256":
+	default:
+		return fmt.Errorf("token algorithm %q not accepted", alg)
+	}
+
+	key, err := v.key(ctx, kid)
+	if err != nil {
+		return err
+	}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == "ES256" && len(sig) == 64 {
+			r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("invalid token signature")
+}
+
+// key returns the JWKS key with ID kid, refetching the JWKS when the ID is
+// unknown at most once per jwksRefresh.
+func (v *jwtVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.lookupKey(kid); ok {
+		return key, nil
+	}
+	if v.keys != nil && v.now().Sub(v.fetched) < jwksRefresh {
+		return nil, fmt.Errorf("unknown token key %q", kid)
+	}
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token keys: %w", err)
+	}
+	v.keys, v.fetched = keys, v.now()
+	if key, ok := v.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token key %q", kid)
+}
+
+// lookupKey finds kid, or the only key when the token names none.
+func (v *jwtVerifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *jwtVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	url := v.cfg.JWKSURL
+	if url == "" {
+		if v.cfg.Issuer == "" {
+			return nil, errors.New("no jwks_url or issuer configured")
+		}
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("OpenID configuration has no jwks_uri")
+		}
+		url = discovery.JWKSURI
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, url, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if key.Curve.IsOnCurve(key.X, key.Y) {
+				keys[k.Kid] = key
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (v *jwtVerifier) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+func decodeSegment(seg string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// claimStrings reads a claim holding a string array or a space-separated
+// string.
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metrics counts requests for GET /metrics, in the Prometheus text
+// exposition format.
+type metrics struct {
+	mu          sync.Mutex
+	requests    map[[2]string]uint64 // route, status code
+	validations map[bool]uint64
+	limited     uint64
+	tooLarge    uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: map[[2]string]uint64{}, validations: map[bool]uint64{}}
+}
+
+func (m *metrics) request(route string, status int) {
+	m.mu.Lock()
+	m.requests[[2]string{route, fmt.Sprint(status)}]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) validation(valid bool) {
+	m.mu.Lock()
+	m.validations[valid]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) rateLimited() {
+	m.mu.Lock()
+	m.limited++
+	m.mu.Unlock()
+}
+
+func (m *metrics) payloadTooLarge() {
+	m.mu.Lock()
+	m.tooLarge++
+	m.mu.Unlock()
+}
+
+func (m *metrics) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	counter := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	}
+
+	counter("ossa_http_requests_total", "HTTP requests by route and status code.")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&b, "ossa_http_requests_total{route=%q,code=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+	counter("ossa_http_rate_limited_total", "Requests rejected with 429 by the rate limiter.")
+	fmt.Fprintf(&b, "ossa_http_rate_limited_total %d\n", m.limited)
+	counter("ossa_http_payload_too_large_total", "Requests rejected with 413 for their body size.")
+	fmt.Fprintf(&b, "ossa_http_payload_too_large_total %d\n", m.tooLarge)
+	counter("ossa_validations_total", "Manifests validated through the API by result.")
+	fmt.Fprintf(&b, "ossa_validations_total{valid=\"true\"} %d\n", m.validations[true])
+	fmt.Fprintf(&b, "ossa_validations_total{valid=\"false\"} %d\n", m.validations[false])
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
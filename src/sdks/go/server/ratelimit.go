@@ -0,0 +1,114 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit configures per-client request limiting. Clients are told when
+// to retry with a Retry-After header on 429 responses.
+type RateLimit struct {
+	// Rate is the sustained requests per second allowed per client IP;
+	// zero disables limiting.
+	Rate float64
+	// Burst is how many requests a client may make at once; zero means
+	// the rate rounded up, at least 1.
+	Burst int
+	// TrustProxy takes the client IP from the last X-Forwarded-For entry,
+	// as added by one trusted reverse proxy in front of the server.
+	// Without a proxy this lets clients pick their own IP; leave it off.
+	TrustProxy bool
+}
+
+// limiterSweep is how often idle clients are forgotten.
+const limiterSweep = time.Minute
+
+// limiter is a token bucket per client.
+type limiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rl RateLimit) *limiter {
+	burst := float64(rl.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(rl.Rate))
+	}
+	return &limiter{rate: rl.Rate, burst: burst, buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// allow takes a token from key's bucket. When it is empty it returns false
+// and how long until a token is available.
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.swept) > limiterSweep {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep forgets clients whose buckets have refilled, as a new bucket would
+// be the same.
+func (l *limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// clientIP returns the address rate limits are keyed by.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// retryAfter formats a wait as whole seconds, rounded up.
+func retryAfter(wait time.Duration) string {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.Itoa(secs)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newLimiter(RateLimit{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("Expected request %d within the burst to pass", i)
+		}
+	}
+	ok, wait := l.allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("Expected the fourth request to wait 500ms, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("Expected another client to have its own bucket")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("Expected a token after waiting")
+	}
+
+	now = now.Add(2 * limiterSweep)
+	l.allow("c")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("Expected idle buckets to be swept")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6, 203.0.113.7")
+	if got := clientIP(r, false); got != "10.0.0.1" {
+		t.Errorf("Expected RemoteAddr without a trusted proxy, got %s", got)
+	}
+	if got := clientIP(r, true); got != "203.0.113.7" {
+		t.Errorf("Expected the address the proxy added, got %s", got)
+	}
+}
+
+func TestRateLimitAndMetrics(t *testing.T) {
+	srv := httptest.NewServer(New(Options{RateLimit: RateLimit{Rate: 0.01, Burst: 2}, MaxBodyBytes: 1024}))
+	defer srv.Close()
+	post := func(body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/validate", "application/yaml", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post(strings.Repeat("x", 2048)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large body, got %d", resp.StatusCode)
+	}
+	if resp := post(testManifest); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	resp := post(testManifest)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 over the burst, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "100" {
+		t.Errorf("Expected Retry-After 100, got %q", got)
+	}
+
+	mresp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mresp.Body.Close()
+	if mresp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected metrics to bypass the rate limit, got %d", mresp.StatusCode)
+	}
+	body, _ := io.ReadAll(mresp.Body)
+	for _, want := range []string{
+		"# TYPE ossa_http_requests_total counter",
+		`ossa_http_requests_total{route="/api/validate",code="200"} 1`,
+		`ossa_http_requests_total{route="/api/validate",code="429"} 1`,
+		`ossa_http_requests_total{route="/api/validate",code="413"} 1`,
+		"ossa_http_rate_limited_total 1",
+		"ossa_http_payload_too_large_total 1",
+		`ossa_validations_total{valid="true"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %s in metrics:\n%s", want, body)
+		}
+	}
+}
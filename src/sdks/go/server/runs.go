@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+// Server run event types, sent besides the engine.Event types.
+const (
+	// EventRunStarted is the first event of every run.
+	EventRunStarted = "run_started"
+	// EventApprovalRequired asks for a decision on a tool call needing
+	// approval; the run waits for it.
+	EventApprovalRequired = "approval_required"
+	// EventApprovalDecided follows with the decision.
+	EventApprovalDecided = "approval_decided"
+	// EventRunFinished is the last event of every run.
+	EventRunFinished = "run_finished"
+)
+
+// Run timings.
+const (
+	// runRetention is how long a finished run's events can still be read.
+	runRetention = 10 * time.Minute
+	// approvalTimeout is how long a run waits for an approval before
+	// treating it as denied.
+	approvalTimeout = 10 * time.Minute
+	// heartbeat is how often idle event streams send a keep-alive.
+	heartbeat = 15 * time.Second
+)
+
+// RunEvent is one event of a run's stream: an engine.Event as the run
+// makes progress, or one of the run_started, approval_required,
+// approval_decided and run_finished events of the server.
+type RunEvent struct {
+	// Seq numbers the run's events from 1.
+	Seq int    `json:"seq"`
+	Run string `json:"run"`
+	engine.Event
+	// Kind and Name are what a run_started event runs.
+	Kind ossa.Kind `json:"kind,omitempty"`
+	Name string    `json:"name,omitempty"`
+	// Summary describes the tool call an approval_required event is about.
+	Summary  string `json:"summary,omitempty"`
+	Approved *bool  `json:"approved,omitempty"`
+	// Status is a run_finished event's runs.Status value.
+	Status string `json:"status,omitempty"`
+}
+
+// RunResponse is the body of POST /api/agents/{namespace}/{name}/runs.
+type RunResponse struct {
+	Run string `json:"run"`
+	// Events is the path streaming the run's events.
+	Events string `json:"events"`
+}
+
+// approvalDecision is the body of POST /api/runs/{id}/approvals/{call_id}
+// and the message WebSocket clients send for one.
+type approvalDecision struct {
+	Type     string `json:"type,omitempty"`
+	CallID   string `json:"call_id,omitempty"`
+	Approved bool   `json:"approved"`
+}
+
+// liveRun is a run started through the API, with the events it has sent
+// so far for its followers.
+type liveRun struct {
+	id        string
+	namespace string
+	cancel    context.CancelFunc
+
+	mu        sync.Mutex
+	events    []RunEvent
+	changed   chan struct{}
+	done      chan struct{}
+	finished  time.Time
+	approvals map[string]chan bool
+}
+
+func newLiveRun(namespace string, cancel context.CancelFunc) *liveRun {
+	return &liveRun{namespace: namespace, cancel: cancel, changed: make(chan struct{}), done: make(chan struct{}), approvals: map[string]chan bool{}}
+}
+
+func (lr *liveRun) emit(ev RunEvent) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	ev.Seq, ev.Run = len(lr.events)+1, lr.id
+	lr.events = append(lr.events, ev)
+	close(lr.changed)
+	lr.changed = make(chan struct{})
+}
+
+// since returns the events after seq, whether the run is over and a channel
+// closed when more come.
+func (lr *liveRun) since(seq int) ([]RunEvent, bool, <-chan struct{}) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if seq < 0 || seq > len(lr.events) {
+		seq = len(lr.events)
+	}
+	return lr.events[seq:], !lr.finished.IsZero(), lr.changed
+}
+
+func (lr *liveRun) finish(run *runs.Run, err error) {
+	ev := RunEvent{Event: engine.Event{Type: EventRunFinished}, Status: runs.StatusSucceeded}
+	if run != nil {
+		ev.Status, ev.Output, ev.Usage = run.Status, run.Output, &run.Usage
+	}
+	if err != nil {
+		ev.Status, ev.Error = runs.StatusFailed, err.Error()
+	}
+	lr.emit(ev)
+	lr.mu.Lock()
+	lr.finished = time.Now()
+	lr.mu.Unlock()
+	close(lr.done)
+}
+
+// approve asks the run's followers about req and waits for their decision.
+func (lr *liveRun) approve(ctx context.Context, req ossa.ApprovalRequest) (bool, error) {
+	ch := make(chan bool, 1)
+	lr.mu.Lock()
+	id := req.CallID
+	if id == "" {
+		id = fmt.Sprintf("approval-%d", len(lr.events)+1)
+	}
+	lr.approvals[id] = ch
+	lr.mu.Unlock()
+	defer func() {
+		lr.mu.Lock()
+		delete(lr.approvals, id)
+		lr.mu.Unlock()
+	}()
+	lr.emit(RunEvent{Event: engine.Event{Type: EventApprovalRequired, Agent: req.Agent, Tool: req.Tool, CallID: id}, Summary: req.Summary})
+
+	timer := time.NewTimer(approvalTimeout)
+	defer timer.Stop()
+	var ok bool
+	select {
+	case ok = <-ch:
+	case <-timer.C:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	lr.emit(RunEvent{Event: engine.Event{Type: EventApprovalDecided, Agent: req.Agent, Tool: req.Tool, CallID: id}, Approved: &ok})
+	return ok, nil
+}
+
+// decide answers a pending approval, reporting whether there was one.
+func (lr *liveRun) decide(callID string, ok bool) bool {
+	lr.mu.Lock()
+	ch := lr.approvals[callID]
+	delete(lr.approvals, callID)
+	lr.mu.Unlock()
+	if ch == nil {
+		return false
+	}
+	ch <- ok
+	return true
+}
+
+// handleStartRun starts a run of the catalog's namespace/name with the
+// JSON object in the body as input and answers 202 with where to follow it.
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request, c *caller, namespace, name string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	if s.opts.Engine == nil {
+		writeError(w, http.StatusForbidden, "running is not enabled on this server")
+		return
+	}
+	if !c.can(RoleRun) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s role", c.subject, RoleRun))
+		return
+	}
+	if !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var entry *ossa.CatalogEntry
+	for i, e := range entries {
+		if e.Name == name && namespaceOf(e.Manifest) == namespace {
+			entry = &entries[i]
+		}
+	}
+	if entry == nil {
+		writeError(w, http.StatusNotFound, "agent not found: "+namespace+"/"+name)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "input too large")
+		return
+	}
+	input := map[string]interface{}{}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &input); err != nil {
+			writeError(w, http.StatusBadRequest, "body is not a JSON object: "+err.Error())
+			return
+		}
+	}
+
+	var start func(ctx context.Context, rec *runs.Recorder) (*runs.Run, error)
+	m := entry.Manifest
+	switch m.Kind {
+	case ossa.KindAgent:
+		start = func(ctx context.Context, rec *runs.Recorder) (*runs.Run, error) {
+			return rec.RunAgent(ctx, m, entry.Path, input)
+		}
+	case ossa.KindTask:
+		start = func(ctx context.Context, rec *runs.Recorder) (*runs.Run, error) {
+			return rec.RunTask(ctx, m, entry.Path, input)
+		}
+	case ossa.KindWorkflow:
+		source, err := s.store.Get(r.Context(), entry.Path)
+		if err == nil {
+			var wf *engine.Workflow
+			if wf, err = engine.ParseWorkflow(source); err == nil {
+				start = func(ctx context.Context, rec *runs.Recorder) (*runs.Run, error) {
+					return rec.RunWorkflow(ctx, wf, entry.Path, input)
+				}
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("a %s cannot be run", m.Kind))
+		return
+	}
+
+	// Callers do not see each other's cached tool results.
+	ctx, cancel := context.WithCancel(engine.WithCacheScope(context.Background(), namespace+"/"+c.subject))
+	lr := newLiveRun(namespace, cancel)
+	eng := *s.opts.Engine(*entry, lr.approve)
+	if eng.AuditLogger == nil {
+		eng.AuditLogger = s.auditLog()
+	}
+	if locale := ossa.ParseAcceptLanguage(r.Header.Get("Accept-Language")); locale != "" {
+		eng.Locale = locale
+	}
+	events := eng.Events
+	eng.Events = func(ev engine.Event) {
+		lr.emit(RunEvent{Event: ev})
+		if events != nil {
+			events(ev)
+		}
+	}
+	started := make(chan struct{})
+	rec := &runs.Recorder{Engine: &eng, Store: s.opts.Store, OnStart: func(run *runs.Run) {
+		lr.mu.Lock()
+		lr.id = run.ID
+		lr.mu.Unlock()
+		s.runsMu.Lock()
+		s.prune()
+		s.live[run.ID] = lr
+		s.runsMu.Unlock()
+		lr.emit(RunEvent{Event: engine.Event{Type: EventRunStarted}, Kind: m.Kind, Name: m.Metadata.Name})
+		close(started)
+	}}
+	go func() {
+		defer cancel()
+		run, err := start(ctx, rec)
+		lr.finish(run, err)
+	}()
+	select {
+	case <-started:
+	case <-lr.done:
+		// The run could not be recorded, so never started.
+		events, _, _ := lr.since(0)
+		writeError(w, http.StatusInternalServerError, events[len(events)-1].Error)
+		return
+	}
+	s.audit(r, c, "run", "namespace", namespace, "name", name, "run", lr.id)
+	writeJSON(w, http.StatusAccepted, RunResponse{Run: lr.id, Events: "/api/runs/" + lr.id + "/events"})
+}
+
+// prune forgets runs finished longer than runRetention ago. runsMu must be
+// held.
+func (s *Server) prune() {
+	for id, lr := range s.live {
+		lr.mu.Lock()
+		old := !lr.finished.IsZero() && time.Since(lr.finished) > runRetention
+		lr.mu.Unlock()
+		if old {
+			delete(s.live, id)
+		}
+	}
+}
+
+// handleRun serves /api/runs/{id}/events, /api/runs/{id}/approvals/{call_id}
+// and DELETE /api/runs/{id}, which cancels the run.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request, c *caller) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/runs/"), "/", 3)
+	s.runsMu.Lock()
+	lr := s.live[parts[0]]
+	s.runsMu.Unlock()
+	if lr == nil || !c.allows(lr.namespace) {
+		writeError(w, http.StatusNotFound, "run not found: "+parts[0])
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		lr.cancel()
+		s.audit(r, c, "cancel", "run", lr.id)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2 && parts[1] == "events":
+		if isWebSocket(r) {
+			s.streamWebSocket(w, r, c, lr)
+		} else {
+			s.streamSSE(w, r, lr)
+		}
+	case len(parts) == 3 && parts[1] == "approvals":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		var d approvalDecision
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			writeError(w, http.StatusBadRequest, "body is not a decision: "+err.Error())
+			return
+		}
+		if !lr.decide(parts[2], d.Approved) {
+			writeError(w, http.StatusNotFound, "no pending approval: "+parts[2])
+			return
+		}
+		s.audit(r, c, "approve", "run", lr.id, "call_id", parts[2], "approved", d.Approved)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "not found: "+r.URL.Path)
+	}
+}
+
+// streamSSE sends the run's events as Server-Sent Events, from the start
+// or after the Last-Event-ID a reconnecting client sends, until the run
+// finishes or the client goes away.
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request, lr *liveRun) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("after")
+	}
+	seq, _ := strconv.Atoi(last)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	tick := time.NewTicker(heartbeat)
+	defer tick.Stop()
+	for {
+		events, finished, changed := lr.since(seq)
+		for _, ev := range events {
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.Seq, data)
+			seq = ev.Seq
+		}
+		flusher.Flush()
+		if finished && len(events) == 0 {
+			return
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-changed:
+		case <-tick.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// streamWebSocket sends the run's events as WebSocket text messages and
+// takes approval decisions back as {"type": "approval", "call_id",
+// "approved"} messages.
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request, c *caller, lr *liveRun) {
+	ws := upgradeWebSocket(w, r)
+	if ws == nil {
+		return
+	}
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			data, err := ws.read()
+			if err != nil {
+				return
+			}
+			var d approvalDecision
+			if json.Unmarshal(data, &d) == nil && d.Type == "approval" && lr.decide(d.CallID, d.Approved) {
+				s.audit(r, c, "approve", "run", lr.id, "call_id", d.CallID, "approved", d.Approved)
+			}
+		}
+	}()
+
+	tick := time.NewTicker(heartbeat)
+	defer tick.Stop()
+	seq := 0
+	for {
+		events, finished, changed := lr.since(seq)
+		for _, ev := range events {
+			data, _ := json.Marshal(ev)
+			if ws.write(wsText, data) != nil {
+				ws.conn.Close()
+				return
+			}
+			seq = ev.Seq
+		}
+		if finished && len(events) == 0 {
+			ws.close(1000)
+			<-gone
+			return
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-changed:
+		case <-tick.C:
+			ws.write(wsPing, nil)
+		case <-gone:
+			ws.conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+// newRunServer serves the reviewer agent, which calls search, needing
+// approval, then answers with what it found.
+func newRunServer(t *testing.T) (*httptest.Server, runs.Store) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.ossa.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	model := modelFunc(func(req *engine.Request) (*engine.Response, error) {
+		last := req.Messages[len(req.Messages)-1]
+		if last.Role == engine.RoleUser {
+			return &engine.Response{ToolCalls: []ossa.ToolCall{{ID: "c1", Tool: "search"}}}, nil
+		}
+		return &engine.Response{Content: fmt.Sprintf(`{"found": %q}`, last.Content)}, nil
+	})
+	store := &runs.DirStore{Dir: t.TempDir()}
+	srv := httptest.NewServer(New(Options{Dir: dir, Store: store, Engine: func(_ ossa.CatalogEntry, approve ossa.ApprovalFunc) *engine.Engine {
+		return &engine.Engine{Model: model, Tools: func(m *ossa.Manifest) ossa.ToolExecFunc {
+			return func(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+				err := ossa.RequestApproval(ctx, approve, ossa.ApprovalRequest{Agent: m.Metadata.Name, Tool: call.Tool, CallID: call.ID, Summary: "Search the code"})
+				if err != nil {
+					return nil, err
+				}
+				return []byte("3 results"), nil
+			}
+		}}
+	}}))
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func startRun(t *testing.T, srv *httptest.Server) RunResponse {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/api/agents/default/reviewer/runs", "application/json", strings.NewReader(`{"mr": 7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out RunResponse
+	json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != http.StatusAccepted || out.Run == "" {
+		t.Fatalf("Expected 202 with a run, got %d %+v", resp.StatusCode, out)
+	}
+	return out
+}
+
+func TestRunEventsSSE(t *testing.T) {
+	srv, store := newRunServer(t)
+	run := startRun(t, srv)
+	resp, err := http.Get(srv.URL + run.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Unexpected content type %q", ct)
+	}
+
+	var types []string
+	var last RunEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev RunEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, ev.Type)
+		last = ev
+		if ev.Type == EventApprovalRequired {
+			if ev.CallID != "c1" || ev.Summary != "Search the code" {
+				t.Errorf("Unexpected approval event %+v", ev)
+			}
+			resp, err := http.Post(srv.URL+"/api/runs/"+run.Run+"/approvals/c1", "application/json", strings.NewReader(`{"approved": true}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("Expected 204 for the decision, got %d", resp.StatusCode)
+			}
+		}
+	}
+	want := "run_started agent_started tool_call approval_required approval_decided tool_result agent_finished run_finished"
+	if strings.Join(types, " ") != want {
+		t.Errorf("Expected events %q, got %q", want, strings.Join(types, " "))
+	}
+	if last.Run != run.Run || last.Seq != 8 || last.Status != runs.StatusSucceeded || last.Output["found"] != "3 results" {
+		t.Errorf("Unexpected last event %+v", last)
+	}
+	recorded, err := store.Get(context.Background(), run.Run)
+	if err != nil || recorded.Status != runs.StatusSucceeded {
+		t.Errorf("Expected the run recorded, got %+v %v", recorded, err)
+	}
+
+	// A reconnecting client picks up after the last event it saw.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+run.Events, nil)
+	req.Header.Set("Last-Event-ID", "7")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(string(body), "id: 8\n") || strings.Count(string(body), "data: ") != 1 {
+		t.Errorf("Expected only event 8, got %s", body)
+	}
+}
+
+func TestRunEventsWebSocket(t *testing.T) {
+	srv, _ := newRunServer(t)
+	run := startRun(t, srv)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	key := make([]byte, 16)
+	rand.Read(key)
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: %s\r\n\r\n",
+		run.Events, base64.StdEncoding.EncodeToString(key))
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+
+	var types []string
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			t.Fatal(err)
+		}
+		n := int(head[1] & 0x7F)
+		if n == 126 {
+			var ext [2]byte
+			io.ReadFull(r, ext[:])
+			n = int(ext[0])<<8 | int(ext[1])
+		}
+		payload := make([]byte, n)
+		io.ReadFull(r, payload)
+		if head[0]&0x0F == wsClose {
+			break
+		}
+		var ev RunEvent
+		if err := json.Unmarshal(payload, &ev); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, ev.Type)
+		if ev.Type == EventApprovalRequired {
+			// Deny it with a masked client frame.
+			msg := []byte(`{"type": "approval", "call_id": "c1", "approved": false}`)
+			mask := []byte{1, 2, 3, 4}
+			frame := append([]byte{0x81, 0x80 | byte(len(msg))}, mask...)
+			for i, b := range msg {
+				frame = append(frame, b^mask[i%4])
+			}
+			conn.Write(frame)
+		}
+		if ev.Type == EventApprovalDecided && (ev.Approved == nil || *ev.Approved) {
+			t.Errorf("Expected the call denied, got %+v", ev)
+		}
+		if ev.Type == engine.EventToolResult && !strings.Contains(ev.Error, "not approved") {
+			t.Errorf("Expected the denial as the tool's error, got %+v", ev)
+		}
+	}
+	if len(types) != 8 || types[len(types)-1] != EventRunFinished {
+		t.Errorf("Unexpected events %v", types)
+	}
+}
+
+func TestWebSocketFrameLimits(t *testing.T) {
+	// maskedFrame is a client frame with the given first byte and length
+	// bytes, masked with a zero key.
+	maskedFrame := func(head byte, length []byte, payload string) []byte {
+		length[0] |= 0x80
+		frame := append([]byte{head}, length...)
+		return append(append(frame, 0, 0, 0, 0), payload...)
+	}
+	for name, frames := range map[string][][]byte{
+		"wrapping continuation": {
+			maskedFrame(wsText, []byte{1}, "a"),
+			maskedFrame(0x80|wsContinuation, []byte{127, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, ""),
+		},
+		"top length bit":    {maskedFrame(0x80|wsText, []byte{127, 0x80, 0, 0, 0, 0, 0, 0, 1}, "")},
+		"oversized message": {maskedFrame(0x80|wsBinary, []byte{127, 0, 0, 0, 0, 0, 1, 0, 1}, "")},
+		"long ping":         {maskedFrame(0x80|wsPing, []byte{126, 0, 126}, "")},
+		"fragmented close":  {maskedFrame(wsClose, []byte{0}, "")},
+	} {
+		server, client := net.Pipe()
+		go io.Copy(io.Discard, client)
+		c := &wsConn{conn: server, r: bufio.NewReader(bytes.NewReader(bytes.Join(frames, nil)))}
+		if _, err := c.read(); err == nil || err == io.EOF {
+			t.Errorf("%s: expected the frame rejected, got %v", name, err)
+		}
+		client.Close()
+	}
+}
+
+func TestRunEngineEvents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.ossa.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan string, 10)
+	model := modelFunc(func(req *engine.Request) (*engine.Response, error) {
+		return &engine.Response{Content: `{"ok": true}`}, nil
+	})
+	srv := httptest.NewServer(New(Options{Dir: dir, Store: &runs.DirStore{Dir: t.TempDir()}, Engine: func(_ ossa.CatalogEntry, _ ossa.ApprovalFunc) *engine.Engine {
+		return &engine.Engine{Model: model, Events: func(ev engine.Event) { events <- ev.Type }}
+	}}))
+	t.Cleanup(srv.Close)
+
+	run := startRun(t, srv)
+	resp, err := http.Get(srv.URL + run.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	close(events)
+	var types []string
+	for ev := range events {
+		types = append(types, ev)
+	}
+	if strings.Join(types, " ") != "agent_started agent_finished" {
+		t.Errorf("Expected the engine's own Events to get the run's events, got %v", types)
+	}
+}
+
+func TestRunDisabled(t *testing.T) {
+	srv := newTestServer(t)
+	resp, err := http.Post(srv.URL+"/api/agents/default/reviewer/runs", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without an engine, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(srv.URL + "/api/runs/nope/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for events without an engine, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,782 @@
+// Package server implements ossa serve: an HTTP API and embedded web UI
+// for validating manifests and browsing the agents in a workspace.
+//
+// The API is small and JSON-only:
+//
+//	POST /api/validate                    validate the manifest in the request body
+//	GET  /api/agents?q=text&namespace=ns  list workspace agents, optionally filtered
+//	GET  /api/agents/{[namespace/]name}   one agent with its manifest
+//	PUT  /api/agents/{namespace}/{name}   publish a manifest (tenants only)
+//	DELETE /api/agents/{namespace}/{name} unpublish an agent (tenants only)
+//	GET  /api/agents/{namespace}/{name}/versions[/{version}]
+//	                                      released versions, or one release
+//	GET  /api/agents/{namespace}/{name}/channels[/{channel}]
+//	                                      release channels, or one's release
+//	PUT  /api/agents/{namespace}/{name}/channels/{channel}
+//	                                      promote a release to a channel
+//	GET  /api/namespaces                  namespaces with agent counts and quotas
+//	POST /api/agents/{namespace}/{name}/runs
+//	                                      run an agent, Task or Workflow on the JSON input
+//	GET  /api/runs/{id}/events            follow a run over SSE or WebSocket
+//	POST /api/runs/{id}/approvals/{call_id}
+//	                                      approve or deny a tool call: {"approved": bool}
+//	DELETE /api/runs/{id}                 cancel a run
+//	GET  /metrics                         request counters in Prometheus text format
+//
+// GET /api/agents also takes selector, a label selector such as
+// team=ops,tier!=policy (see package labels), and kind. With limit it
+// returns a page at a time, linking the next one in a Link header with
+// rel="next" whose continue parameter is an ossa.ManifestList token.
+//
+// Agents are grouped by metadata.namespace, DefaultNamespace if unset.
+// When Options.Tenants or Options.Auth is set, API requests and agent pages
+// need a tenant token, an API key or a JWT. Each caller sees the namespaces
+// its credential grants and acts within its roles: read, run, publish or
+// admin. Validations, publishes and unpublishes are audit logged with the
+// caller's identity.
+//
+// Publishing a manifest whose metadata.version is a semantic version also
+// keeps it as a release, which versioned refs such as reviewer@^1.2
+// resolve against; see package resolve. PUT with ?channel=beta publishes a
+// release to a channel other than stable, leaving the catalog's manifest
+// alone until the release is promoted.
+//
+// Runs need Options.Engine and the run role, and use the locale of their
+// request's Accept-Language header for localized prompts. Their events
+// (RunEvent) are JSON objects sent as Server-Sent Events, resumable with
+// Last-Event-ID, or as WebSocket text messages, over which clients may
+// also send {"type": "approval", "call_id": ..., "approved": bool}. A
+// finished run's events stay readable for ten minutes.
+//
+// Options.RateLimit throttles each client IP, answering 429 with a
+// Retry-After header, and bodies over Options.MaxBodyBytes get 413.
+//
+// The UI (drag-and-drop validation, a searchable catalog and a rendered
+// documentation page per agent under /agents/{namespace}/{name}) is served
+// from assets embedded in the binary.
+package server
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/labels"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/runs"
+)
+
+//go:embed web
+var web embed.FS
+
+var agentPage = template.Must(template.ParseFS(web, "web/templates/agent.html"))
+
+// Options configures a Server.
+type Options struct {
+	// Dir is the workspace listed in the catalog; empty disables the
+	// catalog and leaves only validation.
+	Dir string
+	// Manifests, if set, holds the catalog instead of Dir, which then
+	// only keeps released versions and channels; without Dir there are
+	// none.
+	Manifests ossa.ManifestStore
+	// Limits apply to uploaded manifests; zero fields take their
+	// ossa.DefaultParseLimits value.
+	Limits ossa.ParseLimits
+	// Logger receives one info event per request; nil uses ossa.Logger().
+	Logger *slog.Logger
+	// Tenants enables namespace isolation; see LoadTenants.
+	Tenants []Tenant
+	// Auth adds API keys and JWTs; see LoadAuth. Without tenants or auth
+	// every namespace is open to read and publishing is disabled.
+	Auth *Auth
+	// AuditLogger receives audit events; nil uses Logger.
+	AuditLogger *slog.Logger
+	// RateLimit throttles each client; the zero value disables it.
+	RateLimit RateLimit
+	// MaxBodyBytes caps request bodies; zero uses the manifest size limit
+	// from Limits.
+	MaxBodyBytes int64
+	// Engine, if set, lets callers with the run role run the catalog's
+	// agents, Tasks and Workflows: it returns the engine for an entry,
+	// with approve asking the run's followers about tool calls needing
+	// approval. The run's events are also sent to the engine's Events, if
+	// set, and its moderation is audited to AuditLogger unless it has an
+	// AuditLogger of its own.
+	Engine func(entry ossa.CatalogEntry, approve ossa.ApprovalFunc) *engine.Engine
+	// Store records the runs started through the API; nil records nothing.
+	Store runs.Store
+	// Encrypt, if set, encrypts the manifests publishes store, such as a
+	// crypt.Keyring's Encrypt. Reading them back needs ossa.SetDecrypter;
+	// API callers get them decrypted.
+	Encrypt func(data []byte) ([]byte, error)
+}
+
+// Server is an http.Handler serving the API and UI. It rescans the
+// workspace on each catalog request, so edits show up without a restart.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+	// store holds the catalog; nil without one.
+	store ossa.ManifestStore
+	jwt   *jwtVerifier
+	// limiter is nil when rate limiting is off.
+	limiter *limiter
+	metrics *metrics
+	// publishMu serializes publishes so quotas hold.
+	publishMu sync.Mutex
+	runsMu    sync.Mutex
+	// live holds the runs started through the API by ID.
+	live map[string]*liveRun
+}
+
+// New returns a Server for opts.
+func New(opts Options) *Server {
+	s := &Server{opts: opts, mux: http.NewServeMux(), metrics: newMetrics(), live: map[string]*liveRun{}}
+	switch {
+	case opts.Manifests != nil:
+		s.store = opts.Manifests
+	case opts.Dir != "":
+		s.store = &ossa.DirManifestStore{Dir: opts.Dir}
+	}
+	if opts.Auth != nil && opts.Auth.JWT != nil {
+		s.jwt = newJWTVerifier(*opts.Auth.JWT)
+	}
+	if opts.RateLimit.Rate > 0 {
+		s.limiter = newLimiter(opts.RateLimit)
+	}
+	static, _ := fs.Sub(web, "web/static")
+	s.mux.Handle("/", http.FileServer(http.FS(static)))
+	s.mux.HandleFunc("/api/validate", s.authed(RoleRead, s.handleValidate))
+	s.mux.HandleFunc("/api/agents", s.authed(RoleRead, s.handleAgents))
+	s.mux.HandleFunc("/api/agents/", s.authed(RoleRead, s.handleAgent))
+	s.mux.HandleFunc("/api/namespaces", s.authed(RoleRead, s.handleNamespaces))
+	s.mux.HandleFunc("/api/runs/", s.authed(RoleRun, s.handleRun))
+	s.mux.HandleFunc("/agents/", s.authed(RoleRead, s.handleAgentPage))
+	s.mux.HandleFunc("/metrics", s.metrics.handle)
+	return s
+}
+
+// ServeHTTP implements http.Handler. Scrapes of /metrics are not rate
+// limited, so a throttled server can still be watched.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	_, route := s.mux.Handler(r)
+	maxBody := s.maxBody()
+	switch ok, wait := s.allow(r, route); {
+	case !ok:
+		s.metrics.rateLimited()
+		rec.Header().Set("Retry-After", retryAfter(wait))
+		writeError(rec, http.StatusTooManyRequests, "rate limit exceeded")
+	case r.ContentLength > maxBody:
+		writeError(rec, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body over %d bytes", maxBody))
+	default:
+		r.Body = http.MaxBytesReader(rec, r.Body, maxBody)
+		s.mux.ServeHTTP(rec, r)
+	}
+	if rec.status == http.StatusRequestEntityTooLarge {
+		s.metrics.payloadTooLarge()
+	}
+	s.metrics.request(route, rec.status)
+	s.log().Info("request", "method", r.Method, "path", r.URL.Path, "status", rec.status,
+		"duration", time.Since(start), "remote", r.RemoteAddr)
+}
+
+// allow applies the rate limit, returning how long to wait when r is over
+// it.
+func (s *Server) allow(r *http.Request, route string) (bool, time.Duration) {
+	if s.limiter == nil || route == "/metrics" {
+		return true, 0
+	}
+	return s.limiter.allow(clientIP(r, s.opts.RateLimit.TrustProxy))
+}
+
+// maxBody returns the request body cap.
+func (s *Server) maxBody() int64 {
+	if s.opts.MaxBodyBytes > 0 {
+		return s.opts.MaxBodyBytes
+	}
+	if s.opts.Limits.MaxBytes > 0 {
+		return int64(s.opts.Limits.MaxBytes)
+	}
+	return int64(ossa.DefaultParseLimits.MaxBytes)
+}
+
+func (s *Server) log() *slog.Logger {
+	if s.opts.Logger != nil {
+		return s.opts.Logger
+	}
+	return ossa.Logger()
+}
+
+func (s *Server) auditLog() *slog.Logger {
+	if s.opts.AuditLogger != nil {
+		return s.opts.AuditLogger
+	}
+	return s.log()
+}
+
+// statusRecorder remembers the status code written for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets event streams through.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection cannot be hijacked")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// ValidateResponse is the body of POST /api/validate.
+type ValidateResponse struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+	// Name and Kind are set when the manifest parsed.
+	Name string    `json:"name,omitempty"`
+	Kind ossa.Kind `json:"kind,omitempty"`
+}
+
+// AgentSummary is one catalog entry in GET /api/agents.
+type AgentSummary struct {
+	Name        string          `json:"name"`
+	Namespace   string          `json:"namespace"`
+	Kind        ossa.Kind       `json:"kind"`
+	Version     string          `json:"version,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Tier        ossa.AccessTier `json:"tier,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Tools       int             `json:"tools"`
+	// Digest is "sha256:" and the hex sha256 of the manifest's canonical
+	// JSON, which lock files pin.
+	Digest   string    `json:"digest,omitempty"`
+	Modified time.Time `json:"modified"`
+	Path     string    `json:"path"`
+	Valid    bool      `json:"valid"`
+}
+
+// AgentResponse is the body of GET /api/agents/{name}.
+type AgentResponse struct {
+	AgentSummary
+	Manifest *ossa.Manifest `json:"manifest"`
+	Errors   []string       `json:"errors"`
+	Warnings []string       `json:"warnings"`
+}
+
+// NamespaceSummary is one entry in GET /api/namespaces.
+type NamespaceSummary struct {
+	Namespace string `json:"namespace"`
+	Agents    int    `json:"agents"`
+	Quota     Quota  `json:"quota"`
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request, c *caller) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	limits := s.opts.Limits
+	if limits.MaxBytes == 0 {
+		limits.MaxBytes = ossa.DefaultParseLimits.MaxBytes
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(limits.MaxBytes)+1))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "manifest too large")
+		return
+	}
+
+	m, err := parseUpload(data, r.Header.Get("Content-Type"), limits)
+	if err != nil {
+		s.audit(r, c, "validate", "valid", false)
+		s.metrics.validation(false)
+		writeJSON(w, http.StatusOK, ValidateResponse{Errors: []string{err.Error()}, Warnings: []string{}})
+		return
+	}
+	result := ossa.ValidateManifest(m)
+	s.audit(r, c, "validate", "namespace", namespaceOf(m), "name", m.Metadata.Name, "valid", result.Valid)
+	s.metrics.validation(result.Valid)
+	writeJSON(w, http.StatusOK, ValidateResponse{
+		Valid:    result.Valid,
+		Errors:   nonNil(result.Errors),
+		Warnings: nonNil(result.Warnings),
+		Name:     m.Metadata.Name,
+		Kind:     m.Kind,
+	})
+}
+
+// parseUpload parses an uploaded manifest, as JSON when the content type
+// says so and otherwise by sniffing.
+func parseUpload(data []byte, contentType string, limits ossa.ParseLimits) (*ossa.Manifest, error) {
+	ext := ""
+	if strings.Contains(contentType, "json") {
+		ext = ".json"
+	}
+	return ossa.ParseManifestWithLimits(data, ext, limits)
+}
+
+func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request, c *caller) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	if namespace != "" && !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	sel, err := labels.Parse(r.URL.Query().Get("selector"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	list, err := ossa.NewManifestList(entries, ossa.ListOptions{
+		Kind:     ossa.Kind(r.URL.Query().Get("kind")),
+		Selector: sel,
+		Match: func(e ossa.CatalogEntry) bool {
+			sum := summarize(e)
+			text := strings.ToLower(strings.Join([]string{sum.Name, string(sum.Kind), sum.Description, string(sum.Tier), sum.Model}, " "))
+			return (namespace == "" || sum.Namespace == namespace) && (q == "" || strings.Contains(text, q))
+		},
+		Limit:    limit,
+		Continue: r.URL.Query().Get("continue"),
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	out := make([]AgentSummary, 0, len(list.Items))
+	for _, e := range list.Items {
+		out = append(out, summarize(e))
+	}
+	if list.Continue != "" {
+		next := r.URL.Query()
+		next.Set("continue", list.Continue)
+		w.Header().Set("Link", fmt.Sprintf(`</api/agents?%s>; rel="next"`, next.Encode()))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleAgent(w http.ResponseWriter, r *http.Request, c *caller) {
+	if parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/agents/"), "/", 4); len(parts) >= 3 {
+		sub := ""
+		if len(parts) == 4 {
+			sub = parts[3]
+		}
+		switch parts[2] {
+		case "versions":
+			s.handleVersions(w, r, c, parts[0], parts[1], sub)
+			return
+		case "channels":
+			s.handleChannels(w, r, c, parts[0], parts[1], sub)
+			return
+		case "runs":
+			s.handleStartRun(w, r, c, parts[0], parts[1])
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.handlePublish(w, r, c)
+		return
+	case http.MethodDelete:
+		s.handleUnpublish(w, r, c)
+		return
+	}
+	e, ok := s.lookup(w, r, c, "/api/agents/")
+	if !ok {
+		return
+	}
+	result := ossa.ValidateManifest(e.Manifest)
+	writeJSON(w, http.StatusOK, AgentResponse{
+		AgentSummary: summarize(e),
+		Manifest:     e.Manifest,
+		Errors:       nonNil(result.Errors),
+		Warnings:     nonNil(result.Warnings),
+	})
+}
+
+func (s *Server) handleAgentPage(w http.ResponseWriter, r *http.Request, c *caller) {
+	e, ok := s.lookup(w, r, c, "/agents/")
+	if !ok {
+		return
+	}
+	source, err := e.Manifest.ToYAML()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	agentPage.Execute(w, struct {
+		AgentSummary
+		Manifest *ossa.Manifest
+		Result   *ossa.ValidationResult
+		YAML     string
+	}{summarize(e), e.Manifest, ossa.ValidateManifest(e.Manifest), source})
+}
+
+// lookup finds the agent named by the rest of the path after prefix,
+// namespace/name or a name unique among the caller's namespaces.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request, c *caller, prefix string) (ossa.CatalogEntry, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return ossa.CatalogEntry{}, false
+	}
+	rest := strings.TrimPrefix(r.URL.Path, prefix)
+	namespace, name, qualified := strings.Cut(rest, "/")
+	if !qualified {
+		namespace, name = "", rest
+	}
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return ossa.CatalogEntry{}, false
+	}
+	var found []ossa.CatalogEntry
+	for _, e := range entries {
+		if e.Name == name && (namespace == "" || namespaceOf(e.Manifest) == namespace) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		writeError(w, http.StatusNotFound, "agent not found: "+rest)
+		return ossa.CatalogEntry{}, false
+	case 1:
+		return found[0], true
+	}
+	writeError(w, http.StatusConflict, fmt.Sprintf("agent %s is in several namespaces; use %s{namespace}/%s", name, prefix, name))
+	return ossa.CatalogEntry{}, false
+}
+
+// handlePublish writes the manifest in the request body to the workspace
+// as {namespace}/{name}.ossa.yaml, or over the file already holding that
+// agent, after validating it and checking the namespace's quota.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request, c *caller) {
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/agents/"), "/")
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = rec
+	defer func() { s.audit(r, c, "publish", "namespace", namespace, "name", name, "status", rec.status) }()
+
+	if !c.can(RolePublish) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s role", c.subject, RolePublish))
+		return
+	}
+	if s.store == nil {
+		writeError(w, http.StatusForbidden, "publishing needs a workspace")
+		return
+	}
+	if !ok || !namespacePattern.MatchString(namespace) || !namespacePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, "publish to /api/agents/{namespace}/{name}")
+		return
+	}
+	if !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	var quota Quota
+	if t := s.tenant(namespace); t != nil {
+		quota = t.Quota
+	}
+
+	limits := s.opts.Limits
+	if limits.MaxBytes == 0 {
+		limits.MaxBytes = ossa.DefaultParseLimits.MaxBytes
+	}
+	if quota.MaxManifestBytes > 0 && quota.MaxManifestBytes < limits.MaxBytes {
+		limits.MaxBytes = quota.MaxManifestBytes
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(limits.MaxBytes)+1))
+	if err != nil || len(data) > limits.MaxBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "manifest too large")
+		return
+	}
+	m, err := parseUpload(data, r.Header.Get("Content-Type"), limits)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ValidateResponse{Errors: []string{err.Error()}, Warnings: []string{}})
+		return
+	}
+	if m.Metadata.Name != name {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("metadata.name %q does not match %s", m.Metadata.Name, name))
+		return
+	}
+	if m.Metadata.Namespace == "" && namespace != DefaultNamespace {
+		m.Metadata.Namespace = namespace
+	}
+	if namespaceOf(m) != namespace {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("metadata.namespace %q does not match %s", m.Metadata.Namespace, namespace))
+		return
+	}
+	result := ossa.ValidateManifest(m)
+	resp := ValidateResponse{
+		Valid:    result.Valid,
+		Errors:   nonNil(result.Errors),
+		Warnings: nonNil(result.Warnings),
+		Name:     m.Metadata.Name,
+		Kind:     m.Kind,
+	}
+	if !result.Valid {
+		writeJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	channel := r.URL.Query().Get("channel")
+	if channel != "" {
+		if msg := checkChannel(channel, m); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if m.Metadata.Annotations == nil {
+			m.Metadata.Annotations = map[string]string{}
+		}
+		m.Metadata.Annotations[ChannelAnnotation] = channel
+	}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	status := http.StatusCreated
+	if channel == "" || channel == StableChannel {
+		var ok bool
+		if status, ok = s.saveCurrent(w, m, namespace, name, quota); !ok {
+			return
+		}
+	}
+	if err := s.release(m, channel); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, status, resp)
+}
+
+// saveCurrent writes m as the catalog's manifest for namespace/name,
+// over the file already holding it or as {namespace}/{name}.ossa.yaml
+// within the quota. It returns 200 or 201, or false after writing an error.
+func (s *Server) saveCurrent(w http.ResponseWriter, m *ossa.Manifest, namespace, name string, quota Quota) (int, bool) {
+	entries, err := s.catalog(&caller{all: true})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return 0, false
+	}
+	path, held := "", 0
+	for _, e := range entries {
+		if namespaceOf(e.Manifest) != namespace {
+			continue
+		}
+		if e.Name == name {
+			path = e.Path
+		} else {
+			held++
+		}
+	}
+	if path == "" && quota.MaxAgents > 0 && held >= quota.MaxAgents {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("quota exceeded: namespace %s holds %d of %d agents", namespace, held, quota.MaxAgents))
+		return 0, false
+	}
+	status := http.StatusOK
+	if path == "" {
+		status = http.StatusCreated
+		path = filepath.Join(s.opts.Dir, namespace, name+".ossa.yaml")
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	if err := s.save(m, path, format); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return 0, false
+	}
+	return status, true
+}
+
+// save writes a manifest to the workspace, encrypted if Options.Encrypt
+// is set.
+func (s *Server) save(m *ossa.Manifest, path, format string) error {
+	data, err := s.marshal(m, format)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(context.Background(), path, data)
+}
+
+// marshal encodes m in format, encrypted if Options.Encrypt is set.
+func (s *Server) marshal(m *ossa.Manifest, format string) ([]byte, error) {
+	data, err := ossa.MarshalManifest(m, format)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.Encrypt != nil {
+		if data, err = s.opts.Encrypt(data); err != nil {
+			return nil, fmt.Errorf("failed to encrypt manifest: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// handleUnpublish removes the file holding {namespace}/{name} from the
+// workspace.
+func (s *Server) handleUnpublish(w http.ResponseWriter, r *http.Request, c *caller) {
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/agents/"), "/")
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = rec
+	defer func() { s.audit(r, c, "unpublish", "namespace", namespace, "name", name, "status", rec.status) }()
+
+	if !c.can(RolePublish) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s role", c.subject, RolePublish))
+		return
+	}
+	if !ok || !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, e := range entries {
+		if e.Name == name && namespaceOf(e.Manifest) == namespace {
+			if err := s.store.Delete(r.Context(), e.Path); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "agent not found: "+namespace+"/"+name)
+}
+
+func (s *Server) handleNamespaces(w http.ResponseWriter, r *http.Request, c *caller) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	counts := map[string]int{}
+	for _, t := range s.opts.Tenants {
+		if c.allows(t.Namespace) {
+			counts[t.Namespace] = 0
+		}
+	}
+	for _, e := range entries {
+		counts[namespaceOf(e.Manifest)]++
+	}
+	out := []NamespaceSummary{}
+	for ns, n := range counts {
+		sum := NamespaceSummary{Namespace: ns, Agents: n}
+		if t := s.tenant(ns); t != nil {
+			sum.Quota = t.Quota
+		}
+		out = append(out, sum)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Namespace < out[j].Namespace })
+	writeJSON(w, http.StatusOK, out)
+}
+
+// catalog returns the workspace manifests that loaded, in the namespaces
+// the caller may see.
+func (s *Server) catalog(c *caller) ([]ossa.CatalogEntry, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	entries, err := s.store.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	loaded := entries[:0]
+	for _, e := range entries {
+		if e.Err == nil && c.allows(namespaceOf(e.Manifest)) {
+			loaded = append(loaded, e)
+		}
+	}
+	return loaded, nil
+}
+
+func summarize(e ossa.CatalogEntry) AgentSummary {
+	digest, err := e.Manifest.Digest()
+	if err == nil {
+		digest = "sha256:" + digest
+	}
+	return AgentSummary{
+		Name:        e.Name,
+		Namespace:   namespaceOf(e.Manifest),
+		Kind:        e.Kind,
+		Version:     e.Manifest.Metadata.Version,
+		Description: e.Manifest.Metadata.Description,
+		Tier:        e.Tier,
+		Model:       e.Model,
+		Tools:       len(e.Manifest.Spec.Tools),
+		Digest:      digest,
+		Modified:    e.Modified,
+		Path:        e.Path,
+		Valid:       ossa.ValidateManifest(e.Manifest).Valid,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
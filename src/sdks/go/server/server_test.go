@@ -0,0 +1,363 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+const testManifest = `apiVersion: ossa/v0.3.3
+kind: Agent
+metadata:
+  name: reviewer
+  description: Reviews merge requests
+spec:
+  role: You review code.
+  access_tier: tier_1_read
+  llm:
+    provider: openai
+    model: gpt-4o
+  tools:
+    - type: mcp
+      name: search
+`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.ossa.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(Options{Dir: dir}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/api/validate", "application/yaml", strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body ValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.Valid || body.Name != "reviewer" || body.Kind != "Agent" {
+		t.Errorf("Unexpected response: %+v", body)
+	}
+
+	resp, err = http.Post(srv.URL+"/api/validate", "application/json", strings.NewReader(`{"kind": "Robot"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body = ValidateResponse{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Valid || len(body.Errors) == 0 {
+		t.Errorf("Expected errors, got %+v", body)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestCatalogEndpoints(t *testing.T) {
+	srv := newTestServer(t)
+
+	var agents []AgentSummary
+	getJSON(t, srv.URL+"/api/agents?q=GPT", &agents)
+	if len(agents) != 1 || agents[0].Name != "reviewer" || agents[0].Tools != 1 || !agents[0].Valid {
+		t.Errorf("Unexpected catalog: %+v", agents)
+	}
+	getJSON(t, srv.URL+"/api/agents?q=nomatch", &agents)
+	if len(agents) != 0 {
+		t.Errorf("Expected no matches, got %+v", agents)
+	}
+	getJSON(t, srv.URL+"/api/agents?selector=team%3Dops", &agents)
+	if len(agents) != 0 {
+		t.Errorf("Expected the selector to exclude unlabeled agents, got %+v", agents)
+	}
+	getJSON(t, srv.URL+"/api/agents?selector=team!%3Dops,!deprecated", &agents)
+	if len(agents) != 1 {
+		t.Errorf("Expected the selector to match, got %+v", agents)
+	}
+	if resp, err := http.Get(srv.URL + "/api/agents?selector=in%3D%3D%3D"); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad selector, got %v %v", resp, err)
+	}
+
+	var agent AgentResponse
+	getJSON(t, srv.URL+"/api/agents/reviewer", &agent)
+	if agent.Manifest == nil || agent.Manifest.Spec.LLM.Model != "gpt-4o" {
+		t.Errorf("Unexpected agent: %+v", agent)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/agents/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestUIPages(t *testing.T) {
+	srv := newTestServer(t)
+
+	for path, want := range map[string]string{
+		"/":                "Validate a manifest",
+		"/app.js":          "/api/validate",
+		"/agents/reviewer": "Reviews merge requests",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), want) {
+			t.Errorf("GET %s: status %d, expected %q in:\n%s", path, resp.StatusCode, want, data)
+		}
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	srv := httptest.NewServer(New(Options{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}))
+
+	resp, err := http.Get(srv.URL + "/api/validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	srv.Close() // waits for the handler to finish logging
+	for _, want := range []string{`"msg":"request"`, `"method":"GET"`, `"path":"/api/validate"`, `"status":405`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in log:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestTenancy(t *testing.T) {
+	dir := t.TempDir()
+	for name, ns := range map[string]string{"reviewer": "payments", "triager": "search", "helper": ""} {
+		m := strings.Replace(testManifest, "name: reviewer", "name: "+name, 1)
+		if ns != "" {
+			m = strings.Replace(m, "metadata:\n", "metadata:\n  namespace: "+ns+"\n", 1)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".ossa.yaml"), []byte(m), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tenantsFile := filepath.Join(t.TempDir(), "tenants.yaml")
+	os.WriteFile(tenantsFile, []byte(`tenants:
+  - namespace: payments
+    tokens: [pay, ops]
+    quota: {max_agents: 2, max_manifest_bytes: 4096}
+  - namespace: search
+    tokens: [ops]
+`), 0644)
+	tenants, err := LoadTenants(tenantsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(Options{Dir: dir, Tenants: tenants}))
+	defer srv.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	names := func(token, query string) []string {
+		t.Helper()
+		var agents []AgentSummary
+		json.NewDecoder(do("GET", "/api/agents"+query, token, "").Body).Decode(&agents)
+		var out []string
+		for _, a := range agents {
+			out = append(out, a.Namespace+"/"+a.Name)
+		}
+		return out
+	}
+
+	if resp := do("GET", "/api/agents", "", ""); resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with a challenge, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/api/agents", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown token, got %d", resp.StatusCode)
+	}
+	if got := names("pay", ""); len(got) != 1 || got[0] != "payments/reviewer" {
+		t.Errorf("Expected only payments agents, got %v", got)
+	}
+	if got := names("ops", "?namespace=search"); len(got) != 1 || got[0] != "search/triager" {
+		t.Errorf("Expected search agents, got %v", got)
+	}
+	if resp := do("GET", "/api/agents?namespace=search", "pay", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for another namespace, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/api/agents/search/triager", "pay", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected another namespace's agent to be hidden, got %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/api/agents/payments/reviewer", "pay", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var namespaces []NamespaceSummary
+	json.NewDecoder(do("GET", "/api/namespaces", "ops", "").Body).Decode(&namespaces)
+	if len(namespaces) != 2 || namespaces[0].Namespace != "payments" || namespaces[0].Agents != 1 || namespaces[0].Quota.MaxAgents != 2 {
+		t.Errorf("Unexpected namespaces %+v", namespaces)
+	}
+
+	// Publishing: new agents land in {namespace}/{name}.ossa.yaml, within quota.
+	publish := func(name, token string) *http.Response {
+		return do("PUT", "/api/agents/payments/"+name, token, strings.Replace(testManifest, "name: reviewer", "name: "+name, 1))
+	}
+	if resp := publish("biller", "pay"); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	published, err := os.ReadFile(filepath.Join(dir, "payments", "biller.ossa.yaml"))
+	if err != nil || !strings.Contains(string(published), "namespace: payments") {
+		t.Errorf("Expected the published manifest with its namespace, got %s (%v)", published, err)
+	}
+	if resp := publish("biller", "pay"); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 on republish, got %d", resp.StatusCode)
+	}
+	if resp := publish("third", "pay"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected the quota to refuse a third agent, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/api/agents/search/biller", "pay", testManifest); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 publishing to another namespace, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/api/agents/payments/other", "pay", testManifest); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a name mismatch, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/api/agents/payments/biller", "pay", strings.Repeat("#", 5000)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 over max_manifest_bytes, got %d", resp.StatusCode)
+	}
+	if resp := do("PUT", "/api/agents/payments/bad", "pay", "apiVersion: ossa/v0.3.3\nkind: Robot\nmetadata:\n  name: bad\n"); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid manifest, got %d", resp.StatusCode)
+	}
+
+	// Agent pages take the token from a cookie.
+	req, _ := http.NewRequest("GET", srv.URL+"/agents/payments/reviewer", nil)
+	req.AddCookie(&http.Cookie{Name: tokenCookie, Value: "pay"})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the cookie to authorize the page, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoadTenants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	for _, bad := range []string{
+		"tenants:\n  - namespace: Payments\n    tokens: [x]\n",
+		"tenants:\n  - namespace: a\n    tokens: [x]\n  - namespace: a\n    tokens: [y]\n",
+		"tenants:\n  - namespace: a\n",
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadTenants(path); err == nil {
+			t.Errorf("Expected an error for:\n%s", bad)
+		}
+	}
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifestStore(t *testing.T) {
+	store := &ossa.MemoryManifestStore{}
+	ctx := context.Background()
+	m := strings.Replace(testManifest, "metadata:\n", "metadata:\n  namespace: payments\n", 1)
+	if err := store.Put(ctx, "payments/reviewer.ossa.yaml", []byte(m)); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(Options{Manifests: store, Tenants: []Tenant{{Namespace: "payments", Tokens: []string{"pay"}}}}))
+	defer srv.Close()
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer pay")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var agents []AgentSummary
+	json.NewDecoder(do("GET", "/api/agents", "").Body).Decode(&agents)
+	if len(agents) != 1 || agents[0].Name != "reviewer" {
+		t.Errorf("Expected the stored agent, got %+v", agents)
+	}
+	if resp := do("PUT", "/api/agents/payments/biller", strings.Replace(testManifest, "name: reviewer", "name: biller", 1)); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	data, err := store.Get(ctx, "payments/biller.ossa.yaml")
+	if err != nil || !strings.Contains(string(data), "namespace: payments") {
+		t.Errorf("Expected the published manifest in the store, got %s (%v)", data, err)
+	}
+	resp := do("GET", "/api/agents?limit=1", "")
+	json.NewDecoder(resp.Body).Decode(&agents)
+	link := resp.Header.Get("Link")
+	if len(agents) != 1 || agents[0].Name != "biller" || !strings.HasSuffix(link, `>; rel="next"`) {
+		t.Fatalf("Expected the first page and a next link, got %+v %q", agents, link)
+	}
+	resp = do("GET", strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`), "")
+	json.NewDecoder(resp.Body).Decode(&agents)
+	if len(agents) != 1 || agents[0].Name != "reviewer" || resp.Header.Get("Link") != "" {
+		t.Errorf("Expected the last page, got %+v %q", agents, resp.Header.Get("Link"))
+	}
+	for _, bad := range []string{"limit=0", "continue=%21"} {
+		if resp := do("GET", "/api/agents?"+bad, ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", bad, resp.StatusCode)
+		}
+	}
+	if resp := do("DELETE", "/api/agents/payments/biller", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", resp.StatusCode)
+	}
+	if _, err := store.Get(ctx, "payments/biller.ossa.yaml"); !errors.Is(err, ossa.ErrManifestNotFound) {
+		t.Errorf("Expected the unpublished manifest to be gone, got %v", err)
+	}
+}
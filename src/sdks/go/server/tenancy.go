@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultNamespace holds manifests without metadata.namespace.
+const DefaultNamespace = ossa.DefaultNamespace
+
+// Tenant is a namespace with its own tokens and quota. A token listed by
+// several tenants grants all of their namespaces, with the read and
+// publish roles.
+type Tenant struct {
+	Namespace string   `yaml:"namespace"`
+	Tokens    []string `yaml:"tokens"`
+	Quota     Quota    `yaml:"quota"`
+}
+
+// Quota limits what a tenant may publish. Zero fields are unlimited.
+type Quota struct {
+	// MaxAgents caps the agents the namespace holds.
+	MaxAgents int `json:"max_agents,omitempty" yaml:"max_agents"`
+	// MaxManifestBytes caps each published manifest, below Options.Limits.
+	MaxManifestBytes int `json:"max_manifest_bytes,omitempty" yaml:"max_manifest_bytes"`
+}
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// LoadTenants reads a tenants file:
+//
+//	tenants:
+//	  - namespace: payments
+//	    tokens: [s3cr3t]
+//	    quota:
+//	      max_agents: 50
+//	      max_manifest_bytes: 65536
+func LoadTenants(path string) ([]Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	var file struct {
+		Tenants []Tenant `yaml:"tenants"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i, t := range file.Tenants {
+		switch {
+		case !namespacePattern.MatchString(t.Namespace):
+			return nil, fmt.Errorf("%s: tenants[%d]: invalid namespace %q", path, i, t.Namespace)
+		case seen[t.Namespace]:
+			return nil, fmt.Errorf("%s: tenants[%d]: duplicate namespace %s", path, i, t.Namespace)
+		case len(t.Tokens) == 0:
+			return nil, fmt.Errorf("%s: tenants[%d]: namespace %s has no tokens", path, i, t.Namespace)
+		}
+		seen[t.Namespace] = true
+	}
+	return file.Tenants, nil
+}
+
+// tenant returns the tenant owning namespace, or nil.
+func (s *Server) tenant(namespace string) *Tenant {
+	for i := range s.opts.Tenants {
+		if s.opts.Tenants[i].Namespace == namespace {
+			return &s.opts.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// namespaceOf returns the manifest's namespace, DefaultNamespace if unset.
+func namespaceOf(m *ossa.Manifest) string {
+	if m.Metadata.Namespace == "" {
+		return DefaultNamespace
+	}
+	return m.Metadata.Namespace
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/semver"
+)
+
+// versionsDir keeps a copy of each published semver release, as
+// {namespace}/{name}/{version}.ossa.yaml, so versioned refs can resolve
+// to older releases. ScanWorkspace skips it, being hidden.
+const versionsDir = ".versions"
+
+// archive keeps a copy of m under versionsDir if its metadata.version is a
+// semantic version. Publishing a version again replaces its copy.
+func (s *Server) archive(m *ossa.Manifest) error {
+	v, err := semver.Parse(m.Metadata.Version)
+	if err != nil {
+		return nil
+	}
+	path := filepath.Join(s.opts.Dir, versionsDir, namespaceOf(m), m.Metadata.Name, v.String()+".ossa.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := s.marshal(m, "yaml")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// versions returns the released versions of namespace/name, ascending: the
+// archived ones and that of the current manifest, if any. Without Dir only
+// the current manifest's is known.
+func (s *Server) versions(namespace, name string, current *ossa.Manifest) ([]semver.Version, error) {
+	seen := map[semver.Version]bool{}
+	if current != nil {
+		if v, err := semver.Parse(current.Metadata.Version); err == nil {
+			seen[v] = true
+		}
+	}
+	var files []os.DirEntry
+	if s.opts.Dir != "" {
+		var err error
+		files, err = os.ReadDir(filepath.Join(s.opts.Dir, versionsDir, namespace, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		if v, err := semver.Parse(strings.TrimSuffix(f.Name(), ".ossa.yaml")); err == nil {
+			seen[v] = true
+		}
+	}
+	out := make([]semver.Version, 0, len(seen))
+	for v := range seen {
+		out = append(out, v)
+	}
+	semver.Sort(out)
+	return out, nil
+}
+
+// handleVersions serves GET /api/agents/{namespace}/{name}/versions, the
+// released versions, and .../versions/{version}, one release. Releases
+// outlive an unpublish, so locked refs keep resolving.
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request, c *caller, namespace, name, version string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	if !c.allows(namespace) {
+		writeError(w, http.StatusForbidden, "no access to namespace "+namespace)
+		return
+	}
+	entries, err := s.catalog(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var current *ossa.CatalogEntry
+	for i, e := range entries {
+		if e.Name == name && namespaceOf(e.Manifest) == namespace {
+			current = &entries[i]
+		}
+	}
+
+	if version == "" {
+		var m *ossa.Manifest
+		if current != nil {
+			m = current.Manifest
+		}
+		versions, err := s.versions(namespace, name, m)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(versions) == 0 && current == nil {
+			writeError(w, http.StatusNotFound, "agent not found: "+namespace+"/"+name)
+			return
+		}
+		out := make([]string, len(versions))
+		for i, v := range versions {
+			out[i] = v.String()
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	v, err := semver.Parse(version)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Prefer the archived copy: the catalog's may carry another channel.
+	e, err := s.releaseEntry(namespace, name, v.String())
+	if err != nil {
+		if current == nil || current.Manifest.Metadata.Version != version {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		e = *current
+	}
+	result := ossa.ValidateManifest(e.Manifest)
+	writeJSON(w, http.StatusOK, AgentResponse{
+		AgentSummary: summarize(e),
+		Manifest:     e.Manifest,
+		Errors:       nonNil(result.Errors),
+		Warnings:     nonNil(result.Warnings),
+	})
+}
@@ -0,0 +1,97 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+function list(title, items, cls) {
+  if (!items.length) return "";
+  const li = items.map((s) => `<li>${escapeHTML(s)}</li>`).join("");
+  return `<h3 class="${cls}">${title}</h3><ul>${li}</ul>`;
+}
+
+function escapeHTML(s) {
+  return String(s).replace(/[&<>"']/g, (c) => ({ "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" })[c]);
+}
+
+// The token is kept in a cookie so agent pages, which are plain links,
+// send it too; API calls also send it as a bearer token.
+function token() {
+  const m = document.cookie.match(/(?:^|; )ossa_token=([^;]*)/);
+  return m ? decodeURIComponent(m[1]) : "";
+}
+
+function authHeaders(headers) {
+  const t = token();
+  return t ? { ...headers, Authorization: `Bearer ${t}` } : headers;
+}
+
+async function validate(text, type) {
+  $("result").textContent = "Validating…";
+  const res = await fetch("/api/validate", {
+    method: "POST",
+    headers: authHeaders({ "Content-Type": type || "application/yaml" }),
+    body: text,
+  });
+  const body = await res.json();
+  if (!res.ok) {
+    $("result").innerHTML = `<p class="bad">${escapeHTML(body.error)}</p>`;
+    return;
+  }
+  const name = body.name ? ` ${escapeHTML(body.name)}` : "";
+  const status = body.valid ? `<p class="good">✅${name} is valid</p>` : `<p class="bad">❌${name} is invalid</p>`;
+  $("result").innerHTML = status + list("Errors", body.errors, "bad") + list("Warnings", body.warnings, "warn");
+}
+
+function readFile(file) {
+  const reader = new FileReader();
+  reader.onload = () => {
+    $("source").value = reader.result;
+    validate(reader.result, file.name.endsWith(".json") ? "application/json" : "application/yaml");
+  };
+  reader.readAsText(file);
+}
+
+const drop = $("drop");
+drop.addEventListener("dragover", (e) => {
+  e.preventDefault();
+  drop.classList.add("over");
+});
+drop.addEventListener("dragleave", () => drop.classList.remove("over"));
+drop.addEventListener("drop", (e) => {
+  e.preventDefault();
+  drop.classList.remove("over");
+  if (e.dataTransfer.files.length) readFile(e.dataTransfer.files[0]);
+});
+$("file").addEventListener("change", (e) => {
+  if (e.target.files.length) readFile(e.target.files[0]);
+});
+$("check").addEventListener("click", () => validate($("source").value));
+
+let timer;
+async function loadAgents() {
+  const q = encodeURIComponent($("search").value);
+  const res = await fetch(`/api/agents?q=${q}`, { headers: authHeaders({}) });
+  const agents = res.ok ? await res.json() : [];
+  $("agents").innerHTML = agents
+    .map((a) => {
+      const path = `${encodeURIComponent(a.namespace)}/${encodeURIComponent(a.name)}`;
+      const modified = new Date(a.modified).toLocaleString();
+      const valid = a.valid ? "" : `<span class="bad">invalid</span>`;
+      return `<tr><td><a href="/agents/${path}">${escapeHTML(a.name)}</a></td><td>${escapeHTML(a.namespace)}</td>` +
+        `<td>${escapeHTML(a.kind)}</td>` +
+        `<td>${escapeHTML(a.tier || "-")}</td><td>${escapeHTML(a.model || "-")}</td><td>${a.tools}</td>` +
+        `<td>${escapeHTML(modified)}</td><td>${valid}</td></tr>`;
+    })
+    .join("");
+  $("empty").hidden = agents.length > 0;
+  $("empty").textContent = res.status === 401 ? "Enter a token to see agents." : "No agents found.";
+}
+$("search").addEventListener("input", () => {
+  clearTimeout(timer);
+  timer = setTimeout(loadAgents, 200);
+});
+$("token").value = token();
+$("token").addEventListener("change", (e) => {
+  document.cookie = `ossa_token=${encodeURIComponent(e.target.value)}; path=/; SameSite=Strict`;
+  loadAgents();
+});
+loadAgents();
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLangfuseHost is Langfuse Cloud.
+const DefaultLangfuseHost = "https://cloud.langfuse.com"
+
+// Langfuse exports traces and scores to Langfuse's ingestion API: a trace
+// per root span with its spans as observations, LLM spans as generations
+// with their model, parameters and usage.
+type Langfuse struct {
+	// Host is the Langfuse URL; empty means DefaultLangfuseHost.
+	Host      string
+	PublicKey string
+	SecretKey string
+	// Client performs requests; nil means a client with a 10s timeout.
+	Client *http.Client
+}
+
+// LangfuseFromEnv configures Langfuse from LANGFUSE_PUBLIC_KEY,
+// LANGFUSE_SECRET_KEY and LANGFUSE_HOST, as Langfuse's own SDKs do.
+// lookup defaults to os.LookupEnv.
+func LangfuseFromEnv(lookup func(key string) (string, bool)) (*Langfuse, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	l := &Langfuse{}
+	l.PublicKey, _ = lookup("LANGFUSE_PUBLIC_KEY")
+	l.SecretKey, _ = lookup("LANGFUSE_SECRET_KEY")
+	l.Host, _ = lookup("LANGFUSE_HOST")
+	if l.PublicKey == "" || l.SecretKey == "" {
+		return nil, fmt.Errorf("LANGFUSE_PUBLIC_KEY and LANGFUSE_SECRET_KEY must be set")
+	}
+	return l, nil
+}
+
+// Export sends the trace of spans as one ingestion batch.
+func (l *Langfuse) Export(ctx context.Context, spans []*Span) error {
+	var batch []map[string]interface{}
+	for _, s := range spans {
+		if s.ParentID == "" {
+			trace := map[string]interface{}{
+				"id":        s.TraceID,
+				"name":      s.Name,
+				"timestamp": isoTime(s.Start),
+				"metadata":  manifestMetadata(s),
+				"input":     s.Input,
+				"output":    s.Output,
+			}
+			if v, ok := s.Attributes[AttrManifestVersion].(string); ok {
+				trace["version"] = v
+			}
+			batch = append(batch, l.event("trace-create", trace))
+		}
+		obs := map[string]interface{}{
+			"id":        s.SpanID,
+			"traceId":   s.TraceID,
+			"name":      s.Name,
+			"startTime": isoTime(s.Start),
+			"endTime":   isoTime(s.End),
+			"metadata":  manifestMetadata(s),
+			"input":     s.Input,
+			"output":    s.Output,
+		}
+		if s.ParentID != "" {
+			obs["parentObservationId"] = s.ParentID
+		}
+		if s.Error != "" {
+			obs["level"], obs["statusMessage"] = "ERROR", s.Error
+		}
+		kind := "span-create"
+		if s.Kind == KindLLM {
+			kind = "generation-create"
+			obs["model"] = s.Attributes[attrModelName]
+			if params := invocationParameters(s); params != nil {
+				obs["modelParameters"] = params
+			}
+			if u := s.Usage; u != nil {
+				obs["usage"] = map[string]interface{}{"input": u.InputTokens, "output": u.OutputTokens, "total": u.InputTokens + u.OutputTokens, "unit": "TOKENS"}
+			}
+		}
+		batch = append(batch, l.event(kind, obs))
+	}
+	return l.ingest(ctx, batch)
+}
+
+// ExportScores sends scores as score events.
+func (l *Langfuse) ExportScores(ctx context.Context, scores []Score) error {
+	batch := make([]map[string]interface{}, len(scores))
+	for i, s := range scores {
+		score := map[string]interface{}{"id": newID(16), "traceId": s.TraceID, "name": s.Name, "value": s.Value}
+		if s.SpanID != "" {
+			score["observationId"] = s.SpanID
+		}
+		if s.Comment != "" {
+			score["comment"] = s.Comment
+		}
+		batch[i] = l.event("score-create", score)
+	}
+	return l.ingest(ctx, batch)
+}
+
+func (l *Langfuse) event(kind string, body map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"id": newID(16), "timestamp": isoTime(time.Now()), "type": kind, "body": body}
+}
+
+// ingest posts batch, failing if Langfuse rejects any of its events: the
+// API answers 207 with the events that failed.
+func (l *Langfuse) ingest(ctx context.Context, batch []map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"batch": batch})
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+	host := l.Host
+	if host == "" {
+		host = DefaultLangfuseHost
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(l.PublicKey + ":" + l.SecretKey))
+	data, err := postJSON(ctx, l.Client, strings.TrimSuffix(host, "/")+"/api/public/ingestion", map[string]string{"Authorization": "Basic " + auth}, body)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Errors []struct {
+			ID      string      `json:"id"`
+			Status  int         `json:"status"`
+			Message interface{} `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &resp) == nil && len(resp.Errors) > 0 {
+		e := resp.Errors[0]
+		return fmt.Errorf("langfuse rejected %d of %d events: %d %v", len(resp.Errors), len(batch), e.Status, e.Message)
+	}
+	return nil
+}
+
+// manifestMetadata is the manifest attributes of s.
+func manifestMetadata(s *Span) map[string]interface{} {
+	meta := map[string]interface{}{}
+	for _, k := range []string{AttrManifestName, AttrManifestVersion, AttrManifestKind, AttrManifestDigest} {
+		if v, ok := s.Attributes[k]; ok {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+// invocationParameters decodes the llm.invocation_parameters of s.
+func invocationParameters(s *Span) map[string]interface{} {
+	raw, ok := s.Attributes[attrInvocationParameters].(string)
+	if !ok {
+		return nil
+	}
+	var params map[string]interface{}
+	json.Unmarshal([]byte(raw), &params)
+	return params
+}
+
+func isoTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
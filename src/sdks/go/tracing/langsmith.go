@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLangSmithEndpoint is LangSmith's API.
+const DefaultLangSmithEndpoint = "https://api.smith.langchain.com"
+
+// DefaultLangSmithProject is the project runs go to without a Project.
+const DefaultLangSmithProject = "default"
+
+// LangSmith exports traces to LangSmith as runs, and scores as feedback.
+// Agent and workflow spans are chain runs, LLM spans llm runs with their
+// usage, and tool spans tool runs. Run IDs are UUIDs derived from span
+// IDs; a trace's root run has the trace's.
+type LangSmith struct {
+	// Endpoint is the API URL; empty means DefaultLangSmithEndpoint.
+	Endpoint string
+	APIKey   string
+	// Project is the LangSmith project; empty means
+	// DefaultLangSmithProject.
+	Project string
+	// Client performs requests; nil means a client with a 10s timeout.
+	Client *http.Client
+}
+
+// LangSmithFromEnv configures LangSmith from LANGSMITH_API_KEY,
+// LANGSMITH_ENDPOINT and LANGSMITH_PROJECT, or their older LANGCHAIN_
+// names, as LangSmith's own SDKs do. lookup defaults to os.LookupEnv.
+func LangSmithFromEnv(lookup func(key string) (string, bool)) (*LangSmith, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	env := func(name string) string {
+		if v, ok := lookup("LANGSMITH_" + name); ok && v != "" {
+			return v
+		}
+		v, _ := lookup("LANGCHAIN_" + name)
+		return v
+	}
+	l := &LangSmith{APIKey: env("API_KEY"), Endpoint: env("ENDPOINT"), Project: env("PROJECT")}
+	if l.APIKey == "" {
+		return nil, fmt.Errorf("LANGSMITH_API_KEY must be set")
+	}
+	return l, nil
+}
+
+// Export posts the trace of spans as one batch of runs.
+func (l *LangSmith) Export(ctx context.Context, spans []*Span) error {
+	byID := make(map[string]*Span, len(spans))
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	project := l.Project
+	if project == "" {
+		project = DefaultLangSmithProject
+	}
+	runs := make([]map[string]interface{}, len(spans))
+	for i, s := range spans {
+		meta := manifestMetadata(s)
+		extra := map[string]interface{}{"metadata": meta}
+		run := map[string]interface{}{
+			"id":           runID(s),
+			"trace_id":     uuidOf(s.TraceID),
+			"dotted_order": dottedOrder(s, byID),
+			"name":         s.Name,
+			"run_type":     "chain",
+			"start_time":   isoTime(s.Start),
+			"end_time":     isoTime(s.End),
+			"inputs":       runIO("input", s.Input),
+			"session_name": project,
+			"extra":        extra,
+		}
+		if s.ParentID != "" {
+			if parent, ok := byID[s.ParentID]; ok {
+				run["parent_run_id"] = runID(parent)
+			}
+		}
+		if s.Error != "" {
+			run["error"] = s.Error
+		}
+		outputs := runIO("output", s.Output)
+		switch s.Kind {
+		case KindLLM:
+			run["run_type"] = "llm"
+			run["inputs"] = map[string]interface{}{"messages": s.Input}
+			outputs = map[string]interface{}{"choices": []interface{}{map[string]interface{}{"message": s.Output}}}
+			meta["ls_provider"] = s.Attributes[attrProvider]
+			meta["ls_model_name"] = s.Attributes[attrModelName]
+			if params := invocationParameters(s); params != nil {
+				extra["invocation_params"] = params
+			}
+		case KindTool:
+			run["run_type"] = "tool"
+		}
+		if u := s.Usage; u != nil {
+			outputs["usage_metadata"] = map[string]interface{}{"input_tokens": u.InputTokens, "output_tokens": u.OutputTokens, "total_tokens": u.InputTokens + u.OutputTokens}
+		}
+		run["outputs"] = outputs
+		runs[i] = run
+	}
+	body, err := json.Marshal(map[string]interface{}{"post": runs})
+	if err != nil {
+		return fmt.Errorf("failed to encode trace: %w", err)
+	}
+	_, err = postJSON(ctx, l.Client, l.url("/runs/batch"), l.headers(), body)
+	return err
+}
+
+// ExportScores posts each score as feedback on its run.
+func (l *LangSmith) ExportScores(ctx context.Context, scores []Score) error {
+	for _, s := range scores {
+		id := uuidOf(s.TraceID)
+		if s.SpanID != "" {
+			id = uuidOf(s.TraceID + ":" + s.SpanID)
+		}
+		feedback := map[string]interface{}{"run_id": id, "trace_id": uuidOf(s.TraceID), "key": s.Name, "score": s.Value}
+		if s.Comment != "" {
+			feedback["comment"] = s.Comment
+		}
+		body, err := json.Marshal(feedback)
+		if err != nil {
+			return err
+		}
+		if _, err := postJSON(ctx, l.Client, l.url("/feedback"), l.headers(), body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LangSmith) url(path string) string {
+	endpoint := l.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultLangSmithEndpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + path
+}
+
+func (l *LangSmith) headers() map[string]string {
+	return map[string]string{"x-api-key": l.APIKey}
+}
+
+// runIO is v as the object LangSmith wants for inputs and outputs: v
+// itself if it is one, else {key: v}.
+func runIO(key string, v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[k] = v
+		}
+		return out
+	}
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{key: v}
+}
+
+// runID is the run UUID of s: the trace's for a root span, else one from
+// the trace and span IDs.
+func runID(s *Span) string {
+	if s.ParentID == "" {
+		return uuidOf(s.TraceID)
+	}
+	return uuidOf(s.TraceID + ":" + s.SpanID)
+}
+
+// dottedOrder is LangSmith's ordering key of s: its start time and run ID,
+// after its parent's.
+func dottedOrder(s *Span, byID map[string]*Span) string {
+	t := s.Start.UTC()
+	own := t.Format("20060102T150405") + fmt.Sprintf("%06dZ", t.Nanosecond()/int(time.Microsecond)) + runID(s)
+	if parent, ok := byID[s.ParentID]; ok && s.ParentID != "" {
+		return dottedOrder(parent, byID) + "." + own
+	}
+	return own
+}
+
+// uuidOf formats id as a UUID if it is 32 hex digits, else a UUID hashed
+// from it.
+func uuidOf(id string) string {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != 16 {
+		sum := sha256.Sum256([]byte(id))
+		b = sum[:16]
+		b[6] = b[6]&0x0f | 0x50
+		b[8] = b[8]&0x3f | 0x80
+	}
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,267 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+)
+
+// Span attributes identifying the manifest, on every span of its run.
+const (
+	AttrManifestName    = "ossa.manifest.name"
+	AttrManifestVersion = "ossa.manifest.version"
+	AttrManifestKind    = "ossa.manifest.kind"
+	// AttrManifestDigest is "sha256:" and resolve.Digest of the manifest.
+	AttrManifestDigest = "ossa.manifest.digest"
+)
+
+// OpenInference attributes.
+const (
+	attrSpanKind             = "openinference.span.kind"
+	attrAgentName            = "agent.name"
+	attrInputValue           = "input.value"
+	attrInputMIMEType        = "input.mime_type"
+	attrOutputValue          = "output.value"
+	attrOutputMIMEType       = "output.mime_type"
+	attrModelName            = "llm.model_name"
+	attrProvider             = "llm.provider"
+	attrInvocationParameters = "llm.invocation_parameters"
+	attrInputMessages        = "llm.input_messages"
+	attrOutputMessages       = "llm.output_messages"
+	attrTools                = "llm.tools"
+	attrPromptTokens         = "llm.token_count.prompt"
+	attrCompletionTokens     = "llm.token_count.completion"
+	attrTotalTokens          = "llm.token_count.total"
+	attrToolName             = "tool.name"
+	attrToolDescription      = "tool.description"
+	attrToolParameters       = "tool.parameters"
+	attrToolCallID           = "tool_call.id"
+)
+
+// OpenLLMetry (gen_ai) attributes.
+const (
+	attrGenAISystem        = "gen_ai.system"
+	attrGenAIRequestModel  = "gen_ai.request.model"
+	attrGenAIResponseModel = "gen_ai.response.model"
+	attrGenAIInputTokens   = "gen_ai.usage.input_tokens"
+	attrGenAIOutputTokens  = "gen_ai.usage.output_tokens"
+)
+
+// providers maps OSSA providers to OpenInference's llm.provider values
+// where they differ.
+var providers = map[string]string{
+	"bedrock": "aws",
+	"vertex":  "google",
+	"gemini":  "google",
+}
+
+// model traces the turns of a Model.
+type model struct {
+	engine.Model
+	t *Tracer
+}
+
+func (m *model) Complete(ctx context.Context, req *engine.Request) (*engine.Response, error) {
+	if !inRun(ctx) {
+		return m.Model.Complete(ctx, req)
+	}
+	ctx, span := m.t.start(ctx, llmSpanName(req), KindLLM, nil)
+	setRequest(span, req)
+	resp, err := m.Model.Complete(ctx, req)
+	setResponse(span, resp)
+	m.t.end(ctx, span, err)
+	return resp, err
+}
+
+// streamingModel traces the turns of a StreamingModel.
+type streamingModel struct {
+	model
+	s engine.StreamingModel
+}
+
+func (m *streamingModel) Stream(ctx context.Context, req *engine.Request, onText func(string)) (*engine.Response, error) {
+	if !inRun(ctx) {
+		return m.s.Stream(ctx, req, onText)
+	}
+	ctx, span := m.t.start(ctx, llmSpanName(req), KindLLM, nil)
+	setRequest(span, req)
+	resp, err := m.s.Stream(ctx, req, onText)
+	setResponse(span, resp)
+	m.t.end(ctx, span, err)
+	return resp, err
+}
+
+// tools traces the tool calls exec runs for m.
+func (t *Tracer) tools(m *ossa.Manifest, exec ossa.ToolExecFunc) ossa.ToolExecFunc {
+	if exec == nil {
+		return nil
+	}
+	return func(ctx context.Context, call ossa.ToolCall) ([]byte, error) {
+		if !inRun(ctx) {
+			return exec(ctx, call)
+		}
+		ctx, span := t.start(ctx, call.Tool, KindTool, nil)
+		span.Attributes[attrToolName] = call.Tool
+		span.Attributes[attrToolCallID] = call.ID
+		for _, tool := range m.Spec.Tools {
+			if tool.Name == call.Tool {
+				if tool.Description != "" {
+					span.Attributes[attrToolDescription] = tool.Description
+				}
+				if tool.Parameters != nil {
+					setJSON(span, attrToolParameters, "", tool.Parameters)
+				}
+			}
+		}
+		setInput(span, call.Arguments)
+		out, err := exec(ctx, call)
+		if err == nil {
+			span.Output = string(out)
+			span.Attributes[attrOutputValue] = string(out)
+			if json.Valid(out) {
+				span.Attributes[attrOutputMIMEType] = "application/json"
+			} else {
+				span.Attributes[attrOutputMIMEType] = "text/plain"
+			}
+		}
+		t.end(ctx, span, err)
+		return out, err
+	}
+}
+
+func llmSpanName(req *engine.Request) string {
+	if req.LLM == nil || req.LLM.Model == "" {
+		return "chat"
+	}
+	return "chat " + req.LLM.Model
+}
+
+// setRequest sets the OpenInference attributes of a model turn's request.
+func setRequest(span *Span, req *engine.Request) {
+	if llm := req.LLM; llm != nil {
+		provider := llm.Provider
+		if p, ok := providers[provider]; ok {
+			provider = p
+		}
+		span.Attributes[attrModelName] = llm.Model
+		span.Attributes[attrProvider] = provider
+		span.Attributes[attrGenAISystem] = llm.Provider
+		span.Attributes[attrGenAIRequestModel] = llm.Model
+		params := map[string]interface{}{}
+		if llm.Temperature != 0 {
+			params["temperature"] = llm.Temperature
+		}
+		if llm.MaxTokens != 0 {
+			params["max_tokens"] = llm.MaxTokens
+		}
+		if llm.TopP != 0 {
+			params["top_p"] = llm.TopP
+		}
+		if len(llm.Stop) > 0 {
+			params["stop"] = llm.Stop
+		}
+		if len(params) > 0 {
+			setJSON(span, attrInvocationParameters, "", params)
+		}
+	}
+	messages := make([]map[string]interface{}, len(req.Messages))
+	for i, msg := range req.Messages {
+		setMessage(span, fmt.Sprintf("%s.%d.message", attrInputMessages, i), msg.Role, msg.Content, msg.ToolCallID, msg.ToolCalls)
+		messages[i] = openAIMessage(msg.Role, msg.Content, msg.ToolCallID, msg.ToolCalls)
+	}
+	span.Input = messages
+	n := 0
+	for _, tool := range req.Tools {
+		if tool.Name == "" {
+			continue
+		}
+		setJSON(span, fmt.Sprintf("%s.%d.tool.json_schema", attrTools, n), "", map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": tool.Name, "description": tool.Description, "parameters": tool.Parameters},
+		})
+		n++
+	}
+}
+
+// setResponse sets the attributes of a model turn's answer.
+func setResponse(span *Span, resp *engine.Response) {
+	if resp == nil {
+		return
+	}
+	setMessage(span, attrOutputMessages+".0.message", engine.RoleAssistant, resp.Content, "", resp.ToolCalls)
+	span.Output = openAIMessage(engine.RoleAssistant, resp.Content, "", resp.ToolCalls)
+	if resp.Model != "" {
+		span.Attributes[attrGenAIResponseModel] = resp.Model
+		span.Attributes[attrModelName] = resp.Model
+	}
+	setUsage(span, resp.Usage)
+}
+
+func setMessage(span *Span, prefix, role, content, toolCallID string, calls []ossa.ToolCall) {
+	span.Attributes[prefix+".role"] = role
+	if content != "" {
+		span.Attributes[prefix+".content"] = content
+	}
+	if toolCallID != "" {
+		span.Attributes[prefix+".tool_call_id"] = toolCallID
+	}
+	for j, c := range calls {
+		call := fmt.Sprintf("%s.tool_calls.%d.tool_call", prefix, j)
+		span.Attributes[call+".id"] = c.ID
+		span.Attributes[call+".function.name"] = c.Tool
+		setJSON(span, call+".function.arguments", "", c.Arguments)
+	}
+}
+
+// openAIMessage is a message in the shape of OpenAI's chat API, which
+// trace UIs render as a conversation.
+func openAIMessage(role, content, toolCallID string, calls []ossa.ToolCall) map[string]interface{} {
+	msg := map[string]interface{}{"role": role, "content": content}
+	if toolCallID != "" {
+		msg["tool_call_id"] = toolCallID
+	}
+	if len(calls) > 0 {
+		toolCalls := make([]interface{}, len(calls))
+		for i, c := range calls {
+			args, _ := json.Marshal(c.Arguments)
+			toolCalls[i] = map[string]interface{}{"id": c.ID, "type": "function", "function": map[string]interface{}{"name": c.Tool, "arguments": string(args)}}
+		}
+		msg["tool_calls"] = toolCalls
+	}
+	return msg
+}
+
+func setInput(span *Span, v interface{}) {
+	span.Input = v
+	setJSON(span, attrInputValue, attrInputMIMEType, v)
+}
+
+func setOutput(span *Span, v interface{}) {
+	span.Output = v
+	setJSON(span, attrOutputValue, attrOutputMIMEType, v)
+}
+
+func setUsage(span *Span, u engine.Usage) {
+	span.Usage = &u
+	span.Attributes[attrPromptTokens] = u.InputTokens
+	span.Attributes[attrCompletionTokens] = u.OutputTokens
+	span.Attributes[attrTotalTokens] = u.InputTokens + u.OutputTokens
+	span.Attributes[attrGenAIInputTokens] = u.InputTokens
+	span.Attributes[attrGenAIOutputTokens] = u.OutputTokens
+}
+
+// setJSON sets key to v as JSON and, if mimeKey is set, mimeKey to its
+// media type.
+func setJSON(span *Span, key, mimeKey string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	span.Attributes[key] = string(data)
+	if mimeKey != "" {
+		span.Attributes[mimeKey] = "application/json"
+	}
+}
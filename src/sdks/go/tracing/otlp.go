@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultService is the service.name OTLP reports without a Service.
+const DefaultService = "ossa"
+
+// OTLP exports traces over OTLP/HTTP with JSON encoding, which Phoenix,
+// Langfuse, Arize and OpenTelemetry collectors accept.
+type OTLP struct {
+	// Endpoint is the collector's base URL, such as http://localhost:6006
+	// for Phoenix; traces are posted to Endpoint/v1/traces. A URL already
+	// ending in /v1/traces is used as is.
+	Endpoint string
+	// Headers are sent with each export, such as Authorization for
+	// Langfuse or api_key for Arize.
+	Headers map[string]string
+	// Service is the service.name resource attribute; empty means
+	// DefaultService.
+	Service string
+	// Client performs requests; nil means a client with a 10s timeout.
+	Client *http.Client
+}
+
+// Export posts spans as one OTLP ExportTraceServiceRequest.
+func (o *OTLP) Export(ctx context.Context, spans []*Span) error {
+	service := o.Service
+	if service == "" {
+		service = DefaultService
+	}
+	otlpSpans := make([]map[string]interface{}, len(spans))
+	for i, s := range spans {
+		otlpSpans[i] = otlpSpan(s)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": otlpAttributes(map[string]interface{}{"service.name": service})},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/blueflyio/ossa-go/tracing"},
+				"spans": otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	url := strings.TrimSuffix(o.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	_, err = postJSON(ctx, o.Client, url, o.Headers, body)
+	return err
+}
+
+// postJSON posts body to url, returning the response body or an error for
+// a status other than 2xx. A nil client means one with a 10s timeout.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := string(data)
+		if len(msg) > 1024 {
+			msg = msg[:1024]
+		}
+		return nil, fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(msg))
+	}
+	return data, nil
+}
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindClient   = 3
+	otlpStatusOK     = 1
+	otlpStatusError  = 2
+)
+
+func otlpSpan(s *Span) map[string]interface{} {
+	kind := otlpKindInternal
+	if s.Kind == KindLLM {
+		kind = otlpKindClient
+	}
+	status := map[string]interface{}{"code": otlpStatusOK}
+	if s.Error != "" {
+		status = map[string]interface{}{"code": otlpStatusError, "message": s.Error}
+	}
+	span := map[string]interface{}{
+		"traceId":           s.TraceID,
+		"spanId":            s.SpanID,
+		"name":              s.Name,
+		"kind":              kind,
+		"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+		"attributes":        otlpAttributes(s.Attributes),
+		"status":            status,
+	}
+	if s.ParentID != "" {
+		span["parentSpanId"] = s.ParentID
+	}
+	return span
+}
+
+// otlpAttributes encodes attrs as OTLP KeyValues, sorted by key.
+func otlpAttributes(attrs map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		var value map[string]interface{}
+		switch v := attrs[k].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]interface{}{"key": k, "value": value})
+	}
+	return out
+}
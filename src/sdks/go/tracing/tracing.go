@@ -0,0 +1,267 @@
+// Package tracing traces agent runs as OpenTelemetry spans in the
+// OpenInference semantic conventions, so LLM observability tools such as
+// Phoenix, Arize and Langfuse take OSSA traces without custom mapping.
+//
+// A Tracer instruments an engine.Engine: each agent run is an AGENT span,
+// each model turn an LLM span and each tool call a TOOL span under it,
+// and StartWorkflow adds a CHAIN span over a workflow's agents. Every span
+// carries the manifest's name, version and digest, and the common gen_ai
+// attributes of OpenLLMetry are set beside OpenInference's. A trace is
+// exported when its root span ends: OTLP posts it to a collector, and
+// Langfuse and LangSmith to those services' APIs, which also take scores.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+)
+
+// OpenInference span kinds.
+const (
+	KindAgent = "AGENT"
+	KindChain = "CHAIN"
+	KindLLM   = "LLM"
+	KindTool  = "TOOL"
+)
+
+// Span is one traced operation.
+type Span struct {
+	TraceID string
+	SpanID  string
+	// ParentID is empty for the root span of a trace.
+	ParentID string
+	Name     string
+	// Kind is the OpenInference span kind.
+	Kind  string
+	Start time.Time
+	End   time.Time
+	// Attributes are strings, ints, float64s or bools.
+	Attributes map[string]interface{}
+	// Input and Output are what the operation took and gave, for
+	// exporters with structured fields: the run's input and output, the
+	// messages sent and the answer in OpenAI's shape, or the tool call's
+	// arguments and output.
+	Input  interface{}
+	Output interface{}
+	// Usage is the tokens of an agent, workflow or LLM span.
+	Usage *engine.Usage
+	// Error is the operation's error, if it failed.
+	Error string
+}
+
+// Score is a rating of a trace or one of its spans, such as an eval
+// result or user feedback.
+type Score struct {
+	TraceID string
+	// SpanID is the span rated, other than the root; empty rates the
+	// trace.
+	SpanID  string
+	Name    string
+	Value   float64
+	Comment string
+}
+
+// Exporter sends finished traces somewhere.
+type Exporter interface {
+	// Export sends the spans of one trace, in the order they ended.
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// ScoreExporter is an Exporter that also takes scores, as Langfuse and
+// LangSmith do.
+type ScoreExporter interface {
+	Exporter
+	ExportScores(ctx context.Context, scores []Score) error
+}
+
+// Tracer records spans for runs of an instrumented engine and exports
+// each trace to Exporter. It is safe for concurrent use.
+type Tracer struct {
+	Exporter Exporter
+
+	mu     sync.Mutex
+	traces map[string]*trace
+}
+
+// trace collects the spans of a trace until its root span ends.
+type trace struct {
+	spans []*Span
+}
+
+type (
+	spanKey    struct{}
+	traceIDKey struct{}
+)
+
+// current is the span of a context and the manifest attributes its
+// children inherit.
+type current struct {
+	span     *Span
+	manifest map[string]interface{}
+}
+
+// Instrument sets e.Trace and wraps e.Model and e.Tools so e's agent runs,
+// model turns and tool calls are traced. Model and tool calls outside an
+// agent run are not.
+func (t *Tracer) Instrument(e *engine.Engine) {
+	e.Trace = t.startAgent
+	if s, ok := e.Model.(engine.StreamingModel); ok {
+		e.Model = &streamingModel{model{s, t}, s}
+	} else if e.Model != nil {
+		e.Model = &model{e.Model, t}
+	}
+	if tools := e.Tools; tools != nil {
+		e.Tools = func(m *ossa.Manifest) ossa.ToolExecFunc {
+			return t.tools(m, tools(m))
+		}
+	}
+}
+
+// StartWorkflow begins a CHAIN span for a run of w. Running w with the
+// context it returns on an engine Instrument has instrumented makes its
+// agents one trace; end is called with the run's outcome.
+func (t *Tracer) StartWorkflow(ctx context.Context, w *engine.Workflow, input map[string]interface{}) (context.Context, func(res *engine.WorkflowResult, err error)) {
+	attrs := map[string]interface{}{AttrManifestName: w.Name, AttrManifestKind: string(ossa.KindWorkflow)}
+	if w.Version != "" {
+		attrs[AttrManifestVersion] = w.Version
+	}
+	ctx, span := t.start(ctx, w.Name, KindChain, attrs)
+	setInput(span, input)
+	return ctx, func(res *engine.WorkflowResult, err error) {
+		if res != nil {
+			setOutput(span, res.Output)
+			setUsage(span, res.Usage)
+		}
+		t.end(ctx, span, err)
+	}
+}
+
+func (t *Tracer) startAgent(ctx context.Context, m *ossa.Manifest, input map[string]interface{}) (context.Context, func(*engine.Result, error)) {
+	ctx, span := t.start(ctx, m.Metadata.Name, KindAgent, manifestAttributes(m))
+	span.Attributes[attrAgentName] = m.Metadata.Name
+	setInput(span, input)
+	return ctx, func(res *engine.Result, err error) {
+		if res != nil {
+			setOutput(span, res.Output)
+			setUsage(span, res.Usage)
+		}
+		t.end(ctx, span, err)
+	}
+}
+
+// start begins a span under the span of ctx, if any, with the manifest
+// attributes given or else inherited.
+func (t *Tracer) start(ctx context.Context, name, kind string, manifest map[string]interface{}) (context.Context, *Span) {
+	span := &Span{SpanID: newID(8), Name: name, Kind: kind, Start: time.Now(), Attributes: map[string]interface{}{attrSpanKind: kind}}
+	if parent, ok := ctx.Value(spanKey{}).(*current); ok {
+		span.TraceID, span.ParentID = parent.span.TraceID, parent.span.SpanID
+		if manifest == nil {
+			manifest = parent.manifest
+		}
+	} else if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		span.TraceID = id
+	} else {
+		span.TraceID = NewTraceID()
+	}
+	for k, v := range manifest {
+		span.Attributes[k] = v
+	}
+	t.mu.Lock()
+	if t.traces == nil {
+		t.traces = map[string]*trace{}
+	}
+	if t.traces[span.TraceID] == nil {
+		t.traces[span.TraceID] = &trace{}
+	}
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, &current{span, manifest}), span
+}
+
+// end finishes span and, if it is the root of its trace, exports the
+// trace. Export failures are logged, not returned: tracing never fails a
+// run.
+func (t *Tracer) end(ctx context.Context, span *Span, err error) {
+	span.End = time.Now()
+	if err != nil {
+		span.Error = err.Error()
+	}
+	t.mu.Lock()
+	tr := t.traces[span.TraceID]
+	tr.spans = append(tr.spans, span)
+	if span.ParentID != "" {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.traces, span.TraceID)
+	t.mu.Unlock()
+	if t.Exporter == nil {
+		return
+	}
+	if err := t.Exporter.Export(context.WithoutCancel(ctx), tr.spans); err != nil {
+		ossa.Logger().Warn("failed to export trace", "trace", span.TraceID, "spans", len(tr.spans), "error", err)
+	}
+}
+
+// Score sends s to the Exporter, which must be a ScoreExporter.
+func (t *Tracer) Score(ctx context.Context, s Score) error {
+	exp, ok := t.Exporter.(ScoreExporter)
+	if !ok {
+		return fmt.Errorf("the trace exporter does not take scores")
+	}
+	return exp.ExportScores(ctx, []Score{s})
+}
+
+// NewTraceID returns a random trace ID: 32 hex digits.
+func NewTraceID() string {
+	return newID(16)
+}
+
+// WithTraceID returns ctx making id, such as from NewTraceID, the trace ID
+// of the next run started with it, so the run can be scored afterwards.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceID returns the trace ID of the span ctx is in, such as in a tool
+// runtime, or "" outside a traced run.
+func TraceID(ctx context.Context) string {
+	if c, ok := ctx.Value(spanKey{}).(*current); ok {
+		return c.span.TraceID
+	}
+	return ""
+}
+
+// inRun reports whether ctx is that of a traced run.
+func inRun(ctx context.Context) bool {
+	_, ok := ctx.Value(spanKey{}).(*current)
+	return ok
+}
+
+// manifestAttributes identifies m on its spans.
+func manifestAttributes(m *ossa.Manifest) map[string]interface{} {
+	attrs := map[string]interface{}{
+		AttrManifestName: m.Metadata.Name,
+		AttrManifestKind: string(m.Kind),
+	}
+	if m.Metadata.Version != "" {
+		attrs[AttrManifestVersion] = m.Metadata.Version
+	}
+	if digest, err := resolve.Digest(m); err == nil {
+		attrs[AttrManifestDigest] = "sha256:" + digest
+	}
+	return attrs
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,279 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blueflyio/ossa-go/engine"
+	"github.com/blueflyio/ossa-go/ossa"
+	"github.com/blueflyio/ossa-go/resolve"
+)
+
+type modelFunc func(req *engine.Request) (*engine.Response, error)
+
+func (f modelFunc) Complete(_ context.Context, req *engine.Request) (*engine.Response, error) {
+	return f(req)
+}
+
+func lookupThenAnswer(req *engine.Request) (*engine.Response, error) {
+	if req.Messages[len(req.Messages)-1].Role == engine.RoleUser {
+		return &engine.Response{ToolCalls: []ossa.ToolCall{{ID: "c1", Tool: "lookup", Arguments: map[string]interface{}{"ticket": 42}}}, Usage: engine.Usage{InputTokens: 5}}, nil
+	}
+	return &engine.Response{Content: `{"priority": "high"}`, Usage: engine.Usage{InputTokens: 7, OutputTokens: 2}, Model: "gpt-4o-2024-08-06"}, nil
+}
+
+type recorder struct {
+	traces [][]*Span
+}
+
+func (r *recorder) Export(_ context.Context, spans []*Span) error {
+	r.traces = append(r.traces, spans)
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	rec := &recorder{}
+	tracer := &Tracer{Exporter: rec}
+	e := &engine.Engine{
+		Model: modelFunc(lookupThenAnswer),
+		Tools: func(*ossa.Manifest) ossa.ToolExecFunc {
+			return func(context.Context, ossa.ToolCall) ([]byte, error) { return []byte(`{"title": "broken"}`), nil }
+		},
+	}
+	tracer.Instrument(e)
+	m := ossa.NewManifest("triage", ossa.KindAgent)
+	m.Metadata.Version = "1.0.0"
+	m.Spec.LLM = &ossa.LLMConfig{Provider: "openai", Model: "gpt-4o", Temperature: 0.2}
+	m.Spec.Tools = []ossa.ToolConfig{{Type: "function", Name: "lookup", Description: "Look a ticket up"}}
+	if _, err := e.RunAgent(context.Background(), m, map[string]interface{}{"ticket": 42}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.traces) != 1 || len(rec.traces[0]) != 4 {
+		t.Fatalf("Expected one trace of 4 spans, got %v", rec.traces)
+	}
+	spans := rec.traces[0]
+	kinds := []string{}
+	for _, s := range spans {
+		kinds = append(kinds, s.Kind)
+	}
+	if strings.Join(kinds, " ") != "LLM TOOL LLM AGENT" {
+		t.Errorf("Unexpected spans %v", kinds)
+	}
+	root := spans[3]
+	digest, _ := resolve.Digest(m)
+	for _, s := range spans {
+		if s.TraceID != root.TraceID || (s != root && s.ParentID != root.SpanID) {
+			t.Errorf("Expected %s under the agent span, got %+v", s.Name, s)
+		}
+		if s.Attributes[AttrManifestName] != "triage" || s.Attributes[AttrManifestVersion] != "1.0.0" || s.Attributes[AttrManifestDigest] != "sha256:"+digest {
+			t.Errorf("Expected the manifest on %s, got %v", s.Name, s.Attributes)
+		}
+	}
+	if root.Attributes["output.value"] != `{"priority":"high"}` || root.Attributes["llm.token_count.total"] != 14 {
+		t.Errorf("Unexpected agent attributes %v", root.Attributes)
+	}
+	llm := spans[2].Attributes
+	want := map[string]interface{}{
+		"openinference.span.kind":           "LLM",
+		"llm.model_name":                    "gpt-4o-2024-08-06",
+		"llm.provider":                      "openai",
+		"gen_ai.request.model":              "gpt-4o",
+		"llm.invocation_parameters":         `{"temperature":0.2}`,
+		"llm.input_messages.0.message.role": "system",
+		"llm.input_messages.2.message.tool_calls.0.tool_call.function.name": "lookup",
+		"llm.input_messages.3.message.tool_call_id":                         "c1",
+		"llm.output_messages.0.message.content":                             `{"priority": "high"}`,
+		"llm.token_count.prompt":                                            7,
+		"gen_ai.usage.output_tokens":                                        2,
+	}
+	for k, v := range want {
+		if llm[k] != v {
+			t.Errorf("%s = %v, want %v", k, llm[k], v)
+		}
+	}
+	if !strings.Contains(llm["llm.tools.0.tool.json_schema"].(string), `"name":"lookup"`) {
+		t.Errorf("Expected the tool schemas, got %v", llm["llm.tools.0.tool.json_schema"])
+	}
+	tool := spans[1].Attributes
+	if tool["tool.name"] != "lookup" || tool["tool.description"] != "Look a ticket up" || tool["input.value"] != `{"ticket":42}` || tool["output.mime_type"] != "application/json" {
+		t.Errorf("Unexpected tool attributes %v", tool)
+	}
+
+	// Outside a run, model calls are not traced.
+	if _, err := e.Model.Complete(context.Background(), &engine.Request{Messages: []engine.Message{{Role: engine.RoleUser}}}); err != nil || len(rec.traces) != 1 {
+		t.Errorf("Expected no trace outside a run, got %d, %v", len(rec.traces), err)
+	}
+}
+
+func TestOTLP(t *testing.T) {
+	var path, auth string
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+	tracer := &Tracer{Exporter: &OTLP{Endpoint: srv.URL, Headers: map[string]string{"Authorization": "Basic a2V5"}, Service: "support"}}
+	e := &engine.Engine{Model: modelFunc(func(*engine.Request) (*engine.Response, error) {
+		return nil, context.DeadlineExceeded
+	})}
+	tracer.Instrument(e)
+	if _, err := e.RunAgent(context.Background(), ossa.NewManifest("triage", ossa.KindAgent), nil); err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+	if path != "/v1/traces" || auth != "Basic a2V5" {
+		t.Errorf("Unexpected export to %s with %q", path, auth)
+	}
+	data, _ := json.Marshal(body)
+	for _, s := range []string{`"stringValue":"support"`, `"parentSpanId"`, `"code":2`, `"key":"openinference.span.kind","value":{"stringValue":"AGENT"}`, `"key":"llm.token_count.total","value":{"intValue":"0"}`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Expected %s in %s", s, data)
+		}
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { http.Error(w, "bad key", http.StatusUnauthorized) })
+	err := (&OTLP{Endpoint: srv.URL + "/v1/traces"}).Export(context.Background(), nil)
+	if err == nil || !strings.HasSuffix(err.Error(), "401 Unauthorized: bad key") {
+		t.Errorf("Expected the collector's error, got %v", err)
+	}
+}
+
+// traceRun traces one run of lookupThenAnswer under trace ID id.
+func traceRun(t *testing.T, id string) []*Span {
+	rec := &recorder{}
+	e := &engine.Engine{
+		Model: modelFunc(lookupThenAnswer),
+		Tools: func(*ossa.Manifest) ossa.ToolExecFunc {
+			return func(context.Context, ossa.ToolCall) ([]byte, error) { return []byte("ticket 42"), nil }
+		},
+	}
+	(&Tracer{Exporter: rec}).Instrument(e)
+	m := ossa.NewManifest("triage", ossa.KindAgent)
+	m.Metadata.Version = "1.0.0"
+	m.Spec.LLM = &ossa.LLMConfig{Provider: "openai", Model: "gpt-4o", Temperature: 0.2}
+	if _, err := e.RunAgent(WithTraceID(context.Background(), id), m, map[string]interface{}{"ticket": 42}); err != nil {
+		t.Fatal(err)
+	}
+	return rec.traces[0]
+}
+
+func TestLangfuse(t *testing.T) {
+	var auth string
+	var batches []map[string][]map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		var b map[string][]map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&b)
+		batches = append(batches, b)
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`{"successes": [], "errors": []}`))
+	}))
+	defer srv.Close()
+	env := map[string]string{"LANGFUSE_PUBLIC_KEY": "pk", "LANGFUSE_SECRET_KEY": "sk", "LANGFUSE_HOST": srv.URL}
+	l, err := LangfuseFromEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &Tracer{Exporter: l}
+	spans := traceRun(t, "run-1")
+	if err := l.Export(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Basic cGs6c2s=" {
+		t.Errorf("Unexpected auth %q", auth)
+	}
+	var types []string
+	for _, ev := range batches[0]["batch"] {
+		types = append(types, ev["type"].(string))
+	}
+	if strings.Join(types, " ") != "generation-create span-create generation-create trace-create span-create" {
+		t.Errorf("Unexpected events %v", types)
+	}
+	trace := batches[0]["batch"][3]["body"].(map[string]interface{})
+	gen := batches[0]["batch"][2]["body"].(map[string]interface{})
+	if trace["id"] != "run-1" || trace["version"] != "1.0.0" || trace["metadata"].(map[string]interface{})[AttrManifestDigest] == nil {
+		t.Errorf("Unexpected trace %v", trace)
+	}
+	if gen["model"] != "gpt-4o-2024-08-06" || gen["parentObservationId"] != spans[3].SpanID || gen["usage"].(map[string]interface{})["total"] != 9.0 || gen["modelParameters"].(map[string]interface{})["temperature"] != 0.2 {
+		t.Errorf("Unexpected generation %v", gen)
+	}
+
+	if err := tracer.Score(context.Background(), Score{TraceID: "run-1", Name: "accuracy", Value: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	score := batches[1]["batch"][0]
+	if score["type"] != "score-create" || score["body"].(map[string]interface{})["traceId"] != "run-1" {
+		t.Errorf("Unexpected score %v", score)
+	}
+	if _, err := LangfuseFromEnv(func(string) (string, bool) { return "", false }); err == nil {
+		t.Error("Expected missing keys reported")
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`{"successes": [], "errors": [{"id": "e1", "status": 400, "message": "invalid body"}]}`))
+	})
+	if err := l.Export(context.Background(), spans); err == nil || !strings.Contains(err.Error(), "rejected 1 of 5 events: 400 invalid body") {
+		t.Errorf("Expected rejected events reported, got %v", err)
+	}
+}
+
+func TestLangSmith(t *testing.T) {
+	var key string
+	var posts []map[string]interface{}
+	var feedback map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("x-api-key")
+		switch r.URL.Path {
+		case "/runs/batch":
+			var b struct{ Post []map[string]interface{} }
+			json.NewDecoder(r.Body).Decode(&b)
+			posts = b.Post
+		case "/feedback":
+			json.NewDecoder(r.Body).Decode(&feedback)
+		}
+	}))
+	defer srv.Close()
+	env := map[string]string{"LANGCHAIN_API_KEY": "ls-key", "LANGSMITH_ENDPOINT": srv.URL, "LANGSMITH_PROJECT": "support"}
+	l, err := LangSmithFromEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok })
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := NewTraceID()
+	spans := traceRun(t, id)
+	if err := l.Export(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	if key != "ls-key" || len(posts) != 4 {
+		t.Fatalf("Expected 4 runs with the key, got %d with %q", len(posts), key)
+	}
+	root, llm, tool := posts[3], posts[0], posts[1]
+	traceUUID := uuidOf(id)
+	if root["id"] != traceUUID || root["trace_id"] != traceUUID || root["run_type"] != "chain" || root["session_name"] != "support" || root["parent_run_id"] != nil {
+		t.Errorf("Unexpected root run %v", root)
+	}
+	if llm["run_type"] != "llm" || llm["parent_run_id"] != traceUUID || !strings.HasPrefix(llm["dotted_order"].(string), root["dotted_order"].(string)+".") {
+		t.Errorf("Unexpected llm run %v", llm)
+	}
+	meta := llm["extra"].(map[string]interface{})["metadata"].(map[string]interface{})
+	if meta["ls_model_name"] != "gpt-4o" || meta[AttrManifestName] != "triage" {
+		t.Errorf("Unexpected llm metadata %v", meta)
+	}
+	if tool["run_type"] != "tool" || tool["outputs"].(map[string]interface{})["output"] != "ticket 42" {
+		t.Errorf("Unexpected tool run %v", tool)
+	}
+
+	if err := l.ExportScores(context.Background(), []Score{{TraceID: id, Name: "helpful", Value: 1, Comment: "great"}}); err != nil {
+		t.Fatal(err)
+	}
+	if feedback["run_id"] != traceUUID || feedback["key"] != "helpful" || feedback["comment"] != "great" {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+	if err := (&Tracer{Exporter: &OTLP{}}).Score(context.Background(), Score{}); err == nil {
+		t.Error("Expected scores refused by OTLP")
+	}
+}